@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) 2021 Terminus, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+CREATE TABLE `cmp_shell_session`
+(
+    `id`              varchar(36)  NOT NULL COMMENT '会话 ID',
+    `created_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `org_id`          bigint(20)   NOT NULL DEFAULT 0 COMMENT '组织 ID',
+    `org_name`        varchar(50)  NOT NULL DEFAULT '' COMMENT '组织名称',
+    `user_id`         varchar(255) NOT NULL DEFAULT '' COMMENT '用户 ID',
+    `cluster_name`    varchar(41)  NOT NULL DEFAULT '' COMMENT '集群标识',
+    `client_ip`       varchar(64)  NOT NULL DEFAULT '' COMMENT '客户端 IP',
+    `user_agent`      varchar(255) NOT NULL DEFAULT '' COMMENT '客户端 User-Agent',
+    `started_at`      datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '会话开始时间',
+    `ended_at`        datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '会话结束时间',
+    `duration`        bigint(20)   NOT NULL DEFAULT 0 COMMENT '会话时长，单位毫秒',
+    `size`            bigint(20)   NOT NULL DEFAULT 0 COMMENT '录像原始大小，单位 Byte',
+    `commands`        mediumtext   NOT NULL COMMENT '会话中执行的命令（已脱敏），换行分隔',
+    `is_truncated`    tinyint(1)   NOT NULL DEFAULT 0 COMMENT '录像是否因超出大小限制被截断',
+    `recording`       longblob     NOT NULL COMMENT 'gzip 压缩的 asciicast v2 录像',
+    `soft_deleted_at` bigint(20)   NOT NULL DEFAULT 0 COMMENT '删除时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_org_cluster_started` (`org_id`, `cluster_name`, `started_at`),
+    KEY `idx_user_id` (`user_id`),
+    KEY `idx_started_at` (`started_at`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT = 'CMP web shell 会话录像';
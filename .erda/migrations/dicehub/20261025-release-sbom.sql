@@ -0,0 +1,67 @@
+CREATE TABLE `dice_release_sboms`
+(
+    `id`         BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'primary key',
+    `created_at` DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at` DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `release_id` VARCHAR(64)         NOT NULL DEFAULT '' COMMENT 'dice_release.release_id',
+    `image`      VARCHAR(512)        NOT NULL DEFAULT '' COMMENT '镜像地址',
+    `format`     VARCHAR(32)         NOT NULL DEFAULT '' COMMENT 'SBOM 格式: spdx, cyclonedx',
+    `source`     VARCHAR(32)         NOT NULL DEFAULT '' COMMENT '来源: upload, generator',
+    `content`    LONGTEXT COMMENT 'SBOM 文档',
+    `components` INT(11)             NOT NULL DEFAULT 0 COMMENT '组件数',
+    `scanned_at` DATETIME            NULL     DEFAULT NULL COMMENT '最近一次漏洞扫描时间',
+    PRIMARY KEY (`id`),
+    INDEX `idx_release_id` (`release_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='制品镜像 SBOM 表';
+
+CREATE TABLE `dice_vulnerability_advisories`
+(
+    `id`         BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'primary key',
+    `created_at` DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at` DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `vuln_id`    VARCHAR(128)        NOT NULL DEFAULT '' COMMENT '漏洞编号, eg: CVE-2021-44228',
+    `ecosystem`  VARCHAR(64)         NOT NULL DEFAULT '' COMMENT '包生态, eg: maven, npm, go, debian',
+    `package`    VARCHAR(255)        NOT NULL DEFAULT '' COMMENT '包名',
+    `introduced` VARCHAR(128)        NOT NULL DEFAULT '' COMMENT '受影响的起始版本(包含)',
+    `fixed`      VARCHAR(128)        NOT NULL DEFAULT '' COMMENT '修复版本(不包含)',
+    `severity`   VARCHAR(16)         NOT NULL DEFAULT '' COMMENT '漏洞等级',
+    `summary`    VARCHAR(1024)       NOT NULL DEFAULT '' COMMENT '漏洞描述',
+    PRIMARY KEY (`id`),
+    INDEX `idx_package` (`package`),
+    INDEX `idx_vuln_id` (`vuln_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='离线漏洞库';
+
+CREATE TABLE `dice_release_vulnerabilities`
+(
+    `id`            BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'primary key',
+    `created_at`    DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`    DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `release_id`    VARCHAR(64)         NOT NULL DEFAULT '' COMMENT 'dice_release.release_id',
+    `image`         VARCHAR(512)        NOT NULL DEFAULT '' COMMENT '镜像地址',
+    `vuln_id`       VARCHAR(128)        NOT NULL DEFAULT '' COMMENT '漏洞编号',
+    `package`       VARCHAR(255)        NOT NULL DEFAULT '' COMMENT '包名',
+    `version`       VARCHAR(128)        NOT NULL DEFAULT '' COMMENT '当前版本',
+    `fixed_version` VARCHAR(128)        NOT NULL DEFAULT '' COMMENT '修复版本',
+    `severity`      VARCHAR(16)         NOT NULL DEFAULT '' COMMENT '漏洞等级',
+    `summary`       VARCHAR(1024)       NOT NULL DEFAULT '' COMMENT '漏洞描述',
+    PRIMARY KEY (`id`),
+    INDEX `idx_release_id` (`release_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='制品漏洞扫描结果';
+
+CREATE TABLE `dice_release_vulnerability_gates`
+(
+    `id`           BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'primary key',
+    `created_at`   DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`   DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `project_id`   BIGINT(20)          NOT NULL DEFAULT 0 COMMENT '项目 ID',
+    `workspace`    VARCHAR(32)         NOT NULL DEFAULT '' COMMENT '环境',
+    `enabled`      TINYINT(1)          NOT NULL DEFAULT 0 COMMENT '是否启用',
+    `thresholds`   VARCHAR(1024)       NOT NULL DEFAULT '' COMMENT '各等级允许的最大漏洞数, json',
+    `require_sbom` TINYINT(1)          NOT NULL DEFAULT 0 COMMENT '是否要求所有镜像都有 SBOM',
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `uk_project_workspace` (`project_id`, `workspace`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='制品漏洞门禁';
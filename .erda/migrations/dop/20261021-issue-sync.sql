@@ -0,0 +1,52 @@
+CREATE TABLE IF NOT EXISTS `erda_issue_sync_connection` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT 'primary key',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'created time',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'updated time',
+  `org_id` bigint(20) NOT NULL DEFAULT 0 COMMENT 'org id',
+  `project_id` bigint(20) NOT NULL DEFAULT 0 COMMENT 'project id',
+  `name` varchar(191) NOT NULL DEFAULT '' COMMENT 'connection name',
+  `provider` varchar(32) NOT NULL DEFAULT '' COMMENT 'jira or github',
+  `base_url` varchar(255) NOT NULL DEFAULT '' COMMENT 'remote api base url',
+  `remote_project` varchar(191) NOT NULL DEFAULT '' COMMENT 'jira project key or github owner/repo',
+  `username` varchar(191) NOT NULL DEFAULT '' COMMENT 'remote username',
+  `token` text NOT NULL COMMENT 'encrypted remote token',
+  `webhook_secret` text NOT NULL COMMENT 'encrypted webhook secret',
+  `mapping` text NOT NULL COMMENT 'field mapping in json',
+  `conflict_strategy` varchar(32) NOT NULL DEFAULT 'latest-wins' COMMENT 'erda-wins, remote-wins or latest-wins',
+  `enabled` tinyint(1) NOT NULL DEFAULT 0 COMMENT 'enabled',
+  `last_polled_at` datetime DEFAULT NULL COMMENT 'last polled time',
+  `creator` varchar(191) NOT NULL DEFAULT '' COMMENT 'creator',
+  PRIMARY KEY (`id`),
+  KEY `idx_project_id` (`project_id`),
+  KEY `idx_enabled` (`enabled`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='issue sync connection to jira or github';
+
+CREATE TABLE IF NOT EXISTS `erda_issue_sync_link` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT 'primary key',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'created time',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'updated time',
+  `connection_id` bigint(20) unsigned NOT NULL DEFAULT 0 COMMENT 'connection id',
+  `issue_id` bigint(20) unsigned NOT NULL DEFAULT 0 COMMENT 'erda issue id',
+  `remote_key` varchar(191) NOT NULL DEFAULT '' COMMENT 'jira issue key or github issue number',
+  `remote_url` varchar(512) NOT NULL DEFAULT '' COMMENT 'remote issue url',
+  `snapshot` text NOT NULL COMMENT 'field values agreed by both sides at last sync',
+  `remote_updated_at` datetime DEFAULT NULL COMMENT 'remote updated time at last sync',
+  `synced_at` datetime DEFAULT NULL COMMENT 'last synced time',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_connection_remote_key` (`connection_id`, `remote_key`),
+  KEY `idx_connection_issue` (`connection_id`, `issue_id`),
+  KEY `idx_issue_id` (`issue_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='link between erda issue and remote issue';
+
+CREATE TABLE IF NOT EXISTS `erda_issue_sync_comment` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT 'primary key',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'created time',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'updated time',
+  `connection_id` bigint(20) unsigned NOT NULL DEFAULT 0 COMMENT 'connection id',
+  `issue_id` bigint(20) unsigned NOT NULL DEFAULT 0 COMMENT 'erda issue id',
+  `stream_id` bigint(20) unsigned NOT NULL DEFAULT 0 COMMENT 'erda comment stream id',
+  `remote_comment_id` varchar(191) NOT NULL DEFAULT '' COMMENT 'remote comment id',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_connection_remote_comment` (`connection_id`, `remote_comment_id`),
+  KEY `idx_connection_issue` (`connection_id`, `issue_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='link between erda issue comment and remote comment';
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `erda_issue_saved_query` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT 'primary key',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'created time',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'updated time',
+  `org_id` bigint(20) NOT NULL DEFAULT 0 COMMENT 'org id',
+  `project_id` bigint(20) NOT NULL DEFAULT 0 COMMENT 'project id, 0 for cross-project queries',
+  `name` varchar(191) NOT NULL DEFAULT '' COMMENT 'query name',
+  `ql` text NOT NULL COMMENT 'issue query language statement',
+  `shared` tinyint(1) NOT NULL DEFAULT 0 COMMENT 'visible to other members',
+  `creator` varchar(191) NOT NULL DEFAULT '' COMMENT 'creator',
+  PRIMARY KEY (`id`),
+  KEY `idx_org_project` (`org_id`, `project_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='saved issue queries';
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS `erda_issue_state_transition_rule` (
+  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT 'primary key',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'created time',
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'updated time',
+  `project_id` bigint(20) NOT NULL DEFAULT 0 COMMENT 'project id',
+  `issue_type` varchar(40) NOT NULL DEFAULT '' COMMENT 'issue type',
+  `state_from` bigint(20) NOT NULL DEFAULT 0 COMMENT 'source state id, 0 for any state',
+  `state_to` bigint(20) NOT NULL DEFAULT 0 COMMENT 'target state id',
+  `guards` text NOT NULL COMMENT 'transition guards in json',
+  `post_functions` text NOT NULL COMMENT 'post functions in json',
+  `creator` varchar(191) NOT NULL DEFAULT '' COMMENT 'creator',
+  PRIMARY KEY (`id`),
+  KEY `idx_project_type_to` (`project_id`, `issue_type`, `state_to`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='issue state transition guards and post functions';
@@ -0,0 +1 @@
+ALTER TABLE erda_rule_exec_history ADD `node_results` text COMMENT 'results of each action node';
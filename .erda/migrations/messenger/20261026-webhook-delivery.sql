@@ -0,0 +1,26 @@
+CREATE TABLE `erda_webhook_deliveries`
+(
+    `id`               BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'primary key',
+    `created_at`       DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`       DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `hook_id`          VARCHAR(64)         NOT NULL DEFAULT '' COMMENT 'webhook id',
+    `org_id`           VARCHAR(64)         NOT NULL DEFAULT '' COMMENT 'webhook 所属 org',
+    `event`            VARCHAR(128)        NOT NULL DEFAULT '' COMMENT '事件',
+    `action`           VARCHAR(128)        NOT NULL DEFAULT '' COMMENT '事件动作',
+    `status`           VARCHAR(32)         NOT NULL DEFAULT '' COMMENT '投递状态: pending, delivering, retrying, succeeded, dead',
+    `attempts`         INT(11)             NOT NULL DEFAULT 0 COMMENT '已尝试次数',
+    `next_retry_at`    DATETIME            NULL     DEFAULT NULL COMMENT '下次投递时间',
+    `request_url`      VARCHAR(1024)       NOT NULL DEFAULT '' COMMENT '请求地址',
+    `request_headers`  TEXT COMMENT '请求头',
+    `request_body`     MEDIUMTEXT COMMENT '请求体',
+    `response_status`  INT(11)             NOT NULL DEFAULT 0 COMMENT '最近一次响应状态码',
+    `response_headers` TEXT COMMENT '最近一次响应头',
+    `response_body`    TEXT COMMENT '最近一次响应体, 超长截断',
+    `latency_ms`       BIGINT(20)          NOT NULL DEFAULT 0 COMMENT '最近一次请求耗时, 毫秒',
+    `error`            TEXT COMMENT '最近一次投递错误',
+    `replay_of`        BIGINT(20) UNSIGNED NOT NULL DEFAULT 0 COMMENT '重放的原投递 id',
+    PRIMARY KEY (`id`),
+    INDEX `idx_hook_id` (`hook_id`, `created_at`),
+    INDEX `idx_status_next_retry_at` (`status`, `next_retry_at`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='webhook 投递记录表';
@@ -0,0 +1,66 @@
+CREATE TABLE `erda_notify_user_settings`
+(
+    `id`                  BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'primary key',
+    `created_at`          DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`          DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `user_id`             VARCHAR(64)         NOT NULL DEFAULT '' COMMENT '用户 id',
+    `org_id`              BIGINT(20)          NOT NULL DEFAULT 0 COMMENT 'org id',
+    `timezone`            VARCHAR(64)         NOT NULL DEFAULT '' COMMENT 'IANA 时区',
+    `is_quiet_enabled`    TINYINT(1)          NOT NULL DEFAULT 0 COMMENT '是否开启免打扰',
+    `quiet_start`         VARCHAR(8)          NOT NULL DEFAULT '' COMMENT '免打扰开始时间, HH:MM',
+    `quiet_end`           VARCHAR(8)          NOT NULL DEFAULT '' COMMENT '免打扰结束时间, HH:MM',
+    `digest_hour`         INT(11)             NOT NULL DEFAULT 9 COMMENT '摘要发送整点',
+    `digest_weekday`      INT(11)             NOT NULL DEFAULT 1 COMMENT '周摘要发送星期, 0 为周日',
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `uk_user_org` (`user_id`, `org_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='用户通知设置表';
+
+CREATE TABLE `erda_notify_preferences`
+(
+    `id`         BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'primary key',
+    `created_at` DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at` DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `user_id`    VARCHAR(64)         NOT NULL DEFAULT '' COMMENT '用户 id',
+    `org_id`     BIGINT(20)          NOT NULL DEFAULT 0 COMMENT 'org id',
+    `category`   VARCHAR(64)         NOT NULL DEFAULT '' COMMENT '事件类别, * 表示所有类别',
+    `channel`    VARCHAR(32)         NOT NULL DEFAULT '' COMMENT '通知方式: mbox, email, im',
+    `is_enabled` TINYINT(1)          NOT NULL DEFAULT 1 COMMENT '是否接收',
+    `frequency`  VARCHAR(32)         NOT NULL DEFAULT '' COMMENT '发送频率: realtime, daily, weekly',
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `uk_user_org_category_channel` (`user_id`, `org_id`, `category`, `channel`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='用户通知偏好表';
+
+CREATE TABLE `erda_notify_digest_items`
+(
+    `id`         BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'primary key',
+    `created_at` DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at` DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `user_id`    VARCHAR(64)         NOT NULL DEFAULT '' COMMENT '用户 id',
+    `org_id`     BIGINT(20)          NOT NULL DEFAULT 0 COMMENT 'org id',
+    `label`      VARCHAR(64)         NOT NULL DEFAULT '' COMMENT 'eventbox 发送目的 label, 如 MBOX, EMAIL',
+    `target`     VARCHAR(255)        NOT NULL DEFAULT '' COMMENT '发送目标, 如用户 id, 邮箱, 手机号',
+    `category`   VARCHAR(64)         NOT NULL DEFAULT '' COMMENT '事件类别',
+    `title`      VARCHAR(512)        NOT NULL DEFAULT '' COMMENT '通知标题',
+    `content`    MEDIUMTEXT          NOT NULL COMMENT '通知内容, markdown',
+    `due_at`     DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '发送时间',
+    `claim`      VARCHAR(64)         NOT NULL DEFAULT '' COMMENT '发送中的实例标识',
+    PRIMARY KEY (`id`),
+    INDEX `idx_due_at` (`due_at`),
+    INDEX `idx_claim` (`claim`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='通知摘要待发送队列';
+
+CREATE TABLE `erda_notify_dedup`
+(
+    `id`          BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'primary key',
+    `created_at`  DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`  DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `fingerprint` CHAR(64)            NOT NULL DEFAULT '' COMMENT '通知指纹, sha256(用户, 通知方式, 目标, 内容)',
+    `expire_at`   DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '去重窗口结束时间',
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `uk_fingerprint` (`fingerprint`),
+    INDEX `idx_expire_at` (`expire_at`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='通知去重表';
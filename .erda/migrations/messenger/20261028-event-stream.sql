@@ -0,0 +1,17 @@
+CREATE TABLE `erda_event_stream_outbox`
+(
+    `id`              BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'primary key, 同一分区键内按 id 顺序发送',
+    `created_at`      DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`      DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `event_id`        VARCHAR(64)         NOT NULL DEFAULT '' COMMENT 'CloudEvent id',
+    `event_type`      VARCHAR(255)        NOT NULL DEFAULT '' COMMENT 'CloudEvent type',
+    `partition_key`   VARCHAR(255)        NOT NULL DEFAULT '' COMMENT '分区键, 如 pipeline/1',
+    `payload`         MEDIUMTEXT          NOT NULL COMMENT '结构化模式的 CloudEvent',
+    `attempts`        INT(11)             NOT NULL DEFAULT 0 COMMENT '失败次数',
+    `next_attempt_at` DATETIME            NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '下次发送时间',
+    `claim`           VARCHAR(64)         NOT NULL DEFAULT '' COMMENT '发送中的实例标识',
+    `last_error`      VARCHAR(1024)       NOT NULL DEFAULT '' COMMENT '最近一次发送错误',
+    PRIMARY KEY (`id`),
+    INDEX `idx_partition_key_id` (`partition_key`, `id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='事件流待发送队列';
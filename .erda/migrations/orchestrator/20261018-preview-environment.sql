@@ -0,0 +1,42 @@
+/*
+ * Copyright (c) 2021 Terminus, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+CREATE TABLE `erda_v2_preview_environment`
+(
+    `id`              bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '自增 ID',
+    `created_at`      datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`      datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `org_id`          bigint(20) unsigned NOT NULL COMMENT '组织ID',
+    `project_id`      bigint(20) unsigned NOT NULL COMMENT '项目ID',
+    `application_id`  bigint(20) unsigned NOT NULL COMMENT '应用ID',
+    `merge_id`        bigint(20) NOT NULL COMMENT '合并请求 ID (仓库内)',
+    `source_branch`   varchar(255) NOT NULL DEFAULT '' COMMENT '合并请求源分支',
+    `release_id`      varchar(64) NOT NULL DEFAULT '' COMMENT '制品 ID',
+    `runtime_id`      bigint(20) unsigned NOT NULL DEFAULT 0 COMMENT 'Runtime ID',
+    `runtime_name`    varchar(255) NOT NULL DEFAULT '' COMMENT 'Runtime 名称',
+    `base_workspace`  varchar(16) NOT NULL DEFAULT '' COMMENT '基础环境',
+    `namespace`       varchar(64) NOT NULL DEFAULT '' COMMENT '隔离的命名空间',
+    `cluster_name`    varchar(255) NOT NULL DEFAULT '' COMMENT '集群名称',
+    `addon_policies`  text COMMENT 'addon 共享或克隆策略',
+    `status`          varchar(32) NOT NULL DEFAULT '' COMMENT '状态',
+    `teardown_reason` varchar(32) NOT NULL DEFAULT '' COMMENT '销毁原因',
+    `creator`         varchar(255) NOT NULL DEFAULT '' COMMENT '创建人',
+    `expired_at`      datetime NOT NULL COMMENT '过期时间',
+    `soft_deleted_at` bigint(20) NOT NULL DEFAULT 0 COMMENT '删除时间',
+    PRIMARY KEY (`id`),
+    KEY `idx_app_merge` (`application_id`, `merge_id`),
+    KEY `idx_status_expired` (`status`, `expired_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='合并请求预览环境';
@@ -0,0 +1,48 @@
+CREATE TABLE `qa_flaky_tests`
+(
+    `id`                bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+    `created_at`        datetime            NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`        datetime            NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `project_id`        bigint(20)          NOT NULL DEFAULT 0 COMMENT '项目id',
+    `app_id`            bigint(20)          NOT NULL DEFAULT 0 COMMENT '应用id',
+    `test_key`          varchar(64)         NOT NULL DEFAULT '' COMMENT '用例唯一标识, suite/classname/name 的摘要',
+    `suite`             varchar(255)        NOT NULL DEFAULT '' COMMENT '测试套件名称',
+    `classname`         varchar(255)        NOT NULL DEFAULT '' COMMENT '用例类名或包名',
+    `name`              varchar(512)        NOT NULL DEFAULT '' COMMENT '用例名称',
+    `total_runs`        bigint(20)          NOT NULL DEFAULT 0 COMMENT '累计执行次数',
+    `failed_runs`       bigint(20)          NOT NULL DEFAULT 0 COMMENT '累计失败次数',
+    `flips`             bigint(20)          NOT NULL DEFAULT 0 COMMENT '相邻两次执行结果翻转次数',
+    `commit_flips`      bigint(20)          NOT NULL DEFAULT 0 COMMENT '同一 commit 上执行结果翻转次数',
+    `flakiness`         double              NOT NULL DEFAULT 0 COMMENT '不稳定指数, 0-1',
+    `is_flaky`          tinyint(1)          NOT NULL DEFAULT 0 COMMENT '是否判定为不稳定用例',
+    `last_status`       varchar(20)         NOT NULL DEFAULT '' COMMENT '最近一次执行结果',
+    `last_commit_id`    varchar(191)        NOT NULL DEFAULT '' COMMENT '最近一次执行的 commit id',
+    `last_record_id`    bigint(20)          NOT NULL DEFAULT 0 COMMENT '最近一次执行的测试记录id',
+    `last_run_at`       datetime            NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '最近一次执行时间',
+    `quarantined`       tinyint(1)          NOT NULL DEFAULT 0 COMMENT '是否已隔离, 隔离后失败不影响测试报告门禁',
+    `quarantined_by`    varchar(255)        NOT NULL DEFAULT '' COMMENT '隔离操作人',
+    `quarantine_reason` varchar(1024)       NOT NULL DEFAULT '' COMMENT '隔离原因',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_app_test_key` (`app_id`, `test_key`),
+    KEY `idx_app_flaky` (`app_id`, `is_flaky`),
+    KEY `idx_app_quarantined` (`app_id`, `quarantined`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='单元测试不稳定用例统计表';
+
+CREATE TABLE `qa_flaky_test_daily`
+(
+    `id`            bigint(20) unsigned NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+    `created_at`    datetime            NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at`    datetime            NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    `app_id`        bigint(20)          NOT NULL DEFAULT 0 COMMENT '应用id',
+    `flaky_test_id` bigint(20)          NOT NULL DEFAULT 0 COMMENT '不稳定用例统计id',
+    `day`           varchar(10)         NOT NULL DEFAULT '' COMMENT '统计日期, yyyy-mm-dd',
+    `runs`          bigint(20)          NOT NULL DEFAULT 0 COMMENT '当日执行次数',
+    `failed_runs`   bigint(20)          NOT NULL DEFAULT 0 COMMENT '当日失败次数',
+    `flips`         bigint(20)          NOT NULL DEFAULT 0 COMMENT '当日结果翻转次数',
+    `commit_flips`  bigint(20)          NOT NULL DEFAULT 0 COMMENT '当日同一 commit 上结果翻转次数',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_flaky_test_day` (`flaky_test_id`, `day`),
+    KEY `idx_app_day` (`app_id`, `day`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='单元测试用例每日执行趋势表';
@@ -0,0 +1,2 @@
+ALTER TABLE `dice_autotest_plan` ADD `type` VARCHAR(32) NOT NULL DEFAULT 'default' COMMENT 'auto test plan type, default or load';
+ALTER TABLE `dice_autotest_plan` ADD `load_config` TEXT COMMENT 'load test config of load type plan';
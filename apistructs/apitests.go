@@ -90,6 +90,8 @@ var (
 	APIOutParamSourceBodyJsonJacksonPath APIOutParamSource = "body:json:jackson"
 	APIOutParamSourceBodyText            APIOutParamSource = "body:text"
 	APIOutParamSourceHeader              APIOutParamSource = "header"
+	// APIOutParamSourceRegexCapture 由正则断言捕获的出参
+	APIOutParamSourceRegexCapture APIOutParamSource = "regex:capture"
 )
 
 func (source APIOutParamSource) String() string {
@@ -109,6 +111,47 @@ type APIAssert struct {
 	Arg      string `json:"arg"`
 	Operator string `json:"operator"`
 	Value    string `json:"value"`
+	// Type 断言类型, 为空时对出参断言
+	Type APIAssertType `json:"type,omitempty"`
+	// Quantifier 仅 jsonPath 断言使用, 匹配结果为数组时的判定方式, 默认 any
+	Quantifier APIAssertQuantifier `json:"quantifier,omitempty"`
+	// Capture 仅 regex 断言使用, 匹配成功后将第一个分组(无分组时为整体)存为该名称的出参, 命名分组按分组名存储
+	Capture string `json:"capture,omitempty"`
+}
+
+// APIAssertType 断言类型
+type APIAssertType string
+
+var (
+	// APIAssertTypeOutParam Arg 为出参名, 对出参值断言
+	APIAssertTypeOutParam APIAssertType = "outParam"
+	// APIAssertTypeJSONSchema Value 为 JSON Schema, 校验整个响应体; Arg 不为空时校验该出参
+	APIAssertTypeJSONSchema APIAssertType = "jsonSchema"
+	// APIAssertTypeJSONPath Arg 为 JSONPath 表达式, 支持 [*] 及 .. 等数组匹配
+	APIAssertTypeJSONPath APIAssertType = "jsonPath"
+	// APIAssertTypeRegex Value 为正则, Arg 为出参名, 为空时匹配响应体
+	APIAssertTypeRegex APIAssertType = "regex"
+	// APIAssertTypeHeader Arg 为响应头名称
+	APIAssertTypeHeader APIAssertType = "header"
+	// APIAssertTypeResponseTime Value 为响应耗时预算, 单位毫秒, Operator 默认 <=
+	APIAssertTypeResponseTime APIAssertType = "responseTime"
+)
+
+// APIAssertQuantifier JSONPath 数组断言的判定方式
+type APIAssertQuantifier string
+
+var (
+	APIAssertQuantifierAny   APIAssertQuantifier = "any"
+	APIAssertQuantifierAll   APIAssertQuantifier = "all"
+	APIAssertQuantifierNone  APIAssertQuantifier = "none"
+	APIAssertQuantifierCount APIAssertQuantifier = "count"
+)
+
+// APIDataSet 数据驱动的步骤数据集, 每行数据执行一次, 列名可通过 {{列名}} 引用
+type APIDataSet struct {
+	// Format csv 或 json, csv 首行为列名, json 为对象数组
+	Format  string `json:"format"`
+	Content string `json:"content"`
 }
 
 // APIResp API测试的返回结果
@@ -117,6 +160,8 @@ type APIResp struct {
 	Headers map[string][]string `json:"headers"`
 	Body    []byte              `json:"-"`
 	BodyStr string              `json:"body"`
+	// ResponseTime 响应耗时, 单位毫秒
+	ResponseTime int64 `json:"responseTime"`
 }
 
 // CaseParams 传递case内出入参的全局变量
@@ -34,6 +34,7 @@ type AutoTestRunScene struct {
 type AutoTestRunStep struct {
 	ApiSpec map[string]interface{} `json:"apiSpec"`
 	Loop    *PipelineTaskLoop      `json:"loop"`
+	DataSet *APIDataSet            `json:"dataSet,omitempty"`
 }
 
 type AutoTestRunWait struct {
@@ -17,12 +17,15 @@ package apistructs
 type TestSceneSetFileType string
 
 var (
-	TestSceneSetFileTypeExcel TestSceneSetFileType = "excel"
+	TestSceneSetFileTypeExcel   TestSceneSetFileType = "excel"
+	TestSceneSetFileTypePostman TestSceneSetFileType = "postman"
+	TestSceneSetFileTypeHAR     TestSceneSetFileType = "har"
+	TestSceneSetFileTypeOpenAPI TestSceneSetFileType = "openapi"
 )
 
 func (t TestSceneSetFileType) Valid() bool {
 	switch t {
-	case TestSceneSetFileTypeExcel:
+	case TestSceneSetFileTypeExcel, TestSceneSetFileTypePostman, TestSceneSetFileTypeHAR, TestSceneSetFileTypeOpenAPI:
 		return true
 	default:
 		return false
@@ -46,6 +49,8 @@ type AutoTestSceneSetImportRequest struct {
 	ProjectID uint64               `schema:"projectID"`
 	SpaceID   uint64               `schema:"spaceID"`
 	FileType  TestSceneSetFileType `schema:"fileType"`
+	// EnvironmentFileUUID postman environment file uploaded together with the collection
+	EnvironmentFileUUID string `json:"environmentFileUUID,omitempty" schema:"-"`
 
 	IdentityInfo
 }
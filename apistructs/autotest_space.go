@@ -197,11 +197,17 @@ type TestSpaceFileType string
 
 var (
 	TestSpaceFileTypeExcel TestSpaceFileType = "excel"
+	// TestSpaceFileTypePostman postman v2.1 collection
+	TestSpaceFileTypePostman TestSpaceFileType = "postman"
+	// TestSpaceFileTypeHAR browser http archive
+	TestSpaceFileTypeHAR TestSpaceFileType = "har"
+	// TestSpaceFileTypeOpenAPI openapi 3 (or swagger 2) spec
+	TestSpaceFileTypeOpenAPI TestSpaceFileType = "openapi"
 )
 
 func (t TestSpaceFileType) Valid() bool {
 	switch t {
-	case TestSpaceFileTypeExcel:
+	case TestSpaceFileTypeExcel, TestSpaceFileTypePostman, TestSpaceFileTypeHAR, TestSpaceFileTypeOpenAPI:
 		return true
 	default:
 		return false
@@ -211,10 +217,39 @@ func (t TestSpaceFileType) Valid() bool {
 type AutoTestSpaceImportRequest struct {
 	ProjectID uint64            `schema:"projectID"`
 	FileType  TestSpaceFileType `schema:"fileType"`
+	// EnvironmentFileUUID postman environment file uploaded together with the collection
+	EnvironmentFileUUID string `json:"environmentFileUUID,omitempty" schema:"-"`
 
 	IdentityInfo
 }
 
+// AutoTestAPIAssetImportRequest import api asset version spec as autotest scenes
+type AutoTestAPIAssetImportRequest struct {
+	ProjectID uint64 `json:"projectID"`
+	// SpaceID import into the space as scene sets if not empty, otherwise create a new space
+	SpaceID   uint64 `json:"spaceID"`
+	AssetID   string `json:"assetID"`
+	VersionID uint64 `json:"versionID"`
+	OrgID     uint64 `json:"-"`
+
+	IdentityInfo
+}
+
+// AutoTestImportReport report of third party api documents import
+type AutoTestImportReport struct {
+	Scenes      int                         `json:"scenes"`
+	Steps       int                         `json:"steps"`
+	Unsupported []AutoTestImportUnsupported `json:"unsupported"`
+}
+
+// AutoTestImportUnsupported construct can not be converted to autotest steps
+type AutoTestImportUnsupported struct {
+	// Location where the construct is, like folder/request name or method and path
+	Location  string `json:"location"`
+	Construct string `json:"construct"`
+	Message   string `json:"message"`
+}
+
 type AutoTestSpaceImportResponse struct {
 	Header
 	Data uint64 `json:"data"`
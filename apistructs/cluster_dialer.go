@@ -27,6 +27,7 @@ var (
 	ClusterManagerHeaderKeyClusterInfo   ClusterManagerHeaderKey = "X-Erda-Cluster-Info"
 	ClusterManagerHeaderKeyAuthorization ClusterManagerHeaderKey = "Authorization"
 	ClusterManagerHeaderKeyClientDetail  ClusterManagerHeaderKey = "X-Erda-Client-Detail"
+	ClusterManagerHeaderKeyTunnelIndex   ClusterManagerHeaderKey = "X-Erda-Tunnel-Index"
 )
 
 func (c ClusterManagerHeaderKey) String() string {
@@ -62,6 +63,18 @@ func (c ClusterManagerClientType) MakeClientKey(clusterKey string) string {
 	return fmt.Sprintf("%s-client-type-%s", clusterKey, c)
 }
 
+// MakeClusterTunnelKey make the session key of the index-th parallel tunnel of a client,
+// the first tunnel uses the client key itself to be compatible with single tunnel clients.
+func MakeClusterTunnelKey(clientKey string, index int) string {
+	if index <= 0 {
+		return clientKey
+	}
+	return fmt.Sprintf("%s%s%d", clientKey, ClusterTunnelKeySeparator, index)
+}
+
+// ClusterTunnelKeySeparator separates client key and tunnel index in tunnel session key
+const ClusterTunnelKeySeparator = "-tunnel-"
+
 type ClusterManagerClientDetailKey string
 
 var (
@@ -0,0 +1,41 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import "time"
+
+// ClusterTunnelHealth is tunnel state of a cluster on a cluster manager instance
+type ClusterTunnelHealth struct {
+	ClusterKey string               `json:"clusterKey"`
+	Connected  bool                 `json:"connected"`
+	Tunnels    []ClusterTunnelState `json:"tunnels"`
+}
+
+// ClusterTunnelState is state and stream statistics of one parallel tunnel
+type ClusterTunnelState struct {
+	Index          int        `json:"index"`
+	Key            string     `json:"key"`
+	Connected      bool       `json:"connected"`
+	ConnectedAt    *time.Time `json:"connectedAt,omitempty"`
+	DisconnectedAt *time.Time `json:"disconnectedAt,omitempty"`
+	Reconnects     int64      `json:"reconnects"`
+	ActiveStreams  int64      `json:"activeStreams"`
+	TotalStreams   int64      `json:"totalStreams"`
+	FailedStreams  int64      `json:"failedStreams"`
+	BytesReceived  int64      `json:"bytesReceived"`
+	BytesSent      int64      `json:"bytesSent"`
+	// AvgDialLatency is average milliseconds of opening a stream in the tunnel
+	AvgDialLatency float64 `json:"avgDialLatency"`
+}
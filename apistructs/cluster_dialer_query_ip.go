@@ -18,4 +18,6 @@ type QueryClusterManagerIPResponse struct {
 	Succeeded bool   `json:"succeeded"`
 	Error     string `json:"error"`
 	IP        string `json:"IP"`
+	// Tunnels are session keys of parallel tunnels connected to the same cluster manager
+	Tunnels []string `json:"tunnels,omitempty"`
 }
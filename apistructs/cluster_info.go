@@ -223,6 +223,8 @@ type NodeResourceInfo struct {
 	Labels []string `json:"labels"`
 	// dcos, edas 缺少一些 label 或无法获取 label, 所以告诉上层忽略 labels
 	IgnoreLabels bool `json:"ignoreLabels"`
+	// taints of the node, format: key=value:effect or key:effect
+	Taints []string `json:"taints"`
 
 	Ready bool `json:"ready"`
 
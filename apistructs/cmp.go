@@ -117,6 +117,29 @@ type UpgradeClusterInfo struct {
 	IsCentralCluster bool   `json:"isCentralCluster"`
 }
 
+type UpgradeKubernetesRequest struct {
+	ClusterName string `json:"clusterName"`
+	// Version is the target kubernetes version, e.g. v1.22.3
+	Version string `json:"version"`
+	// Provider upgrades control plane and node pools, e.g. aliyun-ack
+	Provider       string            `json:"provider"`
+	ProviderConfig map[string]string `json:"providerConfig"`
+	MaxSurge       int               `json:"maxSurge"`
+}
+
+type UpgradeKubernetesResponse struct {
+	Header
+	Data UpgradeKubernetesData `json:"data"`
+}
+
+type UpgradeKubernetesData struct {
+	RecordID uint64 `json:"recordID"`
+}
+
+type PauseUpgradeKubernetesResponse struct {
+	Header
+}
+
 type BatchUpgradeEdgeClusterRequest struct {
 	Clusters []UpgradeClusterInfo `json:"clusters"`
 }
@@ -0,0 +1,63 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import "time"
+
+// ShellSessionListRequest is the search condition of recorded web shell sessions
+type ShellSessionListRequest struct {
+	UserIDs     []string   `json:"userIDs" query:"userID"`
+	ClusterName string     `json:"clusterName" query:"clusterName"`
+	StartTime   *time.Time `json:"startTime" query:"startTime"`
+	EndTime     *time.Time `json:"endTime" query:"endTime"`
+	// Keyword matches the commands executed in sessions
+	Keyword  string `json:"keyword" query:"keyword"`
+	PageNo   int    `json:"pageNo" query:"pageNo"`
+	PageSize int    `json:"pageSize" query:"pageSize"`
+}
+
+type ShellSessionListResponse struct {
+	Header
+	UserInfoHeader
+	Data ShellSessionListData `json:"data"`
+}
+
+type ShellSessionListData struct {
+	Total int64              `json:"total"`
+	List  []ShellSessionData `json:"list"`
+}
+
+type ShellSessionResponse struct {
+	Header
+	UserInfoHeader
+	Data ShellSessionData `json:"data"`
+}
+
+// ShellSessionData is the recorded web shell session without recording content
+type ShellSessionData struct {
+	ID          string    `json:"id"`
+	OrgID       uint64    `json:"orgID"`
+	UserID      string    `json:"userID"`
+	ClusterName string    `json:"clusterName"`
+	ClientIP    string    `json:"clientIP"`
+	UserAgent   string    `json:"userAgent"`
+	StartedAt   time.Time `json:"startedAt"`
+	EndedAt     time.Time `json:"endedAt"`
+	// Duration in milliseconds
+	Duration  int64    `json:"duration"`
+	Size      int64    `json:"size"`
+	Commands  []string `json:"commands"`
+	Truncated bool     `json:"truncated"`
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+// DeploymentSimulateRequest POST /api/deployments/actions/simulate
+// Dry-run the deployment against the current cluster, nothing is created.
+// The services to deploy come from one of:
+//  1. DiceYml (ApplicationID is required to render group labels)
+//  2. ReleaseID
+//  3. DeploymentOrderID, all applications of the order are simulated together
+type DeploymentSimulateRequest struct {
+	ProjectID     uint64 `json:"projectId"`
+	ApplicationID uint64 `json:"applicationId"`
+	Workspace     string `json:"workspace"`
+
+	DiceYml           string `json:"diceYml"`
+	ReleaseID         string `json:"releaseId"`
+	DeploymentOrderID string `json:"deploymentOrderId"`
+
+	Operator string `json:"-"`
+}
+
+// DeploymentSimulateResponse response of deployment simulation
+type DeploymentSimulateResponse struct {
+	Header
+	Data *DeploymentSimulateData `json:"data"`
+}
+
+// DeploymentSimulateData result of deployment simulation
+type DeploymentSimulateData struct {
+	ClusterName string `json:"clusterName"`
+	Workspace   string `json:"workspace"`
+	// Feasible all services are schedulable and the project quota is enough
+	Feasible     bool                    `json:"feasible"`
+	Quota        *QuotaSimulation        `json:"quota,omitempty"`
+	Applications []ApplicationSimulation `json:"applications"`
+}
+
+// ApplicationSimulation simulation result of the services of an application
+type ApplicationSimulation struct {
+	ApplicationID   uint64 `json:"applicationId"`
+	ApplicationName string `json:"applicationName"`
+	ReleaseID       string `json:"releaseId,omitempty"`
+	// key: service name
+	Services map[string]ServiceSimulation `json:"services"`
+}
+
+// ServiceGroupSimulateData result of the scheduling dry-run of a service group
+type ServiceGroupSimulateData struct {
+	// Status ok or unschedulable
+	Status string `json:"status"`
+	// Quota nil if the service group does not belong to a project workspace
+	Quota *QuotaSimulation `json:"quota,omitempty"`
+	// key: service name
+	Services map[string]ServiceSimulation `json:"services"`
+}
+
+// QuotaSimulation project workspace quota (core_services_quota) check of the simulation
+type QuotaSimulation struct {
+	ProjectID string `json:"projectId"`
+	Workspace string `json:"workspace"`
+	// LeftCPU millicores left in the workspace quota before the deployment
+	LeftCPU int64 `json:"leftCpu"`
+	// LeftMem bytes left in the workspace quota before the deployment
+	LeftMem int64 `json:"leftMem"`
+	// RequestsCPU millicores requested by all replicas of the simulated services
+	RequestsCPU int64 `json:"requestsCpu"`
+	// RequestsMem bytes requested by all replicas of the simulated services
+	RequestsMem int64  `json:"requestsMem"`
+	Fits        bool   `json:"fits"`
+	Info        string `json:"info,omitempty"`
+}
+
+// ServiceSimulation scheduling simulation result of a service
+type ServiceSimulation struct {
+	// Status ok or unschedulable
+	Status              string `json:"status"`
+	Replicas            int    `json:"replicas"`
+	SchedulableReplicas int    `json:"schedulableReplicas"`
+	// RequestsCPU millicores requested by one replica, after cpu overcommit
+	RequestsCPU int64 `json:"requestsCpu"`
+	// RequestsMem bytes requested by one replica, after memory overcommit
+	RequestsMem int64 `json:"requestsMem"`
+	// Constraints node label constraints of the service
+	Constraints string `json:"constraints"`
+	// Placements key: node ip, value: replicas placed on the node
+	Placements map[string]int `json:"placements"`
+	// Reasons why some replicas are unschedulable, e.g. "3 node(s) had insufficient cpu"
+	Reasons []string `json:"reasons,omitempty"`
+}
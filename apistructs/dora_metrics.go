@@ -0,0 +1,59 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import "time"
+
+// DORAMetricsRequest DORA 交付指标查询请求
+type DORAMetricsRequest struct {
+	Start          string   `json:"start"`
+	End            string   `json:"end"`
+	OrgID          uint64   `json:"orgID"`
+	ProjectID      uint64   `json:"projectID"`
+	ApplicationIDs []uint64 `json:"applicationIDs"`
+	// Workspace 统计的部署环境，默认 PROD
+	Workspace  string `json:"workspace"`
+	GroupByApp bool   `json:"groupByApp"`
+}
+
+// DORAMetrics DORA 交付指标，时长单位均为秒
+type DORAMetrics struct {
+	ApplicationID   uint64 `json:"applicationID,omitempty"`
+	ApplicationName string `json:"applicationName,omitempty"`
+	// Deployments 统计周期内成功部署的变更数，不包含回滚和相同制品的重新部署
+	Deployments int `json:"deployments"`
+	// DeploymentFrequency 平均每天部署次数
+	DeploymentFrequency float64 `json:"deploymentFrequency"`
+	// LeadTimeForChanges 从代码提交到部署完成的中位时长
+	LeadTimeForChanges float64 `json:"leadTimeForChanges"`
+	LeadTimeSamples    int     `json:"leadTimeSamples"`
+	// FailedDeployments 之后被回滚或关联了故障事项的部署数
+	FailedDeployments int     `json:"failedDeployments"`
+	ChangeFailureRate float64 `json:"changeFailureRate"`
+	// RecoveredIncidents 统计周期内已恢复的告警数，仅项目维度统计
+	RecoveredIncidents int `json:"recoveredIncidents,omitempty"`
+	// MeanTimeToRecover 告警从触发到恢复的平均时长，仅项目维度统计
+	MeanTimeToRecover float64 `json:"meanTimeToRecover,omitempty"`
+}
+
+// DORAMetricsResult DORA 交付指标查询结果
+type DORAMetricsResult struct {
+	ProjectID    uint64        `json:"projectID"`
+	Workspace    string        `json:"workspace"`
+	Start        time.Time     `json:"start"`
+	End          time.Time     `json:"end"`
+	Project      DORAMetrics   `json:"project"`
+	Applications []DORAMetrics `json:"applications,omitempty"`
+}
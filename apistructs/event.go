@@ -128,6 +128,8 @@ type GroupNotifyContent struct {
 	Label                 string                 `json:"label"`
 	ClusterName           string                 `json:"clusterName"`
 	CalledShowNumber      string                 `json:"calledShowNumber"`
+	// Category 通知项的类别，用于匹配用户的通知偏好
+	Category string `json:"category,omitempty"`
 }
 
 type GroupNotifyChannel struct {
@@ -0,0 +1,46 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import "encoding/json"
+
+// EventStreamType 事件流中注册的事件类型
+type EventStreamType struct {
+	// Event eventbox 事件名，如 pipeline, runtime
+	Event string `json:"event"`
+	// Type CloudEvents type 前缀，实际 type 为 <Type>.<action>
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	// Resource 事件所属资源类型，与资源 id 组成分区键，同一分区键的事件保证顺序
+	Resource string `json:"resource"`
+	// KeyField 资源 id 在事件内容中的路径，如 runtime.id
+	KeyField string `json:"keyField"`
+	// Version schema 版本
+	Version string `json:"version"`
+	// Schema 事件内容(CloudEvents data)的 JSON Schema
+	Schema json.RawMessage `json:"schema"`
+}
+
+// EventStreamTypeListResponse 事件类型列表响应
+type EventStreamTypeListResponse struct {
+	Header
+	Data []EventStreamType `json:"data"`
+}
+
+// EventStreamTypeGetResponse 事件类型详情响应
+type EventStreamTypeGetResponse struct {
+	Header
+	Data EventStreamType `json:"data"`
+}
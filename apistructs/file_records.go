@@ -82,11 +82,13 @@ type ManualTestFileExtraInfo struct {
 type AutoTestSpaceFileExtraInfo struct {
 	ImportRequest *AutoTestSpaceImportRequest `json:"importRequest,omitempty"`
 	ExportRequest *AutoTestSpaceExportRequest `json:"exportRequest,omitempty"`
+	ImportReport  *AutoTestImportReport       `json:"importReport,omitempty"`
 }
 
 type AutoTestSceneSetFileExtraInfo struct {
 	ExportRequest *AutoTestSceneSetExportRequest `json:"exportRequest,omitempty"`
 	ImportRequest *AutoTestSceneSetImportRequest `json:"importRequest"`
+	ImportReport  *AutoTestImportReport          `json:"importReport,omitempty"`
 }
 
 type ProjectTemplateFileExtraInfo struct {
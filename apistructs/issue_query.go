@@ -0,0 +1,105 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import (
+	"fmt"
+	"time"
+)
+
+// IssueQueryRequest 按事件查询语言分页查询事件
+type IssueQueryRequest struct {
+	OrgID uint64 `json:"orgID"`
+	// ProjectIDs 查询范围, 为空时查询用户参与的全部项目
+	ProjectIDs []uint64 `json:"projectIDs"`
+	// QL 查询语句, 如 type = BUG AND priority >= HIGH ORDER BY updatedAt DESC
+	QL string `json:"ql"`
+	// SavedQueryID 使用保存的查询, 此时忽略 QL
+	SavedQueryID uint64 `json:"savedQueryID"`
+	PageNo       uint64 `json:"pageNo"`
+	PageSize     uint64 `json:"pageSize"`
+
+	IdentityInfo
+}
+
+// IssueQueryStatsRequest 按事件查询语言分组统计事件, 用于仪表盘
+type IssueQueryStatsRequest struct {
+	OrgID        uint64   `json:"orgID"`
+	ProjectIDs   []uint64 `json:"projectIDs"`
+	QL           string   `json:"ql"`
+	SavedQueryID uint64   `json:"savedQueryID"`
+	// GroupBy 分组字段: type, priority, severity, complexity, state, stateBelong, assignee, creator, owner, iteration, project, stage
+	GroupBy string `json:"groupBy"`
+
+	IdentityInfo
+}
+
+// IssueQueryStatsItem 分组统计结果
+type IssueQueryStatsItem struct {
+	Key   string `json:"key"`
+	Count uint64 `json:"count"`
+}
+
+// IssueSavedQuery 保存的事件查询
+type IssueSavedQuery struct {
+	ID    uint64 `json:"id"`
+	OrgID uint64 `json:"orgID"`
+	// ProjectID 为 0 表示企业下跨项目的查询
+	ProjectID uint64    `json:"projectID"`
+	Name      string    `json:"name"`
+	QL        string    `json:"ql"`
+	Shared    bool      `json:"shared"`
+	Creator   string    `json:"creator"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// IssueSavedQueryCreateRequest 保存事件查询
+type IssueSavedQueryCreateRequest struct {
+	OrgID     uint64 `json:"orgID"`
+	ProjectID uint64 `json:"projectID"`
+	Name      string `json:"name"`
+	QL        string `json:"ql"`
+	// Shared 是否对项目(跨项目查询为企业)其他成员可见
+	Shared bool `json:"shared"`
+
+	IdentityInfo
+}
+
+func (req *IssueSavedQueryCreateRequest) Check() error {
+	if req.OrgID == 0 {
+		return fmt.Errorf("missing orgID")
+	}
+	if req.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	return nil
+}
+
+// IssueSavedQueryUpdateRequest 更新保存的事件查询, 为空的字段保持不变
+type IssueSavedQueryUpdateRequest struct {
+	ID     uint64 `json:"-"`
+	Name   string `json:"name"`
+	QL     string `json:"ql"`
+	Shared *bool  `json:"shared"`
+
+	IdentityInfo
+}
+
+// IssueSavedQueryListRequest 查询保存的事件查询列表
+type IssueSavedQueryListRequest struct {
+	OrgID     uint64 `schema:"orgID"`
+	ProjectID uint64 `schema:"projectID"`
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// IssueSyncProvider 外部事件系统类型
+type IssueSyncProvider string
+
+const (
+	IssueSyncProviderJira   IssueSyncProvider = "jira"
+	IssueSyncProviderGitHub IssueSyncProvider = "github"
+)
+
+func (p IssueSyncProvider) Valid() bool {
+	switch p {
+	case IssueSyncProviderJira, IssueSyncProviderGitHub:
+		return true
+	default:
+		return false
+	}
+}
+
+// IssueSyncConflictStrategy 双方同时修改同一字段时的冲突处理策略
+type IssueSyncConflictStrategy string
+
+const (
+	IssueSyncConflictErdaWins   IssueSyncConflictStrategy = "erda-wins"   // 以 Erda 为准
+	IssueSyncConflictRemoteWins IssueSyncConflictStrategy = "remote-wins" // 以外部系统为准
+	IssueSyncConflictLatestWins IssueSyncConflictStrategy = "latest-wins" // 以最后更新的一方为准
+)
+
+func (s IssueSyncConflictStrategy) Valid() bool {
+	switch s {
+	case IssueSyncConflictErdaWins, IssueSyncConflictRemoteWins, IssueSyncConflictLatestWins:
+		return true
+	default:
+		return false
+	}
+}
+
+// IssueSyncMapping Erda 事件字段与外部系统字段的映射, key 为 Erda 侧取值, value 为外部系统取值
+type IssueSyncMapping struct {
+	// Types 事件类型, 如 BUG -> Bug; GitHub 下映射为 label
+	Types map[string]string `json:"types,omitempty"`
+	// States 状态名称, 如 进行中 -> In Progress; GitHub 只有 open 与 closed
+	States map[string]string `json:"states,omitempty"`
+	// Priorities 优先级, 如 HIGH -> High
+	Priorities map[string]string `json:"priorities,omitempty"`
+	// Properties 自定义字段名称与外部字段, 如 module -> customfield_10010; GitHub 下为 label 前缀
+	Properties map[string]string `json:"properties,omitempty"`
+	// Users Erda 用户 ID 与外部系统用户名
+	Users map[string]string `json:"users,omitempty"`
+}
+
+// IssueSyncConnection 项目与外部事件系统的同步连接
+type IssueSyncConnection struct {
+	ID               uint64                    `json:"id"`
+	OrgID            uint64                    `json:"orgID"`
+	ProjectID        uint64                    `json:"projectID"`
+	Name             string                    `json:"name"`
+	Provider         IssueSyncProvider         `json:"provider"`
+	BaseURL          string                    `json:"baseURL"`
+	RemoteProject    string                    `json:"remoteProject"`
+	Username         string                    `json:"username"`
+	Mapping          IssueSyncMapping          `json:"mapping"`
+	ConflictStrategy IssueSyncConflictStrategy `json:"conflictStrategy"`
+	Enabled          bool                      `json:"enabled"`
+	LastPolledAt     *time.Time                `json:"lastPolledAt,omitempty"`
+	Creator          string                    `json:"creator"`
+	CreatedAt        time.Time                 `json:"createdAt"`
+	UpdatedAt        time.Time                 `json:"updatedAt"`
+}
+
+// IssueSyncConnectionCreateRequest 创建同步连接请求
+type IssueSyncConnectionCreateRequest struct {
+	ProjectID uint64            `json:"projectID"`
+	Name      string            `json:"name"`
+	Provider  IssueSyncProvider `json:"provider"`
+	// BaseURL Jira 地址; GitHub 为空时使用 https://api.github.com
+	BaseURL string `json:"baseURL"`
+	// RemoteProject Jira project key 或 GitHub owner/repo
+	RemoteProject string `json:"remoteProject"`
+	// Username Jira 用户名, GitHub 不需要
+	Username string `json:"username"`
+	// Token Jira API token 或 GitHub personal access token
+	Token string `json:"token"`
+	// WebhookSecret 必填, GitHub webhook 签名密钥, Jira webhook 地址中的 secret 参数
+	WebhookSecret    string                    `json:"webhookSecret"`
+	Mapping          IssueSyncMapping          `json:"mapping"`
+	ConflictStrategy IssueSyncConflictStrategy `json:"conflictStrategy"`
+	Enabled          bool                      `json:"enabled"`
+
+	IdentityInfo
+}
+
+func (req *IssueSyncConnectionCreateRequest) Check() error {
+	if req.ProjectID == 0 {
+		return fmt.Errorf("missing projectID")
+	}
+	if req.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	if !req.Provider.Valid() {
+		return fmt.Errorf("invalid provider: %s", req.Provider)
+	}
+	if req.Provider == IssueSyncProviderJira && req.BaseURL == "" {
+		return fmt.Errorf("missing baseURL")
+	}
+	if req.BaseURL != "" {
+		if err := checkIssueSyncBaseURL(req.BaseURL); err != nil {
+			return err
+		}
+	}
+	if req.RemoteProject == "" {
+		return fmt.Errorf("missing remoteProject")
+	}
+	if req.Provider == IssueSyncProviderGitHub && len(strings.Split(req.RemoteProject, "/")) != 2 {
+		return fmt.Errorf("invalid remoteProject, must be owner/repo")
+	}
+	if req.Token == "" {
+		return fmt.Errorf("missing token")
+	}
+	if req.WebhookSecret == "" {
+		return fmt.Errorf("missing webhookSecret")
+	}
+	if req.ConflictStrategy == "" {
+		req.ConflictStrategy = IssueSyncConflictLatestWins
+	}
+	if !req.ConflictStrategy.Valid() {
+		return fmt.Errorf("invalid conflictStrategy: %s", req.ConflictStrategy)
+	}
+	return nil
+}
+
+// IssueSyncConnectionUpdateRequest 更新同步连接请求, 为空的字段不更新
+type IssueSyncConnectionUpdateRequest struct {
+	ID               uint64                    `json:"-"`
+	Name             string                    `json:"name"`
+	BaseURL          string                    `json:"baseURL"`
+	RemoteProject    string                    `json:"remoteProject"`
+	Username         string                    `json:"username"`
+	Token            string                    `json:"token"`
+	WebhookSecret    string                    `json:"webhookSecret"`
+	Mapping          *IssueSyncMapping         `json:"mapping"`
+	ConflictStrategy IssueSyncConflictStrategy `json:"conflictStrategy"`
+	Enabled          *bool                     `json:"enabled"`
+
+	IdentityInfo
+}
+
+func (req *IssueSyncConnectionUpdateRequest) Check() error {
+	if req.ConflictStrategy != "" && !req.ConflictStrategy.Valid() {
+		return fmt.Errorf("invalid conflictStrategy: %s", req.ConflictStrategy)
+	}
+	if req.BaseURL != "" {
+		if err := checkIssueSyncBaseURL(req.BaseURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IssueSyncConnectionListRequest 查询项目下同步连接请求
+type IssueSyncConnectionListRequest struct {
+	ProjectID uint64 `schema:"projectID"`
+}
+
+// IssueSyncLink Erda 事件与外部事件的关联
+type IssueSyncLink struct {
+	ConnectionID uint64    `json:"connectionID"`
+	IssueID      uint64    `json:"issueID"`
+	RemoteKey    string    `json:"remoteKey"`
+	RemoteURL    string    `json:"remoteURL"`
+	SyncedAt     time.Time `json:"syncedAt"`
+}
+
+// IssueSyncConflict 一次同步中发生冲突的字段及处理结果
+type IssueSyncConflict struct {
+	IssueID     uint64 `json:"issueID"`
+	RemoteKey   string `json:"remoteKey"`
+	Field       string `json:"field"`
+	LocalValue  string `json:"localValue"`
+	RemoteValue string `json:"remoteValue"`
+	// Winner erda 或 remote
+	Winner string `json:"winner"`
+}
+
+// IssueSyncResult 一次同步的结果统计
+type IssueSyncResult struct {
+	Pushed    int                 `json:"pushed"`
+	Pulled    int                 `json:"pulled"`
+	Created   int                 `json:"created"`
+	Comments  int                 `json:"comments"`
+	Conflicts []IssueSyncConflict `json:"conflicts"`
+	Errors    []string            `json:"errors"`
+}
+
+// IssueSyncPushRequest 手动将事件推送到外部系统
+type IssueSyncPushRequest struct {
+	IssueID uint64 `json:"issueID"`
+
+	IdentityInfo
+}
+
+// checkIssueSyncBaseURL 外部系统地址只允许 http(s), 且不能携带用户信息
+func checkIssueSyncBaseURL(baseURL string) error {
+	u, err := url.ParseRequestURI(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid baseURL: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid baseURL: unsupported scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("invalid baseURL: missing host")
+	}
+	if u.User != nil {
+		return fmt.Errorf("invalid baseURL: user info is not allowed")
+	}
+	return nil
+}
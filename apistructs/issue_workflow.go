@@ -0,0 +1,215 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// 状态流转的前置校验类型
+const (
+	// IssueTransitionGuardRequiredFields 流转前指定字段不能为空
+	IssueTransitionGuardRequiredFields = "REQUIRED_FIELDS"
+	// IssueTransitionGuardMRMerged 关联的合并请求均已合并
+	IssueTransitionGuardMRMerged = "MR_MERGED"
+	// IssueTransitionGuardSubtasksDone 包含的子事件均已完成
+	IssueTransitionGuardSubtasksDone = "SUBTASKS_DONE"
+	// IssueTransitionGuardRoles 仅指定项目角色可以流转
+	IssueTransitionGuardRoles = "ROLES"
+)
+
+// 状态流转的后置动作类型
+const (
+	// IssueTransitionPostAssignToCreator 处理人改为创建人
+	IssueTransitionPostAssignToCreator = "ASSIGN_TO_CREATOR"
+	// IssueTransitionPostSetField 设置字段值
+	IssueTransitionPostSetField = "SET_FIELD"
+	// IssueTransitionPostCreateLinkedIssue 创建关联事件
+	IssueTransitionPostCreateLinkedIssue = "CREATE_LINKED_ISSUE"
+	// IssueTransitionPostTriggerPipeline 触发项目流水线
+	IssueTransitionPostTriggerPipeline = "TRIGGER_PIPELINE"
+)
+
+// IssueTransitionSettableFields SET_FIELD 允许设置的字段
+var IssueTransitionSettableFields = map[string]bool{
+	"assignee":   true,
+	"owner":      true,
+	"priority":   true,
+	"complexity": true,
+	"severity":   true,
+	"iteration":  true,
+	"stage":      true,
+}
+
+// IssueTransitionGuard 状态流转的前置校验, 任一校验不通过则拒绝流转
+type IssueTransitionGuard struct {
+	Type string `json:"type"`
+	// Fields REQUIRED_FIELDS 必填的字段: title, content, assignee, owner, priority, complexity, severity,
+	// iteration, stage, planStartedAt, planFinishedAt, estimateTime
+	Fields []string `json:"fields,omitempty"`
+	// Roles ROLES 允许流转的项目角色, 如 Owner, Lead, Dev, QA
+	Roles []string `json:"roles,omitempty"`
+}
+
+// IssueTransitionPostFunction 状态流转成功后执行的动作
+type IssueTransitionPostFunction struct {
+	Type string `json:"type"`
+	// Field, Value SET_FIELD 设置的字段和值, 字段: assignee, owner, priority, complexity, severity, iteration, stage
+	Field string `json:"field,omitempty"`
+	Value string `json:"value,omitempty"`
+	// IssueType, Title CREATE_LINKED_ISSUE 创建的事件类型和标题, 标题为空时沿用原事件标题
+	IssueType string `json:"issueType,omitempty"`
+	Title     string `json:"title,omitempty"`
+	// PipelineDefinitionID TRIGGER_PIPELINE 触发的项目流水线
+	PipelineDefinitionID string `json:"pipelineDefinitionID,omitempty"`
+}
+
+// IssueStateTransitionRule 状态流转规则, 按项目和事件类型配置
+type IssueStateTransitionRule struct {
+	ID        uint64 `json:"id"`
+	ProjectID uint64 `json:"projectID"`
+	IssueType string `json:"issueType"`
+	// StateFrom 为 0 表示任意状态流转到 StateTo
+	StateFrom     int64                         `json:"stateFrom"`
+	StateTo       int64                         `json:"stateTo"`
+	Guards        []IssueTransitionGuard        `json:"guards"`
+	PostFunctions []IssueTransitionPostFunction `json:"postFunctions"`
+	Creator       string                        `json:"creator"`
+	CreatedAt     time.Time                     `json:"createdAt"`
+	UpdatedAt     time.Time                     `json:"updatedAt"`
+}
+
+// IssueStateTransitionRuleCreateRequest 创建状态流转规则
+type IssueStateTransitionRuleCreateRequest struct {
+	ProjectID     uint64                        `json:"projectID"`
+	IssueType     string                        `json:"issueType"`
+	StateFrom     int64                         `json:"stateFrom"`
+	StateTo       int64                         `json:"stateTo"`
+	Guards        []IssueTransitionGuard        `json:"guards"`
+	PostFunctions []IssueTransitionPostFunction `json:"postFunctions"`
+
+	IdentityInfo
+}
+
+func (req *IssueStateTransitionRuleCreateRequest) Check() error {
+	if req.ProjectID == 0 {
+		return fmt.Errorf("missing projectID")
+	}
+	if req.IssueType == "" {
+		return fmt.Errorf("missing issueType")
+	}
+	if req.StateTo == 0 {
+		return fmt.Errorf("missing stateTo")
+	}
+	return CheckIssueTransition(req.Guards, req.PostFunctions)
+}
+
+// IssueStateTransitionRuleUpdateRequest 更新状态流转规则的校验和动作
+type IssueStateTransitionRuleUpdateRequest struct {
+	ID            uint64                        `json:"-"`
+	Guards        []IssueTransitionGuard        `json:"guards"`
+	PostFunctions []IssueTransitionPostFunction `json:"postFunctions"`
+
+	IdentityInfo
+}
+
+func (req *IssueStateTransitionRuleUpdateRequest) Check() error {
+	return CheckIssueTransition(req.Guards, req.PostFunctions)
+}
+
+// IssueStateTransitionRuleListRequest 查询项目的状态流转规则
+type IssueStateTransitionRuleListRequest struct {
+	ProjectID uint64 `schema:"projectID"`
+	IssueType string `schema:"issueType"`
+}
+
+// CheckIssueTransition 校验前置校验和后置动作的配置
+func CheckIssueTransition(guards []IssueTransitionGuard, postFunctions []IssueTransitionPostFunction) error {
+	for _, g := range guards {
+		switch g.Type {
+		case IssueTransitionGuardRequiredFields:
+			if len(g.Fields) == 0 {
+				return fmt.Errorf("guard %s: missing fields", g.Type)
+			}
+		case IssueTransitionGuardRoles:
+			if len(g.Roles) == 0 {
+				return fmt.Errorf("guard %s: missing roles", g.Type)
+			}
+		case IssueTransitionGuardMRMerged, IssueTransitionGuardSubtasksDone:
+		default:
+			return fmt.Errorf("invalid guard type: %s", g.Type)
+		}
+	}
+	for _, f := range postFunctions {
+		switch f.Type {
+		case IssueTransitionPostSetField:
+			if err := checkIssueTransitionSetField(f.Field, f.Value); err != nil {
+				return fmt.Errorf("post function %s: %v", f.Type, err)
+			}
+		case IssueTransitionPostCreateLinkedIssue:
+			if f.IssueType == "" {
+				return fmt.Errorf("post function %s: missing issueType", f.Type)
+			}
+		case IssueTransitionPostTriggerPipeline:
+			if f.PipelineDefinitionID == "" {
+				return fmt.Errorf("post function %s: missing pipelineDefinitionID", f.Type)
+			}
+		case IssueTransitionPostAssignToCreator:
+		default:
+			return fmt.Errorf("invalid post function type: %s", f.Type)
+		}
+	}
+	return nil
+}
+
+// checkIssueTransitionSetField 校验 SET_FIELD 的字段和值, 迭代和人员是否属于项目由调用方校验
+func checkIssueTransitionSetField(field, value string) error {
+	if field == "" {
+		return fmt.Errorf("missing field")
+	}
+	if !IssueTransitionSettableFields[field] {
+		return fmt.Errorf("field %s can not be set", field)
+	}
+	if value == "" {
+		return fmt.Errorf("missing value of field %s", field)
+	}
+	var valid bool
+	switch field {
+	case "priority":
+		for _, v := range IssuePriorityList {
+			valid = valid || string(v) == value
+		}
+	case "complexity":
+		for _, v := range []IssueComplexity{IssueComplexityHard, IssueComplexityNormal, IssueComplexityEasy} {
+			valid = valid || string(v) == value
+		}
+	case "severity":
+		for _, v := range []IssueSeverity{IssueSeverityFatal, IssueSeveritySerious, IssueSeverityNormal, IssueSeveritySlight, IssueSeverityLow} {
+			valid = valid || string(v) == value
+		}
+	case "iteration":
+		// -1 表示移出迭代
+		id, err := strconv.ParseInt(value, 10, 64)
+		valid = err == nil && (id > 0 || id == -1)
+	default:
+		valid = true
+	}
+	if !valid {
+		return fmt.Errorf("invalid value %s of field %s", value, field)
+	}
+	return nil
+}
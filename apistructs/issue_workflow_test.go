@@ -0,0 +1,35 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckIssueTransitionSetField(t *testing.T) {
+	setField := func(field, value string) []IssueTransitionPostFunction {
+		return []IssueTransitionPostFunction{{Type: IssueTransitionPostSetField, Field: field, Value: value}}
+	}
+	assert.NoError(t, CheckIssueTransition(nil, setField("priority", "HIGH")))
+	assert.NoError(t, CheckIssueTransition(nil, setField("severity", "SUGGEST")))
+	assert.NoError(t, CheckIssueTransition(nil, setField("iteration", "-1")))
+	assert.NoError(t, CheckIssueTransition(nil, setField("assignee", "2")))
+	assert.Error(t, CheckIssueTransition(nil, setField("title", "x")))
+	assert.Error(t, CheckIssueTransition(nil, setField("priority", "VERY_HIGH")))
+	assert.Error(t, CheckIssueTransition(nil, setField("complexity", "")))
+	assert.Error(t, CheckIssueTransition(nil, setField("iteration", "0")))
+}
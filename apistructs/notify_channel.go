@@ -52,6 +52,19 @@ type NotifyChannelConfig struct {
 	SMTPPort        int64  `json:"smtpPort"`
 	SMTPIsSSL       bool   `json:"smtpIsSsl"`
 	VMSTtsCode      string `json:"vmsTtsCode"`
+	// IM 类渠道(slack, teams, feishu, wecom)配置
+	// Mode 渠道模式: webhook(incoming webhook) 或 bot(bot token)
+	Mode          string `json:"mode"`
+	WebhookURL    string `json:"webhookUrl"`
+	WebhookSecret string `json:"webhookSecret"`
+	BotToken      string `json:"botToken"`
+	// ChannelID bot 模式下的默认会话: slack channel, feishu chat_id, teams conversation id, wecom touser
+	ChannelID  string `json:"channelId"`
+	AppID      string `json:"appId"`
+	TenantID   string `json:"tenantId"`
+	ServiceURL string `json:"serviceUrl"`
+	CorpID     string `json:"corpId"`
+	CorpSecret string `json:"corpSecret"`
 }
 
 type NotifyChannelType string
@@ -62,3 +75,15 @@ const NOTIFY_CHANNEL_PROVIDER_TYPE_ALIYUN = NotifyChannelProviderType("aliyun_sm
 const NOTIFY_CHANNEL_TYPE_DINGTALK_WORK_NOTICE = NotifyChannelType("dingtalk_work_notice")
 const NOTIFY_CHANNEL_TYPE_EMAIL = NotifyChannelType("email")
 const NOTIFY_CHANNEL_TYPE_VMS = NotifyChannelType("vms")
+const NOTIFY_CHANNEL_TYPE_SLACK = NotifyChannelType("slack")
+const NOTIFY_CHANNEL_TYPE_TEAMS = NotifyChannelType("teams")
+const NOTIFY_CHANNEL_TYPE_FEISHU = NotifyChannelType("feishu")
+const NOTIFY_CHANNEL_TYPE_WECOM = NotifyChannelType("wecom")
+const NOTIFY_CHANNEL_PROVIDER_TYPE_SLACK = NotifyChannelProviderType("slack")
+const NOTIFY_CHANNEL_PROVIDER_TYPE_TEAMS = NotifyChannelProviderType("teams")
+const NOTIFY_CHANNEL_PROVIDER_TYPE_FEISHU = NotifyChannelProviderType("feishu")
+const NOTIFY_CHANNEL_PROVIDER_TYPE_WECOM = NotifyChannelProviderType("wecom")
+
+// IM 类渠道模式
+const NOTIFY_CHANNEL_MODE_WEBHOOK = "webhook"
+const NOTIFY_CHANNEL_MODE_BOT = "bot"
@@ -0,0 +1,86 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+// NotifyPreferenceChannel 用户可以设置偏好的个人通知方式
+type NotifyPreferenceChannel string
+
+const (
+	NotifyPreferenceChannelMBox  NotifyPreferenceChannel = "mbox"
+	NotifyPreferenceChannelEmail NotifyPreferenceChannel = "email"
+	// NotifyPreferenceChannelIM 发送给个人的 IM 通知，如钉钉工作通知
+	NotifyPreferenceChannelIM NotifyPreferenceChannel = "im"
+)
+
+// NotifyFrequency 通知发送频率
+type NotifyFrequency string
+
+const (
+	NotifyFrequencyRealtime NotifyFrequency = "realtime"
+	// NotifyFrequencyDaily 每天在 DigestHour 汇总发送
+	NotifyFrequencyDaily NotifyFrequency = "daily"
+	// NotifyFrequencyWeekly 每周 DigestWeekday 的 DigestHour 汇总发送
+	NotifyFrequencyWeekly NotifyFrequency = "weekly"
+)
+
+// NotifyCategoryAll 匹配所有事件类别的偏好
+const NotifyCategoryAll = "*"
+
+// NotifyPreference 用户对某类事件在某个通知方式上的偏好
+type NotifyPreference struct {
+	// Category 事件类别，即通知项的 category，* 表示所有类别
+	Category  string                  `json:"category"`
+	Channel   NotifyPreferenceChannel `json:"channel"`
+	Enabled   bool                    `json:"enabled"`
+	Frequency NotifyFrequency         `json:"frequency"`
+}
+
+// NotifyQuietHours 免打扰时段，期间的通知延迟到时段结束后汇总发送
+type NotifyQuietHours struct {
+	Enabled bool `json:"enabled"`
+	// Start 开始时间，格式为 HH:MM，可以晚于 End 表示跨天
+	Start string `json:"start"`
+	// End 结束时间，格式为 HH:MM
+	End string `json:"end"`
+}
+
+// NotifyPreferenceSettings 用户在 org 下的通知偏好
+type NotifyPreferenceSettings struct {
+	UserID string `json:"userId"`
+	OrgID  int64  `json:"orgId"`
+	// Timezone IANA 时区，如 Asia/Shanghai，免打扰时段及摘要发送时间按该时区计算
+	Timezone   string           `json:"timezone"`
+	QuietHours NotifyQuietHours `json:"quietHours"`
+	// DigestHour 摘要发送的整点，0-23
+	DigestHour int `json:"digestHour"`
+	// DigestWeekday 周摘要发送的星期，0 表示周日
+	DigestWeekday int                `json:"digestWeekday"`
+	Preferences   []NotifyPreference `json:"preferences"`
+}
+
+// NotifyPreferenceGetResponse 查询通知偏好响应
+type NotifyPreferenceGetResponse struct {
+	Header
+	Data NotifyPreferenceSettings `json:"data"`
+}
+
+// NotifyPreferenceUpdateRequest 更新通知偏好请求，Preferences 整体覆盖
+type NotifyPreferenceUpdateRequest struct {
+	Timezone      string             `json:"timezone"`
+	QuietHours    NotifyQuietHours   `json:"quietHours"`
+	DigestHour    int                `json:"digestHour"`
+	DigestWeekday int                `json:"digestWeekday"`
+	Preferences   []NotifyPreference `json:"preferences"`
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import "time"
+
+// PreviewEnvironmentStatus status of a preview (ephemeral) environment
+type PreviewEnvironmentStatus string
+
+const (
+	PreviewEnvironmentStatusCreating     PreviewEnvironmentStatus = "CREATING"
+	PreviewEnvironmentStatusRunning      PreviewEnvironmentStatus = "RUNNING"
+	PreviewEnvironmentStatusTearingDown  PreviewEnvironmentStatus = "TEARING_DOWN"
+	PreviewEnvironmentStatusDestroyed    PreviewEnvironmentStatus = "DESTROYED"
+	PreviewEnvironmentStatusCreateFailed PreviewEnvironmentStatus = "CREATE_FAILED"
+)
+
+// PreviewAddonPolicy decides how addons declared in dice.yml are provided to a preview environment
+type PreviewAddonPolicy string
+
+const (
+	// PreviewAddonPolicyShare reuse the addon instance of the base workspace
+	PreviewAddonPolicyShare PreviewAddonPolicy = "SHARE"
+	// PreviewAddonPolicyClone create a dedicated addon instance which is removed with the preview environment
+	PreviewAddonPolicyClone PreviewAddonPolicy = "CLONE"
+)
+
+// the keys of runtime extra params, used to mark a runtime as preview runtime
+const (
+	// PreviewMergeIDParamKey the merge request which the preview runtime belongs to
+	PreviewMergeIDParamKey = "PREVIEW_MERGE_ID"
+	// PreviewCloneAddonsParamKey comma separated addon names which should be cloned for the preview runtime
+	PreviewCloneAddonsParamKey = "PREVIEW_CLONE_ADDONS"
+)
+
+// PreviewTeardownReason why a preview environment is torn down
+type PreviewTeardownReason string
+
+const (
+	PreviewTeardownReasonMerged  PreviewTeardownReason = "MR_MERGED"
+	PreviewTeardownReasonClosed  PreviewTeardownReason = "MR_CLOSED"
+	PreviewTeardownReasonExpired PreviewTeardownReason = "EXPIRED"
+	PreviewTeardownReasonManual  PreviewTeardownReason = "MANUAL"
+)
+
+// PreviewEnvironmentCreateRequest POST /api/preview-environments
+type PreviewEnvironmentCreateRequest struct {
+	// ReleaseID the release built from the merge request source branch
+	ReleaseID     string `json:"releaseId"`
+	ProjectID     uint64 `json:"projectId"`
+	ApplicationID uint64 `json:"applicationId"`
+	// MergeID the repo scoped merge request id (mergeId in gittar)
+	MergeID int64 `json:"mergeId"`
+	// BaseWorkspace the workspace which provides cluster, configs and shared addons, default DEV
+	BaseWorkspace string `json:"baseWorkspace"`
+	// TTL seconds to live, the environment will be destroyed after that, default 72h
+	TTL int64 `json:"ttl"`
+	// AddonPolicies policy of each addon by addon name, addons not listed use DefaultAddonPolicy
+	AddonPolicies      map[string]PreviewAddonPolicy `json:"addonPolicies"`
+	DefaultAddonPolicy PreviewAddonPolicy            `json:"defaultAddonPolicy"`
+	Operator           string                        `json:"-"`
+}
+
+// PreviewEnvironmentListRequest GET /api/preview-environments
+type PreviewEnvironmentListRequest struct {
+	ApplicationID uint64 `schema:"applicationId"`
+	// Status optional, filter by status
+	Status string `schema:"status"`
+}
+
+// PreviewEnvironmentDTO preview environment detail
+type PreviewEnvironmentDTO struct {
+	ID             uint64                        `json:"id"`
+	ProjectID      uint64                        `json:"projectId"`
+	ApplicationID  uint64                        `json:"applicationId"`
+	MergeID        int64                         `json:"mergeId"`
+	SourceBranch   string                        `json:"sourceBranch"`
+	RuntimeID      uint64                        `json:"runtimeId"`
+	RuntimeName    string                        `json:"runtimeName"`
+	BaseWorkspace  string                        `json:"baseWorkspace"`
+	Namespace      string                        `json:"namespace"`
+	ClusterName    string                        `json:"clusterName"`
+	ReleaseID      string                        `json:"releaseId"`
+	Status         PreviewEnvironmentStatus      `json:"status"`
+	AddonPolicies  map[string]PreviewAddonPolicy `json:"addonPolicies"`
+	TeardownReason PreviewTeardownReason         `json:"teardownReason,omitempty"`
+	Creator        string                        `json:"creator"`
+	ExpiredAt      time.Time                     `json:"expiredAt"`
+	CreatedAt      time.Time                     `json:"createdAt"`
+	UpdatedAt      time.Time                     `json:"updatedAt"`
+}
+
+// PreviewEnvironmentResponse response of a single preview environment
+type PreviewEnvironmentResponse struct {
+	Header
+	Data *PreviewEnvironmentDTO `json:"data"`
+}
+
+// PreviewEnvironmentListResponse response of preview environment list
+type PreviewEnvironmentListResponse struct {
+	Header
+	Data []*PreviewEnvironmentDTO `json:"data"`
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import "time"
+
+// FlakyTest 单元测试用例稳定性统计
+type FlakyTest struct {
+	ID               uint64    `json:"id"`
+	ProjectID        int64     `json:"projectID"`
+	ApplicationID    int64     `json:"applicationID"`
+	Suite            string    `json:"suite"`
+	Classname        string    `json:"classname"`
+	Name             string    `json:"name"`
+	TotalRuns        int64     `json:"totalRuns"`
+	FailedRuns       int64     `json:"failedRuns"`
+	FailureRate      float64   `json:"failureRate"`
+	Flips            int64     `json:"flips"`
+	CommitFlips      int64     `json:"commitFlips"`
+	Flakiness        float64   `json:"flakiness"`
+	IsFlaky          bool      `json:"isFlaky"`
+	LastStatus       string    `json:"lastStatus"`
+	LastCommitID     string    `json:"lastCommitID"`
+	LastRecordID     uint64    `json:"lastRecordID"`
+	LastRunAt        time.Time `json:"lastRunAt"`
+	Quarantined      bool      `json:"quarantined"`
+	QuarantinedBy    string    `json:"quarantinedBy"`
+	QuarantineReason string    `json:"quarantineReason"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// FlakyTestListRequest 查询应用下的不稳定用例
+type FlakyTestListRequest struct {
+	ApplicationID int64 `json:"-" schema:"-"`
+	// OnlyFlaky 为 true 时只返回判定为不稳定的用例
+	OnlyFlaky   bool   `json:"onlyFlaky" schema:"onlyFlaky"`
+	Quarantined *bool  `json:"quarantined" schema:"quarantined"`
+	Name        string `json:"name" schema:"name"`
+	// OrderBy 支持 flakiness, failureRate, lastRunAt, 默认 flakiness
+	OrderBy  string `json:"orderBy" schema:"orderBy"`
+	PageNo   int    `json:"pageNo" schema:"pageNo"`
+	PageSize int    `json:"pageSize" schema:"pageSize"`
+}
+
+type FlakyTestListData struct {
+	Total int64        `json:"total"`
+	List  []*FlakyTest `json:"list"`
+}
+
+// FlakyTestQuarantineRequest 隔离或取消隔离不稳定用例
+type FlakyTestQuarantineRequest struct {
+	Quarantined bool   `json:"quarantined"`
+	Reason      string `json:"reason"`
+
+	IdentityInfo
+}
+
+// FlakyTestTrendRequest 查询不稳定用例历史趋势, TestID 为空时按应用聚合
+type FlakyTestTrendRequest struct {
+	ApplicationID int64  `json:"-" schema:"-"`
+	TestID        uint64 `json:"testID" schema:"testID"`
+	Days          int    `json:"days" schema:"days"`
+}
+
+type FlakyTestTrendPoint struct {
+	Day         string  `json:"day"`
+	Runs        int64   `json:"runs"`
+	FailedRuns  int64   `json:"failedRuns"`
+	FailureRate float64 `json:"failureRate"`
+	Flips       int64   `json:"flips"`
+	CommitFlips int64   `json:"commitFlips"`
+	// FlakyTests 当日出现结果翻转的用例数
+	FlakyTests int64 `json:"flakyTests"`
+}
+
+// TestReportGateResult 单元测试报告门禁结果, 已隔离用例的失败不计入门禁
+type TestReportGateResult struct {
+	RecordID          uint64   `json:"recordID"`
+	Passed            bool     `json:"passed"`
+	Failed            []string `json:"failed"`
+	QuarantinedFailed []string `json:"quarantinedFailed"`
+}
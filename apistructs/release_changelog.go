@@ -0,0 +1,79 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+// ReleaseChangelogGenerateRequest 生成项目制品 changelog 请求
+type ReleaseChangelogGenerateRequest struct {
+	ReleaseID string `json:"-"`
+	UserID    string `json:"-"`
+	// PreviousReleaseID 对比的上一个项目制品，为空时取同项目中在该制品之前创建的最近一个项目制品
+	PreviousReleaseID string `json:"previousReleaseId"`
+	// Save 为 true 时将生成的 changelog 保存到制品
+	Save bool `json:"save"`
+}
+
+// ReleaseVersionSuggestRequest 创建项目制品前的版本号建议请求
+type ReleaseVersionSuggestRequest struct {
+	UserID    string `json:"-"`
+	ProjectID int64  `json:"projectId"`
+	// ApplicationReleaseList 即将组成项目制品的应用制品
+	ApplicationReleaseList []string `json:"applicationReleaseList"`
+	// PreviousReleaseID 对比的上一个项目制品，为空时取同项目中最近一个项目制品
+	PreviousReleaseID string `json:"previousReleaseId"`
+}
+
+// ReleaseChangelog 项目制品 changelog
+type ReleaseChangelog struct {
+	ReleaseID         string                    `json:"releaseId,omitempty"`
+	PreviousReleaseID string                    `json:"previousReleaseId"`
+	PreviousVersion   string                    `json:"previousVersion"`
+	SuggestedVersion  string                    `json:"suggestedVersion"`
+	Bump              string                    `json:"bump"`
+	Sections          []ReleaseChangelogSection `json:"sections"`
+	Markdown          string                    `json:"markdown"`
+	// Warnings 无法对比的应用等提示信息
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ReleaseChangelogSection 按提交类型分组的 changelog
+type ReleaseChangelogSection struct {
+	Type    string                  `json:"type"`
+	Title   string                  `json:"title"`
+	Entries []ReleaseChangelogEntry `json:"entries"`
+}
+
+// ReleaseChangelogEntry 一条 changelog，对应一个 conventional commit
+type ReleaseChangelogEntry struct {
+	ApplicationID   int64                   `json:"applicationId"`
+	ApplicationName string                  `json:"applicationName"`
+	CommitID        string                  `json:"commitId"`
+	Scope           string                  `json:"scope"`
+	Subject         string                  `json:"subject"`
+	Breaking        bool                    `json:"breaking"`
+	Issues          []ReleaseChangelogIssue `json:"issues,omitempty"`
+}
+
+// ReleaseChangelogIssue changelog 关联的事项
+type ReleaseChangelogIssue struct {
+	ID    uint64 `json:"id"`
+	Title string `json:"title"`
+	Type  string `json:"type"`
+}
+
+// ReleaseChangelogResponse changelog 响应
+type ReleaseChangelogResponse struct {
+	Header
+	Data ReleaseChangelog `json:"data"`
+}
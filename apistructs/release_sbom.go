@@ -0,0 +1,177 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// SBOMFormat 软件物料清单格式
+type SBOMFormat string
+
+const (
+	SBOMFormatSPDX      SBOMFormat = "spdx"
+	SBOMFormatCycloneDX SBOMFormat = "cyclonedx"
+)
+
+// VulnerabilitySeverity 漏洞等级
+type VulnerabilitySeverity string
+
+const (
+	VulnerabilitySeverityCritical VulnerabilitySeverity = "CRITICAL"
+	VulnerabilitySeverityHigh     VulnerabilitySeverity = "HIGH"
+	VulnerabilitySeverityMedium   VulnerabilitySeverity = "MEDIUM"
+	VulnerabilitySeverityLow      VulnerabilitySeverity = "LOW"
+	VulnerabilitySeverityUnknown  VulnerabilitySeverity = "UNKNOWN"
+)
+
+// VulnerabilitySeverities 按严重程度从高到低排列的漏洞等级
+var VulnerabilitySeverities = []VulnerabilitySeverity{
+	VulnerabilitySeverityCritical,
+	VulnerabilitySeverityHigh,
+	VulnerabilitySeverityMedium,
+	VulnerabilitySeverityLow,
+	VulnerabilitySeverityUnknown,
+}
+
+// NormalizeVulnerabilitySeverity 规范化漏洞等级，无法识别的等级归为 UNKNOWN
+func NormalizeVulnerabilitySeverity(s string) VulnerabilitySeverity {
+	severity := VulnerabilitySeverity(strings.ToUpper(strings.TrimSpace(s)))
+	if severity == "MODERATE" {
+		return VulnerabilitySeverityMedium
+	}
+	for _, known := range VulnerabilitySeverities {
+		if severity == known {
+			return severity
+		}
+	}
+	return VulnerabilitySeverityUnknown
+}
+
+// ReleaseSBOMIngestRequest 上传制品镜像 SBOM 请求
+type ReleaseSBOMIngestRequest struct {
+	ReleaseID string `json:"-"`
+	// Image 镜像地址，必须属于该制品
+	Image string `json:"image"`
+	// Format 为空时根据内容自动识别
+	Format SBOMFormat `json:"format"`
+	// Content SPDX 或 CycloneDX JSON 文档
+	Content json.RawMessage `json:"content"`
+}
+
+// ReleaseSBOM 制品镜像 SBOM 概要
+type ReleaseSBOM struct {
+	ID         uint64     `json:"id"`
+	ReleaseID  string     `json:"releaseId"`
+	Image      string     `json:"image"`
+	Format     SBOMFormat `json:"format"`
+	Source     string     `json:"source"`
+	Components int        `json:"components"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+// ReleaseSBOMListResponse 制品 SBOM 列表响应
+type ReleaseSBOMListResponse struct {
+	Header
+	Data []ReleaseSBOM `json:"data"`
+}
+
+// VulnerabilityAdvisory 离线漏洞库条目，受影响版本区间为 [introduced, fixed)
+type VulnerabilityAdvisory struct {
+	VulnID     string                `json:"vulnId"`
+	Ecosystem  string                `json:"ecosystem"`
+	Package    string                `json:"package"`
+	Introduced string                `json:"introduced"`
+	Fixed      string                `json:"fixed"`
+	Severity   VulnerabilitySeverity `json:"severity"`
+	Summary    string                `json:"summary"`
+}
+
+// VulnerabilityAdvisoryImportRequest 导入离线漏洞库请求，相同 vulnId+package 的条目会被覆盖
+type VulnerabilityAdvisoryImportRequest struct {
+	Advisories []VulnerabilityAdvisory `json:"advisories"`
+}
+
+// ReleaseVulnerability 制品漏洞扫描结果
+type ReleaseVulnerability struct {
+	ReleaseID    string                `json:"releaseId"`
+	Image        string                `json:"image"`
+	VulnID       string                `json:"vulnId"`
+	Package      string                `json:"package"`
+	Version      string                `json:"version"`
+	FixedVersion string                `json:"fixedVersion"`
+	Severity     VulnerabilitySeverity `json:"severity"`
+	Summary      string                `json:"summary"`
+}
+
+// ReleaseVulnerabilityListData 制品漏洞列表
+type ReleaseVulnerabilityListData struct {
+	ReleaseID string                          `json:"releaseId"`
+	Summary   map[VulnerabilitySeverity]int   `json:"summary"`
+	List      []ReleaseVulnerability          `json:"list"`
+	ScannedAt *time.Time                      `json:"scannedAt,omitempty"`
+	Images    map[string]ReleaseImageScanInfo `json:"images,omitempty"`
+}
+
+// ReleaseImageScanInfo 单个镜像的扫描情况
+type ReleaseImageScanInfo struct {
+	HasSBOM bool `json:"hasSBOM"`
+}
+
+// ReleaseVulnerabilityListResponse 制品漏洞列表响应
+type ReleaseVulnerabilityListResponse struct {
+	Header
+	Data ReleaseVulnerabilityListData `json:"data"`
+}
+
+// ReleaseVulnerabilityGate 漏洞门禁配置
+type ReleaseVulnerabilityGate struct {
+	ProjectID uint64 `json:"projectId"`
+	Workspace string `json:"workspace"`
+	Enabled   bool   `json:"enabled"`
+	// Thresholds 各等级允许的最大漏洞数，未配置的等级不限制，例如 {"CRITICAL": 0} 表示不允许存在严重漏洞
+	Thresholds map[VulnerabilitySeverity]int `json:"thresholds"`
+	// RequireSBOM 为 true 时，缺少 SBOM 的镜像同样视为不通过
+	RequireSBOM bool `json:"requireSBOM"`
+}
+
+// ReleaseVulnerabilityGateResponse 漏洞门禁配置响应
+type ReleaseVulnerabilityGateResponse struct {
+	Header
+	Data ReleaseVulnerabilityGate `json:"data"`
+}
+
+// ReleaseVulnerabilityGateCheckRequest 漏洞门禁检查请求
+type ReleaseVulnerabilityGateCheckRequest struct {
+	ReleaseID string `json:"releaseId"`
+	Workspace string `json:"workspace"`
+}
+
+// ReleaseVulnerabilityGateCheckResult 漏洞门禁检查结果
+type ReleaseVulnerabilityGateCheckResult struct {
+	Passed     bool                          `json:"passed"`
+	Enabled    bool                          `json:"enabled"`
+	Summary    map[VulnerabilitySeverity]int `json:"summary"`
+	Violations []string                      `json:"violations"`
+}
+
+// ReleaseVulnerabilityGateCheckResponse 漏洞门禁检查响应
+type ReleaseVulnerabilityGateCheckResponse struct {
+	Header
+	Data ReleaseVulnerabilityGateCheckResult `json:"data"`
+}
@@ -16,6 +16,8 @@ package apistructs
 
 import (
 	"time"
+
+	"github.com/erda-project/erda/pkg/parser/diceyml"
 )
 
 type RuntimeInspectDTO struct {
@@ -65,6 +67,8 @@ type RuntimeInspectServiceDTO struct {
 	Addrs       []string                     `json:"addrs"` // TODO: better name?
 	Expose      []string                     `json:"expose"`
 	Errors      []ErrorResponse              `json:"errors"`
+	// Autoscale 服务在 dice.yml 中声明的弹性伸缩规则
+	Autoscale *diceyml.Autoscale `json:"autoscale,omitempty"`
 }
 
 type RuntimeSummaryDTO struct {
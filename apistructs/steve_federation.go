@@ -0,0 +1,98 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+// FederatedBulkAction is the action type of a multi-cluster bulk operation
+type FederatedBulkAction string
+
+const (
+	FederatedActionRestartWorkload FederatedBulkAction = "restartWorkload"
+	FederatedActionCordonNode      FederatedBulkAction = "cordonNode"
+	FederatedActionUncordonNode    FederatedBulkAction = "uncordonNode"
+	FederatedActionDeletePod       FederatedBulkAction = "deletePod"
+)
+
+// FederatedSearchRequest searches k8s resources across all clusters of an org
+type FederatedSearchRequest struct {
+	UserID string `json:"-"`
+	OrgID  string `json:"-"`
+	// Type of resource, required, e.g. pods, apps.deployments, nodes
+	Type K8SResType `json:"type"`
+	// ClusterNames limits the clusters to search, all clusters of the org are searched if empty
+	ClusterNames  []string `json:"clusterNames"`
+	Namespace     string   `json:"namespace"`
+	LabelSelector []string `json:"labelSelector"`
+	FieldSelector []string `json:"fieldSelector"`
+	// NameRegex filters resources by name with regular expression
+	NameRegex string `json:"nameRegex"`
+	PageNo    int    `json:"pageNo"`
+	PageSize  int    `json:"pageSize"`
+}
+
+type FederatedSearchResponse struct {
+	Header
+	Data FederatedSearchData `json:"data"`
+}
+
+type FederatedSearchData struct {
+	Total int                 `json:"total"`
+	List  []FederatedResource `json:"list"`
+	// Errors are the clusters which failed to search, key is cluster name
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// FederatedResource is a k8s resource with the cluster it belongs to
+type FederatedResource struct {
+	ClusterName string                 `json:"clusterName"`
+	Type        K8SResType             `json:"type"`
+	Namespace   string                 `json:"namespace"`
+	Name        string                 `json:"name"`
+	Object      map[string]interface{} `json:"object"`
+}
+
+// FederatedBulkRequest runs one action on many resources across clusters
+type FederatedBulkRequest struct {
+	UserID  string              `json:"-"`
+	OrgID   string              `json:"-"`
+	Action  FederatedBulkAction `json:"action"`
+	Targets []FederatedTarget   `json:"targets"`
+}
+
+// FederatedTarget is the target resource of a bulk action.
+// Type is required for restartWorkload and must be one of deployment, statefulSet and daemonSet.
+type FederatedTarget struct {
+	ClusterName string     `json:"clusterName"`
+	Type        K8SResType `json:"type,omitempty"`
+	Namespace   string     `json:"namespace,omitempty"`
+	Name        string     `json:"name"`
+}
+
+type FederatedBulkResponse struct {
+	Header
+	Data FederatedBulkData `json:"data"`
+}
+
+type FederatedBulkData struct {
+	Succeeded int                   `json:"succeeded"`
+	Failed    int                   `json:"failed"`
+	Results   []FederatedBulkResult `json:"results"`
+}
+
+// FederatedBulkResult is the result of a bulk action on one target
+type FederatedBulkResult struct {
+	FederatedTarget
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
@@ -44,6 +44,9 @@ type TestPlanV2 struct {
 	ExecuteTime   *time.Time        `json:"executeTime"`
 	SuccessApiNum int64             `json:"successApiNum"`
 	TotalApiNum   int64             `json:"totalApiNum"`
+	Type          TestPlanV2Type    `json:"type"`
+	// LoadConfig only valid when Type is load
+	LoadConfig *TestPlanV2LoadConfig `json:"loadConfig,omitempty"`
 }
 
 // TestPlanV2CreateRequest testplan v2 create request
@@ -54,6 +57,9 @@ type TestPlanV2CreateRequest struct {
 	ProjectID   uint64   `json:"projectID"`
 	SpaceID     uint64   `json:"spaceID"`
 	IterationID uint64   `json:"iterationID"`
+	// +optional default is default type
+	Type       TestPlanV2Type        `json:"type"`
+	LoadConfig *TestPlanV2LoadConfig `json:"loadConfig,omitempty"`
 
 	IdentityInfo
 }
@@ -76,6 +82,20 @@ func (tp *TestPlanV2CreateRequest) Check() error {
 	if tp.IterationID == 0 {
 		return errors.New("iterationID is empty")
 	}
+	if tp.Type == "" {
+		tp.Type = TestPlanV2TypeDefault
+	}
+	if !tp.Type.Valid() {
+		return errors.Errorf("invalid type: %s", tp.Type)
+	}
+	if tp.Type == TestPlanV2TypeLoad {
+		if tp.LoadConfig == nil {
+			return errors.New("loadConfig is empty")
+		}
+		if err := tp.LoadConfig.Check(); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -95,6 +115,8 @@ type TestPlanV2UpdateRequest struct {
 	TestPlanID  uint64   `json:"-"`
 	IsArchived  *bool    `json:"isArchived"`
 	IterationID uint64   `json:"iterationID"`
+	// LoadConfig update the load config of load type testplan, nil means not change
+	LoadConfig *TestPlanV2LoadConfig `json:"loadConfig,omitempty"`
 
 	IdentityInfo
 }
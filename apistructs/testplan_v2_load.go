@@ -0,0 +1,206 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import (
+	"github.com/pkg/errors"
+)
+
+// TestPlanV2Type testplan v2 type
+type TestPlanV2Type string
+
+const (
+	// TestPlanV2TypeDefault execute each scene once
+	TestPlanV2TypeDefault TestPlanV2Type = "default"
+	// TestPlanV2TypeLoad execute one scene repeatedly by virtual users
+	TestPlanV2TypeLoad TestPlanV2Type = "load"
+)
+
+// Valid check the type is valid
+func (t TestPlanV2Type) Valid() bool {
+	switch t {
+	case TestPlanV2TypeDefault, TestPlanV2TypeLoad:
+		return true
+	default:
+		return false
+	}
+}
+
+const (
+	// LoadTestMaxWorkers is the max worker actions of a load testplan
+	LoadTestMaxWorkers = 20
+	// LoadTestMaxVirtualUsersPerWorker is the max virtual users run by one worker
+	LoadTestMaxVirtualUsersPerWorker = 500
+	// LoadTestMaxDurationSec is the max duration of a load testplan, ramp up is not included
+	LoadTestMaxDurationSec = 3600
+
+	// AutotestLoadTest is the AutotestType label value of load test actions
+	AutotestLoadTest = "LOADTEST"
+)
+
+// TestPlanV2LoadConfig load config of the load type testplan
+type TestPlanV2LoadConfig struct {
+	// SceneID the scene executed by virtual users
+	SceneID uint64 `json:"sceneID"`
+	// VirtualUsers total concurrent virtual users, each virtual user executes the scene in loop
+	VirtualUsers int `json:"virtualUsers"`
+	// RampUpSec virtual users are started evenly in ramp up duration
+	RampUpSec int `json:"rampUpSec"`
+	// DurationSec duration after ramp up
+	DurationSec int `json:"durationSec"`
+	// ThinkTimeMs the pause between two iterations of one virtual user
+	ThinkTimeMs int `json:"thinkTimeMs"`
+	// Workers the virtual users are split to workers
+	// +optional default 1
+	Workers    int                 `json:"workers"`
+	Thresholds []LoadTestThreshold `json:"thresholds"`
+}
+
+// Check check load config is valid
+func (c *TestPlanV2LoadConfig) Check() error {
+	if c.SceneID == 0 {
+		return errors.New("loadConfig.sceneID is empty")
+	}
+	if c.Workers == 0 {
+		c.Workers = 1
+	}
+	if c.Workers < 0 || c.Workers > LoadTestMaxWorkers {
+		return errors.Errorf("loadConfig.workers must be between 1 and %d", LoadTestMaxWorkers)
+	}
+	if c.VirtualUsers < c.Workers {
+		return errors.New("loadConfig.virtualUsers must not be less than workers")
+	}
+	if c.VirtualUsers > c.Workers*LoadTestMaxVirtualUsersPerWorker {
+		return errors.Errorf("loadConfig.virtualUsers must not be greater than %d per worker", LoadTestMaxVirtualUsersPerWorker)
+	}
+	if c.DurationSec <= 0 || c.DurationSec > LoadTestMaxDurationSec {
+		return errors.Errorf("loadConfig.durationSec must be between 1 and %d", LoadTestMaxDurationSec)
+	}
+	if c.RampUpSec < 0 || c.RampUpSec > c.DurationSec {
+		return errors.New("loadConfig.rampUpSec must be between 0 and durationSec")
+	}
+	if c.ThinkTimeMs < 0 {
+		return errors.New("loadConfig.thinkTimeMs must not be negative")
+	}
+	for _, threshold := range c.Thresholds {
+		if err := threshold.Check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WorkerVirtualUsers return the virtual users of the worker, the remainder is assigned to the first workers
+func (c *TestPlanV2LoadConfig) WorkerVirtualUsers(worker int) int {
+	vus := c.VirtualUsers / c.Workers
+	if worker < c.VirtualUsers%c.Workers {
+		vus++
+	}
+	return vus
+}
+
+// LoadTestMetric the aggregated metric of load test
+type LoadTestMetric string
+
+const (
+	LoadTestMetricP50 LoadTestMetric = "p50" // latency in milliseconds
+	LoadTestMetricP90 LoadTestMetric = "p90"
+	LoadTestMetricP95 LoadTestMetric = "p95"
+	LoadTestMetricP99 LoadTestMetric = "p99"
+	LoadTestMetricAvg LoadTestMetric = "avg"
+	LoadTestMetricMax LoadTestMetric = "max"
+	// LoadTestMetricRPS requests per second
+	LoadTestMetricRPS LoadTestMetric = "rps"
+	// LoadTestMetricErrorRate percentage of failed requests, invoke error and assert failure are both failed
+	LoadTestMetricErrorRate LoadTestMetric = "error_rate"
+)
+
+// Valid check the metric is valid
+func (m LoadTestMetric) Valid() bool {
+	switch m {
+	case LoadTestMetricP50, LoadTestMetricP90, LoadTestMetricP95, LoadTestMetricP99,
+		LoadTestMetricAvg, LoadTestMetricMax, LoadTestMetricRPS, LoadTestMetricErrorRate:
+		return true
+	default:
+		return false
+	}
+}
+
+// LoadTestThreshold the plan fails if any threshold is not satisfied, eg: p95 < 500
+type LoadTestThreshold struct {
+	Metric LoadTestMetric `json:"metric"`
+	// Operator one of < <= > >=
+	Operator string  `json:"operator"`
+	Value    float64 `json:"value"`
+}
+
+// Check check threshold is valid
+func (t LoadTestThreshold) Check() error {
+	if !t.Metric.Valid() {
+		return errors.Errorf("invalid threshold metric: %s", t.Metric)
+	}
+	switch t.Operator {
+	case "<", "<=", ">", ">=":
+	default:
+		return errors.Errorf("invalid threshold operator: %s", t.Operator)
+	}
+	return nil
+}
+
+// Satisfied check the metric value satisfies the threshold
+func (t LoadTestThreshold) Satisfied(value float64) bool {
+	switch t.Operator {
+	case "<":
+		return value < t.Value
+	case "<=":
+		return value <= t.Value
+	case ">":
+		return value > t.Value
+	case ">=":
+		return value >= t.Value
+	default:
+		return false
+	}
+}
+
+// LoadTestWorkerConfig is the load param of a load test worker action
+type LoadTestWorkerConfig struct {
+	ProjectID    uint64 `json:"projectID"`
+	TestPlanID   uint64 `json:"testPlanID"`
+	SceneID      uint64 `json:"sceneID"`
+	Worker       int    `json:"worker"`
+	VirtualUsers int    `json:"virtualUsers"`
+	RampUpSec    int    `json:"rampUpSec"`
+	DurationSec  int    `json:"durationSec"`
+	ThinkTimeMs  int    `json:"thinkTimeMs"`
+}
+
+// LoadTestReportConfig is the load_report param of the load test report action,
+// which aggregates results of all workers and judges thresholds
+type LoadTestReportConfig struct {
+	ProjectID    uint64              `json:"projectID"`
+	TestPlanID   uint64              `json:"testPlanID"`
+	SceneID      uint64              `json:"sceneID"`
+	VirtualUsers int                 `json:"virtualUsers"`
+	Thresholds   []LoadTestThreshold `json:"thresholds"`
+}
+
+// LoadTestStep is one step of the scene executed by load test worker, it is an api step if Spec is set,
+// otherwise a wait step. Alias is used to render the outputs reference of later steps.
+type LoadTestStep struct {
+	Alias   string                 `json:"alias"`
+	Spec    map[string]interface{} `json:"spec,omitempty"`
+	WaitSec int                    `json:"waitSec,omitempty"`
+}
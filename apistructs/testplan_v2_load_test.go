@@ -0,0 +1,68 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apistructs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTestPlanV2LoadConfigCheck(t *testing.T) {
+	valid := func() *TestPlanV2LoadConfig {
+		return &TestPlanV2LoadConfig{
+			SceneID:      1,
+			VirtualUsers: 10,
+			RampUpSec:    10,
+			DurationSec:  60,
+			Thresholds:   []LoadTestThreshold{{Metric: LoadTestMetricP95, Operator: "<", Value: 500}},
+		}
+	}
+	c := valid()
+	assert.NoError(t, c.Check())
+	assert.Equal(t, 1, c.Workers)
+
+	for name, modify := range map[string]func(c *TestPlanV2LoadConfig){
+		"no scene":          func(c *TestPlanV2LoadConfig) { c.SceneID = 0 },
+		"too many workers":  func(c *TestPlanV2LoadConfig) { c.Workers = LoadTestMaxWorkers + 1 },
+		"vus less than one": func(c *TestPlanV2LoadConfig) { c.Workers, c.VirtualUsers = 2, 1 },
+		"too many vus":      func(c *TestPlanV2LoadConfig) { c.VirtualUsers = LoadTestMaxVirtualUsersPerWorker + 1 },
+		"no duration":       func(c *TestPlanV2LoadConfig) { c.DurationSec = 0 },
+		"long ramp up":      func(c *TestPlanV2LoadConfig) { c.RampUpSec = 61 },
+		"negative think":    func(c *TestPlanV2LoadConfig) { c.ThinkTimeMs = -1 },
+		"invalid metric":    func(c *TestPlanV2LoadConfig) { c.Thresholds[0].Metric = "p42" },
+		"invalid operator":  func(c *TestPlanV2LoadConfig) { c.Thresholds[0].Operator = "==" },
+	} {
+		c := valid()
+		modify(c)
+		assert.Error(t, c.Check(), name)
+	}
+}
+
+func TestTestPlanV2LoadConfigWorkerVirtualUsers(t *testing.T) {
+	c := TestPlanV2LoadConfig{VirtualUsers: 10, Workers: 3}
+	assert.Equal(t, 4, c.WorkerVirtualUsers(0))
+	assert.Equal(t, 3, c.WorkerVirtualUsers(1))
+	assert.Equal(t, 3, c.WorkerVirtualUsers(2))
+}
+
+func TestLoadTestThresholdSatisfied(t *testing.T) {
+	assert.True(t, LoadTestThreshold{Operator: "<", Value: 1}.Satisfied(0.5))
+	assert.False(t, LoadTestThreshold{Operator: "<", Value: 1}.Satisfied(1))
+	assert.True(t, LoadTestThreshold{Operator: "<=", Value: 1}.Satisfied(1))
+	assert.True(t, LoadTestThreshold{Operator: ">", Value: 1}.Satisfied(2))
+	assert.True(t, LoadTestThreshold{Operator: ">=", Value: 1}.Satisfied(1))
+	assert.False(t, LoadTestThreshold{Operator: "==", Value: 1}.Satisfied(1))
+}
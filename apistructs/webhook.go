@@ -14,6 +14,8 @@
 
 package apistructs
 
+import "time"
+
 //go:generate go run ../pkg/structparser/comment/comment.go -pkg-name apistructs
 
 // WebhookListResponse webhook 列表
@@ -194,12 +196,73 @@ type Hook struct {
 	UpdatedAt string `json:"updatedAt"`
 	CreatedAt string `json:"createdAt"`
 
-	// 用于计算后续发送的事件内容的sha值，目前没有用
+	// 用于对推送内容进行 HMAC-SHA256 签名，签名放在请求头 X-Erda-Signature-256 中
 	Secret string `json:"secret"`
 
+	// ConsecutiveFailures 连续投递失败(重试耗尽)的次数，投递成功后清零
+	ConsecutiveFailures int `json:"consecutiveFailures,omitempty"`
+	// DisabledReason 因持续投递失败被自动停用的原因
+	DisabledReason string `json:"disabledReason,omitempty"`
+
 	CreateHookRequest
 }
 
+// WebhookDeliveryStatus webhook 投递状态
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending    WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusDelivering WebhookDeliveryStatus = "delivering"
+	WebhookDeliveryStatusRetrying   WebhookDeliveryStatus = "retrying"
+	WebhookDeliveryStatusSucceeded  WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryStatusDead 重试次数耗尽，不再投递，可手动重放
+	WebhookDeliveryStatusDead WebhookDeliveryStatus = "dead"
+)
+
+// WebhookDelivery webhook 投递记录
+type WebhookDelivery struct {
+	ID              uint64                `json:"id"`
+	HookID          string                `json:"hookID"`
+	Event           string                `json:"event"`
+	Action          string                `json:"action"`
+	Status          WebhookDeliveryStatus `json:"status"`
+	Attempts        int                   `json:"attempts"`
+	NextRetryAt     *time.Time            `json:"nextRetryAt,omitempty"`
+	RequestURL      string                `json:"requestURL"`
+	RequestHeaders  map[string]string     `json:"requestHeaders"`
+	RequestBody     string                `json:"requestBody,omitempty"`
+	ResponseStatus  int                   `json:"responseStatus"`
+	ResponseHeaders map[string]string     `json:"responseHeaders,omitempty"`
+	ResponseBody    string                `json:"responseBody,omitempty"`
+	// LatencyMs 最近一次请求耗时，毫秒
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+	// ReplayOf 重放的原投递记录 id
+	ReplayOf  uint64    `json:"replayOf,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// WebhookDeliveryListRequest webhook 投递记录列表
+// BackendPath:  "/api/dice/eventbox/webhooks/<id>/deliveries",
+type WebhookDeliveryListRequest struct {
+	HookID   string                `json:"-" path:"id"`
+	Status   WebhookDeliveryStatus `json:"status" query:"status"`
+	PageNo   int                   `json:"pageNo" query:"pageNo"`
+	PageSize int                   `json:"pageSize" query:"pageSize"`
+}
+
+// WebhookDeliveryListResponseData webhook 投递记录列表，列表中不包含请求体和响应体
+type WebhookDeliveryListResponseData struct {
+	Total int64              `json:"total"`
+	List  []*WebhookDelivery `json:"list"`
+}
+
+// WebhookSecretResponseData 重新生成的 webhook 签名密钥
+type WebhookSecretResponseData struct {
+	Secret string `json:"secret"`
+}
+
 // CreateHookRequest 内部使用的创建 webhook 的请求结构体
 type CreateHookRequest struct {
 	// webhook 名字
@@ -246,6 +246,9 @@ func (b *Bundle) CreateGroupNotifyEvent(groupNotifyRequest apistructs.EventBoxGr
 	notifyItem := groupNotifyRequest.NotifyItem
 	params := groupNotifyRequest.Params
 	eventboxReqContent.Channels = []apistructs.GroupNotifyChannel{}
+	if eventboxReqContent.Category == "" && notifyItem != nil {
+		eventboxReqContent.Category = notifyItem.Category
+	}
 	channels := strings.Split(groupNotifyRequest.Channels, ",")
 	for _, channel := range channels {
 		if channel == "sms" {
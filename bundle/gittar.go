@@ -855,6 +855,35 @@ func (b *Bundle) GetGittarCompare(after, before string, appID int64, userID stri
 	return &compareResponse.Data, nil
 }
 
+// ListGittarCompareCommits lists the commits reachable from after but not from before, paged by skip and limit
+func (b *Bundle) ListGittarCompareCommits(after, before string, appID int64, userID string, skip, limit int) ([]apistructs.Commit, error) {
+	var (
+		host            string
+		err             error
+		compareResponse apistructs.GittarCompareResponse
+	)
+	hc := b.hc
+	host, err = b.urls.Gittar()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := hc.Get(host).
+		Header(httputil.UserHeader, userID).
+		Path(fmt.Sprintf("/app-repo/%d/compare/%s...%s/commits", appID, after, before)).
+		Param("skip", strconv.Itoa(skip)).
+		Param("limit", strconv.Itoa(limit)).
+		Do().JSON(&compareResponse)
+	if err != nil {
+		return nil, apierrors.ErrInvoke.InternalError(err)
+	}
+	if !resp.IsOK() || !compareResponse.Success {
+		return nil, toAPIError(resp.StatusCode(), compareResponse.Error)
+	}
+
+	return compareResponse.Data.Commits, nil
+}
+
 func (b *Bundle) MergeRequestCount(userID string, req apistructs.MergeRequestCountRequest) (map[string]int, error) {
 	var (
 		host string
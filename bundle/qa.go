@@ -69,3 +69,29 @@ func (b *Bundle) InternalRemoveTestPlanCaseRelIssueRelationsByIssueID(issueID ui
 
 	return nil
 }
+
+// GetTestReportGateByUUID 按单元测试 action 生成的 uuid 获取测试报告门禁结果
+func (b *Bundle) GetTestReportGateByUUID(uuid string) (*apistructs.TestReportGateResult, error) {
+	host, err := b.urls.DOP()
+	if err != nil {
+		return nil, err
+	}
+	hc := b.hc
+
+	var gateResp struct {
+		apistructs.Header
+		Data apistructs.TestReportGateResult `json:"data"`
+	}
+	resp, err := hc.Get(host).Path("/api/qa/test-records/actions/gate").
+		Header(httputil.InternalHeader, "bundle").
+		Param("uuid", uuid).
+		Do().JSON(&gateResp)
+	if err != nil {
+		return nil, apierrors.ErrInvoke.InternalError(err)
+	}
+	if !resp.IsOK() || !gateResp.Success {
+		return nil, toAPIError(resp.StatusCode(), gateResp.Error)
+	}
+
+	return &gateResp.Data, nil
+}
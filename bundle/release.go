@@ -305,3 +305,48 @@ func (b *Bundle) ToFormalReleases(orgID uint64, userID string, req apistructs.Re
 	}
 	return nil
 }
+
+// GetReleaseVulnerabilityGate 获取项目环境的漏洞门禁配置
+func (b *Bundle) GetReleaseVulnerabilityGate(projectID uint64, workspace string) (*apistructs.ReleaseVulnerabilityGate, error) {
+	host, err := b.urls.ErdaServer()
+	if err != nil {
+		return nil, err
+	}
+	hc := b.hc
+
+	var gateResp apistructs.ReleaseVulnerabilityGateResponse
+	resp, err := hc.Get(host).Path("/api/release-vulnerability-gates").
+		Param("projectID", strconv.FormatUint(projectID, 10)).
+		Param("workspace", workspace).
+		Header(httputil.InternalHeader, "bundle").
+		Do().JSON(&gateResp)
+	if err != nil {
+		return nil, apierrors.ErrInvoke.InternalError(err)
+	}
+	if !resp.IsOK() || !gateResp.Success {
+		return nil, toAPIError(resp.StatusCode(), gateResp.Error)
+	}
+	return &gateResp.Data, nil
+}
+
+// CheckReleaseVulnerabilityGate 检查制品能否通过目标环境的漏洞门禁
+func (b *Bundle) CheckReleaseVulnerabilityGate(releaseID, workspace string) (*apistructs.ReleaseVulnerabilityGateCheckResult, error) {
+	host, err := b.urls.ErdaServer()
+	if err != nil {
+		return nil, err
+	}
+	hc := b.hc
+
+	var checkResp apistructs.ReleaseVulnerabilityGateCheckResponse
+	resp, err := hc.Get(host).Path(fmt.Sprintf("/api/releases/%s/actions/check-vulnerability-gate", releaseID)).
+		Param("workspace", workspace).
+		Header(httputil.InternalHeader, "bundle").
+		Do().JSON(&checkResp)
+	if err != nil {
+		return nil, apierrors.ErrInvoke.InternalError(err)
+	}
+	if !resp.IsOK() || !checkResp.Success {
+		return nil, toAPIError(resp.StatusCode(), checkResp.Error)
+	}
+	return &checkResp.Data, nil
+}
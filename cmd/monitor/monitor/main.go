@@ -56,12 +56,14 @@ import (
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/metric/query"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/metric/query-example"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/metric/query/metricq"
+	_ "github.com/erda-project/erda/internal/tools/monitor/core/metric/query/promql"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/metric/storage/clickhouse"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/metric/storage/elasticsearch"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/profile/query"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/settings"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/settings/retention-strategy"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/loader"
+	_ "github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/rollup"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/storekit/elasticsearch/index/cleaner"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/storekit/elasticsearch/index/loader"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/storekit/elasticsearch/index/retention-strategy"
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS <database>.<table_name> ON CLUSTER '{cluster}'
+(
+    `org_name`            LowCardinality(String),
+    `tenant_id`           LowCardinality(String),
+    `metric_group`        LowCardinality(String),
+    `timestamp`           DateTime64(9,'Asia/Shanghai') CODEC (DoubleDelta),
+    `tag_keys`            Array(LowCardinality(String)),
+    `tag_values`          Array(LowCardinality(String)),
+    `number_field_sums`   SimpleAggregateFunction(sumMap, Tuple(Array(String), Array(Float64))),
+    `number_field_mins`   SimpleAggregateFunction(minMap, Tuple(Array(String), Array(Float64))),
+    `number_field_maxs`   SimpleAggregateFunction(maxMap, Tuple(Array(String), Array(Float64))),
+    `number_field_counts` SimpleAggregateFunction(sumMap, Tuple(Array(String), Array(UInt64))),
+
+    INDEX idx_metric_service_id(tag_values[indexOf(tag_keys, 'service_id')]) TYPE bloom_filter GRANULARITY 1,
+    INDEX idx_metric_cluster_name(tag_values[indexOf(tag_keys, 'cluster_name')]) TYPE bloom_filter GRANULARITY 1,
+    INDEX idx_terminus_key(tag_values[indexOf(tag_keys, 'terminus_key')]) TYPE bloom_filter GRANULARITY 1
+)
+ENGINE = ReplicatedAggregatingMergeTree('/clickhouse/tables/{cluster}-{shard}/{database}/<table_name>', '{replica}')
+PARTITION BY toYYYYMMDD(timestamp)
+ORDER BY (org_name, tenant_id, metric_group, timestamp, tag_keys, tag_values)
+TTL toDateTime(timestamp) + INTERVAL <ttl_in_days> DAY;
+
+CREATE TABLE IF NOT EXISTS <database>.<table_name>_all ON CLUSTER '{cluster}' AS <database>.<table_name>
+ENGINE = Distributed('{cluster}', <database>, <table_name>, rand());
@@ -0,0 +1,19 @@
+CREATE MATERIALIZED VIEW IF NOT EXISTS <database>.<view_name> ON CLUSTER '{cluster}'
+TO <database>.<table_name>
+AS SELECT
+    org_name,
+    tenant_id,
+    metric_group,
+    bucket AS timestamp,
+    tag_keys,
+    tag_values,
+    sumMap((number_field_keys, number_field_values)) AS number_field_sums,
+    minMap((number_field_keys, number_field_values)) AS number_field_mins,
+    maxMap((number_field_keys, number_field_values)) AS number_field_maxs,
+    sumMap((number_field_keys, arrayMap(x -> toUInt64(1), number_field_keys))) AS number_field_counts
+FROM
+(
+    SELECT *, toDateTime64(toStartOfInterval(timestamp, INTERVAL <interval_in_seconds> SECOND), 9, 'Asia/Shanghai') AS bucket
+    FROM <database>.<source_table_name>
+)
+GROUP BY org_name, tenant_id, metric_group, bucket, tag_keys, tag_values;
@@ -48,6 +48,7 @@ import (
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/creator"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/initializer"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/loader"
+	_ "github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/rollup"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/storekit/elasticsearch/index/creator"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/storekit/elasticsearch/index/initializer"
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/storekit/elasticsearch/index/loader"
@@ -16,6 +16,7 @@
 package conf
 
 import (
+	"strings"
 	"time"
 
 	"github.com/erda-project/erda/pkg/envconf"
@@ -52,6 +53,14 @@ type Conf struct {
 	ErdaProtocol    string `env:"DICE_PROTOCOL"`
 	ErdaClusterName string `env:"DICE_CLUSTER_NAME"`
 	ErdaDomain      string `env:"DICE_ROOT_DOMAIN"`
+
+	// web shell session recording
+	ShellRecordingEnabled bool          `env:"SHELL_RECORDING_ENABLED" default:"true"`
+	ShellRecordingInput   bool          `env:"SHELL_RECORDING_INPUT" default:"false"`
+	ShellRecordingMaxSize int64         `env:"SHELL_RECORDING_MAX_SIZE" default:"10485760"`
+	ShellRecordingTTL     time.Duration `env:"SHELL_RECORDING_RETENTION" default:"720h"`
+	// extra redact regular expressions, separated by newline
+	ShellRecordingRedactPatterns string `env:"SHELL_RECORDING_REDACT_PATTERNS"`
 }
 
 var cfg Conf
@@ -149,3 +158,29 @@ func ErdaClusterName() string {
 func ErdaDomain() string {
 	return cfg.ErdaDomain
 }
+
+func ShellRecordingEnabled() bool {
+	return cfg.ShellRecordingEnabled
+}
+
+func ShellRecordingInput() bool {
+	return cfg.ShellRecordingInput
+}
+
+func ShellRecordingMaxSize() int64 {
+	return cfg.ShellRecordingMaxSize
+}
+
+func ShellRecordingTTL() time.Duration {
+	return cfg.ShellRecordingTTL
+}
+
+func ShellRecordingRedactPatterns() []string {
+	var patterns []string
+	for _, p := range strings.Split(cfg.ShellRecordingRedactPatterns, "\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
@@ -40,6 +40,7 @@ const (
 	RecordTypeResetClusterCredential  RecordType = "resetClusterCredential"
 	RecordTypeUpgradeEdgeCluster      RecordType = "upgradeEdgeCluster"
 	RecordTypeOfflineEdgeCluster      RecordType = "offlineEdgeCluster"
+	RecordTypeUpgradeKubernetes       RecordType = "upgradeKubernetes"
 	RecordTypeCreateAliCloudMysql     RecordType = "createAliCloudMysql"
 	RecordTypeCreateAliCloudMysqlDB   RecordType = "createAliCloudMysqlDB"
 	RecordTypeCreateAliCloudRedis     RecordType = "createAliCloudRedis"
@@ -0,0 +1,122 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbclient
+
+import (
+	"time"
+)
+
+// ShellSession is a recorded web shell session (kubectl-shell).
+type ShellSession struct {
+	ID          string    `gorm:"primary_key;type:varchar(36)"`
+	CreatedAt   time.Time `gorm:"column:created_at"`
+	UpdatedAt   time.Time `gorm:"column:updated_at"`
+	OrgID       uint64    `gorm:"column:org_id"`
+	OrgName     string    `gorm:"column:org_name"`
+	UserID      string    `gorm:"column:user_id"`
+	ClusterName string    `gorm:"column:cluster_name"`
+	ClientIP    string    `gorm:"column:client_ip"`
+	UserAgent   string    `gorm:"column:user_agent"`
+	StartedAt   time.Time `gorm:"column:started_at"`
+	EndedAt     time.Time `gorm:"column:ended_at"`
+	// Duration in milliseconds
+	Duration int64 `gorm:"column:duration"`
+	// Size of the uncompressed recording in bytes
+	Size int64 `gorm:"column:size"`
+	// Commands is the newline separated list of redacted commands
+	Commands    string `gorm:"column:commands"`
+	IsTruncated bool   `gorm:"column:is_truncated"`
+	// Recording is the gzip compressed asciicast v2 recording
+	Recording     []byte `gorm:"column:recording"`
+	SoftDeletedAt uint64 `gorm:"column:soft_deleted_at"`
+}
+
+func (ShellSession) TableName() string {
+	return "cmp_shell_session"
+}
+
+// shellSessionColumns are the columns returned when listing sessions, the recording itself is excluded.
+var shellSessionColumns = []string{"id", "created_at", "updated_at", "org_id", "org_name", "user_id", "cluster_name",
+	"client_ip", "user_agent", "started_at", "ended_at", "duration", "size", "commands", "is_truncated"}
+
+// ShellSessionFilter is the search condition of shell sessions
+type ShellSessionFilter struct {
+	OrgID       uint64
+	UserIDs     []string
+	ClusterName string
+	StartTime   *time.Time
+	EndTime     *time.Time
+	// Keyword matches the recorded commands
+	Keyword  string
+	PageNo   int
+	PageSize int
+}
+
+// CreateShellSession saves a recorded shell session
+func (c *DBClient) CreateShellSession(s *ShellSession) error {
+	return c.Create(s).Error
+}
+
+// GetShellSession gets the shell session by id, the recording is included
+func (c *DBClient) GetShellSession(id string) (*ShellSession, error) {
+	var s ShellSession
+	if err := c.Where("id = ? AND soft_deleted_at = 0", id).First(&s).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ListShellSessions lists shell sessions without the recording, ordered by start time desc
+func (c *DBClient) ListShellSessions(f ShellSessionFilter) ([]ShellSession, int64, error) {
+	db := c.Model(&ShellSession{}).Where("org_id = ? AND soft_deleted_at = 0", f.OrgID)
+	if len(f.UserIDs) > 0 {
+		db = db.Where("user_id IN (?)", f.UserIDs)
+	}
+	if f.ClusterName != "" {
+		db = db.Where("cluster_name = ?", f.ClusterName)
+	}
+	if f.StartTime != nil {
+		db = db.Where("started_at >= ?", *f.StartTime)
+	}
+	if f.EndTime != nil {
+		db = db.Where("started_at <= ?", *f.EndTime)
+	}
+	if f.Keyword != "" {
+		db = db.Where("commands LIKE ?", "%"+f.Keyword+"%")
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if f.PageNo <= 0 {
+		f.PageNo = 1
+	}
+	if f.PageSize <= 0 {
+		f.PageSize = 20
+	}
+	var sessions []ShellSession
+	if err := db.Select(shellSessionColumns).Order("started_at DESC").
+		Offset((f.PageNo - 1) * f.PageSize).Limit(f.PageSize).Find(&sessions).Error; err != nil {
+		return nil, 0, err
+	}
+	return sessions, total, nil
+}
+
+// DeleteShellSessionsBefore deletes the sessions started before t, returns the number of deleted sessions
+func (c *DBClient) DeleteShellSessionsBefore(t time.Time) (int64, error) {
+	db := c.Where("started_at < ?", t).Delete(&ShellSession{})
+	return db.RowsAffected, db.Error
+}
@@ -162,6 +162,9 @@ func (e *Endpoints) Routes() []httpserver.Endpoint {
 		{Path: "/api/cluster/actions/init-retry", Method: http.MethodPost, Handler: auth(i18nPrinter(e.InitClusterRetry))},
 		{Path: "/api/cluster/actions/upgrade", Method: http.MethodPost, Handler: auth(i18nPrinter(e.UpgradeEdgeCluster))},
 		{Path: "/api/cluster/actions/batch-upgrade", Method: http.MethodPost, Handler: auth(i18nPrinter(e.BatchUpgradeEdgeCluster))},
+		{Path: "/api/cluster/actions/upgrade-kubernetes", Method: http.MethodPost, Handler: auth(i18nPrinter(e.UpgradeKubernetes))},
+		{Path: "/api/records/{recordID}/actions/pause", Method: http.MethodPost, Handler: auth(i18nPrinter(e.PauseUpgradeKubernetes))},
+		{Path: "/api/records/{recordID}/actions/resume", Method: http.MethodPost, Handler: auth(i18nPrinter(e.ResumeUpgradeKubernetes))},
 		{Path: "/api/cluster/actions/batch-offline", Method: http.MethodDelete, Handler: auth(i18nPrinter(e.BatchOfflineEdgeCluster))},
 		{Path: "/api/cluster", Method: http.MethodDelete, Handler: auth(i18nPrinter(e.OfflineEdgeCluster))},
 		{Path: "/api/cluster", Method: http.MethodGet, Handler: auth(i18nPrinter(e.ClusterInfo))},
@@ -171,6 +174,15 @@ func (e *Endpoints) Routes() []httpserver.Endpoint {
 		{Path: "/api/cluster/credential/access-keys/actions/reset", Method: http.MethodPost, Handler: auth(i18nPrinter(e.ResetAccessKey))},
 		{Path: "/api/org-cluster-info", Method: http.MethodGet, Handler: auth(i18nPrinter(e.OrgClusterInfo))},
 
+		// multi-cluster resource search and bulk operations
+		{Path: "/api/federated-resources", Method: http.MethodGet, Handler: auth(i18nPrinter(e.FederatedSearch))},
+		{Path: "/api/federated-resources/actions/bulk", Method: http.MethodPost, Handler: auth(i18nPrinter(e.FederatedBulk))},
+
+		// web shell session recordings
+		{Path: "/api/shell-sessions", Method: http.MethodGet, Handler: auth(i18nPrinter(e.ListShellSessions))},
+		{Path: "/api/shell-sessions/{id}", Method: http.MethodGet, Handler: auth(i18nPrinter(e.GetShellSession))},
+		{Path: "/api/shell-sessions/{id}/recording", Method: http.MethodGet, WriterHandler: e.PlayShellSession},
+
 		// officer apis
 		{Path: "/api/clusters/{clusterName}/registry/readonly", Method: http.MethodGet, Handler: e.RegistryReadonly},
 		{Path: "/api/clusters/{clusterName}/registry/layers", Method: http.MethodDelete, Handler: e.RegistryRemoveLayers},
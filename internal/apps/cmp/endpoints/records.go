@@ -79,6 +79,10 @@ func (e *Endpoints) RecordTypeList(ctx context.Context, r *http.Request, vars ma
 				RecordType:    i18n.Sprintf(string(dbclient.RecordTypeUpgradeEdgeCluster)),
 				RawRecordType: string(dbclient.RecordTypeUpgradeEdgeCluster),
 			},
+			{
+				RecordType:    i18n.Sprintf(string(dbclient.RecordTypeUpgradeKubernetes)),
+				RawRecordType: string(dbclient.RecordTypeUpgradeKubernetes),
+			},
 			{
 				RecordType:    i18n.Sprintf(string(dbclient.RecordTypeAddAliCSECluster)),
 				RawRecordType: string(dbclient.RecordTypeAddAliACKECluster),
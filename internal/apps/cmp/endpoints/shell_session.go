@@ -0,0 +1,192 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoints
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/cmp/dbclient"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+	"github.com/erda-project/erda/pkg/strutil"
+)
+
+// ListShellSessions searches recorded web shell sessions by user, cluster, time range and command keyword.
+// Org managers can see all sessions in the org, other users can only see their own sessions.
+func (e *Endpoints) ListShellSessions(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	i, resp := e.GetIdentity(r)
+	if resp != nil {
+		return resp, nil
+	}
+	orgID, err := strconv.ParseUint(i.OrgID, 10, 64)
+	if err != nil {
+		return shellSessionErrResponse(fmt.Errorf("invalid org id %s", i.OrgID))
+	}
+
+	query := r.URL.Query()
+	filter := dbclient.ShellSessionFilter{
+		OrgID:       orgID,
+		UserIDs:     strutil.Split(query.Get("userID"), ",", true),
+		ClusterName: query.Get("clusterName"),
+		Keyword:     query.Get("keyword"),
+	}
+	if filter.PageNo, err = parseIntParam(query.Get("pageNo"), 1); err != nil {
+		return shellSessionErrResponse(fmt.Errorf("failed to parse 'pageNo' arg"))
+	}
+	if filter.PageSize, err = parseIntParam(query.Get("pageSize"), 20); err != nil {
+		return shellSessionErrResponse(fmt.Errorf("failed to parse 'pageSize' arg"))
+	}
+	if filter.StartTime, err = parseTimeParam(query.Get("startTime")); err != nil {
+		return shellSessionErrResponse(fmt.Errorf("failed to parse 'startTime' arg, %v", err))
+	}
+	if filter.EndTime, err = parseTimeParam(query.Get("endTime")); err != nil {
+		return shellSessionErrResponse(fmt.Errorf("failed to parse 'endTime' arg, %v", err))
+	}
+	if e.IsManager(i.UserID, apistructs.OrgScope, i.OrgID) != nil {
+		filter.UserIDs = []string{i.UserID}
+	}
+
+	sessions, total, err := e.dbclient.ListShellSessions(filter)
+	if err != nil {
+		logrus.Errorf("failed to list shell sessions, %v", err)
+		return shellSessionErrResponse(err)
+	}
+	data := apistructs.ShellSessionListData{Total: total, List: make([]apistructs.ShellSessionData, 0, len(sessions))}
+	var userIDs []string
+	for _, s := range sessions {
+		data.List = append(data.List, convertShellSession(s))
+		userIDs = append(userIDs, s.UserID)
+	}
+	return mkResponse(apistructs.ShellSessionListResponse{
+		Header:         apistructs.Header{Success: true},
+		UserInfoHeader: apistructs.UserInfoHeader{UserIDs: strutil.DedupSlice(userIDs)},
+		Data:           data,
+	})
+}
+
+// GetShellSession gets the detail of a recorded web shell session
+func (e *Endpoints) GetShellSession(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	session, err := e.getPermittedShellSession(r, vars["id"])
+	if err != nil {
+		return shellSessionErrResponse(err)
+	}
+	return mkResponse(apistructs.ShellSessionResponse{
+		Header:         apistructs.Header{Success: true},
+		UserInfoHeader: apistructs.UserInfoHeader{UserIDs: []string{session.UserID}},
+		Data:           convertShellSession(*session),
+	})
+}
+
+// PlayShellSession returns the asciicast v2 recording of a web shell session for playback
+func (e *Endpoints) PlayShellSession(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	session, err := e.getPermittedShellSession(r, vars["id"])
+	if err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		_, err = w.Write([]byte(err.Error()))
+		return err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(session.Recording))
+	if err != nil {
+		return fmt.Errorf("failed to read recording of shell session %s, %v", session.ID, err)
+	}
+	defer zr.Close()
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%s.cast", session.ID))
+	_, err = io.Copy(w, zr)
+	return err
+}
+
+func (e *Endpoints) getPermittedShellSession(r *http.Request, id string) (*dbclient.ShellSession, error) {
+	i, resp := e.GetIdentity(r)
+	if resp != nil {
+		return nil, fmt.Errorf("failed to get User-ID or Org-ID from request header")
+	}
+	session, err := e.dbclient.GetShellSession(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shell session %s, %v", id, err)
+	}
+	if strconv.FormatUint(session.OrgID, 10) != i.OrgID {
+		return nil, fmt.Errorf("access denied")
+	}
+	if session.UserID != i.UserID {
+		if err := e.IsManager(i.UserID, apistructs.OrgScope, i.OrgID); err != nil {
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+func convertShellSession(s dbclient.ShellSession) apistructs.ShellSessionData {
+	var commands []string
+	if s.Commands != "" {
+		commands = strings.Split(s.Commands, "\n")
+	}
+	return apistructs.ShellSessionData{
+		ID:          s.ID,
+		OrgID:       s.OrgID,
+		UserID:      s.UserID,
+		ClusterName: s.ClusterName,
+		ClientIP:    s.ClientIP,
+		UserAgent:   s.UserAgent,
+		StartedAt:   s.StartedAt,
+		EndedAt:     s.EndedAt,
+		Duration:    s.Duration,
+		Size:        s.Size,
+		Commands:    commands,
+		Truncated:   s.IsTruncated,
+	}
+}
+
+func shellSessionErrResponse(err error) (httpserver.Responser, error) {
+	return mkResponse(apistructs.ShellSessionListResponse{
+		Header: apistructs.Header{
+			Success: false,
+			Error:   apistructs.ErrorResponse{Msg: err.Error()},
+		},
+	})
+}
+
+func parseIntParam(s string, defaultValue int) (int, error) {
+	if s == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// parseTimeParam parses time in RFC3339 or unix milliseconds
+func parseTimeParam(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		t := time.Unix(0, ms*int64(time.Millisecond))
+		return &t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+	"github.com/erda-project/erda/pkg/strutil"
+)
+
+// FederatedSearch searches k8s resources across all clusters of the org
+func (e *Endpoints) FederatedSearch(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	i, resp := e.GetIdentity(r)
+	if resp != nil {
+		return resp, nil
+	}
+	query := r.URL.Query()
+	req := apistructs.FederatedSearchRequest{
+		UserID:        i.UserID,
+		OrgID:         i.OrgID,
+		Type:          apistructs.K8SResType(query.Get("type")),
+		ClusterNames:  strutil.Split(query.Get("clusterName"), ",", true),
+		Namespace:     query.Get("namespace"),
+		LabelSelector: query["labelSelector"],
+		FieldSelector: query["fieldSelector"],
+		NameRegex:     query.Get("nameRegex"),
+	}
+	var err error
+	if req.PageNo, err = parseIntParam(query.Get("pageNo"), 1); err != nil {
+		return federatedErrResponse(fmt.Errorf("failed to parse 'pageNo' arg"))
+	}
+	if req.PageSize, err = parseIntParam(query.Get("pageSize"), 20); err != nil {
+		return federatedErrResponse(fmt.Errorf("failed to parse 'pageSize' arg"))
+	}
+
+	data, err := e.SteveAggregator.FederatedSearch(ctx, &req)
+	if err != nil {
+		logrus.Errorf("failed to search %s across clusters, %v", req.Type, err)
+		return federatedErrResponse(err)
+	}
+	return mkResponse(apistructs.FederatedSearchResponse{
+		Header: apistructs.Header{Success: true},
+		Data:   *data,
+	})
+}
+
+// FederatedBulk runs a bulk action on resources across clusters and returns result of each target
+func (e *Endpoints) FederatedBulk(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	i, resp := e.GetIdentity(r)
+	if resp != nil {
+		return resp, nil
+	}
+	var req apistructs.FederatedBulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return federatedErrResponse(fmt.Errorf("failed to unmarshal to apistructs.FederatedBulkRequest: %v", err))
+	}
+	req.UserID = i.UserID
+	req.OrgID = i.OrgID
+
+	data, err := e.SteveAggregator.FederatedBulk(ctx, &req)
+	if err != nil {
+		logrus.Errorf("failed to run bulk action %s, %v", req.Action, err)
+		return federatedErrResponse(err)
+	}
+	logrus.Infof("bulk action %s by user %s in org %s, succeeded: %d, failed: %d", req.Action, req.UserID,
+		req.OrgID, data.Succeeded, data.Failed)
+	return mkResponse(apistructs.FederatedBulkResponse{
+		Header: apistructs.Header{Success: true},
+		Data:   *data,
+	})
+}
+
+func federatedErrResponse(err error) (httpserver.Responser, error) {
+	return mkResponse(apistructs.FederatedSearchResponse{
+		Header: apistructs.Header{
+			Success: false,
+			Error:   apistructs.ErrorResponse{Msg: err.Error()},
+		},
+	})
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+)
+
+func (e *Endpoints) UpgradeKubernetes(ctx context.Context, r *http.Request, vars map[string]string) (resp httpserver.Responser, err error) {
+	defer func() {
+		if err != nil {
+			logrus.Errorf("error happened: %+v", err)
+			resp, err = mkResponse(apistructs.UpgradeKubernetesResponse{
+				Header: apistructs.Header{
+					Success: false,
+					Error:   apistructs.ErrorResponse{Msg: err.Error()},
+				},
+			})
+		}
+	}()
+	var req apistructs.UpgradeKubernetesRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err = fmt.Errorf("failed to unmarshal request: %+v", err)
+		return
+	}
+
+	// get identity info
+	i, resp := e.GetIdentity(r)
+	if resp != nil {
+		err = fmt.Errorf("failed to get User-ID or Org-ID from request header")
+		return
+	}
+	// permission check
+	err = e.CloudResourcePermissionCheck(ctx, i.UserID, i.OrgID, req.ClusterName, apistructs.UpdateAction)
+	if err != nil {
+		return
+	}
+
+	recordID, err := e.clusters.UpgradeKubernetes(ctx, req, i.UserID, i.OrgID)
+	if err != nil {
+		err = fmt.Errorf("failed to upgrade kubernetes: %v", err)
+		return
+	}
+	return mkResponse(apistructs.UpgradeKubernetesResponse{
+		Header: apistructs.Header{Success: true},
+		Data:   apistructs.UpgradeKubernetesData{RecordID: recordID},
+	})
+}
+
+func (e *Endpoints) PauseUpgradeKubernetes(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	return e.setUpgradeKubernetesPaused(ctx, r, vars, true)
+}
+
+func (e *Endpoints) ResumeUpgradeKubernetes(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	return e.setUpgradeKubernetesPaused(ctx, r, vars, false)
+}
+
+func (e *Endpoints) setUpgradeKubernetesPaused(ctx context.Context, r *http.Request, vars map[string]string, paused bool) (resp httpserver.Responser, err error) {
+	defer func() {
+		if err != nil {
+			logrus.Errorf("error happened: %+v", err)
+			resp, err = mkResponse(apistructs.PauseUpgradeKubernetesResponse{
+				Header: apistructs.Header{
+					Success: false,
+					Error:   apistructs.ErrorResponse{Msg: err.Error()},
+				},
+			})
+		}
+	}()
+	recordID, err := strconv.ParseUint(vars["recordID"], 10, 64)
+	if err != nil {
+		err = fmt.Errorf("invalid record id: %s", vars["recordID"])
+		return
+	}
+
+	// get identity info
+	i, resp := e.GetIdentity(r)
+	if resp != nil {
+		err = fmt.Errorf("failed to get User-ID or Org-ID from request header")
+		return
+	}
+	record, err := e.clusters.GetUpgradeKubernetesRecord(recordID, i.OrgID)
+	if err != nil {
+		return
+	}
+	// permission check
+	err = e.CloudResourcePermissionCheck(ctx, i.UserID, i.OrgID, record.ClusterName, apistructs.UpdateAction)
+	if err != nil {
+		return
+	}
+
+	if err = e.clusters.SetUpgradeKubernetesPaused(recordID, i.OrgID, paused); err != nil {
+		return
+	}
+	return mkResponse(apistructs.PauseUpgradeKubernetesResponse{
+		Header: apistructs.Header{Success: true},
+	})
+}
@@ -29,6 +29,7 @@ func InitI18N() {
 	message.SetString(language.SimplifiedChinese, "addAliCSEdgeCluster", "添加阿里云容器服务标准专有集群")
 	message.SetString(language.SimplifiedChinese, "addAliCSManagedEdgeCluster", "添加阿里云容器服务标准托管集群")
 	message.SetString(language.SimplifiedChinese, "upgradeEdgeCluster", "升级边缘集群")
+	message.SetString(language.SimplifiedChinese, "upgradeKubernetes", "升级 Kubernetes 版本")
 	message.SetString(language.SimplifiedChinese, "offlineEdgeCluster", "集群下线")
 	message.SetString(language.SimplifiedChinese, "importKubernetesCluster", "导入Kubernetes集群")
 	message.SetString(language.SimplifiedChinese, "createClusterCredential", "创建集群认证 Token")
@@ -0,0 +1,193 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/cmp/conf"
+	"github.com/erda-project/erda/internal/apps/cmp/dbclient"
+	"github.com/erda-project/erda/pkg/database/dbengine"
+	"github.com/erda-project/erda/pkg/discover"
+	"github.com/erda-project/erda/pkg/k8sclient"
+)
+
+// passThroughEnvs are envs of cmp which cluster-ops upgrade job needs
+var passThroughEnvs = []string{
+	"MYSQL_HOST", "MYSQL_PORT", "MYSQL_USERNAME", "MYSQL_PASSWORD", "MYSQL_DATABASE",
+	discover.EnvClusterManager,
+}
+
+// UpgradeKubernetes create a cluster-ops job in erda cluster which upgrades kubernetes of target cluster,
+// the progress of upgrade is saved in the detail of record.
+func (c *Clusters) UpgradeKubernetes(ctx context.Context, req apistructs.UpgradeKubernetesRequest, userID, orgID string) (uint64, error) {
+	if req.ClusterName == "" || req.Version == "" || req.Provider == "" {
+		return 0, fmt.Errorf("clusterName, version and provider are required")
+	}
+	if req.MaxSurge <= 0 {
+		req.MaxSurge = 1
+	}
+
+	records, err := c.db.RecordsReader().ByClusterNames(req.ClusterName).
+		ByRecordTypes(dbclient.RecordTypeUpgradeKubernetes.String()).
+		ByStatuses(dbclient.StatusTypeProcessing.String()).Do()
+	if err != nil {
+		return 0, fmt.Errorf("failed to query record: %v", err)
+	}
+	if len(records) > 0 {
+		return 0, fmt.Errorf("cluster %s is being upgraded, record: %d", req.ClusterName, records[0].ID)
+	}
+
+	providerConfig, err := json.Marshal(req.ProviderConfig)
+	if err != nil {
+		return 0, err
+	}
+
+	recordID, err := c.db.RecordsWriter().Create(&dbclient.Record{
+		RecordType:  dbclient.RecordTypeUpgradeKubernetes,
+		UserID:      userID,
+		OrgID:       orgID,
+		ClusterName: req.ClusterName,
+		Status:      dbclient.StatusTypeProcessing,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create record: %v", err)
+	}
+
+	kc, err := k8sclient.New(conf.ErdaClusterName())
+	if err != nil {
+		c.failRecord(recordID, err)
+		return 0, err
+	}
+
+	envs := []corev1.EnvVar{
+		{Name: "DEBUG", Value: "true"},
+		{Name: "CLUSTER_OPS_ACTION", Value: "upgrade"},
+		{Name: "INSTALL_MODE", Value: "REMOTE"},
+		{Name: "TARGET_CLUSTER", Value: req.ClusterName},
+		{Name: "UPGRADE_K8S_VERSION", Value: req.Version},
+		{Name: "UPGRADE_PROVIDER", Value: req.Provider},
+		{Name: "UPGRADE_PROVIDER_CONFIG", Value: string(providerConfig)},
+		{Name: "UPGRADE_MAX_SURGE", Value: strconv.Itoa(req.MaxSurge)},
+		{Name: "OPS_RECORD_ID", Value: strconv.FormatUint(recordID, 10)},
+	}
+	for _, name := range passThroughEnvs {
+		envs = append(envs, corev1.EnvVar{Name: name, Value: os.Getenv(name)})
+	}
+
+	job := generateUpgradeKubernetesJob(recordID, req.ClusterName, envs)
+	if _, err = kc.ClientSet.BatchV1().Jobs(getWorkerNamespace()).Create(context.Background(), job,
+		metav1.CreateOptions{}); err != nil {
+		logrus.Errorf("create upgrade kubernetes job error: %v", err)
+		c.failRecord(recordID, err)
+		return 0, err
+	}
+
+	return recordID, nil
+}
+
+// GetUpgradeKubernetesRecord get the upgrade kubernetes record of the org
+func (c *Clusters) GetUpgradeKubernetesRecord(recordID uint64, orgID string) (dbclient.Record, error) {
+	records, err := c.db.RecordsReader().ByIDs(strconv.FormatUint(recordID, 10)).Do()
+	if err != nil {
+		return dbclient.Record{}, err
+	}
+	if len(records) == 0 || records[0].OrgID != orgID ||
+		records[0].RecordType != dbclient.RecordTypeUpgradeKubernetes {
+		return dbclient.Record{}, fmt.Errorf("upgrade record %d not found", recordID)
+	}
+	return records[0], nil
+}
+
+// SetUpgradeKubernetesPaused request the upgrade job to pause or resume at the next checkpoint
+func (c *Clusters) SetUpgradeKubernetesPaused(recordID uint64, orgID string, paused bool) error {
+	record, err := c.GetUpgradeKubernetesRecord(recordID, orgID)
+	if err != nil {
+		return err
+	}
+	if record.Status != dbclient.StatusTypeProcessing {
+		return fmt.Errorf("upgrade record %d is already %s", recordID, record.Status)
+	}
+
+	detail := make(map[string]interface{})
+	if record.Detail != "" {
+		if err = json.Unmarshal([]byte(record.Detail), &detail); err != nil {
+			return fmt.Errorf("invalid upgrade progress of record %d: %v", recordID, err)
+		}
+	}
+	detail["pauseRequested"] = paused
+	content, err := json.Marshal(detail)
+	if err != nil {
+		return err
+	}
+	record.Detail = string(content)
+
+	return c.db.RecordsWriter().Update(record)
+}
+
+func (c *Clusters) failRecord(recordID uint64, err error) {
+	if er := c.db.RecordsWriter().Update(dbclient.Record{
+		BaseModel: dbengine.BaseModel{ID: recordID},
+		Status:    dbclient.StatusTypeFailed,
+		Detail:    err.Error(),
+	}); er != nil {
+		logrus.Errorf("update record %d error: %v", recordID, er)
+	}
+}
+
+// generateUpgradeKubernetesJob generate cluster-ops job which upgrades kubernetes
+func generateUpgradeKubernetesJob(recordID uint64, clusterName string, envs []corev1.EnvVar) *batchv1.Job {
+	var backOffLimit int32
+	jobName := fmt.Sprintf("erda-cluster-upgrade-%d", recordID)
+
+	return &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Job",
+			APIVersion: "batch/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName,
+			Namespace: getWorkerNamespace(),
+			Labels:    map[string]string{"erda.cloud/upgrade-cluster": clusterName},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backOffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: "Never",
+					Containers: []corev1.Container{
+						{
+							Name:            jobName,
+							Image:           renderReleaseImageAddr(),
+							ImagePullPolicy: "Always",
+							Command:         []string{"sh", "-c", fmt.Sprintf("/app/%s", ModuleClusterOps)},
+							Env:             envs,
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -39,6 +39,7 @@ import (
 	org_resource "github.com/erda-project/erda/internal/apps/cmp/impl/org-resource"
 	"github.com/erda-project/erda/internal/apps/cmp/resource"
 	"github.com/erda-project/erda/internal/apps/cmp/steve/middleware"
+	"github.com/erda-project/erda/internal/apps/cmp/steve/recording"
 	"github.com/erda-project/erda/internal/apps/cmp/tasks"
 	"github.com/erda-project/erda/pkg/database/dbengine"
 	"github.com/erda-project/erda/pkg/discover"
@@ -189,6 +190,15 @@ func (p *provider) do(ctx context.Context) (*httpserver.Server, error) {
 		shellHandler.HandleShell,
 		auditor.AuditMiddleWare,
 	}
+	if conf.ShellRecordingEnabled() {
+		middlewares = append(middlewares, newSessionRecorder(db).RecordMiddleware)
+		go loop.New(loop.WithInterval(time.Hour)).Do(func() (bool, error) {
+			if err := middleware.CleanShellSessions(db, conf.ShellRecordingTTL()); err != nil {
+				logrus.Errorf("failed to clean expired shell sessions, %v", err)
+			}
+			return false, nil
+		})
+	}
 
 	server.Router().Path("/api/k8s/clusters/{clusterName}/**").Handler(middlewares.Handler(ep.SteveAggregator))
 	server.Router().Path("/api/apim/metrics/**").Handler(endpoints.InternalReverseHandler(endpoints.ProxyMetrics))
@@ -236,6 +246,19 @@ func (p *provider) initEndpoints(ctx context.Context, db *dbclient.DBClient, js,
 	return ep, nil
 }
 
+func newSessionRecorder(db *dbclient.DBClient) *middleware.SessionRecorder {
+	redactor, err := recording.NewRedactor(conf.ShellRecordingRedactPatterns()...)
+	if err != nil {
+		logrus.Errorf("invalid shell recording redact patterns, use default patterns only, %v", err)
+		redactor, _ = recording.NewRedactor()
+	}
+	return middleware.NewSessionRecorder(db,
+		middleware.WithRecordMaxSize(int(conf.ShellRecordingMaxSize())),
+		middleware.WithRecordInput(conf.ShellRecordingInput()),
+		middleware.WithRecordRedactor(redactor),
+	)
+}
+
 func initServices(ep *endpoints.Endpoints) {
 	// run mns service, monitor mns messages & consume them
 	ep.Mns.Run()
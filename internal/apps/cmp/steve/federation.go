@@ -0,0 +1,416 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package steve
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/erda-project/erda-infra/pkg/transport"
+	clusterpb "github.com/erda-project/erda-proto-go/core/clustermanager/cluster/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/bundle/apierrors"
+	"github.com/erda-project/erda/internal/apps/cmp/cache"
+	"github.com/erda-project/erda/internal/apps/cmp/steve/middleware"
+	"github.com/erda-project/erda/pkg/http/httputil"
+	"github.com/erda-project/erda/pkg/k8sclient"
+	"github.com/erda-project/erda/pkg/strutil"
+)
+
+const (
+	// maxFederatedConcurrency limits the number of clusters or targets handled at the same time
+	maxFederatedConcurrency = 10
+	federatedClusterTimeout = 15 * time.Second
+	federatedCacheDuration  = 30 * time.Second
+	defaultFederatedPage    = 20
+	maxFederatedBulkTargets = 500
+)
+
+// FederatedCacheKey is the cache key of a federated search result.
+// Results are cached per user since resources visible to users are different.
+type FederatedCacheKey struct {
+	UserID        string
+	OrgID         string
+	Type          string
+	Namespace     string
+	ClusterNames  []string
+	LabelSelector []string
+	FieldSelector []string
+	NameRegex     string
+}
+
+func (k *FederatedCacheKey) GetKey() string {
+	d := sha256.New()
+	data, _ := json.Marshal(k)
+	d.Write([]byte("federated"))
+	d.Write(data)
+	return hex.EncodeToString(d.Sum(nil))
+}
+
+// FederatedSearch lists resources of one type across all clusters of the org which match the selectors
+// and name regex. Results are sorted by cluster, namespace and name, and the whole result is cached
+// for a short time so that paging through it does not query all clusters again.
+// Clusters which failed to search are reported in Errors instead of failing the whole request.
+func (a *Aggregator) FederatedSearch(ctx context.Context, req *apistructs.FederatedSearchRequest) (*apistructs.FederatedSearchData, error) {
+	if req.Type == "" {
+		return nil, apierrors.ErrInvoke.InvalidParameter(errors.New("type is required"))
+	}
+	var nameRegex *regexp.Regexp
+	if req.NameRegex != "" {
+		var err error
+		if nameRegex, err = regexp.Compile(req.NameRegex); err != nil {
+			return nil, apierrors.ErrInvoke.InvalidParameter(fmt.Errorf("invalid name regex, %v", err))
+		}
+	}
+
+	clusters, err := a.listOrgClusters(req.OrgID, req.ClusterNames)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(clusters)
+
+	key := FederatedCacheKey{
+		UserID:        req.UserID,
+		OrgID:         req.OrgID,
+		Type:          string(req.Type),
+		Namespace:     req.Namespace,
+		ClusterNames:  clusters,
+		LabelSelector: req.LabelSelector,
+		FieldSelector: req.FieldSelector,
+		NameRegex:     req.NameRegex,
+	}
+	var result *apistructs.FederatedSearchData
+	values, expired, err := cache.GetFreeCache().Get(key.GetKey())
+	if err == nil && values != nil && !expired {
+		result, _ = values[0].Value().(*apistructs.FederatedSearchData)
+	}
+	if result == nil {
+		result = a.searchClusters(ctx, req, clusters, nameRegex)
+		if vals, err := cache.GetInterfaceValue(result); err != nil {
+			logrus.Errorf("failed to marshal cache data for federated search, %v", err)
+		} else if err = cache.GetFreeCache().Set(key.GetKey(), vals, federatedCacheDuration.Nanoseconds()); err != nil {
+			logrus.Errorf("failed to set cache for federated search, %v", err)
+		}
+	}
+
+	return &apistructs.FederatedSearchData{
+		Total:  result.Total,
+		List:   paginateResources(result.List, req.PageNo, req.PageSize),
+		Errors: result.Errors,
+	}, nil
+}
+
+func (a *Aggregator) searchClusters(ctx context.Context, req *apistructs.FederatedSearchRequest, clusters []string,
+	nameRegex *regexp.Regexp) *apistructs.FederatedSearchData {
+	var (
+		lock     sync.Mutex
+		list     []apistructs.FederatedResource
+		failures = make(map[string]string)
+	)
+	runConcurrently(len(clusters), maxFederatedConcurrency, func(i int) {
+		clusterName := clusters[i]
+		cctx, cancel := context.WithTimeout(ctx, federatedClusterTimeout)
+		defer cancel()
+		objs, err := a.ListSteveResource(cctx, &apistructs.SteveRequest{
+			UserID:        req.UserID,
+			OrgID:         req.OrgID,
+			Type:          req.Type,
+			ClusterName:   clusterName,
+			Namespace:     req.Namespace,
+			LabelSelector: req.LabelSelector,
+			FieldSelector: req.FieldSelector,
+		})
+		lock.Lock()
+		defer lock.Unlock()
+		if err != nil {
+			logrus.Errorf("failed to search %s in cluster %s, %v", req.Type, clusterName, err)
+			failures[clusterName] = err.Error()
+			return
+		}
+		list = append(list, convertFederatedResources(clusterName, req.Type, objs, nameRegex)...)
+	})
+	sortResources(list)
+
+	result := &apistructs.FederatedSearchData{Total: len(list), List: list}
+	if len(failures) > 0 {
+		result.Errors = failures
+	}
+	return result
+}
+
+// listOrgClusters returns the clusters of org which are managed by the aggregator.
+// If clusterNames is not empty, only the given clusters are returned and all of them must belong to the org.
+func (a *Aggregator) listOrgClusters(orgID string, clusterNames []string) ([]string, error) {
+	scopeID, err := strconv.ParseUint(orgID, 10, 64)
+	if err != nil {
+		return nil, apierrors.ErrInvoke.InvalidParameter(fmt.Sprintf("invalid org id %s, %v", orgID, err))
+	}
+	ctx := transport.WithHeader(a.Ctx, metadata.New(map[string]string{httputil.InternalHeader: "true"}))
+	resp, err := a.clusterSvc.ListCluster(ctx, &clusterpb.ListClusterRequest{OrgID: uint32(scopeID)})
+	if err != nil {
+		return nil, err
+	}
+
+	managed := make(map[string]bool)
+	for _, name := range a.GetAllClusters() {
+		managed[name] = true
+	}
+	var orgClusters []string
+	for _, c := range resp.Data {
+		if (c.Type == "k8s" || c.Type == "edas") && managed[c.Name] {
+			orgClusters = append(orgClusters, c.Name)
+		}
+	}
+	if len(clusterNames) == 0 {
+		return orgClusters, nil
+	}
+	var result []string
+	for _, name := range strutil.DedupSlice(clusterNames, true) {
+		if !strutil.Exist(orgClusters, name) {
+			return nil, apierrors.ErrInvoke.InvalidParameter(fmt.Sprintf("cluster %s not found in org %s", name, orgID))
+		}
+		result = append(result, name)
+	}
+	return result, nil
+}
+
+// FederatedBulk runs the action on all targets concurrently and returns the result of each target.
+// Failures of some targets do not stop others.
+func (a *Aggregator) FederatedBulk(ctx context.Context, req *apistructs.FederatedBulkRequest) (*apistructs.FederatedBulkData, error) {
+	if len(req.Targets) == 0 {
+		return nil, apierrors.ErrInvoke.MissingParameter("targets")
+	}
+	if len(req.Targets) > maxFederatedBulkTargets {
+		return nil, apierrors.ErrInvoke.InvalidParameter(fmt.Sprintf("too many targets, at most %d", maxFederatedBulkTargets))
+	}
+	var do func(ctx context.Context, target apistructs.FederatedTarget) error
+	switch req.Action {
+	case apistructs.FederatedActionRestartWorkload:
+		do = func(ctx context.Context, target apistructs.FederatedTarget) error {
+			return a.RestartWorkload(ctx, a.targetRequest(req, target, target.Type))
+		}
+	case apistructs.FederatedActionCordonNode:
+		do = func(ctx context.Context, target apistructs.FederatedTarget) error {
+			return a.CordonNode(ctx, a.targetRequest(req, target, apistructs.K8SNode))
+		}
+	case apistructs.FederatedActionUncordonNode:
+		do = func(ctx context.Context, target apistructs.FederatedTarget) error {
+			return a.UnCordonNode(ctx, a.targetRequest(req, target, apistructs.K8SNode))
+		}
+	case apistructs.FederatedActionDeletePod:
+		do = func(ctx context.Context, target apistructs.FederatedTarget) error {
+			if target.Namespace == "" {
+				return errors.New("namespace is required")
+			}
+			return a.DeleteSteveResource(ctx, a.targetRequest(req, target, apistructs.K8SPod))
+		}
+	default:
+		return nil, apierrors.ErrInvoke.InvalidParameter(fmt.Sprintf("unsupported action %s", req.Action))
+	}
+
+	results := make([]apistructs.FederatedBulkResult, len(req.Targets))
+	runConcurrently(len(req.Targets), maxFederatedConcurrency, func(i int) {
+		target := req.Targets[i]
+		results[i] = apistructs.FederatedBulkResult{FederatedTarget: target}
+		if target.ClusterName == "" || target.Name == "" {
+			results[i].Error = "clusterName and name are required"
+			return
+		}
+		cctx, cancel := context.WithTimeout(ctx, federatedClusterTimeout)
+		defer cancel()
+		if err := do(cctx, target); err != nil {
+			logrus.Errorf("failed to %s %s/%s in cluster %s, %v", req.Action, target.Namespace, target.Name, target.ClusterName, err)
+			results[i].Error = err.Error()
+			return
+		}
+		results[i].Success = true
+	})
+
+	data := &apistructs.FederatedBulkData{Results: results}
+	for _, r := range results {
+		if r.Success {
+			data.Succeeded++
+		} else {
+			data.Failed++
+		}
+	}
+	return data, nil
+}
+
+func (a *Aggregator) targetRequest(req *apistructs.FederatedBulkRequest, target apistructs.FederatedTarget,
+	typ apistructs.K8SResType) *apistructs.SteveRequest {
+	return &apistructs.SteveRequest{
+		UserID:      req.UserID,
+		OrgID:       req.OrgID,
+		Type:        typ,
+		ClusterName: target.ClusterName,
+		Namespace:   target.Namespace,
+		Name:        target.Name,
+	}
+}
+
+// RestartWorkload restarts a deployment, statefulSet or daemonSet by patching the restartedAt annotation
+// of pod template as the user, and creates an audit event.
+// Required fields: ClusterName, Type, Namespace, Name
+func (a *Aggregator) RestartWorkload(ctx context.Context, req *apistructs.SteveRequest) error {
+	if req.ClusterName == "" || req.Namespace == "" || req.Name == "" {
+		return apierrors.ErrInvoke.InvalidParameter(errors.New("clusterName, namespace and name fields are required"))
+	}
+	user, err := a.Auth(req.UserID, req.OrgID, req.ClusterName)
+	if err != nil {
+		return err
+	}
+	config, err := k8sclient.GetRestConfig(req.ClusterName)
+	if err != nil {
+		return errors.Errorf("failed to get rest config for cluster %s, %v", req.ClusterName, err)
+	}
+	config.Impersonate.UserName = user.GetName()
+	config.Impersonate.Groups = user.GetGroups()
+	config.Impersonate.Extra = user.GetExtra()
+	client, err := k8sclient.NewForRestConfig(config)
+	if err != nil {
+		return errors.Errorf("failed to get k8s client, %v", err)
+	}
+
+	patchBody := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{
+						"kubectl.kubernetes.io/restartedAt": time.Now().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	}
+	data, err := json.Marshal(patchBody)
+	if err != nil {
+		return errors.Errorf("failed to marshal body, %v", err)
+	}
+
+	apps := client.ClientSet.AppsV1()
+	switch req.Type {
+	case apistructs.K8SDeployment:
+		_, err = apps.Deployments(req.Namespace).Patch(ctx, req.Name, k8stypes.StrategicMergePatchType, data, metav1.PatchOptions{})
+	case apistructs.K8SStatefulSet:
+		_, err = apps.StatefulSets(req.Namespace).Patch(ctx, req.Name, k8stypes.StrategicMergePatchType, data, metav1.PatchOptions{})
+	case apistructs.K8SDaemonSet:
+		_, err = apps.DaemonSets(req.Namespace).Patch(ctx, req.Name, k8stypes.StrategicMergePatchType, data, metav1.PatchOptions{})
+	default:
+		return apierrors.ErrInvoke.InvalidParameter(fmt.Sprintf("invalid workload kind %s (only deployment, statefulSet and daemonSet can be restarted)", req.Type))
+	}
+	if err != nil {
+		return err
+	}
+
+	RemoveCache(req.ClusterName, "", string(req.Type))
+	RemoveCache(req.ClusterName, req.Namespace, string(req.Type))
+	RemoveCache(req.ClusterName, req.Namespace, string(apistructs.K8SPod))
+
+	auditCtx := map[string]interface{}{
+		middleware.AuditClusterName:  req.ClusterName,
+		middleware.AuditResourceName: req.Name,
+		middleware.AuditNamespace:    req.Namespace,
+		middleware.AuditResourceType: req.Type,
+	}
+	if err := a.Audit(req.UserID, req.OrgID, middleware.AuditRestartWorkload, auditCtx); err != nil {
+		logrus.Errorf("failed to audit for restarting workload, %v", err)
+	}
+	return nil
+}
+
+func convertFederatedResources(clusterName string, typ apistructs.K8SResType, objs []types.APIObject,
+	nameRegex *regexp.Regexp) []apistructs.FederatedResource {
+	var list []apistructs.FederatedResource
+	for _, obj := range objs {
+		data := obj.Data()
+		name := data.String("metadata", "name")
+		if nameRegex != nil && !nameRegex.MatchString(name) {
+			continue
+		}
+		list = append(list, apistructs.FederatedResource{
+			ClusterName: clusterName,
+			Type:        typ,
+			Namespace:   data.String("metadata", "namespace"),
+			Name:        name,
+			Object:      data,
+		})
+	}
+	return list
+}
+
+func sortResources(list []apistructs.FederatedResource) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].ClusterName != list[j].ClusterName {
+			return list[i].ClusterName < list[j].ClusterName
+		}
+		if list[i].Namespace != list[j].Namespace {
+			return list[i].Namespace < list[j].Namespace
+		}
+		return strings.Compare(list[i].Name, list[j].Name) < 0
+	})
+}
+
+func paginateResources(list []apistructs.FederatedResource, pageNo, pageSize int) []apistructs.FederatedResource {
+	if pageNo <= 0 {
+		pageNo = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultFederatedPage
+	}
+	start := (pageNo - 1) * pageSize
+	if start >= len(list) {
+		return []apistructs.FederatedResource{}
+	}
+	end := start + pageSize
+	if end > len(list) {
+		end = len(list)
+	}
+	return list[start:end]
+}
+
+// runConcurrently calls f with index 0 to n-1, at most limit calls run at the same time
+func runConcurrently(n, limit int, f func(i int)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, limit)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			f(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package steve
+
+import (
+	"regexp"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+func newTestObject(namespace, name string) types.APIObject {
+	return types.APIObject{
+		Object: map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"namespace": namespace,
+				"name":      name,
+			},
+		},
+	}
+}
+
+func TestConvertFederatedResources(t *testing.T) {
+	objs := []types.APIObject{
+		newTestObject("default", "web-1"),
+		newTestObject("default", "api-1"),
+		newTestObject("kube-system", "web-2"),
+	}
+	list := convertFederatedResources("c1", apistructs.K8SPod, objs, regexp.MustCompile("^web-"))
+	if len(list) != 2 {
+		t.Fatalf("expect 2 resources, got %d", len(list))
+	}
+	if list[1].ClusterName != "c1" || list[1].Namespace != "kube-system" || list[1].Name != "web-2" {
+		t.Errorf("unexpected resource %+v", list[1])
+	}
+	if len(convertFederatedResources("c1", apistructs.K8SPod, objs, nil)) != 3 {
+		t.Errorf("expect all resources without name regex")
+	}
+}
+
+func TestSortAndPaginateResources(t *testing.T) {
+	list := []apistructs.FederatedResource{
+		{ClusterName: "b", Namespace: "a", Name: "x"},
+		{ClusterName: "a", Namespace: "b", Name: "y"},
+		{ClusterName: "a", Namespace: "b", Name: "a"},
+		{ClusterName: "a", Namespace: "a", Name: "z"},
+	}
+	sortResources(list)
+	expected := []string{"a/a/z", "a/b/a", "a/b/y", "b/a/x"}
+	for i, r := range list {
+		if got := r.ClusterName + "/" + r.Namespace + "/" + r.Name; got != expected[i] {
+			t.Errorf("index %d, expect %s, got %s", i, expected[i], got)
+		}
+	}
+
+	if page := paginateResources(list, 2, 3); len(page) != 1 || page[0].ClusterName != "b" {
+		t.Errorf("unexpected page %+v", page)
+	}
+	if page := paginateResources(list, 3, 3); len(page) != 0 {
+		t.Errorf("expect empty page, got %+v", page)
+	}
+	if page := paginateResources(list, 0, 0); len(page) != 4 {
+		t.Errorf("expect default page contains all resources, got %d", len(page))
+	}
+}
+
+func TestRunConcurrently(t *testing.T) {
+	var (
+		count   int32
+		running int32
+		maxRun  int32
+	)
+	runConcurrently(50, 5, func(i int) {
+		cur := atomic.AddInt32(&running, 1)
+		for {
+			old := atomic.LoadInt32(&maxRun)
+			if cur <= old || atomic.CompareAndSwapInt32(&maxRun, old, cur) {
+				break
+			}
+		}
+		atomic.AddInt32(&count, 1)
+		atomic.AddInt32(&running, -1)
+	})
+	if count != 50 {
+		t.Errorf("expect 50 calls, got %d", count)
+	}
+	if maxRun > 5 {
+		t.Errorf("expect at most 5 concurrent calls, got %d", maxRun)
+	}
+}
+
+func TestFederatedCacheKey(t *testing.T) {
+	k1 := FederatedCacheKey{UserID: "1", OrgID: "1", Type: "pods", ClusterNames: []string{"a", "b"}}
+	k2 := FederatedCacheKey{UserID: "2", OrgID: "1", Type: "pods", ClusterNames: []string{"a", "b"}}
+	k3 := FederatedCacheKey{UserID: "1", OrgID: "1", Type: "pods", ClusterNames: []string{"a", "b"}}
+	if k1.GetKey() == k2.GetKey() {
+		t.Errorf("keys of different users should be different")
+	}
+	if k1.GetKey() != k3.GetKey() {
+		t.Errorf("keys of same request should be equal")
+	}
+}
@@ -0,0 +1,339 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bugaolengdeyuxiaoer/go-ansiterm"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/internal/apps/cmp/dbclient"
+	"github.com/erda-project/erda/internal/apps/cmp/steve/recording"
+	"github.com/erda-project/erda/pkg/crypto/uuid"
+	"github.com/erda-project/erda/pkg/http/httputil"
+)
+
+const (
+	channelStdin  = 0
+	channelStdout = 1
+	channelStderr = 2
+	channelResize = 4
+)
+
+// ShellSessionStore persists recorded shell sessions
+type ShellSessionStore interface {
+	CreateShellSession(s *dbclient.ShellSession) error
+}
+
+// SessionRecorder records kubectl-shell sessions in asciicast v2 format
+type SessionRecorder struct {
+	store       ShellSessionStore
+	maxSize     int
+	recordInput bool
+	redactor    *recording.Redactor
+}
+
+// RecorderOption configures a SessionRecorder
+type RecorderOption func(*SessionRecorder)
+
+// WithRecordMaxSize sets the max size of a single recording
+func WithRecordMaxSize(size int) RecorderOption {
+	return func(s *SessionRecorder) {
+		s.maxSize = size
+	}
+}
+
+// WithRecordInput sets whether keyboard input is recorded
+func WithRecordInput(recordInput bool) RecorderOption {
+	return func(s *SessionRecorder) {
+		s.recordInput = recordInput
+	}
+}
+
+// WithRecordRedactor sets the redactor applied on output and commands
+func WithRecordRedactor(redactor *recording.Redactor) RecorderOption {
+	return func(s *SessionRecorder) {
+		s.redactor = redactor
+	}
+}
+
+// NewSessionRecorder returns a SessionRecorder which saves sessions into store
+func NewSessionRecorder(store ShellSessionStore, options ...RecorderOption) *SessionRecorder {
+	s := &SessionRecorder{store: store}
+	for _, op := range options {
+		op(s)
+	}
+	if s.redactor == nil {
+		s.redactor, _ = recording.NewRedactor()
+	}
+	return s
+}
+
+// RecordMiddleware records the terminal stream of kubectl-shell requests
+func (s *SessionRecorder) RecordMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		vars, _ := req.Context().Value(varsKey).(map[string]string)
+		if vars["kubectl-shell"] == "" {
+			next.ServeHTTP(resp, req)
+			return
+		}
+		orgID, _ := strconv.ParseUint(req.Header.Get(httputil.OrgHeader), 10, 64)
+		clusterName := vars["clusterName"]
+		userID := req.Header.Get(httputil.UserHeader)
+
+		options := []recording.Option{
+			recording.WithInput(s.recordInput),
+			recording.WithRedactor(s.redactor),
+			recording.WithTitle(fmt.Sprintf("%s@%s", userID, clusterName)),
+		}
+		if s.maxSize > 0 {
+			options = append(options, recording.WithMaxSize(s.maxSize))
+		}
+		session := &dbclient.ShellSession{
+			ID:          uuid.New(),
+			OrgID:       orgID,
+			UserID:      userID,
+			ClusterName: clusterName,
+			ClientIP:    getRealIP(req),
+			UserAgent:   req.UserAgent(),
+		}
+		writer := &recordWriter{
+			ResponseWriter: resp,
+			ctx:            req.Context(),
+			store:          s.store,
+			session:        session,
+			recorder:       recording.NewRecorder(options...),
+		}
+		next.ServeHTTP(writer, req)
+	})
+}
+
+type recordWriter struct {
+	http.ResponseWriter
+	ctx      context.Context
+	store    ShellSessionStore
+	session  *dbclient.ShellSession
+	recorder *recording.Recorder
+}
+
+func (w *recordWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("upstream ResponseWriter of type %v does not implement http.Hijacker", reflect.TypeOf(w.ResponseWriter))
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	rc := newRecordConn(w.ctx, conn, w.store, w.session, w.recorder)
+	return rc, rw, nil
+}
+
+type recordConn struct {
+	net.Conn
+	store    ShellSessionStore
+	session  *dbclient.ShellSession
+	recorder *recording.Recorder
+
+	inputs  *recording.FrameReader
+	outputs *recording.FrameReader
+	parser  *ansiterm.AnsiParser
+	// read and write may happen in different goroutines
+	readLock   sync.Mutex
+	writeLock  sync.Mutex
+	closed     chan struct{}
+	closeOnce  sync.Once
+	finishOnce sync.Once
+}
+
+func newRecordConn(ctx context.Context, conn net.Conn, store ShellSessionStore, session *dbclient.ShellSession,
+	recorder *recording.Recorder) *recordConn {
+	cmdChan := make(chan *cmdWithTimestamp)
+	closeChan := make(chan struct{})
+	d := NewDispatcher(cmdChan, closeChan)
+	rc := &recordConn{
+		Conn:     conn,
+		store:    store,
+		session:  session,
+		recorder: recorder,
+		inputs:   recording.NewFrameReader(false),
+		outputs:  recording.NewFrameReader(true),
+		parser:   ansiterm.CreateParser("Ground", d),
+		closed:   make(chan struct{}),
+	}
+	go func() {
+		// the dispatcher sends commands synchronously, keep consuming until the connection is closed
+		ctxDone := ctx.Done()
+		for {
+			select {
+			case cmd := <-cmdChan:
+				rc.recorder.Command(cmd.cmd)
+			case <-closeChan:
+			case <-ctxDone:
+				rc.finish()
+				ctxDone = nil
+			case <-rc.closed:
+				return
+			}
+		}
+	}()
+	return rc
+}
+
+func (c *recordConn) Read(p []byte) (n int, err error) {
+	n, err = c.Conn.Read(p)
+	if n == 0 {
+		return n, err
+	}
+	c.readLock.Lock()
+	defer c.readLock.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("failed to record shell input, %v", r)
+		}
+	}()
+	for _, payload := range c.inputs.Feed(p[:n]) {
+		channel, data, ok := recording.DecodeChannelMessage(payload)
+		if !ok || len(data) == 0 {
+			continue
+		}
+		switch channel {
+		case channelStdin:
+			c.recorder.Input(data)
+			if c.isClosed() {
+				continue
+			}
+			if _, err := c.parser.Parse(data); err != nil {
+				logrus.Errorf("record message parse err: %v", err)
+			}
+		case channelResize:
+			var size struct {
+				Width  int
+				Height int
+			}
+			if json.Unmarshal(data, &size) == nil {
+				c.recorder.Resize(size.Width, size.Height)
+			}
+		}
+	}
+	return
+}
+
+func (c *recordConn) Write(p []byte) (n int, err error) {
+	n, err = c.Conn.Write(p)
+	if n == 0 {
+		return n, err
+	}
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("failed to record shell output, %v", r)
+		}
+	}()
+	for _, payload := range c.outputs.Feed(p[:n]) {
+		channel, data, ok := recording.DecodeChannelMessage(payload)
+		if !ok {
+			continue
+		}
+		if channel == channelStdout || channel == channelStderr {
+			c.recorder.Output(data)
+		}
+	}
+	return
+}
+
+func (c *recordConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() { close(c.closed) })
+	c.finish()
+	return err
+}
+
+func (c *recordConn) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// finish saves the session once, when the connection is closed or the request is done
+func (c *recordConn) finish() {
+	c.finishOnce.Do(func() {
+		c.recorder.Flush()
+		if c.recorder.Empty() {
+			return
+		}
+		if err := c.save(); err != nil {
+			logrus.Errorf("failed to save shell session %s of user %s, %v", c.session.ID, c.session.UserID, err)
+		}
+	})
+}
+
+func (c *recordConn) save() error {
+	data := c.recorder.Bytes()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	s := c.session
+	s.StartedAt = c.recorder.StartedAt()
+	s.EndedAt = s.StartedAt.Add(c.recorder.Duration())
+	s.Duration = c.recorder.Duration().Milliseconds()
+	s.Size = int64(len(data))
+	s.Commands = strings.Join(c.recorder.Commands(), "\n")
+	s.IsTruncated = c.recorder.Truncated()
+	s.Recording = buf.Bytes()
+	return c.store.CreateShellSession(s)
+}
+
+// ShellSessionCleaner deletes recorded sessions older than the retention
+type ShellSessionCleaner interface {
+	DeleteShellSessionsBefore(t time.Time) (int64, error)
+}
+
+// CleanShellSessions deletes the sessions which exceed retention
+func CleanShellSessions(cleaner ShellSessionCleaner, retention time.Duration) error {
+	if retention <= 0 {
+		return nil
+	}
+	n, err := cleaner.DeleteShellSessionsBefore(time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		logrus.Infof("deleted %d shell sessions older than %s", n, retention)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erda-project/erda/internal/apps/cmp/dbclient"
+	"github.com/erda-project/erda/internal/apps/cmp/steve/recording"
+)
+
+type fakeSessionStore struct {
+	sessions []*dbclient.ShellSession
+}
+
+func (f *fakeSessionStore) CreateShellSession(s *dbclient.ShellSession) error {
+	f.sessions = append(f.sessions, s)
+	return nil
+}
+
+type fakeConn struct {
+	net.Conn
+	in  *bytes.Buffer
+	out bytes.Buffer
+}
+
+func (f *fakeConn) Read(p []byte) (int, error) { return f.in.Read(p) }
+
+func (f *fakeConn) Write(p []byte) (int, error) { return f.out.Write(p) }
+
+func (f *fakeConn) Close() error { return nil }
+
+func textFrame(payload string, masked bool) []byte {
+	frame := []byte{0x81, byte(len(payload))}
+	data := []byte(payload)
+	if masked {
+		key := []byte{7, 7, 7, 7}
+		frame[1] |= 0x80
+		frame = append(frame, key...)
+		for i := range data {
+			data[i] ^= key[i&3]
+		}
+	}
+	return append(frame, data...)
+}
+
+func TestRecordConn(t *testing.T) {
+	input := textFrame("4"+base64.StdEncoding.EncodeToString([]byte(`{"Width":100,"Height":30}`)), true)
+	input = append(input, textFrame("0"+base64.StdEncoding.EncodeToString([]byte("mysql --password=abc\r")), true)...)
+	conn := &fakeConn{in: bytes.NewBuffer(input)}
+	store := &fakeSessionStore{}
+	session := &dbclient.ShellSession{ID: "1", UserID: "2", ClusterName: "dev"}
+	redactor, err := recording.NewRedactor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc := newRecordConn(context.Background(), conn, store, session, recording.NewRecorder(recording.WithRedactor(redactor)))
+
+	buf := make([]byte, 1024)
+	if _, err := rc.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	// commands are consumed asynchronously
+	for i := 0; i < 100 && len(rc.recorder.Commands()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := rc.Write([]byte("HTTP/1.1 101 Switching Protocols\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rc.Write(textFrame("1"+base64.StdEncoding.EncodeToString([]byte("token=xyz\r\n")), false)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+
+	if len(store.sessions) != 1 {
+		t.Fatalf("expect 1 session saved, got %d", len(store.sessions))
+	}
+	saved := store.sessions[0]
+	if saved.Commands != "mysql --password=******" {
+		t.Errorf("unexpected commands %q", saved.Commands)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(saved.Recording))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cast, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(cast)) != saved.Size {
+		t.Errorf("expect size %d, got %d", len(cast), saved.Size)
+	}
+	if !strings.Contains(string(cast), `"width":100`) || strings.Contains(string(cast), "xyz") {
+		t.Errorf("unexpected recording %s", cast)
+	}
+	if !strings.Contains(conn.out.String(), "101 Switching Protocols") {
+		t.Errorf("output is not forwarded")
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package recording records web shell sessions in asciicast v2 format, which can be replayed by asciinema player.
+// See https://github.com/asciinema/asciinema/blob/develop/doc/asciicast-v2.md
+package recording
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// EventOutput data written to the terminal
+	EventOutput = "o"
+	// EventInput data read from the keyboard
+	EventInput = "i"
+	// EventMarker marker, the label is the redacted command executed
+	EventMarker = "m"
+	// EventResize terminal resize, data is "{columns}x{rows}"
+	EventResize = "r"
+
+	defaultWidth   = 80
+	defaultHeight  = 24
+	defaultMaxSize = 10 << 20
+	// maxInputLine buffered input without a newline is recorded when exceeding the limit
+	maxInputLine = 4 << 10
+)
+
+// Header is the first line of an asciicast v2 file
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Recorder collects terminal events of one session
+type Recorder struct {
+	mu          sync.Mutex
+	header      Header
+	start       time.Time
+	last        time.Time
+	events      bytes.Buffer
+	size        int
+	maxSize     int
+	truncated   bool
+	recordInput bool
+	input       []byte
+	redactor    *Redactor
+	commands    []string
+	now         func() time.Time
+}
+
+// Option configures a Recorder
+type Option func(*Recorder)
+
+// WithMaxSize limits the size of recorded events, events exceeding the limit are dropped
+func WithMaxSize(size int) Option {
+	return func(r *Recorder) {
+		if size > 0 {
+			r.maxSize = size
+		}
+	}
+}
+
+// WithInput records keyboard input as well, input is buffered and redacted line by line.
+// Input is not recorded by default because secrets typed without a recognizable pattern, e.g. at a password prompt, are not redacted
+func WithInput(recordInput bool) Option {
+	return func(r *Recorder) {
+		r.recordInput = recordInput
+	}
+}
+
+// WithRedactor redacts sensitive content of output, input and commands
+func WithRedactor(redactor *Redactor) Option {
+	return func(r *Recorder) {
+		r.redactor = redactor
+	}
+}
+
+// WithTitle sets title of the recording
+func WithTitle(title string) Option {
+	return func(r *Recorder) {
+		r.header.Title = title
+	}
+}
+
+func withClock(now func() time.Time) Option {
+	return func(r *Recorder) {
+		r.now = now
+	}
+}
+
+// NewRecorder creates a Recorder, the session starts when it is created
+func NewRecorder(options ...Option) *Recorder {
+	r := &Recorder{
+		header: Header{
+			Version: 2,
+			Width:   defaultWidth,
+			Height:  defaultHeight,
+			Env:     map[string]string{"TERM": "xterm-256color", "SHELL": "/bin/bash"},
+		},
+		maxSize: defaultMaxSize,
+		now:     time.Now,
+	}
+	for _, op := range options {
+		op(r)
+	}
+	r.start = r.now()
+	r.last = r.start
+	r.header.Timestamp = r.start.Unix()
+	return r
+}
+
+// Output records data written to the terminal
+func (r *Recorder) Output(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	r.append(EventOutput, r.redact(string(data)))
+}
+
+// Input records data read from the keyboard if input recording is enabled.
+// Keystrokes are buffered until a line is completed, so that secrets typed in several chunks can be redacted
+func (r *Recorder) Input(data []byte) {
+	if !r.recordInput || len(data) == 0 {
+		return
+	}
+	r.mu.Lock()
+	r.input = append(r.input, data...)
+	end := bytes.LastIndexAny(r.input, "\r\n") + 1
+	if end == 0 && len(r.input) >= maxInputLine {
+		end = len(r.input)
+	}
+	line := string(r.input[:end])
+	r.input = append(r.input[:0], r.input[end:]...)
+	r.mu.Unlock()
+	if line != "" {
+		r.append(EventInput, r.redact(line))
+	}
+}
+
+// Flush records the buffered input which has not been completed by a newline
+func (r *Recorder) Flush() {
+	r.mu.Lock()
+	line := string(r.input)
+	r.input = nil
+	r.mu.Unlock()
+	if line != "" {
+		r.append(EventInput, r.redact(line))
+	}
+}
+
+// Command records an executed command as a marker
+func (r *Recorder) Command(cmd string) {
+	if cmd == "" {
+		return
+	}
+	cmd = r.redact(cmd)
+	r.mu.Lock()
+	r.commands = append(r.commands, cmd)
+	r.mu.Unlock()
+	r.append(EventMarker, cmd)
+}
+
+// Resize records terminal size change, the first resize before any output updates the header
+func (r *Recorder) Resize(width, height int) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	r.mu.Lock()
+	if r.size == 0 {
+		r.header.Width, r.header.Height = width, height
+		r.mu.Unlock()
+		return
+	}
+	r.mu.Unlock()
+	r.append(EventResize, fmt.Sprintf("%dx%d", width, height))
+}
+
+func (r *Recorder) redact(s string) string {
+	if r.redactor == nil {
+		return s
+	}
+	return r.redactor.Redact(s)
+}
+
+func (r *Recorder) append(typ, data string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.truncated {
+		return
+	}
+	now := r.now()
+	b, err := json.Marshal([]interface{}{now.Sub(r.start).Seconds(), typ, data})
+	if err != nil {
+		return
+	}
+	if r.size+len(b)+1 > r.maxSize {
+		r.truncated = true
+		return
+	}
+	r.events.Write(b)
+	r.events.WriteByte('\n')
+	r.size += len(b) + 1
+	r.last = now
+}
+
+// Bytes returns the asciicast v2 content
+func (r *Recorder) Bytes() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	header, _ := json.Marshal(r.header)
+	var buf bytes.Buffer
+	buf.Grow(len(header) + 1 + r.events.Len())
+	buf.Write(header)
+	buf.WriteByte('\n')
+	buf.Write(r.events.Bytes())
+	return buf.Bytes()
+}
+
+// Commands returns the redacted commands executed in the session
+func (r *Recorder) Commands() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.commands...)
+}
+
+// StartedAt returns the start time of the session
+func (r *Recorder) StartedAt() time.Time {
+	return r.start
+}
+
+// Duration returns the time between session start and the last event
+func (r *Recorder) Duration() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last.Sub(r.start)
+}
+
+// Truncated reports whether events were dropped because of size limit
+func (r *Recorder) Truncated() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.truncated
+}
+
+// Empty reports whether nothing has been recorded
+func (r *Recorder) Empty() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.size == 0
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recording
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+)
+
+const (
+	finalBit   = 1 << 7
+	maskBit    = 1 << 7
+	opcodeMask = 0x0f
+	// control frames (close, ping, pong) have the highest bit of opcode set
+	controlOpcode = 0x08
+
+	// maxPendingSize drops buffered data if a frame never completes
+	maxPendingSize = 16 << 20
+)
+
+var httpHeaderEnd = []byte("\r\n\r\n")
+
+// FrameReader incrementally decodes websocket frames from a raw byte stream.
+// Data can be fed in arbitrary chunks, incomplete frames are buffered until the rest arrives.
+type FrameReader struct {
+	buf        []byte
+	skipHeader bool
+}
+
+// NewFrameReader creates a FrameReader.
+// If skipHTTPHeader is true, the leading http upgrade response of the stream is skipped.
+func NewFrameReader(skipHTTPHeader bool) *FrameReader {
+	return &FrameReader{skipHeader: skipHTTPHeader}
+}
+
+// Feed appends p to the stream and returns payloads of all completed data frames
+func (f *FrameReader) Feed(p []byte) [][]byte {
+	f.buf = append(f.buf, p...)
+	if f.skipHeader {
+		if !bytes.HasPrefix(f.buf, []byte("HTTP/")) && len(f.buf) >= 5 {
+			f.skipHeader = false
+		} else if idx := bytes.Index(f.buf, httpHeaderEnd); idx >= 0 {
+			f.buf = f.buf[idx+len(httpHeaderEnd):]
+			f.skipHeader = false
+		} else {
+			f.truncate()
+			return nil
+		}
+	}
+
+	var payloads [][]byte
+	for {
+		payload, opcode, n := parseFrame(f.buf)
+		if n == 0 {
+			break
+		}
+		f.buf = f.buf[n:]
+		if opcode&controlOpcode == 0 {
+			payloads = append(payloads, payload)
+		}
+	}
+	if len(f.buf) == 0 {
+		f.buf = nil
+	}
+	f.truncate()
+	return payloads
+}
+
+func (f *FrameReader) truncate() {
+	if len(f.buf) > maxPendingSize {
+		f.buf = nil
+	}
+}
+
+// parseFrame parses one frame from data, n is 0 if the frame is incomplete
+func parseFrame(data []byte) (payload []byte, opcode byte, n int) {
+	if len(data) < 2 {
+		return nil, 0, 0
+	}
+	opcode = data[0] & opcodeMask
+	masked := data[1]&maskBit != 0
+	length := uint64(data[1] & 0x7f)
+	pos := 2
+	switch length {
+	case 126:
+		if len(data) < pos+2 {
+			return nil, 0, 0
+		}
+		length = uint64(binary.BigEndian.Uint16(data[pos:]))
+		pos += 2
+	case 127:
+		if len(data) < pos+8 {
+			return nil, 0, 0
+		}
+		length = binary.BigEndian.Uint64(data[pos:])
+		pos += 8
+	}
+	var key []byte
+	if masked {
+		if len(data) < pos+4 {
+			return nil, 0, 0
+		}
+		key = data[pos : pos+4]
+		pos += 4
+	}
+	if length > uint64(len(data)-pos) {
+		return nil, 0, 0
+	}
+	end := pos + int(length)
+	payload = make([]byte, length)
+	copy(payload, data[pos:end])
+	for i := range payload {
+		if masked {
+			payload[i] ^= key[i&3]
+		}
+	}
+	return payload, opcode, end
+}
+
+// DecodeChannelMessage decodes a message of the kubernetes remote command stream protocol.
+// Both base64.channel.k8s.io (channel as ascii digit and base64 data) and channel.k8s.io
+// (channel as raw byte) are supported. Channel 0 is stdin, 1 stdout, 2 stderr and 4 resize.
+func DecodeChannelMessage(payload []byte) (channel byte, data []byte, ok bool) {
+	if len(payload) == 0 {
+		return 0, nil, false
+	}
+	if payload[0] < '0' {
+		return payload[0], payload[1:], true
+	}
+	if payload[0] > '9' {
+		return 0, nil, false
+	}
+	var out []byte
+	// several messages may be joined by newline, each carries its own channel prefix
+	for _, line := range bytes.Split(payload, []byte("\n")) {
+		if len(line) < 2 {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(string(line[1:]))
+		if err != nil {
+			return 0, nil, false
+		}
+		out = append(out, decoded...)
+	}
+	return payload[0] - '0', out, true
+}
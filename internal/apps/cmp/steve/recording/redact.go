@@ -0,0 +1,86 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recording
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Mask replaces the redacted content
+const Mask = "******"
+
+// DefaultRedactPatterns redact common credentials in commands and terminal output.
+// If a pattern has capture groups, only the last group is masked, otherwise the whole match is masked.
+var DefaultRedactPatterns = []string{
+	`(?i)\b(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key(?:[_-]?id)?)\s*[=:]\s*("[^"]*"|'[^']*'|[^\s"';&|]+)`,
+	`(?i)--(?:password|token|client-key-data|from-literal=[\w.-]+)[= ]("[^"]*"|'[^']*'|[^\s"';&|]+)`,
+	`(?i)\bauthorization:\s*(?:bearer|basic)\s+([\w\-.~+/=]+)`,
+	`(?i)://[^\s:/@]+:([^\s@/]+)@`,
+	`\bAKIA[0-9A-Z]{16}\b`,
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+}
+
+// Redactor masks sensitive content by regular expressions
+type Redactor struct {
+	rules []*regexp.Regexp
+}
+
+// NewRedactor compiles the patterns, DefaultRedactPatterns are always included
+func NewRedactor(patterns ...string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, p := range append(append([]string{}, DefaultRedactPatterns...), patterns...) {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid redact pattern %q", p)
+		}
+		r.rules = append(r.rules, re)
+	}
+	return r, nil
+}
+
+// Redact returns s with all sensitive content masked
+func (r *Redactor) Redact(s string) string {
+	for _, re := range r.rules {
+		s = redact(re, s)
+	}
+	return s
+}
+
+func redact(re *regexp.Regexp, s string) string {
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if n := len(m); n > 2 && m[n-2] >= 0 {
+			start, end = m[n-2], m[n-1]
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(Mask)
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
@@ -148,3 +148,15 @@ func (i IssueMock) BatchGetIssuePropertyInstances(orgID int64, issueType string,
 func (i IssueMock) BatchGetIssue(id []int64, identityInfo *commonpb.IdentityInfo) ([]*issuepb.Issue, error) {
 	panic("implement me")
 }
+
+func (i IssueMock) QueryIssues(req *apistructs.IssueQueryRequest) ([]*issuepb.Issue, uint64, error) {
+	panic("implement me")
+}
+
+func (i IssueMock) QueryIssueStats(req *apistructs.IssueQueryStatsRequest) ([]apistructs.IssueQueryStatsItem, error) {
+	panic("implement me")
+}
+
+func (i IssueMock) MatchIssue(projectID, issueID uint64, ql string) (bool, error) {
+	panic("implement me")
+}
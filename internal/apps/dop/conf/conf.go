@@ -78,6 +78,9 @@ type Conf struct {
 	ExportIssueFileStoreDay     int    `env:"EXPORT_ISSUE_FILE_STORE_DAY" default:"7"`
 	UpdateGuideExpiryStatusCron string `env:"UPDATE_GUIDE_EXPIRY_STATUS_CRON" default:"0 0/5 * * * ?"`
 
+	IssueSyncPollInterval        time.Duration `env:"ISSUE_SYNC_POLL_INTERVAL" default:"5m"`
+	IssueSyncAllowPrivateNetwork bool          `env:"ISSUE_SYNC_ALLOW_PRIVATE_NETWORK" default:"false"`
+
 	PipelineGrpcClientMaxCallSendSizeBytes int `env:"PIPELINE_GRPC_CLIENT_MAX_SEND_SIZE_BYTES" default:"0"`
 
 	GittarPublicURL string `env:"GITTAR_PUBLIC_URL"`
@@ -272,6 +275,16 @@ func ExportIssueFileStoreDay() int {
 	return cfg.ExportIssueFileStoreDay
 }
 
+// IssueSyncPollInterval 轮询 Jira/GitHub 同步事件的周期
+func IssueSyncPollInterval() time.Duration {
+	return cfg.IssueSyncPollInterval
+}
+
+// IssueSyncAllowPrivateNetwork 是否允许同步连接的外部系统地址指向内网
+func IssueSyncAllowPrivateNetwork() bool {
+	return cfg.IssueSyncAllowPrivateNetwork
+}
+
 func UpdateGuideExpiryStatusCron() string {
 	return cfg.UpdateGuideExpiryStatusCron
 }
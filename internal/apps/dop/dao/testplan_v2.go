@@ -15,6 +15,8 @@
 package dao
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"time"
 
 	"github.com/jinzhu/gorm"
@@ -43,6 +45,47 @@ type TestPlanV2 struct {
 	TotalApiNum   int64
 	ExecuteRate   float64
 	CostTimeSec   int64
+	Type          apistructs.TestPlanV2Type
+	LoadConfig    *TestPlanV2LoadConfig
+}
+
+// TestPlanV2LoadConfig load config stored as json
+type TestPlanV2LoadConfig apistructs.TestPlanV2LoadConfig
+
+func (config TestPlanV2LoadConfig) Value() (driver.Value, error) {
+	if b, err := json.Marshal(config); err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal load config")
+	} else {
+		return string(b), nil
+	}
+}
+
+func (config *TestPlanV2LoadConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	v, ok := value.([]byte)
+	if !ok {
+		return errors.New("invalid scan source for load config")
+	}
+	if len(v) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(v, config); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal load config")
+	}
+	return nil
+}
+
+func (tp *TestPlanV2) convertLoad(dto *apistructs.TestPlanV2) {
+	dto.Type = tp.Type
+	if dto.Type == "" {
+		dto.Type = apistructs.TestPlanV2TypeDefault
+	}
+	if tp.LoadConfig != nil {
+		loadConfig := apistructs.TestPlanV2LoadConfig(*tp.LoadConfig)
+		dto.LoadConfig = &loadConfig
+	}
 }
 
 // TableName table name
@@ -52,7 +95,7 @@ func (TestPlanV2) TableName() string {
 
 // Convert2DTO convert DAO to DTO
 func (tp *TestPlanV2) Convert2DTO() apistructs.TestPlanV2 {
-	return apistructs.TestPlanV2{
+	dto := apistructs.TestPlanV2{
 		ID:            tp.ID,
 		Name:          tp.Name,
 		Desc:          tp.Desc,
@@ -70,6 +113,8 @@ func (tp *TestPlanV2) Convert2DTO() apistructs.TestPlanV2 {
 		SuccessApiNum: tp.SuccessApiNum,
 		TotalApiNum:   tp.TotalApiNum,
 	}
+	tp.convertLoad(&dto)
+	return dto
 }
 
 // TestPlanV2Join join dice_autotest_space
@@ -81,7 +126,7 @@ type TestPlanV2Join struct {
 
 // Convert2DTO convert DAO to DTO
 func (tp TestPlanV2Join) Convert2DTO() *apistructs.TestPlanV2 {
-	return &apistructs.TestPlanV2{
+	dto := &apistructs.TestPlanV2{
 		ID:            tp.ID,
 		Name:          tp.Name,
 		Desc:          tp.Desc,
@@ -100,6 +145,8 @@ func (tp TestPlanV2Join) Convert2DTO() *apistructs.TestPlanV2 {
 		SuccessApiNum: tp.SuccessApiNum,
 		TotalApiNum:   tp.TotalApiNum,
 	}
+	tp.convertLoad(dto)
+	return dto
 }
 
 // CreateTestPlanV2 Create test plan
@@ -148,7 +195,7 @@ func (client *DBClient) PagingTestPlanV2(req *apistructs.TestPlanV2PagingRequest
 		"dice_autotest_plan.pass_rate, "+"dice_autotest_plan.execute_time, "+"dice_autotest_plan.execute_api_num, "+
 		"dice_autotest_plan.success_api_num,dice_autotest_plan.total_api_num,"+
 		"dice_iterations.title AS iteration_name,"+
-		"dice_autotest_space.name as space_name, "+"dice_autotest_plan.is_archived").
+		"dice_autotest_space.name as space_name, "+"dice_autotest_plan.is_archived, dice_autotest_plan.type").
 		Joins("inner join dice_autotest_space on dice_autotest_plan.space_id = dice_autotest_space.id").
 		Joins("LEFT join dice_iterations on dice_autotest_plan.iteration_id = dice_iterations.id").
 		Where("dice_autotest_plan.project_id = ?", req.ProjectID)
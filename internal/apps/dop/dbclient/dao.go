@@ -63,6 +63,22 @@ func FindTPRecordByCommitId(commitID string) (*TPRecordDO, error) {
 	return r, nil
 }
 
+func FindTPRecordByUUID(uuid string) (*TPRecordDO, error) {
+	r := NewTPRecordDO()
+	r.UUID = uuid
+
+	success, err := cimysql.Engine.Desc("id").Get(r)
+	if err != nil {
+		return nil, errors.Errorf("failed to find record, uuid: %s, (%+v)", uuid, err)
+	}
+
+	if !success {
+		return nil, errors.Errorf("failed to find record, uuid: %s", uuid)
+	}
+
+	return r, nil
+}
+
 func FindTPRecordPagingByAppID(req *pb.TestRecordPagingRequest) (*Paging, error) {
 	var list []*TPRecordDO
 	total, err := cimysql.Engine.Select("id,name,branch,operator_name,totals,type,created_at,coverage_report").Where("app_id = ?", req.ApplicationId).
@@ -0,0 +1,252 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbclient
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/database/cimysql"
+)
+
+// QAFlakyTest 单元测试用例稳定性统计, 对应数据库表 qa_flaky_tests
+type QAFlakyTest struct {
+	ID        uint64    `xorm:"pk autoincr 'id'"`
+	CreatedAt time.Time `xorm:"created"`
+	UpdatedAt time.Time `xorm:"updated"`
+
+	ProjectID        int64     `xorm:"project_id"`
+	ApplicationID    int64     `xorm:"app_id"`
+	TestKey          string    `xorm:"test_key"`
+	Suite            string    `xorm:"suite"`
+	Classname        string    `xorm:"classname"`
+	Name             string    `xorm:"name"`
+	TotalRuns        int64     `xorm:"total_runs"`
+	FailedRuns       int64     `xorm:"failed_runs"`
+	Flips            int64     `xorm:"flips"`
+	CommitFlips      int64     `xorm:"commit_flips"`
+	Flakiness        float64   `xorm:"flakiness"`
+	IsFlaky          bool      `xorm:"is_flaky"`
+	LastStatus       string    `xorm:"last_status"`
+	LastCommitID     string    `xorm:"last_commit_id"`
+	LastRecordID     uint64    `xorm:"last_record_id"`
+	LastRunAt        time.Time `xorm:"last_run_at"`
+	Quarantined      bool      `xorm:"quarantined"`
+	QuarantinedBy    string    `xorm:"quarantined_by"`
+	QuarantineReason string    `xorm:"quarantine_reason"`
+}
+
+func (QAFlakyTest) TableName() string {
+	return "qa_flaky_tests"
+}
+
+func (t *QAFlakyTest) ToAPI() *apistructs.FlakyTest {
+	ft := &apistructs.FlakyTest{
+		ID:               t.ID,
+		ProjectID:        t.ProjectID,
+		ApplicationID:    t.ApplicationID,
+		Suite:            t.Suite,
+		Classname:        t.Classname,
+		Name:             t.Name,
+		TotalRuns:        t.TotalRuns,
+		FailedRuns:       t.FailedRuns,
+		Flips:            t.Flips,
+		CommitFlips:      t.CommitFlips,
+		Flakiness:        t.Flakiness,
+		IsFlaky:          t.IsFlaky,
+		LastStatus:       t.LastStatus,
+		LastCommitID:     t.LastCommitID,
+		LastRecordID:     t.LastRecordID,
+		LastRunAt:        t.LastRunAt,
+		Quarantined:      t.Quarantined,
+		QuarantinedBy:    t.QuarantinedBy,
+		QuarantineReason: t.QuarantineReason,
+		UpdatedAt:        t.UpdatedAt,
+	}
+	if t.TotalRuns > 0 {
+		ft.FailureRate = float64(t.FailedRuns) / float64(t.TotalRuns)
+	}
+	return ft
+}
+
+// QAFlakyTestDaily 单元测试用例每日执行统计, 对应数据库表 qa_flaky_test_daily
+type QAFlakyTestDaily struct {
+	ID        uint64    `xorm:"pk autoincr 'id'"`
+	CreatedAt time.Time `xorm:"created"`
+	UpdatedAt time.Time `xorm:"updated"`
+
+	ApplicationID int64  `xorm:"app_id"`
+	FlakyTestID   uint64 `xorm:"flaky_test_id"`
+	Day           string `xorm:"day"`
+	Runs          int64  `xorm:"runs"`
+	FailedRuns    int64  `xorm:"failed_runs"`
+	Flips         int64  `xorm:"flips"`
+	CommitFlips   int64  `xorm:"commit_flips"`
+}
+
+func (QAFlakyTestDaily) TableName() string {
+	return "qa_flaky_test_daily"
+}
+
+// FindFlakyTestsByKeys 查询应用下指定用例的统计数据, 以 test_key 为索引返回
+func FindFlakyTestsByKeys(appID int64, keys []string) (map[string]*QAFlakyTest, error) {
+	result := make(map[string]*QAFlakyTest, len(keys))
+	// 分批查询, 避免 in 条件过长
+	const batch = 500
+	for start := 0; start < len(keys); start += batch {
+		end := start + batch
+		if end > len(keys) {
+			end = len(keys)
+		}
+		var list []*QAFlakyTest
+		if err := cimysql.Engine.Where("app_id = ?", appID).In("test_key", keys[start:end]).Find(&list); err != nil {
+			return nil, errors.Wrapf(err, "find flaky tests, appID: %d", appID)
+		}
+		for _, t := range list {
+			result[t.TestKey] = t
+		}
+	}
+	return result, nil
+}
+
+// SaveFlakyTests 在同一事务中保存用例统计及当日趋势, tests 与 dailies 按下标一一对应
+func SaveFlakyTests(tests []*QAFlakyTest, dailies []*QAFlakyTestDaily) (err error) {
+	if len(tests) != len(dailies) {
+		return errors.Errorf("mismatched flaky tests and dailies, %d != %d", len(tests), len(dailies))
+	}
+	session := cimysql.Engine.NewSession()
+	defer session.Close()
+	if err = session.Begin(); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = session.Rollback()
+			return
+		}
+		err = session.Commit()
+	}()
+
+	for i, t := range tests {
+		if t.ID == 0 {
+			if _, err = session.InsertOne(t); err != nil {
+				return errors.Wrapf(err, "insert flaky test %s", t.Name)
+			}
+		} else if _, err = session.ID(t.ID).AllCols().Omit("created_at", "quarantined", "quarantined_by", "quarantine_reason").Update(t); err != nil {
+			return errors.Wrapf(err, "update flaky test %d", t.ID)
+		}
+
+		d := dailies[i]
+		d.FlakyTestID = t.ID
+		d.ApplicationID = t.ApplicationID
+		var (
+			existed QAFlakyTestDaily
+			ok      bool
+		)
+		ok, err = session.Where("flaky_test_id = ? AND day = ?", d.FlakyTestID, d.Day).Get(&existed)
+		if err != nil {
+			return errors.Wrapf(err, "get flaky test daily %d", d.FlakyTestID)
+		}
+		if !ok {
+			if _, err = session.InsertOne(d); err != nil {
+				return errors.Wrapf(err, "insert flaky test daily %d", d.FlakyTestID)
+			}
+			continue
+		}
+		existed.Runs += d.Runs
+		existed.FailedRuns += d.FailedRuns
+		existed.Flips += d.Flips
+		existed.CommitFlips += d.CommitFlips
+		if _, err = session.ID(existed.ID).Cols("runs", "failed_runs", "flips", "commit_flips").Update(&existed); err != nil {
+			return errors.Wrapf(err, "update flaky test daily %d", existed.ID)
+		}
+	}
+	return nil
+}
+
+// PagingFlakyTests 分页查询应用下的用例稳定性统计
+func PagingFlakyTests(req apistructs.FlakyTestListRequest) ([]*QAFlakyTest, int64, error) {
+	sql := cimysql.Engine.Where("app_id = ?", req.ApplicationID)
+	if req.OnlyFlaky {
+		sql = sql.And("is_flaky = ?", true)
+	}
+	if req.Quarantined != nil {
+		sql = sql.And("quarantined = ?", *req.Quarantined)
+	}
+	if req.Name != "" {
+		sql = sql.And("name LIKE ?", "%"+req.Name+"%")
+	}
+	switch req.OrderBy {
+	case "failureRate":
+		sql = sql.OrderBy("failed_runs / total_runs DESC")
+	case "lastRunAt":
+		sql = sql.Desc("last_run_at")
+	default:
+		sql = sql.Desc("flakiness")
+	}
+
+	var list []*QAFlakyTest
+	total, err := sql.Desc("id").Limit(req.PageSize, (req.PageNo-1)*req.PageSize).FindAndCount(&list)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "paging flaky tests, appID: %d", req.ApplicationID)
+	}
+	return list, total, nil
+}
+
+func GetFlakyTest(id uint64) (*QAFlakyTest, error) {
+	var t QAFlakyTest
+	ok, err := cimysql.Engine.ID(id).Get(&t)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get flaky test %d", id)
+	}
+	if !ok {
+		return nil, errors.Errorf("flaky test %d not found", id)
+	}
+	return &t, nil
+}
+
+// UpdateFlakyTestQuarantine 更新用例隔离状态
+func UpdateFlakyTestQuarantine(t *QAFlakyTest) error {
+	_, err := cimysql.Engine.ID(t.ID).Cols("quarantined", "quarantined_by", "quarantine_reason").Update(t)
+	return err
+}
+
+// ListQuarantinedTestKeys 返回应用下所有已隔离用例的 test_key
+func ListQuarantinedTestKeys(appID int64) (map[string]bool, error) {
+	var list []*QAFlakyTest
+	if err := cimysql.Engine.Cols("test_key").Where("app_id = ? AND quarantined = ?", appID, true).Find(&list); err != nil {
+		return nil, errors.Wrapf(err, "list quarantined tests, appID: %d", appID)
+	}
+	keys := make(map[string]bool, len(list))
+	for _, t := range list {
+		keys[t.TestKey] = true
+	}
+	return keys, nil
+}
+
+// ListFlakyTestDaily 查询 since 之后的每日趋势, testID 为 0 时返回应用下所有用例
+func ListFlakyTestDaily(appID int64, testID uint64, since string) ([]*QAFlakyTestDaily, error) {
+	sql := cimysql.Engine.Where("app_id = ? AND day >= ?", appID, since)
+	if testID > 0 {
+		sql = sql.And("flaky_test_id = ?", testID)
+	}
+	var list []*QAFlakyTestDaily
+	if err := sql.Asc("day").Find(&list); err != nil {
+		return nil, errors.Wrapf(err, "list flaky test daily, appID: %d", appID)
+	}
+	return list, nil
+}
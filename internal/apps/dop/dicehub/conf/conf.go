@@ -30,6 +30,7 @@ type Conf struct {
 	MaxTimeReserved string              `env:"RELEASE_MAX_TIME_RESERVED" default:"72"` // default: 72h
 	ExtensionMenu   map[string][]string `env:"EXTENSION_MENU" default:"{}"`
 	SiteUrl         string              `env:"SITE_URL"`
+	SBOMGenerator   string              `env:"RELEASE_SBOM_GENERATOR"` // eg: syft {image} -o cyclonedx-json
 }
 
 // Load 加载环境变量配置.
@@ -70,3 +71,8 @@ func ExtensionMenu() map[string][]string {
 func MonitorAddr() string {
 	return cfg.MonitorAddr
 }
+
+// SBOMGenerator 生成镜像 SBOM 的命令，为空时不生成
+func SBOMGenerator() string {
+	return cfg.SBOMGenerator
+}
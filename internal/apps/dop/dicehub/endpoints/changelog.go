@@ -0,0 +1,102 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/apierrors"
+	"github.com/erda-project/erda/internal/pkg/user"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+)
+
+// GenerateReleaseChangelog POST /api/releases/{releaseId}/changelog/actions/generate 生成项目制品 changelog
+func (e *Endpoints) GenerateReleaseChangelog(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identity, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrGenerateReleaseChangelog.NotLogin().ToResp(), nil
+	}
+	var req apistructs.ReleaseChangelogGenerateRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return apierrors.ErrGenerateReleaseChangelog.InvalidParameter(err).ToResp(), nil
+		}
+	}
+	// 仅预览时只需读权限，保存到制品需要写权限
+	if _, apiErr := e.checkReleaseSBOMAccess(r, vars["releaseId"], req.Save, apierrors.ErrGenerateReleaseChangelog); apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	req.ReleaseID = vars["releaseId"]
+	req.UserID = identity.UserID
+	data, apiErr := e.changelog.Generate(&req)
+	if apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	return httpserver.OkResp(data)
+}
+
+// DownloadReleaseChangelog GET /api/releases/{releaseId}/changelog 下载项目制品 changelog markdown
+func (e *Endpoints) DownloadReleaseChangelog(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	release, apiErr := e.checkReleaseSBOMAccess(r, vars["releaseId"], false, apierrors.ErrGetReleaseChangelog)
+	if apiErr != nil {
+		return apiErr
+	}
+	identity, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrGetReleaseChangelog.NotLogin()
+	}
+	markdown, apiErr := e.changelog.Markdown(release.ReleaseID, identity.UserID)
+	if apiErr != nil {
+		return apiErr
+	}
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment;fileName=%s_%s_CHANGELOG.md", release.ProjectName, release.Version))
+	_, err = w.Write([]byte(markdown))
+	return err
+}
+
+// SuggestReleaseVersion POST /api/releases/actions/suggest-version 根据应用制品获取项目制品建议版本号及 changelog 预览
+func (e *Endpoints) SuggestReleaseVersion(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identity, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrSuggestReleaseVersion.NotLogin().ToResp(), nil
+	}
+	var req apistructs.ReleaseVersionSuggestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierrors.ErrSuggestReleaseVersion.InvalidParameter(err).ToResp(), nil
+	}
+	if req.ProjectID == 0 {
+		return apierrors.ErrSuggestReleaseVersion.MissingParameter("projectId").ToResp(), nil
+	}
+	if !identity.IsInternalClient() {
+		hasAccess, err := e.hasReadAccess(identity, req.ProjectID)
+		if err != nil {
+			return apierrors.ErrSuggestReleaseVersion.InternalError(err).ToResp(), nil
+		}
+		if !hasAccess {
+			return apierrors.ErrSuggestReleaseVersion.AccessDenied().ToResp(), nil
+		}
+	}
+	req.UserID = identity.UserID
+	data, apiErr := e.changelog.SuggestVersion(&req)
+	if apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	return httpserver.OkResp(data)
+}
@@ -22,8 +22,10 @@ import (
 
 	"github.com/erda-project/erda/bundle"
 	"github.com/erda-project/erda/internal/apps/dop/dicehub/dbclient"
+	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/changelog"
 	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/release"
 	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/release_rule"
+	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/sbom"
 	"github.com/erda-project/erda/internal/core/org"
 	"github.com/erda-project/erda/pkg/http/httpserver"
 )
@@ -34,6 +36,8 @@ type Endpoints struct {
 	bdl                *bundle.Bundle
 	release            *release.Release
 	releaseRule        *release_rule.ReleaseRule
+	sbom               *sbom.SBOM
+	changelog          *changelog.Changelog
 	queryStringDecoder *schema.Decoder
 	org                org.Interface
 }
@@ -78,6 +82,20 @@ func WithReleaseRule(rule *release_rule.ReleaseRule) Option {
 	}
 }
 
+// WithSBOM 配置 sbom service
+func WithSBOM(sb *sbom.SBOM) Option {
+	return func(e *Endpoints) {
+		e.sbom = sb
+	}
+}
+
+// WithChangelog 配置 changelog service
+func WithChangelog(cl *changelog.Changelog) Option {
+	return func(e *Endpoints) {
+		e.changelog = cl
+	}
+}
+
 // WithQueryStringDecoder 配置 queryStringDecoder
 func WithQueryStringDecoder(decoder *schema.Decoder) Option {
 	return func(e *Endpoints) {
@@ -112,5 +130,21 @@ func (e *Endpoints) Routes() []httpserver.Endpoint {
 		{Path: "/api/release-rules", Method: http.MethodGet, Handler: httpserver.Wrap(e.ListRules, e.ReleaseRuleMiddleware)},
 		{Path: "/api/release-rules/{id}", Method: http.MethodPut, Handler: httpserver.Wrap(e.UpdateRule, e.ReleaseRuleMiddleware)},
 		{Path: "/api/release-rules/{id}", Method: http.MethodDelete, Handler: httpserver.Wrap(e.DeleteRule, e.ReleaseRuleMiddleware)},
+
+		// 制品 SBOM 及漏洞门禁
+		{Path: "/api/releases/{releaseId}/sboms", Method: http.MethodPost, Handler: e.IngestReleaseSBOM},
+		{Path: "/api/releases/{releaseId}/sboms", Method: http.MethodGet, Handler: e.ListReleaseSBOMs},
+		{Path: "/api/releases/{releaseId}/sboms/actions/generate", Method: http.MethodPost, Handler: e.GenerateReleaseSBOMs},
+		{Path: "/api/releases/{releaseId}/vulnerabilities", Method: http.MethodGet, Handler: e.ListReleaseVulnerabilities},
+		{Path: "/api/releases/{releaseId}/vulnerabilities/actions/scan", Method: http.MethodPost, Handler: e.ScanReleaseVulnerabilities},
+		{Path: "/api/releases/{releaseId}/actions/check-vulnerability-gate", Method: http.MethodGet, Handler: e.CheckReleaseVulnerabilityGate},
+		{Path: "/api/vulnerability-advisories/actions/import", Method: http.MethodPost, Handler: e.ImportVulnerabilityAdvisories},
+		{Path: "/api/release-vulnerability-gates", Method: http.MethodGet, Handler: e.GetReleaseVulnerabilityGate},
+		{Path: "/api/release-vulnerability-gates", Method: http.MethodPut, Handler: e.UpdateReleaseVulnerabilityGate},
+
+		// 项目制品 changelog 及建议版本号
+		{Path: "/api/releases/{releaseId}/changelog", Method: http.MethodGet, WriterHandler: e.DownloadReleaseChangelog},
+		{Path: "/api/releases/{releaseId}/changelog/actions/generate", Method: http.MethodPost, Handler: e.GenerateReleaseChangelog},
+		{Path: "/api/releases/actions/suggest-version", Method: http.MethodPost, Handler: e.SuggestReleaseVersion},
 	}
 }
@@ -0,0 +1,249 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/dicehub/dbclient"
+	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/apierrors"
+	"github.com/erda-project/erda/internal/pkg/user"
+	"github.com/erda-project/erda/pkg/database/dbengine"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+	"github.com/erda-project/erda/pkg/http/httpserver/errorresp"
+)
+
+// IngestReleaseSBOM POST /api/releases/{releaseId}/sboms 上传制品镜像 SBOM
+func (e *Endpoints) IngestReleaseSBOM(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	if _, apiErr := e.checkReleaseSBOMAccess(r, vars["releaseId"], true, apierrors.ErrIngestSBOM); apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	var req apistructs.ReleaseSBOMIngestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierrors.ErrIngestSBOM.InvalidParameter(err).ToResp(), nil
+	}
+	req.ReleaseID = vars["releaseId"]
+	data, apiErr := e.sbom.Ingest(&req)
+	if apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	return httpserver.OkResp(data)
+}
+
+// GenerateReleaseSBOMs POST /api/releases/{releaseId}/sboms/actions/generate 生成制品所有镜像的 SBOM
+func (e *Endpoints) GenerateReleaseSBOMs(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	if _, apiErr := e.checkReleaseSBOMAccess(r, vars["releaseId"], true, apierrors.ErrGenerateSBOM); apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	data, apiErr := e.sbom.Generate(vars["releaseId"])
+	if apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	return httpserver.OkResp(data)
+}
+
+// ListReleaseSBOMs GET /api/releases/{releaseId}/sboms 获取制品 SBOM 列表
+func (e *Endpoints) ListReleaseSBOMs(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	if _, apiErr := e.checkReleaseSBOMAccess(r, vars["releaseId"], false, apierrors.ErrListSBOM); apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	data, apiErr := e.sbom.List(vars["releaseId"])
+	if apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	return httpserver.OkResp(data)
+}
+
+// ScanReleaseVulnerabilities POST /api/releases/{releaseId}/vulnerabilities/actions/scan 重新扫描制品漏洞
+func (e *Endpoints) ScanReleaseVulnerabilities(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	if _, apiErr := e.checkReleaseSBOMAccess(r, vars["releaseId"], true, apierrors.ErrScanVulnerability); apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	if apiErr := e.sbom.Scan(vars["releaseId"]); apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	data, apiErr := e.sbom.ListVulnerabilities(vars["releaseId"])
+	if apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	return httpserver.OkResp(data)
+}
+
+// ListReleaseVulnerabilities GET /api/releases/{releaseId}/vulnerabilities 获取制品漏洞列表
+func (e *Endpoints) ListReleaseVulnerabilities(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	if _, apiErr := e.checkReleaseSBOMAccess(r, vars["releaseId"], false, apierrors.ErrListVulnerability); apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	data, apiErr := e.sbom.ListVulnerabilities(vars["releaseId"])
+	if apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	return httpserver.OkResp(data)
+}
+
+// CheckReleaseVulnerabilityGate GET /api/releases/{releaseId}/actions/check-vulnerability-gate 检查制品能否通过漏洞门禁
+func (e *Endpoints) CheckReleaseVulnerabilityGate(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	if _, apiErr := e.checkReleaseSBOMAccess(r, vars["releaseId"], false, apierrors.ErrCheckVulnerabilityGate); apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	data, apiErr := e.sbom.CheckGate(&apistructs.ReleaseVulnerabilityGateCheckRequest{
+		ReleaseID: vars["releaseId"],
+		Workspace: r.URL.Query().Get("workspace"),
+	})
+	if apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	return httpserver.OkResp(data)
+}
+
+// ImportVulnerabilityAdvisories POST /api/vulnerability-advisories/actions/import 导入离线漏洞库
+func (e *Endpoints) ImportVulnerabilityAdvisories(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identity, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrImportVulnerabilityAdvisory.NotLogin().ToResp(), nil
+	}
+	if !identity.IsInternalClient() {
+		access, err := e.bdl.CheckPermission(&apistructs.PermissionCheckRequest{
+			UserID:   identity.UserID,
+			Scope:    apistructs.SysScope,
+			ScopeID:  1,
+			Resource: apistructs.OrgResource,
+			Action:   apistructs.CreateAction,
+		})
+		if err != nil {
+			return apierrors.ErrImportVulnerabilityAdvisory.InternalError(err).ToResp(), nil
+		}
+		if !access.Access {
+			return apierrors.ErrImportVulnerabilityAdvisory.AccessDenied().ToResp(), nil
+		}
+	}
+	var req apistructs.VulnerabilityAdvisoryImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierrors.ErrImportVulnerabilityAdvisory.InvalidParameter(err).ToResp(), nil
+	}
+	count, apiErr := e.sbom.ImportAdvisories(&req)
+	if apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	return httpserver.OkResp(map[string]int{"total": count})
+}
+
+// GetReleaseVulnerabilityGate GET /api/release-vulnerability-gates 获取项目环境的漏洞门禁
+func (e *Endpoints) GetReleaseVulnerabilityGate(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	projectID, apiErr := e.checkGateAccess(r, false, apierrors.ErrGetVulnerabilityGate)
+	if apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	workspace := r.URL.Query().Get("workspace")
+	if workspace == "" {
+		return apierrors.ErrGetVulnerabilityGate.MissingParameter("workspace").ToResp(), nil
+	}
+	data, apiErr := e.sbom.GetGate(projectID, workspace)
+	if apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	return httpserver.OkResp(data)
+}
+
+// UpdateReleaseVulnerabilityGate PUT /api/release-vulnerability-gates 更新项目环境的漏洞门禁
+func (e *Endpoints) UpdateReleaseVulnerabilityGate(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	projectID, apiErr := e.checkGateAccess(r, true, apierrors.ErrUpdateVulnerabilityGate)
+	if apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	var gate apistructs.ReleaseVulnerabilityGate
+	if err := json.NewDecoder(r.Body).Decode(&gate); err != nil {
+		return apierrors.ErrUpdateVulnerabilityGate.InvalidParameter(err).ToResp(), nil
+	}
+	gate.ProjectID = projectID
+	data, apiErr := e.sbom.UpdateGate(&gate)
+	if apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+	return httpserver.OkResp(data)
+}
+
+// checkReleaseSBOMAccess 校验用户对制品的读写权限，内部调用不鉴权
+func (e *Endpoints) checkReleaseSBOMAccess(r *http.Request, releaseID string, write bool,
+	apiErr *errorresp.APIError) (*dbclient.Release, *errorresp.APIError) {
+	identity, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return nil, apiErr.NotLogin()
+	}
+	if releaseID == "" {
+		return nil, apiErr.MissingParameter("releaseId")
+	}
+	release, err := e.db.GetRelease(releaseID)
+	if err != nil {
+		if errors.Is(err, dbengine.ErrNotFound) {
+			return nil, apiErr.NotFound()
+		}
+		return nil, apiErr.InternalError(err)
+	}
+	if identity.IsInternalClient() {
+		return release, nil
+	}
+	var access bool
+	if write {
+		access, err = e.hasWriteAccess(identity, release.ProjectID, release.IsProjectRelease, release.ApplicationID)
+	} else {
+		access, err = e.hasReadAccess(identity, release.ProjectID)
+	}
+	if err != nil {
+		return nil, apiErr.InternalError(err)
+	}
+	if !access {
+		return nil, apiErr.AccessDenied()
+	}
+	return release, nil
+}
+
+// checkGateAccess 校验用户对项目漏洞门禁的读写权限，返回项目 ID
+func (e *Endpoints) checkGateAccess(r *http.Request, write bool, apiErr *errorresp.APIError) (uint64, *errorresp.APIError) {
+	identity, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return 0, apiErr.NotLogin()
+	}
+	projectIDStr := r.URL.Query().Get("projectID")
+	if projectIDStr == "" {
+		return 0, apiErr.MissingParameter("projectID")
+	}
+	projectID, err := strconv.ParseUint(projectIDStr, 10, 64)
+	if err != nil {
+		return 0, apiErr.InvalidParameter("invalid query parameter projectID")
+	}
+	if identity.IsInternalClient() {
+		return projectID, nil
+	}
+	var access bool
+	if write {
+		access, err = e.hasWriteAccess(identity, int64(projectID), true, 0)
+	} else {
+		access, err = e.hasReadAccess(identity, int64(projectID))
+	}
+	if err != nil {
+		return 0, apiErr.InternalError(err)
+	}
+	if !access {
+		return 0, apiErr.AccessDenied()
+	}
+	return projectID, nil
+}
@@ -26,8 +26,10 @@ import (
 	"github.com/erda-project/erda/internal/apps/dop/dicehub/conf"
 	"github.com/erda-project/erda/internal/apps/dop/dicehub/dbclient"
 	"github.com/erda-project/erda/internal/apps/dop/dicehub/endpoints"
+	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/changelog"
 	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/release"
 	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/release_rule"
+	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/sbom"
 	"github.com/erda-project/erda/pkg/http/httpserver"
 )
 
@@ -76,6 +78,8 @@ func initEndpoints(p *provider) (*endpoints.Endpoints, error) {
 		bundle.WithMonitor(),
 		bundle.WithPipeline(),
 		bundle.WithClusterManager(),
+		bundle.WithGittar(),
+		bundle.WithDOP(),
 	}
 	bdl := bundle.New(bundleOpts...)
 	rl := release.New(
@@ -86,6 +90,15 @@ func initEndpoints(p *provider) (*endpoints.Endpoints, error) {
 	// publishItem.Migration320()
 
 	releaseRule := release_rule.New(release_rule.WithDBClient(db))
+	sb := sbom.New(
+		sbom.WithDBClient(db),
+		sbom.WithGenerator(conf.SBOMGenerator()),
+	)
+
+	cl := changelog.New(
+		changelog.WithDBClient(db),
+		changelog.WithBundle(bdl),
+	)
 
 	// queryStringDecoder
 	queryStringDecoder := schema.NewDecoder()
@@ -97,6 +110,8 @@ func initEndpoints(p *provider) (*endpoints.Endpoints, error) {
 		endpoints.WithBundle(bdl),
 		endpoints.WithRelease(rl),
 		endpoints.WithReleaseRule(releaseRule),
+		endpoints.WithSBOM(sb),
+		endpoints.WithChangelog(cl),
 		endpoints.WithQueryStringDecoder(queryStringDecoder),
 		endpoints.WithOrg(p.Org),
 	)
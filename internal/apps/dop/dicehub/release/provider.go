@@ -43,7 +43,9 @@ import (
 	imagedb "github.com/erda-project/erda/internal/apps/dop/dicehub/image/db"
 	"github.com/erda-project/erda/internal/apps/dop/dicehub/registry"
 	"github.com/erda-project/erda/internal/apps/dop/dicehub/release/db"
+	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/changelog"
 	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/release_rule"
+	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/sbom"
 	"github.com/erda-project/erda/internal/core/org"
 	extensiondb "github.com/erda-project/erda/internal/pkg/extension/db"
 	"github.com/erda-project/erda/pkg/common/apis"
@@ -55,6 +57,7 @@ import (
 type config struct {
 	MaxTimeReserved string `file:"max_time_reserved" env:"RELEASE_MAX_TIME_RESERVED"`
 	GCSwitch        bool   `file:"gc_switch" env:"RELEASE_GC_SWITCH"`
+	SBOMGenerator   string `file:"sbom_generator" env:"RELEASE_SBOM_GENERATOR"`
 }
 
 // +provider
@@ -75,7 +78,7 @@ type provider struct {
 }
 
 func (p *provider) Init(ctx servicehub.Context) error {
-	p.bdl = bundle.New(bundle.WithScheduler(), bundle.WithErdaServer())
+	p.bdl = bundle.New(bundle.WithScheduler(), bundle.WithErdaServer(), bundle.WithGittar(), bundle.WithDOP())
 
 	p.opusService = &opus{d: &db.OpusDB{DB: p.DBv2}}
 	p.releaseService = &ReleaseService{
@@ -96,6 +99,14 @@ func (p *provider) Init(ctx servicehub.Context) error {
 		gallery:  p.GallerySvc,
 		org:      p.Org,
 		registry: registry.New(p.ClusterSvc),
+		sbom: sbom.New(
+			sbom.WithDBClient(&dbclient.DBClient{DBEngine: &dbengine.DBEngine{DB: p.DB}}),
+			sbom.WithGenerator(p.Cfg.SBOMGenerator),
+		),
+		changelog: changelog.New(
+			changelog.WithDBClient(&dbclient.DBClient{DBEngine: &dbengine.DBEngine{DB: p.DB}}),
+			changelog.WithBundle(p.bdl),
+		),
 	}
 	p.releaseGetDiceService = &releaseGetDiceService{
 		p:  p,
@@ -42,7 +42,9 @@ import (
 	"github.com/erda-project/erda/internal/apps/dop/dicehub/registry"
 	"github.com/erda-project/erda/internal/apps/dop/dicehub/release/db"
 	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/apierrors"
+	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/changelog"
 	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/release_rule"
+	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/sbom"
 	"github.com/erda-project/erda/internal/core/org"
 	"github.com/erda-project/erda/internal/pkg/addonutil"
 	extensiondb "github.com/erda-project/erda/internal/pkg/extension/db"
@@ -68,6 +70,8 @@ type ReleaseService struct {
 	ReleaseRule     *release_rule.ReleaseRule
 	org             org.Interface
 	registry        registry.Interface
+	sbom            *sbom.SBOM
+	changelog       *changelog.Changelog
 }
 
 // CreateRelease POST /api/releases release create release
@@ -160,6 +164,28 @@ func (s *ReleaseService) CreateRelease(ctx context.Context, req *pb.ReleaseCreat
 		return nil, apierrors.ErrCreateRelease.InternalError(err)
 	}
 
+	// 应用制品创建后异步生成镜像 SBOM 并扫描漏洞，项目制品的漏洞由其应用制品汇总
+	if !req.IsProjectRelease && s.sbom != nil && s.sbom.GeneratorEnabled() {
+		go func() {
+			if _, apiError := s.sbom.Generate(releaseID); apiError != nil {
+				l.WithError(apiError).Errorf("failed to generate sbom of release %s", releaseID)
+			}
+		}()
+	}
+
+	// 未填写 changelog 的项目制品根据应用制品间的提交异步生成
+	if req.IsProjectRelease && req.Changelog == "" && s.changelog != nil {
+		go func() {
+			if _, apiError := s.changelog.Generate(&apistructs.ReleaseChangelogGenerateRequest{
+				ReleaseID: releaseID,
+				UserID:    identityInfo.UserID,
+				Save:      true,
+			}); apiError != nil {
+				l.WithError(apiError).Errorf("failed to generate changelog of release %s", releaseID)
+			}
+		}()
+	}
+
 	if !identityInfo.IsInternalClient() {
 		go func() {
 			if err := s.audit(auditParams{
@@ -77,6 +77,20 @@ var (
 	ErrDeleteReleaseRule = err("ErrDeleteReleaseRule", "删除制品规则失败")
 	ErrAuthReleaseRule   = err("ErrAuthReleaseRule", "制品规则鉴权失败")
 	ErrGetReleaseRule    = err("ErrGetReleaseRule", "获取制品规则失败")
+
+	ErrIngestSBOM                  = err("ErrIngestSBOM", "上传SBOM失败")
+	ErrGenerateSBOM                = err("ErrGenerateSBOM", "生成SBOM失败")
+	ErrListSBOM                    = err("ErrListSBOM", "获取SBOM列表失败")
+	ErrScanVulnerability           = err("ErrScanVulnerability", "漏洞扫描失败")
+	ErrListVulnerability           = err("ErrListVulnerability", "获取漏洞列表失败")
+	ErrImportVulnerabilityAdvisory = err("ErrImportVulnerabilityAdvisory", "导入漏洞库失败")
+	ErrGetVulnerabilityGate        = err("ErrGetVulnerabilityGate", "获取漏洞门禁失败")
+	ErrUpdateVulnerabilityGate     = err("ErrUpdateVulnerabilityGate", "更新漏洞门禁失败")
+	ErrCheckVulnerabilityGate      = err("ErrCheckVulnerabilityGate", "漏洞门禁检查失败")
+
+	ErrGenerateReleaseChangelog = err("ErrGenerateReleaseChangelog", "生成制品changelog失败")
+	ErrGetReleaseChangelog      = err("ErrGetReleaseChangelog", "获取制品changelog失败")
+	ErrSuggestReleaseVersion    = err("ErrSuggestReleaseVersion", "获取制品建议版本号失败")
 )
 
 func err(template, defaultValue string) *errorresp.APIError {
@@ -0,0 +1,390 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package changelog suggests the semantic version of project releases and generates their changelog
+// from the conventional commits between the previous and the current release.
+package changelog
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/bundle"
+	"github.com/erda-project/erda/internal/apps/dop/dicehub/dbclient"
+	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/apierrors"
+	"github.com/erda-project/erda/pkg/database/dbengine"
+	"github.com/erda-project/erda/pkg/http/httpserver/errorresp"
+	"github.com/erda-project/erda/pkg/semver"
+)
+
+const (
+	commitPageSize    = 100
+	maxCommitsPerApp  = 1000
+	mergeRequestState = "merged"
+)
+
+// Changelog is the handle to generate release changelog
+type Changelog struct {
+	db  *dbclient.DBClient
+	bdl *bundle.Bundle
+}
+
+// New returns a *Changelog
+func New(options ...Option) *Changelog {
+	c := new(Changelog)
+	for _, opt := range options {
+		opt(c)
+	}
+	return c
+}
+
+// appRef is an application release in a project release
+type appRef struct {
+	ApplicationID   int64
+	ApplicationName string
+	ReleaseID       string
+	CommitID        string
+}
+
+// Generate generates the changelog of the project release, and saves it to the release if required
+func (c *Changelog) Generate(req *apistructs.ReleaseChangelogGenerateRequest) (*apistructs.ReleaseChangelog, *errorresp.APIError) {
+	release, err := c.db.GetRelease(req.ReleaseID)
+	if err != nil {
+		if errors.Is(err, dbengine.ErrNotFound) {
+			return nil, apierrors.ErrGenerateReleaseChangelog.NotFound()
+		}
+		return nil, apierrors.ErrGenerateReleaseChangelog.InternalError(err)
+	}
+	if !release.IsProjectRelease {
+		return nil, apierrors.ErrGenerateReleaseChangelog.InvalidParameter("only project release has changelog")
+	}
+	current, err := c.appRefsOfProjectRelease(release)
+	if err != nil {
+		return nil, apierrors.ErrGenerateReleaseChangelog.InternalError(err)
+	}
+	previous, err := c.previousRelease(release.ProjectID, req.PreviousReleaseID, &release.CreatedAt)
+	if err != nil {
+		return nil, apierrors.ErrGenerateReleaseChangelog.InternalError(err)
+	}
+	userID := req.UserID
+	if userID == "" {
+		userID = release.UserID
+	}
+	cl, err := c.build(release.ProjectID, userID, current, previous)
+	if err != nil {
+		return nil, apierrors.ErrGenerateReleaseChangelog.InternalError(err)
+	}
+	cl.ReleaseID = release.ReleaseID
+	cl.Markdown = renderMarkdown(release.Version, cl, release.CreatedAt)
+	if req.Save {
+		if err := c.db.Model(&dbclient.Release{}).Where("release_id = ?", release.ReleaseID).
+			Update("changelog", cl.Markdown).Error; err != nil {
+			return nil, apierrors.ErrGenerateReleaseChangelog.InternalError(err)
+		}
+	}
+	return cl, nil
+}
+
+// SuggestVersion suggests the version of a project release to be created from the application releases
+func (c *Changelog) SuggestVersion(req *apistructs.ReleaseVersionSuggestRequest) (*apistructs.ReleaseChangelog, *errorresp.APIError) {
+	if req.ProjectID == 0 {
+		return nil, apierrors.ErrSuggestReleaseVersion.MissingParameter("projectId")
+	}
+	if len(req.ApplicationReleaseList) == 0 {
+		return nil, apierrors.ErrSuggestReleaseVersion.MissingParameter("applicationReleaseList")
+	}
+	releases, err := c.db.GetReleases(req.ApplicationReleaseList)
+	if err != nil {
+		return nil, apierrors.ErrSuggestReleaseVersion.InternalError(err)
+	}
+	for _, r := range releases {
+		if r.ProjectID != req.ProjectID {
+			return nil, apierrors.ErrSuggestReleaseVersion.InvalidParameter(
+				fmt.Sprintf("release %s does not belong to project %d", r.ReleaseID, req.ProjectID))
+		}
+	}
+	previous, err := c.previousRelease(req.ProjectID, req.PreviousReleaseID, nil)
+	if err != nil {
+		return nil, apierrors.ErrSuggestReleaseVersion.InternalError(err)
+	}
+	cl, err := c.build(req.ProjectID, req.UserID, appRefs(releases), previous)
+	if err != nil {
+		return nil, apierrors.ErrSuggestReleaseVersion.InternalError(err)
+	}
+	cl.Markdown = renderMarkdown("", cl, time.Now())
+	return cl, nil
+}
+
+// Markdown returns the changelog saved on the release, it is generated if not saved yet
+func (c *Changelog) Markdown(releaseID, userID string) (string, *errorresp.APIError) {
+	release, err := c.db.GetRelease(releaseID)
+	if err != nil {
+		if errors.Is(err, dbengine.ErrNotFound) {
+			return "", apierrors.ErrGetReleaseChangelog.NotFound()
+		}
+		return "", apierrors.ErrGetReleaseChangelog.InternalError(err)
+	}
+	if release.Changelog != "" || !release.IsProjectRelease {
+		return release.Changelog, nil
+	}
+	cl, apiErr := c.Generate(&apistructs.ReleaseChangelogGenerateRequest{ReleaseID: releaseID, UserID: userID})
+	if apiErr != nil {
+		return "", apiErr
+	}
+	return cl.Markdown, nil
+}
+
+func (c *Changelog) build(projectID int64, userID string, current map[int64]appRef, previous *dbclient.Release) (*apistructs.ReleaseChangelog, error) {
+	cl := new(apistructs.ReleaseChangelog)
+	previousRefs := make(map[int64]appRef)
+	if previous != nil {
+		cl.PreviousReleaseID = previous.ReleaseID
+		cl.PreviousVersion = previous.Version
+		refs, err := c.appRefsOfProjectRelease(previous)
+		if err != nil {
+			return nil, err
+		}
+		previousRefs = refs
+	}
+
+	appIDs := make([]int64, 0, len(current))
+	for appID := range current {
+		appIDs = append(appIDs, appID)
+	}
+	sort.Slice(appIDs, func(i, j int) bool { return appIDs[i] < appIDs[j] })
+
+	var changes []appChanges
+	for _, appID := range appIDs {
+		cur := current[appID]
+		prev, ok := previousRefs[appID]
+		switch {
+		case previous == nil:
+			continue
+		case cur.CommitID == "":
+			cl.Warnings = append(cl.Warnings, fmt.Sprintf("release %s of application %s has no commit", cur.ReleaseID, cur.ApplicationName))
+			continue
+		case !ok || prev.CommitID == "":
+			cl.Warnings = append(cl.Warnings, fmt.Sprintf("application %s is not in the previous release, its commits are not included", cur.ApplicationName))
+			continue
+		case prev.CommitID == cur.CommitID:
+			continue
+		}
+		commits, warning := c.appCommits(appID, userID, cur.CommitID, prev.CommitID)
+		if warning != "" {
+			cl.Warnings = append(cl.Warnings, fmt.Sprintf("application %s: %s", cur.ApplicationName, warning))
+		}
+		changes = append(changes, appChanges{
+			ApplicationID:   appID,
+			ApplicationName: cur.ApplicationName,
+			Commits:         commits,
+		})
+	}
+
+	cl.Sections = buildSections(changes, c.resolveIssues(projectID, changes))
+	if previous == nil {
+		cl.SuggestedVersion, _, _ = suggestVersion("", semver.LevelNone)
+		cl.Bump = semver.LevelMajor.String()
+		return cl, nil
+	}
+	version, level, err := suggestVersion(previous.Version, bumpLevel(changes))
+	if err != nil {
+		cl.Warnings = append(cl.Warnings, fmt.Sprintf("previous version %s is not a semantic version", previous.Version))
+	}
+	cl.SuggestedVersion = version
+	cl.Bump = level.String()
+	return cl, nil
+}
+
+// appCommits lists and parses the commits between the two commits of the application,
+// the merge commits are replaced by their merge requests
+func (c *Changelog) appCommits(appID int64, userID, after, before string) ([]Commit, string) {
+	var (
+		commits []Commit
+		warning string
+	)
+	for skip := 0; ; skip += commitPageSize {
+		if skip >= maxCommitsPerApp {
+			warning = fmt.Sprintf("only the latest %d commits are included", maxCommitsPerApp)
+			break
+		}
+		page, err := c.bdl.ListGittarCompareCommits(after, before, appID, userID, skip, commitPageSize)
+		if err != nil {
+			logrus.Errorf("failed to list commits of application %d between %s and %s, err: %v", appID, before, after, err)
+			return commits, fmt.Sprintf("failed to list commits, %v", err)
+		}
+		for _, commit := range page {
+			parsed := ParseCommit(commit.ID, commit.CommitMessage)
+			if parsed.IsMerge() {
+				if mr := c.mergeRequestOf(appID, userID, commit, parsed); mr != nil {
+					fromMR := ParseCommit(commit.ID, mr.Title+"\n\n"+mr.Description)
+					commits = append(commits, fromMR)
+				}
+				continue
+			}
+			commits = append(commits, parsed)
+		}
+		if len(page) < commitPageSize {
+			break
+		}
+	}
+	return commits, warning
+}
+
+// mergeRequestOf finds the merge request of the merge commit, the one merged closest to the commit time is chosen
+func (c *Changelog) mergeRequestOf(appID int64, userID string, commit apistructs.Commit, parsed Commit) *apistructs.MergeRequestInfo {
+	data, err := c.bdl.ListMergeRequest(uint64(appID), userID, apistructs.GittarQueryMrRequest{
+		State:        mergeRequestState,
+		SourceBranch: parsed.MergeSource,
+		TargetBranch: parsed.MergeTarget,
+		Page:         1,
+		Size:         20,
+	})
+	if err != nil {
+		logrus.Errorf("failed to list merge requests of application %d, err: %v", appID, err)
+		return nil
+	}
+	var (
+		matched *apistructs.MergeRequestInfo
+		minDiff = math.MaxFloat64
+	)
+	for _, mr := range data.List {
+		if mr == nil {
+			continue
+		}
+		if matched == nil {
+			matched = mr
+		}
+		if mr.MergeAt == nil || commit.Committer == nil {
+			continue
+		}
+		if diff := math.Abs(mr.MergeAt.Sub(commit.Committer.When).Seconds()); diff < minDiff {
+			minDiff = diff
+			matched = mr
+		}
+	}
+	return matched
+}
+
+// resolveIssues fetches the referenced issues, issues of other projects are ignored
+func (c *Changelog) resolveIssues(projectID int64, changes []appChanges) map[uint64]apistructs.ReleaseChangelogIssue {
+	issues := make(map[uint64]apistructs.ReleaseChangelogIssue)
+	visited := make(map[uint64]struct{})
+	for _, app := range changes {
+		for _, commit := range app.Commits {
+			for _, id := range commit.Issues {
+				if _, ok := visited[id]; ok {
+					continue
+				}
+				visited[id] = struct{}{}
+				issue, err := c.bdl.GetIssue(id)
+				if err != nil {
+					logrus.Warnf("failed to get issue %d referenced by commit %s, err: %v", id, commit.ID, err)
+					continue
+				}
+				if issue.ProjectID != uint64(projectID) {
+					continue
+				}
+				issues[id] = apistructs.ReleaseChangelogIssue{
+					ID:    id,
+					Title: issue.Title,
+					Type:  string(issue.Type),
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// previousRelease returns the given release, or the latest project release of the project created before the time
+func (c *Changelog) previousRelease(projectID int64, releaseID string, before *time.Time) (*dbclient.Release, error) {
+	if releaseID != "" {
+		release, err := c.db.GetRelease(releaseID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get previous release %s", releaseID)
+		}
+		if !release.IsProjectRelease || release.ProjectID != projectID {
+			return nil, errors.Errorf("release %s is not a project release of project %d", releaseID, projectID)
+		}
+		return release, nil
+	}
+	var release dbclient.Release
+	db := c.db.Where("project_id = ? AND is_project_release = ?", projectID, true)
+	if before != nil {
+		db = db.Where("created_at < ?", *before)
+	}
+	err := db.Order("created_at DESC").First(&release).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+func (c *Changelog) appRefsOfProjectRelease(release *dbclient.Release) (map[int64]appRef, error) {
+	modes := make(map[string]apistructs.ReleaseDeployMode)
+	if release.Modes != "" {
+		if err := json.Unmarshal([]byte(release.Modes), &modes); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal modes of release %s", release.ReleaseID)
+		}
+	}
+	var ids []string
+	for _, mode := range modes {
+		for _, group := range mode.ApplicationReleaseList {
+			ids = append(ids, group...)
+		}
+	}
+	if len(ids) == 0 {
+		return map[int64]appRef{}, nil
+	}
+	releases, err := c.db.GetReleases(ids)
+	if err != nil {
+		return nil, err
+	}
+	return appRefs(releases), nil
+}
+
+// appRefs returns the application releases by application, the latest one wins if an application appears twice
+func appRefs(releases []dbclient.Release) map[int64]appRef {
+	refs := make(map[int64]appRef)
+	created := make(map[int64]time.Time)
+	for _, r := range releases {
+		if t, ok := created[r.ApplicationID]; ok && t.After(r.CreatedAt) {
+			continue
+		}
+		labels := make(map[string]string)
+		if r.Labels != "" {
+			if err := json.Unmarshal([]byte(r.Labels), &labels); err != nil {
+				logrus.Warnf("failed to unmarshal labels of release %s, err: %v", r.ReleaseID, err)
+			}
+		}
+		created[r.ApplicationID] = r.CreatedAt
+		refs[r.ApplicationID] = appRef{
+			ApplicationID:   r.ApplicationID,
+			ApplicationName: r.ApplicationName,
+			ReleaseID:       r.ReleaseID,
+			CommitID:        labels["gitCommitId"],
+		}
+	}
+	return refs
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package changelog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/dicehub/dbclient"
+	"github.com/erda-project/erda/pkg/semver"
+)
+
+func TestParseCommit(t *testing.T) {
+	c := ParseCommit("a1", "feat(release): support changelog #12\n\ncloses https://erda.cloud/org/dop/projects/1/issues/all?id=34&type=TASK")
+	assert.Equal(t, "feat", c.Type)
+	assert.Equal(t, "release", c.Scope)
+	assert.Equal(t, "support changelog #12", c.Subject)
+	assert.False(t, c.Breaking)
+	assert.Equal(t, []uint64{34, 12}, c.Issues)
+
+	c = ParseCommit("a2", "refactor!: drop v1 api")
+	assert.Equal(t, "refactor", c.Type)
+	assert.True(t, c.Breaking)
+
+	c = ParseCommit("a3", "fix: typo\r\n\r\nBREAKING CHANGE: config key renamed")
+	assert.True(t, c.Breaking)
+
+	c = ParseCommit("a4", "update readme")
+	assert.Equal(t, typeOther, c.Type)
+	assert.Equal(t, "update readme", c.Subject)
+
+	c = ParseCommit("a5", "Merge branch 'feature/x' into 'master'")
+	assert.True(t, c.IsMerge())
+	assert.Equal(t, "feature/x", c.MergeSource)
+	assert.Equal(t, "master", c.MergeTarget)
+}
+
+func TestParseIssueRefs(t *testing.T) {
+	assert.Equal(t, []uint64{1, 2}, ParseIssueRefs("fix #1, #2 and #1"))
+	assert.Empty(t, ParseIssueRefs("color &#35; and https://example.com/page#3 and issue#4"))
+}
+
+func TestSuggestVersion(t *testing.T) {
+	cases := []struct {
+		previous  string
+		commits   []Commit
+		want      string
+		wantLevel semver.Level
+	}{
+		{"", nil, "1.0.0", semver.LevelMajor},
+		{"1.2.3", nil, "1.2.4", semver.LevelPatch},
+		{"1.2.3", []Commit{{Type: "fix"}, {Type: "chore"}}, "1.2.4", semver.LevelPatch},
+		{"v1.2.3", []Commit{{Type: "fix"}, {Type: "feat"}}, "v1.3.0", semver.LevelMinor},
+		{"1.2.3", []Commit{{Type: "feat"}, {Type: "fix", Breaking: true}}, "2.0.0", semver.LevelMajor},
+	}
+	for _, c := range cases {
+		version, level, err := suggestVersion(c.previous, bumpLevel([]appChanges{{Commits: c.commits}}))
+		assert.NoError(t, err)
+		assert.Equal(t, c.want, version, c.previous)
+		assert.Equal(t, c.wantLevel, level, c.previous)
+	}
+	_, _, err := suggestVersion("2022-02-10", semver.LevelPatch)
+	assert.Error(t, err)
+}
+
+func TestBuildSections(t *testing.T) {
+	changes := []appChanges{
+		{
+			ApplicationID:   1,
+			ApplicationName: "api",
+			Commits: []Commit{
+				{ID: "c1", Type: "feat", Scope: "user", Subject: "add login", Issues: []uint64{10}},
+				{ID: "c2", Type: "feat", Scope: "user", Subject: "add login", Issues: []uint64{11, 99}},
+				{ID: "c3", Type: "fix", Subject: "remove field", Breaking: true},
+				{ID: "c4", Type: "wip", Subject: "something"},
+				{ID: "c5", Type: typeOther, Subject: "Merge branch 'a' into 'b'", MergeSource: "a", MergeTarget: "b"},
+			},
+		},
+		{
+			ApplicationID:   2,
+			ApplicationName: "ui",
+			Commits:         []Commit{{ID: "c6", Type: "feat", Scope: "user", Subject: "add login"}},
+		},
+	}
+	issues := map[uint64]apistructs.ReleaseChangelogIssue{
+		10: {ID: 10, Title: "login", Type: "REQUIREMENT"},
+		11: {ID: 11, Title: "sso", Type: "TASK"},
+	}
+	sections := buildSections(changes, issues)
+	var types []string
+	for _, s := range sections {
+		types = append(types, s.Type)
+	}
+	assert.Equal(t, []string{typeBreaking, "feat", "fix", typeOther}, types)
+	assert.Equal(t, "c3", sections[0].Entries[0].CommitID)
+	assert.Len(t, sections[1].Entries, 2)
+	assert.Equal(t, []apistructs.ReleaseChangelogIssue{issues[10], issues[11]}, sections[1].Entries[0].Issues)
+	assert.Equal(t, "ui", sections[1].Entries[1].ApplicationName)
+	assert.Equal(t, "something", sections[3].Entries[0].Subject)
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	cl := &apistructs.ReleaseChangelog{
+		PreviousVersion:  "1.0.0",
+		SuggestedVersion: "1.1.0",
+		Sections: []apistructs.ReleaseChangelogSection{{
+			Type:  "feat",
+			Title: "Features",
+			Entries: []apistructs.ReleaseChangelogEntry{{
+				ApplicationName: "api",
+				CommitID:        "0123456789abcdef",
+				Scope:           "user",
+				Subject:         "add login",
+				Issues:          []apistructs.ReleaseChangelogIssue{{ID: 10, Title: "login"}},
+			}},
+		}},
+	}
+	date := time.Date(2022, 2, 10, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, "## 1.1.0 (2022-02-10)\n\nCompared with 1.0.0\n\n### Features\n\n"+
+		"* **api:** **user:** add login (01234567), issues: #10 login\n", renderMarkdown("", cl, date))
+	assert.Equal(t, "## 1.0.1 (2022-02-10)\n\nNo notable changes.\n",
+		renderMarkdown("1.0.1", &apistructs.ReleaseChangelog{}, date))
+}
+
+func TestAppRefs(t *testing.T) {
+	now := time.Now()
+	refs := appRefs([]dbclient.Release{
+		{ReleaseID: "r1", ApplicationID: 1, ApplicationName: "api", Labels: `{"gitCommitId":"c1"}`, CreatedAt: now.Add(-time.Hour)},
+		{ReleaseID: "r2", ApplicationID: 1, ApplicationName: "api", Labels: `{"gitCommitId":"c2"}`, CreatedAt: now},
+		{ReleaseID: "r3", ApplicationID: 2, ApplicationName: "ui", Labels: `invalid`, CreatedAt: now},
+	})
+	assert.Equal(t, appRef{ApplicationID: 1, ApplicationName: "api", ReleaseID: "r2", CommitID: "c2"}, refs[1])
+	assert.Equal(t, "", refs[2].CommitID)
+}
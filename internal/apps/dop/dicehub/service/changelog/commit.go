@@ -0,0 +1,101 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package changelog
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const typeOther = "other"
+
+var (
+	// conventional commit header, see https://www.conventionalcommits.org
+	headerRegexp = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^()]*)\))?(!)?:\s*(.+)$`)
+	// breaking change footer
+	breakingRegexp = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE:`)
+	// issue reference like #123, not part of an html entity or an url fragment
+	issueRefRegexp = regexp.MustCompile(`(?:^|[^\w&/#])#(\d+)\b`)
+	// issue link copied from the dop issue page, e.g. .../issues/all?id=123&type=TASK
+	issueLinkRegexp = regexp.MustCompile(`/issues/[\w-]+\?(?:[^\s)]*&)?id=(\d+)`)
+	// default commit message of gittar merge requests
+	mergeRegexp = regexp.MustCompile(`^Merge branch '([^']+)' into '([^']+)'`)
+)
+
+// Commit is a parsed commit or merge request
+type Commit struct {
+	ID       string
+	Type     string
+	Scope    string
+	Subject  string
+	Breaking bool
+	Issues   []uint64
+	// MergeSource and MergeTarget are set for the merge commits of merge requests
+	MergeSource string
+	MergeTarget string
+}
+
+// ParseCommit parses the commit message as a conventional commit,
+// the type of a non-conventional commit is "other"
+func ParseCommit(id, message string) Commit {
+	message = strings.TrimSpace(strings.ReplaceAll(message, "\r\n", "\n"))
+	header, body := message, ""
+	if i := strings.Index(message, "\n"); i >= 0 {
+		header, body = strings.TrimSpace(message[:i]), message[i+1:]
+	}
+	c := Commit{ID: id, Type: typeOther, Subject: header, Issues: ParseIssueRefs(message)}
+	if m := mergeRegexp.FindStringSubmatch(header); m != nil {
+		c.MergeSource, c.MergeTarget = m[1], m[2]
+		return c
+	}
+	if m := headerRegexp.FindStringSubmatch(header); m != nil {
+		c.Type = strings.ToLower(m[1])
+		c.Scope = strings.TrimSpace(m[2])
+		c.Breaking = m[3] == "!"
+		c.Subject = strings.TrimSpace(m[4])
+	}
+	if breakingRegexp.MatchString(body) {
+		c.Breaking = true
+	}
+	return c
+}
+
+// IsMerge reports whether the commit is the merge commit of a merge request
+func (c Commit) IsMerge() bool {
+	return c.MergeSource != ""
+}
+
+// ParseIssueRefs returns the ids of the issues referenced in the text
+func ParseIssueRefs(text string) []uint64 {
+	var (
+		ids  []uint64
+		seen = make(map[uint64]struct{})
+	)
+	for _, re := range []*regexp.Regexp{issueLinkRegexp, issueRefRegexp} {
+		for _, m := range re.FindAllStringSubmatch(text, -1) {
+			id, err := strconv.ParseUint(m[1], 10, 64)
+			if err != nil || id == 0 {
+				continue
+			}
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
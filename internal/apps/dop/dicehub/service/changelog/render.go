@@ -0,0 +1,223 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package changelog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/semver"
+)
+
+const typeBreaking = "breaking"
+
+// sectionTitles defines the order and titles of the changelog sections
+var sectionTitles = []struct {
+	Type  string
+	Title string
+}{
+	{typeBreaking, "Breaking Changes"},
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance Improvements"},
+	{"revert", "Reverts"},
+	{"refactor", "Code Refactoring"},
+	{"docs", "Documentation"},
+	{"style", "Styles"},
+	{"test", "Tests"},
+	{"build", "Build System"},
+	{"ci", "Continuous Integration"},
+	{"chore", "Chores"},
+	{typeOther, "Other Changes"},
+}
+
+// appChanges is the commits of an application between two releases
+type appChanges struct {
+	ApplicationID   int64
+	ApplicationName string
+	Commits         []Commit
+}
+
+// bumpLevel returns the version bump level implied by the commits
+func bumpLevel(changes []appChanges) semver.Level {
+	level := semver.LevelNone
+	for _, app := range changes {
+		for _, c := range app.Commits {
+			switch {
+			case c.Breaking:
+				return semver.LevelMajor
+			case c.Type == "feat":
+				level = semver.LevelMinor
+			case level < semver.LevelPatch:
+				level = semver.LevelPatch
+			}
+		}
+	}
+	return level
+}
+
+// suggestVersion suggests the next version by the previous version and the bump level,
+// a new release is at least a patch release. The first release is 1.0.0.
+func suggestVersion(previous string, level semver.Level) (string, semver.Level, error) {
+	if previous == "" {
+		return semver.New(1), semver.LevelMajor, nil
+	}
+	v, err := semver.Parse(previous)
+	if err != nil {
+		return "", level, err
+	}
+	if level < semver.LevelPatch {
+		level = semver.LevelPatch
+	}
+	return v.Bump(level).String(), level, nil
+}
+
+// buildSections groups the commits by type, the breaking changes are also listed in a separate section.
+// Commits with the same type, scope and subject in one application are merged.
+func buildSections(changes []appChanges, issues map[uint64]apistructs.ReleaseChangelogIssue) []apistructs.ReleaseChangelogSection {
+	entries := make(map[string][]apistructs.ReleaseChangelogEntry)
+	for _, app := range changes {
+		seen := make(map[string]int)
+		for _, c := range app.Commits {
+			if c.IsMerge() || c.Subject == "" {
+				continue
+			}
+			typ := c.Type
+			if !knownType(typ) {
+				typ = typeOther
+			}
+			key := typ + "/" + c.Scope + "/" + c.Subject
+			if i, ok := seen[key]; ok {
+				entry := &entries[typ][i]
+				entry.Issues = mergeIssues(entry.Issues, c.Issues, issues)
+				entry.Breaking = entry.Breaking || c.Breaking
+				continue
+			}
+			seen[key] = len(entries[typ])
+			entries[typ] = append(entries[typ], apistructs.ReleaseChangelogEntry{
+				ApplicationID:   app.ApplicationID,
+				ApplicationName: app.ApplicationName,
+				CommitID:        c.ID,
+				Scope:           c.Scope,
+				Subject:         c.Subject,
+				Breaking:        c.Breaking,
+				Issues:          mergeIssues(nil, c.Issues, issues),
+			})
+		}
+	}
+	for _, s := range sectionTitles {
+		for _, entry := range entries[s.Type] {
+			if entry.Breaking && s.Type != typeBreaking {
+				entries[typeBreaking] = append(entries[typeBreaking], entry)
+			}
+		}
+	}
+
+	var sections []apistructs.ReleaseChangelogSection
+	for _, s := range sectionTitles {
+		if len(entries[s.Type]) == 0 {
+			continue
+		}
+		sections = append(sections, apistructs.ReleaseChangelogSection{
+			Type:    s.Type,
+			Title:   s.Title,
+			Entries: entries[s.Type],
+		})
+	}
+	return sections
+}
+
+func knownType(typ string) bool {
+	for _, s := range sectionTitles {
+		if s.Type == typ && typ != typeBreaking {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeIssues appends the resolved issues of ids to list, unresolved ids are dropped
+func mergeIssues(list []apistructs.ReleaseChangelogIssue, ids []uint64,
+	issues map[uint64]apistructs.ReleaseChangelogIssue) []apistructs.ReleaseChangelogIssue {
+	for _, id := range ids {
+		issue, ok := issues[id]
+		if !ok {
+			continue
+		}
+		exists := false
+		for _, item := range list {
+			if item.ID == id {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			list = append(list, issue)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// renderMarkdown renders the changelog as markdown
+func renderMarkdown(version string, cl *apistructs.ReleaseChangelog, date time.Time) string {
+	var b strings.Builder
+	if version == "" {
+		version = cl.SuggestedVersion
+	}
+	fmt.Fprintf(&b, "## %s (%s)\n", version, date.Format("2006-01-02"))
+	if cl.PreviousVersion != "" {
+		fmt.Fprintf(&b, "\nCompared with %s\n", cl.PreviousVersion)
+	}
+	if len(cl.Sections) == 0 {
+		b.WriteString("\nNo notable changes.\n")
+	}
+	for _, section := range cl.Sections {
+		fmt.Fprintf(&b, "\n### %s\n\n", section.Title)
+		for _, entry := range section.Entries {
+			b.WriteString("* ")
+			if entry.ApplicationName != "" {
+				fmt.Fprintf(&b, "**%s:** ", entry.ApplicationName)
+			}
+			if entry.Scope != "" {
+				fmt.Fprintf(&b, "**%s:** ", entry.Scope)
+			}
+			b.WriteString(entry.Subject)
+			if entry.CommitID != "" {
+				fmt.Fprintf(&b, " (%s)", shortCommitID(entry.CommitID))
+			}
+			for i, issue := range entry.Issues {
+				if i == 0 {
+					b.WriteString(", issues: ")
+				} else {
+					b.WriteString(", ")
+				}
+				fmt.Fprintf(&b, "#%d %s", issue.ID, issue.Title)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func shortCommitID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"time"
+
+	"github.com/erda-project/erda/pkg/database/dbengine"
+)
+
+const (
+	sourceUpload    = "upload"
+	sourceGenerator = "generator"
+)
+
+// ImageSBOM is the SBOM document of one release image
+type ImageSBOM struct {
+	dbengine.BaseModel
+	ReleaseID  string     `gorm:"index:idx_release_id"`
+	Image      string     `gorm:"type:varchar(512)"`
+	Format     string     `gorm:"type:varchar(32)"`
+	Source     string     `gorm:"type:varchar(32)"`
+	Content    string     `gorm:"type:longtext"`
+	Components int        `gorm:"type:int"`
+	ScannedAt  *time.Time `gorm:"column:scanned_at"`
+}
+
+// TableName sets table name
+func (ImageSBOM) TableName() string {
+	return "dice_release_sboms"
+}
+
+// Advisory is one entry of the offline vulnerability database
+type Advisory struct {
+	dbengine.BaseModel
+	VulnID     string `gorm:"type:varchar(128)"`
+	Ecosystem  string `gorm:"type:varchar(64)"`
+	Package    string `gorm:"type:varchar(255);index:idx_package"`
+	Introduced string `gorm:"type:varchar(128)"`
+	Fixed      string `gorm:"type:varchar(128)"`
+	Severity   string `gorm:"type:varchar(16)"`
+	Summary    string `gorm:"type:varchar(1024)"`
+}
+
+// TableName sets table name
+func (Advisory) TableName() string {
+	return "dice_vulnerability_advisories"
+}
+
+// Vulnerability is a finding of the release image scanning
+type Vulnerability struct {
+	dbengine.BaseModel
+	ReleaseID    string `gorm:"index:idx_release_id"`
+	Image        string `gorm:"type:varchar(512)"`
+	VulnID       string `gorm:"type:varchar(128)"`
+	Package      string `gorm:"type:varchar(255)"`
+	Version      string `gorm:"type:varchar(128)"`
+	FixedVersion string `gorm:"type:varchar(128)"`
+	Severity     string `gorm:"type:varchar(16)"`
+	Summary      string `gorm:"type:varchar(1024)"`
+}
+
+// TableName sets table name
+func (Vulnerability) TableName() string {
+	return "dice_release_vulnerabilities"
+}
+
+// Gate is the vulnerability gate of a project workspace
+type Gate struct {
+	dbengine.BaseModel
+	ProjectID   uint64 `gorm:"unique_index:uk_project_workspace"`
+	Workspace   string `gorm:"type:varchar(32);unique_index:uk_project_workspace"`
+	Enabled     bool
+	Thresholds  string `gorm:"type:varchar(1024)"`
+	RequireSBOM bool   `gorm:"column:require_sbom"`
+}
+
+// TableName sets table name
+func (Gate) TableName() string {
+	return "dice_release_vulnerability_gates"
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"time"
+
+	"github.com/erda-project/erda/internal/apps/dop/dicehub/dbclient"
+)
+
+// Option defines *SBOM configurations
+type Option func(s *SBOM)
+
+// WithDBClient sets the db client to *SBOM
+func WithDBClient(db *dbclient.DBClient) Option {
+	return func(s *SBOM) {
+		s.db = db
+	}
+}
+
+// WithGenerator sets the command used to generate the SBOM of an image,
+// e.g. "syft {image} -o cyclonedx-json". An empty command disables generation.
+func WithGenerator(cmd string) Option {
+	return func(s *SBOM) {
+		s.generator = cmd
+	}
+}
+
+// WithGenerateTimeout sets the timeout of a single generator run
+func WithGenerateTimeout(timeout time.Duration) Option {
+	return func(s *SBOM) {
+		s.generateTimeout = timeout
+	}
+}
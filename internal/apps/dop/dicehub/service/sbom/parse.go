@@ -0,0 +1,220 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/strutil"
+)
+
+// Component is a package listed in an SBOM document
+type Component struct {
+	Name      string
+	Version   string
+	PURL      string
+	Ecosystem string
+}
+
+// purlEcosystems maps package-url types to the ecosystem names used by the vulnerability database
+var purlEcosystems = map[string]string{
+	"golang":   "go",
+	"cargo":    "crates.io",
+	"deb":      "debian",
+	"apk":      "alpine",
+	"gem":      "rubygems",
+	"composer": "packagist",
+}
+
+type spdxDocument struct {
+	SPDXVersion string `json:"spdxVersion"`
+	Packages    []struct {
+		Name         string `json:"name"`
+		VersionInfo  string `json:"versionInfo"`
+		ExternalRefs []struct {
+			ReferenceType    string `json:"referenceType"`
+			ReferenceLocator string `json:"referenceLocator"`
+		} `json:"externalRefs"`
+	} `json:"packages"`
+}
+
+type cycloneDXComponent struct {
+	Name       string               `json:"name"`
+	Group      string               `json:"group"`
+	Version    string               `json:"version"`
+	PURL       string               `json:"purl"`
+	Components []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXDocument struct {
+	BOMFormat  string               `json:"bomFormat"`
+	Components []cycloneDXComponent `json:"components"`
+}
+
+// DetectFormat detects the format of the SBOM document
+func DetectFormat(content []byte) (apistructs.SBOMFormat, error) {
+	var probe struct {
+		SPDXVersion string `json:"spdxVersion"`
+		BOMFormat   string `json:"bomFormat"`
+	}
+	if err := json.Unmarshal(content, &probe); err != nil {
+		return "", errors.Wrap(err, "sbom is not a valid json document")
+	}
+	switch {
+	case probe.SPDXVersion != "":
+		return apistructs.SBOMFormatSPDX, nil
+	case strings.EqualFold(probe.BOMFormat, "CycloneDX"):
+		return apistructs.SBOMFormatCycloneDX, nil
+	}
+	return "", errors.New("unknown sbom format, only SPDX and CycloneDX json documents are supported")
+}
+
+// Parse parses the SBOM document and returns its components.
+// The format is detected from the content if it is empty.
+func Parse(format apistructs.SBOMFormat, content []byte) (apistructs.SBOMFormat, []Component, error) {
+	if format == "" {
+		detected, err := DetectFormat(content)
+		if err != nil {
+			return "", nil, err
+		}
+		format = detected
+	}
+	var (
+		components []Component
+		err        error
+	)
+	switch format {
+	case apistructs.SBOMFormatSPDX:
+		components, err = parseSPDX(content)
+	case apistructs.SBOMFormatCycloneDX:
+		components, err = parseCycloneDX(content)
+	default:
+		return "", nil, errors.Errorf("unsupported sbom format %s", format)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return format, components, nil
+}
+
+func parseSPDX(content []byte) ([]Component, error) {
+	var doc spdxDocument
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, errors.Wrap(err, "failed to parse spdx document")
+	}
+	if doc.SPDXVersion == "" {
+		return nil, errors.New("missing spdxVersion in spdx document")
+	}
+	components := make([]Component, 0, len(doc.Packages))
+	for _, pkg := range doc.Packages {
+		c := Component{Name: pkg.Name, Version: pkg.VersionInfo}
+		for _, ref := range pkg.ExternalRefs {
+			if strings.EqualFold(ref.ReferenceType, "purl") {
+				c.PURL = ref.ReferenceLocator
+				break
+			}
+		}
+		c.Ecosystem = ecosystemOfPURL(c.PURL)
+		components = append(components, c)
+	}
+	return components, nil
+}
+
+func parseCycloneDX(content []byte) ([]Component, error) {
+	var doc cycloneDXDocument
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, errors.Wrap(err, "failed to parse cyclonedx document")
+	}
+	if !strings.EqualFold(doc.BOMFormat, "CycloneDX") {
+		return nil, errors.New("bomFormat of cyclonedx document must be CycloneDX")
+	}
+	var components []Component
+	var walk func(list []cycloneDXComponent)
+	walk = func(list []cycloneDXComponent) {
+		for _, item := range list {
+			name := item.Name
+			if item.Group != "" {
+				name = item.Group + "/" + item.Name
+			}
+			components = append(components, Component{
+				Name:      name,
+				Version:   item.Version,
+				PURL:      item.PURL,
+				Ecosystem: ecosystemOfPURL(item.PURL),
+			})
+			walk(item.Components)
+		}
+	}
+	walk(doc.Components)
+	return components, nil
+}
+
+// ecosystemOfPURL returns the ecosystem of a package url like pkg:npm/lodash@4.17.20
+func ecosystemOfPURL(purl string) string {
+	typ, _, _ := splitPURL(purl)
+	if eco, ok := purlEcosystems[typ]; ok {
+		return eco
+	}
+	return typ
+}
+
+// splitPURL returns the type, namespace and name of a package url
+func splitPURL(purl string) (typ, namespace, name string) {
+	if !strings.HasPrefix(purl, "pkg:") {
+		return "", "", ""
+	}
+	rest := strings.TrimPrefix(purl, "pkg:")
+	if i := strings.IndexAny(rest, "?#"); i >= 0 {
+		rest = rest[:i]
+	}
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		rest = rest[:i]
+	}
+	parts := strings.Split(rest, "/")
+	for i := range parts {
+		if unescaped, err := url.PathUnescape(parts[i]); err == nil {
+			parts[i] = unescaped
+		}
+	}
+	typ = strings.ToLower(parts[0])
+	if len(parts) == 1 {
+		return typ, "", ""
+	}
+	name = parts[len(parts)-1]
+	namespace = strings.Join(parts[1:len(parts)-1], "/")
+	return typ, namespace, name
+}
+
+// packageNames returns all names the component may be recorded under in the vulnerability database,
+// e.g. maven packages are recorded as group:artifact while go modules use the full module path.
+func (c Component) packageNames() []string {
+	names := []string{strings.ToLower(c.Name)}
+	_, namespace, name := splitPURL(c.PURL)
+	if name != "" {
+		names = append(names, strings.ToLower(name))
+		if namespace != "" {
+			names = append(names,
+				strings.ToLower(namespace+"/"+name),
+				strings.ToLower(namespace+":"+name),
+			)
+		}
+	}
+	return strutil.DedupSlice(names, true)
+}
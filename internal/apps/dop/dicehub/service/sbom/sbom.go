@@ -0,0 +1,491 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sbom generates and ingests the SBOM of release images, scans them against
+// the offline vulnerability database and enforces the vulnerability gate on promotion.
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/dicehub/dbclient"
+	imagedb "github.com/erda-project/erda/internal/apps/dop/dicehub/image/db"
+	"github.com/erda-project/erda/internal/apps/dop/dicehub/service/apierrors"
+	"github.com/erda-project/erda/pkg/database/dbengine"
+	"github.com/erda-project/erda/pkg/http/httpserver/errorresp"
+	"github.com/erda-project/erda/pkg/strutil"
+)
+
+const defaultGenerateTimeout = 5 * time.Minute
+
+// SBOM is the handle to operate release sboms, vulnerabilities and gates
+type SBOM struct {
+	db              *dbclient.DBClient
+	generator       string
+	generateTimeout time.Duration
+}
+
+// New returns a *SBOM
+func New(options ...Option) *SBOM {
+	s := &SBOM{generateTimeout: defaultGenerateTimeout}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// GeneratorEnabled reports whether the sbom generator is configured
+func (s *SBOM) GeneratorEnabled() bool {
+	return strings.TrimSpace(s.generator) != ""
+}
+
+// Ingest stores the uploaded sbom of a release image and scans it
+func (s *SBOM) Ingest(req *apistructs.ReleaseSBOMIngestRequest) (*apistructs.ReleaseSBOM, *errorresp.APIError) {
+	if req.Image == "" {
+		return nil, apierrors.ErrIngestSBOM.MissingParameter("image")
+	}
+	if len(req.Content) == 0 {
+		return nil, apierrors.ErrIngestSBOM.MissingParameter("content")
+	}
+	images, err := s.releaseImages(req.ReleaseID)
+	if err != nil {
+		return nil, apierrors.ErrIngestSBOM.InternalError(err)
+	}
+	if !strutil.Exist(images, req.Image) {
+		return nil, apierrors.ErrIngestSBOM.InvalidParameter(fmt.Sprintf("image %s does not belong to release %s", req.Image, req.ReleaseID))
+	}
+	format, components, err := Parse(req.Format, req.Content)
+	if err != nil {
+		return nil, apierrors.ErrIngestSBOM.InvalidParameter(err)
+	}
+	record, err := s.save(req.ReleaseID, req.Image, format, req.Content, components, sourceUpload)
+	if err != nil {
+		return nil, apierrors.ErrIngestSBOM.InternalError(err)
+	}
+	return toAPISBOM(record), nil
+}
+
+// Generate generates the sboms of all images of the release by the configured generator and scans them
+func (s *SBOM) Generate(releaseID string) ([]apistructs.ReleaseSBOM, *errorresp.APIError) {
+	if !s.GeneratorEnabled() {
+		return nil, apierrors.ErrGenerateSBOM.InvalidState("sbom generator is not configured")
+	}
+	images, err := s.releaseImages(releaseID)
+	if err != nil {
+		return nil, apierrors.ErrGenerateSBOM.InternalError(err)
+	}
+	var result []apistructs.ReleaseSBOM
+	for _, image := range images {
+		content, err := s.runGenerator(image)
+		if err != nil {
+			return nil, apierrors.ErrGenerateSBOM.InternalError(errors.Wrapf(err, "image %s", image))
+		}
+		format, components, err := Parse("", content)
+		if err != nil {
+			return nil, apierrors.ErrGenerateSBOM.InternalError(errors.Wrapf(err, "image %s", image))
+		}
+		record, err := s.save(releaseID, image, format, content, components, sourceGenerator)
+		if err != nil {
+			return nil, apierrors.ErrGenerateSBOM.InternalError(errors.Wrapf(err, "image %s", image))
+		}
+		result = append(result, *toAPISBOM(record))
+	}
+	return result, nil
+}
+
+// List lists the sboms of the release
+func (s *SBOM) List(releaseID string) ([]apistructs.ReleaseSBOM, *errorresp.APIError) {
+	var records []ImageSBOM
+	if err := s.db.Select("id, created_at, updated_at, release_id, image, format, source, components, scanned_at").
+		Where("release_id = ?", releaseID).Order("id").Find(&records).Error; err != nil {
+		return nil, apierrors.ErrListSBOM.InternalError(err)
+	}
+	result := make([]apistructs.ReleaseSBOM, 0, len(records))
+	for i := range records {
+		result = append(result, *toAPISBOM(&records[i]))
+	}
+	return result, nil
+}
+
+// Scan rescans all sboms of the release, it should be called after the vulnerability database is updated
+func (s *SBOM) Scan(releaseID string) *errorresp.APIError {
+	var records []ImageSBOM
+	if err := s.db.Where("release_id = ?", releaseID).Find(&records).Error; err != nil {
+		return apierrors.ErrScanVulnerability.InternalError(err)
+	}
+	for i := range records {
+		_, components, err := Parse(apistructs.SBOMFormat(records[i].Format), []byte(records[i].Content))
+		if err != nil {
+			return apierrors.ErrScanVulnerability.InternalError(errors.Wrapf(err, "image %s", records[i].Image))
+		}
+		if err := s.scan(&records[i], components); err != nil {
+			return apierrors.ErrScanVulnerability.InternalError(err)
+		}
+	}
+	return nil
+}
+
+// ListVulnerabilities lists the findings of the release, the findings of a project release
+// are aggregated from its application releases
+func (s *SBOM) ListVulnerabilities(releaseID string) (*apistructs.ReleaseVulnerabilityListData, *errorresp.APIError) {
+	data, _, err := s.collect(releaseID)
+	if err != nil {
+		return nil, apierrors.ErrListVulnerability.InternalError(err)
+	}
+	return data, nil
+}
+
+// ImportAdvisories imports entries into the offline vulnerability database,
+// entries with the same vulnId and package are replaced
+func (s *SBOM) ImportAdvisories(req *apistructs.VulnerabilityAdvisoryImportRequest) (int, *errorresp.APIError) {
+	for i, adv := range req.Advisories {
+		if adv.VulnID == "" || adv.Package == "" {
+			return 0, apierrors.ErrImportVulnerabilityAdvisory.InvalidParameter(fmt.Sprintf("advisories[%d]: vulnId and package are required", i))
+		}
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, adv := range req.Advisories {
+			if err := tx.Where("vuln_id = ? AND package = ?", adv.VulnID, adv.Package).Delete(&Advisory{}).Error; err != nil {
+				return err
+			}
+		}
+		for _, adv := range req.Advisories {
+			if err := tx.Create(&Advisory{
+				VulnID:     adv.VulnID,
+				Ecosystem:  strings.ToLower(adv.Ecosystem),
+				Package:    adv.Package,
+				Introduced: adv.Introduced,
+				Fixed:      adv.Fixed,
+				Severity:   string(apistructs.NormalizeVulnerabilitySeverity(string(adv.Severity))),
+				Summary:    strutil.Truncate(adv.Summary, 1024),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, apierrors.ErrImportVulnerabilityAdvisory.InternalError(err)
+	}
+	return len(req.Advisories), nil
+}
+
+// GetGate returns the vulnerability gate of the project workspace, a disabled gate is returned if not configured
+func (s *SBOM) GetGate(projectID uint64, workspace string) (*apistructs.ReleaseVulnerabilityGate, *errorresp.APIError) {
+	gate, err := s.getGate(projectID, workspace)
+	if err != nil {
+		return nil, apierrors.ErrGetVulnerabilityGate.InternalError(err)
+	}
+	return gate, nil
+}
+
+// UpdateGate creates or updates the vulnerability gate of the project workspace
+func (s *SBOM) UpdateGate(gate *apistructs.ReleaseVulnerabilityGate) (*apistructs.ReleaseVulnerabilityGate, *errorresp.APIError) {
+	if gate.ProjectID == 0 {
+		return nil, apierrors.ErrUpdateVulnerabilityGate.MissingParameter("projectId")
+	}
+	if gate.Workspace == "" {
+		return nil, apierrors.ErrUpdateVulnerabilityGate.MissingParameter("workspace")
+	}
+	gate.Workspace = strings.ToUpper(gate.Workspace)
+	thresholds := make(map[apistructs.VulnerabilitySeverity]int, len(gate.Thresholds))
+	for severity, max := range gate.Thresholds {
+		normalized := apistructs.NormalizeVulnerabilitySeverity(string(severity))
+		if normalized == apistructs.VulnerabilitySeverityUnknown && !strings.EqualFold(string(severity), string(normalized)) {
+			return nil, apierrors.ErrUpdateVulnerabilityGate.InvalidParameter(fmt.Sprintf("invalid severity %s", severity))
+		}
+		thresholds[normalized] = max
+	}
+	gate.Thresholds = thresholds
+	b, err := json.Marshal(thresholds)
+	if err != nil {
+		return nil, apierrors.ErrUpdateVulnerabilityGate.InternalError(err)
+	}
+
+	var record Gate
+	err = s.db.Where("project_id = ? AND workspace = ?", gate.ProjectID, gate.Workspace).First(&record).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, apierrors.ErrUpdateVulnerabilityGate.InternalError(err)
+	}
+	record.ProjectID = gate.ProjectID
+	record.Workspace = gate.Workspace
+	record.Enabled = gate.Enabled
+	record.Thresholds = string(b)
+	record.RequireSBOM = gate.RequireSBOM
+	if err := s.db.Save(&record).Error; err != nil {
+		return nil, apierrors.ErrUpdateVulnerabilityGate.InternalError(err)
+	}
+	return gate, nil
+}
+
+// CheckGate checks whether the release can be promoted to the workspace
+func (s *SBOM) CheckGate(req *apistructs.ReleaseVulnerabilityGateCheckRequest) (*apistructs.ReleaseVulnerabilityGateCheckResult, *errorresp.APIError) {
+	if req.ReleaseID == "" {
+		return nil, apierrors.ErrCheckVulnerabilityGate.MissingParameter("releaseId")
+	}
+	if req.Workspace == "" {
+		return nil, apierrors.ErrCheckVulnerabilityGate.MissingParameter("workspace")
+	}
+	release, err := s.db.GetRelease(req.ReleaseID)
+	if err != nil {
+		if errors.Is(err, dbengine.ErrNotFound) {
+			return nil, apierrors.ErrCheckVulnerabilityGate.NotFound()
+		}
+		return nil, apierrors.ErrCheckVulnerabilityGate.InternalError(err)
+	}
+	gate, err := s.getGate(uint64(release.ProjectID), req.Workspace)
+	if err != nil {
+		return nil, apierrors.ErrCheckVulnerabilityGate.InternalError(err)
+	}
+	data, withoutSBOM, err := s.collect(req.ReleaseID)
+	if err != nil {
+		return nil, apierrors.ErrCheckVulnerabilityGate.InternalError(err)
+	}
+	result := &apistructs.ReleaseVulnerabilityGateCheckResult{
+		Passed:  true,
+		Enabled: gate.Enabled,
+		Summary: data.Summary,
+	}
+	if !gate.Enabled {
+		return result, nil
+	}
+	result.Violations = evaluateGate(gate, data.Summary, withoutSBOM)
+	result.Passed = len(result.Violations) == 0
+	return result, nil
+}
+
+func (s *SBOM) getGate(projectID uint64, workspace string) (*apistructs.ReleaseVulnerabilityGate, error) {
+	workspace = strings.ToUpper(workspace)
+	var record Gate
+	err := s.db.Where("project_id = ? AND workspace = ?", projectID, workspace).First(&record).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return &apistructs.ReleaseVulnerabilityGate{
+			ProjectID:  projectID,
+			Workspace:  workspace,
+			Thresholds: map[apistructs.VulnerabilitySeverity]int{},
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	gate := &apistructs.ReleaseVulnerabilityGate{
+		ProjectID:   record.ProjectID,
+		Workspace:   record.Workspace,
+		Enabled:     record.Enabled,
+		Thresholds:  map[apistructs.VulnerabilitySeverity]int{},
+		RequireSBOM: record.RequireSBOM,
+	}
+	if record.Thresholds != "" {
+		if err := json.Unmarshal([]byte(record.Thresholds), &gate.Thresholds); err != nil {
+			return nil, errors.Wrap(err, "invalid thresholds of vulnerability gate")
+		}
+	}
+	return gate, nil
+}
+
+// collect collects the findings of the release and the images which have no sbom
+func (s *SBOM) collect(releaseID string) (*apistructs.ReleaseVulnerabilityListData, []string, error) {
+	releaseIDs, err := s.expandReleaseIDs(releaseID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var images []imagedb.Image
+	if err := s.db.Where("release_id IN (?)", releaseIDs).Find(&images).Error; err != nil {
+		return nil, nil, err
+	}
+	var sboms []ImageSBOM
+	if err := s.db.Select("release_id, image, scanned_at").Where("release_id IN (?)", releaseIDs).
+		Find(&sboms).Error; err != nil {
+		return nil, nil, err
+	}
+	var findings []Vulnerability
+	if err := s.db.Where("release_id IN (?)", releaseIDs).Order("id").Find(&findings).Error; err != nil {
+		return nil, nil, err
+	}
+
+	data := &apistructs.ReleaseVulnerabilityListData{
+		ReleaseID: releaseID,
+		Images:    make(map[string]apistructs.ReleaseImageScanInfo),
+		List:      make([]apistructs.ReleaseVulnerability, 0, len(findings)),
+	}
+	for _, image := range images {
+		data.Images[image.Image] = apistructs.ReleaseImageScanInfo{}
+	}
+	for i := range sboms {
+		data.Images[sboms[i].Image] = apistructs.ReleaseImageScanInfo{HasSBOM: true}
+		if scannedAt := sboms[i].ScannedAt; scannedAt != nil && (data.ScannedAt == nil || scannedAt.After(*data.ScannedAt)) {
+			data.ScannedAt = scannedAt
+		}
+	}
+	var withoutSBOM []string
+	for image, info := range data.Images {
+		if !info.HasSBOM {
+			withoutSBOM = append(withoutSBOM, image)
+		}
+	}
+	sort.Strings(withoutSBOM)
+	for _, f := range findings {
+		data.List = append(data.List, apistructs.ReleaseVulnerability{
+			ReleaseID:    f.ReleaseID,
+			Image:        f.Image,
+			VulnID:       f.VulnID,
+			Package:      f.Package,
+			Version:      f.Version,
+			FixedVersion: f.FixedVersion,
+			Severity:     apistructs.VulnerabilitySeverity(f.Severity),
+			Summary:      f.Summary,
+		})
+	}
+	data.Summary = summarize(data.List)
+	return data, withoutSBOM, nil
+}
+
+// expandReleaseIDs returns the release itself and, for a project release, all its application releases
+func (s *SBOM) expandReleaseIDs(releaseID string) ([]string, error) {
+	release, err := s.db.GetRelease(releaseID)
+	if err != nil {
+		return nil, err
+	}
+	if !release.IsProjectRelease || release.Modes == "" {
+		return []string{releaseID}, nil
+	}
+	modes := make(map[string]apistructs.ReleaseDeployMode)
+	if err := json.Unmarshal([]byte(release.Modes), &modes); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal modes of project release")
+	}
+	ids := []string{releaseID}
+	for _, mode := range modes {
+		for _, group := range mode.ApplicationReleaseList {
+			ids = append(ids, group...)
+		}
+	}
+	return strutil.DedupSlice(ids, true), nil
+}
+
+func (s *SBOM) releaseImages(releaseID string) ([]string, error) {
+	var images []imagedb.Image
+	if err := s.db.Where("release_id = ?", releaseID).Find(&images).Error; err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(images))
+	for _, image := range images {
+		result = append(result, image.Image)
+	}
+	return strutil.DedupSlice(result, true), nil
+}
+
+// save replaces the sbom of the release image and scans it
+func (s *SBOM) save(releaseID, image string, format apistructs.SBOMFormat, content []byte, components []Component,
+	source string) (*ImageSBOM, error) {
+	var record ImageSBOM
+	err := s.db.Where("release_id = ? AND image = ?", releaseID, image).First(&record).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+	record.ReleaseID = releaseID
+	record.Image = image
+	record.Format = string(format)
+	record.Source = source
+	record.Content = string(content)
+	record.Components = len(components)
+	if err := s.db.Save(&record).Error; err != nil {
+		return nil, err
+	}
+	if err := s.scan(&record, components); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// scan matches the components of the sbom against the vulnerability database and replaces the findings of the image
+func (s *SBOM) scan(record *ImageSBOM, components []Component) error {
+	var names []string
+	for _, c := range components {
+		names = append(names, c.packageNames()...)
+	}
+	var advisories []Advisory
+	if len(names) > 0 {
+		if err := s.db.Where("LOWER(package) IN (?)", strutil.DedupSlice(names, true)).Find(&advisories).Error; err != nil {
+			return err
+		}
+	}
+	findings := matchAdvisories(record.ReleaseID, record.Image, components, advisories)
+	now := time.Now()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("release_id = ? AND image = ?", record.ReleaseID, record.Image).
+			Delete(&Vulnerability{}).Error; err != nil {
+			return err
+		}
+		for i := range findings {
+			if err := tx.Create(&findings[i]).Error; err != nil {
+				return err
+			}
+		}
+		record.ScannedAt = &now
+		return tx.Model(record).Update("scanned_at", now).Error
+	})
+}
+
+// runGenerator runs the generator command for the image and returns its stdout as the sbom document.
+// The {image} placeholder in the command is replaced by the image, or the image is appended if absent.
+func (s *SBOM) runGenerator(image string) ([]byte, error) {
+	args := strings.Fields(s.generator)
+	replaced := false
+	for i := range args {
+		if strings.Contains(args[i], "{image}") {
+			args[i] = strings.ReplaceAll(args[i], "{image}", image)
+			replaced = true
+		}
+	}
+	if !replaced {
+		args = append(args, image)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.generateTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		logrus.Errorf("failed to generate sbom of image %s, stderr: %s", image, stderr.String())
+		return nil, errors.Wrapf(err, "failed to run sbom generator")
+	}
+	return stdout.Bytes(), nil
+}
+
+func toAPISBOM(record *ImageSBOM) *apistructs.ReleaseSBOM {
+	return &apistructs.ReleaseSBOM{
+		ID:         record.ID,
+		ReleaseID:  record.ReleaseID,
+		Image:      record.Image,
+		Format:     apistructs.SBOMFormat(record.Format),
+		Source:     record.Source,
+		Components: record.Components,
+		CreatedAt:  record.CreatedAt,
+		UpdatedAt:  record.UpdatedAt,
+	}
+}
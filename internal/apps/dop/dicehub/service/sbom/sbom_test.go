@@ -0,0 +1,167 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+const spdxDoc = `{
+  "spdxVersion": "SPDX-2.3",
+  "packages": [
+    {
+      "name": "log4j-core",
+      "versionInfo": "2.14.1",
+      "externalRefs": [
+        {"referenceType": "purl", "referenceLocator": "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1"}
+      ]
+    },
+    {"name": "openssl", "versionInfo": "1.1.1k"}
+  ]
+}`
+
+const cycloneDXDoc = `{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.4",
+  "components": [
+    {
+      "name": "lodash",
+      "version": "4.17.20",
+      "purl": "pkg:npm/lodash@4.17.20",
+      "components": [
+        {"name": "minimist", "version": "1.2.5", "purl": "pkg:npm/minimist@1.2.5"}
+      ]
+    },
+    {"name": "golang.org/x/text", "version": "v0.3.6", "purl": "pkg:golang/golang.org/x/text@v0.3.6"}
+  ]
+}`
+
+func TestParse(t *testing.T) {
+	format, components, err := Parse("", []byte(spdxDoc))
+	assert.NoError(t, err)
+	assert.Equal(t, apistructs.SBOMFormatSPDX, format)
+	assert.Len(t, components, 2)
+	assert.Equal(t, "maven", components[0].Ecosystem)
+	assert.Contains(t, components[0].packageNames(), "org.apache.logging.log4j:log4j-core")
+
+	format, components, err = Parse("", []byte(cycloneDXDoc))
+	assert.NoError(t, err)
+	assert.Equal(t, apistructs.SBOMFormatCycloneDX, format)
+	assert.Len(t, components, 3)
+	assert.Equal(t, "minimist", components[1].Name)
+	assert.Equal(t, "go", components[2].Ecosystem)
+	assert.Contains(t, components[2].packageNames(), "golang.org/x/text")
+
+	_, _, err = Parse("", []byte(`{"foo": "bar"}`))
+	assert.Error(t, err)
+	_, _, err = Parse(apistructs.SBOMFormatCycloneDX, []byte(spdxDoc))
+	assert.Error(t, err)
+}
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2.10", "1.2.9", 1},
+		{"1.2", "1.2.1", -1},
+		{"1.0.0-rc1", "1.0.0", -1},
+		{"1.0.0-rc2", "1.0.0-rc10", -1},
+		{"1.1.1k", "1.1.1l", -1},
+		{"1:2.30-1", "2.29", 1},
+		{"1.0.0+build5", "1.0.0", 0},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, compareVersions(c.a, c.b), "%s vs %s", c.a, c.b)
+		assert.Equal(t, -c.want, compareVersions(c.b, c.a), "%s vs %s", c.b, c.a)
+	}
+}
+
+func TestAffected(t *testing.T) {
+	assert.True(t, affected("2.14.1", "2.0", "2.15.0"))
+	assert.False(t, affected("2.15.0", "2.0", "2.15.0"))
+	assert.False(t, affected("1.9", "2.0", "2.15.0"))
+	assert.True(t, affected("4.17.20", "0", "4.17.21"))
+	assert.True(t, affected("0.1", "", ""))
+	assert.False(t, affected("", "", "1.0"))
+}
+
+func TestMatchAdvisories(t *testing.T) {
+	_, components, err := Parse("", []byte(spdxDoc))
+	assert.NoError(t, err)
+	advisories := []Advisory{
+		{VulnID: "CVE-2021-44228", Ecosystem: "maven", Package: "org.apache.logging.log4j:log4j-core",
+			Introduced: "2.0-beta9", Fixed: "2.15.0", Severity: "critical"},
+		{VulnID: "CVE-2021-45046", Ecosystem: "maven", Package: "org.apache.logging.log4j:log4j-core",
+			Introduced: "2.0", Fixed: "2.12.2", Severity: "CRITICAL"},
+		{VulnID: "CVE-2021-3711", Package: "openssl", Fixed: "1.1.1l", Severity: "moderate"},
+		{VulnID: "GHSA-npm", Ecosystem: "npm", Package: "openssl", Fixed: "9.9.9", Severity: "HIGH"},
+	}
+	findings := matchAdvisories("r1", "img:1", components, advisories)
+	assert.Len(t, findings, 2)
+	assert.Equal(t, "CVE-2021-44228", findings[0].VulnID)
+	assert.Equal(t, "CRITICAL", findings[0].Severity)
+	assert.Equal(t, "log4j-core", findings[0].Package)
+	assert.Equal(t, "CVE-2021-3711", findings[1].VulnID)
+	assert.Equal(t, "MEDIUM", findings[1].Severity)
+	assert.Equal(t, "img:1", findings[1].Image)
+}
+
+func TestEvaluateGate(t *testing.T) {
+	summary := summarize([]apistructs.ReleaseVulnerability{
+		{Severity: apistructs.VulnerabilitySeverityCritical},
+		{Severity: apistructs.VulnerabilitySeverityHigh},
+		{Severity: apistructs.VulnerabilitySeverityHigh},
+	})
+	assert.Equal(t, 0, summary[apistructs.VulnerabilitySeverityLow])
+
+	gate := &apistructs.ReleaseVulnerabilityGate{
+		Enabled: true,
+		Thresholds: map[apistructs.VulnerabilitySeverity]int{
+			apistructs.VulnerabilitySeverityCritical: 0,
+			apistructs.VulnerabilitySeverityHigh:     5,
+			apistructs.VulnerabilitySeverityMedium:   -1,
+		},
+	}
+	violations := evaluateGate(gate, summary, []string{"img:2"})
+	assert.Equal(t, []string{"1 CRITICAL vulnerabilities found, at most 0 allowed"}, violations)
+
+	gate.RequireSBOM = true
+	gate.Thresholds[apistructs.VulnerabilitySeverityHigh] = 1
+	violations = evaluateGate(gate, summary, []string{"img:2"})
+	assert.Len(t, violations, 3)
+	assert.Equal(t, "image img:2 has no sbom", violations[0])
+}
+
+func TestRunGenerator(t *testing.T) {
+	s := New(WithGenerator("echo --image={image}"))
+	assert.True(t, s.GeneratorEnabled())
+	out, err := s.runGenerator("nginx:1.21")
+	assert.NoError(t, err)
+	assert.Equal(t, "--image=nginx:1.21\n", string(out))
+
+	s = New(WithGenerator("echo"))
+	out, err = s.runGenerator("nginx:1.21")
+	assert.NoError(t, err)
+	assert.Equal(t, "nginx:1.21\n", string(out))
+
+	assert.False(t, New().GeneratorEnabled())
+}
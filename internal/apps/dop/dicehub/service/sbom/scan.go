@@ -0,0 +1,202 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+// compareVersions compares two package versions, returns -1, 0 or 1.
+// Versions are split into numeric and alphabetic tokens, numeric tokens are compared as numbers
+// and a version with an extra alphabetic token (e.g. 1.0.0-rc1) is lower than the one without.
+func compareVersions(a, b string) int {
+	ta, tb := versionTokens(a), versionTokens(b)
+	for i := 0; i < len(ta) || i < len(tb); i++ {
+		switch {
+		case i >= len(ta):
+			if isNumeric(tb[i]) {
+				return -1
+			}
+			return 1
+		case i >= len(tb):
+			if isNumeric(ta[i]) {
+				return 1
+			}
+			return -1
+		}
+		if c := compareToken(ta[i], tb[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareToken(a, b string) int {
+	an, bn := isNumeric(a), isNumeric(b)
+	switch {
+	case an && bn:
+		ia, _ := strconv.ParseUint(a, 10, 64)
+		ib, _ := strconv.ParseUint(b, 10, 64)
+		switch {
+		case ia < ib:
+			return -1
+		case ia > ib:
+			return 1
+		}
+		return 0
+	case an:
+		return 1
+	case bn:
+		return -1
+	}
+	return strings.Compare(a, b)
+}
+
+func versionTokens(v string) []string {
+	v = strings.TrimPrefix(strings.TrimSpace(strings.ToLower(v)), "v")
+	// drop the epoch of debian/rpm versions and the build metadata of semver
+	if i := strings.Index(v, ":"); i >= 0 {
+		v = v[i+1:]
+	}
+	if i := strings.Index(v, "+"); i >= 0 {
+		v = v[:i]
+	}
+	var (
+		tokens []string
+		cur    strings.Builder
+		digit  bool
+	)
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range v {
+		switch {
+		case unicode.IsDigit(r):
+			if !digit {
+				flush()
+			}
+			digit = true
+			cur.WriteRune(r)
+		case unicode.IsLetter(r):
+			if digit {
+				flush()
+			}
+			digit = false
+			cur.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+func isNumeric(token string) bool {
+	return token != "" && unicode.IsDigit(rune(token[0]))
+}
+
+// affected reports whether the version falls into the range [introduced, fixed)
+func affected(version, introduced, fixed string) bool {
+	if version == "" {
+		return false
+	}
+	if introduced != "" && introduced != "0" && compareVersions(version, introduced) < 0 {
+		return false
+	}
+	return fixed == "" || compareVersions(version, fixed) < 0
+}
+
+// matchAdvisories matches the components of an image against the advisories
+func matchAdvisories(releaseID, image string, components []Component, advisories []Advisory) []Vulnerability {
+	byPackage := make(map[string][]Advisory)
+	for _, adv := range advisories {
+		key := strings.ToLower(adv.Package)
+		byPackage[key] = append(byPackage[key], adv)
+	}
+	var (
+		findings []Vulnerability
+		seen     = make(map[string]struct{})
+	)
+	for _, c := range components {
+		for _, name := range c.packageNames() {
+			for _, adv := range byPackage[name] {
+				if adv.Ecosystem != "" && !strings.EqualFold(adv.Ecosystem, c.Ecosystem) {
+					continue
+				}
+				if !affected(c.Version, adv.Introduced, adv.Fixed) {
+					continue
+				}
+				key := adv.VulnID + "/" + name + "@" + c.Version
+				if _, ok := seen[key]; ok {
+					continue
+				}
+				seen[key] = struct{}{}
+				findings = append(findings, Vulnerability{
+					ReleaseID:    releaseID,
+					Image:        image,
+					VulnID:       adv.VulnID,
+					Package:      c.Name,
+					Version:      c.Version,
+					FixedVersion: adv.Fixed,
+					Severity:     string(apistructs.NormalizeVulnerabilitySeverity(adv.Severity)),
+					Summary:      adv.Summary,
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// summarize counts the findings by severity
+func summarize(findings []apistructs.ReleaseVulnerability) map[apistructs.VulnerabilitySeverity]int {
+	summary := make(map[apistructs.VulnerabilitySeverity]int, len(apistructs.VulnerabilitySeverities))
+	for _, severity := range apistructs.VulnerabilitySeverities {
+		summary[severity] = 0
+	}
+	for _, f := range findings {
+		summary[f.Severity]++
+	}
+	return summary
+}
+
+// evaluateGate returns the reasons why the release does not pass the gate
+func evaluateGate(gate *apistructs.ReleaseVulnerabilityGate, summary map[apistructs.VulnerabilitySeverity]int,
+	imagesWithoutSBOM []string) []string {
+	var violations []string
+	if gate.RequireSBOM {
+		for _, image := range imagesWithoutSBOM {
+			violations = append(violations, fmt.Sprintf("image %s has no sbom", image))
+		}
+	}
+	for _, severity := range apistructs.VulnerabilitySeverities {
+		max, ok := gate.Thresholds[severity]
+		if !ok || max < 0 {
+			continue
+		}
+		if count := summary[severity]; count > max {
+			violations = append(violations, fmt.Sprintf("%d %s vulnerabilities found, at most %d allowed",
+				count, severity, max))
+		}
+	}
+	return violations
+}
@@ -357,7 +357,7 @@ func (e *Endpoints) ExecuteManualTestAPI(ctx context.Context, r *http.Request, v
 
 		if len(apiTest.API.Asserts) > 0 {
 			asserts := apiTest.API.Asserts[0]
-			succ, assertResult := apiTest.JudgeAsserts(outParams, asserts)
+			succ, assertResult := apiTest.JudgeAssertsWithResp(apiResp, outParams, asserts)
 			logrus.Infof("judge assert result: %v", succ)
 
 			respData.Asserts = &apistructs.APITestsAssertResult{
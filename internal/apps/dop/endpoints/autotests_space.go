@@ -26,6 +26,7 @@ import (
 	"github.com/erda-project/erda/internal/pkg/user"
 	"github.com/erda-project/erda/pkg/http/httpserver"
 	"github.com/erda-project/erda/pkg/http/httpserver/errorresp"
+	"github.com/erda-project/erda/pkg/swagger/oasconv"
 )
 
 // CreateAutoTestSpace 创建测试空间
@@ -322,6 +323,80 @@ func (e *Endpoints) ImportAutotestSpace(ctx context.Context, r *http.Request, va
 	}, nil
 }
 
+// ImportAutotestSpaceFromAPIAsset import the spec of api asset version as autotest scenes
+func (e *Endpoints) ImportAutotestSpaceFromAPIAsset(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrImportAutoTestSpace.NotLogin().ToResp(), nil
+	}
+	orgID, err := user.GetOrgID(r)
+	if err != nil {
+		return apierrors.ErrImportAutoTestSpace.MissingParameter("orgID").ToResp(), nil
+	}
+
+	if r.ContentLength == 0 {
+		return apierrors.ErrImportAutoTestSpace.MissingParameter("request body").ToResp(), nil
+	}
+	var req apistructs.AutoTestAPIAssetImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierrors.ErrImportAutoTestSpace.InvalidParameter(err).ToResp(), nil
+	}
+	req.OrgID = orgID
+	req.IdentityInfo = identityInfo
+
+	// permission check
+	if !identityInfo.IsInternalClient() {
+		access, err := e.bdl.CheckPermission(&apistructs.PermissionCheckRequest{
+			UserID:   identityInfo.UserID,
+			Scope:    apistructs.ProjectScope,
+			ScopeID:  req.ProjectID,
+			Resource: apistructs.TestSpaceResource,
+			Action:   apistructs.CreateAction,
+		})
+		if err != nil {
+			return apierrors.ErrImportAutoTestSpace.InvalidParameter(err).ToResp(), nil
+		}
+		if !access.Access {
+			return apierrors.ErrImportAutoTestSpace.AccessDenied().ToResp(), nil
+		}
+	}
+
+	spec, apiErr := e.assetSvc.DownloadSpecText(&apistructs.DownloadSpecTextReq{
+		OrgID:    orgID,
+		Identity: &identityInfo,
+		URIParams: &apistructs.DownloadSpecTextURIParams{
+			AssetID:   req.AssetID,
+			VersionID: req.VersionID,
+		},
+		QueryParams: &apistructs.DownloadSpecTextQueryParams{SpecProtocol: oasconv.OAS3JSON.String()},
+	})
+	if apiErr != nil {
+		return apiErr.ToResp(), nil
+	}
+
+	recordID, err := e.autotestV2.ImportAPIAsset(req, spec)
+	if err != nil {
+		return errorresp.ErrResp(err)
+	}
+
+	actionType := apistructs.FileSpaceActionTypeImport
+	if req.SpaceID != 0 {
+		actionType = apistructs.FileSceneSetActionTypeImport
+	}
+	ok, _, err := e.testcase.GetFirstFileReady(actionType)
+	if err != nil {
+		return errorresp.ErrResp(err)
+	}
+	if ok {
+		e.ImportChannel <- recordID
+	}
+
+	return httpserver.HTTPResponse{
+		Status:  http.StatusAccepted,
+		Content: recordID,
+	}, nil
+}
+
 func (e *Endpoints) AutotestSpaceStats(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
 	var req apistructs.AutoTestSpaceStatsRequest
 	if err := e.queryStringDecoder.Decode(&req, r.URL.Query()); err != nil {
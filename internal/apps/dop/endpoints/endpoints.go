@@ -50,8 +50,10 @@ import (
 	"github.com/erda-project/erda/internal/apps/dop/services/cq"
 	"github.com/erda-project/erda/internal/apps/dop/services/environment"
 	"github.com/erda-project/erda/internal/apps/dop/services/filetree"
+	"github.com/erda-project/erda/internal/apps/dop/services/flakytest"
 	"github.com/erda-project/erda/internal/apps/dop/services/issue"
 	"github.com/erda-project/erda/internal/apps/dop/services/issuestate"
+	"github.com/erda-project/erda/internal/apps/dop/services/issuesync"
 	"github.com/erda-project/erda/internal/apps/dop/services/iteration"
 	"github.com/erda-project/erda/internal/apps/dop/services/libreference"
 	"github.com/erda-project/erda/internal/apps/dop/services/migrate"
@@ -248,6 +250,39 @@ func (e *Endpoints) Routes() []httpserver.Endpoint {
 		// test platform
 		{Path: "/api/qa/actions/get-sonar-credential", Method: http.MethodGet, Handler: e.GetSonarCredential},
 
+		// flaky tests
+		{Path: "/api/applications/{applicationID}/flaky-tests", Method: http.MethodGet, Handler: e.ListFlakyTests},
+		{Path: "/api/applications/{applicationID}/flaky-tests/actions/trend", Method: http.MethodGet, Handler: e.GetFlakyTestTrend},
+		{Path: "/api/applications/{applicationID}/flaky-tests/{id}/actions/quarantine", Method: http.MethodPut, Handler: e.QuarantineFlakyTest},
+		{Path: "/api/qa/test-records/{id}/actions/gate", Method: http.MethodGet, Handler: e.GetTestReportGate},
+		{Path: "/api/qa/test-records/actions/gate", Method: http.MethodGet, Handler: e.GetTestReportGateByUUID},
+
+		// issue sync with jira and github
+		{Path: "/api/issue-sync/connections", Method: http.MethodGet, Handler: e.ListIssueSyncConnections},
+		{Path: "/api/issue-sync/connections", Method: http.MethodPost, Handler: e.CreateIssueSyncConnection},
+		{Path: "/api/issue-sync/connections/{id}", Method: http.MethodGet, Handler: e.GetIssueSyncConnection},
+		{Path: "/api/issue-sync/connections/{id}", Method: http.MethodPut, Handler: e.UpdateIssueSyncConnection},
+		{Path: "/api/issue-sync/connections/{id}", Method: http.MethodDelete, Handler: e.DeleteIssueSyncConnection},
+		{Path: "/api/issue-sync/connections/{id}/actions/sync", Method: http.MethodPost, Handler: e.SyncIssueSyncConnection},
+		{Path: "/api/issue-sync/connections/{id}/actions/push", Method: http.MethodPost, Handler: e.PushIssueToRemote},
+		{Path: "/api/issue-sync/connections/{id}/actions/webhook", Method: http.MethodPost, Handler: e.ReceiveIssueSyncWebhook},
+		{Path: "/api/issue-sync/issues/{issueID}/links", Method: http.MethodGet, Handler: e.ListIssueSyncLinks},
+
+		// issue query language
+		{Path: "/api/issues/actions/query", Method: http.MethodPost, Handler: e.QueryIssues},
+		{Path: "/api/issues/actions/query-stats", Method: http.MethodPost, Handler: e.QueryIssueStats},
+		{Path: "/api/issue-saved-queries", Method: http.MethodGet, Handler: e.ListIssueSavedQueries},
+		{Path: "/api/issue-saved-queries", Method: http.MethodPost, Handler: e.CreateIssueSavedQuery},
+		{Path: "/api/issue-saved-queries/{id}", Method: http.MethodGet, Handler: e.GetIssueSavedQuery},
+		{Path: "/api/issue-saved-queries/{id}", Method: http.MethodPut, Handler: e.UpdateIssueSavedQuery},
+		{Path: "/api/issue-saved-queries/{id}", Method: http.MethodDelete, Handler: e.DeleteIssueSavedQuery},
+
+		// issue state transition guards and post functions
+		{Path: "/api/issue-state-transition-rules", Method: http.MethodGet, Handler: e.ListIssueStateTransitionRules},
+		{Path: "/api/issue-state-transition-rules", Method: http.MethodPost, Handler: e.CreateIssueStateTransitionRule},
+		{Path: "/api/issue-state-transition-rules/{id}", Method: http.MethodPut, Handler: e.UpdateIssueStateTransitionRule},
+		{Path: "/api/issue-state-transition-rules/{id}", Method: http.MethodDelete, Handler: e.DeleteIssueStateTransitionRule},
+
 		// pmp api test
 		{Path: "/api/apitests", Method: http.MethodPost, Handler: e.CreateAPITest},
 		{Path: "/api/apitests/{id}", Method: http.MethodPut, Handler: e.UpdateApiTest},
@@ -346,6 +381,7 @@ func (e *Endpoints) Routes() []httpserver.Endpoint {
 		{Path: "/api/autotests/spaces/actions/copy", Method: http.MethodPost, Handler: e.CopyAutoTestSpaceV2},
 		{Path: "/api/autotests/spaces/actions/export", Method: http.MethodPost, Handler: e.ExportAutoTestSpace},
 		{Path: "/api/autotests/spaces/actions/import", Method: http.MethodPost, Handler: e.ImportAutotestSpace},
+		{Path: "/api/autotests/spaces/actions/import-api-asset", Method: http.MethodPost, Handler: e.ImportAutotestSpaceFromAPIAsset},
 		{Path: "/api/autotests/spaces/actions/stats", Method: http.MethodGet, Handler: e.AutotestSpaceStats},
 
 		// 自动化测试 - 场景
@@ -642,6 +678,8 @@ type Endpoints struct {
 	app             *application.Application
 	codeCoverageSvc *code_coverage.CodeCoverage
 	testReportSvc   *test_report.TestReport
+	flakyTest       *flakytest.Service
+	issueSync       *issuesync.Service
 
 	publishItem *publish_item.PublishItem
 
@@ -970,6 +1008,18 @@ func WithCodeCoverageExecRecord(svc *code_coverage.CodeCoverage) Option {
 	}
 }
 
+func WithFlakyTest(svc *flakytest.Service) Option {
+	return func(e *Endpoints) {
+		e.flakyTest = svc
+	}
+}
+
+func WithIssueSync(svc *issuesync.Service) Option {
+	return func(e *Endpoints) {
+		e.issueSync = svc
+	}
+}
+
 func WithTestReportRecord(svc *test_report.TestReport) Option {
 	return func(e *Endpoints) {
 		e.testReportSvc = svc
@@ -0,0 +1,179 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/dbclient"
+	"github.com/erda-project/erda/internal/apps/dop/services/apierrors"
+	"github.com/erda-project/erda/internal/pkg/user"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+	"github.com/erda-project/erda/pkg/strutil"
+)
+
+// ListFlakyTests 查询应用下的用例稳定性统计
+func (e *Endpoints) ListFlakyTests(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrListFlakyTests.NotLogin().ToResp(), nil
+	}
+	appID, err := strutil.Atoi64(vars["applicationID"])
+	if err != nil {
+		return apierrors.ErrListFlakyTests.InvalidParameter("applicationID").ToResp(), nil
+	}
+	var req apistructs.FlakyTestListRequest
+	if err := e.queryStringDecoder.Decode(&req, r.URL.Query()); err != nil {
+		return apierrors.ErrListFlakyTests.InvalidParameter(err).ToResp(), nil
+	}
+	req.ApplicationID = appID
+	if !e.checkFlakyTestPermission(identityInfo, appID, apistructs.GetAction) {
+		return apierrors.ErrListFlakyTests.AccessDenied().ToResp(), nil
+	}
+
+	data, err := e.flakyTest.List(req)
+	if err != nil {
+		return apierrors.ErrListFlakyTests.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(data)
+}
+
+// QuarantineFlakyTest 隔离或取消隔离用例
+func (e *Endpoints) QuarantineFlakyTest(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrQuarantineFlakyTest.NotLogin().ToResp(), nil
+	}
+	appID, err := strutil.Atoi64(vars["applicationID"])
+	if err != nil {
+		return apierrors.ErrQuarantineFlakyTest.InvalidParameter("applicationID").ToResp(), nil
+	}
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return apierrors.ErrQuarantineFlakyTest.InvalidParameter("id").ToResp(), nil
+	}
+	var req apistructs.FlakyTestQuarantineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierrors.ErrQuarantineFlakyTest.InvalidParameter(err).ToResp(), nil
+	}
+	req.IdentityInfo = identityInfo
+	if !e.checkFlakyTestPermission(identityInfo, appID, apistructs.UpdateAction) {
+		return apierrors.ErrQuarantineFlakyTest.AccessDenied().ToResp(), nil
+	}
+
+	test, err := e.flakyTest.Get(id)
+	if err != nil {
+		return apierrors.ErrQuarantineFlakyTest.NotFound().ToResp(), nil
+	}
+	if test.ApplicationID != appID {
+		return apierrors.ErrQuarantineFlakyTest.InvalidParameter("id").ToResp(), nil
+	}
+	test, err = e.flakyTest.Quarantine(id, req)
+	if err != nil {
+		return apierrors.ErrQuarantineFlakyTest.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(test)
+}
+
+// GetFlakyTestTrend 查询应用或单个用例的每日执行趋势
+func (e *Endpoints) GetFlakyTestTrend(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrGetFlakyTestTrend.NotLogin().ToResp(), nil
+	}
+	appID, err := strutil.Atoi64(vars["applicationID"])
+	if err != nil {
+		return apierrors.ErrGetFlakyTestTrend.InvalidParameter("applicationID").ToResp(), nil
+	}
+	var req apistructs.FlakyTestTrendRequest
+	if err := e.queryStringDecoder.Decode(&req, r.URL.Query()); err != nil {
+		return apierrors.ErrGetFlakyTestTrend.InvalidParameter(err).ToResp(), nil
+	}
+	req.ApplicationID = appID
+	if !e.checkFlakyTestPermission(identityInfo, appID, apistructs.GetAction) {
+		return apierrors.ErrGetFlakyTestTrend.AccessDenied().ToResp(), nil
+	}
+
+	points, err := e.flakyTest.Trend(req)
+	if err != nil {
+		return apierrors.ErrGetFlakyTestTrend.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(points)
+}
+
+// GetTestReportGate 按当前隔离状态计算单元测试记录的门禁结果
+func (e *Endpoints) GetTestReportGate(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrGetTestReportGate.NotLogin().ToResp(), nil
+	}
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return apierrors.ErrGetTestReportGate.InvalidParameter("id").ToResp(), nil
+	}
+
+	record, err := e.flakyTest.GetRecord(id)
+	if err != nil {
+		return apierrors.ErrGetTestReportGate.NotFound().ToResp(), nil
+	}
+	return e.testReportGate(identityInfo, record)
+}
+
+// GetTestReportGateByUUID 按单元测试 action 生成的 uuid 计算门禁结果, 供流水线判定单元测试任务是否通过
+func (e *Endpoints) GetTestReportGateByUUID(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrGetTestReportGate.NotLogin().ToResp(), nil
+	}
+	uuid := r.URL.Query().Get("uuid")
+	if uuid == "" {
+		return apierrors.ErrGetTestReportGate.MissingParameter("uuid").ToResp(), nil
+	}
+
+	record, err := e.flakyTest.GetRecordByUUID(uuid)
+	if err != nil {
+		return apierrors.ErrGetTestReportGate.NotFound().ToResp(), nil
+	}
+	return e.testReportGate(identityInfo, record)
+}
+
+func (e *Endpoints) testReportGate(identityInfo apistructs.IdentityInfo, record *dbclient.TPRecordDO) (httpserver.Responser, error) {
+	if !e.checkFlakyTestPermission(identityInfo, record.ApplicationID, apistructs.GetAction) {
+		return apierrors.ErrGetTestReportGate.AccessDenied().ToResp(), nil
+	}
+	gate, err := e.flakyTest.Gate(record)
+	if err != nil {
+		return apierrors.ErrGetTestReportGate.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(gate)
+}
+
+func (e *Endpoints) checkFlakyTestPermission(identityInfo apistructs.IdentityInfo, appID int64, action string) bool {
+	if identityInfo.IsInternalClient() {
+		return true
+	}
+	access, err := e.bdl.CheckPermission(&apistructs.PermissionCheckRequest{
+		UserID:   identityInfo.UserID,
+		Scope:    apistructs.AppScope,
+		ScopeID:  uint64(appID),
+		Resource: apistructs.AppResource,
+		Action:   action,
+	})
+	return err == nil && access.Access
+}
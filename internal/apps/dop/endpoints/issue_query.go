@@ -0,0 +1,303 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/erda-project/erda-proto-go/dop/issue/core/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/providers/issue/core/query/issueql"
+	issuedao "github.com/erda-project/erda/internal/apps/dop/providers/issue/dao"
+	"github.com/erda-project/erda/internal/apps/dop/services/apierrors"
+	"github.com/erda-project/erda/internal/pkg/user"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+	"github.com/erda-project/erda/pkg/http/httpserver/errorresp"
+	"github.com/erda-project/erda/pkg/strutil"
+)
+
+// QueryIssues 按事件查询语言跨项目查询事件
+func (e *Endpoints) QueryIssues(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrQueryIssues.NotLogin().ToResp(), nil
+	}
+	var req apistructs.IssueQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierrors.ErrQueryIssues.InvalidParameter(err).ToResp(), nil
+	}
+	req.IdentityInfo = identityInfo
+	if req.OrgID == 0 {
+		req.OrgID, _ = user.GetOrgID(r)
+	}
+	if req.SavedQueryID != 0 {
+		ql, projectIDs, err := e.resolveIssueSavedQuery(req.SavedQueryID, identityInfo, req.ProjectIDs)
+		if err != nil {
+			return errorresp.ErrResp(err)
+		}
+		req.QL, req.ProjectIDs = ql, projectIDs
+	}
+	req.ProjectIDs, err = e.issueQueryScope(req.OrgID, identityInfo, req.ProjectIDs)
+	if err != nil {
+		return apierrors.ErrQueryIssues.InternalError(err).ToResp(), nil
+	}
+	if len(req.ProjectIDs) == 0 {
+		return httpserver.OkResp(&pb.IssuePagingResponseData{List: []*pb.Issue{}})
+	}
+
+	issues, total, err := e.issueQuery.QueryIssues(&req)
+	if err != nil {
+		return errorresp.ErrResp(err)
+	}
+	userIDs := make([]string, 0, len(issues)*3)
+	for _, issue := range issues {
+		userIDs = append(userIDs, issue.Creator, issue.Assignee, issue.Owner)
+	}
+	return httpserver.OkResp(&pb.IssuePagingResponseData{
+		Total: total,
+		List:  issues,
+	}, strutil.DedupSlice(userIDs, true))
+}
+
+// QueryIssueStats 按事件查询语言分组统计事件数量
+func (e *Endpoints) QueryIssueStats(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrQueryIssueStats.NotLogin().ToResp(), nil
+	}
+	var req apistructs.IssueQueryStatsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierrors.ErrQueryIssueStats.InvalidParameter(err).ToResp(), nil
+	}
+	if req.GroupBy == "" {
+		return apierrors.ErrQueryIssueStats.MissingParameter("groupBy").ToResp(), nil
+	}
+	req.IdentityInfo = identityInfo
+	if req.OrgID == 0 {
+		req.OrgID, _ = user.GetOrgID(r)
+	}
+	if req.SavedQueryID != 0 {
+		ql, projectIDs, err := e.resolveIssueSavedQuery(req.SavedQueryID, identityInfo, req.ProjectIDs)
+		if err != nil {
+			return errorresp.ErrResp(err)
+		}
+		req.QL, req.ProjectIDs = ql, projectIDs
+	}
+	req.ProjectIDs, err = e.issueQueryScope(req.OrgID, identityInfo, req.ProjectIDs)
+	if err != nil {
+		return apierrors.ErrQueryIssueStats.InternalError(err).ToResp(), nil
+	}
+	if len(req.ProjectIDs) == 0 {
+		return httpserver.OkResp([]apistructs.IssueQueryStatsItem{})
+	}
+
+	items, err := e.issueQuery.QueryIssueStats(&req)
+	if err != nil {
+		return errorresp.ErrResp(err)
+	}
+	return httpserver.OkResp(items)
+}
+
+// issueQueryScope 计算查询范围: 用户参与的项目与请求项目的交集, 内部调用直接使用请求的项目
+func (e *Endpoints) issueQueryScope(orgID uint64, identityInfo apistructs.IdentityInfo, projectIDs []uint64) ([]uint64, error) {
+	if identityInfo.IsInternalClient() {
+		return projectIDs, nil
+	}
+	myProjectIDs, err := e.bdl.GetMyProjectIDs(orgID, identityInfo.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(projectIDs) == 0 {
+		return myProjectIDs, nil
+	}
+	mine := make(map[uint64]struct{}, len(myProjectIDs))
+	for _, id := range myProjectIDs {
+		mine[id] = struct{}{}
+	}
+	scope := make([]uint64, 0, len(projectIDs))
+	for _, id := range projectIDs {
+		if _, ok := mine[id]; ok {
+			scope = append(scope, id)
+		}
+	}
+	return scope, nil
+}
+
+// resolveIssueSavedQuery 返回保存的查询语句, 项目级的查询限定在该项目内
+func (e *Endpoints) resolveIssueSavedQuery(id uint64, identityInfo apistructs.IdentityInfo, projectIDs []uint64) (string, []uint64, error) {
+	q, err := e.issueDBClient.GetIssueSavedQuery(id)
+	if err != nil {
+		return "", nil, apierrors.ErrGetIssueSavedQuery.NotFound()
+	}
+	if !q.Shared && q.Creator != identityInfo.UserID && !identityInfo.IsInternalClient() {
+		return "", nil, apierrors.ErrGetIssueSavedQuery.AccessDenied()
+	}
+	if q.ProjectID != 0 {
+		projectIDs = []uint64{q.ProjectID}
+	}
+	return q.QL, projectIDs, nil
+}
+
+// CreateIssueSavedQuery 保存事件查询
+func (e *Endpoints) CreateIssueSavedQuery(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrCreateIssueSavedQuery.NotLogin().ToResp(), nil
+	}
+	var req apistructs.IssueSavedQueryCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierrors.ErrCreateIssueSavedQuery.InvalidParameter(err).ToResp(), nil
+	}
+	if err := req.Check(); err != nil {
+		return apierrors.ErrCreateIssueSavedQuery.InvalidParameter(err).ToResp(), nil
+	}
+	if _, err := issueql.Parse(req.QL); err != nil {
+		return apierrors.ErrCreateIssueSavedQuery.InvalidParameter(err).ToResp(), nil
+	}
+	if req.ProjectID != 0 && !e.checkProjectPermission(identityInfo, req.ProjectID, apistructs.GetAction) {
+		return apierrors.ErrCreateIssueSavedQuery.AccessDenied().ToResp(), nil
+	}
+
+	q := &issuedao.IssueSavedQuery{
+		OrgID:     req.OrgID,
+		ProjectID: req.ProjectID,
+		Name:      req.Name,
+		QL:        req.QL,
+		Shared:    req.Shared,
+		Creator:   identityInfo.UserID,
+	}
+	if err := e.issueDBClient.CreateIssueSavedQuery(q); err != nil {
+		return apierrors.ErrCreateIssueSavedQuery.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(q.ToAPI())
+}
+
+// UpdateIssueSavedQuery 更新保存的事件查询, 仅创建者可操作
+func (e *Endpoints) UpdateIssueSavedQuery(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrUpdateIssueSavedQuery.NotLogin().ToResp(), nil
+	}
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return apierrors.ErrUpdateIssueSavedQuery.InvalidParameter("id").ToResp(), nil
+	}
+	var req apistructs.IssueSavedQueryUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierrors.ErrUpdateIssueSavedQuery.InvalidParameter(err).ToResp(), nil
+	}
+	q, err := e.issueDBClient.GetIssueSavedQuery(id)
+	if err != nil {
+		return apierrors.ErrUpdateIssueSavedQuery.NotFound().ToResp(), nil
+	}
+	if q.Creator != identityInfo.UserID {
+		return apierrors.ErrUpdateIssueSavedQuery.AccessDenied().ToResp(), nil
+	}
+
+	if req.Name != "" {
+		q.Name = req.Name
+	}
+	if req.QL != "" {
+		if _, err := issueql.Parse(req.QL); err != nil {
+			return apierrors.ErrUpdateIssueSavedQuery.InvalidParameter(err).ToResp(), nil
+		}
+		q.QL = req.QL
+	}
+	if req.Shared != nil {
+		q.Shared = *req.Shared
+	}
+	if err := e.issueDBClient.UpdateIssueSavedQuery(q); err != nil {
+		return apierrors.ErrUpdateIssueSavedQuery.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(q.ToAPI())
+}
+
+// DeleteIssueSavedQuery 删除保存的事件查询, 仅创建者可操作
+func (e *Endpoints) DeleteIssueSavedQuery(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrDeleteIssueSavedQuery.NotLogin().ToResp(), nil
+	}
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return apierrors.ErrDeleteIssueSavedQuery.InvalidParameter("id").ToResp(), nil
+	}
+	q, err := e.issueDBClient.GetIssueSavedQuery(id)
+	if err != nil {
+		return apierrors.ErrDeleteIssueSavedQuery.NotFound().ToResp(), nil
+	}
+	if q.Creator != identityInfo.UserID {
+		return apierrors.ErrDeleteIssueSavedQuery.AccessDenied().ToResp(), nil
+	}
+
+	if err := e.issueDBClient.DeleteIssueSavedQuery(id); err != nil {
+		return apierrors.ErrDeleteIssueSavedQuery.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(q.ToAPI())
+}
+
+// GetIssueSavedQuery 获取保存的事件查询
+func (e *Endpoints) GetIssueSavedQuery(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrGetIssueSavedQuery.NotLogin().ToResp(), nil
+	}
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return apierrors.ErrGetIssueSavedQuery.InvalidParameter("id").ToResp(), nil
+	}
+	q, err := e.issueDBClient.GetIssueSavedQuery(id)
+	if err != nil {
+		return apierrors.ErrGetIssueSavedQuery.NotFound().ToResp(), nil
+	}
+	if !q.Shared && q.Creator != identityInfo.UserID {
+		return apierrors.ErrGetIssueSavedQuery.AccessDenied().ToResp(), nil
+	}
+	return httpserver.OkResp(q.ToAPI())
+}
+
+// ListIssueSavedQueries 查询自己创建的和他人共享的事件查询
+func (e *Endpoints) ListIssueSavedQueries(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrListIssueSavedQueries.NotLogin().ToResp(), nil
+	}
+	var req apistructs.IssueSavedQueryListRequest
+	if err := e.queryStringDecoder.Decode(&req, r.URL.Query()); err != nil {
+		return apierrors.ErrListIssueSavedQueries.InvalidParameter(err).ToResp(), nil
+	}
+	if req.OrgID == 0 {
+		req.OrgID, _ = user.GetOrgID(r)
+	}
+	if req.OrgID == 0 {
+		return apierrors.ErrListIssueSavedQueries.MissingParameter("orgID").ToResp(), nil
+	}
+	if req.ProjectID != 0 && !e.checkProjectPermission(identityInfo, req.ProjectID, apistructs.GetAction) {
+		return apierrors.ErrListIssueSavedQueries.AccessDenied().ToResp(), nil
+	}
+
+	queries, err := e.issueDBClient.ListIssueSavedQueries(req.OrgID, req.ProjectID, identityInfo.UserID)
+	if err != nil {
+		return apierrors.ErrListIssueSavedQueries.InternalError(err).ToResp(), nil
+	}
+	results := make([]*apistructs.IssueSavedQuery, 0, len(queries))
+	for i := range queries {
+		results = append(results, queries[i].ToAPI())
+	}
+	return httpserver.OkResp(results)
+}
@@ -0,0 +1,260 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/services/apierrors"
+	"github.com/erda-project/erda/internal/apps/dop/services/issuesync"
+	"github.com/erda-project/erda/internal/pkg/user"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+)
+
+// CreateIssueSyncConnection 创建项目与 Jira/GitHub 的事件同步连接
+func (e *Endpoints) CreateIssueSyncConnection(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrCreateIssueSyncConnection.NotLogin().ToResp(), nil
+	}
+	var req apistructs.IssueSyncConnectionCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierrors.ErrCreateIssueSyncConnection.InvalidParameter(err).ToResp(), nil
+	}
+	if err := req.Check(); err != nil {
+		return apierrors.ErrCreateIssueSyncConnection.InvalidParameter(err).ToResp(), nil
+	}
+	req.IdentityInfo = identityInfo
+	if !e.checkProjectPermission(identityInfo, req.ProjectID, apistructs.UpdateAction) {
+		return apierrors.ErrCreateIssueSyncConnection.AccessDenied().ToResp(), nil
+	}
+	project, err := e.bdl.GetProject(req.ProjectID)
+	if err != nil {
+		return apierrors.ErrCreateIssueSyncConnection.InternalError(err).ToResp(), nil
+	}
+
+	conn, err := e.issueSync.CreateConnection(project.OrgID, &req)
+	if err != nil {
+		return apierrors.ErrCreateIssueSyncConnection.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(conn)
+}
+
+// UpdateIssueSyncConnection 更新事件同步连接
+func (e *Endpoints) UpdateIssueSyncConnection(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrUpdateIssueSyncConnection.NotLogin().ToResp(), nil
+	}
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return apierrors.ErrUpdateIssueSyncConnection.InvalidParameter("id").ToResp(), nil
+	}
+	var req apistructs.IssueSyncConnectionUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierrors.ErrUpdateIssueSyncConnection.InvalidParameter(err).ToResp(), nil
+	}
+	if err := req.Check(); err != nil {
+		return apierrors.ErrUpdateIssueSyncConnection.InvalidParameter(err).ToResp(), nil
+	}
+	req.ID = id
+	req.IdentityInfo = identityInfo
+	conn, err := e.issueSync.GetConnection(id)
+	if err != nil {
+		return apierrors.ErrUpdateIssueSyncConnection.NotFound().ToResp(), nil
+	}
+	if !e.checkProjectPermission(identityInfo, conn.ProjectID, apistructs.UpdateAction) {
+		return apierrors.ErrUpdateIssueSyncConnection.AccessDenied().ToResp(), nil
+	}
+
+	conn, err = e.issueSync.UpdateConnection(&req)
+	if err != nil {
+		return apierrors.ErrUpdateIssueSyncConnection.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(conn)
+}
+
+// DeleteIssueSyncConnection 删除事件同步连接
+func (e *Endpoints) DeleteIssueSyncConnection(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrDeleteIssueSyncConnection.NotLogin().ToResp(), nil
+	}
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return apierrors.ErrDeleteIssueSyncConnection.InvalidParameter("id").ToResp(), nil
+	}
+	conn, err := e.issueSync.GetConnection(id)
+	if err != nil {
+		return apierrors.ErrDeleteIssueSyncConnection.NotFound().ToResp(), nil
+	}
+	if !e.checkProjectPermission(identityInfo, conn.ProjectID, apistructs.UpdateAction) {
+		return apierrors.ErrDeleteIssueSyncConnection.AccessDenied().ToResp(), nil
+	}
+
+	if err := e.issueSync.DeleteConnection(id); err != nil {
+		return apierrors.ErrDeleteIssueSyncConnection.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(conn)
+}
+
+// GetIssueSyncConnection 获取事件同步连接, 不返回凭证
+func (e *Endpoints) GetIssueSyncConnection(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrGetIssueSyncConnection.NotLogin().ToResp(), nil
+	}
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return apierrors.ErrGetIssueSyncConnection.InvalidParameter("id").ToResp(), nil
+	}
+	conn, err := e.issueSync.GetConnection(id)
+	if err != nil {
+		return apierrors.ErrGetIssueSyncConnection.NotFound().ToResp(), nil
+	}
+	if !e.checkProjectPermission(identityInfo, conn.ProjectID, apistructs.GetAction) {
+		return apierrors.ErrGetIssueSyncConnection.AccessDenied().ToResp(), nil
+	}
+	return httpserver.OkResp(conn)
+}
+
+// ListIssueSyncConnections 查询项目下的事件同步连接
+func (e *Endpoints) ListIssueSyncConnections(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrListIssueSyncConnections.NotLogin().ToResp(), nil
+	}
+	var req apistructs.IssueSyncConnectionListRequest
+	if err := e.queryStringDecoder.Decode(&req, r.URL.Query()); err != nil {
+		return apierrors.ErrListIssueSyncConnections.InvalidParameter(err).ToResp(), nil
+	}
+	if req.ProjectID == 0 {
+		return apierrors.ErrListIssueSyncConnections.MissingParameter("projectID").ToResp(), nil
+	}
+	if !e.checkProjectPermission(identityInfo, req.ProjectID, apistructs.GetAction) {
+		return apierrors.ErrListIssueSyncConnections.AccessDenied().ToResp(), nil
+	}
+
+	conns, err := e.issueSync.ListConnections(req.ProjectID)
+	if err != nil {
+		return apierrors.ErrListIssueSyncConnections.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(conns)
+}
+
+// SyncIssueSyncConnection 立即同步连接下双方有更新的事件
+func (e *Endpoints) SyncIssueSyncConnection(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrSyncIssues.NotLogin().ToResp(), nil
+	}
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return apierrors.ErrSyncIssues.InvalidParameter("id").ToResp(), nil
+	}
+	conn, err := e.issueSync.GetConnection(id)
+	if err != nil {
+		return apierrors.ErrSyncIssues.NotFound().ToResp(), nil
+	}
+	if !e.checkProjectPermission(identityInfo, conn.ProjectID, apistructs.UpdateAction) {
+		return apierrors.ErrSyncIssues.AccessDenied().ToResp(), nil
+	}
+
+	result, err := e.issueSync.PollByID(ctx, id)
+	if err != nil {
+		return apierrors.ErrSyncIssues.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(result)
+}
+
+// PushIssueToRemote 将事件推送到外部系统, 未关联时在外部系统创建
+func (e *Endpoints) PushIssueToRemote(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrPushSyncIssue.NotLogin().ToResp(), nil
+	}
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return apierrors.ErrPushSyncIssue.InvalidParameter("id").ToResp(), nil
+	}
+	var req apistructs.IssueSyncPushRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierrors.ErrPushSyncIssue.InvalidParameter(err).ToResp(), nil
+	}
+	if req.IssueID == 0 {
+		return apierrors.ErrPushSyncIssue.MissingParameter("issueID").ToResp(), nil
+	}
+	conn, err := e.issueSync.GetConnection(id)
+	if err != nil {
+		return apierrors.ErrPushSyncIssue.NotFound().ToResp(), nil
+	}
+	if !e.checkProjectPermission(identityInfo, conn.ProjectID, apistructs.UpdateAction) {
+		return apierrors.ErrPushSyncIssue.AccessDenied().ToResp(), nil
+	}
+
+	result, err := e.issueSync.PushIssue(ctx, id, req.IssueID)
+	if err != nil {
+		return apierrors.ErrPushSyncIssue.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(result)
+}
+
+// ListIssueSyncLinks 查询事件关联的外部事件
+func (e *Endpoints) ListIssueSyncLinks(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrListIssueSyncConnections.NotLogin().ToResp(), nil
+	}
+	issueID, err := strconv.ParseUint(vars["issueID"], 10, 64)
+	if err != nil {
+		return apierrors.ErrListIssueSyncConnections.InvalidParameter("issueID").ToResp(), nil
+	}
+	issue, err := e.issueDBClient.GetIssue(int64(issueID))
+	if err != nil {
+		return apierrors.ErrListIssueSyncConnections.NotFound().ToResp(), nil
+	}
+	if !e.checkProjectPermission(identityInfo, issue.ProjectID, apistructs.GetAction) {
+		return apierrors.ErrListIssueSyncConnections.AccessDenied().ToResp(), nil
+	}
+
+	links, err := e.issueSync.ListLinks(issueID)
+	if err != nil {
+		return apierrors.ErrListIssueSyncConnections.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(links)
+}
+
+// ReceiveIssueSyncWebhook 接收 Jira/GitHub webhook, 不校验登录, 由连接配置的 secret 校验来源
+func (e *Endpoints) ReceiveIssueSyncWebhook(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return apierrors.ErrHandleIssueSyncWebhook.InvalidParameter("id").ToResp(), nil
+	}
+
+	result, err := e.issueSync.HandleWebhook(ctx, id, r)
+	if err != nil {
+		if errors.Is(err, issuesync.ErrInvalidWebhook) {
+			return apierrors.ErrHandleIssueSyncWebhook.AccessDenied().ToResp(), nil
+		}
+		return apierrors.ErrHandleIssueSyncWebhook.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(result)
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	orgpb "github.com/erda-project/erda-proto-go/core/org/pb"
+	definitionpb "github.com/erda-project/erda-proto-go/core/pipeline/definition/pb"
+	"github.com/erda-project/erda/apistructs"
+	issuedao "github.com/erda-project/erda/internal/apps/dop/providers/issue/dao"
+	"github.com/erda-project/erda/internal/apps/dop/services/apierrors"
+	"github.com/erda-project/erda/internal/pkg/user"
+	"github.com/erda-project/erda/pkg/common/apis"
+	"github.com/erda-project/erda/pkg/discover"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+)
+
+// CreateIssueStateTransitionRule 创建状态流转规则
+func (e *Endpoints) CreateIssueStateTransitionRule(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrCreateIssueStateTransitionRule.NotLogin().ToResp(), nil
+	}
+	var req apistructs.IssueStateTransitionRuleCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierrors.ErrCreateIssueStateTransitionRule.InvalidParameter(err).ToResp(), nil
+	}
+	if err := req.Check(); err != nil {
+		return apierrors.ErrCreateIssueStateTransitionRule.InvalidParameter(err).ToResp(), nil
+	}
+	if !e.checkProjectPermission(identityInfo, req.ProjectID, apistructs.UpdateAction) {
+		return apierrors.ErrCreateIssueStateTransitionRule.AccessDenied().ToResp(), nil
+	}
+	for _, stateID := range []int64{req.StateFrom, req.StateTo} {
+		if stateID == 0 {
+			continue
+		}
+		if err := e.checkTransitionState(stateID, req.ProjectID, req.IssueType); err != nil {
+			return apierrors.ErrCreateIssueStateTransitionRule.InvalidParameter(err).ToResp(), nil
+		}
+	}
+	if err := e.checkTransitionPostFunctions(ctx, req.ProjectID, req.PostFunctions); err != nil {
+		return apierrors.ErrCreateIssueStateTransitionRule.InvalidParameter(err).ToResp(), nil
+	}
+
+	rule := &issuedao.IssueStateTransitionRule{
+		ProjectID: req.ProjectID,
+		IssueType: req.IssueType,
+		StateFrom: req.StateFrom,
+		StateTo:   req.StateTo,
+		Creator:   identityInfo.UserID,
+	}
+	if err := rule.SetTransition(req.Guards, req.PostFunctions); err != nil {
+		return apierrors.ErrCreateIssueStateTransitionRule.InvalidParameter(err).ToResp(), nil
+	}
+	if err := e.issueDBClient.CreateIssueStateTransitionRule(rule); err != nil {
+		return apierrors.ErrCreateIssueStateTransitionRule.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(rule.ToAPI())
+}
+
+// checkTransitionState 校验状态属于项目下的该事件类型
+func (e *Endpoints) checkTransitionState(stateID int64, projectID uint64, issueType string) error {
+	state, err := e.issueDBClient.GetIssueStateByID(stateID)
+	if err != nil {
+		return fmt.Errorf("state %d not found", stateID)
+	}
+	if state.ProjectID != projectID || state.IssueType != issueType {
+		return fmt.Errorf("state %d does not belong to issue type %s of the project", stateID, issueType)
+	}
+	return nil
+}
+
+// checkTransitionPostFunctions 校验后置动作引用的迭代、人员和流水线属于项目
+func (e *Endpoints) checkTransitionPostFunctions(ctx context.Context, projectID uint64, postFunctions []apistructs.IssueTransitionPostFunction) error {
+	for _, f := range postFunctions {
+		switch f.Type {
+		case apistructs.IssueTransitionPostSetField:
+			switch f.Field {
+			case "iteration":
+				iterationID, err := strconv.ParseInt(f.Value, 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid iteration %s", f.Value)
+				}
+				if iterationID == -1 {
+					continue
+				}
+				iteration, err := e.iteration.Get(uint64(iterationID))
+				if err != nil {
+					return fmt.Errorf("iteration %d not found", iterationID)
+				}
+				if iteration.ProjectID != projectID {
+					return fmt.Errorf("iteration %d does not belong to the project", iterationID)
+				}
+			case "assignee", "owner":
+				members, err := e.bdl.GetMemberByUserAndScope(apistructs.ProjectScope, f.Value, projectID)
+				if err != nil {
+					return err
+				}
+				if len(members) == 0 {
+					return fmt.Errorf("user %s is not a member of the project", f.Value)
+				}
+			}
+		case apistructs.IssueTransitionPostTriggerPipeline:
+			if err := e.checkTransitionPipeline(ctx, projectID, f.PipelineDefinitionID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkTransitionPipeline 校验流水线定义属于项目
+func (e *Endpoints) checkTransitionPipeline(ctx context.Context, projectID uint64, definitionID string) error {
+	resp, err := e.PipelineDefinition.Get(ctx, &definitionpb.PipelineDefinitionGetRequest{PipelineDefinitionID: definitionID})
+	if err != nil || resp == nil || resp.PipelineDefinition == nil {
+		return fmt.Errorf("pipeline definition %s not found", definitionID)
+	}
+	project, err := e.bdl.GetProject(projectID)
+	if err != nil {
+		return err
+	}
+	orgResp, err := e.orgClient.GetOrg(apis.WithInternalClientContext(ctx, discover.SvcDOP),
+		&orgpb.GetOrgRequest{IdOrName: strconv.FormatUint(project.OrgID, 10)})
+	if err != nil {
+		return err
+	}
+	location := apistructs.MakeLocation(&apistructs.ApplicationDTO{
+		OrgName:     orgResp.Data.Name,
+		ProjectName: project.Name,
+	}, apistructs.PipelineTypeCICD)
+	if resp.PipelineDefinition.Location != location {
+		return fmt.Errorf("pipeline definition %s does not belong to the project", definitionID)
+	}
+	return nil
+}
+
+// UpdateIssueStateTransitionRule 更新状态流转规则的校验和动作
+func (e *Endpoints) UpdateIssueStateTransitionRule(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrUpdateIssueStateTransitionRule.NotLogin().ToResp(), nil
+	}
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return apierrors.ErrUpdateIssueStateTransitionRule.InvalidParameter("id").ToResp(), nil
+	}
+	var req apistructs.IssueStateTransitionRuleUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierrors.ErrUpdateIssueStateTransitionRule.InvalidParameter(err).ToResp(), nil
+	}
+	if err := req.Check(); err != nil {
+		return apierrors.ErrUpdateIssueStateTransitionRule.InvalidParameter(err).ToResp(), nil
+	}
+	rule, err := e.issueDBClient.GetIssueStateTransitionRule(id)
+	if err != nil {
+		return apierrors.ErrUpdateIssueStateTransitionRule.NotFound().ToResp(), nil
+	}
+	if !e.checkProjectPermission(identityInfo, rule.ProjectID, apistructs.UpdateAction) {
+		return apierrors.ErrUpdateIssueStateTransitionRule.AccessDenied().ToResp(), nil
+	}
+	if err := e.checkTransitionPostFunctions(ctx, rule.ProjectID, req.PostFunctions); err != nil {
+		return apierrors.ErrUpdateIssueStateTransitionRule.InvalidParameter(err).ToResp(), nil
+	}
+
+	if err := rule.SetTransition(req.Guards, req.PostFunctions); err != nil {
+		return apierrors.ErrUpdateIssueStateTransitionRule.InvalidParameter(err).ToResp(), nil
+	}
+	if err := e.issueDBClient.UpdateIssueStateTransitionRule(rule); err != nil {
+		return apierrors.ErrUpdateIssueStateTransitionRule.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(rule.ToAPI())
+}
+
+// DeleteIssueStateTransitionRule 删除状态流转规则
+func (e *Endpoints) DeleteIssueStateTransitionRule(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrDeleteIssueStateTransitionRule.NotLogin().ToResp(), nil
+	}
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		return apierrors.ErrDeleteIssueStateTransitionRule.InvalidParameter("id").ToResp(), nil
+	}
+	rule, err := e.issueDBClient.GetIssueStateTransitionRule(id)
+	if err != nil {
+		return apierrors.ErrDeleteIssueStateTransitionRule.NotFound().ToResp(), nil
+	}
+	if !e.checkProjectPermission(identityInfo, rule.ProjectID, apistructs.UpdateAction) {
+		return apierrors.ErrDeleteIssueStateTransitionRule.AccessDenied().ToResp(), nil
+	}
+
+	if err := e.issueDBClient.DeleteIssueStateTransitionRule(id); err != nil {
+		return apierrors.ErrDeleteIssueStateTransitionRule.InternalError(err).ToResp(), nil
+	}
+	return httpserver.OkResp(rule.ToAPI())
+}
+
+// ListIssueStateTransitionRules 查询项目的状态流转规则
+func (e *Endpoints) ListIssueStateTransitionRules(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		return apierrors.ErrListIssueStateTransitionRules.NotLogin().ToResp(), nil
+	}
+	var req apistructs.IssueStateTransitionRuleListRequest
+	if err := e.queryStringDecoder.Decode(&req, r.URL.Query()); err != nil {
+		return apierrors.ErrListIssueStateTransitionRules.InvalidParameter(err).ToResp(), nil
+	}
+	if req.ProjectID == 0 {
+		return apierrors.ErrListIssueStateTransitionRules.MissingParameter("projectID").ToResp(), nil
+	}
+	if !e.checkProjectPermission(identityInfo, req.ProjectID, apistructs.GetAction) {
+		return apierrors.ErrListIssueStateTransitionRules.AccessDenied().ToResp(), nil
+	}
+
+	rules, err := e.issueDBClient.ListIssueStateTransitionRules(req.ProjectID, req.IssueType)
+	if err != nil {
+		return apierrors.ErrListIssueStateTransitionRules.InternalError(err).ToResp(), nil
+	}
+	results := make([]*apistructs.IssueStateTransitionRule, 0, len(rules))
+	for i := range rules {
+		results = append(results, rules[i].ToAPI())
+	}
+	return httpserver.OkResp(results)
+}
@@ -584,6 +584,21 @@ func (e *Endpoints) getProjectID(vars map[string]string) (uint64, error) {
 	return projectID, nil
 }
 
+// checkProjectPermission 校验用户在项目下的权限, 内部调用不校验
+func (e *Endpoints) checkProjectPermission(identityInfo apistructs.IdentityInfo, projectID uint64, action string) bool {
+	if identityInfo.IsInternalClient() {
+		return true
+	}
+	access, err := e.bdl.CheckPermission(&apistructs.PermissionCheckRequest{
+		UserID:   identityInfo.UserID,
+		Scope:    apistructs.ProjectScope,
+		ScopeID:  projectID,
+		Resource: apistructs.ProjectResource,
+		Action:   action,
+	})
+	return err == nil && access.Access
+}
+
 func (e *Endpoints) getOrgID(vars map[string]string) (int64, error) {
 	orgIDStr := vars["orgID"]
 	if orgIDStr == "" {
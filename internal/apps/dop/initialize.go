@@ -57,9 +57,11 @@ import (
 	"github.com/erda-project/erda/internal/apps/dop/services/cq"
 	"github.com/erda-project/erda/internal/apps/dop/services/environment"
 	"github.com/erda-project/erda/internal/apps/dop/services/filetree"
+	"github.com/erda-project/erda/internal/apps/dop/services/flakytest"
 	"github.com/erda-project/erda/internal/apps/dop/services/issue"
 	"github.com/erda-project/erda/internal/apps/dop/services/issuefilterbm"
 	"github.com/erda-project/erda/internal/apps/dop/services/issuestate"
+	"github.com/erda-project/erda/internal/apps/dop/services/issuesync"
 	"github.com/erda-project/erda/internal/apps/dop/services/iteration"
 	"github.com/erda-project/erda/internal/apps/dop/services/libreference"
 	"github.com/erda-project/erda/internal/apps/dop/services/migrate"
@@ -531,6 +533,17 @@ func (p *provider) initEndpoints(db *dao.DBClient) (*endpoints.Endpoints, error)
 		nexussvc.WithPipelineCms(p.PipelineCms),
 	)
 
+	// init issue sync service, poll jira and github in background as a fallback of webhooks,
+	// only the leader polls to avoid duplicate remote writes
+	issueSync := issuesync.New(
+		issuesync.WithDBClient(issueDB),
+		issuesync.WithSecretCrypt(rsaCrypt),
+		issuesync.WithAllowPrivateNetwork(conf.IssueSyncAllowPrivateNetwork()),
+	)
+	p.IssueSyncElection.OnLeader(func(ctx context.Context) {
+		issueSync.Run(ctx, conf.IssueSyncPollInterval())
+	})
+
 	// init publisher service
 	pub := publisher.New(
 		publisher.WithDBClient(db),
@@ -683,6 +696,8 @@ func (p *provider) initEndpoints(db *dao.DBClient) (*endpoints.Endpoints, error)
 		endpoints.WithOrg(o),
 		endpoints.WithCodeCoverageExecRecord(codeCvc),
 		endpoints.WithTestReportRecord(testReportSvc),
+		endpoints.WithFlakyTest(flakytest.New()),
+		endpoints.WithIssueSync(issueSync),
 		endpoints.WithPipelineCron(p.PipelineCron),
 		endpoints.WithPipelineSource(p.PipelineSource),
 		endpoints.WithPipelineDefinition(p.PipelineDefinition),
@@ -30,6 +30,7 @@ import (
 	componentprotocol "github.com/erda-project/erda-infra/providers/component-protocol"
 	"github.com/erda-project/erda-infra/providers/component-protocol/protocol"
 	"github.com/erda-project/erda-infra/providers/etcd"
+	election "github.com/erda-project/erda-infra/providers/etcd-election"
 	"github.com/erda-project/erda-infra/providers/httpserver"
 	"github.com/erda-project/erda-infra/providers/i18n"
 	dashboardPb "github.com/erda-project/erda-proto-go/cmp/dashboard/pb"
@@ -122,6 +123,8 @@ type provider struct {
 	DB            *gorm.DB         `autowired:"mysql-client"`
 	ETCD          etcd.Interface   // autowired
 	EtcdClient    *clientv3.Client // autowired
+
+	IssueSyncElection election.Interface `autowired:"etcd-election@issue-sync"`
 }
 
 func (p *provider) Init(ctx servicehub.Context) error {
@@ -0,0 +1,184 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efficiency_measure
+
+import (
+	"sort"
+	"time"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+const deployTypeRedeploy = "REDEPLOY"
+
+// DORADeploymentRow is a successful deployment of a runtime
+type DORADeploymentRow struct {
+	ID            uint64     `gorm:"column:id"`
+	RuntimeID     uint64     `gorm:"column:runtime_id"`
+	ApplicationID uint64     `gorm:"column:application_id"`
+	ReleaseID     string     `gorm:"column:release_id"`
+	Type          string     `gorm:"column:type"`
+	CreatedAt     time.Time  `gorm:"column:created_at"`
+	FinishedAt    *time.Time `gorm:"column:finished_at"`
+}
+
+// DeployedAt returns the time the deployment finished, or created if the finish time is missing
+func (d *DORADeploymentRow) DeployedAt() time.Time {
+	if d.FinishedAt != nil && !d.FinishedAt.IsZero() {
+		return *d.FinishedAt
+	}
+	return d.CreatedAt
+}
+
+// DORAAlertRow is a recovered alert event
+type DORAAlertRow struct {
+	FirstTriggerTime time.Time `gorm:"column:first_trigger_time"`
+	LastTriggerTime  time.Time `gorm:"column:last_trigger_time"`
+}
+
+// doraChange is a deployment which delivers a new release to the runtime
+type doraChange struct {
+	deployment *DORADeploymentRow
+	failed     bool
+}
+
+// classifyDeployments picks out the changes deployed in [start, end] from the successful deployments.
+// Deployments are grouped by runtime and ordered by creation. Redeploying the current release is not a change;
+// redeploying a release deployed before is a rollback, which marks the change it replaces as failed.
+func classifyDeployments(deployments []*DORADeploymentRow, start, end time.Time) []*doraChange {
+	byRuntime := make(map[uint64][]*DORADeploymentRow)
+	var runtimeIDs []uint64
+	for _, d := range deployments {
+		if _, ok := byRuntime[d.RuntimeID]; !ok {
+			runtimeIDs = append(runtimeIDs, d.RuntimeID)
+		}
+		byRuntime[d.RuntimeID] = append(byRuntime[d.RuntimeID], d)
+	}
+
+	var changes []*doraChange
+	for _, runtimeID := range runtimeIDs {
+		list := byRuntime[runtimeID]
+		sort.SliceStable(list, func(i, j int) bool {
+			if list[i].CreatedAt.Equal(list[j].CreatedAt) {
+				return list[i].ID < list[j].ID
+			}
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		})
+		var (
+			current       *DORADeploymentRow
+			currentChange *doraChange
+			deployed      = make(map[string]bool)
+		)
+		for _, d := range list {
+			if current != nil && d.ReleaseID == current.ReleaseID {
+				continue
+			}
+			if current != nil && d.Type == deployTypeRedeploy && deployed[d.ReleaseID] {
+				if currentChange != nil {
+					currentChange.failed = true
+				}
+				current, currentChange = d, nil
+				continue
+			}
+			deployed[d.ReleaseID] = true
+			current, currentChange = d, nil
+			if at := d.DeployedAt(); !at.Before(start) && !at.After(end) {
+				currentChange = &doraChange{deployment: d}
+				changes = append(changes, currentChange)
+			}
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].deployment.DeployedAt().Before(changes[j].deployment.DeployedAt())
+	})
+	return changes
+}
+
+// markIncidentFailures marks the latest change deployed within the window before each incident as failed
+func markIncidentFailures(changes []*doraChange, incidents []time.Time, window time.Duration) {
+	for _, t := range incidents {
+		for i := len(changes) - 1; i >= 0; i-- {
+			at := changes[i].deployment.DeployedAt()
+			if at.After(t) {
+				continue
+			}
+			if t.Sub(at) <= window {
+				changes[i].failed = true
+			}
+			break
+		}
+	}
+}
+
+// summarizeChanges calculates the deployment metrics of the changes, commitTimes is keyed by release id
+func summarizeChanges(changes []*doraChange, commitTimes map[string]time.Time, days float64) apistructs.DORAMetrics {
+	var (
+		m         apistructs.DORAMetrics
+		leadTimes []float64
+	)
+	for _, c := range changes {
+		m.Deployments++
+		if c.failed {
+			m.FailedDeployments++
+		}
+		if committedAt, ok := commitTimes[c.deployment.ReleaseID]; ok {
+			if lt := c.deployment.DeployedAt().Sub(committedAt); lt >= 0 {
+				leadTimes = append(leadTimes, lt.Seconds())
+			}
+		}
+	}
+	if days > 0 {
+		m.DeploymentFrequency = float64(m.Deployments) / days
+	}
+	if m.Deployments > 0 {
+		m.ChangeFailureRate = float64(m.FailedDeployments) / float64(m.Deployments)
+	}
+	m.LeadTimeSamples = len(leadTimes)
+	m.LeadTimeForChanges = median(leadTimes)
+	return m
+}
+
+// meanTimeToRecover returns the count of the recovered alerts and the mean seconds from trigger to recover
+func meanTimeToRecover(alerts []*DORAAlertRow) (int, float64) {
+	var (
+		count int
+		total float64
+	)
+	for _, a := range alerts {
+		d := a.LastTriggerTime.Sub(a.FirstTriggerTime)
+		if d < 0 {
+			continue
+		}
+		count++
+		total += d.Seconds()
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	return count, total / float64(count)
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,322 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efficiency_measure
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/pkg/user"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+)
+
+const (
+	doraTimeLayout       = "2006-01-02 15:04:05"
+	doraDateLayout       = "2006-01-02"
+	doraDefaultWorkspace = "PROD"
+	doraDefaultDays      = 30
+)
+
+type doraReleaseRow struct {
+	ReleaseID     string `gorm:"column:release_id"`
+	ApplicationID uint64 `gorm:"column:application_id"`
+	Labels        string `gorm:"column:labels"`
+}
+
+type doraAppRow struct {
+	ID            uint64 `gorm:"column:id"`
+	Name          string `gorm:"column:name"`
+	GitRepoAbbrev string `gorm:"column:git_repo_abbrev"`
+}
+
+func (p *provider) queryDORAMetrics(rw http.ResponseWriter, r *http.Request) {
+	identityInfo, err := user.GetIdentityInfo(r)
+	if err != nil {
+		p.wrapBadRequest(rw, err)
+		return
+	}
+	req := &apistructs.DORAMetricsRequest{}
+	bodyData, err := io.ReadAll(r.Body)
+	if err != nil {
+		p.wrapBadRequest(rw, err)
+		return
+	}
+	if err := json.Unmarshal(bodyData, req); err != nil {
+		p.wrapBadRequest(rw, err)
+		return
+	}
+	if req.ProjectID == 0 {
+		p.wrapBadRequest(rw, fmt.Errorf("missing projectID"))
+		return
+	}
+	if req.Workspace == "" {
+		req.Workspace = doraDefaultWorkspace
+	}
+	req.Workspace = strings.ToUpper(req.Workspace)
+	start, end, err := parseDORATimeRange(req.Start, req.End, time.Now())
+	if err != nil {
+		p.wrapBadRequest(rw, err)
+		return
+	}
+	if !identityInfo.IsInternalClient() {
+		access, err := p.bdl.CheckPermission(&apistructs.PermissionCheckRequest{
+			UserID:   identityInfo.UserID,
+			Scope:    apistructs.ProjectScope,
+			ScopeID:  req.ProjectID,
+			Resource: apistructs.ProjectResource,
+			Action:   apistructs.GetAction,
+		})
+		if err != nil {
+			p.wrapBadRequest(rw, err)
+			return
+		}
+		if !access.Access {
+			httpserver.WriteErr(rw, strconv.FormatInt(int64(http.StatusForbidden), 10), "access denied")
+			return
+		}
+	}
+
+	result, err := p.calculateDORAMetrics(req, identityInfo.UserID, start, end)
+	if err != nil {
+		p.wrapBadRequest(rw, err)
+		return
+	}
+	httpserver.WriteData(rw, result)
+}
+
+func (p *provider) calculateDORAMetrics(req *apistructs.DORAMetricsRequest, userID string, start, end time.Time) (*apistructs.DORAMetricsResult, error) {
+	deployments, err := p.listDORADeployments(req, start.Add(-p.Cfg.DORADeploymentLookback), end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments, err: %v", err)
+	}
+	changes := classifyDeployments(deployments, start, end)
+	incidents, err := p.listDORAIncidentIssues(req.ProjectID, start, end.Add(p.Cfg.DORAIncidentWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incident issues, err: %v", err)
+	}
+	markIncidentFailures(changes, incidents, p.Cfg.DORAIncidentWindow)
+	alerts, err := p.listDORARecoveredAlerts(req.ProjectID, req.Workspace, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recovered alerts, err: %v", err)
+	}
+
+	releaseIDs := make([]string, 0, len(changes))
+	for _, c := range changes {
+		releaseIDs = append(releaseIDs, c.deployment.ReleaseID)
+	}
+	apps, commitTimes, err := p.getDORACommitTimes(releaseIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	days := end.Sub(start).Hours() / 24
+	result := &apistructs.DORAMetricsResult{
+		ProjectID: req.ProjectID,
+		Workspace: req.Workspace,
+		Start:     start,
+		End:       end,
+		Project:   summarizeChanges(changes, commitTimes, days),
+	}
+	result.Project.RecoveredIncidents, result.Project.MeanTimeToRecover = meanTimeToRecover(alerts)
+	if !req.GroupByApp {
+		return result, nil
+	}
+
+	byApp := make(map[uint64][]*doraChange)
+	for _, c := range changes {
+		byApp[c.deployment.ApplicationID] = append(byApp[c.deployment.ApplicationID], c)
+	}
+	for appID, appChanges := range byApp {
+		m := summarizeChanges(appChanges, commitTimes, days)
+		m.ApplicationID = appID
+		if app, ok := apps[appID]; ok {
+			m.ApplicationName = app.Name
+		}
+		result.Applications = append(result.Applications, m)
+	}
+	sort.Slice(result.Applications, func(i, j int) bool {
+		return result.Applications[i].ApplicationID < result.Applications[j].ApplicationID
+	})
+	return result, nil
+}
+
+func (p *provider) listDORADeployments(req *apistructs.DORAMetricsRequest, start, end time.Time) ([]*DORADeploymentRow, error) {
+	var rows []*DORADeploymentRow
+	tx := p.DB.Table("ps_v2_deployments AS d").
+		Select("d.id, d.runtime_id, r.application_id, d.release_id, d.type, d.created_at, d.finished_at").
+		Joins("JOIN ps_v2_project_runtimes AS r ON r.id = d.runtime_id").
+		Where("r.project_id = ?", req.ProjectID).
+		Where("r.workspace = ?", req.Workspace).
+		Where("d.status = ?", apistructs.DeploymentStatusOK).
+		Where("d.release_id != ''").
+		Where("d.created_at >= ?", start).
+		Where("d.created_at <= ?", end)
+	if len(req.ApplicationIDs) > 0 {
+		tx = tx.Where("r.application_id IN ?", req.ApplicationIDs)
+	}
+	if err := tx.Order("d.runtime_id, d.created_at, d.id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// listDORAIncidentIssues returns the creation time of the bugs labeled as incident
+func (p *provider) listDORAIncidentIssues(projectID uint64, start, end time.Time) ([]time.Time, error) {
+	if len(p.Cfg.DORAIncidentLabels) == 0 {
+		return nil, nil
+	}
+	var rows []struct {
+		ID        uint64    `gorm:"column:id"`
+		CreatedAt time.Time `gorm:"column:created_at"`
+	}
+	err := p.DB.Table("dice_issues AS i").
+		Select("DISTINCT i.id, i.created_at").
+		Joins("JOIN dice_label_relations AS lr ON lr.ref_id = CAST(i.id AS CHAR) AND lr.ref_type = ?", apistructs.LabelTypeIssue).
+		Joins("JOIN dice_labels AS l ON l.id = lr.label_id").
+		Where("i.project_id = ?", projectID).
+		Where("i.type = ?", apistructs.IssueTypeBug).
+		Where("i.deleted = 0").
+		Where("l.name IN ?", p.Cfg.DORAIncidentLabels).
+		Where("i.created_at >= ?", start).
+		Where("i.created_at <= ?", end).
+		Order("i.created_at").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	createdAts := make([]time.Time, 0, len(rows))
+	for _, row := range rows {
+		createdAts = append(createdAts, row.CreatedAt)
+	}
+	return createdAts, nil
+}
+
+// listDORARecoveredAlerts returns the alerts of the project workspace recovered in [start, end]
+func (p *provider) listDORARecoveredAlerts(projectID uint64, workspace string, start, end time.Time) ([]*DORAAlertRow, error) {
+	var rows []*DORAAlertRow
+	err := p.DB.Table("sp_alert_event AS e").
+		Select("e.first_trigger_time, e.last_trigger_time").
+		Joins("JOIN msp_tenant AS t ON t.id = e.scope_id").
+		Where("t.related_project_id = ?", strconv.FormatUint(projectID, 10)).
+		Where("t.related_workspace = ?", workspace).
+		Where("t.is_deleted = 0").
+		Where("e.alert_state = ?", "recover").
+		Where("e.last_trigger_time >= ?", start).
+		Where("e.last_trigger_time <= ?", end).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// getDORACommitTimes returns the applications of the releases and the commit time of each release
+func (p *provider) getDORACommitTimes(releaseIDs []string, userID string) (map[uint64]doraAppRow, map[string]time.Time, error) {
+	apps := make(map[uint64]doraAppRow)
+	commitTimes := make(map[string]time.Time)
+	if len(releaseIDs) == 0 {
+		return apps, commitTimes, nil
+	}
+	var releases []doraReleaseRow
+	if err := p.DB.Table("dice_release").Select("release_id, application_id, labels").
+		Where("release_id IN ?", releaseIDs).Scan(&releases).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to list releases, err: %v", err)
+	}
+	appIDs := make([]uint64, 0)
+	for _, r := range releases {
+		if _, ok := apps[r.ApplicationID]; !ok {
+			apps[r.ApplicationID] = doraAppRow{}
+			appIDs = append(appIDs, r.ApplicationID)
+		}
+	}
+	var appRows []doraAppRow
+	if err := p.DB.Table("dice_app").Select("id, name, git_repo_abbrev").
+		Where("id IN ?", appIDs).Scan(&appRows).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to list applications, err: %v", err)
+	}
+	for _, app := range appRows {
+		apps[app.ID] = app
+	}
+
+	for _, r := range releases {
+		labels := make(map[string]string)
+		if r.Labels != "" {
+			if err := json.Unmarshal([]byte(r.Labels), &labels); err != nil {
+				p.Log.Warnf("failed to unmarshal labels of release %s, err: %v", r.ReleaseID, err)
+				continue
+			}
+		}
+		commitID, repo := labels["gitCommitId"], apps[r.ApplicationID].GitRepoAbbrev
+		if commitID == "" || repo == "" {
+			continue
+		}
+		if committedAt, ok := p.getCommitTime(repo, commitID, userID); ok {
+			commitTimes[r.ReleaseID] = committedAt
+		}
+	}
+	return apps, commitTimes, nil
+}
+
+// getCommitTime returns the committer time of the commit, commits are immutable so the result is cached
+func (p *provider) getCommitTime(repo, commitID, userID string) (time.Time, bool) {
+	key := repo + "@" + commitID
+	if v, ok := p.commitTimeCache.Get(key); ok {
+		return v.(time.Time), true
+	}
+	commit, err := p.bdl.GetGittarCommit(repo, commitID, userID)
+	if err != nil || commit.Committer == nil {
+		p.Log.Warnf("failed to get commit %s of repo %s, err: %v", commitID, repo, err)
+		return time.Time{}, false
+	}
+	p.commitTimeCache.SetDefault(key, commit.Committer.When)
+	return commit.Committer.When, true
+}
+
+// parseDORATimeRange parses the time range, the default range is the last 30 days
+func parseDORATimeRange(startStr, endStr string, now time.Time) (time.Time, time.Time, error) {
+	end := now
+	if endStr != "" {
+		t, err := parseDORATime(endStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end: %s", endStr)
+		}
+		end = t
+	}
+	start := end.AddDate(0, 0, -doraDefaultDays)
+	if startStr != "" {
+		t, err := parseDORATime(startStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid start: %s", startStr)
+		}
+		start = t
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start must be before end")
+	}
+	return start, end, nil
+}
+
+func parseDORATime(s string) (time.Time, error) {
+	for _, layout := range []string{doraTimeLayout, doraDateLayout, time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s", s)
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package efficiency_measure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyDeployments(t *testing.T) {
+	base := time.Date(2022, 3, 1, 0, 0, 0, 0, time.Local)
+	at := func(hours int) time.Time { return base.Add(time.Duration(hours) * time.Hour) }
+	finished := at(1)
+	deployments := []*DORADeploymentRow{
+		// runtime 1: r0 before the window, r1, redeploy r1, r2, rollback to r1, r3
+		{ID: 1, RuntimeID: 1, ApplicationID: 10, ReleaseID: "r0", Type: "BUILD", CreatedAt: at(-48)},
+		{ID: 2, RuntimeID: 1, ApplicationID: 10, ReleaseID: "r1", Type: "BUILD", CreatedAt: at(0), FinishedAt: &finished},
+		{ID: 3, RuntimeID: 1, ApplicationID: 10, ReleaseID: "r1", Type: deployTypeRedeploy, CreatedAt: at(2)},
+		{ID: 4, RuntimeID: 1, ApplicationID: 10, ReleaseID: "r2", Type: "BUILD", CreatedAt: at(3)},
+		{ID: 5, RuntimeID: 1, ApplicationID: 10, ReleaseID: "r1", Type: deployTypeRedeploy, CreatedAt: at(4)},
+		{ID: 6, RuntimeID: 1, ApplicationID: 10, ReleaseID: "r3", Type: "RELEASE", CreatedAt: at(5)},
+		// runtime 2: first deployment is not a rollback
+		{ID: 7, RuntimeID: 2, ApplicationID: 20, ReleaseID: "s1", Type: deployTypeRedeploy, CreatedAt: at(6)},
+	}
+	changes := classifyDeployments(deployments, base, at(24))
+	var ids []uint64
+	var failed []uint64
+	for _, c := range changes {
+		ids = append(ids, c.deployment.ID)
+		if c.failed {
+			failed = append(failed, c.deployment.ID)
+		}
+	}
+	assert.Equal(t, []uint64{2, 4, 6, 7}, ids)
+	assert.Equal(t, []uint64{4}, failed)
+}
+
+func TestMarkIncidentFailures(t *testing.T) {
+	base := time.Date(2022, 3, 1, 0, 0, 0, 0, time.Local)
+	changes := []*doraChange{
+		{deployment: &DORADeploymentRow{ID: 1, CreatedAt: base}},
+		{deployment: &DORADeploymentRow{ID: 2, CreatedAt: base.Add(10 * time.Hour)}},
+		{deployment: &DORADeploymentRow{ID: 3, CreatedAt: base.Add(100 * time.Hour)}},
+	}
+	markIncidentFailures(changes, []time.Time{base.Add(12 * time.Hour), base.Add(-time.Hour), base.Add(130 * time.Hour)}, 24*time.Hour)
+	assert.False(t, changes[0].failed)
+	assert.True(t, changes[1].failed)
+	assert.False(t, changes[2].failed)
+}
+
+func TestSummarizeChanges(t *testing.T) {
+	base := time.Date(2022, 3, 1, 0, 0, 0, 0, time.Local)
+	changes := []*doraChange{
+		{deployment: &DORADeploymentRow{ReleaseID: "r1", CreatedAt: base.Add(time.Hour)}, failed: true},
+		{deployment: &DORADeploymentRow{ReleaseID: "r2", CreatedAt: base.Add(3 * time.Hour)}},
+		{deployment: &DORADeploymentRow{ReleaseID: "r3", CreatedAt: base.Add(5 * time.Hour)}},
+		{deployment: &DORADeploymentRow{ReleaseID: "r4", CreatedAt: base.Add(7 * time.Hour)}},
+	}
+	commitTimes := map[string]time.Time{"r1": base, "r2": base, "r3": base}
+	m := summarizeChanges(changes, commitTimes, 2)
+	assert.Equal(t, 4, m.Deployments)
+	assert.Equal(t, float64(2), m.DeploymentFrequency)
+	assert.Equal(t, 1, m.FailedDeployments)
+	assert.Equal(t, 0.25, m.ChangeFailureRate)
+	assert.Equal(t, 3, m.LeadTimeSamples)
+	assert.Equal(t, (3 * time.Hour).Seconds(), m.LeadTimeForChanges)
+
+	assert.Equal(t, float64(0), summarizeChanges(nil, nil, 0).ChangeFailureRate)
+}
+
+func TestMeanTimeToRecover(t *testing.T) {
+	base := time.Date(2022, 3, 1, 0, 0, 0, 0, time.Local)
+	count, mttr := meanTimeToRecover([]*DORAAlertRow{
+		{FirstTriggerTime: base, LastTriggerTime: base.Add(10 * time.Minute)},
+		{FirstTriggerTime: base, LastTriggerTime: base.Add(30 * time.Minute)},
+		{FirstTriggerTime: base, LastTriggerTime: base.Add(-time.Minute)},
+	})
+	assert.Equal(t, 2, count)
+	assert.Equal(t, (20 * time.Minute).Seconds(), mttr)
+}
+
+func TestParseDORATimeRange(t *testing.T) {
+	now := time.Date(2022, 3, 31, 0, 0, 0, 0, time.Local)
+	start, end, err := parseDORATimeRange("", "", now)
+	assert.NoError(t, err)
+	assert.Equal(t, now, end)
+	assert.Equal(t, now.AddDate(0, 0, -30), start)
+
+	start, _, err = parseDORATimeRange("2022-03-01", "2022-03-10 12:00:00", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 3, 1, 0, 0, 0, 0, time.Local), start)
+
+	_, _, err = parseDORATimeRange("2022-03-10", "2022-03-01", now)
+	assert.Error(t, err)
+	_, _, err = parseDORATimeRange("yesterday", "", now)
+	assert.Error(t, err)
+}
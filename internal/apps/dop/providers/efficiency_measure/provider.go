@@ -53,6 +53,9 @@ type config struct {
 	OrgWhiteList                               []string      `file:"performance_measure_org_white_list" env:"PERFORMANCE_MEASURE_ORG_WHITE_LIST"`
 	DemandStageList                            []string      `file:"demand_stage_list" env:"DEMAND_STAGE_LIST" default:"demandDesign,需求设计,架构设计,architectureDesign,需求调研"`
 	ArchitectureStageList                      []string      `file:"architecture_stage_list" env:"ARCHITECTURE_STAGE_LIST" default:"交互设计,技术设计,UI设计"`
+	DORADeploymentLookback                     time.Duration `file:"dora_deployment_lookback" env:"DORA_DEPLOYMENT_LOOKBACK" default:"720h"`
+	DORAIncidentWindow                         time.Duration `file:"dora_incident_window" env:"DORA_INCIDENT_WINDOW" default:"24h"`
+	DORAIncidentLabels                         []string      `file:"dora_incident_labels" env:"DORA_INCIDENT_LABELS" default:"incident,线上故障"`
 }
 
 // +provider
@@ -75,6 +78,7 @@ type provider struct {
 	errors                prometheus.Gauge
 	propertySet           *propertyCache
 	personalEfficiencySet *personalEfficiencyCache
+	commitTimeCache       *cache.Cache
 }
 
 type itemCollector struct {
@@ -82,7 +86,7 @@ type itemCollector struct {
 }
 
 func (p *provider) Init(ctx servicehub.Context) error {
-	p.bdl = bundle.New(bundle.WithErdaServer())
+	p.bdl = bundle.New(bundle.WithErdaServer(), bundle.WithGittar())
 	js, err := jsonstore.New()
 	if err != nil {
 		return fmt.Errorf("failed to init jsonstore, err: %v", err)
@@ -106,6 +110,7 @@ func (p *provider) Init(ctx servicehub.Context) error {
 	})
 	p.personalEfficiencySet = &personalEfficiencyCache{cache.New(cache.NoExpiration, cache.NoExpiration)}
 	p.propertySet = &propertyCache{cache.New(cache.NoExpiration, cache.NoExpiration)}
+	p.commitTimeCache = cache.New(24*time.Hour, time.Hour)
 
 	registry := prometheus.NewRegistry()
 	registry.MustRegister(p)
@@ -125,6 +130,7 @@ func (p *provider) Init(ctx servicehub.Context) error {
 	p.Register.Add(http.MethodPost, "/api/efficiency-measure/actions/query", p.queryPersonalEfficiency)
 	p.Register.Add(http.MethodPost, "/api/personal-contribution/actions/query", p.queryPersonalContributors)
 	p.Register.Add(http.MethodPost, "/api/func-points-trend/actions/query", p.queryFuncPointTrend)
+	p.Register.Add(http.MethodPost, "/api/dora-metrics/actions/query", p.queryDORAMetrics)
 	return nil
 }
 
@@ -0,0 +1,171 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package issueql 事件查询语言, 如
+//
+//	type = BUG AND priority >= HIGH AND iteration in openIterations() AND label ~ "payment"
+//
+// 语句解析后编译为 dice_issues 表上的查询条件, 用于事件列表、仪表盘统计及规则触发条件.
+package issueql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Operator 条件运算符
+type Operator string
+
+const (
+	OpEq         Operator = "="
+	OpNe         Operator = "!="
+	OpGt         Operator = ">"
+	OpGe         Operator = ">="
+	OpLt         Operator = "<"
+	OpLe         Operator = "<="
+	OpLike       Operator = "~"
+	OpNotLike    Operator = "!~"
+	OpIn         Operator = "in"
+	OpNotIn      Operator = "not in"
+	OpIsEmpty    Operator = "is empty"
+	OpIsNotEmpty Operator = "is not empty"
+)
+
+// negative 返回运算符是否为否定形式
+func (op Operator) negative() bool {
+	return op == OpNe || op == OpNotLike || op == OpNotIn || op == OpIsNotEmpty
+}
+
+// Query 解析后的查询语句
+type Query struct {
+	// Where 为空表示不限制条件
+	Where   Expr
+	OrderBy []Order
+}
+
+func (q *Query) String() string {
+	var parts []string
+	if q.Where != nil {
+		parts = append(parts, q.Where.String())
+	}
+	if len(q.OrderBy) > 0 {
+		orders := make([]string, 0, len(q.OrderBy))
+		for _, o := range q.OrderBy {
+			orders = append(orders, o.String())
+		}
+		parts = append(parts, "ORDER BY "+strings.Join(orders, ", "))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Order 排序字段
+type Order struct {
+	Field string
+	Desc  bool
+}
+
+func (o Order) String() string {
+	if o.Desc {
+		return o.Field + " DESC"
+	}
+	return o.Field + " ASC"
+}
+
+// Expr 条件表达式: *LogicalExpr, *NotExpr 或 *Clause
+type Expr interface {
+	String() string
+}
+
+// LogicalExpr AND / OR 表达式
+type LogicalExpr struct {
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+func (e *LogicalExpr) String() string {
+	return fmt.Sprintf("(%s %s %s)", e.Left, e.Op, e.Right)
+}
+
+// NotExpr NOT 表达式
+type NotExpr struct {
+	Expr Expr
+}
+
+func (e *NotExpr) String() string {
+	return "NOT " + e.Expr.String()
+}
+
+// Clause 字段条件, 如 priority >= HIGH
+type Clause struct {
+	Field  string
+	Op     Operator
+	Values []Value
+	Pos    int
+}
+
+func (c *Clause) String() string {
+	field := quoteField(c.Field)
+	switch c.Op {
+	case OpIsEmpty, OpIsNotEmpty:
+		return field + " " + string(c.Op)
+	case OpIn, OpNotIn:
+		if len(c.Values) == 1 && c.Values[0].Func != nil {
+			return fmt.Sprintf("%s %s %s", field, c.Op, c.Values[0])
+		}
+		values := make([]string, 0, len(c.Values))
+		for _, v := range c.Values {
+			values = append(values, v.String())
+		}
+		return fmt.Sprintf("%s %s (%s)", field, c.Op, strings.Join(values, ", "))
+	default:
+		return fmt.Sprintf("%s %s %s", field, c.Op, c.Values[0])
+	}
+}
+
+// quoteField 自定义字段名称包含空格等字符时以 property."<名称>" 表示
+func quoteField(field string) string {
+	for _, r := range field {
+		if !isIdentRune(r) {
+			idx := strings.Index(field, ".")
+			return field[:idx+1] + strconv.Quote(field[idx+1:])
+		}
+	}
+	return field
+}
+
+// Value 条件取值, 字面量或函数调用
+type Value struct {
+	Literal string
+	Func    *Func
+	Pos     int
+}
+
+func (v Value) String() string {
+	if v.Func != nil {
+		return v.Func.String()
+	}
+	return strconv.Quote(v.Literal)
+}
+
+// Func 函数调用, 如 currentUser() 或 startOfWeek(-1)
+type Func struct {
+	Name string
+	Args []string
+}
+
+func (f *Func) String() string {
+	return f.Name + "(" + strings.Join(f.Args, ", ") + ")"
+}
@@ -0,0 +1,345 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issueql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Env 编译查询语句的上下文
+type Env struct {
+	// OrgID 自定义字段所属企业, 为 0 时不限制
+	OrgID uint64
+	// ProjectIDs 查询范围, 为空时不限制项目
+	ProjectIDs []uint64
+	// UserID currentUser() 的取值
+	UserID string
+	// Now 相对时间的基准, 为空时使用当前时间
+	Now time.Time
+}
+
+// Condition 编译结果, 用于查询 dice_issues 表
+type Condition struct {
+	// Where 为空表示不限制条件
+	Where string
+	Args  []interface{}
+	// Order 排序表达式, 为空时由调用方决定
+	Order []string
+}
+
+// Compile 将查询语句编译为 dice_issues 上的查询条件
+func Compile(q *Query, env Env) (*Condition, error) {
+	c := &compiler{env: env}
+	if c.env.Now.IsZero() {
+		c.env.Now = time.Now()
+	}
+	cond := &Condition{}
+	var where []string
+	if len(env.ProjectIDs) > 0 {
+		where = append(where, "dice_issues.project_id IN (?)")
+		cond.Args = append(cond.Args, env.ProjectIDs)
+	}
+	if q.Where != nil {
+		sql, args, err := c.expr(q.Where)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, sql)
+		cond.Args = append(cond.Args, args...)
+	}
+	cond.Where = strings.Join(where, " AND ")
+	for _, o := range q.OrderBy {
+		f, ok := lookupField(o.Field, env.OrgID)
+		if !ok || f.order == "" {
+			return nil, fmt.Errorf("issue query does not support order by %s", o.Field)
+		}
+		if o.Desc {
+			cond.Order = append(cond.Order, f.order+" DESC")
+		} else {
+			cond.Order = append(cond.Order, f.order)
+		}
+	}
+	return cond, nil
+}
+
+// CompileString 解析并编译查询语句
+func CompileString(ql string, env Env) (*Condition, error) {
+	q, err := Parse(ql)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(q, env)
+}
+
+type compiler struct {
+	env Env
+}
+
+func (c *compiler) expr(e Expr) (string, []interface{}, error) {
+	switch e := e.(type) {
+	case *LogicalExpr:
+		left, largs, err := c.expr(e.Left)
+		if err != nil {
+			return "", nil, err
+		}
+		right, rargs, err := c.expr(e.Right)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("(%s %s %s)", left, e.Op, right), append(largs, rargs...), nil
+	case *NotExpr:
+		sql, args, err := c.expr(e.Expr)
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT (" + sql + ")", args, nil
+	case *Clause:
+		sql, args, err := c.clause(e)
+		if err != nil {
+			return "", nil, err
+		}
+		if e.Op.negative() {
+			sql = "NOT (" + sql + ")"
+		}
+		return sql, args, nil
+	default:
+		return "", nil, fmt.Errorf("unknown expression %T", e)
+	}
+}
+
+// clause 编译字段条件, 否定形式的运算符按肯定形式编译, 由调用方取反
+func (c *compiler) clause(cl *Clause) (string, []interface{}, error) {
+	f, ok := lookupField(cl.Field, c.env.OrgID)
+	if !ok {
+		return "", nil, errorf(cl.Pos, "unknown field %s", cl.Field)
+	}
+	switch cl.Op {
+	case OpIsEmpty, OpIsNotEmpty:
+		return c.isEmpty(f)
+	case OpLike, OpNotLike:
+		if f.kind != kindString && f.kind != kindUser {
+			return "", nil, errorf(cl.Pos, "field %s does not support %s", cl.Field, cl.Op)
+		}
+		v := cl.Values[0]
+		if v.Func != nil {
+			return "", nil, errorf(v.Pos, "%s does not support functions", cl.Op)
+		}
+		return f.match("LIKE ?", "%"+escapeLike(v.Literal)+"%")
+	case OpGt, OpGe, OpLt, OpLe:
+		return c.compare(cl, f)
+	case OpEq, OpNe, OpIn, OpNotIn:
+		if f.kind == kindDate {
+			return c.dateEqual(cl, f)
+		}
+		return c.in(cl, f)
+	default:
+		return "", nil, errorf(cl.Pos, "unknown operator %s", cl.Op)
+	}
+}
+
+func (c *compiler) isEmpty(f *field) (string, []interface{}, error) {
+	if len(f.sets) > 0 {
+		sql, args, err := f.match("IS NOT NULL")
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT (" + sql + ")", args, nil
+	}
+	if f.empty != "" {
+		return f.empty, nil, nil
+	}
+	switch f.kind {
+	case kindNumber, kindDate:
+		return f.column + " IS NULL", nil, nil
+	default:
+		return fmt.Sprintf("(%s IS NULL OR %s = '')", f.column, f.column), nil, nil
+	}
+}
+
+// in 编译 =, !=, in, not in, 字面量与返回集合的函数以 OR 合并
+func (c *compiler) in(cl *Clause, f *field) (string, []interface{}, error) {
+	var (
+		literals []interface{}
+		parts    []string
+		args     []interface{}
+	)
+	for _, v := range cl.Values {
+		if v.Func != nil {
+			if set, ok := f.funcs[strings.ToLower(v.Func.Name)]; ok {
+				parts = append(parts, fmt.Sprintf("%s IN (%s)", f.column, set.query))
+				args = append(args, set.args...)
+				continue
+			}
+		}
+		value, err := c.value(cl, f, v)
+		if err != nil {
+			return "", nil, err
+		}
+		literals = append(literals, value)
+	}
+	if len(literals) > 0 {
+		var (
+			sql  string
+			larg []interface{}
+			err  error
+		)
+		if len(literals) == 1 {
+			sql, larg, err = f.match("= ?", literals[0])
+		} else {
+			sql, larg, err = f.match("IN (?)", literals)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append([]string{sql}, parts...)
+		args = append(larg, args...)
+	}
+	if len(parts) == 1 {
+		return parts[0], args, nil
+	}
+	return "(" + strings.Join(parts, " OR ") + ")", args, nil
+}
+
+// compare 编译 >, >=, <, <=, 有序枚举转换为取值集合
+func (c *compiler) compare(cl *Clause, f *field) (string, []interface{}, error) {
+	v, err := c.value(cl, f, cl.Values[0])
+	if err != nil {
+		return "", nil, err
+	}
+	switch {
+	case f.kind == kindEnum && f.ordered:
+		idx := indexOf(f.values, v.(string))
+		var matched []string
+		for i, value := range f.values {
+			if (cl.Op == OpGt && i > idx) || (cl.Op == OpGe && i >= idx) ||
+				(cl.Op == OpLt && i < idx) || (cl.Op == OpLe && i <= idx) {
+				matched = append(matched, value)
+			}
+		}
+		if len(matched) == 0 {
+			return "1 = 0", nil, nil
+		}
+		return f.match("IN (?)", matched)
+	case (f.kind == kindNumber || f.kind == kindDate) && len(f.sets) == 0:
+		return fmt.Sprintf("%s %s ?", f.column, cl.Op), []interface{}{v}, nil
+	default:
+		return "", nil, errorf(cl.Pos, "field %s does not support %s", cl.Field, cl.Op)
+	}
+}
+
+// dateEqual 日期字段的 = 按天匹配
+func (c *compiler) dateEqual(cl *Clause, f *field) (string, []interface{}, error) {
+	if len(cl.Values) != 1 {
+		return "", nil, errorf(cl.Pos, "field %s does not support %s", cl.Field, cl.Op)
+	}
+	v, err := c.value(cl, f, cl.Values[0])
+	if err != nil {
+		return "", nil, err
+	}
+	t := v.(time.Time)
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return fmt.Sprintf("(%s >= ? AND %s < ?)", f.column, f.column), []interface{}{start, start.AddDate(0, 0, 1)}, nil
+}
+
+// value 将取值转换为字段类型的参数
+func (c *compiler) value(cl *Clause, f *field, v Value) (interface{}, error) {
+	if v.Func != nil {
+		return c.call(cl, f, v)
+	}
+	switch f.kind {
+	case kindNumber:
+		n, err := strconv.ParseInt(v.Literal, 10, 64)
+		if err != nil {
+			return nil, errorf(v.Pos, "field %s expects a number, got %q", cl.Field, v.Literal)
+		}
+		return n, nil
+	case kindEnum:
+		s := strings.ToUpper(v.Literal)
+		if indexOf(f.values, s) < 0 {
+			return nil, errorf(v.Pos, "invalid value %q for field %s, valid values: %s",
+				v.Literal, cl.Field, strings.Join(f.values, ", "))
+		}
+		return s, nil
+	case kindDate:
+		t, err := parseTime(v.Literal, c.env.Now)
+		if err != nil {
+			return nil, errorf(v.Pos, "field %s: %v", cl.Field, err)
+		}
+		return t, nil
+	default:
+		return v.Literal, nil
+	}
+}
+
+func (c *compiler) call(cl *Clause, f *field, v Value) (interface{}, error) {
+	fn := v.Func
+	name := strings.ToLower(fn.Name)
+	switch {
+	case name == "currentuser" && f.kind == kindUser:
+		if c.env.UserID == "" {
+			return nil, errorf(v.Pos, "currentUser() is not available")
+		}
+		return c.env.UserID, nil
+	case f.kind == kindDate:
+		offset := 0
+		if len(fn.Args) > 1 {
+			return nil, errorf(v.Pos, "%s() accepts at most one argument", fn.Name)
+		}
+		if len(fn.Args) == 1 {
+			n, err := strconv.Atoi(fn.Args[0])
+			if err != nil {
+				return nil, errorf(v.Pos, "invalid argument %q of %s()", fn.Args[0], fn.Name)
+			}
+			offset = n
+		}
+		if t, ok := dateFunc(name, c.env.Now, offset); ok {
+			return t, nil
+		}
+	}
+	return nil, errorf(v.Pos, "function %s() is not supported by field %s", fn.Name, cl.Field)
+}
+
+// match 生成字段与取值的比较条件, pred 为比较部分, 如 "= ?"
+func (f *field) match(pred string, args ...interface{}) (string, []interface{}, error) {
+	if len(f.sets) == 0 {
+		return f.column + " " + pred, args, nil
+	}
+	queries := make([]string, 0, len(f.sets))
+	var all []interface{}
+	for _, s := range f.sets {
+		queries = append(queries, fmt.Sprintf(s.query, s.value+" "+pred))
+		all = append(all, s.args...)
+		all = append(all, args...)
+	}
+	return fmt.Sprintf("%s IN (%s)", f.column, strings.Join(queries, " UNION ")), all, nil
+}
+
+func indexOf(values []string, v string) int {
+	for i, value := range values {
+		if value == v {
+			return i
+		}
+	}
+	return -1
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issueql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile(t *testing.T) {
+	// 2026-10-21 is a wednesday
+	now := time.Date(2026, 10, 21, 15, 30, 0, 0, time.UTC)
+	env := Env{OrgID: 1, UserID: "1001", Now: now}
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC) }
+
+	cases := []struct {
+		ql    string
+		where string
+		args  []interface{}
+	}{
+		{
+			`type = bug AND priority >= HIGH`,
+			`(dice_issues.type = ? AND dice_issues.priority IN (?))`,
+			[]interface{}{"BUG", []string{"HIGH", "URGENT"}},
+		},
+		{
+			`iteration in openIterations() AND label ~ "pay_ment"`,
+			`(dice_issues.iteration_id IN (SELECT id FROM dice_iterations WHERE state = ?) AND ` +
+				`dice_issues.id IN (SELECT lr.ref_id FROM dice_label_relations lr JOIN dice_labels l ON l.id = lr.label_id WHERE lr.ref_type = ? AND l.name LIKE ?))`,
+			[]interface{}{"UNFILED", "issue", `%pay\_ment%`},
+		},
+		{
+			`iteration in (-1, 3, closedIterations())`,
+			`(dice_issues.iteration_id IN (?) OR dice_issues.iteration_id IN (SELECT id FROM dice_iterations WHERE state = ?))`,
+			[]interface{}{[]interface{}{int64(-1), int64(3)}, "FILED"},
+		},
+		{
+			`assignee = currentUser() OR NOT (creator != 2 AND severity < normal)`,
+			`(dice_issues.assignee = ? OR NOT ((NOT (dice_issues.creator = ?) AND dice_issues.severity IN (?))))`,
+			[]interface{}{"1001", "2", []string{"SUGGEST", "SLIGHT"}},
+		},
+		{
+			`state in ("待处理", "进行中") AND stateBelong != done`,
+			`(dice_issues.state IN (SELECT id FROM dice_issue_state WHERE name IN (?)) AND ` +
+				`NOT (dice_issues.state IN (SELECT id FROM dice_issue_state WHERE belong = ?)))`,
+			[]interface{}{[]interface{}{"待处理", "进行中"}, "DONE"},
+		},
+		{
+			`property.模块 = api AND iteration is empty AND owner is not empty`,
+			`((dice_issues.id IN (SELECT r.issue_id FROM dice_issue_property_relation r JOIN dice_issue_property p ON p.id = r.property_id ` +
+				`LEFT JOIN dice_issue_property_value v ON v.id = r.property_value_id WHERE p.org_id = ? AND p.property_name = ? AND COALESCE(v.name, r.arbitrary_value) = ?) AND ` +
+				`dice_issues.iteration_id = -1) AND NOT ((dice_issues.owner IS NULL OR dice_issues.owner = '')))`,
+			[]interface{}{uint64(1), "模块", "api"},
+		},
+		{
+			`related = 7 AND parent is empty`,
+			`(dice_issues.id IN (SELECT related_issue FROM dice_issue_relation WHERE type = ? AND issue_id = ? UNION ` +
+				`SELECT issue_id FROM dice_issue_relation WHERE type = ? AND related_issue = ?) AND ` +
+				`NOT (dice_issues.id IN (SELECT related_issue FROM dice_issue_relation WHERE type = ? AND issue_id IS NOT NULL)))`,
+			[]interface{}{"connection", int64(7), "connection", int64(7), "inclusion"},
+		},
+		{
+			`createdAt >= startOfWeek() AND createdAt < startOfMonth(1) AND updatedAt > -2d AND planFinishedAt = "2026-10-01"`,
+			`(((dice_issues.created_at >= ? AND dice_issues.created_at < ?) AND dice_issues.updated_at > ?) AND ` +
+				`(dice_issues.plan_finished_at >= ? AND dice_issues.plan_finished_at < ?))`,
+			[]interface{}{day(19), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), now.AddDate(0, 0, -2), day(1), day(2)},
+		},
+	}
+	for _, c := range cases {
+		cond, err := CompileString(c.ql, env)
+		require.NoError(t, err, c.ql)
+		assert.Equal(t, c.where, cond.Where, c.ql)
+		assert.Equal(t, c.args, cond.Args, c.ql)
+	}
+}
+
+func TestCompile_ScopeAndOrder(t *testing.T) {
+	cond, err := CompileString(`ORDER BY priority DESC, updatedAt`, Env{ProjectIDs: []uint64{1, 2}})
+	require.NoError(t, err)
+	assert.Equal(t, `dice_issues.project_id IN (?)`, cond.Where)
+	assert.Equal(t, []interface{}{[]uint64{1, 2}}, cond.Args)
+	assert.Equal(t, []string{
+		`FIELD(dice_issues.priority, 'LOW', 'NORMAL', 'HIGH', 'URGENT') DESC`,
+		`dice_issues.updated_at`,
+	}, cond.Order)
+
+	cond, err = CompileString(``, Env{})
+	require.NoError(t, err)
+	assert.Empty(t, cond.Where)
+	assert.Empty(t, cond.Args)
+}
+
+func TestCompile_Error(t *testing.T) {
+	cases := []string{
+		`unknown = 1`,
+		`type = STORY`,
+		`id = abc`,
+		`title > a`,
+		`priority ~ HIGH`,
+		`assignee = currentUser()`,
+		`createdAt = someday`,
+		`createdAt in ("2026-10-01", "2026-10-02")`,
+		`iteration in openSprints()`,
+		`createdAt > startOfWeek(a)`,
+		`ORDER BY content`,
+	}
+	for _, ql := range cases {
+		_, err := CompileString(ql, Env{})
+		assert.Error(t, err, ql)
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	g, err := GroupBy("State")
+	require.NoError(t, err)
+	assert.Equal(t, &Group{Column: "dice_issue_state.name", Join: joinState}, g)
+	g, err = GroupBy("priority")
+	require.NoError(t, err)
+	assert.Equal(t, "dice_issues.priority", g.Column)
+	_, err = GroupBy("title")
+	assert.Error(t, err)
+}
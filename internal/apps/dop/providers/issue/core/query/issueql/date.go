@@ -0,0 +1,71 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issueql
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var timeLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+}
+
+// parseTime 解析日期, 支持绝对时间与相对 now 的时间, 如 -7d, -2w, 4h, -30m
+func parseTime(s string, now time.Time) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	if len(s) >= 2 {
+		if n, err := strconv.Atoi(s[:len(s)-1]); err == nil {
+			switch s[len(s)-1] {
+			case 'm':
+				return now.Add(time.Duration(n) * time.Minute), nil
+			case 'h':
+				return now.Add(time.Duration(n) * time.Hour), nil
+			case 'd':
+				return now.AddDate(0, 0, n), nil
+			case 'w':
+				return now.AddDate(0, 0, 7*n), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// dateFunc 计算日期函数的取值, offset 为偏移的天/周/月数
+func dateFunc(name string, now time.Time, offset int) (time.Time, bool) {
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch name {
+	case "now":
+		return now, true
+	case "startofday":
+		return startOfDay.AddDate(0, 0, offset), true
+	case "startofweek":
+		// weeks start on monday
+		weekday := (int(now.Weekday()) + 6) % 7
+		return startOfDay.AddDate(0, 0, 7*offset-weekday), true
+	case "startofmonth":
+		return time.Date(now.Year(), now.Month()+time.Month(offset), 1, 0, 0, 0, 0, now.Location()), true
+	default:
+		return time.Time{}, false
+	}
+}
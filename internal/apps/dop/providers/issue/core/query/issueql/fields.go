@@ -0,0 +1,221 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issueql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+const (
+	propertyFieldPrefix = "property."
+	joinState           = "LEFT JOIN dice_issue_state ON dice_issues.state = dice_issue_state.id"
+)
+
+type valueKind int
+
+const (
+	kindNumber valueKind = iota
+	kindString
+	kindEnum
+	kindUser
+	kindDate
+)
+
+// Group 分组统计使用的列及关联
+type Group struct {
+	Column string
+	Join   string
+}
+
+// subquery 返回 field.column 取值的子查询, query 中的 %s 替换为取值条件
+type subquery struct {
+	query string
+	value string
+	args  []interface{}
+}
+
+// field 查询字段定义
+type field struct {
+	kind   valueKind
+	column string
+	// values kindEnum 的合法取值, ordered 时按从低到高排列
+	values  []string
+	ordered bool
+	// sets 非空时通过子查询匹配取值, 多个子查询以 UNION 合并
+	sets []subquery
+	// empty is empty 的条件, 为空时按类型生成
+	empty string
+	// funcs 返回集合的函数, 如 iteration in openIterations()
+	funcs map[string]subquery
+	order string
+	group *Group
+}
+
+var (
+	priorities   = []string{"LOW", "NORMAL", "HIGH", "URGENT"}
+	severities   = []string{"SUGGEST", "SLIGHT", "NORMAL", "SERIOUS", "FATAL"}
+	complexities = []string{"EASY", "NORMAL", "HARD"}
+	stateBelongs = []string{"OPEN", "WORKING", "DONE", "WONTFIX", "REOPEN", "RESOLVED", "CLOSED"}
+)
+
+func issueTypes() []string {
+	types := make([]string, 0, len(apistructs.IssueTypes))
+	for _, t := range apistructs.IssueTypes {
+		types = append(types, string(t))
+	}
+	return types
+}
+
+func enumOrder(column string, values []string) string {
+	return fmt.Sprintf("FIELD(%s, '%s')", column, strings.Join(values, "', '"))
+}
+
+func column(kind valueKind, name string) *field {
+	return &field{kind: kind, column: name, order: name}
+}
+
+// grouped 允许按字段分组统计
+func grouped(f *field) *field {
+	f.group = &Group{Column: f.column}
+	return f
+}
+
+func enum(name string, values []string, ordered bool) *field {
+	f := column(kindEnum, name)
+	f.values = values
+	f.ordered = ordered
+	if ordered {
+		f.order = enumOrder(name, values)
+	}
+	return f
+}
+
+func issueSet(kind valueKind, sets ...subquery) *field {
+	return &field{kind: kind, column: "dice_issues.id", sets: sets}
+}
+
+func iterationField() *field {
+	f := column(kindNumber, "dice_issues.iteration_id")
+	f.empty = fmt.Sprintf("dice_issues.iteration_id = %d", apistructs.UnassignedIterationID)
+	f.funcs = map[string]subquery{
+		"openiterations": {
+			query: "SELECT id FROM dice_iterations WHERE state = ?",
+			args:  []interface{}{string(apistructs.IterationStateUnfiled)},
+		},
+		"closediterations": {
+			query: "SELECT id FROM dice_iterations WHERE state = ?",
+			args:  []interface{}{string(apistructs.IterationStateFiled)},
+		},
+	}
+	return f
+}
+
+func stateField(kind valueKind, value string, values []string) *field {
+	return &field{
+		kind:   kind,
+		column: "dice_issues.state",
+		values: values,
+		sets:   []subquery{{query: "SELECT id FROM dice_issue_state WHERE %s", value: value}},
+		order:  "dice_issues.state",
+		group:  &Group{Column: "dice_issue_state." + value, Join: joinState},
+	}
+}
+
+func inclusion(selectColumn, valueColumn string) subquery {
+	return subquery{
+		query: fmt.Sprintf("SELECT %s FROM dice_issue_relation WHERE type = ? AND %%s", selectColumn),
+		value: valueColumn,
+		args:  []interface{}{apistructs.IssueRelationInclusion},
+	}
+}
+
+func connection(selectColumn, valueColumn string) subquery {
+	s := inclusion(selectColumn, valueColumn)
+	s.args = []interface{}{apistructs.IssueRelationConnection}
+	return s
+}
+
+// fields 支持的字段, key 为小写的字段名称
+var fields = map[string]*field{
+	"id":             column(kindNumber, "dice_issues.id"),
+	"project":        grouped(column(kindNumber, "dice_issues.project_id")),
+	"iteration":      grouped(iterationField()),
+	"type":           grouped(enum("dice_issues.type", issueTypes(), false)),
+	"title":          column(kindString, "dice_issues.title"),
+	"content":        {kind: kindString, column: "dice_issues.content"},
+	"state":          stateField(kindString, "name", nil),
+	"statebelong":    stateField(kindEnum, "belong", stateBelongs),
+	"priority":       grouped(enum("dice_issues.priority", priorities, true)),
+	"severity":       grouped(enum("dice_issues.severity", severities, true)),
+	"complexity":     grouped(enum("dice_issues.complexity", complexities, true)),
+	"assignee":       grouped(column(kindUser, "dice_issues.assignee")),
+	"creator":        grouped(column(kindUser, "dice_issues.creator")),
+	"owner":          grouped(column(kindUser, "dice_issues.owner")),
+	"stage":          grouped(column(kindString, "dice_issues.stage")),
+	"source":         column(kindString, "dice_issues.source"),
+	"reopencount":    column(kindNumber, "dice_issues.reopen_count"),
+	"createdat":      column(kindDate, "dice_issues.created_at"),
+	"updatedat":      column(kindDate, "dice_issues.updated_at"),
+	"planstartedat":  column(kindDate, "dice_issues.plan_started_at"),
+	"planfinishedat": column(kindDate, "dice_issues.plan_finished_at"),
+	"finishedat":     column(kindDate, "dice_issues.finish_time"),
+	"participant": issueSet(kindUser, subquery{
+		query: "SELECT issue_id FROM erda_issue_subscriber WHERE %s",
+		value: "user_id",
+	}),
+	"label": issueSet(kindString, subquery{
+		query: "SELECT lr.ref_id FROM dice_label_relations lr JOIN dice_labels l ON l.id = lr.label_id WHERE lr.ref_type = ? AND %s",
+		value: "l.name",
+		args:  []interface{}{string(apistructs.LabelTypeIssue)},
+	}),
+	// parent = 1 查询被事件 1 包含的事件, child = 1 查询包含事件 1 的事件
+	"parent":  issueSet(kindNumber, inclusion("related_issue", "issue_id")),
+	"child":   issueSet(kindNumber, inclusion("issue_id", "related_issue")),
+	"related": issueSet(kindNumber, connection("related_issue", "issue_id"), connection("issue_id", "related_issue")),
+}
+
+// lookupField 查找字段, 自定义字段按名称与企业匹配
+func lookupField(name string, orgID uint64) (*field, bool) {
+	if len(name) > len(propertyFieldPrefix) && strings.EqualFold(name[:len(propertyFieldPrefix)], propertyFieldPrefix) {
+		return propertyField(name[len(propertyFieldPrefix):], orgID), true
+	}
+	f, ok := fields[strings.ToLower(name)]
+	return f, ok
+}
+
+func propertyField(name string, orgID uint64) *field {
+	query := "SELECT r.issue_id FROM dice_issue_property_relation r " +
+		"JOIN dice_issue_property p ON p.id = r.property_id " +
+		"LEFT JOIN dice_issue_property_value v ON v.id = r.property_value_id " +
+		"WHERE p.property_name = ? AND %s"
+	args := []interface{}{name}
+	if orgID > 0 {
+		query = strings.Replace(query, "WHERE", "WHERE p.org_id = ? AND", 1)
+		args = []interface{}{orgID, name}
+	}
+	return issueSet(kindString, subquery{query: query, value: "COALESCE(v.name, r.arbitrary_value)", args: args})
+}
+
+// GroupBy 返回分组统计字段对应的列
+func GroupBy(name string) (*Group, error) {
+	f, ok := lookupField(name, 0)
+	if !ok || f.group == nil {
+		return nil, fmt.Errorf("issue query does not support group by %s", name)
+	}
+	return f.group, nil
+}
@@ -0,0 +1,268 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issueql
+
+import (
+	"strings"
+)
+
+// Parse 解析查询语句, 语法:
+//
+//	query   = [expr] [ORDER BY field [ASC|DESC] {, field [ASC|DESC]}]
+//	expr    = term {OR term}
+//	term    = factor {AND factor}
+//	factor  = NOT factor | "(" expr ")" | clause
+//	clause  = field op value | field [NOT] IN (value {, value}) | field [NOT] IN func | field IS [NOT] EMPTY
+//	value   = ident | "string" | func
+//	func    = ident "(" [ident {, ident}] ")"
+//
+// 关键字不区分大小写, 自定义字段以 property.<名称> 或 property."<名称>" 表示.
+func Parse(ql string) (*Query, error) {
+	tokens, err := lex(ql)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	q := &Query{}
+	if !p.peek().is("ORDER") && p.peek().kind != tokenEOF {
+		if q.Where, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek().is("ORDER") {
+		p.next()
+		if t := p.next(); !t.is("BY") {
+			return nil, errorf(t.pos, "expect BY after ORDER")
+		}
+		for {
+			t := p.next()
+			if t.kind != tokenIdent {
+				return nil, errorf(t.pos, "expect order field")
+			}
+			order := Order{Field: t.text}
+			if p.peek().is("ASC") {
+				p.next()
+			} else if p.peek().is("DESC") {
+				p.next()
+				order.Desc = true
+			}
+			q.OrderBy = append(q.OrderBy, order)
+			if p.peek().kind != tokenComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, errorf(t.pos, "unexpected %q", t.text)
+	}
+	return q, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpr{Op: "OR", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("AND") {
+		p.next()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpr{Op: "AND", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseFactor() (Expr, error) {
+	t := p.peek()
+	switch {
+	case t.is("NOT"):
+		p.next()
+		expr, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpr{Expr: expr}, nil
+	case t.kind == tokenLParen:
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokenRParen {
+			return nil, errorf(t.pos, "expect )")
+		}
+		return expr, nil
+	default:
+		return p.parseClause()
+	}
+}
+
+func (p *parser) parseField() (string, int, error) {
+	t := p.next()
+	if t.kind != tokenIdent || isKeyword(t.text) {
+		return "", 0, errorf(t.pos, "expect field name")
+	}
+	field := t.text
+	// property."Module Name"
+	if strings.HasSuffix(field, ".") && p.peek().kind == tokenString {
+		field += p.next().text
+	}
+	return field, t.pos, nil
+}
+
+func isKeyword(s string) bool {
+	switch strings.ToUpper(s) {
+	case "AND", "OR", "NOT", "IN", "IS", "EMPTY", "NULL", "ORDER", "BY":
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseClause() (Expr, error) {
+	field, pos, err := p.parseField()
+	if err != nil {
+		return nil, err
+	}
+	clause := &Clause{Field: field, Pos: pos}
+	t := p.next()
+	switch {
+	case t.kind == tokenOperator:
+		clause.Op = Operator(t.text)
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		clause.Values = []Value{v}
+	case t.is("IS"):
+		clause.Op = OpIsEmpty
+		if p.peek().is("NOT") {
+			p.next()
+			clause.Op = OpIsNotEmpty
+		}
+		if t := p.next(); !t.is("EMPTY") && !t.is("NULL") {
+			return nil, errorf(t.pos, "expect EMPTY")
+		}
+	case t.is("IN"), t.is("NOT"):
+		clause.Op = OpIn
+		if t.is("NOT") {
+			clause.Op = OpNotIn
+			if t := p.next(); !t.is("IN") {
+				return nil, errorf(t.pos, "expect IN after NOT")
+			}
+		}
+		if clause.Values, err = p.parseList(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errorf(t.pos, "expect operator after %s", field)
+	}
+	return clause, nil
+}
+
+// parseList 解析 (v1, v2) 或返回集合的函数调用
+func (p *parser) parseList() ([]Value, error) {
+	if p.peek().kind != tokenLParen {
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if v.Func == nil {
+			return nil, errorf(v.Pos, "expect ( or function after IN")
+		}
+		return []Value{v}, nil
+	}
+	p.next()
+	var values []Value
+	for {
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		t := p.next()
+		if t.kind == tokenRParen {
+			return values, nil
+		}
+		if t.kind != tokenComma {
+			return nil, errorf(t.pos, "expect , or )")
+		}
+	}
+}
+
+func (p *parser) parseValue() (Value, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return Value{Literal: t.text, Pos: t.pos}, nil
+	case tokenIdent:
+		if p.peek().kind != tokenLParen {
+			return Value{Literal: t.text, Pos: t.pos}, nil
+		}
+		p.next()
+		f := &Func{Name: t.text}
+		for p.peek().kind != tokenRParen {
+			arg := p.next()
+			if arg.kind != tokenIdent && arg.kind != tokenString {
+				return Value{}, errorf(arg.pos, "invalid argument of %s()", f.Name)
+			}
+			f.Args = append(f.Args, arg.text)
+			if p.peek().kind == tokenComma {
+				p.next()
+			} else if p.peek().kind != tokenRParen {
+				return Value{}, errorf(p.peek().pos, "expect , or )")
+			}
+		}
+		p.next()
+		return Value{Func: f, Pos: t.pos}, nil
+	default:
+		return Value{}, errorf(t.pos, "expect value")
+	}
+}
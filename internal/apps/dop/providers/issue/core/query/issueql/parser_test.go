@@ -0,0 +1,73 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issueql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		ql   string
+		want string
+	}{
+		{``, ``},
+		{`type = BUG`, `type = "BUG"`},
+		{`type = BUG AND priority >= HIGH OR label ~ "payment"`,
+			`((type = "BUG" AND priority >= "HIGH") OR label ~ "payment")`},
+		{`type = BUG and (priority >= high or not assignee is empty)`,
+			`(type = "BUG" AND (priority >= "high" OR NOT assignee is empty))`},
+		{`iteration in openIterations() AND assignee in (currentUser(), "2")`,
+			`(iteration in openIterations() AND assignee in (currentUser(), "2"))`},
+		{`state not in ("待处理", 'In \'QA\'') ORDER BY priority DESC, id`,
+			`state not in ("待处理", "In 'QA'") ORDER BY priority DESC, id ASC`},
+		{`property."Module Name" != api AND property.模块 is not null`,
+			`(property."Module Name" != "api" AND property.模块 is not empty)`},
+		{`createdAt >= startOfWeek(-1) AND updatedAt < -7d`,
+			`(createdAt >= startOfWeek(-1) AND updatedAt < "-7d")`},
+		{`ORDER BY updatedAt`, `ORDER BY updatedAt ASC`},
+	}
+	for _, c := range cases {
+		q, err := Parse(c.ql)
+		require.NoError(t, err, c.ql)
+		assert.Equal(t, c.want, q.String(), c.ql)
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	cases := map[string]int{
+		`type =`:                  6,
+		`type BUG`:                5,
+		`(type = BUG`:             11,
+		`type = BUG priority = 1`: 11,
+		`label ~ "payment`:        8,
+		`type ! BUG`:              5,
+		`type in BUG`:             8,
+		`type is BUG`:             8,
+		`ORDER type`:              6,
+		`AND type = BUG`:          0,
+		`assignee in f(a b)`:      16,
+	}
+	for ql, pos := range cases {
+		_, err := Parse(ql)
+		require.Error(t, err, ql)
+		var qlErr *Error
+		require.ErrorAs(t, err, &qlErr, ql)
+		assert.Equal(t, pos, qlErr.Pos, ql)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	userpb "github.com/erda-project/erda-proto-go/core/user/pb"
 	"github.com/erda-project/erda-proto-go/dop/issue/core/pb"
 	syncpb "github.com/erda-project/erda-proto-go/dop/issue/sync/pb"
+	projectpipelinepb "github.com/erda-project/erda-proto-go/dop/projectpipeline/pb"
 	"github.com/erda-project/erda/apistructs"
 	"github.com/erda-project/erda/bundle"
 	"github.com/erda-project/erda/internal/apps/dop/providers/issue/dao"
@@ -43,10 +44,12 @@ type provider struct {
 	db       *dao.DBClient
 	Stream   stream.Interface
 	Identity userpb.UserServiceServer
+
+	ProjectPipeline projectpipelinepb.ProjectPipelineServiceServer `autowired:"erda.dop.projectpipeline.ProjectPipelineService" optional:"true"`
 }
 
 func (p *provider) Init(ctx servicehub.Context) error {
-	p.bdl = bundle.New(bundle.WithErdaServer())
+	p.bdl = bundle.New(bundle.WithErdaServer(), bundle.WithGittar())
 	p.db = &dao.DBClient{
 		DBEngine: &dbengine.DBEngine{
 			DB: p.DB,
@@ -89,6 +92,9 @@ type Interface interface {
 	GetIssueStateIDs(req *pb.GetIssueStatesRequest) ([]int64, error)
 	GetIssueStatesBelong(req *pb.GetIssueStateRelationRequest) ([]apistructs.IssueStateState, error)
 	AfterIssueInclusionRelationChange(id uint64) error
+	QueryIssues(req *apistructs.IssueQueryRequest) ([]*pb.Issue, uint64, error)
+	QueryIssueStats(req *apistructs.IssueQueryStatsRequest) ([]apistructs.IssueQueryStatsItem, error)
+	MatchIssue(projectID, issueID uint64, ql string) (bool, error)
 }
 
 func init() {
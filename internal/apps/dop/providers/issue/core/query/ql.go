@@ -0,0 +1,122 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"fmt"
+
+	"github.com/erda-project/erda-proto-go/dop/issue/core/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/providers/issue/core/query/issueql"
+	"github.com/erda-project/erda/internal/apps/dop/providers/issue/dao"
+	"github.com/erda-project/erda/internal/apps/dop/services/apierrors"
+)
+
+// QueryIssues 按事件查询语言分页查询事件, req.ProjectIDs 为调用方鉴权后的查询范围
+func (p *provider) QueryIssues(req *apistructs.IssueQueryRequest) ([]*pb.Issue, uint64, error) {
+	if len(req.ProjectIDs) == 0 {
+		return nil, 0, apierrors.ErrQueryIssues.MissingParameter("projectIDs")
+	}
+	cond, err := issueql.CompileString(req.QL, issueql.Env{
+		OrgID:      req.OrgID,
+		ProjectIDs: req.ProjectIDs,
+		UserID:     req.UserID,
+	})
+	if err != nil {
+		return nil, 0, apierrors.ErrQueryIssues.InvalidParameter(err)
+	}
+	if req.PageNo == 0 {
+		req.PageNo = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = 20
+	}
+	models, total, err := p.db.PagingIssuesByCondition(cond, req.PageNo, req.PageSize)
+	if err != nil {
+		return nil, 0, apierrors.ErrQueryIssues.InternalError(err)
+	}
+	issues, err := p.batchConvertByProject(models)
+	if err != nil {
+		return nil, 0, apierrors.ErrQueryIssues.InternalError(err)
+	}
+	return issues, total, nil
+}
+
+// batchConvertByProject 跨项目的结果按项目分批转换, 保持原有顺序
+func (p *provider) batchConvertByProject(models []dao.Issue) ([]*pb.Issue, error) {
+	var projectIDs []uint64
+	byProject := make(map[uint64][]dao.Issue)
+	for _, model := range models {
+		if _, ok := byProject[model.ProjectID]; !ok {
+			projectIDs = append(projectIDs, model.ProjectID)
+		}
+		byProject[model.ProjectID] = append(byProject[model.ProjectID], model)
+	}
+	converted := make(map[int64]*pb.Issue, len(models))
+	for _, projectID := range projectIDs {
+		issues, err := p.BatchConvert(byProject[projectID], nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			converted[issue.Id] = issue
+		}
+	}
+	results := make([]*pb.Issue, 0, len(models))
+	for _, model := range models {
+		results = append(results, converted[int64(model.ID)])
+	}
+	return results, nil
+}
+
+// QueryIssueStats 按事件查询语言分组统计事件数量
+func (p *provider) QueryIssueStats(req *apistructs.IssueQueryStatsRequest) ([]apistructs.IssueQueryStatsItem, error) {
+	if len(req.ProjectIDs) == 0 {
+		return nil, apierrors.ErrQueryIssueStats.MissingParameter("projectIDs")
+	}
+	group, err := issueql.GroupBy(req.GroupBy)
+	if err != nil {
+		return nil, apierrors.ErrQueryIssueStats.InvalidParameter(err)
+	}
+	cond, err := issueql.CompileString(req.QL, issueql.Env{
+		OrgID:      req.OrgID,
+		ProjectIDs: req.ProjectIDs,
+		UserID:     req.UserID,
+	})
+	if err != nil {
+		return nil, apierrors.ErrQueryIssueStats.InvalidParameter(err)
+	}
+	counts, err := p.db.CountIssuesByCondition(cond, group)
+	if err != nil {
+		return nil, apierrors.ErrQueryIssueStats.InternalError(err)
+	}
+	items := make([]apistructs.IssueQueryStatsItem, 0, len(counts))
+	for _, c := range counts {
+		items = append(items, apistructs.IssueQueryStatsItem{Key: c.Key, Count: c.Count})
+	}
+	return items, nil
+}
+
+// MatchIssue 判断项目下的事件是否符合查询语句, 用于规则的触发条件, 其他项目的事件不匹配
+func (p *provider) MatchIssue(projectID, issueID uint64, ql string) (bool, error) {
+	if projectID == 0 {
+		return false, fmt.Errorf("missing projectID")
+	}
+	cond, err := issueql.CompileString(ql, issueql.Env{ProjectIDs: []uint64{projectID}})
+	if err != nil {
+		return false, err
+	}
+	return p.db.MatchIssueByCondition(issueID, cond)
+}
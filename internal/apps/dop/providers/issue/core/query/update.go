@@ -162,6 +162,12 @@ func (p *provider) UpdateIssue(req *pb.UpdateIssueRequest) error {
 	if err := p.checkUpdateStatePermission(issueModel, changedFields, req.IdentityInfo); err != nil {
 		return err
 	}
+	// 校验状态流转规则的前置条件, 设置字段类的后置动作随本次更新生效
+	transitionRules, err := p.checkStateTransition(issueModel, changedFields, req.IdentityInfo, cache)
+	if err != nil {
+		return err
+	}
+	applyTransitionFields(issueModel, transitionRules, changedFields, issueStreamFields)
 
 	// 更新实际需要更新的字段
 	if err := p.db.UpdateIssue(id, changedFields); err != nil {
@@ -193,6 +199,7 @@ func (p *provider) UpdateIssue(req *pb.UpdateIssueRequest) error {
 			return err
 		}
 	}
+	p.runTransitionPostFunctions(issueModel, transitionRules, req.IdentityInfo)
 
 	currentBelong, err := cache.TryGetState(issueModel.State)
 	if err != nil {
@@ -408,8 +415,13 @@ func (p *provider) BatchUpdateIssue(req *pb.BatchUpdateIssueRequest) error {
 		return apierrors.ErrBatchUpdateIssue.InternalError(err)
 	}
 
-	//如果更新的是状态，单独鉴权
+	//如果更新的是状态，单独鉴权，并逐个校验状态流转规则的前置条件
+	transitionRules := make(map[uint64][]dao.IssueStateTransitionRule)
 	if req.State != 0 {
+		cache, err := NewIssueCache(p.db)
+		if err != nil {
+			return apierrors.ErrBatchUpdateIssue.InternalError(err)
+		}
 		changedFields := map[string]interface{}{"state": req.State}
 		for _, v := range issues {
 			if err := p.checkUpdateStatePermission(v, changedFields, req.IdentityInfo); err != nil {
@@ -417,6 +429,13 @@ func (p *provider) BatchUpdateIssue(req *pb.BatchUpdateIssueRequest) error {
 					"update to %v failed: no permission or some issue's state couldn't update to %v directly",
 					req.State, req.State))
 			}
+			rules, err := p.checkStateTransition(v, changedFields, req.IdentityInfo, cache)
+			if err != nil {
+				return err
+			}
+			if len(rules) > 0 {
+				transitionRules[v.ID] = rules
+			}
 		}
 	}
 
@@ -425,6 +444,32 @@ func (p *provider) BatchUpdateIssue(req *pb.BatchUpdateIssueRequest) error {
 		return apierrors.ErrBatchUpdateIssue.InternalError(err)
 	}
 
+	// 状态流转规则的后置动作逐个执行
+	for _, v := range issues {
+		rules, ok := transitionRules[v.ID]
+		if !ok {
+			continue
+		}
+		v.State = req.State
+		if req.Assignee != "" {
+			v.Assignee = req.Assignee
+		}
+		if req.Owner != "" {
+			v.Owner = req.Owner
+		}
+		if req.NewIterationID != 0 {
+			v.IterationID = req.NewIterationID
+		}
+		changedFields := make(map[string]interface{})
+		applyTransitionFields(v, rules, changedFields, make(map[string][]interface{}))
+		if len(changedFields) > 0 {
+			if err := p.db.UpdateIssue(v.ID, changedFields); err != nil {
+				return apierrors.ErrBatchUpdateIssue.InternalError(err)
+			}
+		}
+		p.runTransitionPostFunctions(v, rules, req.IdentityInfo)
+	}
+
 	if req.NewIterationID != 0 {
 		for _, v := range issues {
 			// 需求迭代变更时，集联变更需求下的任务迭代
@@ -0,0 +1,336 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	commonpb "github.com/erda-project/erda-proto-go/common/pb"
+	"github.com/erda-project/erda-proto-go/dop/issue/core/pb"
+	projectpipelinepb "github.com/erda-project/erda-proto-go/dop/projectpipeline/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/providers/issue/dao"
+	"github.com/erda-project/erda/internal/apps/dop/services/apierrors"
+	"github.com/erda-project/erda/pkg/common/apis"
+)
+
+const (
+	mrStateMerged = "merged"
+	mrStateClosed = "closed"
+)
+
+// transitionFieldColumns 流转规则中可用的字段与 dice_issues 列的对应关系
+var transitionFieldColumns = map[string]string{
+	"title":          "title",
+	"content":        "content",
+	"assignee":       "assignee",
+	"owner":          "owner",
+	"priority":       "priority",
+	"complexity":     "complexity",
+	"severity":       "severity",
+	"iteration":      "iteration_id",
+	"stage":          "stage",
+	"planStartedAt":  "plan_started_at",
+	"planFinishedAt": "plan_finished_at",
+	"estimateTime":   "man_hour",
+}
+
+// checkStateTransition 状态变更时校验匹配规则的前置条件, 返回匹配的规则用于执行后置动作
+func (p *provider) checkStateTransition(model dao.Issue, changedFields map[string]interface{}, identityInfo *commonpb.IdentityInfo, cache *issueCache) ([]dao.IssueStateTransitionRule, error) {
+	v, ok := changedFields["state"]
+	if !ok {
+		return nil, nil
+	}
+	newState, ok := v.(int64)
+	if !ok {
+		return nil, nil
+	}
+	rules, err := p.db.GetMatchedIssueStateTransitionRules(model.ProjectID, model.Type, model.State, newState)
+	if err != nil {
+		return nil, apierrors.ErrUpdateIssueState.InternalError(err)
+	}
+	for _, rule := range rules {
+		for _, guard := range rule.GetGuards() {
+			if err := p.checkTransitionGuard(model, changedFields, identityInfo, cache, guard); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return rules, nil
+}
+
+func (p *provider) checkTransitionGuard(model dao.Issue, changedFields map[string]interface{}, identityInfo *commonpb.IdentityInfo,
+	cache *issueCache, guard apistructs.IssueTransitionGuard) error {
+	switch guard.Type {
+	case apistructs.IssueTransitionGuardRequiredFields:
+		missing := missingRequiredFields(model, changedFields, guard.Fields)
+		if len(missing) > 0 {
+			return apierrors.ErrUpdateIssueState.InvalidState(fmt.Sprintf("required fields are empty: %s", strings.Join(missing, ", ")))
+		}
+	case apistructs.IssueTransitionGuardRoles:
+		return p.checkTransitionRoles(model, identityInfo, guard.Roles)
+	case apistructs.IssueTransitionGuardSubtasksDone:
+		return p.checkSubtasksDone(model, cache)
+	case apistructs.IssueTransitionGuardMRMerged:
+		return p.checkMRMerged(model, identityInfo)
+	}
+	return nil
+}
+
+// missingRequiredFields 返回更新后仍为空的字段
+func missingRequiredFields(model dao.Issue, changedFields map[string]interface{}, fields []string) []string {
+	current := model.GetCanUpdateFields()
+	var missing []string
+	for _, field := range fields {
+		column, ok := transitionFieldColumns[field]
+		if !ok {
+			continue
+		}
+		v, ok := changedFields[column]
+		if !ok {
+			v = current[column]
+		}
+		if isEmptyTransitionField(column, v) {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+func isEmptyTransitionField(column string, v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case string:
+		if column == "man_hour" {
+			var manHour pb.IssueManHour
+			if t == "" || json.Unmarshal([]byte(t), &manHour) != nil {
+				return true
+			}
+			return manHour.EstimateTime <= 0
+		}
+		return t == ""
+	case int64:
+		// 不归属任何迭代时 iteration_id 为 -1
+		return t <= 0
+	case *time.Time:
+		return t == nil
+	}
+	return false
+}
+
+func (p *provider) checkTransitionRoles(model dao.Issue, identityInfo *commonpb.IdentityInfo, roles []string) error {
+	// 内部调用不校验角色, 其余调用必须能识别出操作人
+	if identityInfo != nil && identityInfo.InternalClient != "" {
+		return nil
+	}
+	if identityInfo == nil || identityInfo.UserID == "" {
+		return apierrors.ErrUpdateIssueState.AccessDenied(fmt.Sprintf("only %s can change to this state", strings.Join(roles, ", ")))
+	}
+	members, err := p.bdl.GetMemberByUserAndScope(apistructs.ProjectScope, identityInfo.UserID, model.ProjectID)
+	if err != nil {
+		return apierrors.ErrUpdateIssueState.InternalError(err)
+	}
+	for _, member := range members {
+		for _, role := range member.Roles {
+			for _, allowed := range roles {
+				if strings.EqualFold(role, allowed) {
+					return nil
+				}
+			}
+		}
+	}
+	return apierrors.ErrUpdateIssueState.AccessDenied(fmt.Sprintf("only %s can change to this state", strings.Join(roles, ", ")))
+}
+
+func (p *provider) checkSubtasksDone(model dao.Issue, cache *issueCache) error {
+	childIDs, err := p.db.GetRelatingIssues(model.ID, []string{apistructs.IssueRelationInclusion})
+	if err != nil {
+		return apierrors.ErrUpdateIssueState.InternalError(err)
+	}
+	if len(childIDs) == 0 {
+		return nil
+	}
+	children, err := p.db.GetIssueByIssueIDs(childIDs)
+	if err != nil {
+		return apierrors.ErrUpdateIssueState.InternalError(err)
+	}
+	for _, child := range children {
+		state, err := cache.TryGetState(child.State)
+		if err != nil {
+			return apierrors.ErrUpdateIssueState.InternalError(err)
+		}
+		switch state.Belong {
+		case pb.IssueStateBelongEnum_DONE.String(), pb.IssueStateBelongEnum_CLOSED.String(), pb.IssueStateBelongEnum_WONTFIX.String():
+		default:
+			return apierrors.ErrUpdateIssueState.InvalidState(fmt.Sprintf("subtask #%d %s is not done", child.ID, child.Title))
+		}
+	}
+	return nil
+}
+
+// checkMRMerged 至少关联一个已合并的合并请求, 且没有未合并的合并请求
+func (p *provider) checkMRMerged(model dao.Issue, identityInfo *commonpb.IdentityInfo) error {
+	rels, err := p.db.GetIssueAppRelationsByIssueID(int64(model.ID))
+	if err != nil {
+		return apierrors.ErrUpdateIssueState.InternalError(err)
+	}
+	var userID string
+	if identityInfo != nil {
+		userID = identityInfo.UserID
+	}
+	var merged int
+	for _, rel := range rels {
+		if rel.MRID <= 0 {
+			continue
+		}
+		mr, err := p.bdl.GetMergeRequestDetail(uint64(rel.AppID), userID, uint64(rel.MRID))
+		if err != nil {
+			return apierrors.ErrUpdateIssueState.InternalError(err)
+		}
+		switch mr.State {
+		case mrStateMerged:
+			merged++
+		case mrStateClosed:
+		default:
+			return apierrors.ErrUpdateIssueState.InvalidState(fmt.Sprintf("merge request !%d %s is not merged", mr.RepoMergeId, mr.Title))
+		}
+	}
+	if merged == 0 {
+		return apierrors.ErrUpdateIssueState.InvalidState("no merged merge request linked")
+	}
+	return nil
+}
+
+// applyTransitionFields 将设置字段类的后置动作合并到本次更新中
+func applyTransitionFields(model dao.Issue, rules []dao.IssueStateTransitionRule, changedFields map[string]interface{}, streamFields map[string][]interface{}) {
+	current := model.GetCanUpdateFields()
+	set := func(column string, v interface{}) {
+		if current[column] == v {
+			delete(changedFields, column)
+			delete(streamFields, column)
+			return
+		}
+		changedFields[column] = v
+		streamFields[column] = []interface{}{current[column], v}
+	}
+	for _, rule := range rules {
+		for _, f := range rule.GetPostFunctions() {
+			switch f.Type {
+			case apistructs.IssueTransitionPostAssignToCreator:
+				set("assignee", model.Creator)
+			case apistructs.IssueTransitionPostSetField:
+				if !apistructs.IssueTransitionSettableFields[f.Field] {
+					logrus.Warnf("issue %d transition rule %d: field %s can not be set", model.ID, rule.ID, f.Field)
+					continue
+				}
+				column := transitionFieldColumns[f.Field]
+				if column == "iteration_id" {
+					iterationID, err := strconv.ParseInt(f.Value, 10, 64)
+					if err != nil {
+						logrus.Warnf("issue %d transition rule %d: invalid iteration %s", model.ID, rule.ID, f.Value)
+						continue
+					}
+					set(column, iterationID)
+					continue
+				}
+				set(column, f.Value)
+			}
+		}
+	}
+}
+
+// runTransitionPostFunctions 状态流转完成后执行创建关联事件、触发流水线等动作, 失败不影响流转结果
+func (p *provider) runTransitionPostFunctions(model dao.Issue, rules []dao.IssueStateTransitionRule, identityInfo *commonpb.IdentityInfo) {
+	var operator string
+	if identityInfo != nil {
+		operator = identityInfo.UserID
+	}
+	for _, rule := range rules {
+		for _, f := range rule.GetPostFunctions() {
+			var err error
+			switch f.Type {
+			case apistructs.IssueTransitionPostCreateLinkedIssue:
+				err = p.createLinkedIssue(model, f, operator)
+			case apistructs.IssueTransitionPostTriggerPipeline:
+				err = p.triggerTransitionPipeline(model, f, operator)
+			}
+			if err != nil {
+				logrus.Errorf("issue %d transition rule %d: failed to run %s, err: %v", model.ID, rule.ID, f.Type, err)
+			}
+		}
+	}
+}
+
+func (p *provider) createLinkedIssue(model dao.Issue, f apistructs.IssueTransitionPostFunction, operator string) error {
+	states, err := p.db.GetIssuesStatesByProjectID(model.ProjectID, f.IssueType)
+	if err != nil {
+		return err
+	}
+	if len(states) == 0 {
+		return fmt.Errorf("no state of issue type %s", f.IssueType)
+	}
+	title := f.Title
+	if title == "" {
+		title = model.Title
+	}
+	if operator == "" {
+		operator = model.Creator
+	}
+	now := time.Date(time.Now().Year(), time.Now().Month(), time.Now().Day(), 0, 0, 0, 0, time.Now().Location())
+	linked := dao.Issue{
+		ProjectID:    model.ProjectID,
+		IterationID:  model.IterationID,
+		Type:         f.IssueType,
+		Title:        title,
+		State:        int64(states[0].ID),
+		Priority:     model.Priority,
+		Complexity:   pb.IssueComplexityEnum_NORMAL.String(),
+		Severity:     pb.IssueSeverityEnum_NORMAL.String(),
+		Creator:      operator,
+		Assignee:     model.Assignee,
+		ExpiryStatus: dao.GetExpiryStatus(nil, now),
+	}
+	if f.IssueType == pb.IssueTypeEnum_BUG.String() {
+		linked.Owner = linked.Assignee
+	}
+	if err := p.db.CreateIssue(&linked); err != nil {
+		return err
+	}
+	return p.db.CreateIssueRelations(&dao.IssueRelation{
+		IssueID:      model.ID,
+		RelatedIssue: linked.ID,
+		Type:         apistructs.IssueRelationConnection,
+	})
+}
+
+func (p *provider) triggerTransitionPipeline(model dao.Issue, f apistructs.IssueTransitionPostFunction, operator string) error {
+	if p.ProjectPipeline == nil {
+		return fmt.Errorf("project pipeline service is not available")
+	}
+	ctx := apis.WithUserIDContext(context.Background(), operator)
+	_, err := p.ProjectPipeline.Run(ctx, &projectpipelinepb.RunProjectPipelineRequest{
+		PipelineDefinitionID: f.PipelineDefinitionID,
+		ProjectID:            int64(model.ProjectID),
+	})
+	return err
+}
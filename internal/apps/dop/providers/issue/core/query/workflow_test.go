@@ -0,0 +1,106 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	commonpb "github.com/erda-project/erda-proto-go/common/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/providers/issue/dao"
+)
+
+func Test_missingRequiredFields(t *testing.T) {
+	now := time.Now()
+	model := dao.Issue{
+		Title:       "issue",
+		Assignee:    "1",
+		IterationID: -1,
+		ManHour:     `{"estimateTime":0}`,
+	}
+	fields := []string{"title", "assignee", "owner", "iteration", "planFinishedAt", "estimateTime", "unknown"}
+
+	got := missingRequiredFields(model, map[string]interface{}{}, fields)
+	want := []string{"owner", "iteration", "planFinishedAt", "estimateTime"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("missingRequiredFields() = %v, want %v", got, want)
+	}
+
+	changed := map[string]interface{}{
+		"owner":            "2",
+		"iteration_id":     int64(3),
+		"plan_finished_at": &now,
+		"man_hour":         `{"estimateTime":60}`,
+		"assignee":         "",
+	}
+	got = missingRequiredFields(model, changed, fields)
+	want = []string{"assignee"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("missingRequiredFields() = %v, want %v", got, want)
+	}
+}
+
+func Test_applyTransitionFields(t *testing.T) {
+	model := dao.Issue{
+		Creator:     "1",
+		Assignee:    "2",
+		Priority:    "NORMAL",
+		IterationID: 5,
+	}
+	rule := dao.IssueStateTransitionRule{}
+	if err := rule.SetTransition(nil, []apistructs.IssueTransitionPostFunction{
+		{Type: apistructs.IssueTransitionPostAssignToCreator},
+		{Type: apistructs.IssueTransitionPostSetField, Field: "priority", Value: "HIGH"},
+		{Type: apistructs.IssueTransitionPostSetField, Field: "iteration", Value: "5"},
+		{Type: apistructs.IssueTransitionPostSetField, Field: "title", Value: "not allowed"},
+		{Type: apistructs.IssueTransitionPostTriggerPipeline, PipelineDefinitionID: "x"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := map[string]interface{}{"state": int64(2), "iteration_id": int64(6)}
+	stream := map[string][]interface{}{"state": {int64(1), int64(2)}, "iteration_id": {int64(5), int64(6)}}
+	applyTransitionFields(model, []dao.IssueStateTransitionRule{rule}, changed, stream)
+
+	wantChanged := map[string]interface{}{"state": int64(2), "assignee": "1", "priority": "HIGH"}
+	if !reflect.DeepEqual(changed, wantChanged) {
+		t.Errorf("changedFields = %v, want %v", changed, wantChanged)
+	}
+	wantStream := map[string][]interface{}{
+		"state":    {int64(1), int64(2)},
+		"assignee": {"2", "1"},
+		"priority": {"NORMAL", "HIGH"},
+	}
+	if !reflect.DeepEqual(stream, wantStream) {
+		t.Errorf("streamFields = %v, want %v", stream, wantStream)
+	}
+}
+
+func Test_checkTransitionRoles(t *testing.T) {
+	p := &provider{}
+	model := dao.Issue{ProjectID: 1}
+	roles := []string{"PM"}
+	if err := p.checkTransitionRoles(model, &commonpb.IdentityInfo{InternalClient: "bundle"}, roles); err != nil {
+		t.Errorf("internal client should bypass role guard, got %v", err)
+	}
+	if err := p.checkTransitionRoles(model, nil, roles); err == nil {
+		t.Error("missing identity should be denied")
+	}
+	if err := p.checkTransitionRoles(model, &commonpb.IdentityInfo{}, roles); err == nil {
+		t.Error("missing user should be denied")
+	}
+}
@@ -49,3 +49,12 @@ func (client *DBClient) DeleteIssueAppRelationsByComment(commentID int64) error
 func (client *DBClient) DeleteIssueAppRelationsByApp(appID int64) error {
 	return client.Where("app_id = ?", appID).Delete(&IssueAppRelation{}).Error
 }
+
+// GetIssueAppRelationsByIssueID 查询事件关联的合并请求
+func (client *DBClient) GetIssueAppRelationsByIssueID(issueID int64) ([]IssueAppRelation, error) {
+	var rels []IssueAppRelation
+	if err := client.Where("issue_id = ?", issueID).Find(&rels).Error; err != nil {
+		return nil, err
+	}
+	return rels, nil
+}
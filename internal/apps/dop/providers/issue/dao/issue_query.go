@@ -0,0 +1,80 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/erda-project/erda/internal/apps/dop/providers/issue/core/query/issueql"
+)
+
+// IssueGroupCount 事件分组统计结果
+type IssueGroupCount struct {
+	Key   string `gorm:"column:group_key"`
+	Count uint64 `gorm:"column:count"`
+}
+
+func (client *DBClient) issueQL(cond *issueql.Condition) *gorm.DB {
+	sql := client.Model(&Issue{}).Where("dice_issues.deleted = ?", 0)
+	if cond.Where != "" {
+		sql = sql.Where(cond.Where, cond.Args...)
+	}
+	return sql
+}
+
+// PagingIssuesByCondition 按事件查询语言编译的条件分页查询事件
+func (client *DBClient) PagingIssuesByCondition(cond *issueql.Condition, pageNo, pageSize uint64) ([]Issue, uint64, error) {
+	var (
+		total  uint64
+		issues []Issue
+	)
+	sql := client.issueQL(cond)
+	for _, order := range cond.Order {
+		sql = sql.Order(order)
+	}
+	sql = sql.Order("dice_issues.id DESC")
+	offset := (pageNo - 1) * pageSize
+	if err := sql.Offset(offset).Limit(pageSize).Find(&issues).
+		// reset offset & limit before count
+		Offset(0).Limit(-1).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	return issues, total, nil
+}
+
+// CountIssuesByCondition 按字段分组统计符合条件的事件数量
+func (client *DBClient) CountIssuesByCondition(cond *issueql.Condition, group *issueql.Group) ([]IssueGroupCount, error) {
+	sql := client.issueQL(cond)
+	if group.Join != "" {
+		sql = sql.Joins(group.Join)
+	}
+	var counts []IssueGroupCount
+	if err := sql.Select(fmt.Sprintf("%s AS group_key, COUNT(*) AS count", group.Column)).
+		Group(group.Column).Order("count DESC").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// MatchIssueByCondition 判断事件是否符合条件
+func (client *DBClient) MatchIssueByCondition(issueID uint64, cond *issueql.Condition) (bool, error) {
+	var count uint64
+	if err := client.issueQL(cond).Where("dice_issues.id = ?", issueID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
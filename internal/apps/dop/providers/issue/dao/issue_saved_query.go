@@ -0,0 +1,81 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/database/dbengine"
+)
+
+// IssueSavedQuery 保存的事件查询, ProjectID 为 0 表示企业下跨项目的查询
+type IssueSavedQuery struct {
+	dbengine.BaseModel
+
+	OrgID     uint64 `gorm:"column:org_id"`
+	ProjectID uint64 `gorm:"column:project_id"`
+	Name      string `gorm:"column:name"`
+	QL        string `gorm:"column:ql"`
+	Shared    bool   `gorm:"column:shared"`
+	Creator   string `gorm:"column:creator"`
+}
+
+func (IssueSavedQuery) TableName() string {
+	return "erda_issue_saved_query"
+}
+
+func (q *IssueSavedQuery) ToAPI() *apistructs.IssueSavedQuery {
+	return &apistructs.IssueSavedQuery{
+		ID:        q.ID,
+		OrgID:     q.OrgID,
+		ProjectID: q.ProjectID,
+		Name:      q.Name,
+		QL:        q.QL,
+		Shared:    q.Shared,
+		Creator:   q.Creator,
+		CreatedAt: q.CreatedAt,
+		UpdatedAt: q.UpdatedAt,
+	}
+}
+
+func (client *DBClient) CreateIssueSavedQuery(q *IssueSavedQuery) error {
+	return client.Create(q).Error
+}
+
+func (client *DBClient) UpdateIssueSavedQuery(q *IssueSavedQuery) error {
+	return client.Save(q).Error
+}
+
+func (client *DBClient) DeleteIssueSavedQuery(id uint64) error {
+	return client.Where("id = ?", id).Delete(&IssueSavedQuery{}).Error
+}
+
+func (client *DBClient) GetIssueSavedQuery(id uint64) (*IssueSavedQuery, error) {
+	var q IssueSavedQuery
+	if err := client.Where("id = ?", id).First(&q).Error; err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+// ListIssueSavedQueries 查询用户创建的及共享的查询
+func (client *DBClient) ListIssueSavedQueries(orgID, projectID uint64, userID string) ([]IssueSavedQuery, error) {
+	var queries []IssueSavedQuery
+	if err := client.Where("org_id = ? AND project_id = ?", orgID, projectID).
+		Where("creator = ? OR shared = ?", userID, true).
+		Order("id DESC").Find(&queries).Error; err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
@@ -0,0 +1,315 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/providers/issue/stream/common"
+	"github.com/erda-project/erda/pkg/database/dbengine"
+)
+
+// IssueSyncConnection 项目与外部事件系统(Jira/GitHub)的同步连接
+type IssueSyncConnection struct {
+	dbengine.BaseModel
+
+	OrgID            uint64           `gorm:"column:org_id"`
+	ProjectID        uint64           `gorm:"column:project_id"`
+	Name             string           `gorm:"column:name"`
+	Provider         string           `gorm:"column:provider"`
+	BaseURL          string           `gorm:"column:base_url"`
+	RemoteProject    string           `gorm:"column:remote_project"`
+	Username         string           `gorm:"column:username"`
+	Token            string           `gorm:"column:token"`          // 加密存储
+	WebhookSecret    string           `gorm:"column:webhook_secret"` // 加密存储
+	Mapping          IssueSyncMapping `gorm:"column:mapping"`
+	ConflictStrategy string           `gorm:"column:conflict_strategy"`
+	Enabled          bool             `gorm:"column:enabled"`
+	LastPolledAt     *time.Time       `gorm:"column:last_polled_at"`
+	Creator          string           `gorm:"column:creator"`
+}
+
+func (IssueSyncConnection) TableName() string {
+	return "erda_issue_sync_connection"
+}
+
+func (c *IssueSyncConnection) ToAPI() *apistructs.IssueSyncConnection {
+	return &apistructs.IssueSyncConnection{
+		ID:               c.ID,
+		OrgID:            c.OrgID,
+		ProjectID:        c.ProjectID,
+		Name:             c.Name,
+		Provider:         apistructs.IssueSyncProvider(c.Provider),
+		BaseURL:          c.BaseURL,
+		RemoteProject:    c.RemoteProject,
+		Username:         c.Username,
+		Mapping:          apistructs.IssueSyncMapping(c.Mapping),
+		ConflictStrategy: apistructs.IssueSyncConflictStrategy(c.ConflictStrategy),
+		Enabled:          c.Enabled,
+		LastPolledAt:     c.LastPolledAt,
+		Creator:          c.Creator,
+		CreatedAt:        c.CreatedAt,
+		UpdatedAt:        c.UpdatedAt,
+	}
+}
+
+// IssueSyncMapping 字段映射, 以 json 存储
+type IssueSyncMapping apistructs.IssueSyncMapping
+
+func (m IssueSyncMapping) Value() (driver.Value, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, errors.Errorf("failed to marshal IssueSyncMapping, err: %v", err)
+	}
+	return string(b), nil
+}
+
+func (m *IssueSyncMapping) Scan(value interface{}) error {
+	return scanJSON(value, m)
+}
+
+// IssueSyncLink Erda 事件与外部事件的关联, Snapshot 为最近一次同步完成时双方一致的字段值
+type IssueSyncLink struct {
+	dbengine.BaseModel
+
+	ConnectionID    uint64            `gorm:"column:connection_id"`
+	IssueID         uint64            `gorm:"column:issue_id"`
+	RemoteKey       string            `gorm:"column:remote_key"`
+	RemoteURL       string            `gorm:"column:remote_url"`
+	Snapshot        IssueSyncSnapshot `gorm:"column:snapshot"`
+	RemoteUpdatedAt *time.Time        `gorm:"column:remote_updated_at"`
+	SyncedAt        *time.Time        `gorm:"column:synced_at"`
+}
+
+func (IssueSyncLink) TableName() string {
+	return "erda_issue_sync_link"
+}
+
+func (l *IssueSyncLink) ToAPI() *apistructs.IssueSyncLink {
+	link := &apistructs.IssueSyncLink{
+		ConnectionID: l.ConnectionID,
+		IssueID:      l.IssueID,
+		RemoteKey:    l.RemoteKey,
+		RemoteURL:    l.RemoteURL,
+	}
+	if l.SyncedAt != nil {
+		link.SyncedAt = *l.SyncedAt
+	}
+	return link
+}
+
+// IssueSyncSnapshot 同步字段快照, 以 json 存储
+type IssueSyncSnapshot map[string]string
+
+func (s IssueSyncSnapshot) Value() (driver.Value, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, errors.Errorf("failed to marshal IssueSyncSnapshot, err: %v", err)
+	}
+	return string(b), nil
+}
+
+func (s *IssueSyncSnapshot) Scan(value interface{}) error {
+	return scanJSON(value, s)
+}
+
+// IssueSyncComment Erda 评论与外部评论的关联
+type IssueSyncComment struct {
+	dbengine.BaseModel
+
+	ConnectionID    uint64 `gorm:"column:connection_id"`
+	IssueID         uint64 `gorm:"column:issue_id"`
+	StreamID        uint64 `gorm:"column:stream_id"`
+	RemoteCommentID string `gorm:"column:remote_comment_id"`
+}
+
+func (IssueSyncComment) TableName() string {
+	return "erda_issue_sync_comment"
+}
+
+func scanJSON(value interface{}, dst interface{}) error {
+	if value == nil {
+		return nil
+	}
+	var v []byte
+	switch t := value.(type) {
+	case []byte:
+		v = t
+	case string:
+		v = []byte(t)
+	default:
+		return errors.Errorf("invalid scan source for %T", dst)
+	}
+	if len(v) == 0 {
+		return nil
+	}
+	return json.Unmarshal(v, dst)
+}
+
+func (client *DBClient) CreateIssueSyncConnection(conn *IssueSyncConnection) error {
+	return client.Create(conn).Error
+}
+
+func (client *DBClient) UpdateIssueSyncConnection(conn *IssueSyncConnection) error {
+	return client.Save(conn).Error
+}
+
+func (client *DBClient) DeleteIssueSyncConnection(id uint64) error {
+	if err := client.Where("connection_id = ?", id).Delete(&IssueSyncComment{}).Error; err != nil {
+		return err
+	}
+	if err := client.Where("connection_id = ?", id).Delete(&IssueSyncLink{}).Error; err != nil {
+		return err
+	}
+	return client.Where("id = ?", id).Delete(&IssueSyncConnection{}).Error
+}
+
+func (client *DBClient) GetIssueSyncConnection(id uint64) (*IssueSyncConnection, error) {
+	var conn IssueSyncConnection
+	if err := client.Where("id = ?", id).First(&conn).Error; err != nil {
+		return nil, err
+	}
+	return &conn, nil
+}
+
+func (client *DBClient) ListIssueSyncConnections(projectID uint64) ([]IssueSyncConnection, error) {
+	var conns []IssueSyncConnection
+	if err := client.Where("project_id = ?", projectID).Order("id").Find(&conns).Error; err != nil {
+		return nil, err
+	}
+	return conns, nil
+}
+
+func (client *DBClient) ListEnabledIssueSyncConnections() ([]IssueSyncConnection, error) {
+	var conns []IssueSyncConnection
+	if err := client.Where("enabled = ?", true).Order("id").Find(&conns).Error; err != nil {
+		return nil, err
+	}
+	return conns, nil
+}
+
+func (client *DBClient) UpdateIssueSyncConnectionPolledAt(id uint64, polledAt time.Time) error {
+	return client.Model(&IssueSyncConnection{}).Where("id = ?", id).
+		Update("last_polled_at", polledAt).Error
+}
+
+// ClaimIssueSyncLink 插入关联记录, 依赖唯一索引保证同一外部事件只被一个实例处理, 已存在时返回 false
+func (client *DBClient) ClaimIssueSyncLink(link *IssueSyncLink) (bool, error) {
+	var count int64
+	if err := client.Model(&IssueSyncLink{}).
+		Where("connection_id = ? AND (remote_key = ? OR (issue_id = ? AND issue_id > 0))", link.ConnectionID, link.RemoteKey, link.IssueID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	if err := client.Create(link).Error; err != nil {
+		// lost the race on the unique key
+		return false, nil
+	}
+	return true, nil
+}
+
+func (client *DBClient) SaveIssueSyncLink(link *IssueSyncLink) error {
+	return client.Save(link).Error
+}
+
+func (client *DBClient) DeleteIssueSyncLink(id uint64) error {
+	return client.Where("id = ?", id).Delete(&IssueSyncLink{}).Error
+}
+
+// GetIssueSyncLinkByIssue 不存在时返回 nil
+func (client *DBClient) GetIssueSyncLinkByIssue(connectionID, issueID uint64) (*IssueSyncLink, error) {
+	var link IssueSyncLink
+	r := client.Where("connection_id = ? AND issue_id = ?", connectionID, issueID).First(&link)
+	if r.RecordNotFound() {
+		return nil, nil
+	}
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	return &link, nil
+}
+
+// GetIssueSyncLinkByRemote 不存在时返回 nil
+func (client *DBClient) GetIssueSyncLinkByRemote(connectionID uint64, remoteKey string) (*IssueSyncLink, error) {
+	var link IssueSyncLink
+	r := client.Where("connection_id = ? AND remote_key = ?", connectionID, remoteKey).First(&link)
+	if r.RecordNotFound() {
+		return nil, nil
+	}
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	return &link, nil
+}
+
+func (client *DBClient) ListIssueSyncLinksByIssue(issueID uint64) ([]IssueSyncLink, error) {
+	var links []IssueSyncLink
+	if err := client.Where("issue_id = ?", issueID).Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (client *DBClient) ListIssueSyncComments(connectionID, issueID uint64) ([]IssueSyncComment, error) {
+	var comments []IssueSyncComment
+	if err := client.Where("connection_id = ? AND issue_id = ?", connectionID, issueID).Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (client *DBClient) CreateIssueSyncComment(comment *IssueSyncComment) error {
+	return client.Create(comment).Error
+}
+
+// ListIssueIDsUpdatedSince 查询项目下指定时间后有更新的事件 ID
+func (client *DBClient) ListIssueIDsUpdatedSince(projectID uint64, since time.Time) ([]uint64, error) {
+	var ids []uint64
+	if err := client.Model(&Issue{}).Where("project_id = ? AND deleted = 0 AND updated_at >= ?", projectID, since).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ListIssueIDsCommentedSince 查询项目下指定时间后有新评论的事件 ID
+func (client *DBClient) ListIssueIDsCommentedSince(projectID uint64, since time.Time) ([]uint64, error) {
+	var ids []uint64
+	if err := client.Table("dice_issue_streams AS stream").
+		Joins("JOIN dice_issues issue ON stream.issue_id = issue.id").
+		Where("issue.project_id = ? AND issue.deleted = 0 AND stream.stream_type = ? AND stream.created_at >= ?", projectID, common.ISTComment, since).
+		Pluck("DISTINCT stream.issue_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ListIssueCommentStreams 查询事件的评论
+func (client *DBClient) ListIssueCommentStreams(issueID uint64) ([]IssueStream, error) {
+	var streams []IssueStream
+	if err := client.Where("issue_id = ? AND stream_type = ?", issueID, common.ISTComment).
+		Order("id").Find(&streams).Error; err != nil {
+		return nil, err
+	}
+	return streams, nil
+}
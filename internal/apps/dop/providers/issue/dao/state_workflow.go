@@ -0,0 +1,136 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"encoding/json"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/database/dbengine"
+)
+
+// IssueStateTransitionRule 状态流转规则, 配置流转的前置校验和后置动作
+type IssueStateTransitionRule struct {
+	dbengine.BaseModel
+
+	ProjectID     uint64 `gorm:"column:project_id"`
+	IssueType     string `gorm:"column:issue_type"`
+	StateFrom     int64  `gorm:"column:state_from"`
+	StateTo       int64  `gorm:"column:state_to"`
+	Guards        string `gorm:"column:guards"`
+	PostFunctions string `gorm:"column:post_functions"`
+	Creator       string `gorm:"column:creator"`
+}
+
+func (IssueStateTransitionRule) TableName() string {
+	return "erda_issue_state_transition_rule"
+}
+
+// GetGuards 解析前置校验
+func (r *IssueStateTransitionRule) GetGuards() []apistructs.IssueTransitionGuard {
+	var guards []apistructs.IssueTransitionGuard
+	if r.Guards != "" {
+		_ = json.Unmarshal([]byte(r.Guards), &guards)
+	}
+	return guards
+}
+
+// GetPostFunctions 解析后置动作
+func (r *IssueStateTransitionRule) GetPostFunctions() []apistructs.IssueTransitionPostFunction {
+	var funcs []apistructs.IssueTransitionPostFunction
+	if r.PostFunctions != "" {
+		_ = json.Unmarshal([]byte(r.PostFunctions), &funcs)
+	}
+	return funcs
+}
+
+// SetTransition 序列化前置校验和后置动作
+func (r *IssueStateTransitionRule) SetTransition(guards []apistructs.IssueTransitionGuard, funcs []apistructs.IssueTransitionPostFunction) error {
+	if guards == nil {
+		guards = []apistructs.IssueTransitionGuard{}
+	}
+	if funcs == nil {
+		funcs = []apistructs.IssueTransitionPostFunction{}
+	}
+	g, err := json.Marshal(guards)
+	if err != nil {
+		return err
+	}
+	f, err := json.Marshal(funcs)
+	if err != nil {
+		return err
+	}
+	r.Guards, r.PostFunctions = string(g), string(f)
+	return nil
+}
+
+func (r *IssueStateTransitionRule) ToAPI() *apistructs.IssueStateTransitionRule {
+	return &apistructs.IssueStateTransitionRule{
+		ID:            r.ID,
+		ProjectID:     r.ProjectID,
+		IssueType:     r.IssueType,
+		StateFrom:     r.StateFrom,
+		StateTo:       r.StateTo,
+		Guards:        r.GetGuards(),
+		PostFunctions: r.GetPostFunctions(),
+		Creator:       r.Creator,
+		CreatedAt:     r.CreatedAt,
+		UpdatedAt:     r.UpdatedAt,
+	}
+}
+
+func (client *DBClient) CreateIssueStateTransitionRule(r *IssueStateTransitionRule) error {
+	return client.Create(r).Error
+}
+
+func (client *DBClient) UpdateIssueStateTransitionRule(r *IssueStateTransitionRule) error {
+	return client.Save(r).Error
+}
+
+func (client *DBClient) DeleteIssueStateTransitionRule(id uint64) error {
+	return client.Where("id = ?", id).Delete(&IssueStateTransitionRule{}).Error
+}
+
+func (client *DBClient) GetIssueStateTransitionRule(id uint64) (*IssueStateTransitionRule, error) {
+	var r IssueStateTransitionRule
+	if err := client.Where("id = ?", id).First(&r).Error; err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ListIssueStateTransitionRules 查询项目的状态流转规则, issueType 为空时返回全部类型
+func (client *DBClient) ListIssueStateTransitionRules(projectID uint64, issueType string) ([]IssueStateTransitionRule, error) {
+	var rules []IssueStateTransitionRule
+	db := client.Where("project_id = ?", projectID)
+	if issueType != "" {
+		db = db.Where("issue_type = ?", issueType)
+	}
+	if err := db.Order("state_to, state_from").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetMatchedIssueStateTransitionRules 查询一次状态流转匹配的规则, 包括从任意状态流转到目标状态的规则
+func (client *DBClient) GetMatchedIssueStateTransitionRules(projectID uint64, issueType string, from, to int64) ([]IssueStateTransitionRule, error) {
+	var rules []IssueStateTransitionRule
+	if err := client.Where("project_id = ? AND issue_type = ? AND state_to = ?", projectID, issueType, to).
+		Where("state_from IN (?)", []int64{0, from}).
+		Order("id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
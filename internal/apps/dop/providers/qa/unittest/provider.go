@@ -19,6 +19,7 @@ import (
 	"github.com/erda-project/erda-infra/base/servicehub"
 	"github.com/erda-project/erda-infra/pkg/transport"
 	"github.com/erda-project/erda-proto-go/dop/qa/unittest/pb"
+	"github.com/erda-project/erda/internal/apps/dop/services/flakytest"
 	"github.com/erda-project/erda/pkg/common/apis"
 )
 
@@ -34,7 +35,7 @@ type provider struct {
 }
 
 func (p *provider) Init(ctx servicehub.Context) error {
-	p.unitTestService = &UnitTestService{logger: p.Log}
+	p.unitTestService = &UnitTestService{logger: p.Log, flakyTest: flakytest.New()}
 	if p.Register != nil {
 		pb.RegisterUnitTestServiceImp(p.Register, p.unitTestService, apis.Options())
 	}
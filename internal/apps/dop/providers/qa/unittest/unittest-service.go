@@ -25,13 +25,15 @@ import (
 	"github.com/erda-project/erda/apistructs"
 	"github.com/erda-project/erda/internal/apps/dop/dao"
 	"github.com/erda-project/erda/internal/apps/dop/dbclient"
+	"github.com/erda-project/erda/internal/apps/dop/services/flakytest"
 	"github.com/erda-project/erda/pkg/qaparser/types"
 )
 
 type UnitTestService struct {
 	logger logs.Logger
 
-	db *dao.DBClient
+	db        *dao.DBClient
+	flakyTest *flakytest.Service
 }
 
 func (s *UnitTestService) Callback(ctx context.Context, req *pb.TestCallBackRequest) (*pb.TestCallBackResponse, error) {
@@ -61,6 +63,11 @@ func (s *UnitTestService) Callback(ctx context.Context, req *pb.TestCallBackRequ
 	if err != nil {
 		return nil, err
 	}
+	if s.flakyTest != nil {
+		if err := s.flakyTest.Record(tpRecord); err != nil {
+			s.logger.Errorf("failed to record flaky test stats, record: %d, err: %v", tpRecord.ID, err)
+		}
+	}
 	return &pb.TestCallBackResponse{Data: strconv.FormatUint(tpRecord.ID, 10)}, nil
 }
 
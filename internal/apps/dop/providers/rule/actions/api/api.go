@@ -16,6 +16,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/url"
@@ -36,7 +37,7 @@ type config struct {
 }
 
 type Interface interface {
-	Send(api *API) (string, error)
+	Send(ctx context.Context, api *API) (string, error)
 }
 
 type provider struct {
@@ -70,11 +71,15 @@ type APIConfig struct {
 }
 
 // outgoing API
-func (a *provider) Send(api *API) (string, error) {
+func (a *provider) Send(ctx context.Context, api *API) (string, error) {
 	apiConfig, err := a.getAPIConfig(api)
 	if err != nil {
 		return "", err
 	}
+	// httpclient can't be cancelled, at least don't send after the node is cancelled
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 
 	parsed, err := url.Parse(apiConfig.URL)
 	if err != nil {
@@ -28,7 +28,7 @@ import (
 )
 
 type Interface interface {
-	Send(param *JsonnetParam) (string, error)
+	Send(ctx context.Context, param *JsonnetParam) (string, error)
 }
 
 type provider struct {
@@ -60,18 +60,21 @@ func (p *provider) Init(ctx servicehub.Context) error {
 	return nil
 }
 
-func (p *provider) Send(w *JsonnetParam) (string, error) {
-	d, err := p.getDingTalkConfig(w)
+func (p *provider) Send(ctx context.Context, w *JsonnetParam) (string, error) {
+	d, err := p.getDingTalkConfig(ctx, w)
 	if err != nil {
 		return "", err
 	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 
 	client := p.DingtalkApiClient.GetClient(d.AppKey, d.AppSecret, d.AgentId)
 	err = client.SendWorkNotice(d.Users, d.Title, d.Content)
 	return "", err
 }
 
-func (p *provider) getDingTalkConfig(param *JsonnetParam) (*DingTalkConfig, error) {
+func (p *provider) getDingTalkConfig(ctx context.Context, param *JsonnetParam) (*DingTalkConfig, error) {
 	// parse content with jsonnet
 	b, err := json.Marshal(param.TLARaw)
 	if err != nil {
@@ -94,7 +97,7 @@ func (p *provider) getDingTalkConfig(param *JsonnetParam) (*DingTalkConfig, erro
 	}
 
 	// find users mobile info
-	resp, err := p.Identity.FindUsers(context.Background(), &userpb.FindUsersRequest{IDs: d.Users})
+	resp, err := p.Identity.FindUsers(ctx, &userpb.FindUsersRequest{IDs: d.Users})
 	if err != nil {
 		return nil, err
 	}
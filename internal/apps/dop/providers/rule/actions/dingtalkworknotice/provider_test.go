@@ -85,7 +85,7 @@ func Test_provider_getDingTalkConfig(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			got, err := p.getDingTalkConfig(tt.args.param)
+			got, err := p.getDingTalkConfig(context.Background(), tt.args.param)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("provider.getDingTalkConfig() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -0,0 +1,147 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	gojsonnet "github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda-infra/base/servicehub"
+	userpb "github.com/erda-project/erda-proto-go/core/user/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/bundle"
+	"github.com/erda-project/erda/internal/apps/dop/providers/rule/jsonnet"
+	"github.com/erda-project/erda/pkg/strutil"
+)
+
+type Interface interface {
+	Send(ctx context.Context, param *JsonnetParam) (string, error)
+}
+
+type provider struct {
+	Identity       userpb.UserServiceServer
+	TemplateParser *jsonnet.Engine
+	bdl            *bundle.Bundle
+}
+
+type JsonnetParam struct {
+	// email config jsonnet snippet
+	Snippet string
+	// request jsonnet top level arguments, key: TLA key, value: TLA value
+	TLARaw map[string]interface{}
+}
+
+// EmailConfig is rendered by the jsonnet snippet
+type EmailConfig struct {
+	// OrgID decides which email channel of the org is used
+	OrgID uint64 `json:"orgID"`
+	// Users are user ids, their emails are looked up
+	Users   []string `json:"users"`
+	Emails  []string `json:"emails"`
+	Subject string   `json:"subject"`
+	// Content is rendered as markdown
+	Content string `json:"content"`
+}
+
+func (p *provider) Init(ctx servicehub.Context) error {
+	p.TemplateParser = &jsonnet.Engine{
+		JsonnetVM: gojsonnet.MakeVM(),
+	}
+	p.bdl = bundle.New(bundle.WithErdaServer())
+	return nil
+}
+
+func (p *provider) Send(ctx context.Context, param *JsonnetParam) (string, error) {
+	c, err := p.getEmailConfig(ctx, param)
+	if err != nil {
+		return "", err
+	}
+	if len(c.Emails) == 0 {
+		return "", errors.New("no email receivers")
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	err = p.bdl.CreateEventNotify(&apistructs.EventBoxRequest{
+		Sender: "dop-rule",
+		Labels: map[string]interface{}{
+			"EMAIL": c.Emails,
+		},
+		Content: map[string]interface{}{
+			"template": c.Content,
+			"type":     "markdown",
+			"params":   map[string]string{"title": c.Subject},
+			"orgID":    int64(c.OrgID),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return strutil.Join(c.Emails, ","), nil
+}
+
+func (p *provider) getEmailConfig(ctx context.Context, param *JsonnetParam) (*EmailConfig, error) {
+	b, err := json.Marshal(param.TLARaw)
+	if err != nil {
+		return nil, err
+	}
+	jsonStr, err := p.TemplateParser.EvaluateBySnippet(param.Snippet, []jsonnet.TLACodeConfig{
+		{
+			Key:   "ctx",
+			Value: string(b),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var c EmailConfig
+	if err := json.Unmarshal([]byte(jsonStr), &c); err != nil {
+		return nil, err
+	}
+	if c.OrgID == 0 {
+		return nil, errors.New("missing orgID")
+	}
+
+	// find users email info
+	if len(c.Users) > 0 {
+		resp, err := p.Identity.FindUsers(ctx, &userpb.FindUsersRequest{IDs: c.Users})
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range resp.Data {
+			if u.Email != "" {
+				c.Emails = append(c.Emails, u.Email)
+			}
+		}
+	}
+	c.Emails = strutil.DedupSlice(c.Emails, true)
+	return &c, nil
+}
+
+func init() {
+	servicehub.Register("erda.dop.rule.action.email", &servicehub.Spec{
+		Services: []string{"erda.core.rule.action.email"},
+		Types:    []reflect.Type{reflect.TypeOf((*Interface)(nil)).Elem()},
+		Creator: func() servicehub.Provider {
+			return &provider{}
+		},
+	})
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package im
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	gojsonnet "github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda-infra/base/servicehub"
+	"github.com/erda-project/erda/internal/apps/dop/providers/rule/jsonnet"
+)
+
+// supported im action types
+const (
+	TypeSlack  = "slack"
+	TypeFeishu = "feishu"
+	TypeWeCom  = "wecom"
+)
+
+type config struct {
+	Timeout time.Duration `default:"30s" file:"timeout" env:"RULE_IM_TIMEOUT"`
+}
+
+type Interface interface {
+	Send(ctx context.Context, typ string, param *JsonnetParam) (string, error)
+}
+
+type provider struct {
+	Cfg            *config
+	TemplateParser *jsonnet.Engine
+	client         *http.Client
+}
+
+type JsonnetParam struct {
+	// message config jsonnet snippet
+	Snippet string
+	// request jsonnet top level arguments, key: TLA key, value: TLA value
+	TLARaw map[string]interface{}
+}
+
+// MessageConfig is rendered by the jsonnet snippet
+type MessageConfig struct {
+	Webhook string `json:"webhook"`
+	// Secret is the signing secret of feishu custom bots
+	Secret  string `json:"secret"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+func (p *provider) Init(ctx servicehub.Context) error {
+	p.TemplateParser = &jsonnet.Engine{
+		JsonnetVM: gojsonnet.MakeVM(),
+	}
+	p.client = &http.Client{Timeout: p.Cfg.Timeout}
+	return nil
+}
+
+func (p *provider) Send(ctx context.Context, typ string, param *JsonnetParam) (string, error) {
+	c, err := p.getMessageConfig(param)
+	if err != nil {
+		return "", err
+	}
+	payload, err := buildPayload(typ, c, time.Now())
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Webhook, bytes.NewReader(b))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json;charset=utf-8")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", errors.Errorf("%s webhook err: %v", typ, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if err := checkResponse(typ, resp.StatusCode, body); err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+func (p *provider) getMessageConfig(param *JsonnetParam) (*MessageConfig, error) {
+	b, err := json.Marshal(param.TLARaw)
+	if err != nil {
+		return nil, err
+	}
+	jsonStr, err := p.TemplateParser.EvaluateBySnippet(param.Snippet, []jsonnet.TLACodeConfig{
+		{
+			Key:   "ctx",
+			Value: string(b),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var c MessageConfig
+	if err := json.Unmarshal([]byte(jsonStr), &c); err != nil {
+		return nil, err
+	}
+	if c.Webhook == "" {
+		return nil, errors.New("missing webhook")
+	}
+	return &c, nil
+}
+
+func buildPayload(typ string, c *MessageConfig, now time.Time) (map[string]interface{}, error) {
+	switch typ {
+	case TypeSlack:
+		text := c.Content
+		if c.Title != "" {
+			text = fmt.Sprintf("*%s*\n%s", c.Title, c.Content)
+		}
+		return map[string]interface{}{"text": text}, nil
+	case TypeFeishu:
+		text := c.Content
+		if c.Title != "" {
+			text = c.Title + "\n" + c.Content
+		}
+		payload := map[string]interface{}{
+			"msg_type": "text",
+			"content":  map[string]interface{}{"text": text},
+		}
+		if c.Secret != "" {
+			timestamp := strconv.FormatInt(now.Unix(), 10)
+			payload["timestamp"] = timestamp
+			payload["sign"] = feishuSign(c.Secret, timestamp)
+		}
+		return payload, nil
+	case TypeWeCom:
+		content := c.Content
+		if c.Title != "" {
+			content = fmt.Sprintf("### %s\n%s", c.Title, c.Content)
+		}
+		return map[string]interface{}{
+			"msgtype":  "markdown",
+			"markdown": map[string]interface{}{"content": content},
+		}, nil
+	default:
+		return nil, errors.Errorf("invalid im type: %s", typ)
+	}
+}
+
+// feishuSign signs with base64(hmac_sha256(timestamp + "\n" + secret, ""))
+func feishuSign(secret, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// checkResponse checks both http status and the error code in body, feishu and wecom respond 200 with error codes
+func checkResponse(typ string, status int, body []byte) error {
+	if status < 200 || status >= 300 {
+		return errors.Errorf("%s webhook httpcode: %d, body: %s", typ, status, body)
+	}
+	var result struct {
+		Code       *int   `json:"code"`
+		StatusCode *int   `json:"StatusCode"`
+		ErrCode    *int   `json:"errcode"`
+		Msg        string `json:"msg"`
+		ErrMsg     string `json:"errmsg"`
+	}
+	switch typ {
+	case TypeFeishu:
+		if err := json.Unmarshal(body, &result); err != nil {
+			return errors.Errorf("feishu webhook invalid response: %s", body)
+		}
+		if result.Code != nil && *result.Code != 0 {
+			return errors.Errorf("feishu webhook code: %d, msg: %s", *result.Code, result.Msg)
+		}
+		if result.StatusCode != nil && *result.StatusCode != 0 {
+			return errors.Errorf("feishu webhook code: %d, body: %s", *result.StatusCode, body)
+		}
+	case TypeWeCom:
+		if err := json.Unmarshal(body, &result); err != nil {
+			return errors.Errorf("wecom webhook invalid response: %s", body)
+		}
+		if result.ErrCode != nil && *result.ErrCode != 0 {
+			return errors.Errorf("wecom webhook errcode: %d, errmsg: %s", *result.ErrCode, result.ErrMsg)
+		}
+	}
+	return nil
+}
+
+func init() {
+	servicehub.Register("erda.dop.rule.action.im", &servicehub.Spec{
+		Services:   []string{"erda.core.rule.action.im"},
+		Types:      []reflect.Type{reflect.TypeOf((*Interface)(nil)).Elem()},
+		ConfigFunc: func() interface{} { return &config{} },
+		Creator: func() servicehub.Provider {
+			return &provider{}
+		},
+	})
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package im
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_buildPayload(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	c := &MessageConfig{Title: "Bug created", Content: "issue #1"}
+
+	payload, err := buildPayload(TypeSlack, c, now)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"text": "*Bug created*\nissue #1"}, payload)
+
+	payload, err = buildPayload(TypeWeCom, c, now)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"msgtype":  "markdown",
+		"markdown": map[string]interface{}{"content": "### Bug created\nissue #1"},
+	}, payload)
+
+	payload, err = buildPayload(TypeFeishu, &MessageConfig{Content: "issue #1", Secret: "secret"}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"msg_type":  "text",
+		"content":   map[string]interface{}{"text": "issue #1"},
+		"timestamp": "1700000000",
+		"sign":      "fiWS2+gh28DOydAv7hzONH/mDn9+b1Y4Y5ivXWXy8vA=",
+	}, payload)
+
+	_, err = buildPayload("teams", c, now)
+	assert.Error(t, err)
+}
+
+func Test_checkResponse(t *testing.T) {
+	assert.NoError(t, checkResponse(TypeSlack, 200, []byte("ok")))
+	assert.Error(t, checkResponse(TypeSlack, 404, []byte("no_team")))
+	assert.NoError(t, checkResponse(TypeFeishu, 200, []byte(`{"code":0,"msg":"success"}`)))
+	assert.NoError(t, checkResponse(TypeFeishu, 200, []byte(`{"StatusCode":0,"StatusMessage":"success"}`)))
+	assert.Error(t, checkResponse(TypeFeishu, 200, []byte(`{"code":19021,"msg":"sign match fail"}`)))
+	assert.NoError(t, checkResponse(TypeWeCom, 200, []byte(`{"errcode":0,"errmsg":"ok"}`)))
+	assert.Error(t, checkResponse(TypeWeCom, 200, []byte(`{"errcode":93000,"errmsg":"invalid webhook url"}`)))
+}
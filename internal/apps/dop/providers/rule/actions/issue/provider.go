@@ -0,0 +1,141 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	gojsonnet "github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/erda-project/erda-infra/base/servicehub"
+	"github.com/erda-project/erda-proto-go/dop/issue/core/pb"
+	"github.com/erda-project/erda/internal/apps/dop/providers/rule/jsonnet"
+	"github.com/erda-project/erda/pkg/common/apis"
+)
+
+// supported issue operations
+const (
+	OperationCreate = "create"
+	OperationUpdate = "update"
+)
+
+type Interface interface {
+	Send(ctx context.Context, param *JsonnetParam) (string, error)
+}
+
+type provider struct {
+	IssueCore      pb.IssueCoreServiceServer `autowired:"erda.dop.issue.core.IssueCoreService"`
+	TemplateParser *jsonnet.Engine
+}
+
+type JsonnetParam struct {
+	// issue config jsonnet snippet
+	Snippet string
+	// request jsonnet top level arguments, key: TLA key, value: TLA value
+	TLARaw map[string]interface{}
+	// the issue is created or updated as actor
+	Actor string
+}
+
+// IssueConfig is rendered by the jsonnet snippet, Issue is an IssueCreateRequest
+// when creating and an UpdateIssueRequest with id when updating
+type IssueConfig struct {
+	Operation string          `json:"operation"`
+	Issue     json.RawMessage `json:"issue"`
+}
+
+var unmarshaler = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+func (p *provider) Init(ctx servicehub.Context) error {
+	p.TemplateParser = &jsonnet.Engine{
+		JsonnetVM: gojsonnet.MakeVM(),
+	}
+	return nil
+}
+
+func (p *provider) Send(ctx context.Context, param *JsonnetParam) (string, error) {
+	c, err := p.getIssueConfig(param)
+	if err != nil {
+		return "", err
+	}
+	ctx = apis.WithUserIDContext(ctx, param.Actor)
+
+	switch c.Operation {
+	case OperationCreate:
+		var req pb.IssueCreateRequest
+		if err := unmarshaler.Unmarshal(c.Issue, &req); err != nil {
+			return "", errors.Wrap(err, "invalid issue create request")
+		}
+		resp, err := p.IssueCore.CreateIssue(ctx, &req)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("issue %d created", resp.Data), nil
+	case OperationUpdate:
+		var req pb.UpdateIssueRequest
+		if err := unmarshaler.Unmarshal(c.Issue, &req); err != nil {
+			return "", errors.Wrap(err, "invalid issue update request")
+		}
+		if req.Id == 0 {
+			return "", errors.New("missing issue id")
+		}
+		if _, err := p.IssueCore.UpdateIssue(ctx, &req); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("issue %d updated", req.Id), nil
+	default:
+		return "", errors.Errorf("invalid issue operation: %s", c.Operation)
+	}
+}
+
+func (p *provider) getIssueConfig(param *JsonnetParam) (*IssueConfig, error) {
+	b, err := json.Marshal(param.TLARaw)
+	if err != nil {
+		return nil, err
+	}
+	jsonStr, err := p.TemplateParser.EvaluateBySnippet(param.Snippet, []jsonnet.TLACodeConfig{
+		{
+			Key:   "ctx",
+			Value: string(b),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var c IssueConfig
+	if err := json.Unmarshal([]byte(jsonStr), &c); err != nil {
+		return nil, err
+	}
+	if len(c.Issue) == 0 {
+		return nil, errors.New("missing issue")
+	}
+	return &c, nil
+}
+
+func init() {
+	servicehub.Register("erda.dop.rule.action.issue", &servicehub.Spec{
+		Services: []string{"erda.core.rule.action.issue"},
+		Types:    []reflect.Type{reflect.TypeOf((*Interface)(nil)).Elem()},
+		Creator: func() servicehub.Provider {
+			return &provider{}
+		},
+	})
+}
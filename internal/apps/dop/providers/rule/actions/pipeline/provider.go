@@ -58,7 +58,7 @@ func (p *provider) WithBranchRule(branchRule *branchrule.BranchRule) {
 }
 
 type Interface interface {
-	CreatePipeline(env map[string]interface{}) (string, error)
+	CreatePipeline(ctx context.Context, env map[string]interface{}) (string, error)
 	WithPipelineSvc(svc *pipeline.Pipeline)
 	WithBranchRule(branchRule *branchrule.BranchRule)
 }
@@ -83,7 +83,7 @@ type PipelineRuleEnv struct {
 	Config  *PipelineConfig                   `json:"pipelineConfig"`
 }
 
-func (p *provider) CreatePipeline(env map[string]interface{}) (string, error) {
+func (p *provider) CreatePipeline(ctx context.Context, env map[string]interface{}) (string, error) {
 	gitPush, ok := env["git_push"]
 	if !ok {
 		return "", fmt.Errorf("empty git_push config")
@@ -107,7 +107,6 @@ func (p *provider) CreatePipeline(env map[string]interface{}) (string, error) {
 	}
 
 	// create pipeline
-	ctx := context.Background()
 	definitionID, err := p.GetPipelineDefinitionID(apis.WithUserIDContext(ctx, req.UserID), app, req.RefName, req.Path, req.FileName, req.PipelineYmlStr)
 	if err != nil {
 		logrus.Errorf("failed to bind definition %v", err)
@@ -0,0 +1,189 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	gojsonnet "github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda-infra/base/servicehub"
+	"github.com/erda-project/erda/internal/apps/dop/providers/rule/jsonnet"
+	"github.com/erda-project/erda/pkg/http/httputil"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 signature of timestamp and body
+	SignatureHeader = "X-Erda-Signature"
+	// TimestampHeader carries the unix timestamp used in the signature
+	TimestampHeader = "X-Erda-Timestamp"
+)
+
+type config struct {
+	Timeout time.Duration `default:"30s" file:"timeout" env:"RULE_WEBHOOK_TIMEOUT"`
+}
+
+type Interface interface {
+	Send(ctx context.Context, param *JsonnetParam) (string, error)
+}
+
+type provider struct {
+	Cfg            *config
+	TemplateParser *jsonnet.Engine
+	client         *http.Client
+}
+
+type JsonnetParam struct {
+	// webhook config jsonnet snippet
+	Snippet string
+	// request jsonnet top level arguments, key: TLA key, value: TLA value
+	TLARaw map[string]interface{}
+}
+
+// WebhookConfig is rendered by the jsonnet snippet
+type WebhookConfig struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers"`
+	// Body is sent as is if it is a string, otherwise encoded as json
+	Body interface{} `json:"body"`
+	// Secret signs the request, receivers verify it with hex(hmac_sha256(secret, timestamp + "." + body))
+	Secret string `json:"secret"`
+}
+
+func (p *provider) Init(ctx servicehub.Context) error {
+	p.TemplateParser = &jsonnet.Engine{
+		JsonnetVM: gojsonnet.MakeVM(),
+	}
+	p.client = &http.Client{Timeout: p.Cfg.Timeout}
+	return nil
+}
+
+func (p *provider) Send(ctx context.Context, param *JsonnetParam) (string, error) {
+	c, err := p.getWebhookConfig(param)
+	if err != nil {
+		return "", err
+	}
+	req, err := newRequest(ctx, c, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", errors.Errorf("webhook: %s, err: %v", c.URL, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", errors.Errorf("webhook: %s, httpcode: %d, body: %s", c.URL, resp.StatusCode, body)
+	}
+	return string(body), nil
+}
+
+func (p *provider) getWebhookConfig(param *JsonnetParam) (*WebhookConfig, error) {
+	b, err := json.Marshal(param.TLARaw)
+	if err != nil {
+		return nil, err
+	}
+	jsonStr, err := p.TemplateParser.EvaluateBySnippet(param.Snippet, []jsonnet.TLACodeConfig{
+		{
+			Key:   "ctx",
+			Value: string(b),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var c WebhookConfig
+	if err := json.Unmarshal([]byte(jsonStr), &c); err != nil {
+		return nil, err
+	}
+	if c.URL == "" {
+		return nil, errors.New("missing webhook url")
+	}
+	if c.Method == "" {
+		c.Method = http.MethodPost
+	}
+	return &c, nil
+}
+
+func newRequest(ctx context.Context, c *WebhookConfig, now time.Time) (*http.Request, error) {
+	var body []byte
+	switch v := c.Body.(type) {
+	case nil:
+	case string:
+		body = []byte(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(c.Method), c.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+	// never forward internal identities to outside
+	req.Header.Del(httputil.InternalHeader)
+	req.Header.Del(httputil.UserHeader)
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json;charset=utf-8")
+	}
+	if c.Secret != "" {
+		timestamp := strconv.FormatInt(now.Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, "sha256="+Sign(c.Secret, timestamp, body))
+	}
+	return req, nil
+}
+
+// Sign returns hex(hmac_sha256(secret, timestamp + "." + body))
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%s.", timestamp)))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func init() {
+	servicehub.Register("erda.dop.rule.action.webhook", &servicehub.Spec{
+		Services:   []string{"erda.core.rule.action.webhook"},
+		Types:      []reflect.Type{reflect.TypeOf((*Interface)(nil)).Elem()},
+		ConfigFunc: func() interface{} { return &config{} },
+		Creator: func() servicehub.Provider {
+			return &provider{}
+		},
+	})
+}
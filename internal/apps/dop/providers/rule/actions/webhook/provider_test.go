@@ -0,0 +1,63 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/pkg/http/httputil"
+)
+
+func Test_newRequest(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	req, err := newRequest(context.Background(), &WebhookConfig{
+		URL:    "https://example.com/hook",
+		Method: "post",
+		Headers: map[string]string{
+			"X-Custom":              "1",
+			httputil.InternalHeader: "dop",
+		},
+		Body:   map[string]interface{}{"issue": 1},
+		Secret: "s3cret",
+	}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "1", req.Header.Get("X-Custom"))
+	assert.Equal(t, "", req.Header.Get(httputil.InternalHeader))
+	assert.Equal(t, "application/json;charset=utf-8", req.Header.Get("Content-Type"))
+	assert.Equal(t, "1700000000", req.Header.Get(TimestampHeader))
+
+	body, _ := io.ReadAll(req.Body)
+	assert.Equal(t, `{"issue":1}`, string(body))
+	assert.Equal(t, "sha256="+Sign("s3cret", "1700000000", body), req.Header.Get(SignatureHeader))
+
+	req, err = newRequest(context.Background(), &WebhookConfig{URL: "https://example.com/hook", Method: http.MethodPut, Body: "plain"}, now)
+	assert.NoError(t, err)
+	body, _ = io.ReadAll(req.Body)
+	assert.Equal(t, "plain", string(body))
+	assert.Equal(t, "", req.Header.Get(SignatureHeader))
+}
+
+func TestSign(t *testing.T) {
+	assert.Equal(t, "0867895167042b052d6a5d8721fad518cf31d26deb78184d29525d718f18eed6", Sign("s3cret", "1700000000", []byte(`{"issue":1}`)))
+	assert.NotEqual(t, Sign("a", "1", []byte("body")), Sign("b", "1", []byte("body")))
+	assert.NotEqual(t, Sign("a", "1", []byte("body")), Sign("a", "2", []byte("body")))
+}
@@ -43,6 +43,7 @@ type RuleExecRecord struct {
 	ActionOutput  string
 	SoftDeletedAt uint64
 	Actor         string
+	NodeResults   NodeResults
 }
 
 func (RuleExecRecord) TableName() string {
@@ -76,6 +77,34 @@ func (p *Env) Scan(value interface{}) error {
 	return nil
 }
 
+// NodeResults is the result of each action node
+type NodeResults []*pb.ActionNodeResult
+
+func (p NodeResults) Value() (driver.Value, error) {
+	if b, err := json.Marshal(p); err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal node results")
+	} else {
+		return string(b), nil
+	}
+}
+
+func (p *NodeResults) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	v, ok := value.([]byte)
+	if !ok {
+		return errors.New("invalid scan source for node results")
+	}
+	if len(v) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(v, p); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal node results")
+	}
+	return nil
+}
+
 func (db *DBClient) CreateRuleExecRecord(r *RuleExecRecord) error {
 	id, err := uuid.NewRandom()
 	if err != nil {
@@ -15,21 +15,54 @@
 package executor
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
 
 	"github.com/erda-project/erda-proto-go/dop/rule/pb"
 	"github.com/erda-project/erda/apistructs"
 	"github.com/erda-project/erda/internal/apps/dop/providers/rule/actions/api"
 	"github.com/erda-project/erda/internal/apps/dop/providers/rule/actions/dingtalkworknotice"
+	"github.com/erda-project/erda/internal/apps/dop/providers/rule/actions/email"
+	"github.com/erda-project/erda/internal/apps/dop/providers/rule/actions/im"
+	"github.com/erda-project/erda/internal/apps/dop/providers/rule/actions/issue"
 	"github.com/erda-project/erda/internal/apps/dop/providers/rule/actions/pipeline"
+	"github.com/erda-project/erda/internal/apps/dop/providers/rule/actions/webhook"
 	"github.com/erda-project/erda/pkg/strutil"
 )
 
+const (
+	defaultNodeTimeout   = 30 * time.Second
+	defaultRetryInterval = time.Second
+	maxNodeRetry         = 5
+)
+
+var (
+	errInvalidActionType = errors.New("invalid action type")
+	// errNodeTimeout the action may have taken effect, so the node is not retried after timeout
+	errNodeTimeout = errors.New("timeout")
+	errNodePanic   = errors.New("panic")
+)
+
 type Executor struct {
 	RuleExecutor
 	API      api.Interface
 	Pipeline pipeline.Interface
 	DingTalk dingtalkworknotice.Interface
+	Webhook  webhook.Interface
+	Email    email.Interface
+	Issue    issue.Interface
+	IM       im.Interface
+
+	// NodeTimeout limits each attempt of a node without its own timeout
+	NodeTimeout time.Duration
+	// RetryInterval is multiplied by the attempt number between retries
+	RetryInterval time.Duration
 }
 
 func (e *Executor) Fire(req *pb.FireRequest) ([]bool, error) {
@@ -45,6 +78,7 @@ func (e *Executor) Fire(req *pb.FireRequest) ([]bool, error) {
 	results := make([]bool, len(configs))
 	actionOutputs := make([]string, len(configs))
 	actors := make([]string, len(configs))
+	nodeResults := make([][]*pb.ActionNodeResult, len(configs))
 	for i, v := range configs {
 		res, err := e.Exec(v, ruleEnv.Env)
 		if err != nil {
@@ -54,7 +88,8 @@ func (e *Executor) Fire(req *pb.FireRequest) ([]bool, error) {
 			results[i] = res
 			continue
 		}
-		actionOutputs[i] = e.Do(ruleEnv.Env, v)
+		nodeResults[i] = e.DoNodes(ruleEnv.Env, v)
+		actionOutputs[i] = joinOutputs(nodeResults[i])
 		results[i] = res
 		actors[i] = v.Actor
 	}
@@ -67,6 +102,7 @@ func (e *Executor) Fire(req *pb.FireRequest) ([]bool, error) {
 		Env:           ruleEnv.Env,
 		ActionOutputs: actionOutputs,
 		Actors:        actors,
+		NodeResults:   nodeResults,
 	})
 	if err != nil {
 		return nil, err
@@ -76,60 +112,170 @@ func (e *Executor) Fire(req *pb.FireRequest) ([]bool, error) {
 }
 
 func (e *Executor) Do(content map[string]interface{}, config *RuleConfig) string {
-	params := config.Action
-	if len(params.Nodes) == 0 {
-		return "no valid action nodes"
+	return joinOutputs(e.DoNodes(content, config))
+}
+
+// DoNodes runs the action nodes concurrently and returns results in the order of nodes,
+// a panic of one node is turned into its failed result
+func (e *Executor) DoNodes(content map[string]interface{}, config *RuleConfig) []*pb.ActionNodeResult {
+	nodes := config.Action.Nodes
+	results := make([]*pb.ActionNodeResult, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node *pb.ActionNode) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					logrus.Errorf("rule action node %s panic: %v\n%s", node.Type, r, debug.Stack())
+					results[i] = &pb.ActionNodeResult{
+						Type:   node.Type,
+						Output: fmt.Sprintf("action %s %v: %v", node.Type, errNodePanic, r),
+					}
+				}
+			}()
+			results[i] = e.runNode(content, config.Actor, node)
+		}(i, node)
 	}
-	// TODO: multiple nodes concurrency
-	results := make([]string, 0, len(params.Nodes))
-	for _, node := range params.Nodes {
-		switch node.Type {
-		case "api":
-			res, err := e.API.Send(&api.API{
-				Snippet: node.Snippet,
-				TLARaw:  content,
-				Actor:   config.Actor,
-			})
-			results = addToResults(res, err, results)
-		case "dingtalk":
-			d := node.DingTalk
-			target := apistructs.Target{
-				Receiver: d.Webhook,
-				Secret:   d.Signature,
-			}
-			url, err := target.GetSignURL()
-			if err != nil {
-				results = addToResults(url, err, results)
-				continue
+	wg.Wait()
+	return results
+}
+
+// runNode runs the node until it succeeds or retries are used up, each attempt is limited by the timeout
+// and a timed out attempt is not retried
+func (e *Executor) runNode(content map[string]interface{}, actor string, node *pb.ActionNode) *pb.ActionNodeResult {
+	timeout := e.NodeTimeout
+	if node.Timeout > 0 {
+		timeout = time.Duration(node.Timeout) * time.Second
+	}
+	if timeout <= 0 {
+		timeout = defaultNodeTimeout
+	}
+	interval := e.RetryInterval
+	if interval <= 0 {
+		interval = defaultRetryInterval
+	}
+	retry := node.Retry
+	if retry > maxNodeRetry {
+		retry = maxNodeRetry
+	}
+
+	start := time.Now()
+	result := &pb.ActionNodeResult{Type: node.Type}
+	for attempt := int64(0); attempt <= retry; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * interval)
+		}
+		result.Attempts++
+		output, err := e.doNodeWithTimeout(content, actor, node, timeout)
+		if err == nil {
+			result.Succeed = true
+			result.Output = output
+			break
+		}
+		result.Output = err.Error()
+		if errors.Is(err, errInvalidActionType) || errors.Is(err, errNodeTimeout) || errors.Is(err, errNodePanic) {
+			break
+		}
+	}
+	result.Duration = time.Since(start).Milliseconds()
+	return result
+}
+
+func (e *Executor) doNodeWithTimeout(content map[string]interface{}, actor string, node *pb.ActionNode, timeout time.Duration) (string, error) {
+	type nodeOutput struct {
+		output string
+		err    error
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	// buffered so that the action goroutine is not leaked after timeout
+	ch := make(chan nodeOutput, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logrus.Errorf("rule action node %s panic: %v\n%s", node.Type, r, debug.Stack())
+				ch <- nodeOutput{err: fmt.Errorf("action %s %w: %v", node.Type, errNodePanic, r)}
 			}
-			res, err := e.API.Send(&api.API{
-				URL:     url,
-				Snippet: node.Snippet,
-				TLARaw:  content,
-				Actor:   config.Actor,
-			})
-			results = addToResults(res, err, results)
-		case "pipeline":
-			res, err := e.Pipeline.CreatePipeline(content)
-			results = addToResults(res, err, results)
-		case "dingtalkworknotice":
-			res, err := e.DingTalk.Send(&dingtalkworknotice.JsonnetParam{
-				Snippet: node.Snippet,
-				TLARaw:  content,
-			})
-			results = addToResults(res, err, results)
-		default:
-			results = addToResults("", errors.New("invalid action type"), results)
+		}()
+		output, err := e.doNode(ctx, content, actor, node)
+		ch <- nodeOutput{output: output, err: err}
+	}()
+	select {
+	case o := <-ch:
+		if o.err != nil && ctx.Err() != nil {
+			return "", fmt.Errorf("action %s %w after %v", node.Type, errNodeTimeout, timeout)
 		}
+		return o.output, o.err
+	case <-ctx.Done():
+		return "", fmt.Errorf("action %s %w after %v", node.Type, errNodeTimeout, timeout)
 	}
-	return strutil.Join(results, ";")
 }
 
-func addToResults(output string, err error, results []string) []string {
-	if err != nil {
-		results = append(results, err.Error())
-	} else {
-		results = append(results, output)
+func (e *Executor) doNode(ctx context.Context, content map[string]interface{}, actor string, node *pb.ActionNode) (string, error) {
+	switch node.Type {
+	case "api":
+		return e.API.Send(ctx, &api.API{
+			Snippet: node.Snippet,
+			TLARaw:  content,
+			Actor:   actor,
+		})
+	case "dingtalk":
+		d := node.DingTalk
+		target := apistructs.Target{
+			Receiver: d.Webhook,
+			Secret:   d.Signature,
+		}
+		url, err := target.GetSignURL()
+		if err != nil {
+			return url, err
+		}
+		return e.API.Send(ctx, &api.API{
+			URL:     url,
+			Snippet: node.Snippet,
+			TLARaw:  content,
+			Actor:   actor,
+		})
+	case "pipeline":
+		return e.Pipeline.CreatePipeline(ctx, content)
+	case "dingtalkworknotice":
+		return e.DingTalk.Send(ctx, &dingtalkworknotice.JsonnetParam{
+			Snippet: node.Snippet,
+			TLARaw:  content,
+		})
+	case "webhook":
+		return e.Webhook.Send(ctx, &webhook.JsonnetParam{
+			Snippet: node.Snippet,
+			TLARaw:  content,
+		})
+	case "email":
+		return e.Email.Send(ctx, &email.JsonnetParam{
+			Snippet: node.Snippet,
+			TLARaw:  content,
+		})
+	case "issue":
+		return e.Issue.Send(ctx, &issue.JsonnetParam{
+			Snippet: node.Snippet,
+			TLARaw:  content,
+			Actor:   actor,
+		})
+	case im.TypeSlack, im.TypeFeishu, im.TypeWeCom:
+		return e.IM.Send(ctx, node.Type, &im.JsonnetParam{
+			Snippet: node.Snippet,
+			TLARaw:  content,
+		})
+	default:
+		return "", errInvalidActionType
 	}
-	return results
+}
+
+func joinOutputs(results []*pb.ActionNodeResult) string {
+	if len(results) == 0 {
+		return "no valid action nodes"
+	}
+	outputs := make([]string, 0, len(results))
+	for _, r := range results {
+		outputs = append(outputs, r.Output)
+	}
+	return strutil.Join(outputs, ";")
 }
@@ -15,18 +15,23 @@
 package executor
 
 import (
+	"context"
+	"errors"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/erda-project/erda-proto-go/dop/rule/pb"
 
 	"github.com/erda-project/erda/internal/apps/dop/providers/rule/actions/api"
+	"github.com/erda-project/erda/internal/apps/dop/providers/rule/actions/webhook"
 	"github.com/erda-project/erda/internal/apps/dop/providers/rule/db"
 )
 
 type MockAPI struct {
 }
 
-func (a *MockAPI) Send(api *api.API) (string, error) {
+func (a *MockAPI) Send(ctx context.Context, api *api.API) (string, error) {
 	return "ok", nil
 }
 
@@ -79,3 +84,120 @@ func TestExecutor_Do(t *testing.T) {
 		})
 	}
 }
+
+type flakyWebhook struct {
+	calls   int32
+	failing int32
+}
+
+func (w *flakyWebhook) Send(ctx context.Context, param *webhook.JsonnetParam) (string, error) {
+	if atomic.AddInt32(&w.calls, 1) <= w.failing {
+		return "", errors.New("unavailable")
+	}
+	return "delivered", nil
+}
+
+type slowAPI struct {
+	delay     time.Duration
+	cancelled int32
+}
+
+func (a *slowAPI) Send(ctx context.Context, api *api.API) (string, error) {
+	select {
+	case <-time.After(a.delay):
+		return "ok", nil
+	case <-ctx.Done():
+		atomic.AddInt32(&a.cancelled, 1)
+		return "", ctx.Err()
+	}
+}
+
+func TestExecutor_DoNodes(t *testing.T) {
+	e := &Executor{
+		API:           &slowAPI{delay: 50 * time.Millisecond},
+		Webhook:       &flakyWebhook{failing: 2},
+		NodeTimeout:   time.Second,
+		RetryInterval: time.Millisecond,
+	}
+	config := &RuleConfig{
+		Action: db.ActionParams{
+			Nodes: []*pb.ActionNode{
+				{Type: "api"},
+				{Type: "api"},
+				{Type: "webhook", Retry: 2},
+				{Type: "unknown", Retry: 3},
+			},
+		},
+	}
+
+	start := time.Now()
+	results := e.DoNodes(map[string]interface{}{}, config)
+	if elapsed := time.Since(start); elapsed > 90*time.Millisecond {
+		t.Errorf("nodes should run concurrently, elapsed %v", elapsed)
+	}
+	if len(results) != 4 {
+		t.Fatalf("DoNodes() got %d results, want 4", len(results))
+	}
+	for i := 0; i < 2; i++ {
+		if !results[i].Succeed || results[i].Output != "ok" || results[i].Attempts != 1 {
+			t.Errorf("api node result = %v", results[i])
+		}
+	}
+	if !results[2].Succeed || results[2].Output != "delivered" || results[2].Attempts != 3 {
+		t.Errorf("webhook node result = %v", results[2])
+	}
+	if results[3].Succeed || results[3].Attempts != 1 || results[3].Output != errInvalidActionType.Error() {
+		t.Errorf("invalid node result = %v", results[3])
+	}
+}
+
+func TestExecutor_DoNodesTimeout(t *testing.T) {
+	slow := &slowAPI{delay: 200 * time.Millisecond}
+	e := &Executor{
+		API:           slow,
+		NodeTimeout:   20 * time.Millisecond,
+		RetryInterval: time.Millisecond,
+	}
+	results := e.DoNodes(map[string]interface{}{}, &RuleConfig{
+		Action: db.ActionParams{
+			Nodes: []*pb.ActionNode{{Type: "api", Retry: 1}},
+		},
+	})
+	// a timed out node is not retried
+	if results[0].Succeed || results[0].Attempts != 1 {
+		t.Errorf("timeout node result = %v", results[0])
+	}
+	if results[0].Output != "action api timeout after 20ms" {
+		t.Errorf("timeout node output = %s", results[0].Output)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if atomic.LoadInt32(&slow.cancelled) != 1 {
+		t.Errorf("the action should be cancelled after timeout")
+	}
+}
+
+type panicWebhook struct{}
+
+func (w *panicWebhook) Send(ctx context.Context, param *webhook.JsonnetParam) (string, error) {
+	panic("boom")
+}
+
+func TestExecutor_DoNodesPanic(t *testing.T) {
+	e := &Executor{
+		API:           &MockAPI{},
+		Webhook:       &panicWebhook{},
+		RetryInterval: time.Millisecond,
+	}
+	results := e.DoNodes(map[string]interface{}{}, &RuleConfig{
+		Action: db.ActionParams{
+			Nodes: []*pb.ActionNode{{Type: "webhook", Retry: 2}, {Type: "api"}},
+		},
+	})
+	// a panic node is failed and not retried, other nodes are not affected
+	if results[0].Succeed || results[0].Attempts != 1 || results[0].Output != "action webhook panic: boom" {
+		t.Errorf("panic node result = %v", results[0])
+	}
+	if !results[1].Succeed || results[1].Output != "ok" {
+		t.Errorf("api node result = %v", results[1])
+	}
+}
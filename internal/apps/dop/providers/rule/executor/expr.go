@@ -16,6 +16,8 @@ package executor
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
 
 	"github.com/antonmedv/expr"
 
@@ -23,14 +25,22 @@ import (
 	"github.com/erda-project/erda/internal/apps/dop/providers/rule/db"
 )
 
+const ruleScopeProject = "project"
+
 type RuleExecutor interface {
 	Exec(r *RuleConfig, env map[string]interface{}) (bool, error)
 	BuildRuleEnv(req *rulepb.FireRequest) (*RuleEnv, error)
 	AddExecutionRecords(c *RecordConfig) error
 }
 
+// IssueMatcher reports whether an issue of the project matches the given issue query language
+type IssueMatcher interface {
+	MatchIssue(projectID, issueID uint64, ql string) (bool, error)
+}
+
 type ExprExecutor struct {
-	DB *db.DBClient
+	DB    *db.DBClient
+	Issue IssueMatcher
 }
 
 type RuleEnv struct {
@@ -43,9 +53,38 @@ type RuleConfig struct {
 	Code   string
 	Action db.ActionParams
 	Actor  string
+	// ProjectID is the project of a project scoped rule, matchIssue only matches issues of the project
+	ProjectID uint64
 }
 
 func (e *ExprExecutor) Exec(r *RuleConfig, env map[string]interface{}) (bool, error) {
+	var matchErr error
+	if e.Issue != nil {
+		// copy env so that the function is not persisted into execution records
+		withFuncs := make(map[string]interface{}, len(env)+1)
+		for k, v := range env {
+			withFuncs[k] = v
+		}
+		withFuncs["matchIssue"] = func(id interface{}, ql string) bool {
+			if r.ProjectID == 0 {
+				matchErr = errors.New("matchIssue is only available in project scoped rules")
+				return false
+			}
+			issueID, err := toIssueID(id)
+			if err != nil {
+				matchErr = err
+				return false
+			}
+			matched, err := e.Issue.MatchIssue(r.ProjectID, issueID, ql)
+			if err != nil {
+				matchErr = err
+				return false
+			}
+			return matched
+		}
+		env = withFuncs
+	}
+
 	program, err := expr.Compile(r.Code, expr.Env(env))
 	if err != nil {
 		return false, err
@@ -55,6 +94,9 @@ func (e *ExprExecutor) Exec(r *RuleConfig, env map[string]interface{}) (bool, er
 	if err != nil {
 		return false, err
 	}
+	if matchErr != nil {
+		return false, matchErr
+	}
 	res, ok := output.(bool)
 	if !ok {
 		return false, errors.New("invalid expr")
@@ -62,6 +104,23 @@ func (e *ExprExecutor) Exec(r *RuleConfig, env map[string]interface{}) (bool, er
 	return res, nil
 }
 
+func toIssueID(v interface{}) (uint64, error) {
+	switch id := v.(type) {
+	case float64:
+		return uint64(id), nil
+	case int:
+		return uint64(id), nil
+	case int64:
+		return uint64(id), nil
+	case uint64:
+		return id, nil
+	case string:
+		return strconv.ParseUint(id, 10, 64)
+	default:
+		return 0, fmt.Errorf("invalid issue id: %v", v)
+	}
+}
+
 func (e *ExprExecutor) BuildRuleEnv(req *rulepb.FireRequest) (*RuleEnv, error) {
 	if req.Scope == "" || req.ScopeID == "" || req.EventType == "" {
 		return nil, errors.New("invalid request, missing scope info")
@@ -80,13 +139,20 @@ func (e *ExprExecutor) BuildRuleEnv(req *rulepb.FireRequest) (*RuleEnv, error) {
 		return nil, err
 	}
 
+	var projectID uint64
+	if req.Scope == ruleScopeProject {
+		if projectID, err = strconv.ParseUint(req.ScopeID, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid project scopeID: %s", req.ScopeID)
+		}
+	}
 	ruleConfigs := make([]*RuleConfig, 0, len(rules))
 	for _, r := range rules {
 		ruleConfigs = append(ruleConfigs, &RuleConfig{
-			RuleID: r.ID,
-			Code:   r.Code,
-			Action: r.Params,
-			Actor:  r.Actor,
+			RuleID:    r.ID,
+			Code:      r.Code,
+			Action:    r.Params,
+			Actor:     r.Actor,
+			ProjectID: projectID,
 		})
 	}
 
@@ -109,6 +175,7 @@ type RecordConfig struct {
 	RuleConfigs   []*RuleConfig
 	ActionOutputs []string
 	Actors        []string
+	NodeResults   [][]*rulepb.ActionNodeResult
 }
 
 func (e *ExprExecutor) AddExecutionRecords(r *RecordConfig) error {
@@ -123,7 +190,15 @@ func (e *ExprExecutor) AddExecutionRecords(r *RecordConfig) error {
 			Succeed:      &r.Results[i],
 			ActionOutput: r.ActionOutputs[i],
 			Actor:        r.Actors[i],
+			NodeResults:  nodeResultsAt(r.NodeResults, i),
 		})
 	}
 	return e.DB.BatchCreateRuleExecRecords(records)
 }
+
+func nodeResultsAt(results [][]*rulepb.ActionNodeResult, i int) db.NodeResults {
+	if i >= len(results) {
+		return nil
+	}
+	return results[i]
+}
@@ -15,6 +15,7 @@
 package executor
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 
@@ -65,6 +66,55 @@ func TestExprExecutor_Exec(t *testing.T) {
 	}
 }
 
+// issueMatcher issues by project
+type issueMatcher map[uint64]map[uint64]bool
+
+func (m issueMatcher) MatchIssue(projectID, issueID uint64, ql string) (bool, error) {
+	if ql == "" {
+		return false, errors.New("empty ql")
+	}
+	return m[projectID][issueID], nil
+}
+
+func TestExprExecutor_ExecMatchIssue(t *testing.T) {
+	e := &ExprExecutor{Issue: issueMatcher{10: {1: true}}}
+	env := map[string]interface{}{
+		"issue": map[string]interface{}{
+			"id": float64(1),
+		},
+	}
+	got, err := e.Exec(&RuleConfig{Code: `matchIssue(issue.id, "priority >= HIGH")`, ProjectID: 10}, env)
+	if err != nil || !got {
+		t.Errorf("ExprExecutor.Exec() = %v, %v, want true", got, err)
+	}
+	if _, ok := env["matchIssue"]; ok {
+		t.Errorf("env should not be modified")
+	}
+
+	// issue of another project
+	got, err = e.Exec(&RuleConfig{Code: `matchIssue(issue.id, "priority >= HIGH")`, ProjectID: 11}, env)
+	if err != nil || got {
+		t.Errorf("ExprExecutor.Exec() = %v, %v, want false", got, err)
+	}
+
+	env["issue"] = map[string]interface{}{"id": "2"}
+	got, err = e.Exec(&RuleConfig{Code: `matchIssue(issue.id, "priority >= HIGH")`, ProjectID: 10}, env)
+	if err != nil || got {
+		t.Errorf("ExprExecutor.Exec() = %v, %v, want false", got, err)
+	}
+
+	_, err = e.Exec(&RuleConfig{Code: `matchIssue(issue.id, "")`, ProjectID: 10}, env)
+	if err == nil {
+		t.Errorf("ExprExecutor.Exec() should return match error")
+	}
+
+	// not a project scoped rule
+	_, err = e.Exec(&RuleConfig{Code: `matchIssue(issue.id, "priority >= HIGH")`}, env)
+	if err == nil {
+		t.Errorf("ExprExecutor.Exec() should return error without project")
+	}
+}
+
 func TestExprExecutor_BuildRuleEnv(t *testing.T) {
 	type args struct {
 		req *pb.FireRequest
@@ -103,8 +153,9 @@ func TestExprExecutor_BuildRuleEnv(t *testing.T) {
 			want: &RuleEnv{
 				Configs: []*RuleConfig{
 					{
-						RuleID: "1",
-						Code:   "len(issue.content) > 2",
+						RuleID:    "1",
+						Code:      "len(issue.content) > 2",
+						ProjectID: 1,
 					},
 				},
 				Env: map[string]interface{}{
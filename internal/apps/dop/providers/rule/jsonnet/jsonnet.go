@@ -15,11 +15,15 @@
 package jsonnet
 
 import (
+	"sync"
+
 	"github.com/google/go-jsonnet"
 )
 
+// Engine evaluates jsonnet snippets, the VM is shared so evaluations are serialized
 type Engine struct {
 	JsonnetVM *jsonnet.VM
+	mu        sync.Mutex
 }
 
 type TLACodeConfig struct {
@@ -28,6 +32,8 @@ type TLACodeConfig struct {
 }
 
 func (t *Engine) EvaluateBySnippet(snippet string, configs []TLACodeConfig) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.SetTLACodes(configs)
 	jsonStr, err := t.JsonnetVM.EvaluateAnonymousSnippet("template.jsonnet", snippet)
 	if err != nil {
@@ -22,9 +22,14 @@ import (
 	"github.com/erda-project/erda-infra/pkg/transport"
 	"github.com/erda-project/erda-proto-go/dop/rule/pb"
 	"github.com/erda-project/erda/internal/apps/dop/dao"
+	"github.com/erda-project/erda/internal/apps/dop/providers/issue/core/query"
 	"github.com/erda-project/erda/internal/apps/dop/providers/rule/actions/api"
 	"github.com/erda-project/erda/internal/apps/dop/providers/rule/actions/dingtalkworknotice"
+	"github.com/erda-project/erda/internal/apps/dop/providers/rule/actions/email"
+	"github.com/erda-project/erda/internal/apps/dop/providers/rule/actions/im"
+	"github.com/erda-project/erda/internal/apps/dop/providers/rule/actions/issue"
 	"github.com/erda-project/erda/internal/apps/dop/providers/rule/actions/pipeline"
+	"github.com/erda-project/erda/internal/apps/dop/providers/rule/actions/webhook"
 	"github.com/erda-project/erda/internal/apps/dop/providers/rule/db"
 	"github.com/erda-project/erda/internal/apps/dop/providers/rule/executor"
 	"github.com/erda-project/erda/pkg/common/apis"
@@ -46,6 +51,11 @@ type provider struct {
 	API         api.Interface
 	Pipeline    pipeline.Interface
 	DingTalk    dingtalkworknotice.Interface
+	Webhook     webhook.Interface
+	Email       email.Interface
+	Issue       issue.Interface
+	IM          im.Interface
+	IssueQuery  query.Interface `autowired:"erda.dop.issue.core.query" optional:"true"`
 }
 
 func (p *provider) Init(ctx servicehub.Context) error {
@@ -58,11 +68,16 @@ func (p *provider) Init(ctx servicehub.Context) error {
 	}
 	p.ruleExecutor = executor.Executor{
 		RuleExecutor: &executor.ExprExecutor{
-			DB: p.db,
+			DB:    p.db,
+			Issue: p.IssueQuery,
 		},
 		API:      p.API,
 		Pipeline: p.Pipeline,
 		DingTalk: p.DingTalk,
+		Webhook:  p.Webhook,
+		Email:    p.Email,
+		Issue:    p.Issue,
+		IM:       p.IM,
 	}
 	p.ruleService = &ruleService{p}
 	if p.Register != nil {
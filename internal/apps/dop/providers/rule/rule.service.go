@@ -205,5 +205,6 @@ func ToPbRuleExecHistory(r db.RuleExecRecord) *pb.RuleExecHistory {
 		Succeed:      *r.Succeed,
 		ActionOutput: r.ActionOutput,
 		Actor:        r.Actor,
+		NodeResults:  r.NodeResults,
 	}
 }
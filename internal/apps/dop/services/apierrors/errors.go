@@ -507,6 +507,33 @@ var (
 	ErrListTestReportRecord   = err("ErrListTestReportRecord", "查询测试报告记录失败")
 	ErrGetTestReportRecord    = err("ErrGetTestReportRecord", "获取测试报告记录失败")
 
+	ErrListFlakyTests      = err("ErrListFlakyTests", "查询不稳定用例失败")
+	ErrQuarantineFlakyTest = err("ErrQuarantineFlakyTest", "隔离不稳定用例失败")
+	ErrGetFlakyTestTrend   = err("ErrGetFlakyTestTrend", "查询不稳定用例趋势失败")
+	ErrGetTestReportGate   = err("ErrGetTestReportGate", "获取测试报告门禁结果失败")
+
+	ErrCreateIssueSyncConnection = err("ErrCreateIssueSyncConnection", "创建事件同步连接失败")
+	ErrUpdateIssueSyncConnection = err("ErrUpdateIssueSyncConnection", "更新事件同步连接失败")
+	ErrDeleteIssueSyncConnection = err("ErrDeleteIssueSyncConnection", "删除事件同步连接失败")
+	ErrGetIssueSyncConnection    = err("ErrGetIssueSyncConnection", "获取事件同步连接失败")
+	ErrListIssueSyncConnections  = err("ErrListIssueSyncConnections", "查询事件同步连接失败")
+	ErrSyncIssues                = err("ErrSyncIssues", "同步事件失败")
+	ErrPushSyncIssue             = err("ErrPushSyncIssue", "推送事件到外部系统失败")
+	ErrHandleIssueSyncWebhook    = err("ErrHandleIssueSyncWebhook", "处理事件同步 webhook 失败")
+
+	ErrQueryIssues           = err("ErrQueryIssues", "按查询语句查询事件失败")
+	ErrQueryIssueStats       = err("ErrQueryIssueStats", "按查询语句统计事件失败")
+	ErrCreateIssueSavedQuery = err("ErrCreateIssueSavedQuery", "保存事件查询失败")
+	ErrUpdateIssueSavedQuery = err("ErrUpdateIssueSavedQuery", "更新事件查询失败")
+	ErrDeleteIssueSavedQuery = err("ErrDeleteIssueSavedQuery", "删除事件查询失败")
+	ErrGetIssueSavedQuery    = err("ErrGetIssueSavedQuery", "获取事件查询失败")
+	ErrListIssueSavedQueries = err("ErrListIssueSavedQueries", "查询保存的事件查询失败")
+
+	ErrCreateIssueStateTransitionRule = err("ErrCreateIssueStateTransitionRule", "创建状态流转规则失败")
+	ErrUpdateIssueStateTransitionRule = err("ErrUpdateIssueStateTransitionRule", "更新状态流转规则失败")
+	ErrDeleteIssueStateTransitionRule = err("ErrDeleteIssueStateTransitionRule", "删除状态流转规则失败")
+	ErrListIssueStateTransitionRules  = err("ErrListIssueStateTransitionRules", "查询状态流转规则失败")
+
 	ErrApplicationsResources = err("ErrApplicationsResources", "查询应用资源列表失败")
 
 	ErrListErrorLog = err("ErrListErrorLog", "查看错误日志失败")
@@ -389,6 +389,11 @@ func (svc *Service) Import(req apistructs.AutoTestSpaceImportRequest, r *http.Re
 	if err != nil {
 		return 0, err
 	}
+	if req.FileType == apistructs.TestSpaceFileTypePostman {
+		if req.EnvironmentFileUUID, err = svc.uploadPostmanEnvironment(r, "autotest-space"); err != nil {
+			return 0, err
+		}
+	}
 
 	fileReq := apistructs.TestFileRecordRequest{
 		FileName:     fileHeader.Filename,
@@ -479,6 +484,25 @@ func (svc *Service) ImportFile(record *dao.TestFileRecord) {
 			}
 			return
 		}
+	case apistructs.TestSpaceFileTypePostman, apistructs.TestSpaceFileTypeHAR, apistructs.TestSpaceFileTypeOpenAPI:
+		report, err := svc.importAPIDoc(string(req.FileType), f, req.EnvironmentFileUUID, &AutoTestSpaceData{
+			ProjectID:    req.ProjectID,
+			IdentityInfo: req.IdentityInfo,
+			svc:          svc,
+		})
+		if err != nil {
+			logrus.Error(apierrors.ErrImportAutoTestSpace.InternalError(err))
+			if err := svc.UpdateFileRecord(apistructs.TestFileRecordRequest{ID: id, State: apistructs.FileRecordStateFail, ErrorInfo: err}); err != nil {
+				logrus.Error(apierrors.ErrImportAutoTestSpace.InternalError(err))
+			}
+			return
+		}
+		extra.ImportReport = report
+		if err := svc.UpdateFileRecord(apistructs.TestFileRecordRequest{ID: id, State: apistructs.FileRecordStateSuccess,
+			Extra: apistructs.TestFileExtra{AutotestSpaceFileExtraInfo: extra}}); err != nil {
+			logrus.Error(apierrors.ErrImportAutoTestSpace.InternalError(err))
+		}
+		return
 	default:
 	}
 	if err := svc.UpdateFileRecord(apistructs.TestFileRecordRequest{ID: id, State: apistructs.FileRecordStateSuccess}); err != nil {
@@ -516,6 +540,11 @@ func (svc *Service) ImportSceneSet(req apistructs.AutoTestSceneSetImportRequest,
 	if err != nil {
 		return 0, err
 	}
+	if req.FileType == apistructs.TestSceneSetFileTypePostman {
+		if req.EnvironmentFileUUID, err = svc.uploadPostmanEnvironment(r, "autotest-scene-set"); err != nil {
+			return 0, err
+		}
+	}
 
 	fileReq := apistructs.TestFileRecordRequest{
 		FileName:     fileHeader.Filename,
@@ -618,6 +647,28 @@ func (svc *Service) ImportSceneSetFile(record *dao.TestFileRecord) {
 			}
 			return
 		}
+	case apistructs.TestSceneSetFileTypePostman, apistructs.TestSceneSetFileTypeHAR, apistructs.TestSceneSetFileTypeOpenAPI:
+		report, err := svc.importAPIDoc(string(req.FileType), f, req.EnvironmentFileUUID, &AutoTestSpaceData{
+			ProjectID:    req.ProjectID,
+			SpaceID:      req.SpaceID,
+			IdentityInfo: req.IdentityInfo,
+			svc:          svc,
+			Space:        space,
+			NewSpace:     space,
+		})
+		if err != nil {
+			logrus.Error(apierrors.ErrImportAutotestSceneSet.InternalError(err))
+			if err := svc.UpdateFileRecord(apistructs.TestFileRecordRequest{ID: record.ID, State: apistructs.FileRecordStateFail, Description: fmt.Sprintf("%s, import %s err: %v", record.Description, req.FileType, err)}); err != nil {
+				logrus.Error(apierrors.ErrImportAutotestSceneSet.InternalError(err))
+			}
+			return
+		}
+		extra.ImportReport = report
+		if err := svc.UpdateFileRecord(apistructs.TestFileRecordRequest{ID: record.ID, State: apistructs.FileRecordStateSuccess,
+			Extra: apistructs.TestFileExtra{AutotestSceneSetFileExtraInfo: extra}}); err != nil {
+			logrus.Error(apierrors.ErrImportAutotestSceneSet.InternalError(err))
+		}
+		return
 	default:
 
 	}
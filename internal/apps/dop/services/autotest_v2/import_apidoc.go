@@ -0,0 +1,428 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotestv2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/services/apierrors"
+	"github.com/erda-project/erda/internal/core/file/filetypes"
+	"github.com/erda-project/erda/pkg/expression"
+)
+
+// apiDocSpace is the protocol-neutral result of parsing a third party api document,
+// such as postman collection, har capture or openapi spec
+type apiDocSpace struct {
+	Name        string
+	Description string
+	SceneSets   []apiDocSceneSet
+	Report      apistructs.AutoTestImportReport
+}
+
+type apiDocSceneSet struct {
+	Name        string
+	Description string
+	Scenes      []apiDocScene
+}
+
+type apiDocScene struct {
+	Name        string
+	Description string
+	Inputs      []apistructs.AutoTestSceneInput
+	Steps       []apistructs.APIInfoV2
+	// vars the variables referenced by steps, converted to inputs at last
+	vars map[string]struct{}
+}
+
+// useVar mark the variable is referenced and return the scene params expression
+func (s *apiDocScene) useVar(name string) string {
+	if s.vars == nil {
+		s.vars = map[string]struct{}{}
+	}
+	s.vars[name] = struct{}{}
+	return expression.GenParamsRef(name)
+}
+
+// unsupported record the construct which can not be converted
+func (d *apiDocSpace) unsupported(location, construct, message string) {
+	d.Report.Unsupported = append(d.Report.Unsupported, apistructs.AutoTestImportUnsupported{
+		Location:  location,
+		Construct: construct,
+		Message:   message,
+	})
+}
+
+// parseAPIDoc parse third party api documents by file type
+func parseAPIDoc(fileType string, f io.Reader, env io.Reader) (*apiDocSpace, error) {
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	switch fileType {
+	case string(apistructs.TestSpaceFileTypePostman):
+		var envData []byte
+		if env != nil {
+			if envData, err = ioutil.ReadAll(env); err != nil {
+				return nil, err
+			}
+		}
+		return parsePostmanCollection(data, envData)
+	case string(apistructs.TestSpaceFileTypeHAR):
+		return parseHAR(data)
+	case string(apistructs.TestSpaceFileTypeOpenAPI):
+		return parseOpenAPI(data)
+	default:
+		return nil, fmt.Errorf("unsupported file type: %s", fileType)
+	}
+}
+
+// importAPIDoc parse api document and copy the data to db,
+// import as scene sets if data.Space is set, otherwise create a new space
+func (svc *Service) importAPIDoc(fileType string, f io.Reader, envFileUUID string, data *AutoTestSpaceData) (*apistructs.AutoTestImportReport, error) {
+	var env io.Reader
+	if envFileUUID != "" {
+		envFile, err := svc.bdl.DownloadDiceFile(envFileUUID)
+		if err != nil {
+			return nil, err
+		}
+		defer envFile.Close()
+		env = envFile
+	}
+	doc, err := parseAPIDoc(fileType, f, env)
+	if err != nil {
+		return nil, err
+	}
+
+	apiDocData := AutoTestSpaceAPIDoc{doc: doc, Data: data}
+	creator := AutoTestSpaceDirector{}
+	creator.New(&apiDocData)
+	if data.Space != nil {
+		if err := creator.ConstructSceneSet(); err != nil {
+			return nil, err
+		}
+		if err := creator.Creator.GetSpaceData().CopyFromSceneSets(); err != nil {
+			return nil, err
+		}
+		return apiDocData.GetReport(), nil
+	}
+	if err := creator.Construct(); err != nil {
+		return nil, err
+	}
+	if _, err := creator.Creator.GetSpaceData().Copy(); err != nil {
+		return nil, err
+	}
+	return apiDocData.GetReport(), nil
+}
+
+// uploadPostmanEnvironment upload the optional postman environment file and return its uuid
+func (svc *Service) uploadPostmanEnvironment(r *http.Request, from string) (string, error) {
+	f, fileHeader, err := r.FormFile("environment")
+	if err == http.ErrMissingFile {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	file, err := svc.bdl.UploadFile(filetypes.FileUploadRequest{
+		FileNameWithExt: fileHeader.Filename,
+		FileReader:      f,
+		From:            from,
+		IsPublic:        true,
+	})
+	if err != nil {
+		return "", err
+	}
+	return file.UUID, nil
+}
+
+// ImportAPIAsset import the spec of api asset version, the spec is uploaded as
+// an openapi file and imported async like other files
+func (svc *Service) ImportAPIAsset(req apistructs.AutoTestAPIAssetImportRequest, spec []byte) (uint64, error) {
+	if req.ProjectID == 0 {
+		return 0, apierrors.ErrImportAutoTestSpace.MissingParameter("projectID")
+	}
+	if req.AssetID == "" {
+		return 0, apierrors.ErrImportAutoTestSpace.MissingParameter("assetID")
+	}
+	if len(spec) == 0 {
+		return 0, apierrors.ErrImportAutoTestSpace.InvalidParameter("empty api asset spec")
+	}
+	// import into the scene sets of an existing space, the space must belong to the project
+	// which the permission is checked on
+	var space *apistructs.AutoTestSpace
+	if req.SpaceID != 0 {
+		var err error
+		space, err = svc.bdl.GetTestSpace(req.SpaceID)
+		if err != nil {
+			return 0, apierrors.ErrImportAutotestSceneSet.InvalidParameter(fmt.Errorf("autotest space not found, id: %d", req.SpaceID))
+		}
+		if uint64(space.ProjectID) != req.ProjectID {
+			return 0, apierrors.ErrImportAutotestSceneSet.InvalidParameter(fmt.Errorf("autotest space %d does not belong to project %d", req.SpaceID, req.ProjectID))
+		}
+	}
+
+	fileName := fmt.Sprintf("%s-%d.json", req.AssetID, req.VersionID)
+	file, err := svc.bdl.UploadFile(filetypes.FileUploadRequest{
+		FileNameWithExt: fileName,
+		FileReader:      ioutil.NopCloser(bytes.NewReader(spec)),
+		From:            "autotest-space",
+		IsPublic:        true,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	fileReq := apistructs.TestFileRecordRequest{
+		FileName:     fileName,
+		ProjectID:    req.ProjectID,
+		ApiFileUUID:  file.UUID,
+		State:        apistructs.FileRecordStatePending,
+		IdentityInfo: req.IdentityInfo,
+	}
+	if space == nil {
+		fileReq.Description = fmt.Sprintf("ProjectID: %d, AssetID: %s", req.ProjectID, req.AssetID)
+		fileReq.Type = apistructs.FileSpaceActionTypeImport
+		fileReq.Extra.AutotestSpaceFileExtraInfo = &apistructs.AutoTestSpaceFileExtraInfo{
+			ImportRequest: &apistructs.AutoTestSpaceImportRequest{
+				ProjectID:    req.ProjectID,
+				FileType:     apistructs.TestSpaceFileTypeOpenAPI,
+				IdentityInfo: req.IdentityInfo,
+			},
+		}
+	} else {
+		fileReq.Description = fmt.Sprintf("SpaceName: %s, AssetID: %s", space.Name, req.AssetID)
+		fileReq.Type = apistructs.FileSceneSetActionTypeImport
+		fileReq.SpaceID = space.ID
+		fileReq.Extra.AutotestSceneSetFileExtraInfo = &apistructs.AutoTestSceneSetFileExtraInfo{
+			ImportRequest: &apistructs.AutoTestSceneSetImportRequest{
+				ProjectID:    uint64(space.ProjectID),
+				SpaceID:      space.ID,
+				FileType:     apistructs.TestSceneSetFileTypeOpenAPI,
+				IdentityInfo: req.IdentityInfo,
+			},
+		}
+	}
+	return svc.CreateFileRecord(fileReq)
+}
+
+// AutoTestSpaceAPIDoc convert third party api documents to space data
+// will achieve AutoTestSpaceDataCreator implement
+type AutoTestSpaceAPIDoc struct {
+	doc    *apiDocSpace
+	nextID uint64
+	Data   *AutoTestSpaceData
+}
+
+// genID generate temporary id, the real id is generated when copy data to db
+func (a *AutoTestSpaceAPIDoc) genID() uint64 {
+	a.nextID++
+	return a.nextID
+}
+
+// SetSpace use the document name as space name, keep the target space when import scene sets
+func (a *AutoTestSpaceAPIDoc) SetSpace() error {
+	if a.Data.Space != nil {
+		return nil
+	}
+	name := a.doc.Name
+	if a.Data.svc != nil {
+		var err error
+		if name, err = a.Data.svc.GenerateSpaceName(name, int64(a.Data.ProjectID)); err != nil {
+			return err
+		}
+	}
+	a.Data.Space = &apistructs.AutoTestSpace{
+		Name:        name,
+		ProjectID:   int64(a.Data.ProjectID),
+		Description: a.doc.Description,
+	}
+	return nil
+}
+
+func (a *AutoTestSpaceAPIDoc) SetSceneSets() error {
+	a.Data.SceneSets = map[uint64][]apistructs.SceneSet{}
+	a.Data.Scenes = map[uint64][]apistructs.AutoTestScene{}
+	a.Data.Steps = map[uint64][]apistructs.AutoTestSceneStep{}
+	a.nextID = 0
+
+	var preID uint64
+	for _, set := range a.doc.SceneSets {
+		sceneSet := apistructs.SceneSet{
+			ID:          a.genID(),
+			Name:        set.Name,
+			SpaceID:     a.Data.Space.ID,
+			PreID:       preID,
+			Description: set.Description,
+		}
+		preID = sceneSet.ID
+		a.Data.SceneSets[a.Data.Space.ID] = append(a.Data.SceneSets[a.Data.Space.ID], sceneSet)
+	}
+	return nil
+}
+
+func (a *AutoTestSpaceAPIDoc) SetSingleSceneSet(setID uint64) error {
+	return nil
+}
+
+// SetScenes make scenes with inputs, steps are set by SetSceneSteps
+func (a *AutoTestSpaceAPIDoc) SetScenes() error {
+	a.Data.Scenes = map[uint64][]apistructs.AutoTestScene{}
+	for i, sceneSet := range a.Data.SceneSets[a.Data.Space.ID] {
+		var preID uint64
+		for _, each := range a.doc.SceneSets[i].Scenes {
+			scene := apistructs.AutoTestScene{
+				Name:        each.Name,
+				Description: each.Description,
+				PreID:       preID,
+				SetID:       sceneSet.ID,
+			}
+			scene.ID = a.genID()
+			scene.SpaceID = a.Data.Space.ID
+			for _, input := range each.Inputs {
+				input.SceneID = scene.ID
+				scene.Inputs = append(scene.Inputs, input)
+			}
+			preID = scene.ID
+			a.Data.Scenes[sceneSet.ID] = append(a.Data.Scenes[sceneSet.ID], scene)
+		}
+	}
+	return nil
+}
+
+// SetSceneSteps convert api infos to serial api steps
+func (a *AutoTestSpaceAPIDoc) SetSceneSteps() error {
+	a.Data.Steps = map[uint64][]apistructs.AutoTestSceneStep{}
+	a.doc.Report.Scenes, a.doc.Report.Steps = 0, 0
+	for i, sceneSet := range a.Data.SceneSets[a.Data.Space.ID] {
+		for j, scene := range a.Data.Scenes[sceneSet.ID] {
+			a.doc.Report.Scenes++
+			var preID uint64
+			for _, api := range a.doc.SceneSets[i].Scenes[j].Steps {
+				value, err := apiInfoToStepValue(api)
+				if err != nil {
+					return err
+				}
+				step := apistructs.AutoTestSceneStep{
+					Type:    apistructs.StepTypeAPI,
+					Value:   value,
+					Name:    api.Name,
+					PreID:   preID,
+					PreType: apistructs.PreTypeSerial,
+					SceneID: scene.ID,
+					SpaceID: a.Data.Space.ID,
+				}
+				step.ID = a.genID()
+				preID = step.ID
+				a.Data.Steps[scene.ID] = append(a.Data.Steps[scene.ID], step)
+				a.doc.Report.Steps++
+			}
+		}
+	}
+	return nil
+}
+
+// SetConfigs third party variables and environments are imported as scene inputs
+func (a *AutoTestSpaceAPIDoc) SetConfigs() error {
+	a.Data.Configs = []apistructs.AutoTestGlobalConfig{}
+	return nil
+}
+
+func (a *AutoTestSpaceAPIDoc) GetSpaceData() *AutoTestSpaceData {
+	return a.Data
+}
+
+// GetReport return the import report after space data constructed
+func (a *AutoTestSpaceAPIDoc) GetReport() *apistructs.AutoTestImportReport {
+	return &a.doc.Report
+}
+
+// apiInfoToStepValue marshal api info as the value of api step
+func apiInfoToStepValue(api apistructs.APIInfoV2) (string, error) {
+	b, err := json.Marshal(api)
+	if err != nil {
+		return "", err
+	}
+	var apiSpec map[string]interface{}
+	if err := json.Unmarshal(b, &apiSpec); err != nil {
+		return "", err
+	}
+	value, err := json.Marshal(apistructs.AutoTestRunStep{ApiSpec: apiSpec})
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// statusAssert make the basic status code assertion of step
+func statusAssert(api *apistructs.APIInfoV2, operator, value string) {
+	api.OutParams = append(api.OutParams, apistructs.APIOutParam{
+		Key:    "status",
+		Source: apistructs.APIOutParamSourceStatus,
+	})
+	api.Asserts = append(api.Asserts, apistructs.APIAssert{
+		Arg:      "status",
+		Operator: operator,
+		Value:    value,
+	})
+}
+
+// sceneInputs make sorted scene inputs from variables used by steps
+func sceneInputs(used map[string]struct{}, values map[string]string, descs map[string]string) []apistructs.AutoTestSceneInput {
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	inputs := make([]apistructs.AutoTestSceneInput, 0, len(names))
+	for _, name := range names {
+		inputs = append(inputs, apistructs.AutoTestSceneInput{
+			Name:        name,
+			Value:       values[name],
+			Description: descs[name],
+		})
+	}
+	return inputs
+}
+
+// splitURLQuery split raw url to url without query and query params
+func splitURLQuery(rawURL string) (string, []apistructs.APIParam) {
+	idx := strings.Index(rawURL, "?")
+	if idx < 0 {
+		return rawURL, nil
+	}
+	var params []apistructs.APIParam
+	for _, kv := range strings.Split(rawURL[idx+1:], "&") {
+		if kv == "" {
+			continue
+		}
+		pair := strings.SplitN(kv, "=", 2)
+		param := apistructs.APIParam{Key: pair[0]}
+		if len(pair) == 2 {
+			param.Value = pair[1]
+		}
+		params = append(params, param)
+	}
+	return rawURL[:idx], params
+}
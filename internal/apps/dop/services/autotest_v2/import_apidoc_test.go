@@ -0,0 +1,343 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotestv2
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+const testPostmanCollection = `{
+  "info": {"name": "petstore", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+  "variable": [{"key": "baseUrl", "value": "http://localhost:8080"}],
+  "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}"}]},
+  "item": [
+    {"name": "ping", "request": {"method": "GET", "url": "{{baseUrl}}/ping"}},
+    {"name": "pets", "item": [
+      {"name": "list pets", "request": {"method": "GET", "url": {"raw": "{{baseUrl}}/pets?limit=10", "query": [{"key": "limit", "value": "10"}, {"key": "offset", "value": "0", "disabled": true}]}},
+        "event": [{"listen": "test", "script": {"exec": ["pm.test('ok', function () {", "  pm.response.to.have.status(200);", "});"]}}]},
+      {"name": "crud", "item": [
+        {"name": "create pet", "request": {"method": "POST", "url": "{{baseUrl}}/pets",
+          "header": [{"key": "X-Request-Id", "value": "{{$timestamp}}"}],
+          "body": {"mode": "raw", "raw": "{\"name\": \"{{petName}}\"}", "options": {"raw": {"language": "json"}}}},
+          "response": [{"code": 201}]},
+        {"name": "nested", "item": [
+          {"name": "upload", "request": {"method": "POST", "url": "{{baseUrl}}/pets/upload", "auth": {"type": "oauth2"},
+            "body": {"mode": "formdata", "formdata": [{"key": "file", "type": "file", "src": "a.png"}, {"key": "tag", "value": "dog"}]}}}
+        ]}
+      ]}
+    ]}
+  ]
+}`
+
+const testPostmanEnvironment = `{"name": "dev", "values": [{"key": "baseUrl", "value": "http://dev", "enabled": true}, {"key": "token", "value": "abc", "enabled": true}]}`
+
+func TestParsePostmanCollection(t *testing.T) {
+	doc, err := parsePostmanCollection([]byte(testPostmanCollection), []byte(testPostmanEnvironment))
+	assert.NoError(t, err)
+	assert.Equal(t, "petstore", doc.Name)
+	assert.Equal(t, 2, len(doc.SceneSets))
+
+	// root requests
+	root := doc.SceneSets[0]
+	assert.Equal(t, "petstore", root.Name)
+	assert.Equal(t, 1, len(root.Scenes))
+	ping := root.Scenes[0].Steps[0]
+	assert.Equal(t, "${{ params.baseUrl }}/ping", ping.URL)
+	assert.Equal(t, []apistructs.APIHeader{{Key: "Authorization", Value: "Bearer ${{ params.token }}"}}, ping.Headers)
+	assert.Equal(t, "<", ping.Asserts[0].Operator)
+	assert.Equal(t, []apistructs.AutoTestSceneInput{
+		{Name: "baseUrl", Value: "http://dev", Description: "environment: dev"},
+		{Name: "token", Value: "abc", Description: "environment: dev"},
+	}, root.Scenes[0].Inputs)
+
+	// folder requests are a scene, sub folders are flattened scenes
+	pets := doc.SceneSets[1]
+	assert.Equal(t, "pets", pets.Name)
+	assert.Equal(t, 2, len(pets.Scenes))
+	list := pets.Scenes[0].Steps[0]
+	assert.Equal(t, "${{ params.baseUrl }}/pets", list.URL)
+	assert.Equal(t, []apistructs.APIParam{{Key: "limit", Value: "10"}}, list.Params)
+	assert.Equal(t, apistructs.APIAssert{Arg: "status", Operator: "=", Value: "200"}, list.Asserts[0])
+
+	crud := pets.Scenes[1]
+	assert.Equal(t, "crud", crud.Name)
+	assert.Equal(t, 2, len(crud.Steps))
+	create := crud.Steps[0]
+	assert.Equal(t, "201", create.Asserts[0].Value)
+	assert.Equal(t, apistructs.APIBodyTypeApplicationJSON, create.Body.Type)
+	assert.Equal(t, `{"name": "${{ params.petName }}"}`, create.Body.Content)
+	assert.Equal(t, "${{ random.timestamp }}", create.Headers[0].Value)
+	upload := crud.Steps[1]
+	assert.Equal(t, apistructs.APIBodyTypeApplicationXWWWFormUrlencoded, upload.Body.Type)
+	assert.Equal(t, []apistructs.APIParam{{Key: "tag", Value: "dog"}}, upload.Body.Content)
+	assert.Equal(t, 0, len(upload.Headers))
+	assert.Equal(t, []string{"baseUrl", "petName", "token"}, inputNames(crud.Inputs))
+
+	assert.Equal(t, []string{"event.test", "auth.oauth2", "body.formdata.file", "body.formdata"}, unsupportedConstructs(doc))
+}
+
+func TestParsePostmanCollectionInvalid(t *testing.T) {
+	_, err := parsePostmanCollection([]byte(`{"info": {"name": "v1", "schema": "https://schema.getpostman.com/json/collection/v1.0.0/collection.json"}}`), nil)
+	assert.Error(t, err)
+	_, err = parsePostmanCollection([]byte(`[]`), nil)
+	assert.Error(t, err)
+}
+
+const testHAR = `{
+  "log": {
+    "creator": {"name": "WebInspector"},
+    "pages": [{"id": "page_1", "title": "http://localhost/"}],
+    "entries": [
+      {"pageref": "page_1", "request": {"method": "GET", "url": "http://localhost/api/users?page=1",
+        "headers": [{"name": ":authority", "value": "localhost"}, {"name": "Host", "value": "localhost"}, {"name": "Accept", "value": "application/json"}],
+        "queryString": [{"name": "page", "value": "1"}]}, "response": {"status": 200}},
+      {"pageref": "page_1", "request": {"method": "POST", "url": "http://localhost/api/login", "headers": [],
+        "postData": {"mimeType": "application/x-www-form-urlencoded; charset=UTF-8", "text": "user=a%40b&pwd=1"}}, "response": {"status": 302}},
+      {"pageref": "page_1", "request": {"method": "GET", "url": "ws://localhost/socket", "headers": []}, "response": {"status": 101}},
+      {"request": {"method": "PUT", "url": "https://localhost/api/users/1", "headers": [],
+        "postData": {"mimeType": "application/json", "text": "{\"name\":\"a\"}"}}, "response": {"status": 0}}
+    ]
+  }
+}`
+
+func TestParseHAR(t *testing.T) {
+	doc, err := parseHAR([]byte(testHAR))
+	assert.NoError(t, err)
+	assert.Equal(t, "HAR(WebInspector)", doc.Name)
+	assert.Equal(t, 1, len(doc.SceneSets))
+	scenes := doc.SceneSets[0].Scenes
+	assert.Equal(t, 2, len(scenes))
+	assert.Equal(t, "http://localhost/", scenes[0].Name)
+	assert.Equal(t, 2, len(scenes[0].Steps))
+
+	users := scenes[0].Steps[0]
+	assert.Equal(t, "GET users", users.Name)
+	assert.Equal(t, "http://localhost/api/users", users.URL)
+	assert.Equal(t, []apistructs.APIParam{{Key: "page", Value: "1"}}, users.Params)
+	assert.Equal(t, []apistructs.APIHeader{{Key: "Accept", Value: "application/json"}}, users.Headers)
+	assert.Equal(t, "200", users.Asserts[0].Value)
+
+	login := scenes[0].Steps[1]
+	assert.Equal(t, []apistructs.APIParam{{Key: "user", Value: "a@b"}, {Key: "pwd", Value: "1"}}, login.Body.Content)
+
+	update := scenes[1].Steps[0]
+	assert.Equal(t, apistructs.APIBodyTypeApplicationJSON, update.Body.Type)
+	assert.Equal(t, 0, len(update.Asserts))
+	assert.Equal(t, []string{"entry.url", "response.status"}, unsupportedConstructs(doc))
+
+	_, err = parseHAR([]byte(`{"log": {"entries": []}}`))
+	assert.Error(t, err)
+}
+
+const testOpenAPI = `
+openapi: 3.0.0
+info:
+  title: petstore
+  version: 1.0.0
+servers:
+  - url: http://{host}/v1/
+    variables:
+      host:
+        default: localhost
+tags:
+  - name: pets
+    description: pet operations
+  - name: unused
+components:
+  securitySchemes:
+    apiKey:
+      type: apiKey
+      in: header
+      name: X-API-KEY
+    oauth:
+      type: oauth2
+      flows:
+        implicit:
+          authorizationUrl: http://localhost/oauth
+          scopes: {}
+  schemas:
+    Pet:
+      type: object
+      properties:
+        id:
+          type: integer
+          readOnly: true
+        name:
+          type: string
+          example: doggie
+        tags:
+          type: array
+          items:
+            type: string
+security:
+  - apiKey: []
+paths:
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        schema:
+          type: integer
+          example: 1
+    get:
+      tags: [pets]
+      summary: get pet
+      parameters:
+        - name: verbose
+          in: query
+          required: true
+          schema:
+            type: boolean
+        - name: session
+          in: cookie
+          schema:
+            type: string
+      responses:
+        "200":
+          description: ok
+  /pets:
+    post:
+      tags: [pets]
+      operationId: createPet
+      security:
+        - oauth: []
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+      responses:
+        "201":
+          description: created
+        "400":
+          description: bad request
+  /health:
+    get:
+      responses:
+        default:
+          description: ok
+`
+
+func TestParseOpenAPI(t *testing.T) {
+	doc, err := parseOpenAPI([]byte(testOpenAPI))
+	assert.NoError(t, err)
+	assert.Equal(t, "petstore(1.0.0)", doc.Name)
+	assert.Equal(t, []string{"pets", "default"}, []string{doc.SceneSets[0].Name, doc.SceneSets[1].Name})
+
+	pets := doc.SceneSets[0].Scenes
+	assert.Equal(t, 2, len(pets))
+	create := pets[0].Steps[0]
+	assert.Equal(t, "createPet", create.Name)
+	assert.Equal(t, "POST", create.Method)
+	assert.Equal(t, "${{ params.baseUrl }}/pets", create.URL)
+	assert.Equal(t, "201", create.Asserts[0].Value)
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(create.Body.Content.(string)), &body))
+	assert.Equal(t, map[string]interface{}{"name": "doggie", "tags": []interface{}{"string"}}, body)
+	assert.Equal(t, 0, len(create.Headers))
+
+	get := pets[1].Steps[0]
+	assert.Equal(t, "get pet", get.Name)
+	assert.Equal(t, "${{ params.baseUrl }}/pets/${{ params.petId }}", get.URL)
+	assert.Equal(t, []apistructs.APIParam{{Key: "verbose", Value: "false"}}, get.Params)
+	assert.Equal(t, []apistructs.APIHeader{{Key: "X-API-KEY", Value: "${{ params.apiKey }}"}}, get.Headers)
+	assert.Equal(t, []apistructs.AutoTestSceneInput{
+		{Name: "apiKey"},
+		{Name: "baseUrl", Value: "http://localhost/v1", Description: "server url"},
+		{Name: "petId", Value: "1"},
+	}, pets[1].Inputs)
+
+	health := doc.SceneSets[1].Scenes[0].Steps[0]
+	assert.Equal(t, "<", health.Asserts[0].Operator)
+
+	assert.Equal(t, []string{"security.oauth2", "parameter.cookie"}, unsupportedConstructs(doc))
+}
+
+func TestAutoTestSpaceAPIDoc(t *testing.T) {
+	doc, err := parseOpenAPI([]byte(testOpenAPI))
+	assert.NoError(t, err)
+
+	creator := AutoTestSpaceAPIDoc{doc: doc, Data: &AutoTestSpaceData{ProjectID: 1}}
+	director := AutoTestSpaceDirector{}
+	director.New(&creator)
+	assert.NoError(t, director.Construct())
+
+	data := creator.GetSpaceData()
+	assert.Equal(t, "petstore(1.0.0)", data.Space.Name)
+	sets := data.SceneSets[data.Space.ID]
+	assert.Equal(t, 2, len(sets))
+	assert.Equal(t, sets[0].ID, sets[1].PreID)
+	scenes := data.Scenes[sets[0].ID]
+	assert.Equal(t, 2, len(scenes))
+	assert.Equal(t, scenes[0].ID, scenes[1].PreID)
+	assert.Equal(t, scenes[1].ID, scenes[1].Inputs[0].SceneID)
+
+	steps := data.Steps[scenes[0].ID]
+	assert.Equal(t, 1, len(steps))
+	assert.Equal(t, apistructs.StepTypeAPI, steps[0].Type)
+	var value apistructs.AutoTestRunStep
+	assert.NoError(t, json.Unmarshal([]byte(steps[0].Value), &value))
+	assert.Equal(t, "createPet", value.ApiSpec["name"])
+	assert.Equal(t, "${{ params.baseUrl }}/pets", value.ApiSpec["url"])
+
+	report := creator.GetReport()
+	assert.Equal(t, 3, report.Scenes)
+	assert.Equal(t, 3, report.Steps)
+
+	// ids are unique in the whole space
+	ids := map[uint64]struct{}{}
+	for _, set := range sets {
+		ids[set.ID] = struct{}{}
+		for _, scene := range data.Scenes[set.ID] {
+			ids[scene.ID] = struct{}{}
+			for _, step := range data.Steps[scene.ID] {
+				ids[step.ID] = struct{}{}
+			}
+		}
+	}
+	assert.Equal(t, 2+3+3, len(ids))
+}
+
+func TestParseAPIDoc(t *testing.T) {
+	doc, err := parseAPIDoc(string(apistructs.TestSpaceFileTypePostman), strings.NewReader(testPostmanCollection), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080", doc.SceneSets[0].Scenes[0].Inputs[0].Value)
+
+	_, err = parseAPIDoc(string(apistructs.TestSpaceFileTypeExcel), strings.NewReader(""), nil)
+	assert.Error(t, err)
+}
+
+func inputNames(inputs []apistructs.AutoTestSceneInput) []string {
+	var names []string
+	for _, input := range inputs {
+		names = append(names, input.Name)
+	}
+	return names
+}
+
+func unsupportedConstructs(doc *apiDocSpace) []string {
+	var constructs []string
+	for _, u := range doc.Report.Unsupported {
+		constructs = append(constructs, u.Construct)
+	}
+	return constructs
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotestv2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+// harFile is the http archive format captured by browsers, only the fields can be converted are defined
+type harFile struct {
+	Log struct {
+		Creator struct {
+			Name string `json:"name"`
+		} `json:"creator"`
+		Pages   []harPage  `json:"pages"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harPage struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+type harEntry struct {
+	PageRef      string `json:"pageref"`
+	ResourceType string `json:"_resourceType"`
+	Request      struct {
+		Method      string    `json:"method"`
+		URL         string    `json:"url"`
+		Headers     []harPair `json:"headers"`
+		QueryString []harPair `json:"queryString"`
+		PostData    *struct {
+			MimeType string    `json:"mimeType"`
+			Text     string    `json:"text"`
+			Params   []harPair `json:"params"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status int `json:"status"`
+	} `json:"response"`
+}
+
+type harPair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// harSkippedHeaders are computed by the http client or only valid for the captured connection
+var harSkippedHeaders = map[string]struct{}{
+	"host":              {},
+	"content-length":    {},
+	"connection":        {},
+	"accept-encoding":   {},
+	"keep-alive":        {},
+	"transfer-encoding": {},
+	"upgrade":           {},
+}
+
+// harDefaultName is the scene set name when the har file has no pages
+const harDefaultName = "HAR"
+
+// parseHAR convert har capture, the whole capture is a scene set,
+// each page is a scene and each http entry is a step
+func parseHAR(data []byte) (*apiDocSpace, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("invalid har file: %v", err)
+	}
+	if len(har.Log.Entries) == 0 {
+		return nil, fmt.Errorf("invalid har file: no entries")
+	}
+
+	name := harDefaultName
+	if har.Log.Creator.Name != "" {
+		name = fmt.Sprintf("%s(%s)", harDefaultName, har.Log.Creator.Name)
+	}
+	doc := &apiDocSpace{Name: name}
+
+	// keep the order of pages, entries without page are in the default scene
+	sceneIndex := map[string]int{}
+	var scenes []apiDocScene
+	for _, page := range har.Log.Pages {
+		sceneIndex[page.ID] = len(scenes)
+		title := page.Title
+		if title == "" {
+			title = page.ID
+		}
+		scenes = append(scenes, apiDocScene{Name: title})
+	}
+	for i, entry := range har.Log.Entries {
+		location := fmt.Sprintf("entries[%d] %s %s", i, entry.Request.Method, entry.Request.URL)
+		api, ok := convertHAREntry(doc, location, entry)
+		if !ok {
+			continue
+		}
+		idx, ok := sceneIndex[entry.PageRef]
+		if !ok {
+			sceneIndex[entry.PageRef] = len(scenes)
+			idx = len(scenes)
+			scenes = append(scenes, apiDocScene{Name: name})
+		}
+		scenes[idx].Steps = append(scenes[idx].Steps, api)
+	}
+
+	set := apiDocSceneSet{Name: name}
+	for _, scene := range scenes {
+		if len(scene.Steps) > 0 {
+			set.Scenes = append(set.Scenes, scene)
+		}
+	}
+	if len(set.Scenes) == 0 {
+		return nil, fmt.Errorf("invalid har file: no http entries can be imported")
+	}
+	doc.SceneSets = []apiDocSceneSet{set}
+	return doc, nil
+}
+
+func convertHAREntry(doc *apiDocSpace, location string, entry harEntry) (apistructs.APIInfoV2, bool) {
+	req := entry.Request
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		doc.unsupported(location, "entry.url", "only http and https requests are imported")
+		return apistructs.APIInfoV2{}, false
+	}
+	if entry.ResourceType == "websocket" || entry.Response.Status == 101 {
+		doc.unsupported(location, "entry.websocket", "websocket is not supported")
+		return apistructs.APIInfoV2{}, false
+	}
+
+	api := apistructs.APIInfoV2{
+		Name:   harStepName(req.Method, u),
+		Method: strings.ToUpper(req.Method),
+	}
+	api.URL, _ = splitURLQuery(req.URL)
+	if len(req.QueryString) > 0 {
+		for _, q := range req.QueryString {
+			api.Params = append(api.Params, apistructs.APIParam{Key: q.Name, Value: q.Value})
+		}
+	} else {
+		_, api.Params = splitURLQuery(req.URL)
+	}
+	for _, h := range req.Headers {
+		// http2 pseudo headers like :authority
+		if strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		if _, ok := harSkippedHeaders[strings.ToLower(h.Name)]; ok {
+			continue
+		}
+		api.Headers = append(api.Headers, apistructs.APIHeader{Key: h.Name, Value: h.Value})
+	}
+
+	api.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeNone}
+	if post := req.PostData; post != nil {
+		mimeType := strings.ToLower(strings.TrimSpace(strings.Split(post.MimeType, ";")[0]))
+		switch {
+		case mimeType == string(apistructs.APIBodyTypeApplicationJSON) || strings.HasSuffix(mimeType, "+json"):
+			api.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeApplicationJSON, Content: post.Text}
+		case mimeType == string(apistructs.APIBodyTypeApplicationXWWWFormUrlencoded):
+			var params []apistructs.APIParam
+			if len(post.Params) > 0 {
+				for _, p := range post.Params {
+					params = append(params, apistructs.APIParam{Key: p.Name, Value: p.Value})
+				}
+			} else {
+				_, pairs := splitURLQuery("?" + post.Text)
+				for _, p := range pairs {
+					key, _ := url.QueryUnescape(p.Key)
+					value, _ := url.QueryUnescape(p.Value)
+					params = append(params, apistructs.APIParam{Key: key, Value: value})
+				}
+			}
+			api.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeApplicationXWWWFormUrlencoded, Content: params}
+		case strings.HasPrefix(mimeType, "multipart/"):
+			doc.unsupported(location, "postData.multipart", "multipart body is not supported")
+		case post.Text != "":
+			api.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeTextPlain, Content: post.Text}
+		}
+	}
+
+	if entry.Response.Status > 0 {
+		statusAssert(&api, "=", strconv.Itoa(entry.Response.Status))
+	} else {
+		// status 0 means the request was blocked or failed when capturing
+		doc.unsupported(location, "response.status", "no response captured, status assertion is not generated")
+	}
+	return api, true
+}
+
+// harStepName use method and the last path segment as step name
+func harStepName(method string, u *url.URL) string {
+	path := strings.TrimRight(u.Path, "/")
+	if idx := strings.LastIndex(path, "/"); idx >= 0 {
+		path = path[idx+1:]
+	}
+	if path == "" {
+		path = u.Host
+	}
+	return fmt.Sprintf("%s %s", strings.ToUpper(method), path)
+}
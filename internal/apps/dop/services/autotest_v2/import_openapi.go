@@ -0,0 +1,422 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotestv2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/swagger"
+)
+
+const (
+	openAPIBaseURLInput   = "baseUrl"
+	openAPIDefaultTag     = "default"
+	openAPISampleMaxDepth = 5
+)
+
+var (
+	openAPIPathParamRe = regexp.MustCompile(`{([^{}]+)}`)
+	openAPIMethods     = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead, http.MethodOptions}
+)
+
+// openAPIConverter keep the context of openapi spec converting
+type openAPIConverter struct {
+	doc    *apiDocSpace
+	v3     *openapi3.Swagger
+	values map[string]string
+	descs  map[string]string
+}
+
+// parseOpenAPI convert openapi 3 spec (swagger 2 is converted to openapi 3 first),
+// each tag is a scene set and each operation is a scene with one step
+func parseOpenAPI(data []byte) (*apiDocSpace, error) {
+	v3, err := swagger.LoadFromData(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid openapi spec: %v", err)
+	}
+	c := &openAPIConverter{
+		doc:    &apiDocSpace{Name: "OpenAPI"},
+		v3:     v3,
+		values: map[string]string{},
+		descs:  map[string]string{},
+	}
+	if v3.Info != nil {
+		if v3.Info.Title != "" {
+			c.doc.Name = v3.Info.Title
+		}
+		if v3.Info.Version != "" {
+			c.doc.Name = fmt.Sprintf("%s(%s)", c.doc.Name, v3.Info.Version)
+		}
+		c.doc.Description = v3.Info.Description
+	}
+	c.values[openAPIBaseURLInput] = openAPIServerURL(v3.Servers)
+	c.descs[openAPIBaseURLInput] = "server url"
+
+	// scene sets are ordered by tags defined in spec, then by tag names
+	setIndex := map[string]int{}
+	for _, tag := range v3.Tags {
+		setIndex[tag.Name] = len(c.doc.SceneSets)
+		c.doc.SceneSets = append(c.doc.SceneSets, apiDocSceneSet{Name: tag.Name, Description: tag.Description})
+	}
+
+	paths := make([]string, 0, len(v3.Paths))
+	for path := range v3.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		item := v3.Paths[path]
+		if item == nil {
+			continue
+		}
+		operations := item.Operations()
+		for _, method := range openAPIMethods {
+			op := operations[method]
+			if op == nil {
+				continue
+			}
+			tag := openAPIDefaultTag
+			if len(op.Tags) > 0 {
+				tag = op.Tags[0]
+			}
+			idx, ok := setIndex[tag]
+			if !ok {
+				idx = len(c.doc.SceneSets)
+				setIndex[tag] = idx
+				c.doc.SceneSets = append(c.doc.SceneSets, apiDocSceneSet{Name: tag})
+			}
+			c.doc.SceneSets[idx].Scenes = append(c.doc.SceneSets[idx].Scenes, c.convertOperation(method, path, item, op))
+		}
+		for method := range operations {
+			if method == http.MethodTrace || method == http.MethodConnect {
+				c.doc.unsupported(method+" "+path, "operation."+strings.ToLower(method), "method is not supported")
+			}
+		}
+	}
+
+	// drop tags without operations
+	var sets []apiDocSceneSet
+	for _, set := range c.doc.SceneSets {
+		if len(set.Scenes) > 0 {
+			sets = append(sets, set)
+		}
+	}
+	if len(sets) == 0 {
+		return nil, fmt.Errorf("invalid openapi spec: no operations")
+	}
+	c.doc.SceneSets = sets
+	return c.doc, nil
+}
+
+// serverURL use the first server with default variables as base url
+func openAPIServerURL(servers openapi3.Servers) string {
+	if len(servers) == 0 || servers[0] == nil {
+		return ""
+	}
+	server := servers[0]
+	u := server.URL
+	for name, v := range server.Variables {
+		if v != nil {
+			u = strings.ReplaceAll(u, "{"+name+"}", fmt.Sprint(v.Default))
+		}
+	}
+	return strings.TrimRight(u, "/")
+}
+
+func (c *openAPIConverter) convertOperation(method, path string, item *openapi3.PathItem, op *openapi3.Operation) apiDocScene {
+	location := method + " " + path
+	name := op.Summary
+	if name == "" {
+		name = op.OperationID
+	}
+	if name == "" {
+		name = location
+	}
+	desc := op.Description
+	if desc == "" {
+		desc = location
+	}
+	scene := apiDocScene{Name: name, Description: desc}
+
+	api := apistructs.APIInfoV2{Name: name, Method: method}
+	baseURL := scene.useVar(openAPIBaseURLInput)
+	if (op.Servers != nil && len(*op.Servers) > 0) || len(item.Servers) > 0 {
+		c.doc.unsupported(location, "servers", "operation level servers are ignored, the first global server is used")
+	}
+	api.URL = baseURL + openAPIPathParamRe.ReplaceAllStringFunc(path, func(sub string) string {
+		return scene.useVar(sub[1 : len(sub)-1])
+	})
+
+	// operation parameters override path item parameters with the same name and location
+	params := map[string]*openapi3.Parameter{}
+	var keys []string
+	for _, parameters := range []openapi3.Parameters{item.Parameters, op.Parameters} {
+		for _, ref := range parameters {
+			if ref == nil || ref.Value == nil {
+				continue
+			}
+			key := ref.Value.In + ":" + ref.Value.Name
+			if _, ok := params[key]; !ok {
+				keys = append(keys, key)
+			}
+			params[key] = ref.Value
+		}
+	}
+	for _, key := range keys {
+		p := params[key]
+		value := openAPIExample(p.Example, p.Examples, p.Schema)
+		switch p.In {
+		case openapi3.ParameterInPath:
+			c.values[p.Name] = value
+			c.descs[p.Name] = p.Description
+		case openapi3.ParameterInQuery:
+			if !p.Required {
+				continue
+			}
+			api.Params = append(api.Params, apistructs.APIParam{Key: p.Name, Value: value, Desc: p.Description})
+		case openapi3.ParameterInHeader:
+			if !p.Required {
+				continue
+			}
+			api.Headers = append(api.Headers, apistructs.APIHeader{Key: p.Name, Value: value, Desc: p.Description})
+		default:
+			c.doc.unsupported(location, "parameter."+p.In, fmt.Sprintf("parameter %q is skipped", p.Name))
+		}
+	}
+
+	c.convertSecurity(location, op, &scene, &api)
+	c.convertRequestBody(location, op, &api)
+	if len(op.Callbacks) > 0 {
+		c.doc.unsupported(location, "callbacks", "callbacks are not supported")
+	}
+
+	// assert the first success status code defined
+	var codes []string
+	for code := range op.Responses {
+		if strings.HasPrefix(code, "2") && len(code) == 3 && !strings.Contains(strings.ToUpper(code), "X") {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	if len(codes) > 0 {
+		statusAssert(&api, "=", codes[0])
+	} else {
+		statusAssert(&api, "<", "400")
+	}
+
+	scene.Steps = []apistructs.APIInfoV2{api}
+	scene.Inputs = sceneInputs(scene.vars, c.values, c.descs)
+	return scene
+}
+
+// convertSecurity convert api key and bearer security schemes to headers with scene inputs
+func (c *openAPIConverter) convertSecurity(location string, op *openapi3.Operation, scene *apiDocScene, api *apistructs.APIInfoV2) {
+	requirements := c.v3.Security
+	if op.Security != nil {
+		requirements = *op.Security
+	}
+	if len(requirements) == 0 {
+		return
+	}
+	// any one of the requirements is enough, use the first one
+	names := make([]string, 0, len(requirements[0]))
+	for name := range requirements[0] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ref := c.v3.Components.SecuritySchemes[name]
+		if ref == nil || ref.Value == nil {
+			continue
+		}
+		scheme := ref.Value
+		switch {
+		case scheme.Type == "apiKey" && scheme.In == openapi3.ParameterInHeader:
+			api.Headers = append(api.Headers, apistructs.APIHeader{Key: scheme.Name, Value: scene.useVar(name), Desc: scheme.Description})
+		case scheme.Type == "apiKey" && scheme.In == openapi3.ParameterInQuery:
+			api.Params = append(api.Params, apistructs.APIParam{Key: scheme.Name, Value: scene.useVar(name), Desc: scheme.Description})
+		case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "bearer"):
+			api.Headers = append(api.Headers, apistructs.APIHeader{Key: "Authorization", Value: "Bearer " + scene.useVar(name), Desc: scheme.Description})
+		case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "basic"):
+			api.Headers = append(api.Headers, apistructs.APIHeader{Key: "Authorization", Value: "Basic " + scene.useVar(name), Desc: "base64 encoded username:password"})
+		default:
+			c.doc.unsupported(location, "security."+scheme.Type, fmt.Sprintf("security scheme %q is not supported", name))
+		}
+	}
+}
+
+func (c *openAPIConverter) convertRequestBody(location string, op *openapi3.Operation, api *apistructs.APIInfoV2) {
+	api.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeNone}
+	if op.RequestBody == nil || op.RequestBody.Value == nil || len(op.RequestBody.Value.Content) == 0 {
+		return
+	}
+	content := op.RequestBody.Value.Content
+	if media := content.Get(string(apistructs.APIBodyTypeApplicationJSON)); media != nil {
+		sample := openAPIMediaSample(media)
+		b, err := json.MarshalIndent(sample, "", "  ")
+		if err != nil {
+			c.doc.unsupported(location, "requestBody", fmt.Sprintf("failed to generate json body: %v", err))
+			return
+		}
+		api.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeApplicationJSON, Content: string(b)}
+		return
+	}
+	if media := content.Get(string(apistructs.APIBodyTypeApplicationXWWWFormUrlencoded)); media != nil {
+		var params []apistructs.APIParam
+		if obj, ok := openAPIMediaSample(media).(map[string]interface{}); ok {
+			keys := make([]string, 0, len(obj))
+			for key := range obj {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				params = append(params, apistructs.APIParam{Key: key, Value: fmt.Sprint(obj[key])})
+			}
+		}
+		api.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeApplicationXWWWFormUrlencoded, Content: params}
+		return
+	}
+	if media := content.Get(string(apistructs.APIBodyTypeTextPlain)); media != nil {
+		api.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeTextPlain, Content: fmt.Sprint(openAPIMediaSample(media))}
+		return
+	}
+	mediaTypes := make([]string, 0, len(content))
+	for mediaType := range content {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Strings(mediaTypes)
+	c.doc.unsupported(location, "requestBody", fmt.Sprintf("media types %s are not supported", strings.Join(mediaTypes, ",")))
+}
+
+func openAPIMediaSample(media *openapi3.MediaType) interface{} {
+	if media.Example != nil {
+		return media.Example
+	}
+	if example := openAPIFirstExample(media.Examples); example != nil {
+		return example
+	}
+	if media.Schema == nil {
+		return nil
+	}
+	return openAPISchemaSample(media.Schema.Value, 0)
+}
+
+// openAPIExample return example value of parameter as string
+func openAPIExample(example interface{}, examples openapi3.Examples, schema *openapi3.SchemaRef) string {
+	if example == nil {
+		example = openAPIFirstExample(examples)
+	}
+	if example == nil && schema != nil {
+		example = openAPISchemaSample(schema.Value, 0)
+	}
+	if example == nil {
+		return ""
+	}
+	return fmt.Sprint(example)
+}
+
+// openAPIFirstExample return the first example ordered by name
+func openAPIFirstExample(examples openapi3.Examples) interface{} {
+	names := make([]string, 0, len(examples))
+	for name := range examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if ref := examples[name]; ref != nil && ref.Value != nil && ref.Value.Value != nil {
+			return ref.Value.Value
+		}
+	}
+	return nil
+}
+
+// openAPISchemaSample generate sample value from schema, recursion is limited by depth
+func openAPISchemaSample(schema *openapi3.Schema, depth int) interface{} {
+	if schema == nil || depth > openAPISampleMaxDepth {
+		return nil
+	}
+	if schema.Example != nil {
+		return schema.Example
+	}
+	if schema.Default != nil {
+		return schema.Default
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0]
+	}
+	for _, refs := range []openapi3.SchemaRefs{schema.AllOf, schema.OneOf, schema.AnyOf} {
+		if len(refs) == 0 {
+			continue
+		}
+		// merge properties of allOf, use the first one of oneOf and anyOf
+		merged := map[string]interface{}{}
+		for _, ref := range refs {
+			if ref == nil {
+				continue
+			}
+			sample := openAPISchemaSample(ref.Value, depth+1)
+			obj, ok := sample.(map[string]interface{})
+			if !ok {
+				return sample
+			}
+			for k, v := range obj {
+				merged[k] = v
+			}
+			if len(schema.AllOf) == 0 {
+				break
+			}
+		}
+		return merged
+	}
+	switch schema.Type {
+	case "string":
+		switch schema.Format {
+		case "date":
+			return "2006-01-02"
+		case "date-time":
+			return "2006-01-02T15:04:05Z"
+		}
+		return "string"
+	case "integer", "number":
+		if schema.Min != nil {
+			return *schema.Min
+		}
+		return 0
+	case "boolean":
+		return false
+	case "array":
+		if schema.Items == nil {
+			return []interface{}{}
+		}
+		return []interface{}{openAPISchemaSample(schema.Items.Value, depth+1)}
+	default:
+		obj := map[string]interface{}{}
+		for name, ref := range schema.Properties {
+			if ref == nil || ref.Value == nil || ref.Value.ReadOnly {
+				continue
+			}
+			obj[name] = openAPISchemaSample(ref.Value, depth+1)
+		}
+		return obj
+	}
+}
@@ -0,0 +1,473 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotestv2
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/expression"
+	"github.com/erda-project/erda/pkg/mock"
+)
+
+const postmanSchemaV21 = "v2.1.0"
+
+// postmanCollection is the postman collection v2.1 format,
+// only the fields can be converted are defined
+type postmanCollection struct {
+	Info struct {
+		Name        string          `json:"name"`
+		Description json.RawMessage `json:"description"`
+		Schema      string          `json:"schema"`
+	} `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanVariable `json:"variable"`
+	Auth     *postmanAuth      `json:"auth"`
+	Event    []postmanEvent    `json:"event"`
+}
+
+// postmanItem is a folder if Item is not nil, otherwise a request
+type postmanItem struct {
+	Name        string            `json:"name"`
+	Description json.RawMessage   `json:"description"`
+	Item        []postmanItem     `json:"item"`
+	Request     *postmanRequest   `json:"request"`
+	Response    []postmanResponse `json:"response"`
+	Auth        *postmanAuth      `json:"auth"`
+	Event       []postmanEvent    `json:"event"`
+}
+
+type postmanRequest struct {
+	Method string          `json:"method"`
+	Header []postmanKV     `json:"header"`
+	URL    json.RawMessage `json:"url"`
+	Body   *postmanBody    `json:"body"`
+	Auth   *postmanAuth    `json:"auth"`
+}
+
+type postmanURL struct {
+	Raw   string      `json:"raw"`
+	Query []postmanKV `json:"query"`
+}
+
+type postmanKV struct {
+	Key         string          `json:"key"`
+	Value       string          `json:"value"`
+	Type        string          `json:"type"`
+	Disabled    bool            `json:"disabled"`
+	Description json.RawMessage `json:"description"`
+}
+
+type postmanBody struct {
+	Mode       string      `json:"mode"`
+	Raw        string      `json:"raw"`
+	URLEncoded []postmanKV `json:"urlencoded"`
+	FormData   []postmanKV `json:"formdata"`
+	GraphQL    *struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql"`
+	Options *struct {
+		Raw *struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+type postmanAuth struct {
+	Type   string      `json:"type"`
+	Bearer []postmanKV `json:"bearer"`
+	Basic  []postmanKV `json:"basic"`
+	APIKey []postmanKV `json:"apikey"`
+}
+
+type postmanEvent struct {
+	Listen string `json:"listen"`
+	Script struct {
+		Exec json.RawMessage `json:"exec"`
+	} `json:"script"`
+}
+
+type postmanResponse struct {
+	Code int `json:"code"`
+}
+
+type postmanVariable struct {
+	Key         string          `json:"key"`
+	Value       interface{}     `json:"value"`
+	Disabled    bool            `json:"disabled"`
+	Description json.RawMessage `json:"description"`
+}
+
+// postmanEnvironment is the exported postman environment
+type postmanEnvironment struct {
+	Name   string `json:"name"`
+	Values []struct {
+		Key     string      `json:"key"`
+		Value   interface{} `json:"value"`
+		Enabled *bool       `json:"enabled"`
+	} `json:"values"`
+}
+
+var (
+	postmanVariableRe = regexp.MustCompile(`{{\s*([^{}\s]+)\s*}}`)
+	// postman test scripts like pm.response.to.have.status(200) or pm.expect(pm.response.code).to.eql(200)
+	postmanStatusScriptRe = regexp.MustCompile(`pm\.response\.to\.have\.status\(\s*(\d{3})\s*\)|pm\.expect\(\s*pm\.response\.code\s*\)\.to\.(?:eql|equal|be\.eql|be\.equal)\(\s*(\d{3})\s*\)`)
+	// postman dynamic variables which have the same meaning random params
+	postmanDynamicVariables = map[string]string{
+		"$timestamp":     mock.TimeStamp,
+		"$randomInt":     mock.Integer,
+		"$randomBoolean": mock.Boolean,
+	}
+)
+
+// postmanConverter keep the context of postman collection converting
+type postmanConverter struct {
+	doc    *apiDocSpace
+	values map[string]string
+	descs  map[string]string
+	// scene the scene which requests are appended to
+	scene *apiDocScene
+}
+
+// parsePostmanCollection convert postman v2.1 collection with optional environment.
+// top level folders are converted to scene sets, second level folders are converted to scenes,
+// requests of deeper folders are flattened to steps of the scene.
+func parsePostmanCollection(data, envData []byte) (*apiDocSpace, error) {
+	var collection postmanCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, fmt.Errorf("invalid postman collection: %v", err)
+	}
+	if collection.Info.Name == "" {
+		return nil, fmt.Errorf("invalid postman collection: missing info.name")
+	}
+	if collection.Info.Schema != "" && !strings.Contains(collection.Info.Schema, postmanSchemaV21) {
+		return nil, fmt.Errorf("unsupported postman collection schema: %s, please export as collection v2.1", collection.Info.Schema)
+	}
+
+	c := &postmanConverter{
+		doc:    &apiDocSpace{Name: collection.Info.Name, Description: postmanDescription(collection.Info.Description)},
+		values: map[string]string{},
+		descs:  map[string]string{},
+	}
+	for _, v := range collection.Variable {
+		if v.Disabled {
+			continue
+		}
+		c.values[v.Key] = fmt.Sprint(v.Value)
+		c.descs[v.Key] = postmanDescription(v.Description)
+	}
+	if len(envData) > 0 {
+		var env postmanEnvironment
+		if err := json.Unmarshal(envData, &env); err != nil {
+			return nil, fmt.Errorf("invalid postman environment: %v", err)
+		}
+		for _, v := range env.Values {
+			if v.Enabled != nil && !*v.Enabled {
+				continue
+			}
+			c.values[v.Key] = fmt.Sprint(v.Value)
+			c.descs[v.Key] = fmt.Sprintf("environment: %s", env.Name)
+		}
+	}
+	c.checkEvents(collection.Info.Name, collection.Event)
+
+	// requests at the root of collection
+	rootScene := apiDocScene{Name: collection.Info.Name, Description: c.doc.Description}
+	var sets []apiDocSceneSet
+	for _, item := range collection.Item {
+		if item.Request != nil {
+			c.appendRequest(&rootScene, collection.Info.Name, item, collection.Auth)
+			continue
+		}
+		sets = append(sets, c.convertFolder(item, collection.Auth))
+	}
+	if len(rootScene.Steps) > 0 {
+		rootScene.Inputs = sceneInputs(rootScene.vars, c.values, c.descs)
+		c.doc.SceneSets = append(c.doc.SceneSets, apiDocSceneSet{
+			Name:        collection.Info.Name,
+			Description: c.doc.Description,
+			Scenes:      []apiDocScene{rootScene},
+		})
+	}
+	c.doc.SceneSets = append(c.doc.SceneSets, sets...)
+	return c.doc, nil
+}
+
+// convertFolder convert top level folder to scene set
+func (c *postmanConverter) convertFolder(folder postmanItem, auth *postmanAuth) apiDocSceneSet {
+	if folder.Auth != nil {
+		auth = folder.Auth
+	}
+	c.checkEvents(folder.Name, folder.Event)
+	set := apiDocSceneSet{Name: folder.Name, Description: postmanDescription(folder.Description)}
+
+	direct := apiDocScene{Name: folder.Name, Description: set.Description}
+	var scenes []apiDocScene
+	for _, item := range folder.Item {
+		if item.Request != nil {
+			c.appendRequest(&direct, folder.Name, item, auth)
+			continue
+		}
+		scene := apiDocScene{Name: item.Name, Description: postmanDescription(item.Description)}
+		c.flattenFolder(&scene, folder.Name+"/"+item.Name, item, auth)
+		scene.Inputs = sceneInputs(scene.vars, c.values, c.descs)
+		scenes = append(scenes, scene)
+	}
+	if len(direct.Steps) > 0 {
+		direct.Inputs = sceneInputs(direct.vars, c.values, c.descs)
+		set.Scenes = append(set.Scenes, direct)
+	}
+	set.Scenes = append(set.Scenes, scenes...)
+	return set
+}
+
+// flattenFolder append all requests of folder and sub folders to scene
+func (c *postmanConverter) flattenFolder(scene *apiDocScene, location string, folder postmanItem, auth *postmanAuth) {
+	if folder.Auth != nil {
+		auth = folder.Auth
+	}
+	c.checkEvents(location, folder.Event)
+	for _, item := range folder.Item {
+		if item.Request != nil {
+			c.appendRequest(scene, location, item, auth)
+			continue
+		}
+		c.flattenFolder(scene, location+"/"+item.Name, item, auth)
+	}
+}
+
+// appendRequest convert postman request to api step
+func (c *postmanConverter) appendRequest(scene *apiDocScene, location string, item postmanItem, auth *postmanAuth) {
+	location = location + "/" + item.Name
+	c.scene = scene
+	req := item.Request
+	api := apistructs.APIInfoV2{
+		Name:   item.Name,
+		Method: strings.ToUpper(req.Method),
+	}
+	if api.Method == "" {
+		api.Method = "GET"
+	}
+
+	// url and query params
+	var u postmanURL
+	if err := json.Unmarshal(req.URL, &u); err != nil {
+		// url is a plain string
+		_ = json.Unmarshal(req.URL, &u.Raw)
+	}
+	rawURL, _ := splitURLQuery(u.Raw)
+	api.URL = c.render(location, rawURL)
+	if len(u.Query) > 0 {
+		for _, q := range u.Query {
+			if q.Disabled {
+				continue
+			}
+			api.Params = append(api.Params, apistructs.APIParam{Key: c.render(location, q.Key), Value: c.render(location, q.Value), Desc: postmanDescription(q.Description)})
+		}
+	} else {
+		_, params := splitURLQuery(u.Raw)
+		for _, p := range params {
+			api.Params = append(api.Params, apistructs.APIParam{Key: c.render(location, p.Key), Value: c.render(location, p.Value)})
+		}
+	}
+
+	for _, h := range req.Header {
+		if h.Disabled {
+			continue
+		}
+		api.Headers = append(api.Headers, apistructs.APIHeader{Key: h.Key, Value: c.render(location, h.Value), Desc: postmanDescription(h.Description)})
+	}
+	if req.Auth != nil {
+		auth = req.Auth
+	}
+	c.convertAuth(location, auth, &api)
+	c.convertBody(location, req.Body, &api)
+
+	// status assertion from test scripts or saved response examples
+	status := c.checkEvents(location, item.Event)
+	if status == "" {
+		for _, resp := range item.Response {
+			if resp.Code > 0 {
+				status = strconv.Itoa(resp.Code)
+				break
+			}
+		}
+	}
+	if status != "" {
+		statusAssert(&api, "=", status)
+	} else {
+		statusAssert(&api, "<", "400")
+	}
+	scene.Steps = append(scene.Steps, api)
+}
+
+func (c *postmanConverter) convertAuth(location string, auth *postmanAuth, api *apistructs.APIInfoV2) {
+	if auth == nil {
+		return
+	}
+	get := func(kvs []postmanKV, key string) string {
+		for _, kv := range kvs {
+			if kv.Key == key {
+				return kv.Value
+			}
+		}
+		return ""
+	}
+	switch auth.Type {
+	case "", "noauth":
+	case "bearer":
+		api.Headers = append(api.Headers, apistructs.APIHeader{Key: "Authorization", Value: "Bearer " + c.render(location, get(auth.Bearer, "token"))})
+	case "basic":
+		username, password := get(auth.Basic, "username"), get(auth.Basic, "password")
+		if postmanVariableRe.MatchString(username + password) {
+			c.doc.unsupported(location, "auth.basic", "basic auth with variables can not be encoded when importing, please set the Authorization header manually")
+			return
+		}
+		api.Headers = append(api.Headers, apistructs.APIHeader{Key: "Authorization", Value: "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))})
+	case "apikey":
+		key, value := get(auth.APIKey, "key"), c.render(location, get(auth.APIKey, "value"))
+		if get(auth.APIKey, "in") == "query" {
+			api.Params = append(api.Params, apistructs.APIParam{Key: key, Value: value})
+			return
+		}
+		api.Headers = append(api.Headers, apistructs.APIHeader{Key: key, Value: value})
+	default:
+		c.doc.unsupported(location, "auth."+auth.Type, "auth type is not supported")
+	}
+}
+
+func (c *postmanConverter) convertBody(location string, body *postmanBody, api *apistructs.APIInfoV2) {
+	if body == nil || body.Mode == "" {
+		api.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeNone}
+		return
+	}
+	switch body.Mode {
+	case "raw":
+		content := c.render(location, body.Raw)
+		isJSON := body.Options != nil && body.Options.Raw != nil && body.Options.Raw.Language == "json"
+		if !isJSON {
+			isJSON = json.Valid([]byte(body.Raw))
+		}
+		if isJSON {
+			api.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeApplicationJSON, Content: content}
+		} else {
+			api.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeTextPlain, Content: content}
+		}
+	case "urlencoded", "formdata":
+		var params []apistructs.APIParam
+		kvs := body.URLEncoded
+		if body.Mode == "formdata" {
+			kvs = body.FormData
+		}
+		for _, kv := range kvs {
+			if kv.Disabled {
+				continue
+			}
+			if kv.Type == "file" {
+				c.doc.unsupported(location, "body.formdata.file", fmt.Sprintf("file field %q is skipped", kv.Key))
+				continue
+			}
+			params = append(params, apistructs.APIParam{Key: c.render(location, kv.Key), Value: c.render(location, kv.Value), Desc: postmanDescription(kv.Description)})
+		}
+		if body.Mode == "formdata" {
+			c.doc.unsupported(location, "body.formdata", "multipart form data is sent as application/x-www-form-urlencoded")
+		}
+		api.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeApplicationXWWWFormUrlencoded, Content: params}
+	case "graphql":
+		// graphql over http is a json body with query and variables
+		gql := map[string]interface{}{}
+		if body.GraphQL != nil {
+			gql["query"] = body.GraphQL.Query
+			var variables interface{}
+			if err := json.Unmarshal([]byte(body.GraphQL.Variables), &variables); err == nil {
+				gql["variables"] = variables
+			}
+		}
+		b, _ := json.Marshal(gql)
+		api.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeApplicationJSON, Content: c.render(location, string(b))}
+	default:
+		c.doc.unsupported(location, "body."+body.Mode, "body mode is not supported")
+		api.Body = apistructs.APIBody{Type: apistructs.APIBodyTypeNone}
+	}
+}
+
+// checkEvents report scripts and return the expected status code if found in test scripts
+func (c *postmanConverter) checkEvents(location string, events []postmanEvent) string {
+	var status string
+	for _, event := range events {
+		script := postmanScript(event.Script.Exec)
+		if strings.TrimSpace(script) == "" {
+			continue
+		}
+		if event.Listen == "test" {
+			if matches := postmanStatusScriptRe.FindStringSubmatch(script); len(matches) > 0 {
+				status = matches[1] + matches[2]
+			}
+		}
+		c.doc.unsupported(location, "event."+event.Listen, "scripts are not executed, only the status code assertion is converted")
+	}
+	return status
+}
+
+// render replace postman variables {{name}} to scene params
+func (c *postmanConverter) render(location, s string) string {
+	return postmanVariableRe.ReplaceAllStringFunc(s, func(sub string) string {
+		name := postmanVariableRe.FindStringSubmatch(sub)[1]
+		if strings.HasPrefix(name, "$") {
+			if typ, ok := postmanDynamicVariables[name]; ok {
+				return expression.GenRandomRef(typ)
+			}
+			c.doc.unsupported(location, "variable."+name, "dynamic variable is not supported")
+			return sub
+		}
+		return c.scene.useVar(name)
+	})
+}
+
+// postmanDescription description is a string or an object with content
+func postmanDescription(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var d struct {
+		Content string `json:"content"`
+	}
+	_ = json.Unmarshal(raw, &d)
+	return d.Content
+}
+
+// postmanScript script exec is a string or lines
+func postmanScript(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var lines []string
+	if err := json.Unmarshal(raw, &lines); err == nil {
+		return strings.Join(lines, "\n")
+	}
+	var s string
+	_ = json.Unmarshal(raw, &s)
+	return s
+}
@@ -63,3 +63,42 @@ func TestImportSceneSet(t *testing.T) {
 	}, r)
 	assert.NoError(t, err)
 }
+
+func TestImportAPIAssetToSpace(t *testing.T) {
+	bdl := bundle.New(bundle.WithI18nLoader(&i18n.LocaleResourceLoader{}))
+	m := monkey.PatchInstanceMethod(reflect.TypeOf(bdl), "GetTestSpace",
+		func(bdl *bundle.Bundle, id uint64) (*apistructs.AutoTestSpace, error) {
+			return &apistructs.AutoTestSpace{ID: id, ProjectID: 2}, nil
+		})
+	defer m.Unpatch()
+	m1 := monkey.PatchInstanceMethod(reflect.TypeOf(bdl), "UploadFile",
+		func(bdl *bundle.Bundle, req filetypes.FileUploadRequest, clientTimeout ...int64) (*pb.File, error) {
+			return &pb.File{UUID: "123"}, nil
+		})
+	defer m1.Unpatch()
+
+	var created apistructs.TestFileRecordRequest
+	autotestSvc := New()
+	autotestSvc.CreateFileRecord = func(req apistructs.TestFileRecordRequest) (uint64, error) {
+		created = req
+		return 1, nil
+	}
+	autotestSvc.bdl = bdl
+
+	// the space belongs to another project
+	_, err := autotestSvc.ImportAPIAsset(apistructs.AutoTestAPIAssetImportRequest{
+		ProjectID: 1,
+		SpaceID:   1,
+		AssetID:   "petstore",
+	}, []byte(`{}`))
+	assert.Error(t, err)
+
+	_, err = autotestSvc.ImportAPIAsset(apistructs.AutoTestAPIAssetImportRequest{
+		ProjectID: 2,
+		SpaceID:   1,
+		AssetID:   "petstore",
+	}, []byte(`{}`))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), created.Extra.AutotestSceneSetFileExtraInfo.ImportRequest.ProjectID)
+	assert.Equal(t, uint64(1), created.SpaceID)
+}
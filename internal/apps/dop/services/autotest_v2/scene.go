@@ -717,6 +717,18 @@ func (svc *Service) ExecuteDiceAutotestSceneStep(req apistructs.AutotestExecuteS
 		return nil, err
 	}
 
+	// data driven step is executed with the first row of dataset when executing single step
+	var runStep apistructs.AutoTestRunStep
+	if err := json.Unmarshal([]byte(step.Value), &runStep); err == nil && runStep.DataSet != nil {
+		rows, err := apitestsv2.ParseDataSet(runStep.DataSet)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) > 0 {
+			caseParams = apitestsv2.DataSetCaseParams(caseParams, rows[0])
+		}
+	}
+
 	sceneInputs, err := svc.ListAutoTestSceneInput(step.SceneID)
 	if err != nil {
 		return nil, err
@@ -801,7 +813,7 @@ func (svc *Service) ExecuteDiceAutotestSceneStep(req apistructs.AutotestExecuteS
 	outParams := apiTest.ParseOutParams(apiTest.API.OutParams, apiResp, caseParams)
 	if len(apiTest.API.Asserts) > 0 {
 		asserts := apiTest.API.Asserts[0]
-		succ, assertResult := apiTest.JudgeAsserts(outParams, asserts)
+		succ, assertResult := apiTest.JudgeAssertsWithResp(apiResp, outParams, asserts)
 		logrus.Infof("judge assert result: %v", succ)
 
 		respData.Asserts = &apistructs.APITestsAssertResult{
@@ -1014,6 +1026,9 @@ func StepToAction(step apistructs.AutoTestSceneStep, req apistructs.SnippetConfi
 		action.Type = "api-test"
 		action.Version = "2.0"
 		action.Params = value.ApiSpec
+		if value.DataSet != nil && action.Params != nil {
+			action.Params["dataset"] = value.DataSet
+		}
 		if value.Loop != nil && value.Loop.Strategy != nil && value.Loop.Strategy.MaxTimes > 0 {
 			action.Loop = value.Loop
 		}
@@ -40,6 +40,12 @@ func (svc *Service) CreateTestPlanV2(req apistructs.TestPlanV2CreateRequest) (ui
 	// 	return 0, err
 	// }
 
+	if req.Type == apistructs.TestPlanV2TypeLoad {
+		if err := svc.checkLoadScene(req.SpaceID, req.LoadConfig); err != nil {
+			return 0, apierrors.ErrCreateTestPlan.InvalidParameter(err)
+		}
+	}
+
 	// create test plan
 	testPlanV2 := &dao.TestPlanV2{
 		Name:          req.Name,
@@ -52,6 +58,11 @@ func (svc *Service) CreateTestPlanV2(req apistructs.TestPlanV2CreateRequest) (ui
 		ExecuteTime:   nil,
 		PassRate:      float64(0),
 		ExecuteApiNum: 0,
+		Type:          req.Type,
+	}
+	if req.LoadConfig != nil {
+		loadConfig := dao.TestPlanV2LoadConfig(*req.LoadConfig)
+		testPlanV2.LoadConfig = &loadConfig
 	}
 
 	if err := svc.db.CreateTestPlanV2(testPlanV2); err != nil {
@@ -423,6 +434,23 @@ func (svc *Service) getChangedFields(req *apistructs.TestPlanV2UpdateRequest, mo
 		fields["is_archived"] = *req.IsArchived
 	}
 
+	if req.LoadConfig != nil {
+		if model.Type != apistructs.TestPlanV2TypeLoad {
+			return nil, apierrors.ErrUpdateTestPlan.InvalidParameter("loadConfig is only valid for load testplan")
+		}
+		if err := req.LoadConfig.Check(); err != nil {
+			return nil, apierrors.ErrUpdateTestPlan.InvalidParameter(err)
+		}
+		spaceID := model.SpaceID
+		if req.SpaceID != 0 {
+			spaceID = req.SpaceID
+		}
+		if err := svc.checkLoadScene(spaceID, req.LoadConfig); err != nil {
+			return nil, apierrors.ErrUpdateTestPlan.InvalidParameter(err)
+		}
+		fields["load_config"] = dao.TestPlanV2LoadConfig(*req.LoadConfig)
+	}
+
 	if len(fields) != 0 {
 		fields["updater_id"] = req.UserID
 	}
@@ -436,51 +464,12 @@ func (svc *Service) ExecuteDiceAutotestTestPlan(req apistructs.AutotestExecuteTe
 		return nil, err
 	}
 
-	var spec pipelineyml.Spec
-	spec.Version = "1.1"
-	var stagesValue []*pipelineyml.Stage
-
-	// get steps group by groupID
-	stepGroupMap, groupIDs := getStepMapByGroupID(testPlan.Steps)
-
-	for _, groupID := range groupIDs {
-		var specStage pipelineyml.Stage
-		for _, v := range stepGroupMap[groupID] {
-			if v.SceneSetID <= 0 {
-				continue
-			}
-			sceneSetJson, err := json.Marshal(v)
-			if err != nil {
-				return nil, err
-			}
-			action := map[pipelineyml.ActionType]*pipelineyml.Action{
-				pipelineyml.Snippet: {
-					Alias: pipelineyml.ActionAlias(strconv.Itoa(int(v.ID))),
-					Type:  pipelineyml.Snippet,
-					Labels: map[string]string{
-						apistructs.AutotestSceneSet: base64.StdEncoding.EncodeToString(sceneSetJson),
-						apistructs.AutotestType:     apistructs.AutotestSceneSet,
-					},
-					If: expression.LeftPlaceholder + " 1 == 1 " + expression.RightPlaceholder,
-					SnippetConfig: &pipelineyml.SnippetConfig{
-						Name:   apistructs.PipelineSourceAutoTestSceneSet.String() + "-" + strconv.Itoa(int(v.SceneSetID)),
-						Source: apistructs.PipelineSourceAutoTest.String(),
-						Labels: map[string]string{
-							apistructs.LabelAutotestExecType: apistructs.SceneSetsAutotestExecType,
-							apistructs.LabelSceneSetID:       strconv.Itoa(int(v.SceneSetID)),
-							apistructs.LabelSpaceID:          strconv.Itoa(int(testPlan.SpaceID)),
-							apistructs.LabelTestPlanID:       strconv.FormatUint(testPlan.ID, 10),
-							apistructs.LabelIterationID:      req.Labels[apistructs.LabelIterationID],
-						},
-					},
-				},
-			}
-			specStage.Actions = append(specStage.Actions, action)
-		}
-		stagesValue = append(stagesValue, &specStage)
+	var yml []byte
+	if testPlan.Type == apistructs.TestPlanV2TypeLoad {
+		yml, err = svc.loadTestPlanToYml(testPlan)
+	} else {
+		yml, err = testPlanToYml(testPlan, req.Labels)
 	}
-	spec.Stages = stagesValue
-	yml, err := pipelineyml.GenerateYml(&spec)
 	if err != nil {
 		return nil, err
 	}
@@ -549,6 +538,55 @@ func getStepMapByGroupID(steps []*apistructs.TestPlanV2Step) (map[uint64][]*apis
 	return stepGroupMap, groupIDs
 }
 
+// testPlanToYml generate pipeline yml of the default testplan, each scene set is a snippet
+func testPlanToYml(testPlan *apistructs.TestPlanV2, labels map[string]string) ([]byte, error) {
+	var spec pipelineyml.Spec
+	spec.Version = "1.1"
+	var stagesValue []*pipelineyml.Stage
+
+	// get steps group by groupID
+	stepGroupMap, groupIDs := getStepMapByGroupID(testPlan.Steps)
+
+	for _, groupID := range groupIDs {
+		var specStage pipelineyml.Stage
+		for _, v := range stepGroupMap[groupID] {
+			if v.SceneSetID <= 0 {
+				continue
+			}
+			sceneSetJson, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			action := map[pipelineyml.ActionType]*pipelineyml.Action{
+				pipelineyml.Snippet: {
+					Alias: pipelineyml.ActionAlias(strconv.Itoa(int(v.ID))),
+					Type:  pipelineyml.Snippet,
+					Labels: map[string]string{
+						apistructs.AutotestSceneSet: base64.StdEncoding.EncodeToString(sceneSetJson),
+						apistructs.AutotestType:     apistructs.AutotestSceneSet,
+					},
+					If: expression.LeftPlaceholder + " 1 == 1 " + expression.RightPlaceholder,
+					SnippetConfig: &pipelineyml.SnippetConfig{
+						Name:   apistructs.PipelineSourceAutoTestSceneSet.String() + "-" + strconv.Itoa(int(v.SceneSetID)),
+						Source: apistructs.PipelineSourceAutoTest.String(),
+						Labels: map[string]string{
+							apistructs.LabelAutotestExecType: apistructs.SceneSetsAutotestExecType,
+							apistructs.LabelSceneSetID:       strconv.Itoa(int(v.SceneSetID)),
+							apistructs.LabelSpaceID:          strconv.Itoa(int(testPlan.SpaceID)),
+							apistructs.LabelTestPlanID:       strconv.FormatUint(testPlan.ID, 10),
+							apistructs.LabelIterationID:      labels[apistructs.LabelIterationID],
+						},
+					},
+				},
+			}
+			specStage.Actions = append(specStage.Actions, action)
+		}
+		stagesValue = append(stagesValue, &specStage)
+	}
+	spec.Stages = stagesValue
+	return pipelineyml.GenerateYml(&spec)
+}
+
 func (svc *Service) GetTestClusterNameBySpaceID(spaceID uint64) (string, error) {
 	space, err := svc.db.GetAutoTestSpace(spaceID)
 	if err != nil {
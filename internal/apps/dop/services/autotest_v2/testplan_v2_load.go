@@ -0,0 +1,199 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotestv2
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/expression"
+	"github.com/erda-project/erda/pkg/parser/pipelineyml"
+)
+
+const (
+	loadTestActionVersion  = "2.0"
+	loadTestWorkerAlias    = "load-worker-"
+	loadTestReportAlias    = "load-report"
+	loadTestResultMetaName = "load_result"
+)
+
+// checkLoadScene check the scene of load config is in the space
+func (svc *Service) checkLoadScene(spaceID uint64, loadConfig *apistructs.TestPlanV2LoadConfig) error {
+	scene, err := svc.db.GetAutotestScene(loadConfig.SceneID)
+	if err != nil {
+		return fmt.Errorf("failed to get scene %d, err: %v", loadConfig.SceneID, err)
+	}
+	if scene.SpaceID != spaceID {
+		return fmt.Errorf("scene %d is not in the space of testplan", loadConfig.SceneID)
+	}
+	return nil
+}
+
+// loadTestPlanToYml generate pipeline yml of the load testplan.
+// The first stage has one api-test action for each worker, workers run the scene in loop concurrently
+// and output their latency histograms, the second stage aggregates results and judges thresholds.
+func (svc *Service) loadTestPlanToYml(testPlan *apistructs.TestPlanV2) ([]byte, error) {
+	loadConfig := testPlan.LoadConfig
+	if loadConfig == nil {
+		return nil, fmt.Errorf("load config of testplan %d is empty", testPlan.ID)
+	}
+	if err := loadConfig.Check(); err != nil {
+		return nil, err
+	}
+
+	sceneSteps, err := svc.ListAutoTestSceneStep(loadConfig.SceneID)
+	if err != nil {
+		return nil, err
+	}
+	sceneInputs, err := svc.ListAutoTestSceneInput(loadConfig.SceneID)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := sceneToLoadTestSteps(sceneSteps, sceneInputs)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{apistructs.AutotestType: apistructs.AutotestLoadTest}
+	var spec pipelineyml.Spec
+	spec.Version = "1.1"
+
+	var workerStage pipelineyml.Stage
+	var results []string
+	for i := 0; i < loadConfig.Workers; i++ {
+		alias := loadTestWorkerAlias + strconv.Itoa(i)
+		load, err := loadTestParam(apistructs.LoadTestWorkerConfig{
+			ProjectID:    testPlan.ProjectID,
+			TestPlanID:   testPlan.ID,
+			SceneID:      loadConfig.SceneID,
+			Worker:       i,
+			VirtualUsers: loadConfig.WorkerVirtualUsers(i),
+			RampUpSec:    loadConfig.RampUpSec,
+			DurationSec:  loadConfig.DurationSec,
+			ThinkTimeMs:  loadConfig.ThinkTimeMs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		workerStage.Actions = append(workerStage.Actions, map[pipelineyml.ActionType]*pipelineyml.Action{
+			apistructs.ActionTypeAPITest: {
+				Alias:   pipelineyml.ActionAlias(alias),
+				Type:    apistructs.ActionTypeAPITest,
+				Version: loadTestActionVersion,
+				Labels:  labels,
+				Params: map[string]interface{}{
+					"load":       load,
+					"load_steps": steps,
+				},
+			},
+		})
+		results = append(results, expression.GenOutputRef(alias, loadTestResultMetaName))
+	}
+
+	report, err := loadTestParam(apistructs.LoadTestReportConfig{
+		ProjectID:    testPlan.ProjectID,
+		TestPlanID:   testPlan.ID,
+		SceneID:      loadConfig.SceneID,
+		VirtualUsers: loadConfig.VirtualUsers,
+		Thresholds:   loadConfig.Thresholds,
+	})
+	if err != nil {
+		return nil, err
+	}
+	reportStage := pipelineyml.Stage{Actions: []map[pipelineyml.ActionType]*pipelineyml.Action{{
+		apistructs.ActionTypeAPITest: {
+			Alias:   loadTestReportAlias,
+			Type:    apistructs.ActionTypeAPITest,
+			Version: loadTestActionVersion,
+			Labels:  labels,
+			Params: map[string]interface{}{
+				"load_report":  report,
+				"load_results": results,
+			},
+		},
+	}}}
+
+	spec.Stages = []*pipelineyml.Stage{&workerStage, &reportStage}
+	return pipelineyml.GenerateYml(&spec)
+}
+
+// loadTestParam convert config to map, so that the keys in yml are same as json tags
+func loadTestParam(config interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var param map[string]interface{}
+	if err := json.Unmarshal(b, &param); err != nil {
+		return nil, err
+	}
+	return param, nil
+}
+
+// sceneToLoadTestSteps convert scene steps to load test steps, parallel steps are executed serially.
+// Scene inputs are rendered in advance, the result is base64 encoded so that the outputs references
+// between steps are not rendered by pipeline.
+func sceneToLoadTestSteps(sceneSteps []apistructs.AutoTestSceneStep, sceneInputs []apistructs.AutoTestSceneInput) (string, error) {
+	var steps []apistructs.LoadTestStep
+	for _, stage := range StepToStages(sceneSteps) {
+		for _, step := range stage {
+			if step.IsDisabled || step.Value == "" {
+				continue
+			}
+			alias := strconv.FormatUint(step.ID, 10)
+			switch step.Type {
+			case apistructs.StepTypeAPI:
+				var value apistructs.AutoTestRunStep
+				if err := json.Unmarshal([]byte(step.Value), &value); err != nil {
+					return "", err
+				}
+				steps = append(steps, apistructs.LoadTestStep{Alias: alias, Spec: value.ApiSpec})
+			case apistructs.StepTypeWait:
+				var value apistructs.AutoTestRunWait
+				if err := json.Unmarshal([]byte(step.Value), &value); err != nil {
+					return "", err
+				}
+				if value.WaitTime > 0 {
+					value.WaitTimeSec = value.WaitTime
+				}
+				steps = append(steps, apistructs.LoadTestStep{Alias: alias, WaitSec: value.WaitTimeSec})
+			default:
+				return "", fmt.Errorf("step %s of type %s is not supported in load test, only api and wait steps are supported", step.Name, step.Type)
+			}
+		}
+	}
+	if len(steps) == 0 {
+		return "", fmt.Errorf("scene has no api steps to load test")
+	}
+
+	b, err := json.Marshal(steps)
+	if err != nil {
+		return "", err
+	}
+	content := string(b)
+	for _, input := range sceneInputs {
+		value, err := json.Marshal(input.Value)
+		if err != nil {
+			return "", err
+		}
+		// value is json string, remove the quotes only
+		content = strings.ReplaceAll(content, expression.GenParamsRef(input.Name), string(value[1:len(value)-1]))
+	}
+	return base64.StdEncoding.EncodeToString([]byte(content)), nil
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autotestv2
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+func TestSceneToLoadTestSteps(t *testing.T) {
+	apiStep := func(id uint64, url string) apistructs.AutoTestSceneStep {
+		value, _ := json.Marshal(apistructs.AutoTestRunStep{ApiSpec: map[string]interface{}{"url": url, "method": "GET"}})
+		return apistructs.AutoTestSceneStep{AutoTestSceneParams: apistructs.AutoTestSceneParams{ID: id}, Type: apistructs.StepTypeAPI, Value: string(value)}
+	}
+	wait := apistructs.AutoTestSceneStep{AutoTestSceneParams: apistructs.AutoTestSceneParams{ID: 3}, Type: apistructs.StepTypeWait, Value: `{"waitTime":2}`}
+	disabled := apiStep(4, "http://disabled")
+	disabled.IsDisabled = true
+	first := apiStep(1, "${{ params.host }}/login")
+	first.Children = []apistructs.AutoTestSceneStep{apiStep(2, "${{ params.host }}/profile")}
+
+	encoded, err := sceneToLoadTestSteps([]apistructs.AutoTestSceneStep{first, wait, disabled},
+		[]apistructs.AutoTestSceneInput{{Name: "host", Value: `http://"localhost"`}})
+	assert.NoError(t, err)
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	assert.NoError(t, err)
+	var steps []apistructs.LoadTestStep
+	assert.NoError(t, json.Unmarshal(b, &steps))
+	assert.Len(t, steps, 3)
+	assert.Equal(t, "1", steps[0].Alias)
+	assert.Equal(t, `http://"localhost"/login`, steps[0].Spec["url"])
+	assert.Equal(t, "2", steps[1].Alias)
+	assert.Equal(t, 2, steps[2].WaitSec)
+	assert.Nil(t, steps[2].Spec)
+
+	script := apistructs.AutoTestSceneStep{Type: apistructs.StepTypeCustomScript, Value: `{"commands":["echo"]}`}
+	_, err = sceneToLoadTestSteps([]apistructs.AutoTestSceneStep{script}, nil)
+	assert.Error(t, err)
+
+	_, err = sceneToLoadTestSteps([]apistructs.AutoTestSceneStep{disabled}, nil)
+	assert.Error(t, err)
+}
+
+func TestLoadTestParam(t *testing.T) {
+	param, err := loadTestParam(apistructs.LoadTestReportConfig{
+		TestPlanID: 1,
+		Thresholds: []apistructs.LoadTestThreshold{{Metric: apistructs.LoadTestMetricRPS, Operator: ">", Value: 10}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), param["testPlanID"])
+	assert.Equal(t, "rps", param["thresholds"].([]interface{})[0].(map[string]interface{})["metric"])
+}
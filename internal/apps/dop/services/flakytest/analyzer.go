@@ -0,0 +1,207 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flakytest
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/erda-project/erda-proto-go/dop/qa/unittest/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/dbclient"
+)
+
+const dayLayout = "2006-01-02"
+
+// Policy 判定不稳定用例的阈值
+type Policy struct {
+	// MinRuns 按翻转率判定前至少需要的执行次数
+	MinRuns int64
+	// Threshold 翻转率达到该值即判定为不稳定
+	Threshold float64
+}
+
+var DefaultPolicy = Policy{MinRuns: 10, Threshold: 0.2}
+
+// Result 一次测试记录中单个用例的结果
+type Result struct {
+	Key       string
+	Suite     string
+	Classname string
+	Name      string
+	Failed    bool
+	// Mixed 同一次执行中该用例既有成功也有失败, 如框架内重试
+	Mixed bool
+}
+
+// TestKey 由 suite, classname, name 生成用例唯一标识
+func TestKey(suite, classname, name string) string {
+	sum := md5.Sum([]byte(suite + "\x00" + classname + "\x00" + name))
+	return hex.EncodeToString(sum[:])
+}
+
+// Collect 从测试套件中提取用例结果, 跳过的用例不计入, 重复出现的用例合并
+func Collect(suites []*pb.TestSuite) []*Result {
+	var results []*Result
+	index := make(map[string]*Result)
+	for _, suite := range suites {
+		if suite == nil {
+			continue
+		}
+		for _, test := range suite.Tests {
+			if test == nil || test.Status == string(apistructs.TestStatusSkipped) {
+				continue
+			}
+			failed := test.Status == string(apistructs.TestStatusFailed) || test.Status == string(apistructs.TestStatusError)
+			key := TestKey(suite.Name, test.Classname, test.Name)
+			if r, ok := index[key]; ok {
+				if r.Failed != failed {
+					r.Mixed = true
+					r.Failed = true
+				}
+				continue
+			}
+			r := &Result{
+				Key:       key,
+				Suite:     suite.Name,
+				Classname: test.Classname,
+				Name:      test.Name,
+				Failed:    failed,
+			}
+			index[key] = r
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+func status(failed bool) string {
+	if failed {
+		return string(apistructs.TestStatusFailed)
+	}
+	return string(apistructs.TestStatusPassed)
+}
+
+// Observe 将一次执行结果累加到用例统计上, 返回当日的增量统计
+func (p Policy) Observe(t *dbclient.QAFlakyTest, r *Result, commitID string, recordID uint64, now time.Time) *dbclient.QAFlakyTestDaily {
+	st := status(r.Failed)
+	daily := &dbclient.QAFlakyTestDaily{
+		ApplicationID: t.ApplicationID,
+		FlakyTestID:   t.ID,
+		Day:           now.Format(dayLayout),
+		Runs:          1,
+	}
+	flip := t.TotalRuns > 0 && t.LastStatus != "" && t.LastStatus != st
+	commitFlip := r.Mixed || (flip && commitID != "" && t.LastCommitID == commitID)
+
+	t.TotalRuns++
+	if r.Failed {
+		t.FailedRuns++
+		daily.FailedRuns = 1
+	}
+	if flip {
+		t.Flips++
+		daily.Flips = 1
+	}
+	if commitFlip {
+		t.CommitFlips++
+		daily.CommitFlips = 1
+	}
+	t.LastStatus = st
+	t.LastCommitID = commitID
+	t.LastRecordID = recordID
+	t.LastRunAt = now
+	t.Flakiness = p.Flakiness(t)
+	t.IsFlaky = p.IsFlaky(t)
+	return daily
+}
+
+// Flakiness 计算不稳定指数: 相邻执行的翻转率, 同一 commit 上出现过翻转时至少为 0.5
+func (p Policy) Flakiness(t *dbclient.QAFlakyTest) float64 {
+	var score float64
+	if t.TotalRuns > 1 {
+		score = float64(t.Flips) / float64(t.TotalRuns-1)
+	}
+	if t.CommitFlips > 0 && score < 0.5 {
+		score = 0.5
+	}
+	if score > 1 {
+		score = 1
+	}
+	return score
+}
+
+// IsFlaky 同一 commit 上结果翻转即判定为不稳定, 否则需达到最小执行次数且翻转率超过阈值
+func (p Policy) IsFlaky(t *dbclient.QAFlakyTest) bool {
+	if t.CommitFlips > 0 {
+		return true
+	}
+	return t.TotalRuns >= p.MinRuns && p.Flakiness(t) >= p.Threshold
+}
+
+// Evaluate 计算测试报告门禁结果, 已隔离用例的失败不影响门禁
+func Evaluate(recordID uint64, results []*Result, quarantined map[string]bool) *apistructs.TestReportGateResult {
+	gate := &apistructs.TestReportGateResult{
+		RecordID:          recordID,
+		Failed:            []string{},
+		QuarantinedFailed: []string{},
+	}
+	for _, r := range results {
+		if !r.Failed {
+			continue
+		}
+		name := r.Suite + "/" + r.Name
+		if quarantined[r.Key] {
+			gate.QuarantinedFailed = append(gate.QuarantinedFailed, name)
+			continue
+		}
+		gate.Failed = append(gate.Failed, name)
+	}
+	gate.Passed = len(gate.Failed) == 0
+	return gate
+}
+
+// Trend 将每日统计按天聚合, 补齐 [since, until] 内没有数据的日期
+func Trend(dailies []*dbclient.QAFlakyTestDaily, since, until time.Time) []*apistructs.FlakyTestTrendPoint {
+	points := make(map[string]*apistructs.FlakyTestTrendPoint)
+	for day := since; !day.After(until); day = day.AddDate(0, 0, 1) {
+		d := day.Format(dayLayout)
+		points[d] = &apistructs.FlakyTestTrendPoint{Day: d}
+	}
+	for _, d := range dailies {
+		point, ok := points[d.Day]
+		if !ok {
+			continue
+		}
+		point.Runs += d.Runs
+		point.FailedRuns += d.FailedRuns
+		point.Flips += d.Flips
+		point.CommitFlips += d.CommitFlips
+		if d.Flips > 0 || d.CommitFlips > 0 {
+			point.FlakyTests++
+		}
+	}
+	list := make([]*apistructs.FlakyTestTrendPoint, 0, len(points))
+	for _, point := range points {
+		if point.Runs > 0 {
+			point.FailureRate = float64(point.FailedRuns) / float64(point.Runs)
+		}
+		list = append(list, point)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Day < list[j].Day })
+	return list
+}
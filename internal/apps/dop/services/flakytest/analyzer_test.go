@@ -0,0 +1,124 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flakytest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda-proto-go/dop/qa/unittest/pb"
+	"github.com/erda-project/erda/internal/apps/dop/dbclient"
+)
+
+func suites(statuses ...string) []*pb.TestSuite {
+	suite := &pb.TestSuite{Name: "pkg"}
+	for i, st := range statuses {
+		suite.Tests = append(suite.Tests, &pb.Test{Name: string(rune('a' + i)), Classname: "pkg", Status: st})
+	}
+	return []*pb.TestSuite{suite}
+}
+
+func TestCollect(t *testing.T) {
+	results := Collect(suites("passed", "failed", "skipped", "error"))
+	assert.Equal(t, 3, len(results))
+	assert.False(t, results[0].Failed)
+	assert.True(t, results[1].Failed)
+	assert.True(t, results[2].Failed)
+
+	retried := []*pb.TestSuite{{Name: "pkg", Tests: []*pb.Test{
+		{Name: "a", Classname: "pkg", Status: "failed"},
+		{Name: "a", Classname: "pkg", Status: "passed"},
+	}}}
+	results = Collect(retried)
+	assert.Equal(t, 1, len(results))
+	assert.True(t, results[0].Failed)
+	assert.True(t, results[0].Mixed)
+}
+
+func TestObserve(t *testing.T) {
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.Local)
+	p := DefaultPolicy
+	test := &dbclient.QAFlakyTest{ApplicationID: 1}
+
+	// stable passes are not flaky
+	for i := 0; i < 5; i++ {
+		p.Observe(test, &Result{}, "c1", uint64(i), now)
+	}
+	assert.Equal(t, int64(5), test.TotalRuns)
+	assert.Equal(t, int64(0), test.Flips)
+	assert.False(t, test.IsFlaky)
+
+	// a failure on a new commit is a flip but not a same-commit flip
+	daily := p.Observe(test, &Result{Failed: true}, "c2", 6, now)
+	assert.Equal(t, int64(1), daily.Flips)
+	assert.Equal(t, int64(0), daily.CommitFlips)
+	assert.Equal(t, "2026-10-19", daily.Day)
+	assert.False(t, test.IsFlaky)
+
+	// passing again on the same commit marks the test as flaky
+	daily = p.Observe(test, &Result{}, "c2", 7, now)
+	assert.Equal(t, int64(1), daily.CommitFlips)
+	assert.Equal(t, int64(2), test.Flips)
+	assert.Equal(t, int64(1), test.CommitFlips)
+	assert.Equal(t, int64(1), test.FailedRuns)
+	assert.True(t, test.IsFlaky)
+	assert.Equal(t, 0.5, test.Flakiness)
+	assert.Equal(t, uint64(7), test.LastRecordID)
+}
+
+func TestFlakinessByFlipRate(t *testing.T) {
+	p := Policy{MinRuns: 5, Threshold: 0.3}
+	test := &dbclient.QAFlakyTest{TotalRuns: 11, Flips: 4}
+	assert.Equal(t, 0.4, p.Flakiness(test))
+	assert.True(t, p.IsFlaky(test))
+
+	test = &dbclient.QAFlakyTest{TotalRuns: 3, Flips: 2}
+	assert.False(t, p.IsFlaky(test))
+
+	// always failing tests are broken, not flaky
+	test = &dbclient.QAFlakyTest{TotalRuns: 20, FailedRuns: 20}
+	assert.False(t, p.IsFlaky(test))
+}
+
+func TestEvaluate(t *testing.T) {
+	results := Collect(suites("passed", "failed", "error"))
+	gate := Evaluate(1, results, map[string]bool{results[1].Key: true})
+	assert.False(t, gate.Passed)
+	assert.Equal(t, []string{"pkg/c"}, gate.Failed)
+	assert.Equal(t, []string{"pkg/b"}, gate.QuarantinedFailed)
+
+	gate = Evaluate(1, results, map[string]bool{results[1].Key: true, results[2].Key: true})
+	assert.True(t, gate.Passed)
+}
+
+func TestTrend(t *testing.T) {
+	since := time.Date(2026, 10, 17, 0, 0, 0, 0, time.Local)
+	until := since.AddDate(0, 0, 2)
+	points := Trend([]*dbclient.QAFlakyTestDaily{
+		{FlakyTestID: 1, Day: "2026-10-17", Runs: 4, FailedRuns: 1, Flips: 1},
+		{FlakyTestID: 2, Day: "2026-10-17", Runs: 4, FailedRuns: 1},
+		{FlakyTestID: 1, Day: "2026-10-19", Runs: 2, CommitFlips: 1, Flips: 1},
+		{FlakyTestID: 1, Day: "2026-10-10", Runs: 2},
+	}, since, until)
+	assert.Equal(t, 3, len(points))
+	assert.Equal(t, "2026-10-17", points[0].Day)
+	assert.Equal(t, int64(8), points[0].Runs)
+	assert.Equal(t, 0.25, points[0].FailureRate)
+	assert.Equal(t, int64(1), points[0].FlakyTests)
+	assert.Equal(t, int64(0), points[1].Runs)
+	assert.Equal(t, int64(1), points[2].CommitFlips)
+}
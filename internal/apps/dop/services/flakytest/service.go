@@ -0,0 +1,178 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package flakytest 基于单元测试记录统计用例稳定性, 并提供不稳定用例隔离及测试报告门禁
+package flakytest
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/dbclient"
+)
+
+const maxTrendDays = 180
+
+type Service struct {
+	policy Policy
+	now    func() time.Time
+}
+
+type Option func(*Service)
+
+func New(options ...Option) *Service {
+	s := &Service{
+		policy: DefaultPolicy,
+		now:    time.Now,
+	}
+	for _, op := range options {
+		op(s)
+	}
+	return s
+}
+
+func WithPolicy(policy Policy) Option {
+	return func(s *Service) {
+		s.policy = policy
+	}
+}
+
+// GetRecord 查询单元测试记录
+func (s *Service) GetRecord(recordID uint64) (*dbclient.TPRecordDO, error) {
+	return dbclient.FindTPRecordById(recordID)
+}
+
+// GetRecordByUUID 按单元测试 action 生成的 uuid 查询测试记录
+func (s *Service) GetRecordByUUID(uuid string) (*dbclient.TPRecordDO, error) {
+	return dbclient.FindTPRecordByUUID(uuid)
+}
+
+// Gate 按当前隔离状态计算测试记录的门禁结果，被隔离用例的失败不影响门禁
+func (s *Service) Gate(record *dbclient.TPRecordDO) (*apistructs.TestReportGateResult, error) {
+	quarantined, err := dbclient.ListQuarantinedTestKeys(record.ApplicationID)
+	if err != nil {
+		return nil, err
+	}
+	return Evaluate(record.ID, Collect(record.Suites), quarantined), nil
+}
+
+// Record 将一次测试记录累加到用例稳定性统计中
+func (s *Service) Record(record *dbclient.TPRecordDO) error {
+	if record.ApplicationID == 0 {
+		return nil
+	}
+	results := Collect(record.Suites)
+	if len(results) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(results))
+	for _, r := range results {
+		keys = append(keys, r.Key)
+	}
+	existed, err := dbclient.FindFlakyTestsByKeys(record.ApplicationID, keys)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	tests := make([]*dbclient.QAFlakyTest, 0, len(results))
+	dailies := make([]*dbclient.QAFlakyTestDaily, 0, len(results))
+	for _, r := range results {
+		t, ok := existed[r.Key]
+		if !ok {
+			t = &dbclient.QAFlakyTest{
+				ProjectID:     record.ProjectID,
+				ApplicationID: record.ApplicationID,
+				TestKey:       r.Key,
+				Suite:         r.Suite,
+				Classname:     r.Classname,
+				Name:          r.Name,
+			}
+		}
+		dailies = append(dailies, s.policy.Observe(t, r, record.CommitID, record.ID, now))
+		tests = append(tests, t)
+	}
+	return dbclient.SaveFlakyTests(tests, dailies)
+}
+
+func (s *Service) List(req apistructs.FlakyTestListRequest) (*apistructs.FlakyTestListData, error) {
+	if req.ApplicationID == 0 {
+		return nil, errors.New("applicationID is required")
+	}
+	if req.PageNo <= 0 {
+		req.PageNo = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+	list, total, err := dbclient.PagingFlakyTests(req)
+	if err != nil {
+		return nil, err
+	}
+	data := &apistructs.FlakyTestListData{Total: total, List: make([]*apistructs.FlakyTest, 0, len(list))}
+	for _, t := range list {
+		data.List = append(data.List, t.ToAPI())
+	}
+	return data, nil
+}
+
+func (s *Service) Get(id uint64) (*apistructs.FlakyTest, error) {
+	t, err := dbclient.GetFlakyTest(id)
+	if err != nil {
+		return nil, err
+	}
+	return t.ToAPI(), nil
+}
+
+// Quarantine 隔离或取消隔离用例, 隔离后该用例失败不影响测试报告门禁
+func (s *Service) Quarantine(id uint64, req apistructs.FlakyTestQuarantineRequest) (*apistructs.FlakyTest, error) {
+	t, err := dbclient.GetFlakyTest(id)
+	if err != nil {
+		return nil, err
+	}
+	t.Quarantined = req.Quarantined
+	if req.Quarantined {
+		t.QuarantinedBy = req.UserID
+		t.QuarantineReason = req.Reason
+	} else {
+		t.QuarantinedBy = ""
+		t.QuarantineReason = ""
+	}
+	if err := dbclient.UpdateFlakyTestQuarantine(t); err != nil {
+		return nil, err
+	}
+	return t.ToAPI(), nil
+}
+
+func (s *Service) Trend(req apistructs.FlakyTestTrendRequest) ([]*apistructs.FlakyTestTrendPoint, error) {
+	if req.ApplicationID == 0 {
+		return nil, errors.New("applicationID is required")
+	}
+	if req.Days <= 0 {
+		req.Days = 30
+	}
+	if req.Days > maxTrendDays {
+		req.Days = maxTrendDays
+	}
+	now := s.now()
+	until := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	since := until.AddDate(0, 0, 1-req.Days)
+	dailies, err := dbclient.ListFlakyTestDaily(req.ApplicationID, req.TestID, since.Format(dayLayout))
+	if err != nil {
+		return nil, err
+	}
+	return Trend(dailies, since, until), nil
+}
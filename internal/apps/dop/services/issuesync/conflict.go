@@ -0,0 +1,100 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuesync
+
+import (
+	"time"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+const (
+	winnerErda   = "erda"
+	winnerRemote = "remote"
+)
+
+// resolution 三方合并结果, push 为需要写入外部系统的字段, pull 为需要写入 Erda 的字段
+type resolution struct {
+	merged    Fields
+	push      []string
+	pull      []string
+	conflicts []apistructs.IssueSyncConflict
+}
+
+// resolve 以上次同步的快照 base 为基准合并双方修改:
+// 仅一方修改时采用修改方的值, 双方修改为不同的值时按冲突策略处理.
+// base 为空表示首次同步, 此时所有不一致的字段都视为冲突.
+func resolve(base, local, remote Fields, strategy apistructs.IssueSyncConflictStrategy, localUpdatedAt, remoteUpdatedAt time.Time) resolution {
+	res := resolution{merged: make(Fields)}
+	keys := make(Fields)
+	for k := range local {
+		keys[k] = ""
+	}
+	for k := range remote {
+		keys[k] = ""
+	}
+	for _, k := range keys.keys() {
+		l, lok := local[k]
+		r, rok := remote[k]
+		b, bok := base[k]
+		switch {
+		case lok && rok && l == r:
+			res.merged[k] = l
+		case !rok:
+			// field missing on the remote side, e.g. an unmapped custom field
+			res.merged[k] = l
+			res.push = append(res.push, k)
+		case !lok:
+			res.merged[k] = r
+			res.pull = append(res.pull, k)
+		case bok && l == b:
+			res.merged[k] = r
+			res.pull = append(res.pull, k)
+		case bok && r == b:
+			res.merged[k] = l
+			res.push = append(res.push, k)
+		default:
+			winner := pickWinner(strategy, localUpdatedAt, remoteUpdatedAt)
+			if winner == winnerErda {
+				res.merged[k] = l
+				res.push = append(res.push, k)
+			} else {
+				res.merged[k] = r
+				res.pull = append(res.pull, k)
+			}
+			res.conflicts = append(res.conflicts, apistructs.IssueSyncConflict{
+				Field:       k,
+				LocalValue:  l,
+				RemoteValue: r,
+				Winner:      winner,
+			})
+		}
+	}
+	return res
+}
+
+func pickWinner(strategy apistructs.IssueSyncConflictStrategy, localUpdatedAt, remoteUpdatedAt time.Time) string {
+	switch strategy {
+	case apistructs.IssueSyncConflictErdaWins:
+		return winnerErda
+	case apistructs.IssueSyncConflictRemoteWins:
+		return winnerRemote
+	default:
+		if remoteUpdatedAt.After(localUpdatedAt) {
+			return winnerRemote
+		}
+		return winnerErda
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuesync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+func TestResolve(t *testing.T) {
+	earlier := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+	base := Fields{FieldTitle: "a", FieldState: "待处理", FieldPriority: "NORMAL", FieldAssignee: "1"}
+
+	t.Run("one side changed", func(t *testing.T) {
+		local := Fields{FieldTitle: "b", FieldState: "待处理", FieldPriority: "NORMAL", FieldAssignee: "1"}
+		remote := Fields{FieldTitle: "a", FieldState: "进行中", FieldPriority: "NORMAL", FieldAssignee: "1"}
+		res := resolve(base, local, remote, apistructs.IssueSyncConflictLatestWins, earlier, later)
+		assert.Equal(t, []string{FieldTitle}, res.push)
+		assert.Equal(t, []string{FieldState}, res.pull)
+		assert.Empty(t, res.conflicts)
+		assert.Equal(t, "b", res.merged[FieldTitle])
+		assert.Equal(t, "进行中", res.merged[FieldState])
+	})
+
+	t.Run("both changed to the same value", func(t *testing.T) {
+		local := Fields{FieldTitle: "c", FieldState: "待处理", FieldPriority: "NORMAL", FieldAssignee: "1"}
+		res := resolve(base, local, local, apistructs.IssueSyncConflictLatestWins, earlier, later)
+		assert.Empty(t, res.push)
+		assert.Empty(t, res.pull)
+		assert.Empty(t, res.conflicts)
+	})
+
+	conflicting := func() (Fields, Fields) {
+		return Fields{FieldTitle: "local", FieldState: "待处理", FieldPriority: "NORMAL", FieldAssignee: "1"},
+			Fields{FieldTitle: "remote", FieldState: "待处理", FieldPriority: "NORMAL", FieldAssignee: "1"}
+	}
+	cases := []struct {
+		strategy      apistructs.IssueSyncConflictStrategy
+		localUpdated  time.Time
+		remoteUpdated time.Time
+		winner        string
+		title         string
+	}{
+		{apistructs.IssueSyncConflictErdaWins, earlier, later, winnerErda, "local"},
+		{apistructs.IssueSyncConflictRemoteWins, later, earlier, winnerRemote, "remote"},
+		{apistructs.IssueSyncConflictLatestWins, earlier, later, winnerRemote, "remote"},
+		{apistructs.IssueSyncConflictLatestWins, later, earlier, winnerErda, "local"},
+	}
+	for _, c := range cases {
+		local, remote := conflicting()
+		res := resolve(base, local, remote, c.strategy, c.localUpdated, c.remoteUpdated)
+		assert.Len(t, res.conflicts, 1, c.strategy)
+		assert.Equal(t, c.winner, res.conflicts[0].Winner, c.strategy)
+		assert.Equal(t, c.title, res.merged[FieldTitle], c.strategy)
+		if c.winner == winnerErda {
+			assert.Equal(t, []string{FieldTitle}, res.push)
+		} else {
+			assert.Equal(t, []string{FieldTitle}, res.pull)
+		}
+	}
+
+	t.Run("first sync without snapshot", func(t *testing.T) {
+		local := Fields{FieldTitle: "x", PropertyField("module"): "api"}
+		remote := Fields{FieldTitle: "y"}
+		res := resolve(nil, local, remote, apistructs.IssueSyncConflictErdaWins, earlier, later)
+		assert.Equal(t, []string{PropertyField("module"), FieldTitle}, res.push)
+		assert.Len(t, res.conflicts, 1)
+	})
+}
+
+func TestMapper(t *testing.T) {
+	mp := newMapper(apistructs.IssueSyncMapping{
+		Types:      map[string]string{"BUG": "Bug"},
+		States:     map[string]string{"待处理": "open", "进行中": "open", "已完成": "closed"},
+		Priorities: map[string]string{"HIGH": "High"},
+		Properties: map[string]string{"module": "customfield_10010"},
+		Users:      map[string]string{"1": "alice"},
+	})
+	local := Fields{
+		FieldTitle:              "t",
+		FieldContent:            "",
+		FieldState:              "进行中",
+		FieldPriority:           "HIGH",
+		FieldAssignee:           "1",
+		PropertyField("module"): "api",
+		PropertyField("other"):  "ignored",
+	}
+	filtered := mp.filter(local)
+	assert.NotContains(t, filtered, PropertyField("other"))
+
+	remote := mp.toRemote("BUG", filtered)
+	assert.Equal(t, "Bug", remote.Type)
+	assert.Equal(t, "open", remote.State)
+	assert.Equal(t, "High", remote.Priority)
+	assert.Equal(t, "alice", remote.Assignee)
+	assert.Equal(t, map[string]string{"customfield_10010": "api"}, remote.Custom)
+
+	// keep the current state when several erda states map to the same remote state
+	back := mp.toLocal(remote, filtered)
+	assert.Equal(t, filtered, back)
+	back = mp.toLocal(remote, nil)
+	assert.Equal(t, "待处理", back[FieldState])
+	// unmapped values are passed through
+	remote.Priority = "Low"
+	assert.Equal(t, "Low", mp.toLocal(remote, nil)[FieldPriority])
+
+	assert.Equal(t, "BUG", mp.localType("Bug", "TASK"))
+	assert.Equal(t, "TASK", mp.localType("Story", "TASK"))
+	assert.Equal(t, []string{FieldTitle, PropertyField("customfield_10010")},
+		mp.remoteFields([]string{FieldTitle, PropertyField("module")}))
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuesync
+
+import (
+	"context"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/providers/issue/dao"
+)
+
+// CreateConnection 创建同步连接, 凭证加密存储
+func (s *Service) CreateConnection(orgID uint64, req *apistructs.IssueSyncConnectionCreateRequest) (*apistructs.IssueSyncConnection, error) {
+	if err := s.checkBaseURL(context.Background(), req.BaseURL); err != nil {
+		return nil, err
+	}
+	token, err := s.encrypt(req.Token)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := s.encrypt(req.WebhookSecret)
+	if err != nil {
+		return nil, err
+	}
+	conn := &dao.IssueSyncConnection{
+		OrgID:            orgID,
+		ProjectID:        req.ProjectID,
+		Name:             req.Name,
+		Provider:         string(req.Provider),
+		BaseURL:          req.BaseURL,
+		RemoteProject:    req.RemoteProject,
+		Username:         req.Username,
+		Token:            token,
+		WebhookSecret:    secret,
+		Mapping:          dao.IssueSyncMapping(req.Mapping),
+		ConflictStrategy: string(req.ConflictStrategy),
+		Enabled:          req.Enabled,
+		Creator:          req.UserID,
+	}
+	if err := s.links.CreateIssueSyncConnection(conn); err != nil {
+		return nil, err
+	}
+	return conn.ToAPI(), nil
+}
+
+// UpdateConnection 更新同步连接, 请求中为空的字段保持不变
+func (s *Service) UpdateConnection(req *apistructs.IssueSyncConnectionUpdateRequest) (*apistructs.IssueSyncConnection, error) {
+	conn, err := s.links.GetIssueSyncConnection(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name != "" {
+		conn.Name = req.Name
+	}
+	if req.BaseURL != "" {
+		if err := s.checkBaseURL(context.Background(), req.BaseURL); err != nil {
+			return nil, err
+		}
+		conn.BaseURL = req.BaseURL
+	}
+	if req.RemoteProject != "" {
+		conn.RemoteProject = req.RemoteProject
+	}
+	if req.Username != "" {
+		conn.Username = req.Username
+	}
+	if req.Token != "" {
+		if conn.Token, err = s.encrypt(req.Token); err != nil {
+			return nil, err
+		}
+	}
+	if req.WebhookSecret != "" {
+		if conn.WebhookSecret, err = s.encrypt(req.WebhookSecret); err != nil {
+			return nil, err
+		}
+	}
+	if req.Mapping != nil {
+		conn.Mapping = dao.IssueSyncMapping(*req.Mapping)
+	}
+	if req.ConflictStrategy != "" {
+		conn.ConflictStrategy = string(req.ConflictStrategy)
+	}
+	if req.Enabled != nil {
+		conn.Enabled = *req.Enabled
+	}
+	if err := s.links.UpdateIssueSyncConnection(conn); err != nil {
+		return nil, err
+	}
+	return conn.ToAPI(), nil
+}
+
+func (s *Service) GetConnection(id uint64) (*apistructs.IssueSyncConnection, error) {
+	conn, err := s.links.GetIssueSyncConnection(id)
+	if err != nil {
+		return nil, err
+	}
+	return conn.ToAPI(), nil
+}
+
+func (s *Service) ListConnections(projectID uint64) ([]*apistructs.IssueSyncConnection, error) {
+	conns, err := s.links.ListIssueSyncConnections(projectID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*apistructs.IssueSyncConnection, 0, len(conns))
+	for i := range conns {
+		result = append(result, conns[i].ToAPI())
+	}
+	return result, nil
+}
+
+// DeleteConnection 删除同步连接及其关联关系, 双方已同步的事件保留
+func (s *Service) DeleteConnection(id uint64) error {
+	return s.links.DeleteIssueSyncConnection(id)
+}
+
+// ListLinks 查询事件在各连接下关联的外部事件
+func (s *Service) ListLinks(issueID uint64) ([]*apistructs.IssueSyncLink, error) {
+	links, err := s.links.ListIssueSyncLinksByIssue(issueID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*apistructs.IssueSyncLink, 0, len(links))
+	for i := range links {
+		if isPending(&links[i]) {
+			continue
+		}
+		result = append(result, links[i].ToAPI())
+	}
+	return result, nil
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuesync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+// WebhookEventKind 外部系统 webhook 事件类型
+type WebhookEventKind string
+
+const (
+	WebhookEventIssue   WebhookEventKind = "issue"
+	WebhookEventComment WebhookEventKind = "comment"
+	// WebhookEventIgnored 与同步无关的事件, 如 GitHub ping 或 pull request 评论
+	WebhookEventIgnored WebhookEventKind = "ignored"
+)
+
+// WebhookEvent 解析后的 webhook 事件
+type WebhookEvent struct {
+	Kind     WebhookEventKind
+	IssueKey string
+	// Issue webhook 中携带的事件内容, 可能为空
+	Issue *RemoteIssue
+}
+
+// ErrInvalidWebhook webhook 来源校验失败
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// Connector 外部事件系统的读写接口
+type Connector interface {
+	CreateIssue(ctx context.Context, issue *RemoteIssue) (*RemoteIssue, error)
+	// UpdateIssue 只更新 fields 中的字段, 自定义字段以 property.<外部字段名称> 表示
+	UpdateIssue(ctx context.Context, key string, issue *RemoteIssue, fields []string) error
+	GetIssue(ctx context.Context, key string) (*RemoteIssue, error)
+	// ListUpdatedIssues 查询 since 之后有更新的事件
+	ListUpdatedIssues(ctx context.Context, since time.Time) ([]*RemoteIssue, error)
+	ListComments(ctx context.Context, key string) ([]*RemoteComment, error)
+	AddComment(ctx context.Context, key string, body string) (*RemoteComment, error)
+	// ParseWebhook 校验并解析 webhook 请求
+	ParseWebhook(r *http.Request) (*WebhookEvent, error)
+}
+
+// ConnectorConfig 创建连接器所需的配置, Token 与 WebhookSecret 为明文
+type ConnectorConfig struct {
+	Provider      apistructs.IssueSyncProvider
+	BaseURL       string
+	RemoteProject string
+	Username      string
+	Token         string
+	WebhookSecret string
+}
+
+// NewConnector 按 provider 创建连接器
+func NewConnector(cfg ConnectorConfig, client *http.Client) (Connector, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	switch cfg.Provider {
+	case apistructs.IssueSyncProviderJira:
+		return newJiraConnector(cfg, client), nil
+	case apistructs.IssueSyncProviderGitHub:
+		return newGitHubConnector(cfg, client)
+	default:
+		return nil, errors.Errorf("unsupported issue sync provider: %s", cfg.Provider)
+	}
+}
+
+// restClient 外部系统 REST 接口的公共调用逻辑
+type restClient struct {
+	client  *http.Client
+	baseURL string
+	auth    func(req *http.Request)
+}
+
+func (c *restClient) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.baseURL, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.auth(req)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to request %s %s", method, path)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to request %s %s, status: %d, body: %s", method, path, resp.StatusCode, truncate(string(respBody), 512))
+	}
+	if result == nil || len(respBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBody, result)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+func readWebhookBody(r *http.Request) ([]byte, error) {
+	defer r.Body.Close()
+	return io.ReadAll(io.LimitReader(r.Body, 10<<20))
+}
@@ -0,0 +1,332 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuesync
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	githubDefaultBaseURL = "https://api.github.com"
+	githubPageSize       = 100
+	githubStateOpen      = "open"
+	githubStateClosed    = "closed"
+	githubTypeLabel      = "type"
+	githubPriorityLabel  = "priority"
+)
+
+// githubConnector 基于 GitHub Issues REST API. GitHub 没有类型、优先级与自定义字段,
+// 以 <字段>:<值> 形式的 label 表示, 状态只有 open 与 closed
+type githubConnector struct {
+	rest   *restClient
+	repo   string
+	secret string
+}
+
+func newGitHubConnector(cfg ConnectorConfig, client *http.Client) (*githubConnector, error) {
+	parts := strings.Split(cfg.RemoteProject, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, errors.Errorf("invalid github repository %q, must be owner/repo", cfg.RemoteProject)
+	}
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = githubDefaultBaseURL
+	}
+	return &githubConnector{
+		rest: &restClient{
+			client:  client,
+			baseURL: baseURL,
+			auth: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+cfg.Token)
+			},
+		},
+		repo:   url.PathEscape(parts[0]) + "/" + url.PathEscape(parts[1]),
+		secret: cfg.WebhookSecret,
+	}, nil
+}
+
+type githubIssue struct {
+	Number    int64  `json:"number"`
+	HTMLURL   string `json:"html_url"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	State     string `json:"state"`
+	UpdatedAt string `json:"updated_at"`
+	Labels    []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	Assignees []struct {
+		Login string `json:"login"`
+	} `json:"assignees"`
+	PullRequest json.RawMessage `json:"pull_request,omitempty"`
+}
+
+type githubComment struct {
+	ID   int64 `json:"id"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Body      string `json:"body"`
+	CreatedAt string `json:"created_at"`
+}
+
+func (g *githubConnector) issuePath(key string) string {
+	return fmt.Sprintf("/repos/%s/issues/%s", g.repo, url.PathEscape(key))
+}
+
+func githubState(state string) string {
+	if strings.EqualFold(state, githubStateClosed) {
+		return githubStateClosed
+	}
+	return githubStateOpen
+}
+
+func label(name, value string) string {
+	return name + ":" + value
+}
+
+// labels 以 issue 的类型、优先级与自定义字段替换 current 中对应前缀的 label, 其余 label 保持不变
+func (g *githubConnector) labels(current []string, issue *RemoteIssue) []string {
+	managed := map[string]string{
+		githubTypeLabel:     issue.Type,
+		githubPriorityLabel: issue.Priority,
+	}
+	for field, v := range issue.Custom {
+		managed[field] = v
+	}
+	labels := make([]string, 0, len(current)+len(managed))
+	for _, l := range current {
+		if idx := strings.Index(l, ":"); idx > 0 {
+			if _, ok := managed[l[:idx]]; ok {
+				continue
+			}
+		}
+		labels = append(labels, l)
+	}
+	for _, name := range Fields(managed).keys() {
+		if v := managed[name]; v != "" {
+			labels = append(labels, label(name, v))
+		}
+	}
+	return labels
+}
+
+func assignees(assignee string) []string {
+	if assignee == "" {
+		return []string{}
+	}
+	return []string{assignee}
+}
+
+func (g *githubConnector) CreateIssue(ctx context.Context, issue *RemoteIssue) (*RemoteIssue, error) {
+	var created githubIssue
+	if err := g.rest.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/issues", g.repo), map[string]interface{}{
+		"title":     issue.Title,
+		"body":      issue.Content,
+		"labels":    g.labels(nil, issue),
+		"assignees": assignees(issue.Assignee),
+	}, &created); err != nil {
+		return nil, err
+	}
+	key := strconv.FormatInt(created.Number, 10)
+	if githubState(issue.State) == githubStateClosed {
+		if err := g.rest.do(ctx, http.MethodPatch, g.issuePath(key), map[string]string{"state": githubStateClosed}, &created); err != nil {
+			return nil, err
+		}
+	}
+	return convertGitHubIssue(&created), nil
+}
+
+func (g *githubConnector) UpdateIssue(ctx context.Context, key string, issue *RemoteIssue, fields []string) error {
+	updates := make(map[string]interface{})
+	updateLabels := false
+	for _, f := range fields {
+		switch f {
+		case FieldTitle:
+			updates["title"] = issue.Title
+		case FieldContent:
+			updates["body"] = issue.Content
+		case FieldState:
+			updates["state"] = githubState(issue.State)
+		case FieldAssignee:
+			updates["assignees"] = assignees(issue.Assignee)
+		default:
+			updateLabels = true
+		}
+	}
+	if updateLabels {
+		var current githubIssue
+		if err := g.rest.do(ctx, http.MethodGet, g.issuePath(key), nil, &current); err != nil {
+			return err
+		}
+		names := make([]string, 0, len(current.Labels))
+		for _, l := range current.Labels {
+			names = append(names, l.Name)
+		}
+		updates["labels"] = g.labels(names, issue)
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return g.rest.do(ctx, http.MethodPatch, g.issuePath(key), updates, nil)
+}
+
+func (g *githubConnector) GetIssue(ctx context.Context, key string) (*RemoteIssue, error) {
+	var issue githubIssue
+	if err := g.rest.do(ctx, http.MethodGet, g.issuePath(key), nil, &issue); err != nil {
+		return nil, err
+	}
+	return convertGitHubIssue(&issue), nil
+}
+
+func (g *githubConnector) ListUpdatedIssues(ctx context.Context, since time.Time) ([]*RemoteIssue, error) {
+	var issues []*RemoteIssue
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("state", "all")
+		query.Set("sort", "updated")
+		query.Set("direction", "asc")
+		query.Set("since", since.UTC().Format(time.RFC3339))
+		query.Set("per_page", strconv.Itoa(githubPageSize))
+		query.Set("page", strconv.Itoa(page))
+		var resp []githubIssue
+		if err := g.rest.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/issues?%s", g.repo, query.Encode()), nil, &resp); err != nil {
+			return nil, err
+		}
+		for i := range resp {
+			// the issues api also returns pull requests
+			if len(resp[i].PullRequest) > 0 {
+				continue
+			}
+			issues = append(issues, convertGitHubIssue(&resp[i]))
+		}
+		if len(resp) < githubPageSize {
+			return issues, nil
+		}
+	}
+}
+
+func (g *githubConnector) ListComments(ctx context.Context, key string) ([]*RemoteComment, error) {
+	var comments []*RemoteComment
+	for page := 1; ; page++ {
+		var resp []githubComment
+		path := fmt.Sprintf("%s/comments?per_page=%d&page=%d", g.issuePath(key), githubPageSize, page)
+		if err := g.rest.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+			return nil, err
+		}
+		for i := range resp {
+			comments = append(comments, convertGitHubComment(&resp[i]))
+		}
+		if len(resp) < githubPageSize {
+			return comments, nil
+		}
+	}
+}
+
+func (g *githubConnector) AddComment(ctx context.Context, key string, body string) (*RemoteComment, error) {
+	var comment githubComment
+	if err := g.rest.do(ctx, http.MethodPost, g.issuePath(key)+"/comments", map[string]string{"body": body}, &comment); err != nil {
+		return nil, err
+	}
+	return convertGitHubComment(&comment), nil
+}
+
+// ParseWebhook 校验 X-Hub-Signature-256 签名, 未配置 secret 时拒绝所有请求
+func (g *githubConnector) ParseWebhook(r *http.Request) (*WebhookEvent, error) {
+	if g.secret == "" {
+		return nil, errors.Wrap(ErrInvalidWebhook, "github webhook secret is not configured")
+	}
+	body, err := readWebhookBody(r)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, []byte(g.secret))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Hub-Signature-256"))) {
+		return nil, errors.Wrap(ErrInvalidWebhook, "github webhook signature mismatch")
+	}
+	event := r.Header.Get("X-GitHub-Event")
+	if event != "issues" && event != "issue_comment" {
+		return &WebhookEvent{Kind: WebhookEventIgnored}, nil
+	}
+	var payload struct {
+		Issue *githubIssue `json:"issue"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, errors.Wrap(err, "invalid github webhook payload")
+	}
+	if payload.Issue == nil || len(payload.Issue.PullRequest) > 0 {
+		return &WebhookEvent{Kind: WebhookEventIgnored}, nil
+	}
+	issue := convertGitHubIssue(payload.Issue)
+	if event == "issue_comment" {
+		return &WebhookEvent{Kind: WebhookEventComment, IssueKey: issue.Key}, nil
+	}
+	return &WebhookEvent{Kind: WebhookEventIssue, IssueKey: issue.Key, Issue: issue}, nil
+}
+
+func convertGitHubIssue(issue *githubIssue) *RemoteIssue {
+	r := &RemoteIssue{
+		Key:     strconv.FormatInt(issue.Number, 10),
+		URL:     issue.HTMLURL,
+		Title:   issue.Title,
+		Content: issue.Body,
+		State:   issue.State,
+		Custom:  make(map[string]string),
+	}
+	r.UpdatedAt, _ = time.Parse(time.RFC3339, issue.UpdatedAt)
+	if len(issue.Assignees) > 0 {
+		r.Assignee = issue.Assignees[0].Login
+	}
+	for _, l := range issue.Labels {
+		idx := strings.Index(l.Name, ":")
+		if idx <= 0 {
+			continue
+		}
+		name, value := l.Name[:idx], l.Name[idx+1:]
+		switch name {
+		case githubTypeLabel:
+			r.Type = value
+		case githubPriorityLabel:
+			r.Priority = value
+		default:
+			r.Custom[name] = value
+		}
+	}
+	return r
+}
+
+func convertGitHubComment(c *githubComment) *RemoteComment {
+	created, _ := time.Parse(time.RFC3339, c.CreatedAt)
+	return &RemoteComment{
+		ID:        strconv.FormatInt(c.ID, 10),
+		Author:    c.User.Login,
+		Body:      c.Body,
+		CreatedAt: created,
+	}
+}
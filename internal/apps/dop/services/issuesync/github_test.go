@@ -0,0 +1,165 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuesync
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+func TestGitHubConnector(t *testing.T) {
+	issue := map[string]interface{}{
+		"number":     42,
+		"html_url":   "https://github.com/erda/demo/issues/42",
+		"title":      "t",
+		"body":       "b",
+		"state":      "open",
+		"updated_at": "2026-10-01T10:00:00Z",
+		"labels":     []map[string]string{{"name": "help wanted"}, {"name": "type:bug"}, {"name": "priority:high"}},
+		"assignees":  []map[string]string{{"login": "alice"}},
+	}
+	var patched map[string]interface{}
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer ghp", r.Header.Get("Authorization"))
+		switch {
+		case r.URL.Path == "/repos/erda/demo/issues" && r.Method == http.MethodGet:
+			query = r.URL.RawQuery
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				issue,
+				{"number": 43, "title": "pr", "state": "open", "pull_request": map[string]string{"url": "x"}},
+			})
+		case r.URL.Path == "/repos/erda/demo/issues/42" && r.Method == http.MethodGet:
+			json.NewEncoder(w).Encode(issue)
+		case r.URL.Path == "/repos/erda/demo/issues/42" && r.Method == http.MethodPatch:
+			json.NewDecoder(r.Body).Decode(&patched)
+			json.NewEncoder(w).Encode(issue)
+		case r.URL.Path == "/repos/erda/demo/issues/42/comments" && r.Method == http.MethodPost:
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": 7, "user": map[string]string{"login": "bot"}, "body": "hi"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c, err := NewConnector(ConnectorConfig{
+		Provider:      apistructs.IssueSyncProviderGitHub,
+		BaseURL:       server.URL,
+		RemoteProject: "erda/demo",
+		Token:         "ghp",
+	}, server.Client())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	got, err := c.GetIssue(ctx, "42")
+	require.NoError(t, err)
+	assert.Equal(t, "42", got.Key)
+	assert.Equal(t, "bug", got.Type)
+	assert.Equal(t, "high", got.Priority)
+	assert.Equal(t, "alice", got.Assignee)
+	assert.Equal(t, "open", got.State)
+	assert.False(t, got.UpdatedAt.IsZero())
+
+	issues, err := c.ListUpdatedIssues(ctx, got.UpdatedAt)
+	require.NoError(t, err)
+	require.Len(t, issues, 1, "pull requests are skipped")
+	assert.Contains(t, query, "since=2026-10-01T10%3A00%3A00Z")
+	assert.Contains(t, query, "state=all")
+
+	got.State = "已完成"
+	require.NoError(t, c.UpdateIssue(ctx, "42", got, []string{FieldState}))
+	assert.Equal(t, map[string]interface{}{"state": "open"}, patched, "only closed closes the github issue")
+
+	got.State = "closed"
+	got.Priority = "low"
+	got.Custom = map[string]string{"module": "api"}
+	require.NoError(t, c.UpdateIssue(ctx, "42", got, []string{FieldState, FieldPriority, PropertyField("module")}))
+	assert.Equal(t, "closed", patched["state"])
+	assert.Equal(t, []interface{}{"help wanted", "module:api", "priority:low", "type:bug"}, patched["labels"])
+
+	comment, err := c.AddComment(ctx, "42", "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "7", comment.ID)
+
+	_, err = NewConnector(ConnectorConfig{Provider: apistructs.IssueSyncProviderGitHub, RemoteProject: "demo"}, nil)
+	assert.Error(t, err)
+}
+
+func TestGitHubConnector_ParseWebhook(t *testing.T) {
+	c, err := NewConnector(ConnectorConfig{
+		Provider:      apistructs.IssueSyncProviderGitHub,
+		RemoteProject: "erda/demo",
+		WebhookSecret: "s3cret",
+	}, nil)
+	require.NoError(t, err)
+
+	sign := func(body string) string {
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	newRequest := func(event, body, signature string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-Hub-Signature-256", signature)
+		return req
+	}
+
+	body := `{"action":"edited","issue":{"number":42,"title":"t","state":"closed","labels":[{"name":"type:bug"}]}}`
+	_, err = c.ParseWebhook(newRequest("issues", body, "sha256=00"))
+	assert.ErrorIs(t, err, ErrInvalidWebhook)
+
+	event, err := c.ParseWebhook(newRequest("issues", body, sign(body)))
+	require.NoError(t, err)
+	assert.Equal(t, WebhookEventIssue, event.Kind)
+	assert.Equal(t, "42", event.IssueKey)
+	assert.Equal(t, "closed", event.Issue.State)
+
+	body = `{"action":"created","issue":{"number":42},"comment":{"id":1}}`
+	event, err = c.ParseWebhook(newRequest("issue_comment", body, sign(body)))
+	require.NoError(t, err)
+	assert.Equal(t, WebhookEventComment, event.Kind)
+
+	body = `{"action":"created","issue":{"number":43,"pull_request":{"url":"x"}},"comment":{"id":1}}`
+	event, err = c.ParseWebhook(newRequest("issue_comment", body, sign(body)))
+	require.NoError(t, err)
+	assert.Equal(t, WebhookEventIgnored, event.Kind)
+
+	body = `{"zen":"hello"}`
+	event, err = c.ParseWebhook(newRequest("ping", body, sign(body)))
+	require.NoError(t, err)
+	assert.Equal(t, WebhookEventIgnored, event.Kind)
+
+	// unsigned requests are rejected when no secret is configured
+	unsigned, err := NewConnector(ConnectorConfig{
+		Provider:      apistructs.IssueSyncProviderGitHub,
+		RemoteProject: "erda/demo",
+	}, nil)
+	require.NoError(t, err)
+	_, err = unsigned.ParseWebhook(newRequest("issues", body, ""))
+	assert.ErrorIs(t, err, ErrInvalidWebhook)
+}
@@ -0,0 +1,385 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuesync
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	jiraTimeLayout   = "2006-01-02T15:04:05.000-0700"
+	jiraPageSize     = 100
+	jiraOptionSuffix = ":option"
+)
+
+// jiraConnector 基于 Jira REST API v2, 兼容 Jira Cloud 与 Jira Server/Data Center
+type jiraConnector struct {
+	rest       *restClient
+	baseURL    string
+	projectKey string
+	secret     string
+	now        func() time.Time
+}
+
+func newJiraConnector(cfg ConnectorConfig, client *http.Client) *jiraConnector {
+	return &jiraConnector{
+		rest: &restClient{
+			client:  client,
+			baseURL: cfg.BaseURL,
+			auth: func(req *http.Request) {
+				if cfg.Username != "" {
+					req.SetBasicAuth(cfg.Username, cfg.Token)
+					return
+				}
+				req.Header.Set("Authorization", "Bearer "+cfg.Token)
+			},
+		},
+		baseURL:    strings.TrimSuffix(cfg.BaseURL, "/"),
+		projectKey: cfg.RemoteProject,
+		secret:     cfg.WebhookSecret,
+		now:        time.Now,
+	}
+}
+
+type jiraIssue struct {
+	ID     string                     `json:"id"`
+	Key    string                     `json:"key"`
+	Fields map[string]json.RawMessage `json:"fields"`
+}
+
+type jiraNamed struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	DisplayName string `json:"displayName"`
+}
+
+type jiraComment struct {
+	ID      string    `json:"id"`
+	Author  jiraNamed `json:"author"`
+	Body    string    `json:"body"`
+	Created string    `json:"created"`
+}
+
+// jiraCustomField 映射中的外部字段形如 customfield_10010 或 customfield_10010:option, 后者为下拉字段
+func jiraCustomField(remoteField string) (string, bool) {
+	if strings.HasSuffix(remoteField, jiraOptionSuffix) {
+		return strings.TrimSuffix(remoteField, jiraOptionSuffix), true
+	}
+	return remoteField, false
+}
+
+func (j *jiraConnector) issueURL(key string) string {
+	return j.baseURL + "/browse/" + key
+}
+
+func (j *jiraConnector) fields(issue *RemoteIssue, only []string) map[string]interface{} {
+	want := func(f string) bool {
+		if only == nil {
+			return true
+		}
+		for _, o := range only {
+			if o == f {
+				return true
+			}
+		}
+		return false
+	}
+	fields := make(map[string]interface{})
+	if want(FieldTitle) {
+		fields["summary"] = issue.Title
+	}
+	if want(FieldContent) {
+		fields["description"] = issue.Content
+	}
+	if want(FieldPriority) && issue.Priority != "" {
+		fields["priority"] = map[string]string{"name": issue.Priority}
+	}
+	if want(FieldAssignee) {
+		if issue.Assignee == "" {
+			fields["assignee"] = nil
+		} else {
+			fields["assignee"] = map[string]string{"name": issue.Assignee}
+		}
+	}
+	for remoteField, v := range issue.Custom {
+		if !want(PropertyField(remoteField)) {
+			continue
+		}
+		id, option := jiraCustomField(remoteField)
+		if option {
+			fields[id] = map[string]string{"value": v}
+		} else {
+			fields[id] = v
+		}
+	}
+	return fields
+}
+
+func (j *jiraConnector) CreateIssue(ctx context.Context, issue *RemoteIssue) (*RemoteIssue, error) {
+	fields := j.fields(issue, nil)
+	fields["project"] = map[string]string{"key": j.projectKey}
+	fields["issuetype"] = map[string]string{"name": issue.Type}
+	var created jiraIssue
+	if err := j.rest.do(ctx, http.MethodPost, "/rest/api/2/issue", map[string]interface{}{"fields": fields}, &created); err != nil {
+		return nil, err
+	}
+	current, err := j.GetIssue(ctx, created.Key)
+	if err != nil {
+		return nil, err
+	}
+	if issue.State != "" && !strings.EqualFold(current.State, issue.State) {
+		if err := j.transition(ctx, created.Key, issue.State); err != nil {
+			return nil, err
+		}
+		return j.GetIssue(ctx, created.Key)
+	}
+	return current, nil
+}
+
+func (j *jiraConnector) UpdateIssue(ctx context.Context, key string, issue *RemoteIssue, fields []string) error {
+	updates := j.fields(issue, fields)
+	if len(updates) > 0 {
+		if err := j.rest.do(ctx, http.MethodPut, "/rest/api/2/issue/"+url.PathEscape(key),
+			map[string]interface{}{"fields": updates}, nil); err != nil {
+			return err
+		}
+	}
+	for _, f := range fields {
+		if f == FieldState {
+			return j.transition(ctx, key, issue.State)
+		}
+	}
+	return nil
+}
+
+// transition Jira 状态只能通过工作流迁移修改, 按目标状态名称查找可用的迁移
+func (j *jiraConnector) transition(ctx context.Context, key, state string) error {
+	var resp struct {
+		Transitions []struct {
+			ID   string    `json:"id"`
+			Name string    `json:"name"`
+			To   jiraNamed `json:"to"`
+		} `json:"transitions"`
+	}
+	path := "/rest/api/2/issue/" + url.PathEscape(key) + "/transitions"
+	if err := j.rest.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return err
+	}
+	for _, t := range resp.Transitions {
+		if strings.EqualFold(t.To.Name, state) || strings.EqualFold(t.Name, state) {
+			return j.rest.do(ctx, http.MethodPost, path, map[string]interface{}{
+				"transition": map[string]string{"id": t.ID},
+			}, nil)
+		}
+	}
+	return errors.Errorf("no transition to state %q available for jira issue %s", state, key)
+}
+
+func (j *jiraConnector) GetIssue(ctx context.Context, key string) (*RemoteIssue, error) {
+	var issue jiraIssue
+	if err := j.rest.do(ctx, http.MethodGet, "/rest/api/2/issue/"+url.PathEscape(key), nil, &issue); err != nil {
+		return nil, err
+	}
+	return j.convert(&issue), nil
+}
+
+func (j *jiraConnector) ListUpdatedIssues(ctx context.Context, since time.Time) ([]*RemoteIssue, error) {
+	// relative time keeps the query independent of the jira user's timezone
+	minutes := int(math.Ceil(j.now().Sub(since).Minutes())) + 1
+	jql := fmt.Sprintf(`project = "%s" AND updated >= "-%dm" ORDER BY updated ASC`, j.projectKey, minutes)
+	var issues []*RemoteIssue
+	for startAt := 0; ; {
+		var resp struct {
+			Total  int         `json:"total"`
+			Issues []jiraIssue `json:"issues"`
+		}
+		query := url.Values{}
+		query.Set("jql", jql)
+		query.Set("startAt", fmt.Sprint(startAt))
+		query.Set("maxResults", fmt.Sprint(jiraPageSize))
+		if err := j.rest.do(ctx, http.MethodGet, "/rest/api/2/search?"+query.Encode(), nil, &resp); err != nil {
+			return nil, err
+		}
+		for i := range resp.Issues {
+			issues = append(issues, j.convert(&resp.Issues[i]))
+		}
+		startAt += len(resp.Issues)
+		if len(resp.Issues) == 0 || startAt >= resp.Total {
+			return issues, nil
+		}
+	}
+}
+
+func (j *jiraConnector) ListComments(ctx context.Context, key string) ([]*RemoteComment, error) {
+	var comments []*RemoteComment
+	for startAt := 0; ; {
+		var resp struct {
+			Total    int           `json:"total"`
+			Comments []jiraComment `json:"comments"`
+		}
+		path := fmt.Sprintf("/rest/api/2/issue/%s/comment?startAt=%d&maxResults=%d", url.PathEscape(key), startAt, jiraPageSize)
+		if err := j.rest.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+			return nil, err
+		}
+		for i := range resp.Comments {
+			comments = append(comments, convertJiraComment(&resp.Comments[i]))
+		}
+		startAt += len(resp.Comments)
+		if len(resp.Comments) == 0 || startAt >= resp.Total {
+			return comments, nil
+		}
+	}
+}
+
+func (j *jiraConnector) AddComment(ctx context.Context, key string, body string) (*RemoteComment, error) {
+	var comment jiraComment
+	if err := j.rest.do(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(key)+"/comment",
+		map[string]string{"body": body}, &comment); err != nil {
+		return nil, err
+	}
+	return convertJiraComment(&comment), nil
+}
+
+// ParseWebhook Jira webhook 不签名, 通过回调地址中的 secret 参数校验来源, 未配置 secret 时拒绝所有请求
+func (j *jiraConnector) ParseWebhook(r *http.Request) (*WebhookEvent, error) {
+	if j.secret == "" {
+		return nil, errors.Wrap(ErrInvalidWebhook, "jira webhook secret is not configured")
+	}
+	if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("secret")), []byte(j.secret)) != 1 {
+		return nil, errors.Wrap(ErrInvalidWebhook, "jira webhook secret mismatch")
+	}
+	body, err := readWebhookBody(r)
+	if err != nil {
+		return nil, err
+	}
+	var payload struct {
+		WebhookEvent string     `json:"webhookEvent"`
+		Issue        *jiraIssue `json:"issue"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, errors.Wrap(err, "invalid jira webhook payload")
+	}
+	if payload.Issue == nil || payload.Issue.Key == "" {
+		return &WebhookEvent{Kind: WebhookEventIgnored}, nil
+	}
+	switch payload.WebhookEvent {
+	case "jira:issue_created", "jira:issue_updated":
+		return &WebhookEvent{Kind: WebhookEventIssue, IssueKey: payload.Issue.Key, Issue: j.convert(payload.Issue)}, nil
+	case "comment_created", "comment_updated":
+		return &WebhookEvent{Kind: WebhookEventComment, IssueKey: payload.Issue.Key}, nil
+	default:
+		return &WebhookEvent{Kind: WebhookEventIgnored, IssueKey: payload.Issue.Key}, nil
+	}
+}
+
+func (j *jiraConnector) convert(issue *jiraIssue) *RemoteIssue {
+	r := &RemoteIssue{
+		Key:    issue.Key,
+		URL:    j.issueURL(issue.Key),
+		Custom: make(map[string]string),
+	}
+	for name, raw := range issue.Fields {
+		switch name {
+		case "summary":
+			r.Title = jiraString(raw)
+		case "description":
+			r.Content = jiraString(raw)
+		case "status":
+			r.State = jiraString(raw)
+		case "priority":
+			r.Priority = jiraString(raw)
+		case "assignee":
+			r.Assignee = jiraString(raw)
+		case "issuetype":
+			r.Type = jiraString(raw)
+		case "updated":
+			r.UpdatedAt = parseJiraTime(jiraString(raw))
+		default:
+			if strings.HasPrefix(name, "customfield_") {
+				if v := jiraString(raw); v != "" {
+					r.Custom[name] = v
+					r.Custom[name+jiraOptionSuffix] = v
+				}
+			}
+		}
+	}
+	return r
+}
+
+// jiraString 将 Jira 字段值统一转换为字符串, 对象类型取 name 或 value
+func jiraString(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var named jiraNamed
+	if err := json.Unmarshal(raw, &named); err == nil {
+		if named.Name != "" {
+			return named.Name
+		}
+		return named.Value
+	}
+	var strs []string
+	if err := json.Unmarshal(raw, &strs); err == nil {
+		return strings.Join(strs, ",")
+	}
+	var values []jiraNamed
+	if err := json.Unmarshal(raw, &values); err == nil {
+		names := make([]string, 0, len(values))
+		for _, v := range values {
+			if v.Value != "" {
+				names = append(names, v.Value)
+			} else {
+				names = append(names, v.Name)
+			}
+		}
+		return strings.Join(names, ",")
+	}
+	return strings.Trim(string(raw), `"`)
+}
+
+func parseJiraTime(s string) time.Time {
+	t, err := time.Parse(jiraTimeLayout, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func convertJiraComment(c *jiraComment) *RemoteComment {
+	author := c.Author.DisplayName
+	if author == "" {
+		author = c.Author.Name
+	}
+	return &RemoteComment{
+		ID:        c.ID,
+		Author:    author,
+		Body:      c.Body,
+		CreatedAt: parseJiraTime(c.Created),
+	}
+}
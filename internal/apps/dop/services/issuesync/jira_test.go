@@ -0,0 +1,313 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuesync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+// fakeJira 内存实现的 Jira REST API v2 桩服务
+type fakeJira struct {
+	mu       sync.Mutex
+	seq      int
+	issues   map[string]map[string]interface{}
+	comments map[string][]map[string]interface{}
+	jql      string
+	now      time.Time
+}
+
+func newFakeJira() (*fakeJira, *httptest.Server) {
+	j := &fakeJira{
+		issues:   make(map[string]map[string]interface{}),
+		comments: make(map[string][]map[string]interface{}),
+		now:      time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC),
+	}
+	return j, httptest.NewServer(j)
+}
+
+func (j *fakeJira) touch(fields map[string]interface{}) {
+	j.now = j.now.Add(time.Minute)
+	fields["updated"] = j.now.Format(jiraTimeLayout)
+}
+
+func (j *fakeJira) add(fields map[string]interface{}) string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.seq++
+	key := fmt.Sprintf("ERDA-%d", j.seq)
+	if _, ok := fields["status"]; !ok {
+		fields["status"] = map[string]string{"name": "To Do"}
+	}
+	j.touch(fields)
+	j.issues[key] = fields
+	return key
+}
+
+func (j *fakeJira) field(key, name string) interface{} {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.issues[key][name]
+}
+
+func (j *fakeJira) set(key string, fields map[string]interface{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for k, v := range fields {
+		j.issues[key][k] = v
+	}
+	j.touch(j.issues[key])
+}
+
+func (j *fakeJira) write(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (j *fakeJira) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if user, pass, ok := r.BasicAuth(); !ok || user != "bot" || pass != "token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/rest/api/2/")
+	parts := strings.Split(path, "/")
+	switch {
+	case path == "issue" && r.Method == http.MethodPost:
+		var req struct {
+			Fields map[string]interface{} `json:"fields"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		j.seq++
+		key := fmt.Sprintf("ERDA-%d", j.seq)
+		req.Fields["status"] = map[string]string{"name": "To Do"}
+		j.touch(req.Fields)
+		j.issues[key] = req.Fields
+		j.write(w, map[string]string{"id": fmt.Sprint(j.seq), "key": key})
+	case path == "search":
+		j.jql = r.URL.Query().Get("jql")
+		var issues []map[string]interface{}
+		for i := 1; i <= j.seq; i++ {
+			key := fmt.Sprintf("ERDA-%d", i)
+			if fields, ok := j.issues[key]; ok {
+				issues = append(issues, map[string]interface{}{"key": key, "fields": fields})
+			}
+		}
+		j.write(w, map[string]interface{}{"total": len(issues), "issues": issues})
+	case len(parts) == 2 && parts[0] == "issue":
+		fields, ok := j.issues[parts[1]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodPut {
+			var req struct {
+				Fields map[string]interface{} `json:"fields"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			for k, v := range req.Fields {
+				fields[k] = v
+			}
+			j.touch(fields)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		j.write(w, map[string]interface{}{"key": parts[1], "fields": fields})
+	case len(parts) == 3 && parts[2] == "transitions":
+		if r.Method == http.MethodGet {
+			j.write(w, map[string]interface{}{"transitions": []map[string]interface{}{
+				{"id": "11", "name": "Start", "to": map[string]string{"name": "In Progress"}},
+				{"id": "21", "name": "Finish", "to": map[string]string{"name": "Done"}},
+			}})
+			return
+		}
+		var req struct {
+			Transition struct {
+				ID string `json:"id"`
+			} `json:"transition"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		names := map[string]string{"11": "In Progress", "21": "Done"}
+		j.issues[parts[1]]["status"] = map[string]string{"name": names[req.Transition.ID]}
+		j.touch(j.issues[parts[1]])
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 3 && parts[2] == "comment":
+		if r.Method == http.MethodPost {
+			var req map[string]string
+			json.NewDecoder(r.Body).Decode(&req)
+			c := map[string]interface{}{
+				"id":      fmt.Sprintf("c%d", len(j.comments[parts[1]])+1),
+				"author":  map[string]string{"name": "bot"},
+				"body":    req["body"],
+				"created": j.now.Format(jiraTimeLayout),
+			}
+			j.comments[parts[1]] = append(j.comments[parts[1]], c)
+			j.write(w, c)
+			return
+		}
+		j.write(w, map[string]interface{}{"total": len(j.comments[parts[1]]), "comments": j.comments[parts[1]]})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (j *fakeJira) addComment(key, author, body string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.comments[key] = append(j.comments[key], map[string]interface{}{
+		"id":      fmt.Sprintf("c%d", len(j.comments[key])+1),
+		"author":  map[string]string{"name": author, "displayName": author},
+		"body":    body,
+		"created": j.now.Format(jiraTimeLayout),
+	})
+}
+
+func newTestJiraConnector(t *testing.T, server *httptest.Server) Connector {
+	c, err := NewConnector(ConnectorConfig{
+		Provider:      apistructs.IssueSyncProviderJira,
+		BaseURL:       server.URL,
+		RemoteProject: "ERDA",
+		Username:      "bot",
+		Token:         "token",
+		WebhookSecret: "s3cret",
+	}, server.Client())
+	require.NoError(t, err)
+	return c
+}
+
+func TestJiraConnector(t *testing.T) {
+	jira, server := newFakeJira()
+	defer server.Close()
+	c := newTestJiraConnector(t, server)
+	ctx := context.Background()
+
+	created, err := c.CreateIssue(ctx, &RemoteIssue{
+		Type:     "Bug",
+		Title:    "login failed",
+		Content:  "steps",
+		State:    "In Progress",
+		Priority: "High",
+		Assignee: "alice",
+		Custom:   map[string]string{"customfield_1": "api", "customfield_2:option": "P1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ERDA-1", created.Key)
+	assert.Equal(t, server.URL+"/browse/ERDA-1", created.URL)
+	assert.Equal(t, "In Progress", created.State)
+	assert.Equal(t, "High", created.Priority)
+	assert.Equal(t, "alice", created.Assignee)
+	assert.Equal(t, "Bug", created.Type)
+	assert.Equal(t, "api", created.Custom["customfield_1"])
+	assert.Equal(t, "P1", created.Custom["customfield_2:option"])
+	assert.False(t, created.UpdatedAt.IsZero())
+	assert.Equal(t, map[string]interface{}{"key": "ERDA"}, jira.field("ERDA-1", "project"))
+
+	created.Title = "login fails on safari"
+	created.State = "Done"
+	created.Content = "ignored"
+	require.NoError(t, c.UpdateIssue(ctx, "ERDA-1", created, []string{FieldTitle, FieldState}))
+	got, err := c.GetIssue(ctx, "ERDA-1")
+	require.NoError(t, err)
+	assert.Equal(t, "login fails on safari", got.Title)
+	assert.Equal(t, "steps", got.Content)
+	assert.Equal(t, "Done", got.State)
+
+	got.State = "Closed"
+	assert.Error(t, c.UpdateIssue(ctx, "ERDA-1", got, []string{FieldState}))
+
+	now := time.Now()
+	c.(*jiraConnector).now = func() time.Time { return now }
+	issues, err := c.ListUpdatedIssues(ctx, now.Add(-30*time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, issues, 1)
+	assert.Equal(t, `project = "ERDA" AND updated >= "-31m" ORDER BY updated ASC`, jira.jql)
+
+	_, err = c.AddComment(ctx, "ERDA-1", "hello")
+	require.NoError(t, err)
+	comments, err := c.ListComments(ctx, "ERDA-1")
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+	assert.Equal(t, "hello", comments[0].Body)
+	assert.Equal(t, "bot", comments[0].Author)
+}
+
+func TestJiraConnector_ParseWebhook(t *testing.T) {
+	_, server := newFakeJira()
+	defer server.Close()
+	c := newTestJiraConnector(t, server)
+
+	payload := `{"webhookEvent":"jira:issue_updated","issue":{"key":"ERDA-7","fields":{"summary":"s","status":{"name":"Done"}}}}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook?secret=wrong", strings.NewReader(payload))
+	_, err := c.ParseWebhook(req)
+	assert.ErrorIs(t, err, ErrInvalidWebhook)
+
+	req = httptest.NewRequest(http.MethodPost, "/webhook?secret=s3cret", strings.NewReader(payload))
+	event, err := c.ParseWebhook(req)
+	require.NoError(t, err)
+	assert.Equal(t, WebhookEventIssue, event.Kind)
+	assert.Equal(t, "ERDA-7", event.IssueKey)
+	assert.Equal(t, "Done", event.Issue.State)
+
+	req = httptest.NewRequest(http.MethodPost, "/webhook?secret=s3cret",
+		strings.NewReader(`{"webhookEvent":"comment_created","issue":{"key":"ERDA-7"},"comment":{"id":"1"}}`))
+	event, err = c.ParseWebhook(req)
+	require.NoError(t, err)
+	assert.Equal(t, WebhookEventComment, event.Kind)
+
+	req = httptest.NewRequest(http.MethodPost, "/webhook?secret=s3cret",
+		strings.NewReader(`{"webhookEvent":"jira:issue_deleted","issue":{"key":"ERDA-7"}}`))
+	event, err = c.ParseWebhook(req)
+	require.NoError(t, err)
+	assert.Equal(t, WebhookEventIgnored, event.Kind)
+
+	// requests without secret are rejected when no secret is configured
+	unsigned, err := NewConnector(ConnectorConfig{
+		Provider:      apistructs.IssueSyncProviderJira,
+		BaseURL:       server.URL,
+		RemoteProject: "ERDA",
+		Token:         "token",
+	}, server.Client())
+	require.NoError(t, err)
+	_, err = unsigned.ParseWebhook(httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(payload)))
+	assert.ErrorIs(t, err, ErrInvalidWebhook)
+}
+
+func TestJiraString(t *testing.T) {
+	cases := map[string]string{
+		`null`:                         "",
+		`"text"`:                       "text",
+		`{"name":"High"}`:              "High",
+		`{"value":"P1","id":"1"}`:      "P1",
+		`["a","b"]`:                    "a,b",
+		`[{"value":"x"},{"name":"y"}]`: "x,y",
+		`3`:                            "3",
+	}
+	for raw, want := range cases {
+		assert.Equal(t, want, jiraString(json.RawMessage(raw)), raw)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuesync
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+// mapper 按连接配置在 Erda 取值与外部系统取值之间转换, 未配置映射的取值原样透传
+type mapper struct {
+	m apistructs.IssueSyncMapping
+}
+
+func newMapper(m apistructs.IssueSyncMapping) mapper {
+	return mapper{m: m}
+}
+
+func lookup(m map[string]string, k string) string {
+	if v, ok := m[k]; ok && v != "" {
+		return v
+	}
+	return k
+}
+
+// reverse 将外部取值转换为 Erda 取值, 多个 Erda 取值映射到同一外部取值时优先保留当前值
+func reverse(m map[string]string, remote, current string) string {
+	if remote == "" {
+		return ""
+	}
+	if current != "" && strings.EqualFold(lookup(m, current), remote) {
+		return current
+	}
+	var candidates []string
+	for k, v := range m {
+		if strings.EqualFold(v, remote) {
+			candidates = append(candidates, k)
+		}
+	}
+	if len(candidates) == 0 {
+		return remote
+	}
+	sort.Strings(candidates)
+	return candidates[0]
+}
+
+// filter 只保留参与同步的字段, 自定义字段需在映射中配置
+func (mp mapper) filter(f Fields) Fields {
+	filtered := make(Fields, len(f))
+	for k, v := range f {
+		if name, ok := propertyName(k); ok {
+			if _, mapped := mp.m.Properties[name]; !mapped {
+				continue
+			}
+		}
+		filtered[k] = v
+	}
+	return filtered
+}
+
+func (mp mapper) toRemote(issueType string, f Fields) *RemoteIssue {
+	r := &RemoteIssue{
+		Type:     lookup(mp.m.Types, issueType),
+		Title:    f[FieldTitle],
+		Content:  f[FieldContent],
+		State:    lookup(mp.m.States, f[FieldState]),
+		Priority: lookup(mp.m.Priorities, f[FieldPriority]),
+		Assignee: lookup(mp.m.Users, f[FieldAssignee]),
+		Custom:   make(map[string]string),
+	}
+	for name, remoteField := range mp.m.Properties {
+		if v, ok := f[PropertyField(name)]; ok {
+			r.Custom[remoteField] = v
+		}
+	}
+	return r
+}
+
+// toLocal 将外部事件转换为 Erda 取值, current 为 Erda 侧当前取值, 用于消除多对一映射的歧义
+func (mp mapper) toLocal(r *RemoteIssue, current Fields) Fields {
+	f := Fields{
+		FieldTitle:    r.Title,
+		FieldContent:  r.Content,
+		FieldState:    reverse(mp.m.States, r.State, current[FieldState]),
+		FieldPriority: reverse(mp.m.Priorities, r.Priority, current[FieldPriority]),
+		FieldAssignee: reverse(mp.m.Users, r.Assignee, current[FieldAssignee]),
+	}
+	for name, remoteField := range mp.m.Properties {
+		if v, ok := r.Custom[remoteField]; ok {
+			f[PropertyField(name)] = v
+		}
+	}
+	return f
+}
+
+func (mp mapper) localType(remoteType, defaultType string) string {
+	if remoteType == "" {
+		return defaultType
+	}
+	t := reverse(mp.m.Types, remoteType, "")
+	for _, valid := range apistructs.IssueTypes {
+		if string(valid) == t {
+			return t
+		}
+	}
+	return defaultType
+}
+
+// remoteFields 将同步字段名称转换为连接器使用的名称, 自定义字段转换为外部字段名称
+func (mp mapper) remoteFields(fields []string) []string {
+	result := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if name, ok := propertyName(f); ok {
+			result = append(result, PropertyField(lookup(mp.m.Properties, name)))
+			continue
+		}
+		result = append(result, f)
+	}
+	return result
+}
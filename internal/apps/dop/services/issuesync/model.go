@@ -0,0 +1,106 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuesync
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// 同步字段名称, 自定义字段以 property. 为前缀
+const (
+	FieldTitle    = "title"
+	FieldContent  = "content"
+	FieldState    = "state"
+	FieldPriority = "priority"
+	FieldAssignee = "assignee"
+
+	propertyFieldPrefix = "property."
+)
+
+// PropertyField 返回自定义字段的同步字段名称
+func PropertyField(name string) string {
+	return propertyFieldPrefix + name
+}
+
+func propertyName(field string) (string, bool) {
+	if !strings.HasPrefix(field, propertyFieldPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(field, propertyFieldPrefix), true
+}
+
+// Fields 同步字段值, 统一使用 Erda 侧取值
+type Fields map[string]string
+
+func (f Fields) keys() []string {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (f Fields) subset(keys []string) Fields {
+	sub := make(Fields, len(keys))
+	for _, k := range keys {
+		sub[k] = f[k]
+	}
+	return sub
+}
+
+// LocalIssue Erda 侧事件
+type LocalIssue struct {
+	ID        uint64
+	ProjectID uint64
+	Type      string
+	Creator   string
+	Fields    Fields
+	UpdatedAt time.Time
+}
+
+// LocalComment Erda 侧评论
+type LocalComment struct {
+	ID        uint64
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
+// RemoteIssue 外部系统事件, 字段使用外部系统取值
+type RemoteIssue struct {
+	// Key Jira issue key 或 GitHub issue number
+	Key      string
+	URL      string
+	Type     string
+	Title    string
+	Content  string
+	State    string
+	Priority string
+	Assignee string
+	// Custom 自定义字段, key 为外部字段名称
+	Custom    map[string]string
+	UpdatedAt time.Time
+}
+
+// RemoteComment 外部系统评论
+type RemoteComment struct {
+	ID        string
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
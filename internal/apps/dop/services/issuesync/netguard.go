@@ -0,0 +1,86 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuesync
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// deniedIP 外部系统地址不允许指向回环, 链路本地(含云厂商元数据地址), 未指定及组播地址,
+// 未开启 allowPrivate 时同样不允许内网地址
+func deniedIP(ip net.IP, allowPrivate bool) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	return !allowPrivate && ip.IsPrivate()
+}
+
+// checkBaseURL 解析外部系统地址的域名, 任一地址被禁止时返回错误
+func checkBaseURL(ctx context.Context, baseURL string, allowPrivate bool) error {
+	if baseURL == "" {
+		return nil
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return err
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("failed to resolve baseURL host %s: %v", u.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		if deniedIP(addr.IP, allowPrivate) {
+			return fmt.Errorf("baseURL host %s resolves to a forbidden address %s", u.Hostname(), addr.IP)
+		}
+	}
+	return nil
+}
+
+// newGuardedHTTPClient 在建立连接时校验实际拨号的地址, 防止域名重新解析到内部地址;
+// 不使用代理, 保证校验的是目标地址
+func newGuardedHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || deniedIP(ip, allowPrivate) {
+				return fmt.Errorf("dial to forbidden address %s", address)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+	}
+}
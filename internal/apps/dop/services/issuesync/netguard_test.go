@@ -0,0 +1,65 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuesync
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeniedIP(t *testing.T) {
+	cases := []struct {
+		ip           string
+		allowPrivate bool
+		denied       bool
+	}{
+		{"127.0.0.1", true, true},
+		{"::1", true, true},
+		{"169.254.169.254", true, true},
+		{"0.0.0.0", true, true},
+		{"224.0.0.1", true, true},
+		{"10.0.0.1", false, true},
+		{"192.168.1.1", false, true},
+		{"10.0.0.1", true, false},
+		{"140.82.112.6", false, false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.denied, deniedIP(net.ParseIP(c.ip), c.allowPrivate), c.ip)
+	}
+}
+
+func TestCheckBaseURL(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, checkBaseURL(ctx, "", false))
+	assert.NoError(t, checkBaseURL(ctx, "https://140.82.112.6", false))
+	assert.Error(t, checkBaseURL(ctx, "http://127.0.0.1:8080", true))
+	assert.Error(t, checkBaseURL(ctx, "http://169.254.169.254/latest/meta-data", true))
+	assert.Error(t, checkBaseURL(ctx, "https://10.0.0.1", false))
+	assert.NoError(t, checkBaseURL(ctx, "https://10.0.0.1", true))
+}
+
+func TestGuardedHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := newGuardedHTTPClient(time.Second, true).Get(server.URL)
+	assert.Error(t, err)
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package issuesync 将项目事件与外部事件系统(Jira/GitHub Issues)双向同步.
+// 外部系统通过 webhook 推送变更, 同时按周期轮询双方有更新的事件作为兜底;
+// 每个关联保存上次同步时双方一致的字段快照, 用于三方合并与冲突处理.
+package issuesync
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/providers/issue/dao"
+	"github.com/erda-project/erda/pkg/crypto/encryption"
+)
+
+// IssueStore Erda 侧事件的读写接口
+type IssueStore interface {
+	GetIssue(id uint64) (*LocalIssue, error)
+	CreateIssue(orgID uint64, issue *LocalIssue) (uint64, error)
+	// UpdateIssue 只更新 fields 中的字段
+	UpdateIssue(orgID uint64, issue *LocalIssue, fields Fields, operator string) error
+	ListUpdatedIssueIDs(projectID uint64, since time.Time) ([]uint64, error)
+	ListComments(issueID uint64) ([]*LocalComment, error)
+	AddComment(issueID uint64, body, operator string) (uint64, error)
+}
+
+// LinkStore 同步连接及关联关系的存储接口
+type LinkStore interface {
+	CreateIssueSyncConnection(conn *dao.IssueSyncConnection) error
+	UpdateIssueSyncConnection(conn *dao.IssueSyncConnection) error
+	DeleteIssueSyncConnection(id uint64) error
+	GetIssueSyncConnection(id uint64) (*dao.IssueSyncConnection, error)
+	ListIssueSyncConnections(projectID uint64) ([]dao.IssueSyncConnection, error)
+	ListEnabledIssueSyncConnections() ([]dao.IssueSyncConnection, error)
+	UpdateIssueSyncConnectionPolledAt(id uint64, polledAt time.Time) error
+	ClaimIssueSyncLink(link *dao.IssueSyncLink) (bool, error)
+	SaveIssueSyncLink(link *dao.IssueSyncLink) error
+	DeleteIssueSyncLink(id uint64) error
+	GetIssueSyncLinkByIssue(connectionID, issueID uint64) (*dao.IssueSyncLink, error)
+	GetIssueSyncLinkByRemote(connectionID uint64, remoteKey string) (*dao.IssueSyncLink, error)
+	ListIssueSyncLinksByIssue(issueID uint64) ([]dao.IssueSyncLink, error)
+	ListIssueSyncComments(connectionID, issueID uint64) ([]dao.IssueSyncComment, error)
+	CreateIssueSyncComment(comment *dao.IssueSyncComment) error
+}
+
+// SecretCrypt 连接凭证的加解密
+type SecretCrypt interface {
+	Encrypt(src string, outputDataType encryption.Encode) (string, error)
+	Decrypt(src string, srcType encryption.Encode) (string, error)
+}
+
+type Service struct {
+	links      LinkStore
+	issues     IssueStore
+	crypt      SecretCrypt
+	httpClient *http.Client
+	now        func() time.Time
+	// allowPrivateNetwork 是否允许外部系统地址指向内网, 用于私有化部署的 Jira
+	allowPrivateNetwork bool
+	checkBaseURL        func(ctx context.Context, baseURL string) error
+}
+
+type Option func(*Service)
+
+func New(options ...Option) *Service {
+	s := &Service{
+		now: time.Now,
+	}
+	for _, op := range options {
+		op(s)
+	}
+	if s.httpClient == nil {
+		s.httpClient = newGuardedHTTPClient(30*time.Second, s.allowPrivateNetwork)
+	}
+	s.checkBaseURL = func(ctx context.Context, baseURL string) error {
+		return checkBaseURL(ctx, baseURL, s.allowPrivateNetwork)
+	}
+	return s
+}
+
+// WithDBClient 使用事件 db 作为事件及关联关系的存储
+func WithDBClient(db *dao.DBClient) Option {
+	return func(s *Service) {
+		s.links = db
+		s.issues = &issueStore{db: db}
+	}
+}
+
+func WithLinkStore(links LinkStore) Option {
+	return func(s *Service) {
+		s.links = links
+	}
+}
+
+func WithIssueStore(issues IssueStore) Option {
+	return func(s *Service) {
+		s.issues = issues
+	}
+}
+
+func WithSecretCrypt(crypt SecretCrypt) Option {
+	return func(s *Service) {
+		s.crypt = crypt
+	}
+}
+
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Service) {
+		s.httpClient = client
+	}
+}
+
+func WithAllowPrivateNetwork(allow bool) Option {
+	return func(s *Service) {
+		s.allowPrivateNetwork = allow
+	}
+}
+
+// Run 按 interval 轮询所有启用的连接, ctx 结束后退出
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.pollAll(ctx)
+		}
+	}
+}
+
+func (s *Service) pollAll(ctx context.Context) {
+	conns, err := s.links.ListEnabledIssueSyncConnections()
+	if err != nil {
+		logrus.Errorf("failed to list issue sync connections, err: %v", err)
+		return
+	}
+	for i := range conns {
+		result, err := s.Poll(ctx, &conns[i])
+		if err != nil {
+			logrus.Errorf("failed to poll issue sync connection %d, err: %v", conns[i].ID, err)
+			continue
+		}
+		for _, e := range result.Errors {
+			logrus.Warnf("issue sync connection %d: %s", conns[i].ID, e)
+		}
+	}
+}
+
+func (s *Service) connector(conn *dao.IssueSyncConnection) (Connector, error) {
+	token, err := s.decrypt(conn.Token)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := s.decrypt(conn.WebhookSecret)
+	if err != nil {
+		return nil, err
+	}
+	return NewConnector(ConnectorConfig{
+		Provider:      apistructs.IssueSyncProvider(conn.Provider),
+		BaseURL:       conn.BaseURL,
+		RemoteProject: conn.RemoteProject,
+		Username:      conn.Username,
+		Token:         token,
+		WebhookSecret: secret,
+	}, s.httpClient)
+}
+
+func (s *Service) encrypt(plain string) (string, error) {
+	if plain == "" || s.crypt == nil {
+		return plain, nil
+	}
+	return s.crypt.Encrypt(plain, encryption.Base64)
+}
+
+func (s *Service) decrypt(encrypted string) (string, error) {
+	if encrypted == "" || s.crypt == nil {
+		return encrypted, nil
+	}
+	return s.crypt.Decrypt(encrypted, encryption.Base64)
+}
@@ -0,0 +1,362 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuesync
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/providers/issue/dao"
+	"github.com/erda-project/erda/internal/apps/dop/providers/issue/stream/common"
+)
+
+// issueStore 基于事件 db 实现 IssueStore, 变更同时记录活动及状态流转
+type issueStore struct {
+	db *dao.DBClient
+}
+
+func (s *issueStore) GetIssue(id uint64) (*LocalIssue, error) {
+	issue, err := s.db.GetIssue(int64(id))
+	if err != nil {
+		return nil, err
+	}
+	state, err := s.db.GetIssueStateByID(issue.State)
+	if err != nil {
+		return nil, err
+	}
+	fields := Fields{
+		FieldTitle:    issue.Title,
+		FieldContent:  issue.Content,
+		FieldState:    state.Name,
+		FieldPriority: issue.Priority,
+		FieldAssignee: issue.Assignee,
+	}
+	properties, err := s.properties(id)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range properties {
+		fields[PropertyField(name)] = value
+	}
+	return &LocalIssue{
+		ID:        issue.ID,
+		ProjectID: issue.ProjectID,
+		Type:      issue.Type,
+		Creator:   issue.Creator,
+		Fields:    fields,
+		UpdatedAt: issue.UpdatedAt,
+	}, nil
+}
+
+// properties 返回事件自定义字段的取值, 多选字段以逗号分隔
+func (s *issueStore) properties(issueID uint64) (map[string]string, error) {
+	relations, err := s.db.GetPropertyRelationByID(int64(issueID))
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int64]string)
+	values := make(map[string][]string)
+	for _, r := range relations {
+		name, ok := names[r.PropertyID]
+		if !ok {
+			property, err := s.db.GetIssuePropertyByID(r.PropertyID)
+			if err != nil {
+				return nil, err
+			}
+			name = property.PropertyName
+			names[r.PropertyID] = name
+		}
+		value := r.ArbitraryValue
+		if r.PropertyValueID != 0 {
+			v, err := s.db.GetIssuePropertyValue(r.PropertyValueID)
+			if err != nil {
+				return nil, err
+			}
+			value = v.Name
+		}
+		values[name] = append(values[name], value)
+	}
+	result := make(map[string]string, len(values))
+	for name, vs := range values {
+		result[name] = strings.Join(vs, ",")
+	}
+	return result, nil
+}
+
+func (s *issueStore) stateByName(projectID uint64, issueType, name string) (*dao.IssueState, error) {
+	states, err := s.db.GetIssuesStatesByProjectID(projectID, issueType)
+	if err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, errors.Errorf("no state defined for issue type %s", issueType)
+	}
+	if name == "" {
+		return &states[0], nil
+	}
+	for i := range states {
+		if strings.EqualFold(states[i].Name, name) {
+			return &states[i], nil
+		}
+	}
+	return nil, errors.Errorf("state %q not found for issue type %s", name, issueType)
+}
+
+func checkPriority(priority string) error {
+	switch apistructs.IssuePriority(priority) {
+	case apistructs.IssuePriorityUrgent, apistructs.IssuePriorityHigh, apistructs.IssuePriorityNormal, apistructs.IssuePriorityLow:
+		return nil
+	default:
+		return errors.Errorf("invalid priority %q", priority)
+	}
+}
+
+func (s *issueStore) CreateIssue(orgID uint64, issue *LocalIssue) (uint64, error) {
+	state, err := s.stateByName(issue.ProjectID, issue.Type, issue.Fields[FieldState])
+	if err != nil {
+		return 0, err
+	}
+	priority := issue.Fields[FieldPriority]
+	if checkPriority(priority) != nil {
+		priority = string(apistructs.IssuePriorityNormal)
+	}
+	assignee := issue.Fields[FieldAssignee]
+	if assignee == "" {
+		assignee = issue.Creator
+	}
+	create := dao.Issue{
+		ProjectID:   issue.ProjectID,
+		IterationID: apistructs.UnassignedIterationID,
+		Type:        issue.Type,
+		Title:       issue.Fields[FieldTitle],
+		Content:     issue.Fields[FieldContent],
+		State:       int64(state.ID),
+		Priority:    priority,
+		Complexity:  string(apistructs.IssueComplexityNormal),
+		Severity:    string(apistructs.IssueSeverityNormal),
+		Creator:     issue.Creator,
+		Assignee:    assignee,
+		External:    true,
+	}
+	if issue.Type == string(apistructs.IssueTypeBug) {
+		create.Owner = assignee
+	}
+	if err := s.db.CreateIssue(&create); err != nil {
+		return 0, err
+	}
+	if err := s.db.BatchCreateIssueSubscribers([]dao.IssueSubscriber{{IssueID: int64(create.ID), UserID: issue.Creator}}); err != nil {
+		return 0, err
+	}
+	if err := s.db.CreateIssueStream(&dao.IssueStream{
+		IssueID:      int64(create.ID),
+		Operator:     issue.Creator,
+		StreamType:   common.ISTCreate,
+		StreamParams: common.ISTParam{UserName: issue.Creator},
+	}); err != nil {
+		return 0, err
+	}
+	if err := s.db.CreateIssueStateTransition(&dao.IssueStateTransition{
+		ProjectID: create.ProjectID,
+		IssueID:   create.ID,
+		StateTo:   uint64(create.State),
+		Creator:   create.Creator,
+	}); err != nil {
+		return 0, err
+	}
+	for _, k := range issue.Fields.keys() {
+		if name, ok := propertyName(k); ok {
+			if err := s.setProperty(orgID, &create, name, issue.Fields[k]); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return create.ID, nil
+}
+
+func (s *issueStore) UpdateIssue(orgID uint64, local *LocalIssue, fields Fields, operator string) error {
+	issue, err := s.db.GetIssue(int64(local.ID))
+	if err != nil {
+		return err
+	}
+	updates := make(map[string]interface{})
+	var streams []dao.IssueStream
+	addStream := func(streamType string, params common.ISTParam) {
+		streams = append(streams, dao.IssueStream{
+			IssueID:      int64(issue.ID),
+			Operator:     operator,
+			StreamType:   streamType,
+			StreamParams: params,
+		})
+	}
+	var transition *dao.IssueStateTransition
+	for _, k := range fields.keys() {
+		v := fields[k]
+		switch k {
+		case FieldTitle:
+			updates["title"] = v
+			addStream(common.ISTChangeTitle, common.ISTParam{CurrentTitle: issue.Title, NewTitle: v})
+		case FieldContent:
+			updates["content"] = v
+			addStream(common.ISTChangeContent, common.ISTParam{})
+		case FieldPriority:
+			if err := checkPriority(v); err != nil {
+				return err
+			}
+			updates["priority"] = v
+			addStream(common.ISTChangePriority, common.ISTParam{CurrentPriority: issue.Priority, NewPriority: v})
+		case FieldAssignee:
+			updates["assignee"] = v
+			addStream(common.ISTChangeAssignee, common.ISTParam{CurrentAssignee: issue.Assignee, NewAssignee: v})
+		case FieldState:
+			state, err := s.stateByName(issue.ProjectID, issue.Type, v)
+			if err != nil {
+				return err
+			}
+			updates["state"] = int64(state.ID)
+			addStream(common.ISTTransferState, common.ISTParam{CurrentState: local.Fields[FieldState], NewState: state.Name})
+			transition = &dao.IssueStateTransition{
+				ProjectID: issue.ProjectID,
+				IssueID:   issue.ID,
+				StateFrom: uint64(issue.State),
+				StateTo:   uint64(state.ID),
+				Creator:   operator,
+			}
+		default:
+			if name, ok := propertyName(k); ok {
+				if err := s.setProperty(orgID, &issue, name, v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if len(updates) > 0 {
+		if err := s.db.UpdateIssue(issue.ID, updates); err != nil {
+			return err
+		}
+	} else {
+		// custom fields only, still mark the issue as updated
+		if err := s.db.UpdateIssue(issue.ID, map[string]interface{}{"updated_at": time.Now()}); err != nil {
+			return err
+		}
+	}
+	if transition != nil {
+		if err := s.db.CreateIssueStateTransition(transition); err != nil {
+			return err
+		}
+	}
+	if len(streams) > 0 {
+		return s.db.BatchCreateIssueStream(streams)
+	}
+	return nil
+}
+
+// setProperty 替换事件自定义字段的取值, 枚举字段按名称匹配枚举值
+func (s *issueStore) setProperty(orgID uint64, issue *dao.Issue, name, value string) error {
+	property, err := s.db.GetIssuePropertyByName(int64(orgID), name, issue.Type)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get property %s", name)
+	}
+	if err := s.db.DeletePropertyRelationsByPropertyID(int64(issue.ID), int64(property.ID)); err != nil {
+		return err
+	}
+	if value == "" {
+		return nil
+	}
+	base := dao.IssuePropertyRelation{
+		OrgID:      int64(orgID),
+		ProjectID:  int64(issue.ProjectID),
+		IssueID:    int64(issue.ID),
+		PropertyID: int64(property.ID),
+	}
+	enums, err := s.db.GetIssuePropertyValues(int64(property.ID))
+	if err != nil {
+		return err
+	}
+	if len(enums) == 0 {
+		base.ArbitraryValue = value
+		return s.db.CreatePropertyRelation(&base)
+	}
+	var relations []dao.IssuePropertyRelation
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		matched := false
+		for _, enum := range enums {
+			if strings.EqualFold(enum.Name, v) {
+				r := base
+				r.PropertyValueID = int64(enum.ID)
+				relations = append(relations, r)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return errors.Errorf("value %q not found for property %s", v, name)
+		}
+	}
+	return s.db.CreatePropertyRelations(relations)
+}
+
+func (s *issueStore) ListUpdatedIssueIDs(projectID uint64, since time.Time) ([]uint64, error) {
+	updated, err := s.db.ListIssueIDsUpdatedSince(projectID, since)
+	if err != nil {
+		return nil, err
+	}
+	commented, err := s.db.ListIssueIDsCommentedSince(projectID, since)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[uint64]bool, len(updated)+len(commented))
+	ids := make([]uint64, 0, len(updated)+len(commented))
+	for _, id := range append(updated, commented...) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (s *issueStore) ListComments(issueID uint64) ([]*LocalComment, error) {
+	streams, err := s.db.ListIssueCommentStreams(issueID)
+	if err != nil {
+		return nil, err
+	}
+	comments := make([]*LocalComment, 0, len(streams))
+	for _, st := range streams {
+		comments = append(comments, &LocalComment{
+			ID:        st.ID,
+			Author:    st.Operator,
+			Body:      st.StreamParams.Comment,
+			CreatedAt: st.CreatedAt,
+		})
+	}
+	return comments, nil
+}
+
+func (s *issueStore) AddComment(issueID uint64, body, operator string) (uint64, error) {
+	stream := &dao.IssueStream{
+		IssueID:      int64(issueID),
+		Operator:     operator,
+		StreamType:   common.ISTComment,
+		StreamParams: common.ISTParam{Comment: body},
+	}
+	if err := s.db.CreateIssueStream(stream); err != nil {
+		return 0, err
+	}
+	return stream.ID, nil
+}
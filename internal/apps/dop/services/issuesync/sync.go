@@ -0,0 +1,417 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuesync
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/providers/issue/dao"
+)
+
+const (
+	// pollOverlap 轮询时间窗口的重叠, 容忍双方时钟误差, 重复处理由快照保证幂等
+	pollOverlap = time.Minute
+	// pendingKeyPrefix 正在外部系统创建事件时关联记录使用的占位 key
+	pendingKeyPrefix = "erda-pending-"
+	// syncedCommentPrefix 同步产生的评论前缀, 用于避免评论被同步回来源系统
+	syncedCommentPrefix = "[synced from "
+	erdaSource          = "erda"
+)
+
+// syncer 一次同步过程的上下文
+type syncer struct {
+	s         *Service
+	conn      *dao.IssueSyncConnection
+	connector Connector
+	mapper    mapper
+	result    *apistructs.IssueSyncResult
+	synced    map[uint64]bool
+}
+
+func (s *Service) newSyncer(conn *dao.IssueSyncConnection) (*syncer, error) {
+	connector, err := s.connector(conn)
+	if err != nil {
+		return nil, err
+	}
+	return &syncer{
+		s:         s,
+		conn:      conn,
+		connector: connector,
+		mapper:    newMapper(apistructs.IssueSyncMapping(conn.Mapping)),
+		result:    &apistructs.IssueSyncResult{},
+		synced:    make(map[uint64]bool),
+	}, nil
+}
+
+func (sy *syncer) addError(format string, args ...interface{}) {
+	sy.result.Errors = append(sy.result.Errors, fmt.Sprintf(format, args...))
+}
+
+func pendingKey(issueID uint64) string {
+	return fmt.Sprintf("%s%d", pendingKeyPrefix, issueID)
+}
+
+func isPending(link *dao.IssueSyncLink) bool {
+	return link.IssueID == 0 || strings.HasPrefix(link.RemoteKey, pendingKeyPrefix)
+}
+
+// Poll 同步连接在上次轮询后双方有更新的事件.
+// 外部新建的事件会在 Erda 创建; Erda 新建的事件需通过 PushIssue 推送.
+func (s *Service) Poll(ctx context.Context, conn *dao.IssueSyncConnection) (*apistructs.IssueSyncResult, error) {
+	sy, err := s.newSyncer(conn)
+	if err != nil {
+		return nil, err
+	}
+	start := s.now()
+	since := conn.CreatedAt
+	if conn.LastPolledAt != nil {
+		since = *conn.LastPolledAt
+	}
+	since = since.Add(-pollOverlap)
+
+	remotes, err := sy.connector.ListUpdatedIssues(ctx, since)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list updated remote issues")
+	}
+	for _, remote := range remotes {
+		if err := sy.syncRemote(ctx, remote); err != nil {
+			sy.addError("remote issue %s: %v", remote.Key, err)
+		}
+	}
+
+	ids, err := s.issues.ListUpdatedIssueIDs(conn.ProjectID, since)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list updated issues")
+	}
+	for _, id := range ids {
+		if sy.synced[id] {
+			continue
+		}
+		link, err := s.links.GetIssueSyncLinkByIssue(conn.ID, id)
+		if err != nil {
+			sy.addError("issue %d: %v", id, err)
+			continue
+		}
+		if link == nil || isPending(link) {
+			continue
+		}
+		if err := sy.syncLink(ctx, link); err != nil {
+			sy.addError("issue %d: %v", id, err)
+		}
+	}
+
+	if err := s.links.UpdateIssueSyncConnectionPolledAt(conn.ID, start); err != nil {
+		return nil, err
+	}
+	conn.LastPolledAt = &start
+	return sy.result, nil
+}
+
+// PollByID 手动触发一次轮询
+func (s *Service) PollByID(ctx context.Context, connectionID uint64) (*apistructs.IssueSyncResult, error) {
+	conn, err := s.links.GetIssueSyncConnection(connectionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Poll(ctx, conn)
+}
+
+// PushIssue 将 Erda 事件同步到外部系统, 尚未关联时在外部系统创建
+func (s *Service) PushIssue(ctx context.Context, connectionID, issueID uint64) (*apistructs.IssueSyncResult, error) {
+	conn, err := s.links.GetIssueSyncConnection(connectionID)
+	if err != nil {
+		return nil, err
+	}
+	local, err := s.issues.GetIssue(issueID)
+	if err != nil {
+		return nil, err
+	}
+	if local.ProjectID != conn.ProjectID {
+		return nil, errors.Errorf("issue %d does not belong to project %d", issueID, conn.ProjectID)
+	}
+	sy, err := s.newSyncer(conn)
+	if err != nil {
+		return nil, err
+	}
+	link, err := s.links.GetIssueSyncLinkByIssue(conn.ID, issueID)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		err = sy.export(ctx, local)
+	} else if !isPending(link) {
+		err = sy.syncLink(ctx, link)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sy.result, nil
+}
+
+// HandleWebhook 处理外部系统推送的 webhook
+func (s *Service) HandleWebhook(ctx context.Context, connectionID uint64, r *http.Request) (*apistructs.IssueSyncResult, error) {
+	conn, err := s.links.GetIssueSyncConnection(connectionID)
+	if err != nil {
+		return nil, err
+	}
+	if !conn.Enabled {
+		return nil, errors.Errorf("issue sync connection %d is disabled", connectionID)
+	}
+	sy, err := s.newSyncer(conn)
+	if err != nil {
+		return nil, err
+	}
+	event, err := sy.connector.ParseWebhook(r)
+	if err != nil {
+		return nil, err
+	}
+	switch event.Kind {
+	case WebhookEventIssue:
+		// webhooks may arrive out of order, always sync with the latest remote state
+		remote, err := sy.connector.GetIssue(ctx, event.IssueKey)
+		if err != nil {
+			return nil, err
+		}
+		if err := sy.syncRemote(ctx, remote); err != nil {
+			return nil, err
+		}
+	case WebhookEventComment:
+		link, err := s.links.GetIssueSyncLinkByRemote(conn.ID, event.IssueKey)
+		if err != nil {
+			return nil, err
+		}
+		if link != nil && !isPending(link) {
+			if err := sy.syncComments(ctx, link); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sy.result, nil
+}
+
+// syncRemote 处理外部事件的变更, 已关联时合并, 未关联时在 Erda 创建
+func (sy *syncer) syncRemote(ctx context.Context, remote *RemoteIssue) error {
+	link, err := sy.s.links.GetIssueSyncLinkByRemote(sy.conn.ID, remote.Key)
+	if err != nil {
+		return err
+	}
+	if link == nil {
+		return sy.importRemote(ctx, remote)
+	}
+	if isPending(link) {
+		return nil
+	}
+	local, err := sy.s.issues.GetIssue(link.IssueID)
+	if err != nil {
+		return err
+	}
+	return sy.merge(ctx, link, local, remote)
+}
+
+func (sy *syncer) syncLink(ctx context.Context, link *dao.IssueSyncLink) error {
+	local, err := sy.s.issues.GetIssue(link.IssueID)
+	if err != nil {
+		return err
+	}
+	remote, err := sy.connector.GetIssue(ctx, link.RemoteKey)
+	if err != nil {
+		return err
+	}
+	return sy.merge(ctx, link, local, remote)
+}
+
+// importRemote 在 Erda 创建外部事件, 先占用关联记录避免多实例重复创建
+func (sy *syncer) importRemote(ctx context.Context, remote *RemoteIssue) error {
+	link := &dao.IssueSyncLink{
+		ConnectionID: sy.conn.ID,
+		RemoteKey:    remote.Key,
+		RemoteURL:    remote.URL,
+	}
+	claimed, err := sy.s.links.ClaimIssueSyncLink(link)
+	if err != nil || !claimed {
+		return err
+	}
+	issue := &LocalIssue{
+		ProjectID: sy.conn.ProjectID,
+		Type:      sy.mapper.localType(remote.Type, string(apistructs.IssueTypeTask)),
+		Creator:   sy.conn.Creator,
+		Fields:    sy.mapper.toLocal(remote, nil),
+	}
+	id, err := sy.s.issues.CreateIssue(sy.conn.OrgID, issue)
+	if err != nil {
+		sy.releaseLink(link)
+		return err
+	}
+	local, err := sy.s.issues.GetIssue(id)
+	if err != nil {
+		return err
+	}
+	link.IssueID = id
+	if err := sy.saveLink(link, sy.mapper.filter(local.Fields), remote); err != nil {
+		return err
+	}
+	sy.result.Created++
+	return sy.syncComments(ctx, link)
+}
+
+// export 在外部系统创建 Erda 事件
+func (sy *syncer) export(ctx context.Context, local *LocalIssue) error {
+	link := &dao.IssueSyncLink{
+		ConnectionID: sy.conn.ID,
+		IssueID:      local.ID,
+		RemoteKey:    pendingKey(local.ID),
+	}
+	claimed, err := sy.s.links.ClaimIssueSyncLink(link)
+	if err != nil || !claimed {
+		return err
+	}
+	fields := sy.mapper.filter(local.Fields)
+	remote, err := sy.connector.CreateIssue(ctx, sy.mapper.toRemote(local.Type, fields))
+	if err != nil {
+		sy.releaseLink(link)
+		return err
+	}
+	link.RemoteKey = remote.Key
+	link.RemoteURL = remote.URL
+	if err := sy.saveLink(link, fields, remote); err != nil {
+		return err
+	}
+	sy.result.Created++
+	return sy.syncComments(ctx, link)
+}
+
+func (sy *syncer) releaseLink(link *dao.IssueSyncLink) {
+	if err := sy.s.links.DeleteIssueSyncLink(link.ID); err != nil {
+		logrus.Errorf("failed to release issue sync link %d, err: %v", link.ID, err)
+	}
+}
+
+func (sy *syncer) saveLink(link *dao.IssueSyncLink, snapshot Fields, remote *RemoteIssue) error {
+	now := sy.s.now()
+	link.Snapshot = dao.IssueSyncSnapshot(snapshot)
+	link.SyncedAt = &now
+	if !remote.UpdatedAt.IsZero() {
+		updatedAt := remote.UpdatedAt
+		link.RemoteUpdatedAt = &updatedAt
+	}
+	if remote.URL != "" {
+		link.RemoteURL = remote.URL
+	}
+	sy.synced[link.IssueID] = true
+	return sy.s.links.SaveIssueSyncLink(link)
+}
+
+// merge 以关联快照为基准三方合并, 将各自的变更写入对方
+func (sy *syncer) merge(ctx context.Context, link *dao.IssueSyncLink, local *LocalIssue, remote *RemoteIssue) error {
+	localFields := sy.mapper.filter(local.Fields)
+	remoteFields := sy.mapper.toLocal(remote, localFields)
+	res := resolve(Fields(link.Snapshot), localFields, remoteFields,
+		apistructs.IssueSyncConflictStrategy(sy.conn.ConflictStrategy), local.UpdatedAt, remote.UpdatedAt)
+
+	if len(res.push) > 0 {
+		if err := sy.connector.UpdateIssue(ctx, link.RemoteKey, sy.mapper.toRemote(local.Type, res.merged),
+			sy.mapper.remoteFields(res.push)); err != nil {
+			return errors.Wrapf(err, "failed to update remote issue %s", link.RemoteKey)
+		}
+		sy.result.Pushed++
+	}
+	if len(res.pull) > 0 {
+		if err := sy.s.issues.UpdateIssue(sy.conn.OrgID, local, res.merged.subset(res.pull), sy.conn.Creator); err != nil {
+			return errors.Wrapf(err, "failed to update issue %d", local.ID)
+		}
+		sy.result.Pulled++
+	}
+	for _, c := range res.conflicts {
+		c.IssueID = local.ID
+		c.RemoteKey = link.RemoteKey
+		sy.result.Conflicts = append(sy.result.Conflicts, c)
+	}
+	if err := sy.saveLink(link, res.merged, remote); err != nil {
+		return err
+	}
+	return sy.syncComments(ctx, link)
+}
+
+func syncedComment(source, author, body string) string {
+	return fmt.Sprintf("%s%s by %s]\n%s", syncedCommentPrefix, source, author, body)
+}
+
+// syncComments 双向补齐未同步的评论, 评论只新增不更新
+func (sy *syncer) syncComments(ctx context.Context, link *dao.IssueSyncLink) error {
+	linked, err := sy.s.links.ListIssueSyncComments(sy.conn.ID, link.IssueID)
+	if err != nil {
+		return err
+	}
+	localLinked := make(map[uint64]bool, len(linked))
+	remoteLinked := make(map[string]bool, len(linked))
+	for _, c := range linked {
+		localLinked[c.StreamID] = true
+		remoteLinked[c.RemoteCommentID] = true
+	}
+	localComments, err := sy.s.issues.ListComments(link.IssueID)
+	if err != nil {
+		return err
+	}
+	remoteComments, err := sy.connector.ListComments(ctx, link.RemoteKey)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range localComments {
+		if localLinked[c.ID] || strings.HasPrefix(c.Body, syncedCommentPrefix) {
+			continue
+		}
+		rc, err := sy.connector.AddComment(ctx, link.RemoteKey, syncedComment(erdaSource, c.Author, c.Body))
+		if err != nil {
+			return errors.Wrapf(err, "failed to add comment to remote issue %s", link.RemoteKey)
+		}
+		if err := sy.s.links.CreateIssueSyncComment(&dao.IssueSyncComment{
+			ConnectionID:    sy.conn.ID,
+			IssueID:         link.IssueID,
+			StreamID:        c.ID,
+			RemoteCommentID: rc.ID,
+		}); err != nil {
+			return err
+		}
+		sy.result.Comments++
+	}
+	for _, c := range remoteComments {
+		if remoteLinked[c.ID] || strings.HasPrefix(c.Body, syncedCommentPrefix) {
+			continue
+		}
+		streamID, err := sy.s.issues.AddComment(link.IssueID, syncedComment(sy.conn.Provider, c.Author, c.Body), sy.conn.Creator)
+		if err != nil {
+			return errors.Wrapf(err, "failed to add comment to issue %d", link.IssueID)
+		}
+		if err := sy.s.links.CreateIssueSyncComment(&dao.IssueSyncComment{
+			ConnectionID:    sy.conn.ID,
+			IssueID:         link.IssueID,
+			StreamID:        streamID,
+			RemoteCommentID: c.ID,
+		}); err != nil {
+			return err
+		}
+		sy.result.Comments++
+	}
+	return nil
+}
@@ -0,0 +1,444 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package issuesync
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/apps/dop/providers/issue/dao"
+)
+
+// memStore 内存实现的 IssueStore 与 LinkStore
+type memStore struct {
+	mu       sync.Mutex
+	seq      uint64
+	now      time.Time
+	conns    map[uint64]*dao.IssueSyncConnection
+	links    map[uint64]*dao.IssueSyncLink
+	comments []dao.IssueSyncComment
+	issues   map[uint64]*LocalIssue
+	streams  map[uint64][]*LocalComment
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		now:     time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC),
+		conns:   make(map[uint64]*dao.IssueSyncConnection),
+		links:   make(map[uint64]*dao.IssueSyncLink),
+		issues:  make(map[uint64]*LocalIssue),
+		streams: make(map[uint64][]*LocalComment),
+	}
+}
+
+func (m *memStore) nextID() uint64 {
+	m.seq++
+	return m.seq
+}
+
+func (m *memStore) GetIssue(id uint64) (*LocalIssue, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	issue, ok := m.issues[id]
+	if !ok {
+		return nil, errors.Errorf("issue %d not found", id)
+	}
+	cp := *issue
+	cp.Fields = make(Fields, len(issue.Fields))
+	for k, v := range issue.Fields {
+		cp.Fields[k] = v
+	}
+	return &cp, nil
+}
+
+func (m *memStore) CreateIssue(orgID uint64, issue *LocalIssue) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp := *issue
+	cp.ID = m.nextID()
+	cp.UpdatedAt = m.now
+	m.issues[cp.ID] = &cp
+	return cp.ID, nil
+}
+
+func (m *memStore) UpdateIssue(orgID uint64, issue *LocalIssue, fields Fields, operator string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k, v := range fields {
+		m.issues[issue.ID].Fields[k] = v
+	}
+	m.issues[issue.ID].UpdatedAt = m.now
+	return nil
+}
+
+// edit 模拟用户在 Erda 修改事件
+func (m *memStore) edit(id uint64, at time.Time, fields Fields) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k, v := range fields {
+		m.issues[id].Fields[k] = v
+	}
+	m.issues[id].UpdatedAt = at
+}
+
+func (m *memStore) ListUpdatedIssueIDs(projectID uint64, since time.Time) ([]uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []uint64
+	for id, issue := range m.issues {
+		if issue.ProjectID == projectID && !issue.UpdatedAt.Before(since) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+func (m *memStore) ListComments(issueID uint64) ([]*LocalComment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*LocalComment(nil), m.streams[issueID]...), nil
+}
+
+func (m *memStore) AddComment(issueID uint64, body, operator string) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := m.nextID()
+	m.streams[issueID] = append(m.streams[issueID], &LocalComment{ID: id, Author: operator, Body: body})
+	return id, nil
+}
+
+func (m *memStore) CreateIssueSyncConnection(conn *dao.IssueSyncConnection) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	conn.ID = m.nextID()
+	conn.CreatedAt = m.now
+	m.conns[conn.ID] = conn
+	return nil
+}
+
+func (m *memStore) UpdateIssueSyncConnection(conn *dao.IssueSyncConnection) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.conns[conn.ID] = conn
+	return nil
+}
+
+func (m *memStore) DeleteIssueSyncConnection(id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.conns, id)
+	return nil
+}
+
+func (m *memStore) GetIssueSyncConnection(id uint64) (*dao.IssueSyncConnection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	conn, ok := m.conns[id]
+	if !ok {
+		return nil, errors.Errorf("connection %d not found", id)
+	}
+	return conn, nil
+}
+
+func (m *memStore) ListIssueSyncConnections(projectID uint64) ([]dao.IssueSyncConnection, error) {
+	return m.ListEnabledIssueSyncConnections()
+}
+
+func (m *memStore) ListEnabledIssueSyncConnections() ([]dao.IssueSyncConnection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var conns []dao.IssueSyncConnection
+	for _, conn := range m.conns {
+		conns = append(conns, *conn)
+	}
+	return conns, nil
+}
+
+func (m *memStore) UpdateIssueSyncConnectionPolledAt(id uint64, polledAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.conns[id].LastPolledAt = &polledAt
+	return nil
+}
+
+func (m *memStore) ClaimIssueSyncLink(link *dao.IssueSyncLink) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, l := range m.links {
+		if l.ConnectionID != link.ConnectionID {
+			continue
+		}
+		if l.RemoteKey == link.RemoteKey || (link.IssueID > 0 && l.IssueID == link.IssueID) {
+			return false, nil
+		}
+	}
+	link.ID = m.nextID()
+	cp := *link
+	m.links[link.ID] = &cp
+	return true, nil
+}
+
+func (m *memStore) SaveIssueSyncLink(link *dao.IssueSyncLink) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp := *link
+	m.links[link.ID] = &cp
+	return nil
+}
+
+func (m *memStore) DeleteIssueSyncLink(id uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.links, id)
+	return nil
+}
+
+func (m *memStore) findLink(match func(l *dao.IssueSyncLink) bool) *dao.IssueSyncLink {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, l := range m.links {
+		if match(l) {
+			cp := *l
+			return &cp
+		}
+	}
+	return nil
+}
+
+func (m *memStore) GetIssueSyncLinkByIssue(connectionID, issueID uint64) (*dao.IssueSyncLink, error) {
+	return m.findLink(func(l *dao.IssueSyncLink) bool {
+		return l.ConnectionID == connectionID && l.IssueID == issueID
+	}), nil
+}
+
+func (m *memStore) GetIssueSyncLinkByRemote(connectionID uint64, remoteKey string) (*dao.IssueSyncLink, error) {
+	return m.findLink(func(l *dao.IssueSyncLink) bool {
+		return l.ConnectionID == connectionID && l.RemoteKey == remoteKey
+	}), nil
+}
+
+func (m *memStore) ListIssueSyncLinksByIssue(issueID uint64) ([]dao.IssueSyncLink, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var links []dao.IssueSyncLink
+	for _, l := range m.links {
+		if l.IssueID == issueID {
+			links = append(links, *l)
+		}
+	}
+	return links, nil
+}
+
+func (m *memStore) ListIssueSyncComments(connectionID, issueID uint64) ([]dao.IssueSyncComment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var comments []dao.IssueSyncComment
+	for _, c := range m.comments {
+		if c.ConnectionID == connectionID && c.IssueID == issueID {
+			comments = append(comments, c)
+		}
+	}
+	return comments, nil
+}
+
+func (m *memStore) CreateIssueSyncComment(comment *dao.IssueSyncComment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	comment.ID = m.nextID()
+	m.comments = append(m.comments, *comment)
+	return nil
+}
+
+func newTestSyncService(t *testing.T, server *httptest.Server, strategy apistructs.IssueSyncConflictStrategy) (*Service, *memStore, *dao.IssueSyncConnection) {
+	store := newMemStore()
+	s := New(WithLinkStore(store), WithIssueStore(store), WithHTTPClient(server.Client()))
+	// the fake server listens on loopback
+	s.checkBaseURL = func(ctx context.Context, baseURL string) error { return nil }
+	conn, err := s.CreateConnection(1, &apistructs.IssueSyncConnectionCreateRequest{
+		ProjectID:     2,
+		Name:          "jira",
+		Provider:      apistructs.IssueSyncProviderJira,
+		BaseURL:       server.URL,
+		RemoteProject: "ERDA",
+		Username:      "bot",
+		Token:         "token",
+		WebhookSecret: "s3cret",
+		Mapping: apistructs.IssueSyncMapping{
+			Types:      map[string]string{"BUG": "Bug", "TASK": "Task"},
+			States:     map[string]string{"待处理": "To Do", "进行中": "In Progress", "已完成": "Done"},
+			Priorities: map[string]string{"HIGH": "High", "NORMAL": "Medium"},
+		},
+		ConflictStrategy: strategy,
+		Enabled:          true,
+		IdentityInfo:     apistructs.IdentityInfo{UserID: "1"},
+	})
+	require.NoError(t, err)
+	c, err := store.GetIssueSyncConnection(conn.ID)
+	require.NoError(t, err)
+	return s, store, c
+}
+
+func TestService_ImportAndMerge(t *testing.T) {
+	jira, server := newFakeJira()
+	defer server.Close()
+	s, store, conn := newTestSyncService(t, server, apistructs.IssueSyncConflictLatestWins)
+	ctx := context.Background()
+
+	key := jira.add(map[string]interface{}{
+		"summary":   "remote bug",
+		"issuetype": map[string]string{"name": "Bug"},
+		"priority":  map[string]string{"name": "High"},
+	})
+	jira.addComment(key, "bob", "first")
+
+	result, err := s.Poll(ctx, conn)
+	require.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 1, result.Comments)
+
+	link, _ := store.GetIssueSyncLinkByRemote(conn.ID, key)
+	require.NotNil(t, link)
+	issue, err := store.GetIssue(link.IssueID)
+	require.NoError(t, err)
+	assert.Equal(t, "BUG", issue.Type)
+	assert.Equal(t, "remote bug", issue.Fields[FieldTitle])
+	assert.Equal(t, "待处理", issue.Fields[FieldState])
+	assert.Equal(t, "HIGH", issue.Fields[FieldPriority])
+	comments, _ := store.ListComments(issue.ID)
+	require.Len(t, comments, 1)
+	assert.Equal(t, "[synced from jira by bob]\nfirst", comments[0].Body)
+
+	// polling again without changes is a no-op
+	result, err = s.Poll(ctx, conn)
+	require.NoError(t, err)
+	assert.Equal(t, apistructs.IssueSyncResult{}, *result)
+
+	// local change is pushed
+	store.edit(issue.ID, time.Now(), Fields{FieldState: "进行中"})
+	result, err = s.Poll(ctx, conn)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Pushed)
+	assert.Equal(t, map[string]string{"name": "In Progress"}, jira.field(key, "status"))
+
+	// remote change is pulled
+	jira.set(key, map[string]interface{}{"summary": "renamed"})
+	result, err = s.Poll(ctx, conn)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Pulled)
+	issue, _ = store.GetIssue(issue.ID)
+	assert.Equal(t, "renamed", issue.Fields[FieldTitle])
+	assert.Equal(t, "进行中", issue.Fields[FieldState])
+
+	// both sides change the title, the remote side is updated later
+	store.edit(issue.ID, time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), Fields{FieldTitle: "local title"})
+	jira.set(key, map[string]interface{}{"summary": "remote title"})
+	result, err = s.Poll(ctx, conn)
+	require.NoError(t, err)
+	require.Len(t, result.Conflicts, 1)
+	assert.Equal(t, FieldTitle, result.Conflicts[0].Field)
+	assert.Equal(t, "remote", result.Conflicts[0].Winner)
+	issue, _ = store.GetIssue(issue.ID)
+	assert.Equal(t, "remote title", issue.Fields[FieldTitle])
+}
+
+func TestService_PushIssue(t *testing.T) {
+	jira, server := newFakeJira()
+	defer server.Close()
+	s, store, conn := newTestSyncService(t, server, apistructs.IssueSyncConflictErdaWins)
+	ctx := context.Background()
+
+	id, err := store.CreateIssue(1, &LocalIssue{
+		ProjectID: 2,
+		Type:      "TASK",
+		Fields: Fields{
+			FieldTitle:          "local task",
+			FieldContent:        "",
+			FieldAssignee:       "",
+			FieldState:          "已完成",
+			FieldPriority:       "NORMAL",
+			PropertyField("模块"): "api",
+		},
+	})
+	require.NoError(t, err)
+	_, err = store.AddComment(id, "local comment", "alice")
+	require.NoError(t, err)
+
+	result, err := s.PushIssue(ctx, conn.ID, id)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 1, result.Comments)
+	link, _ := store.GetIssueSyncLinkByIssue(conn.ID, id)
+	require.NotNil(t, link)
+	assert.Equal(t, "ERDA-1", link.RemoteKey)
+	assert.NotContains(t, link.Snapshot, PropertyField("模块"), "unmapped properties are not synced")
+	assert.Equal(t, "local task", jira.field("ERDA-1", "summary"))
+	assert.Equal(t, map[string]string{"name": "Done"}, jira.field("ERDA-1", "status"))
+	assert.Equal(t, map[string]interface{}{"name": "Task"}, jira.field("ERDA-1", "issuetype"))
+
+	// pushing again does not create another remote issue or comment
+	result, err = s.PushIssue(ctx, conn.ID, id)
+	require.NoError(t, err)
+	assert.Equal(t, apistructs.IssueSyncResult{}, *result)
+
+	links, err := s.ListLinks(id)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+
+	_, err = s.PushIssue(ctx, conn.ID, 404)
+	assert.Error(t, err)
+}
+
+func TestService_HandleWebhook(t *testing.T) {
+	jira, server := newFakeJira()
+	defer server.Close()
+	s, store, conn := newTestSyncService(t, server, apistructs.IssueSyncConflictRemoteWins)
+	ctx := context.Background()
+
+	key := jira.add(map[string]interface{}{"summary": "from webhook"})
+	newRequest := func(secret, body string) *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/webhook?secret="+secret, strings.NewReader(body))
+	}
+
+	_, err := s.HandleWebhook(ctx, conn.ID, newRequest("wrong", `{"webhookEvent":"jira:issue_created","issue":{"key":"`+key+`"}}`))
+	assert.ErrorIs(t, err, ErrInvalidWebhook)
+
+	result, err := s.HandleWebhook(ctx, conn.ID, newRequest("s3cret", `{"webhookEvent":"jira:issue_created","issue":{"key":"`+key+`"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+	link, _ := store.GetIssueSyncLinkByRemote(conn.ID, key)
+	require.NotNil(t, link)
+
+	jira.addComment(key, "bob", "via webhook")
+	result, err = s.HandleWebhook(ctx, conn.ID, newRequest("s3cret", `{"webhookEvent":"comment_created","issue":{"key":"`+key+`"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Comments)
+
+	conn.Enabled = false
+	_, err = s.HandleWebhook(ctx, conn.ID, newRequest("s3cret", `{"webhookEvent":"comment_created","issue":{"key":"`+key+`"}}`))
+	assert.Error(t, err)
+}
@@ -84,6 +84,12 @@ func (svc *Service) UpdateFileRecord(req apistructs.TestFileRecordRequest) error
 	if req.Description != "" {
 		r.Description = req.Description
 	}
+	if extra := req.Extra.AutotestSpaceFileExtraInfo; extra != nil && extra.ImportReport != nil && r.Extra.AutotestSpaceFileExtraInfo != nil {
+		r.Extra.AutotestSpaceFileExtraInfo.ImportReport = extra.ImportReport
+	}
+	if extra := req.Extra.AutotestSceneSetFileExtraInfo; extra != nil && extra.ImportReport != nil && r.Extra.AutotestSceneSetFileExtraInfo != nil {
+		r.Extra.AutotestSceneSetFileExtraInfo.ImportReport = extra.ImportReport
+	}
 	if req.ErrorInfo != nil {
 		errorInfo := fmt.Sprint(req.ErrorInfo)
 		if err := strutil.Validate(errorInfo, strutil.MaxRuneCountValidator(apistructs.TestFileRecordErrorMaxLength)); err != nil {
@@ -151,6 +151,14 @@ func (o *NotifyGroup) GetDetail(id int64, orgID int64) (*apistructs.NotifyGroupD
 			for _, webhookUrl := range target.Values {
 				result.WebHookList = append(result.WebHookList, webhookUrl.Receiver)
 			}
+		case apistructs.SlackNotifyTarget:
+			result.SlackList = append(result.SlackList, target.Values...)
+		case apistructs.TeamsNotifyTarget:
+			result.TeamsList = append(result.TeamsList, target.Values...)
+		case apistructs.FeishuNotifyTarget:
+			result.FeishuList = append(result.FeishuList, target.Values...)
+		case apistructs.WeComNotifyTarget:
+			result.WeComList = append(result.WeComList, target.Values...)
 		case apistructs.RoleNotifyTarget:
 			var roles []string
 			for _, r := range target.Values {
@@ -214,6 +222,10 @@ func (o *NotifyGroup) GetDetail(id int64, orgID int64) (*apistructs.NotifyGroupD
 	result.Targets = group.Targets
 	result.DingdingList = uniqueTargetList(result.DingdingList)
 	result.DingdingWorkNoticeList = uniqueTargetList(result.DingdingWorkNoticeList)
+	result.SlackList = uniqueTargetList(result.SlackList)
+	result.TeamsList = uniqueTargetList(result.TeamsList)
+	result.FeishuList = uniqueTargetList(result.FeishuList)
+	result.WeComList = uniqueTargetList(result.WeComList)
 	return result, nil
 }
 
@@ -29,18 +29,24 @@ import (
 	"github.com/erda-project/erda/internal/core/messenger/eventbox/input"
 	inputhttp "github.com/erda-project/erda/internal/core/messenger/eventbox/input/http"
 	"github.com/erda-project/erda/internal/core/messenger/eventbox/monitor"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/preference"
 	"github.com/erda-project/erda/internal/core/messenger/eventbox/register"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/stream"
 	"github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber"
 	dingdingsubscriber "github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber/dingding"
 	dingdingworknoticesubscriber "github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber/dingding_worknotice"
 	"github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber/dingtalk_worknotice"
 	emailsubscriber "github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber/email"
 	fakesubscriber "github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber/fake"
+	feishusubscriber "github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber/feishu"
 	groupsubscriber "github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber/group"
 	httpsubscriber "github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber/http"
 	"github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber/mbox"
+	slacksubscriber "github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber/slack"
 	smssubscriber "github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber/sms"
+	teamssubscriber "github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber/teams"
 	vmssubscriber "github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber/vms"
+	wecomsubscriber "github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber/wecom"
 	"github.com/erda-project/erda/internal/core/messenger/eventbox/webhook"
 	"github.com/erda-project/erda/internal/core/messenger/eventbox/websocket"
 	"github.com/erda-project/erda/internal/core/org"
@@ -60,16 +66,23 @@ type DispatcherImpl struct {
 	router          *Router
 	register        register.Register
 	inputs          []input.Input
+	deliverer       *webhook.Deliverer
+	preference      *preference.Manager
+	streamer        *stream.Streamer
 
 	runningWg sync.WaitGroup
 }
 
 func New(dingtalk interfaces.DingTalkApiClientFactory, messenger pb.NotifyServiceServer, httpi *inputhttp.HttpInput,
 	mon *monitor.MonitorHTTP, wh *webhook.WebHookHTTP,
-	registerHttp *register.RegisterHTTP, org org.Interface) (Dispatcher, error) {
+	registerHttp *register.RegisterHTTP, org org.Interface, pm *preference.Manager,
+	streamer *stream.Streamer) (Dispatcher, error) {
 	dispatcher := DispatcherImpl{
 		subscribers:     make(map[string]subscriber.Subscriber),
 		subscriberspool: make(map[string]*goroutinepool.GoroutinePool),
+		deliverer:       wh.Deliverer(),
+		preference:      pm,
+		streamer:        streamer,
 	}
 
 	wsi, err := websocket.New()
@@ -96,6 +109,10 @@ func New(dingtalk interfaces.DingTalkApiClientFactory, messenger pb.NotifyServic
 		conf.AliyunVmsMonitorCalledShowNumber(), bundleS, messenger, org)
 	dingWorkNotice := dingtalk_worknotice.New(bundleS, dingtalk, messenger)
 	groupS := groupsubscriber.New(bundleS)
+	slackS := slacksubscriber.New(conf.Proxy(), bundleS, messenger)
+	teamsS := teamssubscriber.New(conf.Proxy(), bundleS, messenger)
+	feishuS := feishusubscriber.New(conf.Proxy(), bundleS, messenger)
+	wecomS := wecomsubscriber.New(conf.Proxy(), bundleS, messenger)
 	if err != nil {
 		return nil, err
 	}
@@ -119,6 +136,10 @@ func New(dingtalk interfaces.DingTalkApiClientFactory, messenger pb.NotifyServic
 	dispatcher.RegisterSubscriber(mboxS)
 	dispatcher.RegisterSubscriber(groupS)
 	dispatcher.RegisterSubscriber(dingWorkNotice)
+	dispatcher.RegisterSubscriber(slackS)
+	dispatcher.RegisterSubscriber(teamsS)
+	dispatcher.RegisterSubscriber(feishuS)
+	dispatcher.RegisterSubscriber(wecomS)
 
 	reg, err := register.New()
 	if err != nil {
@@ -134,6 +155,9 @@ func New(dingtalk interfaces.DingTalkApiClientFactory, messenger pb.NotifyServic
 	}
 	dispatcher.SetRouter(router)
 	groupS.SetRoute(router)
+	if pm != nil {
+		pm.SetRoute(router)
+	}
 
 	return &dispatcher, nil
 }
@@ -0,0 +1,48 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filters
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/dispatcher/errors"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/preference"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/types"
+)
+
+// PreferenceFilter 在发送前对个人通知应用用户的通知偏好
+type PreferenceFilter struct {
+	manager *preference.Manager
+}
+
+func NewPreferenceFilter(manager *preference.Manager) Filter {
+	return &PreferenceFilter{manager: manager}
+}
+
+func (*PreferenceFilter) Name() string {
+	return "PreferenceFilter"
+}
+
+func (p *PreferenceFilter) Filter(m *types.Message) *errors.DispatchError {
+	derr := errors.New()
+	if p.manager == nil {
+		return derr
+	}
+	// 应用偏好失败时按原目标发送
+	if err := p.manager.Apply(m); err != nil {
+		logrus.Errorf("PreferenceFilter: apply notify preference: %v", err)
+	}
+	return derr
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/preference"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/types"
+)
+
+func TestPreferenceFilter(t *testing.T) {
+	m := types.Message{Labels: map[types.LabelKey]interface{}{
+		"/MBOX":     []string{"1"},
+		"/DINGDING": []string{"https://oapi.dingtalk.com/robot/send"},
+	}}
+
+	f := NewPreferenceFilter(nil)
+	assert.True(t, f.Filter(&m).IsOK())
+	assert.Len(t, m.Labels, 2)
+
+	// 摘要消息已经应用过偏好，不再访问数据库
+	m.SetSkipPreference(true)
+	f = NewPreferenceFilter(preference.New(nil, preference.Config{DefaultTimezone: "UTC"}))
+	assert.True(t, f.Filter(&m).IsOK())
+	assert.Equal(t, []string{"1"}, m.Labels["/MBOX"])
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filters
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/constant"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/dispatcher/errors"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/stream"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/types"
+)
+
+// StreamFilter 将带 WEBHOOK label 的平台事件写入事件流，
+// 需要在 WebhookFilter 之前，此时 message content 仍是事件的原始内容
+type StreamFilter struct {
+	streamer *stream.Streamer
+}
+
+func NewStreamFilter(streamer *stream.Streamer) Filter {
+	return &StreamFilter{streamer: streamer}
+}
+
+func (*StreamFilter) Name() string {
+	return "StreamFilter"
+}
+
+func (s *StreamFilter) Filter(m *types.Message) *errors.DispatchError {
+	derr := errors.New()
+	if s.streamer == nil {
+		return derr
+	}
+	whLabel, ok := m.Labels[types.LabelKey(constant.WebhookLabelKey)]
+	if !ok {
+		return derr
+	}
+	// 写入事件流失败不影响 webhook 及其他通知
+	if err := s.enqueue(m, whLabel); err != nil {
+		logrus.Errorf("StreamFilter: %v", err)
+	}
+	return derr
+}
+
+func (s *StreamFilter) enqueue(m *types.Message, whLabel interface{}) error {
+	eventLabel, err := decodeWebhookLabel(whLabel)
+	if err != nil {
+		return fmt.Errorf("decode label: %v, origin-label: %v", err, whLabel)
+	}
+	content, err := json.Marshal(m.Content)
+	if err != nil {
+		return fmt.Errorf("marshal content: %v", err)
+	}
+	if err := s.streamer.Enqueue(*eventLabel, content); err != nil {
+		return fmt.Errorf("enqueue event %s: %v", eventLabel.Event, err)
+	}
+	return nil
+}
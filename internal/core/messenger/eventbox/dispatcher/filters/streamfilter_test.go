@@ -0,0 +1,38 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/types"
+)
+
+func TestStreamFilter(t *testing.T) {
+	m := types.Message{
+		Labels: map[types.LabelKey]interface{}{
+			"/WEBHOOK": map[string]interface{}{"event": "pipeline"},
+		},
+		Content: map[string]interface{}{"pipelineID": 1},
+	}
+	// 未开启事件流时不修改 message
+	f := NewStreamFilter(nil)
+	assert.Equal(t, "StreamFilter", f.Name())
+	assert.True(t, f.Filter(&m).IsOK())
+	assert.Len(t, m.Labels, 1)
+	assert.Equal(t, map[string]interface{}{"pipelineID": 1}, m.Content)
+}
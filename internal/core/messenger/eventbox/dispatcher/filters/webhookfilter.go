@@ -30,14 +30,16 @@ import (
 
 type WebhookFilter struct {
 	impl *webhook.WebHookImpl
+	// deliverer 不为空时，普通 url 的 hook 由 deliverer 签名并可靠投递，不再经过 http subscriber
+	deliverer *webhook.Deliverer
 }
 
-func NewWebhookFilter() (Filter, error) {
+func NewWebhookFilter(deliverer *webhook.Deliverer) (Filter, error) {
 	impl, err := webhook.NewWebHookImpl()
 	if err != nil {
 		return nil, err
 	}
-	return &WebhookFilter{impl: impl}, nil
+	return &WebhookFilter{impl: impl, deliverer: deliverer}, nil
 }
 
 func (*WebhookFilter) Name() string {
@@ -77,10 +79,15 @@ func (w *WebhookFilter) Filter(m *types.Message) *errors.DispatchError {
 	}
 
 	urls := []string{}
-	for _, h := range hs {
-		urls = append(urls, h.URL)
-	}
-	for _, h := range internalHs {
+	for _, h := range append(hs, internalHs...) {
+		if w.deliverer != nil {
+			if parsed, err := url.Parse(h.URL); err == nil && urltype(parsed) == normalURL {
+				if err := w.enqueue(m, h, *eventLabel); err != nil {
+					logrus.Errorf("WebhookFilter: enqueue delivery of hook %s: %v", h.ID, err)
+				}
+				continue
+			}
+		}
 		urls = append(urls, h.URL)
 	}
 
@@ -95,6 +102,14 @@ func (w *WebhookFilter) Filter(m *types.Message) *errors.DispatchError {
 	return derr
 }
 
+func (w *WebhookFilter) enqueue(m *types.Message, h apistructs.Hook, eventLabel webhook.EventLabel) error {
+	origin, err := json.Marshal(m.Content)
+	if err != nil {
+		return err
+	}
+	return w.deliverer.Enqueue(h, webhook.MkEventMessage(eventLabel, origin))
+}
+
 func decodeWebhookLabel(l interface{}) (*webhook.EventLabel, error) {
 	raw, err := json.Marshal(l)
 	if err != nil {
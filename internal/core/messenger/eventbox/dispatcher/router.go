@@ -42,10 +42,10 @@ import (
 //
 // []filter:
 //
-//	+---------------+  +---------------+  +----------------+	 +-----------------+
-//	| unifylabels   +--> registerlabel +--> webhookfilter  +-->  lastfilter     |
-//	|               |  |               |  |                |	 |                 |
-//	+---------------+  +---------------+  +----------------+	 +-----------------+
+//	+---------------+  +---------------+  +----------------+  +----------------+	 +------------------+  +-----------------+
+//	| unifylabels   +--> registerlabel +--> streamfilter   +--> webhookfilter  +-->  preferencefilter +-->  lastfilter     |
+//	|               |  |               |  |                |  |                |	 |                  |  |                 |
+//	+---------------+  +---------------+  +----------------+  +----------------+	 +------------------+  +-----------------+
 type Router struct {
 	dispatcher *DispatcherImpl
 	filters    []filters.Filter
@@ -59,15 +59,19 @@ func NewRouter(dispatcher *DispatcherImpl) (*Router, error) {
 
 	unifyLabelsFilter := filters.NewUnifyLabelsFilter()
 	registerFilter := filters.NewRegisterFilter(dispatcher.GetRegister())
-	webhookFilter, err := filters.NewWebhookFilter()
+	streamFilter := filters.NewStreamFilter(dispatcher.streamer)
+	webhookFilter, err := filters.NewWebhookFilter(dispatcher.deliverer)
 	if err != nil {
 		return nil, fmt.Errorf("init webhookfilter: %v", err)
 	}
+	preferenceFilter := filters.NewPreferenceFilter(dispatcher.preference)
 	lastFilter := filters.NewLastFilter(dispatcher.GetSubscribersPool(), dispatcher.GetSubscribers())
 
 	r.RegisterFilter(unifyLabelsFilter)
 	r.RegisterFilter(registerFilter)
+	r.RegisterFilter(streamFilter)
 	r.RegisterFilter(webhookFilter)
+	r.RegisterFilter(preferenceFilter)
 	r.RegisterFilter(lastFilter)
 
 	return r, nil
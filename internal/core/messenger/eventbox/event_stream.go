@@ -0,0 +1,95 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventbox
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/stream"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+)
+
+const (
+	eventStreamSinkKafka = "kafka"
+	eventStreamSinkNATS  = "nats"
+)
+
+// initEventStream 根据配置创建事件流，未开启时只提供事件类型查询
+func (p *provider) initEventStream() error {
+	p.eventTypes = stream.NewRegistry()
+	cfg := p.C.EventStream
+	if !cfg.Enabled {
+		return nil
+	}
+	var (
+		sink stream.Sink
+		err  error
+	)
+	switch cfg.Sink {
+	case eventStreamSinkKafka:
+		sink, err = stream.NewKafkaSink(stream.KafkaConfig{
+			Brokers:  []string{cfg.KafkaBrokers},
+			Topic:    cfg.KafkaTopic,
+			Username: cfg.KafkaUsername,
+			Password: cfg.KafkaPassword,
+			Timeout:  cfg.Timeout,
+		})
+	case eventStreamSinkNATS:
+		sink, err = stream.NewNATSSink(stream.NATSConfig{
+			URL:       cfg.NATSURL,
+			Subject:   cfg.NATSSubject,
+			JetStream: cfg.NATSJetStream,
+			Timeout:   cfg.Timeout,
+		})
+	default:
+		err = fmt.Errorf("unsupported sink %q, should be %s or %s", cfg.Sink, eventStreamSinkKafka, eventStreamSinkNATS)
+	}
+	if err != nil {
+		return fmt.Errorf("init event stream: %v", err)
+	}
+	p.streamer = stream.New(p.DB, sink, p.eventTypes, stream.Config{
+		Source:        cfg.Source,
+		SchemaBaseURL: cfg.SchemaBaseURL,
+		PollInterval:  cfg.PollInterval,
+		BatchSize:     cfg.BatchSize,
+		Concurrency:   cfg.Concurrency,
+		BaseBackoff:   cfg.BaseBackoff,
+		MaxBackoff:    cfg.MaxBackoff,
+		ClaimTimeout:  cfg.ClaimTimeout,
+	})
+	return nil
+}
+
+func (p *provider) initEventTypeEndpoints() {
+	p.Register.Add(http.MethodGet, "/api/dice/eventbox/event-types", p.listEventTypes)
+	p.Register.Add(http.MethodGet, "/api/dice/eventbox/event-types/{event}", p.getEventType)
+}
+
+func (p *provider) listEventTypes(rw http.ResponseWriter, r *http.Request) {
+	httpserver.WriteData(rw, p.eventTypes.List())
+}
+
+func (p *provider) getEventType(rw http.ResponseWriter, r *http.Request) {
+	event := mux.Vars(r)["event"]
+	t, ok := p.eventTypes.Get(event)
+	if !ok {
+		writeBadRequest(rw, fmt.Errorf("event type %s not found", event))
+		return
+	}
+	httpserver.WriteData(rw, t)
+}
@@ -26,7 +26,7 @@ import (
 func Initialize(p *provider, ctx context.Context) error {
 	dp, err := dispatcher.New(p.DingtalkApiClient, p.Messenger,
 		p.eventBoxService.HttpI, p.eventBoxService.MonitorHTTP,
-		p.eventBoxService.WebHookHTTP, p.eventBoxService.RegisterHTTP, p.Org)
+		p.eventBoxService.WebHookHTTP, p.eventBoxService.RegisterHTTP, p.Org, p.preference, p.streamer)
 	if err != nil {
 		panic(err)
 	}
@@ -49,6 +49,13 @@ func Initialize(p *provider, ctx context.Context) error {
 	go func() {
 		dp.Start(ctx)
 	}()
+	if d := p.eventBoxService.WebHookHTTP.Deliverer(); d != nil {
+		go d.Run(ctx)
+	}
+	go p.preference.Run(ctx)
+	if p.streamer != nil {
+		go p.streamer.Run(ctx)
+	}
 
 	for err := range ch {
 		return err
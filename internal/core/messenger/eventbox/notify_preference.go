@@ -0,0 +1,76 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+)
+
+func (p *provider) initNotifyPreferenceEndpoints() {
+	p.Register.Add(http.MethodGet, "/api/dice/eventbox/notify-preferences", p.getNotifyPreference)
+	p.Register.Add(http.MethodPut, "/api/dice/eventbox/notify-preferences", p.updateNotifyPreference)
+}
+
+// notifyPreferenceOwner 通知偏好只能由用户本人在当前 org 下查看和修改
+func notifyPreferenceOwner(r *http.Request) (string, int64, error) {
+	userID := r.Header.Get("User-ID")
+	if userID == "" {
+		return "", 0, fmt.Errorf("missing User-ID")
+	}
+	orgID, err := strconv.ParseInt(r.Header.Get("Org-ID"), 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid Org-ID: %s", r.Header.Get("Org-ID"))
+	}
+	return userID, orgID, nil
+}
+
+func (p *provider) getNotifyPreference(rw http.ResponseWriter, r *http.Request) {
+	userID, orgID, err := notifyPreferenceOwner(r)
+	if err != nil {
+		writeBadRequest(rw, err)
+		return
+	}
+	res, err := p.preference.Get(userID, orgID)
+	if err != nil {
+		writeBadRequest(rw, err)
+		return
+	}
+	httpserver.WriteData(rw, res)
+}
+
+func (p *provider) updateNotifyPreference(rw http.ResponseWriter, r *http.Request) {
+	userID, orgID, err := notifyPreferenceOwner(r)
+	if err != nil {
+		writeBadRequest(rw, err)
+		return
+	}
+	var req apistructs.NotifyPreferenceUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(rw, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+	res, err := p.preference.Update(userID, orgID, &req)
+	if err != nil {
+		writeBadRequest(rw, err)
+		return
+	}
+	httpserver.WriteData(rw, res)
+}
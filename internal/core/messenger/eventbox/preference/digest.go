@@ -0,0 +1,145 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preference
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/types"
+	"github.com/erda-project/erda/pkg/crypto/uuid"
+)
+
+// claimTimeout 认领后超过该时长仍未删除的摘要条目视为发送实例已退出，允许重新认领
+const claimTimeout = 10 * time.Minute
+
+// Run 循环发送到期的摘要，直到 ctx 结束
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.PollInterval)
+	defer ticker.Stop()
+	lastCleanup := time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		m.sendDue()
+		if time.Since(lastCleanup) > time.Hour {
+			if err := m.db.Where("expire_at < ?", m.now()).Delete(&DedupRecord{}).Error; err != nil {
+				logrus.Errorf("notify preference: cleanup dedup records: %v", err)
+			}
+			lastCleanup = time.Now()
+		}
+	}
+}
+
+func (m *Manager) sendDue() {
+	if m.router == nil {
+		return
+	}
+	now := m.now()
+	stale := now.Add(-claimTimeout)
+	var ids []uint64
+	if err := m.db.Model(&DigestItem{}).Where("due_at <= ? AND (claim = '' OR updated_at < ?)", now, stale).
+		Order("due_at").Limit(m.cfg.BatchSize).Pluck("id", &ids).Error; err != nil {
+		logrus.Errorf("notify preference: list due digest items: %v", err)
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+	// 通过条件更新认领，多实例部署时同一条目只会被一个实例发送
+	claim := uuid.UUID()
+	if err := m.db.Model(&DigestItem{}).Where("id IN (?) AND (claim = '' OR updated_at < ?)", ids, stale).
+		Update("claim", claim).Error; err != nil {
+		logrus.Errorf("notify preference: claim digest items: %v", err)
+		return
+	}
+	var items []DigestItem
+	if err := m.db.Where("claim = ?", claim).Order("id").Find(&items).Error; err != nil {
+		logrus.Errorf("notify preference: get claimed digest items: %v", err)
+		return
+	}
+	for _, batch := range groupItems(items) {
+		if derr := m.router.Route(digestMessage(batch, now)); derr != nil && !derr.IsOK() {
+			logrus.Errorf("notify preference: send digest to %s %s: %v", batch[0].Label, batch[0].Target, derr)
+		}
+		batchIDs := make([]uint64, 0, len(batch))
+		for _, item := range batch {
+			batchIDs = append(batchIDs, item.ID)
+		}
+		if err := m.db.Where("id IN (?)", batchIDs).Delete(&DigestItem{}).Error; err != nil {
+			logrus.Errorf("notify preference: delete sent digest items: %v", err)
+		}
+	}
+}
+
+// groupItems 将同一用户、同一发送目标的条目合并为一条摘要，保持原有顺序
+func groupItems(items []DigestItem) [][]DigestItem {
+	var (
+		result [][]DigestItem
+		index  = make(map[string]int)
+	)
+	for _, item := range items {
+		key := fmt.Sprintf("%s/%d/%s/%s", item.UserID, item.OrgID, item.Label, item.Target)
+		i, ok := index[key]
+		if !ok {
+			i = len(result)
+			index[key] = i
+			result = append(result, nil)
+		}
+		result[i] = append(result[i], item)
+	}
+	return result
+}
+
+// digestMessage 构造摘要消息，只有一条时按原通知发送
+func digestMessage(items []DigestItem, now time.Time) *types.Message {
+	first := items[0]
+	title, text, category := first.Title, first.Content, first.Category
+	if len(items) > 1 {
+		title = fmt.Sprintf("通知摘要: 共 %d 条通知", len(items))
+		var b strings.Builder
+		for _, item := range items {
+			fmt.Fprintf(&b, "### %s\n\n%s\n\n", item.Title, item.Content)
+			if item.Category != category {
+				category = ""
+			}
+		}
+		text = strings.TrimSpace(b.String())
+	}
+	msg := &types.Message{
+		Sender: "notify-digest",
+		Content: map[string]interface{}{
+			"template": text,
+			"type":     "markdown",
+			"params":   map[string]string{"title": title},
+			"orgID":    first.OrgID,
+		},
+		Time: now.UnixNano(),
+		Labels: map[types.LabelKey]interface{}{
+			types.LabelKey(first.Label): []string{first.Target},
+		},
+		Category: category,
+	}
+	// 摘要在入队时已经应用过偏好
+	msg.SetSkipPreference(true)
+	return msg
+}
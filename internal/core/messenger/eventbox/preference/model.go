@@ -0,0 +1,92 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preference
+
+import (
+	"time"
+)
+
+// Setting 用户在 org 下的通知设置
+type Setting struct {
+	ID             uint64 `gorm:"primary_key"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	UserID         string
+	OrgID          int64
+	Timezone       string
+	IsQuietEnabled bool
+	QuietStart     string
+	QuietEnd       string
+	DigestHour     int
+	DigestWeekday  int
+}
+
+// TableName 设置模型对应数据库表名称
+func (Setting) TableName() string {
+	return "erda_notify_user_settings"
+}
+
+// Preference 用户对某类事件在某个通知方式上的偏好
+type Preference struct {
+	ID        uint64 `gorm:"primary_key"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    string
+	OrgID     int64
+	Category  string
+	Channel   string
+	IsEnabled bool
+	Frequency string
+}
+
+// TableName 设置模型对应数据库表名称
+func (Preference) TableName() string {
+	return "erda_notify_preferences"
+}
+
+// DigestItem 被合并为摘要或因免打扰延迟发送的通知
+type DigestItem struct {
+	ID        uint64 `gorm:"primary_key"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    string
+	OrgID     int64
+	Label     string
+	Target    string
+	Category  string
+	Title     string
+	Content   string
+	DueAt     time.Time
+	Claim     string
+}
+
+// TableName 设置模型对应数据库表名称
+func (DigestItem) TableName() string {
+	return "erda_notify_digest_items"
+}
+
+// DedupRecord 去重窗口内已发送的通知指纹
+type DedupRecord struct {
+	ID          uint64 `gorm:"primary_key"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Fingerprint string
+	ExpireAt    time.Time
+}
+
+// TableName 设置模型对应数据库表名称
+func (DedupRecord) TableName() string {
+	return "erda_notify_dedup"
+}
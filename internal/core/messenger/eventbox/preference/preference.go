@@ -0,0 +1,400 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preference
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/apistructs"
+	dispatchererror "github.com/erda-project/erda/internal/core/messenger/eventbox/dispatcher/errors"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/types"
+	"github.com/erda-project/erda/pkg/template"
+)
+
+// Config 用户通知偏好的默认值及摘要发送策略
+type Config struct {
+	// DedupWindow 相同通知的去重窗口，<= 0 表示不去重
+	DedupWindow time.Duration
+	// DefaultTimezone 用户未设置时区时使用的时区
+	DefaultTimezone string
+	// DefaultDigestHour 用户未设置时摘要发送的整点
+	DefaultDigestHour int
+	// DefaultDigestWeekday 用户未设置时周摘要发送的星期
+	DefaultDigestWeekday int
+	// PollInterval 扫描到期摘要的间隔
+	PollInterval time.Duration
+	// BatchSize 每次扫描取出的摘要条目数
+	BatchSize int
+}
+
+// labelChannels 按用户发送的 label 及其对应的偏好通知方式
+var labelChannels = map[string]apistructs.NotifyPreferenceChannel{
+	"MBOX":                 apistructs.NotifyPreferenceChannelMBox,
+	"EMAIL":                apistructs.NotifyPreferenceChannelEmail,
+	"DINGTALK_WORK_NOTICE": apistructs.NotifyPreferenceChannelIM,
+}
+
+type routerI interface {
+	Route(m *types.Message) *dispatchererror.DispatchError
+}
+
+// Manager 管理用户的通知偏好，并在 dispatcher 中对个人通知应用偏好、去重及免打扰
+type Manager struct {
+	db     *gorm.DB
+	cfg    Config
+	loc    *time.Location
+	router routerI
+	now    func() time.Time
+}
+
+func New(db *gorm.DB, cfg Config) *Manager {
+	loc, err := time.LoadLocation(cfg.DefaultTimezone)
+	if err != nil {
+		logrus.Warnf("notify preference: invalid default timezone %q, use UTC", cfg.DefaultTimezone)
+		loc = time.UTC
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	return &Manager{
+		db:  db,
+		cfg: cfg,
+		loc: loc,
+		now: time.Now,
+	}
+}
+
+// SetRoute 设置发送摘要使用的 router
+func (m *Manager) SetRoute(router routerI) {
+	m.router = router
+}
+
+// Get 返回用户在 org 下的通知偏好，未设置时返回默认值
+func (m *Manager) Get(userID string, orgID int64) (*apistructs.NotifyPreferenceSettings, error) {
+	var s Setting
+	err := m.db.Where("user_id = ? AND org_id = ?", userID, orgID).First(&s).Error
+	if gorm.IsRecordNotFoundError(err) {
+		s = m.defaultSetting(userID, orgID)
+	} else if err != nil {
+		return nil, err
+	}
+	var prefs []Preference
+	if err := m.db.Where("user_id = ? AND org_id = ?", userID, orgID).Order("category, channel").Find(&prefs).Error; err != nil {
+		return nil, err
+	}
+	result := &apistructs.NotifyPreferenceSettings{
+		UserID:   userID,
+		OrgID:    orgID,
+		Timezone: s.Timezone,
+		QuietHours: apistructs.NotifyQuietHours{
+			Enabled: s.IsQuietEnabled,
+			Start:   s.QuietStart,
+			End:     s.QuietEnd,
+		},
+		DigestHour:    s.DigestHour,
+		DigestWeekday: s.DigestWeekday,
+		Preferences:   []apistructs.NotifyPreference{},
+	}
+	if result.Timezone == "" {
+		result.Timezone = m.loc.String()
+	}
+	for _, p := range prefs {
+		result.Preferences = append(result.Preferences, apistructs.NotifyPreference{
+			Category:  p.Category,
+			Channel:   apistructs.NotifyPreferenceChannel(p.Channel),
+			Enabled:   p.IsEnabled,
+			Frequency: apistructs.NotifyFrequency(p.Frequency),
+		})
+	}
+	return result, nil
+}
+
+// Update 覆盖用户在 org 下的通知偏好
+func (m *Manager) Update(userID string, orgID int64, req *apistructs.NotifyPreferenceUpdateRequest) (*apistructs.NotifyPreferenceSettings, error) {
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	tx := m.db.Begin()
+	var s Setting
+	err := tx.Where("user_id = ? AND org_id = ?", userID, orgID).First(&s).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		tx.Rollback()
+		return nil, err
+	}
+	s.UserID = userID
+	s.OrgID = orgID
+	s.Timezone = req.Timezone
+	s.IsQuietEnabled = req.QuietHours.Enabled
+	s.QuietStart = req.QuietHours.Start
+	s.QuietEnd = req.QuietHours.End
+	s.DigestHour = req.DigestHour
+	s.DigestWeekday = req.DigestWeekday
+	if err := tx.Save(&s).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Where("user_id = ? AND org_id = ?", userID, orgID).Delete(&Preference{}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	for _, p := range req.Preferences {
+		frequency := p.Frequency
+		if frequency == "" {
+			frequency = apistructs.NotifyFrequencyRealtime
+		}
+		if err := tx.Create(&Preference{
+			UserID:    userID,
+			OrgID:     orgID,
+			Category:  p.Category,
+			Channel:   string(p.Channel),
+			IsEnabled: p.Enabled,
+			Frequency: string(frequency),
+		}).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return m.Get(userID, orgID)
+}
+
+func (m *Manager) defaultSetting(userID string, orgID int64) Setting {
+	return Setting{
+		UserID:        userID,
+		OrgID:         orgID,
+		DigestHour:    m.cfg.DefaultDigestHour,
+		DigestWeekday: m.cfg.DefaultDigestWeekday,
+	}
+}
+
+// delivery 消息中发给某个用户的一个个人通知目标
+type delivery struct {
+	key     types.LabelKey
+	label   string
+	channel apistructs.NotifyPreferenceChannel
+	target  string
+	userID  string
+}
+
+// messageContent 个人通知(mbox, email, 钉钉工作通知)共用的消息内容
+type messageContent struct {
+	Template string            `json:"template"`
+	Params   map[string]string `json:"params"`
+	OrgID    int64             `json:"orgID"`
+}
+
+// Apply 对消息中的个人通知目标应用收件人的通知偏好:
+// 关闭的通知方式不再发送，去重窗口内相同的通知只发送一次，
+// 按天或按周接收以及处于免打扰时段的通知写入摘要队列，由 Run 到期后汇总发送。
+// 无法识别用户的目标保持原样发送。
+func (m *Manager) Apply(msg *types.Message) error {
+	if msg.SkipPreference() {
+		return nil
+	}
+	deliveries := collectDeliveries(msg)
+	users := make(map[string]bool)
+	for _, d := range deliveries {
+		if d.userID != "" {
+			users[d.userID] = true
+		}
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(msg.Content)
+	if err != nil {
+		return err
+	}
+	var c messageContent
+	digestible := json.Unmarshal(raw, &c) == nil && c.Template != ""
+	if c.OrgID == 0 && msg.CreateHistory != nil {
+		c.OrgID = msg.CreateHistory.OrgID
+	}
+	settings, prefs, err := m.load(users, c.OrgID)
+	if err != nil {
+		return err
+	}
+
+	now := m.now()
+	kept := make(map[types.LabelKey][]string)
+	var items []DigestItem
+	for _, d := range deliveries {
+		if d.userID == "" {
+			kept[d.key] = append(kept[d.key], d.target)
+			continue
+		}
+		act, dueAt := decide(settings[d.userID], prefs[d.userID], msg.Category, d.channel, now, m.loc)
+		if act == actionDrop {
+			continue
+		}
+		if m.duplicated(fingerprint(d.userID, d.label, d.target, raw), now) {
+			continue
+		}
+		if act == actionDefer && digestible {
+			items = append(items, DigestItem{
+				UserID:   d.userID,
+				OrgID:    c.OrgID,
+				Label:    d.label,
+				Target:   d.target,
+				Category: msg.Category,
+				Title:    template.Render(c.Params["title"], c.Params),
+				Content:  template.Render(c.Template, c.Params),
+				DueAt:    dueAt,
+			})
+			continue
+		}
+		kept[d.key] = append(kept[d.key], d.target)
+	}
+	for i := range items {
+		if err := m.db.Create(&items[i]).Error; err != nil {
+			// 写入失败时立即发送，避免通知丢失
+			logrus.Errorf("notify preference: save digest item for user %s: %v", items[i].UserID, err)
+			key := types.LabelKey(items[i].Label).NormalizeLabelKey()
+			kept[key] = append(kept[key], items[i].Target)
+		}
+	}
+	for _, d := range deliveries {
+		if targets := kept[d.key]; len(targets) > 0 {
+			msg.Labels[d.key] = targets
+		} else {
+			delete(msg.Labels, d.key)
+		}
+	}
+	return nil
+}
+
+// collectDeliveries 收集消息中按用户发送的目标，并通过 Recipients 找到对应的用户
+func collectDeliveries(msg *types.Message) []delivery {
+	var result []delivery
+	for k, v := range msg.Labels {
+		label := strings.TrimPrefix(string(k), "/")
+		channel, ok := labelChannels[label]
+		if !ok {
+			continue
+		}
+		targets, ok := toStrings(v)
+		if !ok {
+			continue
+		}
+		for _, t := range targets {
+			result = append(result, delivery{
+				key:     k,
+				label:   label,
+				channel: channel,
+				target:  t,
+				userID:  recipientUser(channel, t, msg.Recipients),
+			})
+		}
+	}
+	return result
+}
+
+func recipientUser(channel apistructs.NotifyPreferenceChannel, target string, recipients []types.Recipient) string {
+	if channel == apistructs.NotifyPreferenceChannelMBox {
+		return target
+	}
+	for _, r := range recipients {
+		switch {
+		case channel == apistructs.NotifyPreferenceChannelEmail && r.Email != "" && strings.EqualFold(r.Email, target):
+			return r.UserID
+		case channel == apistructs.NotifyPreferenceChannelIM && r.Mobile != "" && r.Mobile == target:
+			return r.UserID
+		}
+	}
+	return ""
+}
+
+// toStrings 兼容 []string 及 json 解析得到的 []interface{}
+func toStrings(v interface{}) ([]string, bool) {
+	switch targets := v.(type) {
+	case []string:
+		return targets, true
+	case []interface{}:
+		result := make([]string, 0, len(targets))
+		for _, t := range targets {
+			s, ok := t.(string)
+			if !ok {
+				return nil, false
+			}
+			result = append(result, s)
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+func (m *Manager) load(users map[string]bool, orgID int64) (map[string]*Setting, map[string][]Preference, error) {
+	userIDs := make([]string, 0, len(users))
+	for u := range users {
+		userIDs = append(userIDs, u)
+	}
+	var settings []Setting
+	if err := m.db.Where("user_id IN (?) AND org_id = ?", userIDs, orgID).Find(&settings).Error; err != nil {
+		return nil, nil, err
+	}
+	var prefs []Preference
+	if err := m.db.Where("user_id IN (?) AND org_id = ?", userIDs, orgID).Find(&prefs).Error; err != nil {
+		return nil, nil, err
+	}
+	settingMap := make(map[string]*Setting, len(settings))
+	for i := range settings {
+		settingMap[settings[i].UserID] = &settings[i]
+	}
+	for _, u := range userIDs {
+		if _, ok := settingMap[u]; !ok {
+			s := m.defaultSetting(u, orgID)
+			settingMap[u] = &s
+		}
+	}
+	prefMap := make(map[string][]Preference)
+	for _, p := range prefs {
+		prefMap[p.UserID] = append(prefMap[p.UserID], p)
+	}
+	return settingMap, prefMap, nil
+}
+
+// duplicated 判断去重窗口内是否已发送过相同的通知，未发送过时记录指纹。
+// 数据库异常时按未发送处理，宁可重复也不漏发。
+func (m *Manager) duplicated(fp string, now time.Time) bool {
+	if m.cfg.DedupWindow <= 0 {
+		return false
+	}
+	expireAt := now.Add(m.cfg.DedupWindow)
+	// 过期的指纹直接续期，多实例时通过条件更新保证只有一个实例发送
+	res := m.db.Model(&DedupRecord{}).Where("fingerprint = ? AND expire_at <= ?", fp, now).Update("expire_at", expireAt)
+	if res.Error == nil && res.RowsAffected > 0 {
+		return false
+	}
+	if err := m.db.Create(&DedupRecord{Fingerprint: fp, ExpireAt: expireAt}).Error; err == nil {
+		return false
+	}
+	var count int
+	if err := m.db.Model(&DedupRecord{}).Where("fingerprint = ? AND expire_at > ?", fp, now).Count(&count).Error; err != nil {
+		logrus.Errorf("notify preference: check duplicated notification: %v", err)
+		return false
+	}
+	return count > 0
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preference
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/types"
+)
+
+func TestCollectDeliveries(t *testing.T) {
+	msg := &types.Message{
+		Labels: map[types.LabelKey]interface{}{
+			"/MBOX":                 []string{"1", "2"},
+			"/EMAIL":                []interface{}{"Alice@erda.cloud", "external@example.com"},
+			"/DINGTALK_WORK_NOTICE": []string{"13800000000"},
+			"/DINGDING":             []string{"https://oapi.dingtalk.com/robot/send"},
+		},
+		Recipients: []types.Recipient{
+			{UserID: "1", Email: "alice@erda.cloud", Mobile: "13800000000"},
+		},
+	}
+	users := make(map[string]string)
+	for _, d := range collectDeliveries(msg) {
+		users[d.label+"/"+d.target] = d.userID
+	}
+	assert.Equal(t, map[string]string{
+		"MBOX/1":                           "1",
+		"MBOX/2":                           "2",
+		"EMAIL/Alice@erda.cloud":           "1",
+		"EMAIL/external@example.com":       "",
+		"DINGTALK_WORK_NOTICE/13800000000": "1",
+	}, users)
+}
+
+func TestDigestMessage(t *testing.T) {
+	now := time.Now()
+	items := []DigestItem{
+		{UserID: "1", OrgID: 1, Label: "MBOX", Target: "1", Category: "git", Title: "push", Content: "master"},
+		{UserID: "1", OrgID: 1, Label: "EMAIL", Target: "a@erda.cloud", Category: "git", Title: "push", Content: "master"},
+		{UserID: "1", OrgID: 1, Label: "MBOX", Target: "1", Category: "issue", Title: "assigned", Content: "bug"},
+	}
+	groups := groupItems(items)
+	assert.Len(t, groups, 2)
+	assert.Len(t, groups[0], 2)
+
+	msg := digestMessage(groups[0], now)
+	assert.True(t, msg.SkipPreference())
+	assert.Equal(t, "", msg.Category)
+	assert.Equal(t, []string{"1"}, msg.Labels["MBOX"])
+	content := msg.Content.(map[string]interface{})
+	assert.Equal(t, "通知摘要: 共 2 条通知", content["params"].(map[string]string)["title"])
+	assert.Equal(t, "### push\n\nmaster\n\n### assigned\n\nbug", content["template"])
+
+	msg = digestMessage(groups[1], now)
+	assert.Equal(t, "git", msg.Category)
+	content = msg.Content.(map[string]interface{})
+	assert.Equal(t, "push", content["params"].(map[string]string)["title"])
+	assert.Equal(t, "master", content["template"])
+}
+
+func TestToStrings(t *testing.T) {
+	got, ok := toStrings([]interface{}{"a", "b"})
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a", "b"}, got)
+
+	_, ok = toStrings([]interface{}{"a", 1})
+	assert.False(t, ok)
+
+	_, ok = toStrings(map[string]interface{}{"atMobiles": []string{}})
+	assert.False(t, ok)
+}
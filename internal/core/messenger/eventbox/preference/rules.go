@@ -0,0 +1,213 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preference
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+type action int
+
+const (
+	actionSend action = iota
+	actionDrop
+	// actionDefer 写入摘要队列，到期后汇总发送
+	actionDefer
+)
+
+// decide 根据用户设置和偏好决定一条个人通知立即发送、丢弃还是延迟到某个时间发送
+func decide(s *Setting, prefs []Preference, category string, channel apistructs.NotifyPreferenceChannel,
+	now time.Time, loc *time.Location) (action, time.Time) {
+	enabled, frequency := match(prefs, category, channel)
+	if !enabled {
+		return actionDrop, time.Time{}
+	}
+	if s == nil {
+		s = &Setting{}
+	}
+	if frequency == apistructs.NotifyFrequencyDaily || frequency == apistructs.NotifyFrequencyWeekly {
+		at := nextDigest(s, loc, frequency, now)
+		if until := quietUntil(s, loc, at); !until.IsZero() {
+			at = until
+		}
+		return actionDefer, at
+	}
+	if until := quietUntil(s, loc, now); !until.IsZero() {
+		return actionDefer, until
+	}
+	return actionSend, time.Time{}
+}
+
+// match 返回事件类别在通知方式上的偏好，精确匹配的类别优先于 *，没有设置时默认实时接收
+func match(prefs []Preference, category string, channel apistructs.NotifyPreferenceChannel) (bool, apistructs.NotifyFrequency) {
+	var matched *Preference
+	for i := range prefs {
+		p := &prefs[i]
+		if p.Channel != string(channel) {
+			continue
+		}
+		if category != "" && p.Category == category {
+			matched = p
+			break
+		}
+		if p.Category == apistructs.NotifyCategoryAll && matched == nil {
+			matched = p
+		}
+	}
+	if matched == nil {
+		return true, apistructs.NotifyFrequencyRealtime
+	}
+	if matched.Frequency == "" {
+		return matched.IsEnabled, apistructs.NotifyFrequencyRealtime
+	}
+	return matched.IsEnabled, apistructs.NotifyFrequency(matched.Frequency)
+}
+
+// quietUntil 返回 t 所在免打扰时段的结束时间，t 不在免打扰时段时返回零值
+func quietUntil(s *Setting, loc *time.Location, t time.Time) time.Time {
+	if !s.IsQuietEnabled {
+		return time.Time{}
+	}
+	start, err := parseClock(s.QuietStart)
+	if err != nil {
+		return time.Time{}
+	}
+	end, err := parseClock(s.QuietEnd)
+	if err != nil || start == end {
+		return time.Time{}
+	}
+	local := t.In(s.location(loc))
+	minute := local.Hour()*60 + local.Minute()
+	day := func(offset int) time.Time {
+		return time.Date(local.Year(), local.Month(), local.Day()+offset, 0, end, 0, 0, local.Location())
+	}
+	switch {
+	case start < end && minute >= start && minute < end:
+		return day(0)
+	case start > end && minute >= start:
+		// 跨天的时段，如 22:00 - 08:00
+		return day(1)
+	case start > end && minute < end:
+		return day(0)
+	}
+	return time.Time{}
+}
+
+// nextDigest 返回 t 之后下一次发送摘要的时间
+func nextDigest(s *Setting, loc *time.Location, frequency apistructs.NotifyFrequency, t time.Time) time.Time {
+	local := t.In(s.location(loc))
+	at := time.Date(local.Year(), local.Month(), local.Day(), s.DigestHour, 0, 0, 0, local.Location())
+	if frequency == apistructs.NotifyFrequencyWeekly {
+		at = at.AddDate(0, 0, (s.DigestWeekday-int(at.Weekday())+7)%7)
+		if !at.After(t) {
+			at = at.AddDate(0, 0, 7)
+		}
+		return at
+	}
+	if !at.After(t) {
+		at = at.AddDate(0, 0, 1)
+	}
+	return at
+}
+
+// location 返回用户设置的时区，未设置或不合法时使用 def
+func (s *Setting) location(def *time.Location) *time.Location {
+	if s.Timezone == "" {
+		return def
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return def
+	}
+	return loc
+}
+
+// parseClock 将 HH:MM 解析为距 00:00 的分钟数
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.Errorf("invalid time %q, should be HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// fingerprint 计算用于去重的通知指纹
+func fingerprint(userID, label, target string, content []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", userID, label, target)
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+var (
+	validChannels = map[apistructs.NotifyPreferenceChannel]bool{
+		apistructs.NotifyPreferenceChannelMBox:  true,
+		apistructs.NotifyPreferenceChannelEmail: true,
+		apistructs.NotifyPreferenceChannelIM:    true,
+	}
+	validFrequencies = map[apistructs.NotifyFrequency]bool{
+		apistructs.NotifyFrequencyRealtime: true,
+		apistructs.NotifyFrequencyDaily:    true,
+		apistructs.NotifyFrequencyWeekly:   true,
+	}
+)
+
+func validate(req *apistructs.NotifyPreferenceUpdateRequest) error {
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			return errors.Errorf("invalid timezone %q", req.Timezone)
+		}
+	}
+	if req.QuietHours.Enabled {
+		if _, err := parseClock(req.QuietHours.Start); err != nil {
+			return errors.Wrap(err, "quietHours.start")
+		}
+		if _, err := parseClock(req.QuietHours.End); err != nil {
+			return errors.Wrap(err, "quietHours.end")
+		}
+	}
+	if req.DigestHour < 0 || req.DigestHour > 23 {
+		return errors.Errorf("invalid digestHour %d, should be 0-23", req.DigestHour)
+	}
+	if req.DigestWeekday < 0 || req.DigestWeekday > 6 {
+		return errors.Errorf("invalid digestWeekday %d, should be 0-6", req.DigestWeekday)
+	}
+	seen := make(map[string]bool)
+	for _, p := range req.Preferences {
+		if p.Category == "" || len(p.Category) > 64 {
+			return errors.Errorf("invalid category %q", p.Category)
+		}
+		if !validChannels[p.Channel] {
+			return errors.Errorf("invalid channel %q, should be one of mbox, email, im", p.Channel)
+		}
+		if p.Frequency != "" && !validFrequencies[p.Frequency] {
+			return errors.Errorf("invalid frequency %q, should be one of realtime, daily, weekly", p.Frequency)
+		}
+		key := strings.Join([]string{p.Category, string(p.Channel)}, "/")
+		if seen[key] {
+			return errors.Errorf("duplicate preference, category: %s, channel: %s", p.Category, p.Channel)
+		}
+		seen[key] = true
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preference
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone %s is not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestMatch(t *testing.T) {
+	prefs := []Preference{
+		{Category: "*", Channel: "email", IsEnabled: true, Frequency: "daily"},
+		{Category: "git", Channel: "email", IsEnabled: false, Frequency: "realtime"},
+		{Category: "*", Channel: "mbox", IsEnabled: true},
+	}
+	enabled, frequency := match(prefs, "git", apistructs.NotifyPreferenceChannelEmail)
+	assert.False(t, enabled)
+	assert.Equal(t, apistructs.NotifyFrequencyRealtime, frequency)
+
+	enabled, frequency = match(prefs, "workflow", apistructs.NotifyPreferenceChannelEmail)
+	assert.True(t, enabled)
+	assert.Equal(t, apistructs.NotifyFrequencyDaily, frequency)
+
+	enabled, frequency = match(prefs, "", apistructs.NotifyPreferenceChannelMBox)
+	assert.True(t, enabled)
+	assert.Equal(t, apistructs.NotifyFrequencyRealtime, frequency)
+
+	enabled, frequency = match(prefs, "git", apistructs.NotifyPreferenceChannelIM)
+	assert.True(t, enabled)
+	assert.Equal(t, apistructs.NotifyFrequencyRealtime, frequency)
+}
+
+func TestQuietUntil(t *testing.T) {
+	loc := mustLocation(t, "Asia/Shanghai")
+	overnight := &Setting{IsQuietEnabled: true, QuietStart: "22:00", QuietEnd: "08:00"}
+	daytime := &Setting{IsQuietEnabled: true, QuietStart: "12:00", QuietEnd: "13:30"}
+	tests := []struct {
+		name    string
+		setting *Setting
+		now     time.Time
+		want    time.Time
+	}{
+		{"before midnight", overnight, time.Date(2026, 10, 19, 23, 0, 0, 0, loc), time.Date(2026, 10, 20, 8, 0, 0, 0, loc)},
+		{"after midnight", overnight, time.Date(2026, 10, 20, 7, 59, 0, 0, loc), time.Date(2026, 10, 20, 8, 0, 0, 0, loc)},
+		{"end is not quiet", overnight, time.Date(2026, 10, 20, 8, 0, 0, 0, loc), time.Time{}},
+		{"daytime", daytime, time.Date(2026, 10, 20, 12, 30, 0, 0, loc), time.Date(2026, 10, 20, 13, 30, 0, 0, loc)},
+		{"outside daytime", daytime, time.Date(2026, 10, 20, 14, 0, 0, 0, loc), time.Time{}},
+		{"disabled", &Setting{QuietStart: "00:00", QuietEnd: "23:59"}, time.Date(2026, 10, 20, 14, 0, 0, 0, loc), time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := quietUntil(tt.setting, loc, tt.now)
+			assert.True(t, tt.want.Equal(got), "want %v, got %v", tt.want, got)
+		})
+	}
+}
+
+func TestQuietUntil_Timezone(t *testing.T) {
+	s := &Setting{Timezone: "America/New_York", IsQuietEnabled: true, QuietStart: "22:00", QuietEnd: "07:00"}
+	ny := mustLocation(t, "America/New_York")
+	// UTC 2026-10-20 02:00 即纽约时间 2026-10-19 22:00
+	now := time.Date(2026, 10, 20, 2, 0, 0, 0, time.UTC)
+	got := quietUntil(s, time.UTC, now)
+	assert.True(t, time.Date(2026, 10, 20, 7, 0, 0, 0, ny).Equal(got), "got %v", got)
+}
+
+func TestNextDigest(t *testing.T) {
+	loc := mustLocation(t, "Asia/Shanghai")
+	s := &Setting{DigestHour: 9, DigestWeekday: 1}
+	// 2026-10-19 是周一
+	before := time.Date(2026, 10, 19, 8, 0, 0, 0, loc)
+	after := time.Date(2026, 10, 19, 10, 0, 0, 0, loc)
+
+	assert.Equal(t, time.Date(2026, 10, 19, 9, 0, 0, 0, loc), nextDigest(s, loc, apistructs.NotifyFrequencyDaily, before))
+	assert.Equal(t, time.Date(2026, 10, 20, 9, 0, 0, 0, loc), nextDigest(s, loc, apistructs.NotifyFrequencyDaily, after))
+	assert.Equal(t, time.Date(2026, 10, 19, 9, 0, 0, 0, loc), nextDigest(s, loc, apistructs.NotifyFrequencyWeekly, before))
+	assert.Equal(t, time.Date(2026, 10, 26, 9, 0, 0, 0, loc), nextDigest(s, loc, apistructs.NotifyFrequencyWeekly, after))
+
+	s.DigestWeekday = 5
+	assert.Equal(t, time.Date(2026, 10, 23, 9, 0, 0, 0, loc), nextDigest(s, loc, apistructs.NotifyFrequencyWeekly, after))
+}
+
+func TestDecide(t *testing.T) {
+	loc := mustLocation(t, "Asia/Shanghai")
+	s := &Setting{IsQuietEnabled: true, QuietStart: "22:00", QuietEnd: "08:00", DigestHour: 7}
+	prefs := []Preference{
+		{Category: "git", Channel: "mbox", IsEnabled: false},
+		{Category: "issue", Channel: "mbox", IsEnabled: true, Frequency: "daily"},
+	}
+	noon := time.Date(2026, 10, 19, 12, 0, 0, 0, loc)
+	night := time.Date(2026, 10, 19, 23, 0, 0, 0, loc)
+
+	act, _ := decide(s, prefs, "git", apistructs.NotifyPreferenceChannelMBox, noon, loc)
+	assert.Equal(t, actionDrop, act)
+
+	act, _ = decide(s, prefs, "workflow", apistructs.NotifyPreferenceChannelMBox, noon, loc)
+	assert.Equal(t, actionSend, act)
+
+	act, at := decide(s, prefs, "workflow", apistructs.NotifyPreferenceChannelMBox, night, loc)
+	assert.Equal(t, actionDefer, act)
+	assert.Equal(t, time.Date(2026, 10, 20, 8, 0, 0, 0, loc), at)
+
+	// 摘要发送时间落在免打扰时段内时顺延到时段结束
+	act, at = decide(s, prefs, "issue", apistructs.NotifyPreferenceChannelMBox, noon, loc)
+	assert.Equal(t, actionDefer, act)
+	assert.Equal(t, time.Date(2026, 10, 20, 8, 0, 0, 0, loc), at)
+
+	act, _ = decide(nil, nil, "", apistructs.NotifyPreferenceChannelEmail, night, loc)
+	assert.Equal(t, actionSend, act)
+}
+
+func TestValidate(t *testing.T) {
+	valid := apistructs.NotifyPreferenceUpdateRequest{
+		Timezone:   "Asia/Shanghai",
+		QuietHours: apistructs.NotifyQuietHours{Enabled: true, Start: "22:00", End: "08:00"},
+		DigestHour: 9,
+		Preferences: []apistructs.NotifyPreference{
+			{Category: "*", Channel: apistructs.NotifyPreferenceChannelEmail, Enabled: true, Frequency: apistructs.NotifyFrequencyDaily},
+			{Category: "git", Channel: apistructs.NotifyPreferenceChannelEmail},
+		},
+	}
+	assert.NoError(t, validate(&valid))
+
+	tests := []func(r *apistructs.NotifyPreferenceUpdateRequest){
+		func(r *apistructs.NotifyPreferenceUpdateRequest) { r.Timezone = "Mars/Olympus" },
+		func(r *apistructs.NotifyPreferenceUpdateRequest) { r.QuietHours.Start = "25:00" },
+		func(r *apistructs.NotifyPreferenceUpdateRequest) { r.DigestHour = 24 },
+		func(r *apistructs.NotifyPreferenceUpdateRequest) { r.DigestWeekday = 7 },
+		func(r *apistructs.NotifyPreferenceUpdateRequest) { r.Preferences[0].Channel = "sms" },
+		func(r *apistructs.NotifyPreferenceUpdateRequest) { r.Preferences[0].Frequency = "hourly" },
+		func(r *apistructs.NotifyPreferenceUpdateRequest) { r.Preferences[1].Category = "*" },
+		func(r *apistructs.NotifyPreferenceUpdateRequest) { r.Preferences[1].Category = "" },
+	}
+	for i, modify := range tests {
+		req := valid
+		req.Preferences = append([]apistructs.NotifyPreference(nil), valid.Preferences...)
+		modify(&req)
+		assert.Error(t, validate(&req), "case %d", i)
+	}
+}
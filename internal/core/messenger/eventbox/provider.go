@@ -16,7 +16,9 @@ package eventbox
 
 import (
 	"context"
+	"time"
 
+	"github.com/jinzhu/gorm"
 	"github.com/sirupsen/logrus"
 
 	"github.com/erda-project/erda-infra/base/servicehub"
@@ -27,14 +29,57 @@ import (
 	"github.com/erda-project/erda/internal/core/legacy/services/dingtalk/api/interfaces"
 	"github.com/erda-project/erda/internal/core/messenger/eventbox/input/http"
 	"github.com/erda-project/erda/internal/core/messenger/eventbox/monitor"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/preference"
 	"github.com/erda-project/erda/internal/core/messenger/eventbox/register"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/stream"
 	"github.com/erda-project/erda/internal/core/messenger/eventbox/webhook"
 	"github.com/erda-project/erda/internal/core/org"
 	"github.com/erda-project/erda/pkg/common/apis"
 	perm "github.com/erda-project/erda/pkg/common/permission"
 )
 
-type config struct{}
+type config struct {
+	WebhookDelivery struct {
+		MaxAttempts         int           `file:"max_attempts" env:"WEBHOOK_DELIVERY_MAX_ATTEMPTS" default:"8"`
+		BaseBackoff         time.Duration `file:"base_backoff" env:"WEBHOOK_DELIVERY_BASE_BACKOFF" default:"30s"`
+		MaxBackoff          time.Duration `file:"max_backoff" env:"WEBHOOK_DELIVERY_MAX_BACKOFF" default:"1h"`
+		Timeout             time.Duration `file:"timeout" env:"WEBHOOK_DELIVERY_TIMEOUT" default:"10s"`
+		PollInterval        time.Duration `file:"poll_interval" env:"WEBHOOK_DELIVERY_POLL_INTERVAL" default:"2s"`
+		BatchSize           int           `file:"batch_size" env:"WEBHOOK_DELIVERY_BATCH_SIZE" default:"100"`
+		Concurrency         int           `file:"concurrency" env:"WEBHOOK_DELIVERY_CONCURRENCY" default:"10"`
+		DisableThreshold    int           `file:"disable_threshold" env:"WEBHOOK_DELIVERY_DISABLE_THRESHOLD" default:"20"`
+		Retention           time.Duration `file:"retention" env:"WEBHOOK_DELIVERY_RETENTION" default:"168h"`
+		MaxResponseBodySize int           `file:"max_response_body_size" env:"WEBHOOK_DELIVERY_MAX_RESPONSE_BODY_SIZE" default:"65536"`
+	} `file:"webhook_delivery"`
+	NotifyPreference struct {
+		DedupWindow          time.Duration `file:"dedup_window" env:"NOTIFY_PREFERENCE_DEDUP_WINDOW" default:"10m"`
+		DefaultTimezone      string        `file:"default_timezone" env:"NOTIFY_PREFERENCE_DEFAULT_TIMEZONE" default:"Asia/Shanghai"`
+		DefaultDigestHour    int           `file:"default_digest_hour" env:"NOTIFY_PREFERENCE_DEFAULT_DIGEST_HOUR" default:"9"`
+		DefaultDigestWeekday int           `file:"default_digest_weekday" env:"NOTIFY_PREFERENCE_DEFAULT_DIGEST_WEEKDAY" default:"1"`
+		PollInterval         time.Duration `file:"poll_interval" env:"NOTIFY_PREFERENCE_POLL_INTERVAL" default:"1m"`
+		BatchSize            int           `file:"batch_size" env:"NOTIFY_PREFERENCE_BATCH_SIZE" default:"500"`
+	} `file:"notify_preference"`
+	EventStream struct {
+		Enabled       bool          `file:"enabled" env:"EVENT_STREAM_ENABLED" default:"false"`
+		Sink          string        `file:"sink" env:"EVENT_STREAM_SINK" default:"kafka"`
+		Source        string        `file:"source" env:"EVENT_STREAM_SOURCE" default:"/erda"`
+		SchemaBaseURL string        `file:"schema_base_url" env:"EVENT_STREAM_SCHEMA_BASE_URL"`
+		KafkaBrokers  string        `file:"kafka_brokers" env:"EVENT_STREAM_KAFKA_BROKERS"`
+		KafkaTopic    string        `file:"kafka_topic" env:"EVENT_STREAM_KAFKA_TOPIC" default:"erda-events"`
+		KafkaUsername string        `file:"kafka_username" env:"EVENT_STREAM_KAFKA_USERNAME"`
+		KafkaPassword string        `file:"kafka_password" env:"EVENT_STREAM_KAFKA_PASSWORD"`
+		NATSURL       string        `file:"nats_url" env:"EVENT_STREAM_NATS_URL"`
+		NATSSubject   string        `file:"nats_subject" env:"EVENT_STREAM_NATS_SUBJECT" default:"erda.events"`
+		NATSJetStream bool          `file:"nats_jetstream" env:"EVENT_STREAM_NATS_JETSTREAM" default:"true"`
+		Timeout       time.Duration `file:"timeout" env:"EVENT_STREAM_TIMEOUT" default:"10s"`
+		PollInterval  time.Duration `file:"poll_interval" env:"EVENT_STREAM_POLL_INTERVAL" default:"1s"`
+		BatchSize     int           `file:"batch_size" env:"EVENT_STREAM_BATCH_SIZE" default:"100"`
+		Concurrency   int           `file:"concurrency" env:"EVENT_STREAM_CONCURRENCY" default:"10"`
+		BaseBackoff   time.Duration `file:"base_backoff" env:"EVENT_STREAM_BASE_BACKOFF" default:"1s"`
+		MaxBackoff    time.Duration `file:"max_backoff" env:"EVENT_STREAM_MAX_BACKOFF" default:"5m"`
+		ClaimTimeout  time.Duration `file:"claim_timeout" env:"EVENT_STREAM_CLAIM_TIMEOUT" default:"5m"`
+	} `file:"event_stream"`
+}
 
 type provider struct {
 	DingtalkApiClient interfaces.DingTalkApiClientFactory `autowired:"dingtalk.api"`
@@ -46,6 +91,10 @@ type provider struct {
 	Perm            perm.Interface          `autowired:"permission"`
 	CoreService     legacy.ExposedInterface `autowired:"core-services"`
 	Org             org.Interface
+	DB              *gorm.DB `autowired:"mysql-client"`
+	preference      *preference.Manager
+	streamer        *stream.Streamer
+	eventTypes      *stream.Registry
 }
 
 func (p *provider) Run(ctx context.Context) error {
@@ -73,6 +122,30 @@ func (p *provider) Init(ctx servicehub.Context) error {
 		return err
 	}
 	wh.CoreService = p.CoreService
+	wh.SetDeliverer(webhook.NewDeliverer(p.DB, wh.Impl(), webhook.DeliveryConfig{
+		MaxAttempts:         p.C.WebhookDelivery.MaxAttempts,
+		BaseBackoff:         p.C.WebhookDelivery.BaseBackoff,
+		MaxBackoff:          p.C.WebhookDelivery.MaxBackoff,
+		Timeout:             p.C.WebhookDelivery.Timeout,
+		PollInterval:        p.C.WebhookDelivery.PollInterval,
+		BatchSize:           p.C.WebhookDelivery.BatchSize,
+		Concurrency:         p.C.WebhookDelivery.Concurrency,
+		DisableThreshold:    p.C.WebhookDelivery.DisableThreshold,
+		Retention:           p.C.WebhookDelivery.Retention,
+		MaxResponseBodySize: p.C.WebhookDelivery.MaxResponseBodySize,
+	}))
+	p.preference = preference.New(p.DB, preference.Config{
+		DedupWindow:          p.C.NotifyPreference.DedupWindow,
+		DefaultTimezone:      p.C.NotifyPreference.DefaultTimezone,
+		DefaultDigestHour:    p.C.NotifyPreference.DefaultDigestHour,
+		DefaultDigestWeekday: p.C.NotifyPreference.DefaultDigestWeekday,
+		PollInterval:         p.C.NotifyPreference.PollInterval,
+		BatchSize:            p.C.NotifyPreference.BatchSize,
+	})
+	if err := p.initEventStream(); err != nil {
+		logrus.Error(err)
+		return err
+	}
 	mon, err := monitor.NewMonitorHTTP()
 	if err != nil {
 		logrus.Error("Monitor init is failed err is ", err)
@@ -91,6 +164,9 @@ func (p *provider) Init(ctx servicehub.Context) error {
 	if p.Register != nil {
 		type EventBoxService = eventpb.EventBoxServiceServer
 		eventpb.RegisterEventBoxServiceImp(p.Register, p.eventBoxService, apis.Options())
+		p.initWebhookDeliveryEndpoints()
+		p.initNotifyPreferenceEndpoints()
+		p.initEventTypeEndpoints()
 	}
 	return nil
 }
@@ -0,0 +1,120 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/crypto/uuid"
+)
+
+const (
+	// SpecVersion CloudEvents 规范版本
+	SpecVersion = "1.0"
+	// ContentType 结构化模式(structured content mode)下消息的 content-type
+	ContentType = "application/cloudevents+json"
+
+	// eventbox 事件头中 timestamp 的格式
+	timestampLayout = "2006-01-02 15:04:05"
+)
+
+// CloudEvent 结构化模式的 CloudEvents 1.0 消息，
+// 除 partitionkey (Partitioning 扩展) 外，erda 的事件头以 erda 前缀的扩展属性携带
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	DataSchema      string          `json:"dataschema,omitempty"`
+	Data            json.RawMessage `json:"data"`
+
+	PartitionKey  string `json:"partitionkey"`
+	ErdaEvent     string `json:"erdaevent"`
+	ErdaAction    string `json:"erdaaction,omitempty"`
+	ErdaOrgID     string `json:"erdaorgid,omitempty"`
+	ErdaProjectID string `json:"erdaprojectid,omitempty"`
+	ErdaAppID     string `json:"erdaappid,omitempty"`
+	ErdaEnv       string `json:"erdaenv,omitempty"`
+}
+
+// NewCloudEvent 将 eventbox 事件转换为 CloudEvent，
+// source 为 <sourcePrefix>/orgs/<org>/projects/<project>/applications/<app>，
+// schemaBaseURL 不为空时 dataschema 指向事件类型的查询接口
+func NewCloudEvent(r *Registry, sourcePrefix, schemaBaseURL string, label apistructs.EventHeader, content json.RawMessage) *CloudEvent {
+	if len(content) == 0 {
+		content = json.RawMessage("null")
+	}
+	key := r.PartitionKey(label, content)
+	e := &CloudEvent{
+		SpecVersion:     SpecVersion,
+		ID:              uuid.UUID(),
+		Source:          eventSource(sourcePrefix, label),
+		Type:            r.CloudEventType(label.Event, label.Action),
+		Subject:         key,
+		Time:            eventTime(label.TimeStamp),
+		DataContentType: "application/json",
+		Data:            content,
+		PartitionKey:    key,
+		ErdaEvent:       label.Event,
+		ErdaAction:      label.Action,
+		ErdaOrgID:       scopeID(label.OrgID),
+		ErdaProjectID:   scopeID(label.ProjectID),
+		ErdaAppID:       scopeID(label.ApplicationID),
+		ErdaEnv:         strings.ToLower(label.Env),
+	}
+	if _, ok := r.Get(label.Event); ok && schemaBaseURL != "" {
+		e.DataSchema = strings.TrimSuffix(schemaBaseURL, "/") + "/api/event-types/" + label.Event
+	}
+	return e
+}
+
+func eventSource(prefix string, label apistructs.EventHeader) string {
+	source := strings.TrimSuffix(prefix, "/")
+	for _, s := range []struct{ kind, id string }{
+		{"orgs", label.OrgID},
+		{"projects", label.ProjectID},
+		{"applications", label.ApplicationID},
+	} {
+		if !validID(s.id) {
+			break
+		}
+		source += "/" + s.kind + "/" + s.id
+	}
+	if source == "" {
+		return "/"
+	}
+	return source
+}
+
+// eventTime 解析事件头中的时间，事件头不带时区，按本地时区解析；缺失或格式错误时使用当前时间
+func eventTime(timestamp string) time.Time {
+	if t, err := time.ParseInLocation(timestampLayout, timestamp, time.Local); err == nil {
+		return t
+	}
+	return time.Now()
+}
+
+func scopeID(id string) string {
+	if validID(id) {
+		return id
+	}
+	return ""
+}
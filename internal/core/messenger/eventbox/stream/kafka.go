@@ -0,0 +1,141 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// Sink 事件流的投递目标
+type Sink interface {
+	// Publish 同步发送一条结构化模式的 CloudEvent，返回 nil 表示目标已确认持久化。
+	// key 为分区键，id 为 CloudEvent id
+	Publish(ctx context.Context, key, id string, payload []byte) error
+	Close() error
+}
+
+// KafkaConfig kafka 投递配置
+type KafkaConfig struct {
+	Brokers  []string
+	Topic    string
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+// KafkaSink 以分区键作为 message key 写入 kafka，同一资源的事件落在同一分区内，
+// 等待所有 ISR 副本确认后才视为成功。producer 在首次发布时创建，kafka 不可用时不影响启动
+type KafkaSink struct {
+	topic   string
+	brokers []string
+	config  *sarama.Config
+
+	mu       sync.Mutex
+	producer sarama.SyncProducer
+}
+
+func NewKafkaSink(cfg KafkaConfig) (*KafkaSink, error) {
+	brokers := trimBrokers(cfg.Brokers)
+	if len(brokers) == 0 || cfg.Topic == "" {
+		return nil, fmt.Errorf("kafka brokers and topic are required")
+	}
+	c := sarama.NewConfig()
+	c.ClientID = "erda-eventbox"
+	c.Producer.RequiredAcks = sarama.WaitForAll
+	c.Producer.Return.Successes = true
+	c.Producer.Partitioner = sarama.NewHashPartitioner
+	// 只有一个在途请求，重试时不会乱序
+	c.Net.MaxOpenRequests = 1
+	if cfg.Timeout > 0 {
+		c.Producer.Timeout = cfg.Timeout
+		c.Net.DialTimeout = cfg.Timeout
+		c.Net.ReadTimeout = cfg.Timeout
+		c.Net.WriteTimeout = cfg.Timeout
+	}
+	if cfg.Username != "" {
+		c.Net.SASL.Enable = true
+		c.Net.SASL.User = cfg.Username
+		c.Net.SASL.Password = cfg.Password
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid kafka config: %v", err)
+	}
+	return &KafkaSink{topic: cfg.Topic, brokers: brokers, config: c}, nil
+}
+
+func (k *KafkaSink) Publish(_ context.Context, key, _ string, payload []byte) error {
+	producer, err := k.getProducer()
+	if err != nil {
+		return err
+	}
+	_, _, err = producer.SendMessage(kafkaMessage(k.topic, key, payload))
+	return err
+}
+
+func (k *KafkaSink) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.producer == nil {
+		return nil
+	}
+	err := k.producer.Close()
+	k.producer = nil
+	return err
+}
+
+// getProducer 返回 producer，未创建时连接 kafka 创建，失败时下次发布再重试
+func (k *KafkaSink) getProducer() (sarama.SyncProducer, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.producer != nil {
+		return k.producer, nil
+	}
+	producer, err := sarama.NewSyncProducer(k.brokers, k.config)
+	if err != nil {
+		return nil, fmt.Errorf("create kafka producer: %v", err)
+	}
+	k.producer = producer
+	return producer, nil
+}
+
+// kafkaMessage 按 CloudEvents kafka 协议绑定的结构化模式构造消息
+func kafkaMessage(topic, key string, payload []byte) *sarama.ProducerMessage {
+	return &sarama.ProducerMessage{
+		Topic: topic,
+		Key:   sarama.StringEncoder(key),
+		Value: sarama.ByteEncoder(payload),
+		Headers: []sarama.RecordHeader{
+			{Key: []byte("content-type"), Value: []byte(ContentType)},
+		},
+	}
+}
+
+func trimBrokers(brokers []string) []string {
+	var res []string
+	for _, b := range brokers {
+		for _, s := range strings.Split(b, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				res = append(res, s)
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSConfig nats 投递配置
+type NATSConfig struct {
+	// URL nats://[user:password@]host:port，使用 tls:// 时建立 TLS 连接
+	URL     string
+	Subject string
+	// JetStream 为 true 时等待 JetStream 的发布确认，否则只保证 nats server 已收到
+	JetStream bool
+	Timeout   time.Duration
+}
+
+// NATSSink 通过 nats 发布事件。
+// 连接在首次发布时建立，断开后由客户端自动重连；JetStream 发布时以 CloudEvent id 作为 Nats-Msg-Id，
+// stream 可据此对重试产生的重复消息去重。
+type NATSSink struct {
+	cfg NATSConfig
+
+	mu sync.Mutex
+	nc *nats.Conn
+	js nats.JetStreamContext
+}
+
+func NewNATSSink(cfg NATSConfig) (*NATSSink, error) {
+	if cfg.URL == "" || cfg.Subject == "" {
+		return nil, fmt.Errorf("nats url and subject are required")
+	}
+	if strings.ContainsAny(cfg.Subject, " \t\r\n*>") {
+		return nil, fmt.Errorf("invalid nats subject: %q", cfg.Subject)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return &NATSSink{cfg: cfg}, nil
+}
+
+func (n *NATSSink) Publish(ctx context.Context, _, id string, payload []byte) error {
+	nc, js, err := n.conn()
+	if err != nil {
+		return fmt.Errorf("connect nats: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, n.cfg.Timeout)
+	defer cancel()
+
+	msg := nats.NewMsg(n.cfg.Subject)
+	msg.Data = payload
+	if js != nil {
+		msg.Header.Set("Content-Type", ContentType)
+		_, err := js.PublishMsg(msg, nats.MsgId(id), nats.Context(ctx))
+		if errors.Is(err, nats.ErrNoStreamResponse) {
+			return fmt.Errorf("no jetstream stream bound to subject")
+		}
+		return err
+	}
+	if nc.HeadersSupported() {
+		msg.Header.Set(nats.MsgIdHdr, id)
+		msg.Header.Set("Content-Type", ContentType)
+	} else {
+		msg.Header = nil
+	}
+	if err := nc.PublishMsg(msg); err != nil {
+		return err
+	}
+	// flush 返回时 server 已处理之前的消息
+	return nc.FlushWithContext(ctx)
+}
+
+func (n *NATSSink) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.nc != nil {
+		n.nc.Close()
+	}
+	n.nc, n.js = nil, nil
+	return nil
+}
+
+// conn 返回可用的连接，未连接或连接已关闭时重新建立
+func (n *NATSSink) conn() (*nats.Conn, nats.JetStreamContext, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.nc != nil && !n.nc.IsClosed() {
+		return n.nc, n.js, nil
+	}
+	nc, err := nats.Connect(n.cfg.URL,
+		nats.Name("erda-eventbox"),
+		nats.Timeout(n.cfg.Timeout),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	var js nats.JetStreamContext
+	if n.cfg.JetStream {
+		if js, err = nc.JetStream(); err != nil {
+			nc.Close()
+			return nil, nil, err
+		}
+	}
+	n.nc, n.js = nc, js
+	return nc, js, nil
+}
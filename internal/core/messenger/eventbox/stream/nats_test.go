@@ -0,0 +1,144 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNATS 模拟 nats server，ack 为空时不绑定 stream，返回 503
+type fakeNATS struct {
+	ln        net.Listener
+	jetstream bool
+	ack       string
+	received  chan string
+}
+
+func newFakeNATS(t *testing.T, jetstream bool, ack string) *fakeNATS {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := &fakeNATS{ln: ln, jetstream: jetstream, ack: ack, received: make(chan string, 10)}
+	go s.serve()
+	return s
+}
+
+func (s *fakeNATS) serve() {
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	_, _ = io.WriteString(conn, `INFO {"server_id":"fake","proto":1,"headers":true,"max_payload":1048576}`+"\r\n")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "PING":
+			_, _ = io.WriteString(conn, "PONG\r\n")
+		case "PUB":
+			// 创建 JetStream 上下文时查询账号信息
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+			if _, err := io.ReadFull(r, make([]byte, size+2)); err != nil {
+				return
+			}
+			if fields[1] == "$JS.API.INFO" && len(fields) == 4 {
+				_, _ = io.WriteString(conn, fmt.Sprintf("MSG %s 1 2\r\n{}\r\n", fields[2]))
+			}
+		case "HPUB":
+			total, _ := strconv.Atoi(fields[len(fields)-1])
+			hdrSize, _ := strconv.Atoi(fields[len(fields)-2])
+			buf := make([]byte, total+2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			s.received <- string(buf[:hdrSize]) + string(buf[hdrSize:total])
+			if !s.jetstream {
+				continue
+			}
+			reply := fields[2]
+			// 确认前先发送 PING，客户端应回复 PONG 后继续等待确认
+			_, _ = io.WriteString(conn, "PING\r\n")
+			if s.ack == "" {
+				hdr := "NATS/1.0 503\r\n\r\n"
+				_, _ = io.WriteString(conn, fmt.Sprintf("HMSG %s 1 %d %d\r\n%s\r\n", reply, len(hdr), len(hdr), hdr))
+				continue
+			}
+			_, _ = io.WriteString(conn, fmt.Sprintf("MSG %s 1 %d\r\n%s\r\n", reply, len(s.ack), s.ack))
+		}
+	}
+}
+
+func TestNATSSinkJetStream(t *testing.T) {
+	s := newFakeNATS(t, true, `{"stream":"ERDA","seq":1}`)
+	defer s.ln.Close()
+
+	sink, err := NewNATSSink(NATSConfig{URL: "nats://" + s.ln.Addr().String(), Subject: "erda.events", JetStream: true, Timeout: 3 * time.Second})
+	assert.NoError(t, err)
+	defer sink.Close()
+	assert.NoError(t, sink.Publish(context.Background(), "pipeline/1", "event-1", []byte(`{"id":"event-1"}`)))
+
+	msg := <-s.received
+	assert.Contains(t, msg, "Nats-Msg-Id: event-1\r\n")
+	assert.Contains(t, msg, "Content-Type: "+ContentType)
+	assert.True(t, strings.HasSuffix(msg, `{"id":"event-1"}`))
+}
+
+func TestNATSSinkNoStream(t *testing.T) {
+	s := newFakeNATS(t, true, "")
+	defer s.ln.Close()
+
+	sink, err := NewNATSSink(NATSConfig{URL: "nats://" + s.ln.Addr().String(), Subject: "erda.events", JetStream: true, Timeout: 3 * time.Second})
+	assert.NoError(t, err)
+	defer sink.Close()
+	err = sink.Publish(context.Background(), "pipeline/1", "event-1", []byte(`{}`))
+	assert.EqualError(t, err, "no jetstream stream bound to subject")
+}
+
+func TestNATSSinkCore(t *testing.T) {
+	s := newFakeNATS(t, false, "")
+	defer s.ln.Close()
+
+	sink, err := NewNATSSink(NATSConfig{URL: "nats://" + s.ln.Addr().String(), Subject: "erda.events", Timeout: 3 * time.Second})
+	assert.NoError(t, err)
+	defer sink.Close()
+	assert.NoError(t, sink.Publish(context.Background(), "pipeline/1", "event-1", []byte(`{}`)))
+	assert.NoError(t, sink.Publish(context.Background(), "pipeline/1", "event-2", []byte(`{}`)))
+	assert.Contains(t, <-s.received, "event-1")
+	assert.Contains(t, <-s.received, "event-2")
+}
+
+func TestNewNATSSink(t *testing.T) {
+	_, err := NewNATSSink(NATSConfig{URL: "nats://127.0.0.1:4222"})
+	assert.Error(t, err)
+	_, err = NewNATSSink(NATSConfig{URL: "nats://127.0.0.1:4222", Subject: "erda.>"})
+	assert.Error(t, err)
+}
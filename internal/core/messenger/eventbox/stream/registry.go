@@ -0,0 +1,288 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+// TypePrefix 所有 CloudEvents type 的公共前缀
+const TypePrefix = "io.erda."
+
+// schemaVersion 内置事件类型的 schema 版本，事件内容不兼容变更时递增
+const schemaVersion = "v1"
+
+// Registry 事件类型注册表，记录事件的 CloudEvents type、分区键及内容 schema。
+// 未注册的事件同样会被推送，只是没有 dataschema，分区键退化为事件所属的 org/project/application。
+type Registry struct {
+	types map[string]apistructs.EventStreamType
+}
+
+// NewRegistry 返回包含内置事件类型的注册表
+func NewRegistry() *Registry {
+	r := &Registry{types: make(map[string]apistructs.EventStreamType)}
+	for _, t := range builtinTypes() {
+		r.Register(t)
+	}
+	return r
+}
+
+// Register 注册事件类型，同名覆盖
+func (r *Registry) Register(t apistructs.EventStreamType) {
+	if t.Type == "" {
+		t.Type = TypePrefix + t.Event
+	}
+	if t.Version == "" {
+		t.Version = schemaVersion
+	}
+	r.types[t.Event] = t
+}
+
+// Get 查询事件类型
+func (r *Registry) Get(event string) (apistructs.EventStreamType, bool) {
+	t, ok := r.types[event]
+	return t, ok
+}
+
+// List 按事件名排序返回所有事件类型
+func (r *Registry) List() []apistructs.EventStreamType {
+	list := make([]apistructs.EventStreamType, 0, len(r.types))
+	for _, t := range r.types {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Event < list[j].Event })
+	return list
+}
+
+// CloudEventType 返回事件对应的 CloudEvents type: <type>.<action>，
+// action 为空或与事件名相同(如 gittar 事件)时省略
+func (r *Registry) CloudEventType(event, action string) string {
+	typ := TypePrefix + event
+	if t, ok := r.types[event]; ok {
+		typ = t.Type
+	}
+	action = strings.ToLower(action)
+	if action == "" || action == strings.ToLower(event) {
+		return typ
+	}
+	return typ + "." + action
+}
+
+// PartitionKey 计算事件的分区键，同一资源的事件分区键相同，按产生顺序投递
+func (r *Registry) PartitionKey(label apistructs.EventHeader, content json.RawMessage) string {
+	if t, ok := r.types[label.Event]; ok && t.KeyField != "" {
+		if id := lookup(content, t.KeyField); id != "" {
+			return t.Resource + "/" + id
+		}
+	}
+	switch {
+	case validID(label.ApplicationID):
+		return "application/" + label.ApplicationID
+	case validID(label.ProjectID):
+		return "project/" + label.ProjectID
+	case validID(label.OrgID):
+		return "org/" + label.OrgID
+	}
+	return "event/" + label.Event
+}
+
+// lookup 按 a.b.c 形式的路径取 json 中的标量值
+func lookup(content json.RawMessage, path string) string {
+	var v interface{}
+	d := json.NewDecoder(strings.NewReader(string(content)))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return ""
+	}
+	for _, field := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		v = m[field]
+	}
+	switch val := v.(type) {
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	}
+	return ""
+}
+
+func validID(id string) bool {
+	n, err := strconv.ParseInt(id, 10, 64)
+	return err == nil && n > 0
+}
+
+// objectSchema 生成 JSON Schema，properties 为字段名到 JSON 类型的映射
+func objectSchema(title string, required []string, properties map[string]string) json.RawMessage {
+	props := make(map[string]interface{}, len(properties))
+	for name, typ := range properties {
+		props[name] = map[string]string{"type": typ}
+	}
+	schema := map[string]interface{}{
+		"$schema":    "http://json-schema.org/draft-07/schema#",
+		"title":      title,
+		"type":       "object",
+		"required":   required,
+		"properties": props,
+	}
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		panic(fmt.Sprintf("marshal schema of %s: %v", title, err))
+	}
+	return raw
+}
+
+func builtinTypes() []apistructs.EventStreamType {
+	mergeRequest := map[string]string{
+		"id":           "integer",
+		"mergeId":      "integer",
+		"appId":        "integer",
+		"repoId":       "integer",
+		"title":        "string",
+		"description":  "string",
+		"authorId":     "string",
+		"assigneeId":   "string",
+		"sourceBranch": "string",
+		"targetBranch": "string",
+		"sourceSha":    "string",
+		"targetSha":    "string",
+		"state":        "string",
+		"link":         "string",
+		"eventName":    "string",
+		"createdAt":    "string",
+	}
+	types := []apistructs.EventStreamType{
+		{
+			Event:       "pipeline",
+			Description: "流水线状态变化，action 为流水线状态",
+			Resource:    "pipeline",
+			KeyField:    "pipelineID",
+			Schema: objectSchema("pipeline", []string{"pipelineID", "status"}, map[string]string{
+				"pipelineID":      "integer",
+				"status":          "string",
+				"branch":          "string",
+				"source":          "string",
+				"isCron":          "boolean",
+				"pipelineYmlName": "string",
+				"userID":          "string",
+				"internalClient":  "string",
+				"costTimeSec":     "integer",
+				"diceWorkspace":   "string",
+				"clusterName":     "string",
+				"timeBegin":       "string",
+				"cronExpr":        "string",
+				"labels":          "object",
+			}),
+		},
+		{
+			// 任务事件与所属流水线使用同一分区键，保证与流水线事件之间的顺序
+			Event:       "pipeline_task",
+			Description: "流水线任务状态变化，action 为任务状态",
+			Resource:    "pipeline",
+			KeyField:    "pipelineID",
+			Schema: objectSchema("pipeline_task", []string{"pipelineTaskID", "pipelineID", "status"}, map[string]string{
+				"pipelineTaskID":  "integer",
+				"pipelineID":      "integer",
+				"actionType":      "string",
+				"status":          "string",
+				"clusterName":     "string",
+				"userID":          "string",
+				"createdAt":       "string",
+				"queueTimeSec":    "integer",
+				"costTimeSec":     "integer",
+				"orgName":         "string",
+				"projectName":     "string",
+				"applicationName": "string",
+				"taskName":        "string",
+				"runtimeID":       "string",
+				"releaseID":       "string",
+			}),
+		},
+		{
+			Event:       "runtime",
+			Description: "runtime(部署)的创建，删除",
+			Resource:    "runtime",
+			KeyField:    "runtime.id",
+			Schema: objectSchema("runtime", []string{"eventName", "runtime"}, map[string]string{
+				"eventName":  "string",
+				"operator":   "string",
+				"runtime":    "object",
+				"service":    "object",
+				"deployment": "object",
+			}),
+		},
+		{
+			Event:       "issue",
+			Description: "事项的创建，更新及评论等动态，action 为动态类型",
+			Resource:    "issue",
+			KeyField:    "params.issueID",
+			Schema: objectSchema("issue", []string{"title", "streamType"}, map[string]string{
+				"title":        "string",
+				"content":      "string",
+				"atUserIds":    "string",
+				"receivers":    "array",
+				"issueType":    "string",
+				"streamType":   "string",
+				"streamTypes":  "array",
+				"streamParams": "object",
+				"participants": "array",
+				"params":       "object",
+			}),
+		},
+		{
+			Event:       apistructs.GitPushEvent,
+			Description: "代码推送",
+			Resource:    "repository",
+			KeyField:    "repository.application_id",
+			Schema: objectSchema(apistructs.GitPushEvent, []string{"ref", "after", "before"}, map[string]string{
+				"total_commits_count": "integer",
+				"is_tag":              "boolean",
+				"object_kind":         "string",
+				"ref":                 "string",
+				"after":               "string",
+				"before":              "string",
+				"repository":          "object",
+				"pusher":              "object",
+			}),
+		},
+	}
+	// 合并请求的各类事件共用同一分区键，保证同一合并请求的事件有序
+	for event, desc := range map[string]string{
+		apistructs.GitCreateMREvent:  "合并请求创建",
+		apistructs.GitUpdateMREvent:  "合并请求更新",
+		apistructs.GitMergeMREvent:   "合并请求合并",
+		apistructs.GitCloseMREvent:   "合并请求关闭",
+		apistructs.GitCommentMREvent: "合并请求评论",
+	} {
+		types = append(types, apistructs.EventStreamType{
+			Event:       event,
+			Type:        TypePrefix + "merge_request." + strings.TrimSuffix(strings.TrimPrefix(event, "git_"), "_mr"),
+			Description: desc,
+			Resource:    "merge_request",
+			KeyField:    "id",
+			Schema:      objectSchema(event, []string{"id", "state"}, mergeRequest),
+		})
+	}
+	return types
+}
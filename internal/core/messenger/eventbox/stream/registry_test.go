@@ -0,0 +1,112 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+func TestRegistryPartitionKey(t *testing.T) {
+	r := NewRegistry()
+	cases := []struct {
+		label   apistructs.EventHeader
+		content string
+		want    string
+	}{
+		{apistructs.EventHeader{Event: "pipeline", ApplicationID: "3"}, `{"pipelineID":10001,"status":"Running"}`, "pipeline/10001"},
+		{apistructs.EventHeader{Event: "pipeline_task", ApplicationID: "3"}, `{"pipelineTaskID":7,"pipelineID":10001}`, "pipeline/10001"},
+		{apistructs.EventHeader{Event: "runtime"}, `{"eventName":"RuntimeCreated","runtime":{"id":12}}`, "runtime/12"},
+		{apistructs.EventHeader{Event: "issue", ProjectID: "2"}, `{"params":{"issueID":"42"}}`, "issue/42"},
+		{apistructs.EventHeader{Event: apistructs.GitMergeMREvent}, `{"id":5,"state":"merged"}`, "merge_request/5"},
+		{apistructs.EventHeader{Event: apistructs.GitCommentMREvent}, `{"id":5,"state":"open"}`, "merge_request/5"},
+		// 内容中缺少资源 id 时退化为事件所属范围
+		{apistructs.EventHeader{Event: "runtime", OrgID: "1", ProjectID: "2", ApplicationID: "-1"}, `{}`, "project/2"},
+		{apistructs.EventHeader{Event: "unknown", OrgID: "1"}, `{"id":1}`, "org/1"},
+		{apistructs.EventHeader{Event: "unknown", OrgID: "-1"}, `null`, "event/unknown"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, r.PartitionKey(c.label, json.RawMessage(c.content)), c.label.Event)
+	}
+}
+
+func TestRegistryCloudEventType(t *testing.T) {
+	r := NewRegistry()
+	assert.Equal(t, "io.erda.pipeline.running", r.CloudEventType("pipeline", "Running"))
+	assert.Equal(t, "io.erda.merge_request.merge", r.CloudEventType(apistructs.GitMergeMREvent, apistructs.GitMergeMREvent))
+	assert.Equal(t, "io.erda.git_push", r.CloudEventType(apistructs.GitPushEvent, apistructs.GitPushEvent))
+	assert.Equal(t, "io.erda.org.create", r.CloudEventType("org", "create"))
+}
+
+func TestRegistryList(t *testing.T) {
+	r := NewRegistry()
+	list := r.List()
+	assert.True(t, len(list) > 0)
+	for i, typ := range list {
+		if i > 0 {
+			assert.True(t, list[i-1].Event < typ.Event)
+		}
+		assert.Equal(t, schemaVersion, typ.Version)
+		var schema map[string]interface{}
+		assert.NoError(t, json.Unmarshal(typ.Schema, &schema), typ.Event)
+		assert.Equal(t, "object", schema["type"])
+	}
+	_, ok := r.Get("pipeline")
+	assert.True(t, ok)
+	_, ok = r.Get("unknown")
+	assert.False(t, ok)
+}
+
+func TestNewCloudEvent(t *testing.T) {
+	r := NewRegistry()
+	label := apistructs.EventHeader{
+		Event:         "pipeline",
+		Action:        "Success",
+		OrgID:         "1",
+		ProjectID:     "2",
+		ApplicationID: "3",
+		Env:           "TEST",
+		TimeStamp:     "2021-06-01 10:00:00",
+	}
+	e := NewCloudEvent(r, "/erda/", "https://erda.example.com/", label, json.RawMessage(`{"pipelineID":10001}`))
+	assert.Equal(t, SpecVersion, e.SpecVersion)
+	assert.NotEmpty(t, e.ID)
+	assert.Equal(t, "/erda/orgs/1/projects/2/applications/3", e.Source)
+	assert.Equal(t, "io.erda.pipeline.success", e.Type)
+	assert.Equal(t, "pipeline/10001", e.Subject)
+	assert.Equal(t, "pipeline/10001", e.PartitionKey)
+	assert.Equal(t, "https://erda.example.com/api/event-types/pipeline", e.DataSchema)
+	assert.Equal(t, 2021, e.Time.Year())
+	assert.Equal(t, "test", e.ErdaEnv)
+
+	raw, err := json.Marshal(e)
+	assert.NoError(t, err)
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(raw, &m))
+	assert.Equal(t, "1.0", m["specversion"])
+	assert.Equal(t, "application/json", m["datacontenttype"])
+	assert.Equal(t, float64(10001), m["data"].(map[string]interface{})["pipelineID"])
+
+	// 未注册的事件没有 dataschema，无效的 id 不出现在 source 中
+	e = NewCloudEvent(r, "/erda", "https://erda.example.com", apistructs.EventHeader{Event: "org", OrgID: "1", ProjectID: "-1"}, nil)
+	assert.Equal(t, "/erda/orgs/1", e.Source)
+	assert.Empty(t, e.DataSchema)
+	assert.Empty(t, e.ErdaProjectID)
+	assert.Equal(t, json.RawMessage("null"), e.Data)
+}
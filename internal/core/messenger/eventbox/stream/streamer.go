@@ -0,0 +1,289 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/crypto/uuid"
+)
+
+// maxErrorLength 记录的最近一次发送错误的最大长度
+const maxErrorLength = 1024
+
+// Config 事件流的发送策略
+type Config struct {
+	// Source CloudEvents source 前缀
+	Source string
+	// SchemaBaseURL 不为空时作为 dataschema 的前缀
+	SchemaBaseURL string
+	// PollInterval 扫描待发送事件的间隔
+	PollInterval time.Duration
+	// BatchSize 每次扫描处理的分区键数，以及每个分区键一次连续发送的事件数
+	BatchSize int
+	// Concurrency 并发发送的分区键数
+	Concurrency int
+	// BaseBackoff 首次重试间隔，之后每次翻倍
+	BaseBackoff time.Duration
+	// MaxBackoff 重试间隔上限
+	MaxBackoff time.Duration
+	// ClaimTimeout 认领后超过该时长仍未完成的分区键视为发送实例已退出，允许重新认领，
+	// 每个分区键一次连续发送的时长不超过其一半
+	ClaimTimeout time.Duration
+}
+
+// Record 待发送的事件，发送成功后删除
+type Record struct {
+	ID            uint64 `gorm:"primary_key"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	EventID       string
+	EventType     string
+	PartitionKey  string
+	Payload       string
+	Attempts      int
+	NextAttemptAt time.Time
+	Claim         string
+	LastError     string
+}
+
+// TableName 设置模型对应数据库表名称
+func (Record) TableName() string {
+	return "erda_event_stream_outbox"
+}
+
+// Streamer 将平台事件转换为 CloudEvent 写入 outbox 表，再异步发送到 kafka 或 nats。
+// 每个分区键同一时刻只有队首事件可被认领，认领的实例按 id 顺序连续发送该分区键下的事件，
+// 失败时队首事件退避重试，之后的事件等待，从而保证同一资源的事件有序；
+// 事件在目标确认后才从 outbox 删除，实例退出等情况下可能重复发送(at-least-once)，
+// 消费方可按 CloudEvent id 去重。
+type Streamer struct {
+	db       *gorm.DB
+	sink     Sink
+	registry *Registry
+	cfg      Config
+}
+
+func New(db *gorm.DB, sink Sink, registry *Registry, cfg Config) *Streamer {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.ClaimTimeout <= 0 {
+		cfg.ClaimTimeout = 5 * time.Minute
+	}
+	if registry == nil {
+		registry = NewRegistry()
+	}
+	return &Streamer{db: db, sink: sink, registry: registry, cfg: cfg}
+}
+
+// Registry 返回事件类型注册表
+func (s *Streamer) Registry() *Registry {
+	return s.registry
+}
+
+// Enqueue 将事件写入 outbox
+func (s *Streamer) Enqueue(label apistructs.EventHeader, content json.RawMessage) error {
+	e := NewCloudEvent(s.registry, s.cfg.Source, s.cfg.SchemaBaseURL, label, content)
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	r := Record{
+		EventID:       e.ID,
+		EventType:     e.Type,
+		PartitionKey:  e.PartitionKey,
+		Payload:       string(payload),
+		NextAttemptAt: time.Now(),
+	}
+	return s.db.Create(&r).Error
+}
+
+// Run 循环发送 outbox 中的事件，直到 ctx 结束
+func (s *Streamer) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+	defer func() {
+		if err := s.sink.Close(); err != nil {
+			logrus.Errorf("event stream: close sink: %v", err)
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s.publishDue(ctx)
+	}
+}
+
+func (s *Streamer) publishDue(ctx context.Context) {
+	now := time.Now()
+	var heads []Record
+	err := s.db.Where("id IN (SELECT MIN(id) FROM erda_event_stream_outbox GROUP BY partition_key)").
+		Where("next_attempt_at <= ? AND (claim = '' OR updated_at < ?)", now, now.Add(-s.cfg.ClaimTimeout)).
+		Order("id").Limit(s.cfg.BatchSize).Find(&heads).Error
+	if err != nil {
+		logrus.Errorf("event stream: list due events: %v", err)
+		return
+	}
+
+	sem := make(chan struct{}, s.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i := range heads {
+		if ctx.Err() != nil {
+			break
+		}
+		head := heads[i]
+		if !s.claim(&head) {
+			continue
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			s.publishKey(ctx, &head)
+		}()
+	}
+	wg.Wait()
+}
+
+// claim 通过条件更新认领分区键的队首事件，多实例部署时保证同一分区键同时只被一个实例发送
+func (s *Streamer) claim(head *Record) bool {
+	token := uuid.UUID()
+	res := s.db.Model(&Record{}).Where("id = ? AND claim = ?", head.ID, head.Claim).
+		Updates(map[string]interface{}{"claim": token})
+	if res.Error != nil {
+		logrus.Errorf("event stream: claim event %d: %v", head.ID, res.Error)
+		return false
+	}
+	if res.RowsAffected != 1 {
+		return false
+	}
+	head.Claim = token
+	return true
+}
+
+// publishKey 按顺序发送队首事件及同一分区键下之后的事件，遇到失败即停止
+func (s *Streamer) publishKey(ctx context.Context, head *Record) {
+	rows := []Record{*head}
+	if s.cfg.BatchSize > 1 {
+		var rest []Record
+		if err := s.db.Where("partition_key = ? AND id > ?", head.PartitionKey, head.ID).
+			Order("id").Limit(s.cfg.BatchSize - 1).Find(&rest).Error; err != nil {
+			logrus.Errorf("event stream: list events of %s: %v", head.PartitionKey, err)
+		}
+		rows = append(rows, rest...)
+	}
+
+	// 批次耗时限制在认领超时之内，避免发送过程中其他实例认为认领过期而重复认领同一分区键
+	batchCtx, cancel := context.WithTimeout(ctx, s.cfg.ClaimTimeout/2)
+	defer cancel()
+	published, failed, perr := s.publishBatch(batchCtx, rows)
+	if err := s.settle(head, published, failed, perr); err != nil {
+		// 已发送的事件在认领超时后会被重新发送
+		logrus.Errorf("event stream: settle events of %s: %v", head.PartitionKey, err)
+	}
+}
+
+// publishBatch 按顺序发送事件直到失败或 ctx 结束，返回已发送的事件 id 以及失败的事件。
+// 已有事件发送成功后批次超时的，剩余事件留待下次扫描，不计为失败
+func (s *Streamer) publishBatch(ctx context.Context, rows []Record) ([]uint64, *Record, error) {
+	var published []uint64
+	for i := range rows {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := s.sink.Publish(ctx, rows[i].PartitionKey, rows[i].EventID, []byte(rows[i].Payload)); err != nil {
+			if ctx.Err() != nil && len(published) > 0 {
+				break
+			}
+			return published, &rows[i], err
+		}
+		published = append(published, rows[i].ID)
+	}
+	return published, nil, nil
+}
+
+// settle 删除已发送的事件，失败的事件退避重试并释放认领
+func (s *Streamer) settle(head *Record, published []uint64, failed *Record, perr error) error {
+	tx := s.db.Begin()
+	if len(published) > 0 {
+		if err := tx.Where("id IN (?)", published).Delete(&Record{}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	switch {
+	case failed != nil:
+		failed.Attempts++
+		errMsg := perr.Error()
+		if len(errMsg) > maxErrorLength {
+			errMsg = errMsg[:maxErrorLength]
+		}
+		logrus.Warnf("event stream: publish event %s of %s failed %d times: %s",
+			failed.EventID, failed.PartitionKey, failed.Attempts, errMsg)
+		if err := tx.Model(&Record{}).Where("id = ?", failed.ID).Updates(map[string]interface{}{
+			"attempts":        failed.Attempts,
+			"next_attempt_at": time.Now().Add(backoff(s.cfg.BaseBackoff, s.cfg.MaxBackoff, failed.Attempts)),
+			"last_error":      errMsg,
+			"claim":           "",
+		}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	case len(published) == 0:
+		// 未发送任何事件(ctx 结束)，释放认领
+		if err := tx.Model(&Record{}).Where("id = ? AND claim = ?", head.ID, head.Claim).
+			Updates(map[string]interface{}{"claim": ""}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+// backoff 第 attempts 次失败后的重试间隔: base * 2^(attempts-1)，不超过 max。
+// 事件不会被丢弃，超过上限后按 max 间隔一直重试
+func backoff(base, max time.Duration, attempts int) time.Duration {
+	wait := base
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if max > 0 && wait >= max {
+			return max
+		}
+	}
+	if max > 0 && wait > max {
+		return max
+	}
+	return wait
+}
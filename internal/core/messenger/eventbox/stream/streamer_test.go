@@ -0,0 +1,88 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, backoff(time.Second, time.Minute, 1))
+	assert.Equal(t, 4*time.Second, backoff(time.Second, time.Minute, 3))
+	assert.Equal(t, time.Minute, backoff(time.Second, time.Minute, 10))
+	assert.Equal(t, time.Minute, backoff(time.Second, time.Minute, 1000))
+}
+
+func TestKafkaMessage(t *testing.T) {
+	m := kafkaMessage("erda-events", "pipeline/1", []byte(`{}`))
+	assert.Equal(t, "erda-events", m.Topic)
+	key, err := m.Key.Encode()
+	assert.NoError(t, err)
+	assert.Equal(t, "pipeline/1", string(key))
+	assert.Equal(t, ContentType, string(m.Headers[0].Value))
+	assert.Equal(t, []string{"a:9092", "b:9092", "c:9092"}, trimBrokers([]string{"a:9092, b:9092", "", "c:9092"}))
+}
+
+// fakeSink 每次发送耗时 delay，id 在 fail 中的事件发送失败
+type fakeSink struct {
+	delay time.Duration
+	fail  map[string]bool
+}
+
+func (f *fakeSink) Publish(ctx context.Context, _, id string, _ []byte) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(f.delay):
+	}
+	if f.fail[id] {
+		return fmt.Errorf("publish %s failed", id)
+	}
+	return nil
+}
+
+func (f *fakeSink) Close() error { return nil }
+
+func TestPublishBatch(t *testing.T) {
+	rows := []Record{{ID: 1, EventID: "e1"}, {ID: 2, EventID: "e2"}, {ID: 3, EventID: "e3"}}
+
+	s := New(nil, &fakeSink{fail: map[string]bool{"e2": true}}, nil, Config{})
+	published, failed, err := s.publishBatch(context.Background(), rows)
+	assert.Equal(t, []uint64{1}, published)
+	assert.Equal(t, uint64(2), failed.ID)
+	assert.EqualError(t, err, "publish e2 failed")
+
+	// 批次超时后剩余事件留待下次发送，不计为失败
+	s = New(nil, &fakeSink{delay: 40 * time.Millisecond}, nil, Config{})
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Millisecond)
+	defer cancel()
+	published, failed, err = s.publishBatch(ctx, rows)
+	assert.Equal(t, []uint64{1}, published)
+	assert.Nil(t, failed)
+	assert.NoError(t, err)
+}
+
+func TestKafkaSinkLazyConnect(t *testing.T) {
+	// 创建时不连接 kafka
+	sink, err := NewKafkaSink(KafkaConfig{Brokers: []string{"127.0.0.1:1"}, Topic: "erda-events", Timeout: 100 * time.Millisecond})
+	assert.NoError(t, err)
+	assert.Nil(t, sink.producer)
+	assert.NoError(t, sink.Close())
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feishu
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda-proto-go/core/messenger/notify/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/bundle"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/types"
+)
+
+var apiURL = "https://open.feishu.cn/open-apis"
+
+type FeishuSubscriber struct {
+	bundle    *bundle.Bundle
+	messenger pb.NotifyServiceServer
+	client    *http.Client
+	tokens    subscriber.TokenCache
+}
+
+type Card struct {
+	Config   CardConfig `json:"config"`
+	Header   CardHeader `json:"header"`
+	Elements []Element  `json:"elements"`
+}
+
+type CardConfig struct {
+	WideScreenMode bool `json:"wide_screen_mode"`
+}
+
+type CardHeader struct {
+	Title    PlainText `json:"title"`
+	Template string    `json:"template"`
+}
+
+type PlainText struct {
+	Tag     string `json:"tag"`
+	Content string `json:"content"`
+}
+
+type Element struct {
+	Tag     string `json:"tag"`
+	Content string `json:"content,omitempty"`
+}
+
+// webhookMessage 自定义机器人消息，配置了签名校验时需要带上 timestamp 和 sign
+type webhookMessage struct {
+	Timestamp string `json:"timestamp,omitempty"`
+	Sign      string `json:"sign,omitempty"`
+	MsgType   string `json:"msg_type"`
+	Card      Card   `json:"card"`
+}
+
+type botMessage struct {
+	ReceiveID string `json:"receive_id"`
+	MsgType   string `json:"msg_type"`
+	Content   string `json:"content"`
+}
+
+type apiResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	// 旧版自定义机器人接口返回 StatusCode
+	StatusCode        int    `json:"StatusCode"`
+	StatusMessage     string `json:"StatusMessage"`
+	TenantAccessToken string `json:"tenant_access_token"`
+	Expire            int64  `json:"expire"`
+}
+
+func (r *apiResponse) err() error {
+	if r.Code != 0 {
+		return errors.Errorf("code: %d, msg: %s", r.Code, r.Msg)
+	}
+	if r.StatusCode != 0 {
+		return errors.Errorf("code: %d, msg: %s", r.StatusCode, r.StatusMessage)
+	}
+	return nil
+}
+
+func New(proxy string, bundle *bundle.Bundle, messenger pb.NotifyServiceServer) subscriber.Subscriber {
+	return &FeishuSubscriber{
+		bundle:    bundle,
+		messenger: messenger,
+		client:    subscriber.NewIMHTTPClient(proxy),
+	}
+}
+
+func (s *FeishuSubscriber) Publish(dest string, content string, time int64, msg *types.Message) []error {
+	targets, c, err := subscriber.ParseIMMessage(dest, content)
+	if err != nil {
+		return []error{err}
+	}
+	imTargets, config, errs := subscriber.ResolveIMTargets(targets,
+		subscriber.EnabledIMChannel(s.bundle, c.OrgID, apistructs.NOTIFY_CHANNEL_TYPE_FEISHU))
+	card := Render(c)
+	for _, t := range imTargets {
+		if t.WebhookURL != "" {
+			err = s.sendWebhook(t.WebhookURL, t.Secret, card)
+		} else {
+			err = s.sendMessage(config, t.Conversation, card)
+		}
+		if err != nil {
+			logrus.Errorf("Feishu publish: %v", err)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 && msg != nil && msg.CreateHistory != nil {
+		msg.CreateHistory.Status = "failed"
+	}
+	if msg != nil && msg.CreateHistory != nil {
+		subscriber.SaveNotifyHistories(msg.CreateHistory, s.messenger)
+	}
+	return errs
+}
+
+func (s *FeishuSubscriber) Status() interface{} {
+	return nil
+}
+
+func (s *FeishuSubscriber) Name() string {
+	return "FEISHU"
+}
+
+func (s *FeishuSubscriber) sendWebhook(u, secret string, card Card) error {
+	m := webhookMessage{MsgType: "interactive", Card: card}
+	if secret != "" {
+		ts := time.Now().Unix()
+		m.Timestamp = strconv.FormatInt(ts, 10)
+		m.Sign = Sign(secret, ts)
+	}
+	var resp apiResponse
+	if err := subscriber.PostJSON(s.client, u, nil, m, &resp); err != nil {
+		return err
+	}
+	return resp.err()
+}
+
+func (s *FeishuSubscriber) sendMessage(config *apistructs.NotifyChannelConfig, receiver string, card Card) error {
+	token, err := s.tokens.Get(config.AppID, func() (string, time.Duration, error) {
+		var resp apiResponse
+		if err := subscriber.PostJSON(s.client, apiURL+"/auth/v3/tenant_access_token/internal", nil, map[string]string{
+			"app_id":     config.AppID,
+			"app_secret": config.AppSecret,
+		}, &resp); err != nil {
+			return "", 0, err
+		}
+		if err := resp.err(); err != nil {
+			return "", 0, errors.Wrap(err, "get tenant_access_token")
+		}
+		return resp.TenantAccessToken, time.Duration(resp.Expire) * time.Second, nil
+	})
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(card)
+	if err != nil {
+		return err
+	}
+	var resp apiResponse
+	if err := subscriber.PostJSON(s.client, apiURL+"/im/v1/messages?receive_id_type="+receiveIDType(receiver),
+		http.Header{"Authorization": []string{"Bearer " + token}},
+		botMessage{ReceiveID: receiver, MsgType: "interactive", Content: string(raw)}, &resp); err != nil {
+		return err
+	}
+	return resp.err()
+}
+
+// receiveIDType 按 id 前缀推断接收者类型，默认为群聊
+func receiveIDType(receiver string) string {
+	switch {
+	case strings.Contains(receiver, "@"):
+		return "email"
+	case strings.HasPrefix(receiver, "ou_"):
+		return "open_id"
+	case strings.HasPrefix(receiver, "on_"):
+		return "union_id"
+	default:
+		return "chat_id"
+	}
+}
+
+// Sign 自定义机器人签名: 以 timestamp + "\n" + secret 为密钥对空串做 HmacSHA256 后 base64
+func Sign(secret string, timestamp int64) string {
+	mac := hmac.New(sha256.New, []byte(fmt.Sprintf("%d\n%s", timestamp, secret)))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Render 将 markdown 内容渲染为消息卡片，有邮箱的用户通过 <at email=...> @
+func Render(c *subscriber.IMContent) Card {
+	card := Card{
+		Config: CardConfig{WideScreenMode: true},
+		Header: CardHeader{Title: PlainText{Tag: "plain_text", Content: c.Title}, Template: "blue"},
+	}
+	if c.Text != "" {
+		card.Elements = append(card.Elements, Element{Tag: "markdown", Content: c.Text})
+	}
+	var mentions []string
+	for _, m := range c.Mentions {
+		switch {
+		case m.Email != "":
+			mentions = append(mentions, fmt.Sprintf("<at email=%s></at>", m.Email))
+		case m.Name != "":
+			mentions = append(mentions, "@"+m.Name)
+		}
+	}
+	if len(mentions) > 0 {
+		card.Elements = append(card.Elements, Element{Tag: "markdown", Content: strings.Join(mentions, " ")})
+	}
+	return card
+}
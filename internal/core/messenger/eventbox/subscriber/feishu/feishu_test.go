@@ -0,0 +1,62 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package feishu
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber"
+)
+
+func TestSign(t *testing.T) {
+	require.Equal(t, "vvU1S4ucHy95pQ90meMW66yQJ+Szge4s9g7hQUu9yP8=", Sign("secret", 1600000000))
+}
+
+func TestReceiveIDType(t *testing.T) {
+	require.Equal(t, "email", receiveIDType("dev@erda.cloud"))
+	require.Equal(t, "open_id", receiveIDType("ou_7d8a6e6df7621556ce0d21922b676706"))
+	require.Equal(t, "union_id", receiveIDType("on_8ed6aa67826108097d9ee143816345"))
+	require.Equal(t, "chat_id", receiveIDType("oc_a0553eda9014c201e6969b478895c230"))
+}
+
+func TestFeishuSubscriber_sendWebhook(t *testing.T) {
+	var got webhookMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		if got.Sign == "" {
+			w.Write([]byte(`{"code":19021,"msg":"sign match fail or timestamp is not within one hour from current time"}`))
+			return
+		}
+		w.Write([]byte(`{"code":0,"msg":"success"}`))
+	}))
+	defer srv.Close()
+
+	s := &FeishuSubscriber{client: srv.Client()}
+	card := Render(&subscriber.IMContent{
+		Title:    "Pipeline failed",
+		Text:     "**app** failed",
+		Mentions: []subscriber.IMMention{{Name: "Alice", Email: "alice@erda.cloud"}},
+	})
+	require.Error(t, s.sendWebhook(srv.URL, "", card))
+	require.NoError(t, s.sendWebhook(srv.URL, "secret", card))
+	require.Equal(t, "interactive", got.MsgType)
+	require.NotEmpty(t, got.Timestamp)
+	require.Equal(t, "<at email=alice@erda.cloud></at>", got.Card.Elements[1].Content)
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/erda-project/erda/bundle"
 	"github.com/erda-project/erda/internal/core/messenger/eventbox/conf"
 	dispatchererror "github.com/erda-project/erda/internal/core/messenger/eventbox/dispatcher/errors"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber"
 	"github.com/erda-project/erda/internal/core/messenger/eventbox/types"
 	"github.com/erda-project/erda/pkg/strutil"
 	"github.com/erda-project/erda/pkg/template"
@@ -84,6 +85,15 @@ func (d *GroupSubscriber) Publish(dest string, content string, time int64, msg *
 		ClusterName:   groupNotifyContent.ClusterName,
 	}
 
+	recipients := make([]types.Recipient, 0, len(groupDetail.Users))
+	for _, user := range groupDetail.Users {
+		recipients = append(recipients, types.Recipient{
+			UserID: user.ID,
+			Email:  strings.TrimSpace(user.Email),
+			Mobile: strings.TrimSpace(user.Mobile),
+		})
+	}
+
 	for _, channel := range groupNotifyContent.Channels {
 		chr := *createHistoryRequest
 		chr.NotifySource.Params = channel.Params
@@ -128,6 +138,8 @@ func (d *GroupSubscriber) Publish(dest string, content string, time int64, msg *
 					"EMAIL": emails,
 				},
 				CreateHistory: &chr,
+				Category:      groupNotifyContent.Category,
+				Recipients:    recipients,
 			}
 			if len(emails) > 0 {
 				d.routeMessage(msg)
@@ -226,6 +238,8 @@ func (d *GroupSubscriber) Publish(dest string, content string, time int64, msg *
 					"MBOX": userIDs,
 				},
 				CreateHistory: &chr,
+				Category:      groupNotifyContent.Category,
+				Recipients:    recipients,
 			}
 			if len(userIDs) > 0 {
 				d.routeMessage(msg)
@@ -261,15 +275,74 @@ func (d *GroupSubscriber) Publish(dest string, content string, time int64, msg *
 					"DINGTALK_WORK_NOTICE": mobiles,
 				},
 				CreateHistory: &chr,
+				Category:      groupNotifyContent.Category,
+				Recipients:    recipients,
 			}
 			if len(mobiles) > 0 {
 				d.routeMessage(msg)
 			}
+		} else if targets, ok := imTargets(channel.Name, groupDetail); ok {
+			msg := &types.Message{
+				Content: subscriber.IMContent{
+					Title:    template.Render(channel.Params["title"], channel.Params),
+					Text:     template.Render(channel.Template, channel.Params),
+					OrgID:    groupNotifyContent.OrgID,
+					Mentions: d.imMentions(channel.Params["atUserIDs"]),
+				},
+				Time: time,
+				Labels: map[types.LabelKey]interface{}{
+					types.LabelKey(strings.ToUpper(channel.Name)): targets,
+				},
+				CreateHistory: &chr,
+			}
+			d.routeMessage(msg)
 		}
 	}
 	return errs
 }
 
+// imTargets 返回 IM 类通知方式的发送目标，通知组未配置目标时使用 org 下已启用渠道的默认目标
+func imTargets(channelName string, groupDetail *apistructs.NotifyGroupDetail) ([]apistructs.Target, bool) {
+	var targets []apistructs.Target
+	switch apistructs.NotifyTargetType(channelName) {
+	case apistructs.SlackNotifyTarget:
+		targets = groupDetail.SlackList
+	case apistructs.TeamsNotifyTarget:
+		targets = groupDetail.TeamsList
+	case apistructs.FeishuNotifyTarget:
+		targets = groupDetail.FeishuList
+	case apistructs.WeComNotifyTarget:
+		targets = groupDetail.WeComList
+	default:
+		return nil, false
+	}
+	if len(targets) == 0 {
+		targets = []apistructs.Target{{}}
+	}
+	return targets, true
+}
+
+func (d *GroupSubscriber) imMentions(atUserIDs string) []subscriber.IMMention {
+	userIDs := strutil.Split(atUserIDs, ",", true)
+	if len(userIDs) == 0 {
+		return nil
+	}
+	r, err := d.bundle.ListUsers(apistructs.UserListRequest{UserIDs: userIDs, Plaintext: true})
+	if err != nil {
+		logrus.Warnf("fail to fetch user, err: %v", err)
+		return nil
+	}
+	var mentions []subscriber.IMMention
+	for _, u := range r.Users {
+		name := u.Nick
+		if name == "" {
+			name = u.Name
+		}
+		mentions = append(mentions, subscriber.IMMention{Name: name, Email: u.Email, Mobile: u.Phone})
+	}
+	return mentions
+}
+
 func (d *GroupSubscriber) Status() interface{} {
 	return nil
 }
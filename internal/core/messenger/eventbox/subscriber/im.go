@@ -0,0 +1,212 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subscriber
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/bundle"
+)
+
+// IMMention IM 消息中需要 @ 的 erda 用户，各 IM 按 email 或手机号映射为自己的用户
+type IMMention struct {
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Mobile string `json:"mobile"`
+}
+
+// IMContent IM 类订阅者(slack, teams, feishu, wecom)的消息内容
+type IMContent struct {
+	Title string `json:"title"`
+	// Text markdown 格式的正文，由各 IM 渲染为卡片
+	Text     string      `json:"text"`
+	OrgID    int64       `json:"orgID"`
+	Mentions []IMMention `json:"mentions"`
+}
+
+// IMTarget 解析后的 IM 发送目标，WebhookURL 与 Conversation 二选一
+type IMTarget struct {
+	WebhookURL string
+	// Secret webhook 的签名密钥
+	Secret string
+	// Conversation bot 模式下的会话 id
+	Conversation string
+}
+
+// ParseIMMessage 解析 IM 类订阅者的 dest 和 content。
+// dest 为 []apistructs.Target，receiver 为 incoming webhook 地址、bot 模式下的会话 id，
+// 或为空表示 org 下已启用渠道的默认目标。
+func ParseIMMessage(dest, content string) ([]apistructs.Target, *IMContent, error) {
+	var targets []apistructs.Target
+	if err := json.Unmarshal([]byte(dest), &targets); err != nil {
+		return nil, nil, errors.Errorf("illegal dest: %v", err)
+	}
+	var c IMContent
+	if err := json.Unmarshal([]byte(content), &c); err != nil {
+		return nil, nil, errors.Errorf("illegal content: %v", err)
+	}
+	return targets, &c, nil
+}
+
+// ResolveIMTargets 将 dest 解析为发送目标，只有用到 org 渠道时才调用 getChannel 获取已启用渠道的配置
+func ResolveIMTargets(targets []apistructs.Target, getChannel func() (*apistructs.NotifyChannelConfig, error)) ([]IMTarget, *apistructs.NotifyChannelConfig, []error) {
+	var (
+		result     []IMTarget
+		errs       []error
+		config     *apistructs.NotifyChannelConfig
+		channelErr error
+		fetched    bool
+	)
+	channel := func() (*apistructs.NotifyChannelConfig, error) {
+		if !fetched {
+			fetched = true
+			config, channelErr = getChannel()
+			if channelErr == nil && config == nil {
+				channelErr = errors.New("non-enabled notify channel")
+			}
+		}
+		return config, channelErr
+	}
+	for _, t := range targets {
+		if IsWebhookURL(t.Receiver) {
+			result = append(result, IMTarget{WebhookURL: t.Receiver, Secret: t.Secret})
+			continue
+		}
+		cfg, err := channel()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		switch {
+		case t.Receiver == "" && cfg.Mode == apistructs.NOTIFY_CHANNEL_MODE_WEBHOOK:
+			result = append(result, IMTarget{WebhookURL: cfg.WebhookURL, Secret: cfg.WebhookSecret})
+		case t.Receiver == "" && cfg.Mode == apistructs.NOTIFY_CHANNEL_MODE_BOT:
+			result = append(result, IMTarget{Conversation: cfg.ChannelID})
+		case cfg.Mode == apistructs.NOTIFY_CHANNEL_MODE_BOT:
+			result = append(result, IMTarget{Conversation: t.Receiver})
+		default:
+			errs = append(errs, errors.Errorf("receiver %s requires notify channel in bot mode", t.Receiver))
+		}
+	}
+	return result, config, errs
+}
+
+// EnabledIMChannel 返回获取 org 下已启用的指定类型渠道配置的函数
+func EnabledIMChannel(bdl *bundle.Bundle, orgID int64, channelType apistructs.NotifyChannelType) func() (*apistructs.NotifyChannelConfig, error) {
+	return func() (*apistructs.NotifyChannelConfig, error) {
+		channel, err := bdl.GetEnabledNotifyChannelByType(orgID, channelType)
+		if err != nil {
+			return nil, errors.Errorf("non-enabled %s channel, orgID: %d, err: %v", channelType, orgID, err)
+		}
+		if channel.ID == "" || channel.Config == nil {
+			return nil, errors.Errorf("non-enabled %s channel, orgID: %d", channelType, orgID)
+		}
+		return channel.Config, nil
+	}
+}
+
+func IsWebhookURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// NewIMHTTPClient 创建访问外部 IM 服务的 http client，proxy 为空时不使用代理
+func NewIMHTTPClient(proxy string) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != "" {
+		if u, err := url.Parse(proxy); err == nil {
+			transport.Proxy = http.ProxyURL(u)
+		}
+	}
+	return &http.Client{Transport: transport, Timeout: 10 * time.Second}
+}
+
+// PostJSON 发送 json 请求，非 2xx 时返回错误，result 不为空时解析响应体
+func PostJSON(client *http.Client, u string, header http.Header, body, result interface{}) error {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	return DoRequest(client, req, result)
+}
+
+// DoRequest 发送请求，非 2xx 时返回错误，result 不为空时解析响应体
+func DoRequest(client *http.Client, req *http.Request, result interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s, httpcode: %d, body: %s", req.Method, req.URL.Host+req.URL.Path, resp.StatusCode, string(respBody))
+	}
+	if result == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return fmt.Errorf("%s %s, decode response: %v, body: %s", req.Method, req.URL.Host+req.URL.Path, err, string(respBody))
+	}
+	return nil
+}
+
+// TokenCache 缓存 bot 模式的 access token，过期前 5 分钟刷新
+type TokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]cachedToken
+}
+
+type cachedToken struct {
+	token    string
+	expireAt time.Time
+}
+
+func (c *TokenCache) Get(key string, fetch func() (string, time.Duration, error)) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t, ok := c.tokens[key]; ok && time.Now().Before(t.expireAt) {
+		return t.token, nil
+	}
+	token, expiresIn, err := fetch()
+	if err != nil {
+		return "", err
+	}
+	if c.tokens == nil {
+		c.tokens = make(map[string]cachedToken)
+	}
+	c.tokens[key] = cachedToken{token: token, expireAt: time.Now().Add(expiresIn - 5*time.Minute)}
+	return token, nil
+}
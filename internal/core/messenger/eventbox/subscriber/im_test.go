@@ -0,0 +1,106 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subscriber
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+func TestResolveIMTargets(t *testing.T) {
+	bot := &apistructs.NotifyChannelConfig{Mode: apistructs.NOTIFY_CHANNEL_MODE_BOT, ChannelID: "C001"}
+	webhook := &apistructs.NotifyChannelConfig{Mode: apistructs.NOTIFY_CHANNEL_MODE_WEBHOOK, WebhookURL: "https://example.com/hook", WebhookSecret: "s"}
+	tests := []struct {
+		name    string
+		targets []apistructs.Target
+		config  *apistructs.NotifyChannelConfig
+		err     error
+		want    []IMTarget
+		errs    int
+	}{
+		{
+			name:    "group webhook",
+			targets: []apistructs.Target{{Receiver: "https://hooks.example.com/a", Secret: "x"}},
+			err:     errors.New("should not be called"),
+			want:    []IMTarget{{WebhookURL: "https://hooks.example.com/a", Secret: "x"}},
+		},
+		{
+			name:    "channel default webhook",
+			targets: []apistructs.Target{{}},
+			config:  webhook,
+			want:    []IMTarget{{WebhookURL: "https://example.com/hook", Secret: "s"}},
+		},
+		{
+			name:    "channel default conversation",
+			targets: []apistructs.Target{{}},
+			config:  bot,
+			want:    []IMTarget{{Conversation: "C001"}},
+		},
+		{
+			name:    "bot conversation",
+			targets: []apistructs.Target{{Receiver: "C002"}},
+			config:  bot,
+			want:    []IMTarget{{Conversation: "C002"}},
+		},
+		{
+			name:    "conversation without bot",
+			targets: []apistructs.Target{{Receiver: "C002"}},
+			config:  webhook,
+			errs:    1,
+		},
+		{
+			name:    "non-enabled channel",
+			targets: []apistructs.Target{{}, {Receiver: "C002"}},
+			errs:    2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			got, _, errs := ResolveIMTargets(tt.targets, func() (*apistructs.NotifyChannelConfig, error) {
+				calls++
+				return tt.config, tt.err
+			})
+			require.Equal(t, tt.want, got)
+			require.Len(t, errs, tt.errs)
+			require.LessOrEqual(t, calls, 1)
+		})
+	}
+}
+
+func TestTokenCache_Get(t *testing.T) {
+	var c TokenCache
+	calls := 0
+	fetch := func() (string, time.Duration, error) {
+		calls++
+		return "token", time.Hour, nil
+	}
+	for i := 0; i < 3; i++ {
+		token, err := c.Get("app", fetch)
+		require.NoError(t, err)
+		require.Equal(t, "token", token)
+	}
+	require.Equal(t, 1, calls)
+
+	_, err := c.Get("other", func() (string, time.Duration, error) {
+		return "", 0, errors.New("unauthorized")
+	})
+	require.Error(t, err)
+}
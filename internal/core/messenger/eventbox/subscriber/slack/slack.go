@@ -0,0 +1,245 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slack
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda-proto-go/core/messenger/notify/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/bundle"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/types"
+)
+
+var apiURL = "https://slack.com/api"
+
+const (
+	maxHeaderSize  = 150
+	maxSectionSize = 3000
+)
+
+type SlackSubscriber struct {
+	bundle    *bundle.Bundle
+	messenger pb.NotifyServiceServer
+	client    *http.Client
+}
+
+type Message struct {
+	Channel string  `json:"channel,omitempty"`
+	Text    string  `json:"text"`
+	Blocks  []Block `json:"blocks"`
+}
+
+type Block struct {
+	Type string `json:"type"`
+	Text *Text  `json:"text,omitempty"`
+}
+
+type Text struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type apiResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+	User  struct {
+		ID string `json:"id"`
+	} `json:"user"`
+}
+
+func New(proxy string, bundle *bundle.Bundle, messenger pb.NotifyServiceServer) subscriber.Subscriber {
+	return &SlackSubscriber{
+		bundle:    bundle,
+		messenger: messenger,
+		client:    subscriber.NewIMHTTPClient(proxy),
+	}
+}
+
+func (s *SlackSubscriber) Publish(dest string, content string, time int64, msg *types.Message) []error {
+	targets, c, err := subscriber.ParseIMMessage(dest, content)
+	if err != nil {
+		return []error{err}
+	}
+	imTargets, config, errs := subscriber.ResolveIMTargets(targets,
+		subscriber.EnabledIMChannel(s.bundle, c.OrgID, apistructs.NOTIFY_CHANNEL_TYPE_SLACK))
+	for _, t := range imTargets {
+		if t.WebhookURL != "" {
+			err = s.sendWebhook(t.WebhookURL, Render(c, mentionNames(c.Mentions)))
+		} else {
+			m := Render(c, s.lookupMentions(config.BotToken, c.Mentions))
+			m.Channel = t.Conversation
+			err = s.postMessage(config.BotToken, m)
+		}
+		if err != nil {
+			logrus.Errorf("Slack publish: %v", err)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 && msg != nil && msg.CreateHistory != nil {
+		msg.CreateHistory.Status = "failed"
+	}
+	if msg != nil && msg.CreateHistory != nil {
+		subscriber.SaveNotifyHistories(msg.CreateHistory, s.messenger)
+	}
+	return errs
+}
+
+func (s *SlackSubscriber) Status() interface{} {
+	return nil
+}
+
+func (s *SlackSubscriber) Name() string {
+	return "SLACK"
+}
+
+// incoming webhook 成功时返回纯文本 ok
+func (s *SlackSubscriber) sendWebhook(u string, m Message) error {
+	return subscriber.PostJSON(s.client, u, nil, m, nil)
+}
+
+func (s *SlackSubscriber) postMessage(token string, m Message) error {
+	var resp apiResponse
+	if err := subscriber.PostJSON(s.client, apiURL+"/chat.postMessage", authHeader(token), m, &resp); err != nil {
+		return err
+	}
+	if !resp.OK {
+		return errors.Errorf("chat.postMessage to %s: %s", m.Channel, resp.Error)
+	}
+	return nil
+}
+
+// lookupMentions 通过 email 查找 slack 用户，找不到时退化为 @名字
+func (s *SlackSubscriber) lookupMentions(token string, mentions []subscriber.IMMention) []string {
+	var result []string
+	for _, m := range mentions {
+		if m.Email != "" {
+			req, err := http.NewRequest(http.MethodGet, apiURL+"/users.lookupByEmail?email="+url.QueryEscape(m.Email), nil)
+			if err == nil {
+				req.Header = authHeader(token)
+				var resp apiResponse
+				err = subscriber.DoRequest(s.client, req, &resp)
+				if err == nil && resp.OK && resp.User.ID != "" {
+					result = append(result, "<@"+resp.User.ID+">")
+					continue
+				}
+			}
+			logrus.Warnf("Slack publish: lookup user by email %s failed, err: %v", m.Email, err)
+		}
+		if m.Name != "" {
+			result = append(result, "@"+m.Name)
+		}
+	}
+	return result
+}
+
+func authHeader(token string) http.Header {
+	return http.Header{"Authorization": []string{"Bearer " + token}}
+}
+
+func mentionNames(mentions []subscriber.IMMention) []string {
+	var result []string
+	for _, m := range mentions {
+		if m.Name != "" {
+			result = append(result, "@"+m.Name)
+		}
+	}
+	return result
+}
+
+// Render 将 markdown 内容渲染为 Block Kit 消息
+func Render(c *subscriber.IMContent, mentions []string) Message {
+	m := Message{Text: c.Title}
+	if c.Title != "" {
+		m.Blocks = append(m.Blocks, Block{Type: "header", Text: &Text{Type: "plain_text", Text: truncate(c.Title, maxHeaderSize)}})
+	}
+	for _, section := range split(ToMrkdwn(c.Text), maxSectionSize) {
+		m.Blocks = append(m.Blocks, Block{Type: "section", Text: &Text{Type: "mrkdwn", Text: section}})
+	}
+	if len(mentions) > 0 {
+		m.Blocks = append(m.Blocks, Block{Type: "section", Text: &Text{Type: "mrkdwn", Text: strings.Join(mentions, " ")}})
+	}
+	return m
+}
+
+var (
+	boldRe    = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	strikeRe  = regexp.MustCompile(`~~(.+?)~~`)
+	linkRe    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	headingRe = regexp.MustCompile(`(?m)^#{1,6}\s+(.+?)\s*#*$`)
+)
+
+// ToMrkdwn 将常用 markdown 语法转换为 slack mrkdwn
+func ToMrkdwn(md string) string {
+	s := boldRe.ReplaceAllStringFunc(md, func(m string) string {
+		return "*" + m[2:len(m)-2] + "*"
+	})
+	s = strikeRe.ReplaceAllString(s, "~$1~")
+	s = linkRe.ReplaceAllString(s, "<$2|$1>")
+	s = headingRe.ReplaceAllStringFunc(s, func(m string) string {
+		text := strings.TrimSpace(headingRe.FindStringSubmatch(m)[1])
+		if strings.HasPrefix(text, "*") && strings.HasSuffix(text, "*") {
+			return text
+		}
+		return fmt.Sprintf("*%s*", text)
+	})
+	return s
+}
+
+// split 按行切分超长文本，单行超长时强制截断
+func split(s string, size int) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	var (
+		result []string
+		cur    strings.Builder
+	)
+	for _, line := range strings.Split(s, "\n") {
+		line = truncate(line, size)
+		if cur.Len() > 0 && cur.Len()+len(line)+1 > size {
+			result = append(result, cur.String())
+			cur.Reset()
+		}
+		if cur.Len() > 0 {
+			cur.WriteString("\n")
+		}
+		cur.WriteString(line)
+	}
+	if cur.Len() > 0 {
+		result = append(result, cur.String())
+	}
+	return result
+}
+
+func truncate(s string, size int) string {
+	if len(s) <= size {
+		return s
+	}
+	s = s[:size-3]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s + "..."
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slack
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber"
+)
+
+func TestToMrkdwn(t *testing.T) {
+	require.Equal(t, "*title*\n*bold* ~del~ <https://erda.cloud|link>",
+		ToMrkdwn("## title\n**bold** ~~del~~ [link](https://erda.cloud)"))
+}
+
+func TestRender(t *testing.T) {
+	m := Render(&subscriber.IMContent{Title: "Pipeline failed", Text: "**app** failed"}, []string{"<@U1>", "@erda"})
+	require.Equal(t, "Pipeline failed", m.Text)
+	require.Len(t, m.Blocks, 3)
+	require.Equal(t, "header", m.Blocks[0].Type)
+	require.Equal(t, "*app* failed", m.Blocks[1].Text.Text)
+	require.Equal(t, "<@U1> @erda", m.Blocks[2].Text.Text)
+}
+
+func TestSplit(t *testing.T) {
+	lines := []string{strings.Repeat("a", 6), strings.Repeat("b", 6), strings.Repeat("c", 20)}
+	got := split(strings.Join(lines, "\n"), 10)
+	require.Equal(t, []string{"aaaaaa", "bbbbbb", "ccccccc..."}, got)
+	require.Nil(t, split(" ", 10))
+}
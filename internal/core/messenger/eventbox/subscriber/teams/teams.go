@@ -0,0 +1,220 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teams
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda-proto-go/core/messenger/notify/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/bundle"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/types"
+)
+
+var loginURL = "https://login.microsoftonline.com"
+
+const (
+	defaultTenant   = "botframework.com"
+	botFrameworkAPI = "https://api.botframework.com/.default"
+	cardContentType = "application/vnd.microsoft.card.adaptive"
+)
+
+type TeamsSubscriber struct {
+	bundle    *bundle.Bundle
+	messenger pb.NotifyServiceServer
+	client    *http.Client
+	tokens    subscriber.TokenCache
+}
+
+// Activity incoming webhook 与 Bot Framework 共用的消息结构
+type Activity struct {
+	Type        string       `json:"type"`
+	Attachments []Attachment `json:"attachments"`
+}
+
+type Attachment struct {
+	ContentType string `json:"contentType"`
+	Content     Card   `json:"content"`
+}
+
+type Card struct {
+	Schema  string        `json:"$schema"`
+	Type    string        `json:"type"`
+	Version string        `json:"version"`
+	Body    []TextBlock   `json:"body"`
+	MSTeams *CardSettings `json:"msteams,omitempty"`
+}
+
+type TextBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Weight string `json:"weight,omitempty"`
+	Size   string `json:"size,omitempty"`
+	Wrap   bool   `json:"wrap"`
+}
+
+type CardSettings struct {
+	Width    string    `json:"width,omitempty"`
+	Entities []Mention `json:"entities,omitempty"`
+}
+
+type Mention struct {
+	Type      string    `json:"type"`
+	Text      string    `json:"text"`
+	Mentioned Mentioned `json:"mentioned"`
+}
+
+// Mentioned id 使用用户的 UPN(通常为邮箱)
+type Mentioned struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func New(proxy string, bundle *bundle.Bundle, messenger pb.NotifyServiceServer) subscriber.Subscriber {
+	return &TeamsSubscriber{
+		bundle:    bundle,
+		messenger: messenger,
+		client:    subscriber.NewIMHTTPClient(proxy),
+	}
+}
+
+func (s *TeamsSubscriber) Publish(dest string, content string, time int64, msg *types.Message) []error {
+	targets, c, err := subscriber.ParseIMMessage(dest, content)
+	if err != nil {
+		return []error{err}
+	}
+	imTargets, config, errs := subscriber.ResolveIMTargets(targets,
+		subscriber.EnabledIMChannel(s.bundle, c.OrgID, apistructs.NOTIFY_CHANNEL_TYPE_TEAMS))
+	activity := Render(c)
+	for _, t := range imTargets {
+		if t.WebhookURL != "" {
+			err = subscriber.PostJSON(s.client, t.WebhookURL, nil, activity, nil)
+		} else {
+			err = s.sendActivity(config, t.Conversation, activity)
+		}
+		if err != nil {
+			logrus.Errorf("Teams publish: %v", err)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 && msg != nil && msg.CreateHistory != nil {
+		msg.CreateHistory.Status = "failed"
+	}
+	if msg != nil && msg.CreateHistory != nil {
+		subscriber.SaveNotifyHistories(msg.CreateHistory, s.messenger)
+	}
+	return errs
+}
+
+func (s *TeamsSubscriber) Status() interface{} {
+	return nil
+}
+
+func (s *TeamsSubscriber) Name() string {
+	return "TEAMS"
+}
+
+func (s *TeamsSubscriber) sendActivity(config *apistructs.NotifyChannelConfig, conversation string, activity Activity) error {
+	token, err := s.tokens.Get(config.AppID, func() (string, time.Duration, error) {
+		return s.fetchToken(config)
+	})
+	if err != nil {
+		return err
+	}
+	u := strings.TrimSuffix(config.ServiceURL, "/") + "/v3/conversations/" + url.PathEscape(conversation) + "/activities"
+	return subscriber.PostJSON(s.client, u, http.Header{"Authorization": []string{"Bearer " + token}}, activity, nil)
+}
+
+func (s *TeamsSubscriber) fetchToken(config *apistructs.NotifyChannelConfig) (string, time.Duration, error) {
+	tenant := config.TenantID
+	if tenant == "" {
+		tenant = defaultTenant
+	}
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {config.AppID},
+		"client_secret": {config.AppSecret},
+		"scope":         {botFrameworkAPI},
+	}
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/%s/oauth2/v2.0/token", loginURL, tenant), strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var resp tokenResponse
+	if err := subscriber.DoRequest(s.client, req, &resp); err != nil {
+		return "", 0, err
+	}
+	if resp.AccessToken == "" {
+		return "", 0, errors.New("get bot framework token: empty access_token")
+	}
+	return resp.AccessToken, time.Duration(resp.ExpiresIn) * time.Second, nil
+}
+
+// Render 将 markdown 内容渲染为 Adaptive Card，有邮箱的用户以 mention 实体 @
+func Render(c *subscriber.IMContent) Activity {
+	card := Card{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		MSTeams: &CardSettings{Width: "Full"},
+	}
+	if c.Title != "" {
+		card.Body = append(card.Body, TextBlock{Type: "TextBlock", Text: c.Title, Weight: "Bolder", Size: "Medium", Wrap: true})
+	}
+	if c.Text != "" {
+		card.Body = append(card.Body, TextBlock{Type: "TextBlock", Text: c.Text, Wrap: true})
+	}
+	var mentions []string
+	for _, m := range c.Mentions {
+		if m.Email == "" {
+			if m.Name != "" {
+				mentions = append(mentions, "@"+m.Name)
+			}
+			continue
+		}
+		name := m.Name
+		if name == "" {
+			name = m.Email
+		}
+		text := "<at>" + name + "</at>"
+		mentions = append(mentions, text)
+		card.MSTeams.Entities = append(card.MSTeams.Entities, Mention{
+			Type:      "mention",
+			Text:      text,
+			Mentioned: Mentioned{ID: m.Email, Name: name},
+		})
+	}
+	if len(mentions) > 0 {
+		card.Body = append(card.Body, TextBlock{Type: "TextBlock", Text: strings.Join(mentions, " "), Wrap: true})
+	}
+	return Activity{
+		Type:        "message",
+		Attachments: []Attachment{{ContentType: cardContentType, Content: card}},
+	}
+}
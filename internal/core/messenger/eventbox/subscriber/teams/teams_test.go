@@ -0,0 +1,45 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teams
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber"
+)
+
+func TestRender(t *testing.T) {
+	a := Render(&subscriber.IMContent{
+		Title: "Pipeline failed",
+		Text:  "**app** failed",
+		Mentions: []subscriber.IMMention{
+			{Name: "Alice", Email: "alice@erda.cloud"},
+			{Name: "Bob"},
+		},
+	})
+	require.Equal(t, "message", a.Type)
+	require.Len(t, a.Attachments, 1)
+	require.Equal(t, cardContentType, a.Attachments[0].ContentType)
+	card := a.Attachments[0].Content
+	require.Len(t, card.Body, 3)
+	require.Equal(t, "<at>Alice</at> @Bob", card.Body[2].Text)
+	require.Equal(t, []Mention{{
+		Type:      "mention",
+		Text:      "<at>Alice</at>",
+		Mentioned: Mentioned{ID: "alice@erda.cloud", Name: "Alice"},
+	}}, card.MSTeams.Entities)
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wecom
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda-proto-go/core/messenger/notify/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/bundle"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/types"
+)
+
+var apiURL = "https://qyapi.weixin.qq.com/cgi-bin"
+
+// maxContentSize 企业微信 markdown 消息内容最长 4096 字节
+const maxContentSize = 4096
+
+type WeComSubscriber struct {
+	bundle    *bundle.Bundle
+	messenger pb.NotifyServiceServer
+	client    *http.Client
+	tokens    subscriber.TokenCache
+}
+
+type Markdown struct {
+	Content string `json:"content"`
+}
+
+type Text struct {
+	Content             string   `json:"content"`
+	MentionedMobileList []string `json:"mentioned_mobile_list,omitempty"`
+}
+
+// Message 群机器人与应用消息共用的消息结构
+type Message struct {
+	ToUser   string    `json:"touser,omitempty"`
+	ToParty  string    `json:"toparty,omitempty"`
+	AgentID  int64     `json:"agentid,omitempty"`
+	MsgType  string    `json:"msgtype"`
+	Markdown *Markdown `json:"markdown,omitempty"`
+	Text     *Text     `json:"text,omitempty"`
+}
+
+type apiResponse struct {
+	ErrCode     int    `json:"errcode"`
+	ErrMsg      string `json:"errmsg"`
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	UserID      string `json:"userid"`
+}
+
+func (r *apiResponse) err() error {
+	if r.ErrCode != 0 {
+		return errors.Errorf("errcode: %d, errmsg: %s", r.ErrCode, r.ErrMsg)
+	}
+	return nil
+}
+
+func New(proxy string, bundle *bundle.Bundle, messenger pb.NotifyServiceServer) subscriber.Subscriber {
+	return &WeComSubscriber{
+		bundle:    bundle,
+		messenger: messenger,
+		client:    subscriber.NewIMHTTPClient(proxy),
+	}
+}
+
+func (s *WeComSubscriber) Publish(dest string, content string, time int64, msg *types.Message) []error {
+	targets, c, err := subscriber.ParseIMMessage(dest, content)
+	if err != nil {
+		return []error{err}
+	}
+	imTargets, config, errs := subscriber.ResolveIMTargets(targets,
+		subscriber.EnabledIMChannel(s.bundle, c.OrgID, apistructs.NOTIFY_CHANNEL_TYPE_WECOM))
+	markdown := Render(c)
+	for _, t := range imTargets {
+		if t.WebhookURL != "" {
+			err = s.sendWebhook(t.WebhookURL, c.Title, markdown, c.Mentions)
+		} else {
+			err = s.sendMessage(config, t.Conversation, markdown, c.Mentions)
+		}
+		if err != nil {
+			logrus.Errorf("WeCom publish: %v", err)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 && msg != nil && msg.CreateHistory != nil {
+		msg.CreateHistory.Status = "failed"
+	}
+	if msg != nil && msg.CreateHistory != nil {
+		subscriber.SaveNotifyHistories(msg.CreateHistory, s.messenger)
+	}
+	return errs
+}
+
+func (s *WeComSubscriber) Status() interface{} {
+	return nil
+}
+
+func (s *WeComSubscriber) Name() string {
+	return "WECOM"
+}
+
+// sendWebhook 群机器人的 markdown 消息不支持按手机号 @，需要额外发送一条 text 消息
+func (s *WeComSubscriber) sendWebhook(u, title, markdown string, mentions []subscriber.IMMention) error {
+	if err := s.post(u, Message{MsgType: "markdown", Markdown: &Markdown{Content: markdown}}, nil); err != nil {
+		return err
+	}
+	mobiles := mentionMobiles(mentions)
+	if len(mobiles) == 0 {
+		return nil
+	}
+	return s.post(u, Message{MsgType: "text", Text: &Text{Content: title, MentionedMobileList: mobiles}}, nil)
+}
+
+func (s *WeComSubscriber) sendMessage(config *apistructs.NotifyChannelConfig, receiver, markdown string, mentions []subscriber.IMMention) error {
+	token, err := s.tokens.Get(config.CorpID, func() (string, time.Duration, error) {
+		req, err := http.NewRequest(http.MethodGet, apiURL+"/gettoken?corpid="+url.QueryEscape(config.CorpID)+
+			"&corpsecret="+url.QueryEscape(config.CorpSecret), nil)
+		if err != nil {
+			return "", 0, err
+		}
+		var resp apiResponse
+		if err := subscriber.DoRequest(s.client, req, &resp); err != nil {
+			return "", 0, err
+		}
+		if err := resp.err(); err != nil {
+			return "", 0, errors.Wrap(err, "get access_token")
+		}
+		return resp.AccessToken, time.Duration(resp.ExpiresIn) * time.Second, nil
+	})
+	if err != nil {
+		return err
+	}
+	users := []string{receiver}
+	for _, mobile := range mentionMobiles(mentions) {
+		var resp apiResponse
+		if err := s.post(apiURL+"/user/getuserid?access_token="+url.QueryEscape(token), map[string]string{"mobile": mobile}, &resp); err != nil {
+			logrus.Warnf("WeCom publish: get userid by mobile %s failed, err: %v", mobile, err)
+			continue
+		}
+		users = append(users, resp.UserID)
+	}
+	return s.post(apiURL+"/message/send?access_token="+url.QueryEscape(token), Message{
+		ToUser:   strings.Join(users, "|"),
+		AgentID:  config.AgentId,
+		MsgType:  "markdown",
+		Markdown: &Markdown{Content: markdown},
+	}, nil)
+}
+
+func (s *WeComSubscriber) post(u string, body interface{}, result *apiResponse) error {
+	if result == nil {
+		result = &apiResponse{}
+	}
+	if err := subscriber.PostJSON(s.client, u, nil, body, result); err != nil {
+		return err
+	}
+	return result.err()
+}
+
+func mentionMobiles(mentions []subscriber.IMMention) []string {
+	var result []string
+	for _, m := range mentions {
+		if m.Mobile != "" {
+			result = append(result, m.Mobile)
+		}
+	}
+	return result
+}
+
+// Render 将标题与正文渲染为企业微信 markdown，超长时截断
+func Render(c *subscriber.IMContent) string {
+	var b strings.Builder
+	if c.Title != "" {
+		b.WriteString("### " + c.Title + "\n")
+	}
+	b.WriteString(c.Text)
+	for _, m := range c.Mentions {
+		if m.Mobile == "" && m.Name != "" {
+			b.WriteString(" @" + m.Name)
+		}
+	}
+	return truncate(b.String(), maxContentSize)
+}
+
+// truncate 按字节截断，保证不截断多字节字符
+func truncate(s string, size int) string {
+	if len(s) <= size {
+		return s
+	}
+	s = s[:size-3]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s + "..."
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wecom
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/subscriber"
+)
+
+func TestRender(t *testing.T) {
+	got := Render(&subscriber.IMContent{
+		Title:    "Pipeline failed",
+		Text:     "**app** failed",
+		Mentions: []subscriber.IMMention{{Name: "Alice", Mobile: "13800000000"}, {Name: "Bob"}},
+	})
+	require.Equal(t, "### Pipeline failed\n**app** failed @Bob", got)
+
+	got = Render(&subscriber.IMContent{Text: strings.Repeat("告警", maxContentSize)})
+	require.LessOrEqual(t, len(got), maxContentSize)
+	require.True(t, utf8.ValidString(got))
+}
+
+func TestWeComSubscriber_sendWebhook(t *testing.T) {
+	var got []Message
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m Message
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&m))
+		got = append(got, m)
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer srv.Close()
+
+	s := &WeComSubscriber{client: srv.Client()}
+	err := s.sendWebhook(srv.URL, "title", "content", []subscriber.IMMention{{Name: "Alice", Mobile: "13800000000"}})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Equal(t, "markdown", got[0].MsgType)
+	require.Equal(t, "content", got[0].Markdown.Content)
+	require.Equal(t, []string{"13800000000"}, got[1].Text.MentionedMobileList)
+}
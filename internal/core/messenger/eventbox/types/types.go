@@ -30,8 +30,20 @@ type Message struct {
 	Labels        map[LabelKey]interface{} `json:"labels"`
 	Time          int64                    `json:"time,omitempty"` // UnixNano
 	CreateHistory *apistructs.CreateNotifyHistoryRequest
+	// Category 事件类别，用于匹配用户的通知偏好
+	Category string `json:"category,omitempty"`
+	// Recipients 个人通知目标(邮箱、手机号等)对应的用户，用于匹配用户的通知偏好
+	Recipients []Recipient `json:"recipients,omitempty"`
 
-	originContent interface{} `json:"-"`
+	originContent  interface{} `json:"-"`
+	skipPreference bool
+}
+
+// Recipient 个人通知目标对应的用户
+type Recipient struct {
+	UserID string `json:"userId"`
+	Email  string `json:"email"`
+	Mobile string `json:"mobile"`
 }
 
 // Before 是否早于 `t'
@@ -49,6 +61,16 @@ func (m *Message) SetOriginContent(content interface{}) {
 	m.originContent = content
 }
 
+// SkipPreference 是否跳过用户通知偏好，摘要等已经应用过偏好的消息不再处理
+func (m *Message) SkipPreference() bool {
+	return m.skipPreference
+}
+
+// SetSkipPreference set `Message.skipPreference'
+func (m *Message) SetSkipPreference(skip bool) {
+	m.skipPreference = skip
+}
+
 // HasPrefix 格式化 labelkey & `s' 之后，判断是否有 `s' 前缀
 func (k LabelKey) HasPrefix(s string) bool {
 	k_ := k.Normalize()
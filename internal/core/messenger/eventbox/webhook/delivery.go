@@ -0,0 +1,436 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+// DeliveryConfig webhook 投递的重试及保留策略
+type DeliveryConfig struct {
+	// MaxAttempts 最大投递次数，超过后进入 dead 状态
+	MaxAttempts int
+	// BaseBackoff 首次重试间隔，之后每次翻倍
+	BaseBackoff time.Duration
+	// MaxBackoff 重试间隔上限
+	MaxBackoff time.Duration
+	// Timeout 单次请求超时
+	Timeout time.Duration
+	// PollInterval 扫描待投递记录的间隔
+	PollInterval time.Duration
+	// BatchSize 每次扫描取出的记录数
+	BatchSize int
+	// Concurrency 并发投递数
+	Concurrency int
+	// DisableThreshold 连续 dead 次数达到该值时自动停用 hook，<= 0 表示不停用
+	DisableThreshold int
+	// Retention 已结束(succeeded, dead)的投递记录保留时长
+	Retention time.Duration
+	// MaxResponseBodySize 记录的响应体最大字节数
+	MaxResponseBodySize int
+}
+
+// DeliveryRecord webhook 投递记录
+type DeliveryRecord struct {
+	ID              uint64 `gorm:"primary_key"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	HookID          string
+	OrgID           string
+	Event           string
+	Action          string
+	Status          string
+	Attempts        int
+	NextRetryAt     *time.Time
+	RequestURL      string
+	RequestHeaders  string
+	RequestBody     string
+	ResponseStatus  int
+	ResponseHeaders string
+	ResponseBody    string
+	LatencyMs       int64
+	Error           string
+	ReplayOf        uint64
+}
+
+// TableName 设置模型对应数据库表名称
+func (DeliveryRecord) TableName() string {
+	return "erda_webhook_deliveries"
+}
+
+// ToAPI 转换为 apistructs.WebhookDelivery
+func (r *DeliveryRecord) ToAPI() *apistructs.WebhookDelivery {
+	d := &apistructs.WebhookDelivery{
+		ID:             r.ID,
+		HookID:         r.HookID,
+		Event:          r.Event,
+		Action:         r.Action,
+		Status:         apistructs.WebhookDeliveryStatus(r.Status),
+		Attempts:       r.Attempts,
+		NextRetryAt:    r.NextRetryAt,
+		RequestURL:     r.RequestURL,
+		RequestBody:    r.RequestBody,
+		ResponseStatus: r.ResponseStatus,
+		ResponseBody:   r.ResponseBody,
+		LatencyMs:      r.LatencyMs,
+		Error:          r.Error,
+		ReplayOf:       r.ReplayOf,
+		CreatedAt:      r.CreatedAt,
+		UpdatedAt:      r.UpdatedAt,
+	}
+	if r.RequestHeaders != "" {
+		_ = json.Unmarshal([]byte(r.RequestHeaders), &d.RequestHeaders)
+	}
+	if r.ResponseHeaders != "" {
+		_ = json.Unmarshal([]byte(r.ResponseHeaders), &d.ResponseHeaders)
+	}
+	return d
+}
+
+// Deliverer 将 webhook 事件持久化后异步投递，失败时按指数退避重试，
+// 重试耗尽的记录进入 dead 状态，可通过 Replay 手动重放。
+type Deliverer struct {
+	db     *gorm.DB
+	impl   *WebHookImpl
+	client *http.Client
+	cfg    DeliveryConfig
+
+	// 同一实例内串行更新 hook 的失败计数
+	hookMu sync.Mutex
+}
+
+func NewDeliverer(db *gorm.DB, impl *WebHookImpl, cfg DeliveryConfig) *Deliverer {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.MaxResponseBodySize <= 0 {
+		cfg.MaxResponseBodySize = 64 * 1024
+	}
+	return &Deliverer{
+		db:     db,
+		impl:   impl,
+		client: &http.Client{Timeout: cfg.Timeout},
+		cfg:    cfg,
+	}
+}
+
+// Enqueue 为 hook 生成一条待投递记录
+func (d *Deliverer) Enqueue(h Hook, m EventMessage) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	r := DeliveryRecord{
+		HookID:      h.ID,
+		OrgID:       h.Org,
+		Event:       m.Event,
+		Action:      m.Action,
+		Status:      string(apistructs.WebhookDeliveryStatusPending),
+		NextRetryAt: &now,
+		RequestURL:  h.URL,
+		RequestBody: string(body),
+	}
+	return d.db.Create(&r).Error
+}
+
+// Run 循环投递到期的记录，直到 ctx 结束
+func (d *Deliverer) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	lastCleanup := time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		d.deliverDue(ctx)
+		if d.cfg.Retention > 0 && time.Since(lastCleanup) > time.Hour {
+			if err := d.cleanup(); err != nil {
+				logrus.Errorf("webhook delivery: cleanup: %v", err)
+			}
+			lastCleanup = time.Now()
+		}
+	}
+}
+
+func (d *Deliverer) deliverDue(ctx context.Context) {
+	now := time.Now()
+	// delivering 状态长时间未更新说明投递过程中实例退出，需要重新投递
+	stale := now.Add(-2*d.cfg.Timeout - time.Minute)
+	var due []DeliveryRecord
+	err := d.db.Where("(status IN (?) AND next_retry_at <= ?) OR (status = ? AND updated_at < ?)",
+		[]string{string(apistructs.WebhookDeliveryStatusPending), string(apistructs.WebhookDeliveryStatusRetrying)}, now,
+		string(apistructs.WebhookDeliveryStatusDelivering), stale).
+		Order("next_retry_at").Limit(d.cfg.BatchSize).Find(&due).Error
+	if err != nil {
+		logrus.Errorf("webhook delivery: list due deliveries: %v", err)
+		return
+	}
+
+	sem := make(chan struct{}, d.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		r := due[i]
+		if !d.claim(&r) {
+			continue
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			d.deliver(ctx, &r)
+		}()
+	}
+	wg.Wait()
+}
+
+// claim 通过条件更新抢占记录，多实例部署时保证同一记录同时只被一个实例投递
+func (d *Deliverer) claim(r *DeliveryRecord) bool {
+	res := d.db.Model(&DeliveryRecord{}).
+		Where("id = ? AND status = ? AND attempts = ?", r.ID, r.Status, r.Attempts).
+		Updates(map[string]interface{}{"status": string(apistructs.WebhookDeliveryStatusDelivering)})
+	if res.Error != nil {
+		logrus.Errorf("webhook delivery: claim delivery %d: %v", r.ID, res.Error)
+		return false
+	}
+	return res.RowsAffected == 1
+}
+
+func (d *Deliverer) deliver(ctx context.Context, r *DeliveryRecord) {
+	h, err := d.impl.InspectHook("", r.HookID)
+	if err != nil {
+		// hook 已被删除，不再重试
+		d.finish(r, apistructs.WebhookDeliveryStatusDead, fmt.Sprintf("get hook: %v", err))
+		return
+	}
+
+	body := []byte(r.RequestBody)
+	headers := map[string]string{
+		"Content-Type": "application/json",
+		EventHeader:    r.Event,
+		DeliveryHeader: strconv.FormatUint(r.ID, 10),
+	}
+	// 每次投递使用 hook 当前的地址和 secret，修改地址或轮换密钥后重试的请求也能送达并通过校验
+	if h.Secret != "" {
+		headers[SignatureHeader] = Sign(h.Secret, body)
+	}
+	rawHeaders, _ := json.Marshal(headers)
+	r.RequestURL = h.URL
+	r.RequestHeaders = string(rawHeaders)
+	r.Attempts++
+
+	status, respHeaders, respBody, latency, err := d.send(ctx, r.RequestURL, headers, body)
+	r.ResponseStatus = status
+	r.ResponseHeaders = respHeaders
+	r.ResponseBody = respBody
+	r.LatencyMs = latency.Milliseconds()
+	switch {
+	case err != nil:
+		r.Error = err.Error()
+	case status < 200 || status >= 300:
+		r.Error = fmt.Sprintf("unexpected status code: %d", status)
+	default:
+		r.Error = ""
+		d.finish(r, apistructs.WebhookDeliveryStatusSucceeded, "")
+		d.hookMu.Lock()
+		defer d.hookMu.Unlock()
+		if err := d.impl.ResetFailures(r.HookID); err != nil {
+			logrus.Errorf("webhook delivery: reset failures of hook %s: %v", r.HookID, err)
+		}
+		return
+	}
+
+	if r.Attempts >= d.cfg.MaxAttempts {
+		d.finish(r, apistructs.WebhookDeliveryStatusDead, r.Error)
+		d.hookMu.Lock()
+		defer d.hookMu.Unlock()
+		disabled, err := d.impl.RecordFailure(r.HookID, d.cfg.DisableThreshold)
+		if err != nil {
+			logrus.Errorf("webhook delivery: record failure of hook %s: %v", r.HookID, err)
+		}
+		if disabled {
+			logrus.Warnf("webhook delivery: hook %s disabled after %d consecutive failed deliveries", r.HookID, d.cfg.DisableThreshold)
+		}
+		return
+	}
+	next := time.Now().Add(backoff(d.cfg.BaseBackoff, d.cfg.MaxBackoff, r.Attempts))
+	r.NextRetryAt = &next
+	d.save(r, apistructs.WebhookDeliveryStatusRetrying)
+}
+
+func (d *Deliverer) send(ctx context.Context, url string, headers map[string]string, body []byte) (int, string, string, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", "", 0, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", "", time.Since(start), err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, int64(d.cfg.MaxResponseBodySize)))
+	latency := time.Since(start)
+	if err != nil {
+		return resp.StatusCode, "", "", latency, err
+	}
+	respHeaders := make(map[string]string, len(resp.Header))
+	for k := range resp.Header {
+		respHeaders[k] = resp.Header.Get(k)
+	}
+	rawHeaders, _ := json.Marshal(respHeaders)
+	return resp.StatusCode, string(rawHeaders), string(respBody), latency, nil
+}
+
+func (d *Deliverer) finish(r *DeliveryRecord, status apistructs.WebhookDeliveryStatus, errMsg string) {
+	r.NextRetryAt = nil
+	r.Error = errMsg
+	d.save(r, status)
+}
+
+func (d *Deliverer) save(r *DeliveryRecord, status apistructs.WebhookDeliveryStatus) {
+	r.Status = string(status)
+	if err := d.db.Save(r).Error; err != nil {
+		logrus.Errorf("webhook delivery: save delivery %d: %v", r.ID, err)
+	}
+}
+
+func (d *Deliverer) cleanup() error {
+	return d.db.Where("status IN (?) AND created_at < ?",
+		[]string{string(apistructs.WebhookDeliveryStatusSucceeded), string(apistructs.WebhookDeliveryStatusDead)},
+		time.Now().Add(-d.cfg.Retention)).Delete(&DeliveryRecord{}).Error
+}
+
+// backoff 第 attempts 次失败后的重试间隔: base * 2^(attempts-1)，不超过 max
+func backoff(base, max time.Duration, attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	wait := base
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if max > 0 && wait >= max {
+			return max
+		}
+	}
+	if max > 0 && wait > max {
+		return max
+	}
+	return wait
+}
+
+// ListDeliveries 列出 hook 的投递记录，按创建时间倒序，不返回请求体和响应体
+func (d *Deliverer) ListDeliveries(req apistructs.WebhookDeliveryListRequest) (*apistructs.WebhookDeliveryListResponseData, error) {
+	if req.PageNo <= 0 {
+		req.PageNo = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+	if req.PageSize > 100 {
+		req.PageSize = 100
+	}
+	query := d.db.Model(&DeliveryRecord{}).Where("hook_id = ?", req.HookID)
+	if req.Status != "" {
+		query = query.Where("status = ?", string(req.Status))
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, errors.Wrap(InternalServerErr, err.Error())
+	}
+	var records []DeliveryRecord
+	if err := query.Select("id, created_at, updated_at, hook_id, org_id, event, action, status, attempts, next_retry_at, " +
+		"request_url, response_status, latency_ms, error, replay_of").
+		Order("created_at DESC, id DESC").
+		Offset((req.PageNo - 1) * req.PageSize).Limit(req.PageSize).
+		Find(&records).Error; err != nil {
+		return nil, errors.Wrap(InternalServerErr, err.Error())
+	}
+	list := make([]*apistructs.WebhookDelivery, 0, len(records))
+	for i := range records {
+		list = append(list, records[i].ToAPI())
+	}
+	return &apistructs.WebhookDeliveryListResponseData{Total: total, List: list}, nil
+}
+
+// GetDelivery 获取 hook 的一条投递记录
+func (d *Deliverer) GetDelivery(hookID string, id uint64) (*DeliveryRecord, error) {
+	var r DeliveryRecord
+	if err := d.db.Where("id = ? AND hook_id = ?", id, hookID).First(&r).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, fmt.Errorf("not found")
+		}
+		return nil, errors.Wrap(InternalServerErr, err.Error())
+	}
+	return &r, nil
+}
+
+// Replay 以原请求体重新投递一次，使用 hook 当前的地址和 secret，生成新的投递记录
+func (d *Deliverer) Replay(h Hook, id uint64) (*DeliveryRecord, error) {
+	origin, err := d.GetDelivery(h.ID, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	r := DeliveryRecord{
+		HookID:      h.ID,
+		OrgID:       h.Org,
+		Event:       origin.Event,
+		Action:      origin.Action,
+		Status:      string(apistructs.WebhookDeliveryStatusPending),
+		NextRetryAt: &now,
+		RequestURL:  h.URL,
+		RequestBody: origin.RequestBody,
+		ReplayOf:    origin.ID,
+	}
+	if err := d.db.Create(&r).Error; err != nil {
+		return nil, errors.Wrap(InternalServerErr, err.Error())
+	}
+	return &r, nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"pipeline"}`)
+	sig := Sign("secret", body)
+	assert.Equal(t, "sha256=", sig[:7])
+	assert.Len(t, sig, 7+64)
+	assert.True(t, VerifySignature("secret", body, sig))
+	assert.False(t, VerifySignature("other", body, sig))
+	assert.False(t, VerifySignature("secret", []byte(`{}`), sig))
+}
+
+func TestGenSecret(t *testing.T) {
+	s1, err := genSecret()
+	assert.NoError(t, err)
+	s2, err := genSecret()
+	assert.NoError(t, err)
+	assert.Len(t, s1, 40)
+	assert.NotEqual(t, s1, s2)
+}
+
+func TestBackoff(t *testing.T) {
+	base, max := 30*time.Second, 10*time.Minute
+	assert.Equal(t, 30*time.Second, backoff(base, max, 0))
+	assert.Equal(t, 30*time.Second, backoff(base, max, 1))
+	assert.Equal(t, time.Minute, backoff(base, max, 2))
+	assert.Equal(t, 8*time.Minute, backoff(base, max, 5))
+	assert.Equal(t, max, backoff(base, max, 6))
+	assert.Equal(t, max, backoff(base, max, 100))
+	assert.Equal(t, 4*time.Minute, backoff(base, 0, 4))
+}
+
+func TestDelivererSend(t *testing.T) {
+	var gotSig, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		gotSig = r.Header.Get(SignatureHeader)
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		rw.Header().Set("X-Test", "ok")
+		rw.WriteHeader(http.StatusAccepted)
+		rw.Write([]byte("0123456789"))
+	}))
+	defer srv.Close()
+
+	d := NewDeliverer(nil, nil, DeliveryConfig{Timeout: time.Second, MaxResponseBodySize: 4})
+	body := []byte(`{"event":"runtime"}`)
+	status, headers, respBody, _, err := d.send(context.Background(), srv.URL, map[string]string{
+		SignatureHeader: Sign("s", body),
+	}, body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, status)
+	assert.Contains(t, headers, `"X-Test":"ok"`)
+	assert.Equal(t, "0123", respBody)
+	assert.Equal(t, string(body), gotBody)
+	assert.True(t, VerifySignature("s", []byte(gotBody), gotSig))
+}
+
+func TestDeliveryRecordToAPI(t *testing.T) {
+	r := DeliveryRecord{
+		ID:              1,
+		HookID:          "h",
+		Status:          "dead",
+		RequestHeaders:  `{"X-Erda-Event":"pipeline"}`,
+		ResponseHeaders: `{"Server":"nginx"}`,
+		ReplayOf:        0,
+	}
+	d := r.ToAPI()
+	assert.Equal(t, "pipeline", d.RequestHeaders[EventHeader])
+	assert.Equal(t, "nginx", d.ResponseHeaders["Server"])
+	assert.Equal(t, "dead", string(d.Status))
+}
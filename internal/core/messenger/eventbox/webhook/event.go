@@ -34,6 +34,8 @@ type EventMessage struct {
 }
 
 func PingEvent(org, project, application string, h Hook) (*EventMessage, error) {
+	// ping 内容中不携带签名密钥
+	h.Secret = ""
 	hraw, err := json.Marshal(h)
 	if err != nil {
 		return nil, err
@@ -50,9 +50,23 @@ func toCode(e error) string {
 
 type WebHookHTTP struct {
 	impl        *WebHookImpl
+	deliverer   *Deliverer
 	CoreService legacy.ExposedInterface
 }
 
+// SetDeliverer 设置后，事件通过 deliverer 持久化投递并支持重试，否则直接由 http subscriber 发送
+func (w *WebHookHTTP) SetDeliverer(d *Deliverer) {
+	w.deliverer = d
+}
+
+func (w *WebHookHTTP) Deliverer() *Deliverer {
+	return w.deliverer
+}
+
+func (w *WebHookHTTP) Impl() *WebHookImpl {
+	return w.impl
+}
+
 func NewWebHookHTTP() (*WebHookHTTP, error) {
 	impl, err := NewWebHookImpl()
 	if err != nil {
@@ -79,6 +93,10 @@ func (w *WebHookHTTP) ListHooks(ctx context.Context, req *pb.ListHooksRequest, v
 	if err != nil {
 		return nil, err
 	}
+	// secret 只通过 rotate-secret 返回
+	for i := range r {
+		r[i].Secret = ""
+	}
 
 	if err != nil {
 		return nil, err
@@ -109,6 +127,7 @@ func (w *WebHookHTTP) InspectHook(ctx context.Context, req *pb.InspectHookReques
 			Data: nil,
 		}, err
 	}
+	r.Secret = ""
 	data, err := json.Marshal(r)
 	if err != nil {
 		return &pb.InspectHookResponse{
@@ -148,10 +148,14 @@ func (w *WebHookImpl) CreateHook(realOrg string, h CreateHookRequest) (CreateHoo
 		return CreateHookResponse(""), errors.Wrap(BadRequestErr, fmt.Sprintf("cannot operate on org: %v", hook.Org))
 	}
 
+	secret, err := genSecret()
+	if err != nil {
+		return CreateHookResponse(""), errors.Wrap(InternalServerErr, fmt.Sprintf("generate webhook secret fail: %v", err))
+	}
+	hook.Secret = secret
 	hook.CreatedAt = nowTimestamp()
 	hook.UpdatedAt = nowTimestamp()
 	hook.ID = genID()
-	var err error
 	defer func() {
 		if err != nil {
 			var unused interface{}
@@ -197,6 +201,11 @@ func (w *WebHookImpl) EditHook(realOrg, id string, e EditHookRequest) (EditHookR
 	}
 
 	h.Active = e.Active
+	if h.Active {
+		// 手动启用后重新计数
+		h.ConsecutiveFailures = 0
+		h.DisabledReason = ""
+	}
 	h.UpdatedAt = nowTimestamp()
 
 	if err := w.js.Put(context.Background(), mkHookEtcdName(id), h); err != nil {
@@ -255,6 +264,58 @@ func (w *WebHookImpl) DeleteHook(realOrg, id string) error {
 	return nil
 }
 
+// RotateSecret 重新生成 hook 的签名密钥，返回新密钥
+func (w *WebHookImpl) RotateSecret(realOrg, id string) (string, error) {
+	h := Hook{}
+	if err := w.js.Get(context.Background(), mkHookEtcdName(id), &h); err != nil {
+		return "", errors.Wrap(InternalServerErr, err.Error())
+	}
+	if realOrg != "" && !hookCheckOrg(h, realOrg) {
+		return "", fmt.Errorf("not found")
+	}
+	secret, err := genSecret()
+	if err != nil {
+		return "", errors.Wrap(InternalServerErr, fmt.Sprintf("generate webhook secret fail: %v", err))
+	}
+	h.Secret = secret
+	h.UpdatedAt = nowTimestamp()
+	if err := w.js.Put(context.Background(), mkHookEtcdName(id), h); err != nil {
+		return "", errors.Wrap(InternalServerErr, fmt.Sprintf("jsonstore put webhook fail: %v", err))
+	}
+	return secret, nil
+}
+
+// ResetFailures 投递成功后清零连续失败次数
+func (w *WebHookImpl) ResetFailures(id string) error {
+	h := Hook{}
+	if err := w.js.Get(context.Background(), mkHookEtcdName(id), &h); err != nil {
+		return err
+	}
+	if h.ConsecutiveFailures == 0 {
+		return nil
+	}
+	h.ConsecutiveFailures = 0
+	return w.js.Put(context.Background(), mkHookEtcdName(id), h)
+}
+
+// RecordFailure 记录一次重试耗尽的投递，连续失败次数达到 threshold 时自动停用 hook。
+// threshold <= 0 表示不自动停用。返回 hook 是否因此被停用。
+func (w *WebHookImpl) RecordFailure(id string, threshold int) (bool, error) {
+	h := Hook{}
+	if err := w.js.Get(context.Background(), mkHookEtcdName(id), &h); err != nil {
+		return false, err
+	}
+	h.ConsecutiveFailures++
+	disabled := false
+	if threshold > 0 && h.ConsecutiveFailures >= threshold && h.Active {
+		h.Active = false
+		h.DisabledReason = fmt.Sprintf("auto disabled after %d consecutive failed deliveries", h.ConsecutiveFailures)
+		h.UpdatedAt = nowTimestamp()
+		disabled = true
+	}
+	return disabled, w.js.Put(context.Background(), mkHookEtcdName(id), h)
+}
+
 /* search hooks which include 'event' and is 'active' */
 func (w *WebHookImpl) SearchHooks(location HookLocation, event string) []Hook {
 	hs, err := w.ListHooks(location)
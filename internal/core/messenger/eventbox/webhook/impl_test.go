@@ -29,6 +29,14 @@ func TestAddEvents(t *testing.T) {
 	assert.Equal(t, []string{"a", "c", "d"}, addEvents([]string{"a", "c"}, []string{"c", "c", "d"}))
 }
 
+func TestPingEvent(t *testing.T) {
+	h := Hook{ID: "1", Secret: "s3cr3t"}
+	m, err := PingEvent("1", "2", "3", h)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(m.Content), "s3cr3t")
+	assert.Equal(t, "s3cr3t", h.Secret)
+}
+
 // func TestCreateHook(t *testing.T) {
 // 	impl, _ := NewWebHookImpl()
 // 	r, err := impl.CreateHook("6", CreateHookRequest{
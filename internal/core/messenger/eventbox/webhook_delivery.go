@@ -0,0 +1,170 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventbox
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/erda-project/erda-infra/pkg/transport"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/core/messenger/eventbox/webhook"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+)
+
+func (p *provider) initWebhookDeliveryEndpoints() {
+	p.Register.Add(http.MethodGet, "/api/dice/eventbox/webhooks/{id}/deliveries", p.listWebhookDeliveries)
+	p.Register.Add(http.MethodGet, "/api/dice/eventbox/webhooks/{id}/deliveries/{deliveryId}", p.getWebhookDelivery)
+	p.Register.Add(http.MethodPost, "/api/dice/eventbox/webhooks/{id}/deliveries/{deliveryId}/actions/replay", p.replayWebhookDelivery)
+	p.Register.Add(http.MethodPost, "/api/dice/eventbox/webhooks/{id}/actions/rotate-secret", p.rotateWebhookSecret)
+}
+
+// authorizedHook 获取路径中的 hook 并校验当前用户的操作权限，org 不匹配时按不存在处理
+func (p *provider) authorizedHook(r *http.Request) (webhook.Hook, error) {
+	wh := p.eventBoxService.WebHookHTTP
+	h, err := wh.Impl().InspectHook(r.Header.Get("Org-ID"), mux.Vars(r)["id"])
+	if err != nil {
+		return webhook.Hook{}, err
+	}
+	ctx := transport.WithHeader(r.Context(), metadata.New(map[string]string{
+		"user-id": r.Header.Get("User-ID"),
+		"org-id":  r.Header.Get("Org-ID"),
+	}))
+	if err := wh.CheckPermission(ctx, h.Org, h.Project, h.Application); err != nil {
+		return webhook.Hook{}, err
+	}
+	return webhook.Hook(h), nil
+}
+
+func (p *provider) webhookDeliverer() (*webhook.Deliverer, error) {
+	d := p.eventBoxService.WebHookHTTP.Deliverer()
+	if d == nil {
+		return nil, fmt.Errorf("webhook delivery is not enabled")
+	}
+	return d, nil
+}
+
+func (p *provider) listWebhookDeliveries(rw http.ResponseWriter, r *http.Request) {
+	h, err := p.authorizedHook(r)
+	if err != nil {
+		writeBadRequest(rw, err)
+		return
+	}
+	d, err := p.webhookDeliverer()
+	if err != nil {
+		writeBadRequest(rw, err)
+		return
+	}
+	req := apistructs.WebhookDeliveryListRequest{
+		HookID: h.ID,
+		Status: apistructs.WebhookDeliveryStatus(r.URL.Query().Get("status")),
+	}
+	if req.PageNo, err = queryInt(r, "pageNo"); err != nil {
+		writeBadRequest(rw, err)
+		return
+	}
+	if req.PageSize, err = queryInt(r, "pageSize"); err != nil {
+		writeBadRequest(rw, err)
+		return
+	}
+	res, err := d.ListDeliveries(req)
+	if err != nil {
+		writeBadRequest(rw, err)
+		return
+	}
+	httpserver.WriteData(rw, res)
+}
+
+func (p *provider) getWebhookDelivery(rw http.ResponseWriter, r *http.Request) {
+	h, err := p.authorizedHook(r)
+	if err != nil {
+		writeBadRequest(rw, err)
+		return
+	}
+	d, err := p.webhookDeliverer()
+	if err != nil {
+		writeBadRequest(rw, err)
+		return
+	}
+	id, err := strconv.ParseUint(mux.Vars(r)["deliveryId"], 10, 64)
+	if err != nil {
+		writeBadRequest(rw, fmt.Errorf("invalid deliveryId"))
+		return
+	}
+	record, err := d.GetDelivery(h.ID, id)
+	if err != nil {
+		writeBadRequest(rw, err)
+		return
+	}
+	httpserver.WriteData(rw, record.ToAPI())
+}
+
+func (p *provider) replayWebhookDelivery(rw http.ResponseWriter, r *http.Request) {
+	h, err := p.authorizedHook(r)
+	if err != nil {
+		writeBadRequest(rw, err)
+		return
+	}
+	d, err := p.webhookDeliverer()
+	if err != nil {
+		writeBadRequest(rw, err)
+		return
+	}
+	id, err := strconv.ParseUint(mux.Vars(r)["deliveryId"], 10, 64)
+	if err != nil {
+		writeBadRequest(rw, fmt.Errorf("invalid deliveryId"))
+		return
+	}
+	record, err := d.Replay(h, id)
+	if err != nil {
+		writeBadRequest(rw, err)
+		return
+	}
+	httpserver.WriteData(rw, record.ToAPI())
+}
+
+func (p *provider) rotateWebhookSecret(rw http.ResponseWriter, r *http.Request) {
+	h, err := p.authorizedHook(r)
+	if err != nil {
+		writeBadRequest(rw, err)
+		return
+	}
+	secret, err := p.eventBoxService.WebHookHTTP.Impl().RotateSecret(h.Org, h.ID)
+	if err != nil {
+		writeBadRequest(rw, err)
+		return
+	}
+	httpserver.WriteData(rw, apistructs.WebhookSecretResponseData{Secret: secret})
+}
+
+func queryInt(r *http.Request, key string) (int, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %s", key, v)
+	}
+	return i, nil
+}
+
+func writeBadRequest(rw http.ResponseWriter, err error) {
+	httpserver.WriteErr(rw, strconv.Itoa(http.StatusBadRequest), err.Error())
+}
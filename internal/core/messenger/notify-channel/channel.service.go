@@ -47,6 +47,11 @@ var ProviderAndType = map[string]string{
 	pb.ProviderType_DINGTALK.String():   pb.Type_DINGTALK_WORK_NOTICE.String(),
 	pb.ProviderType_SMTP.String():       pb.Type_EMAIL.String(),
 	pb.ProviderType_ALIYUN_VMS.String(): pb.Type_VMS.String(),
+	// IM 类渠道的 provider 与 type 同名
+	string(apistructs.NOTIFY_CHANNEL_PROVIDER_TYPE_SLACK):  string(apistructs.NOTIFY_CHANNEL_TYPE_SLACK),
+	string(apistructs.NOTIFY_CHANNEL_PROVIDER_TYPE_TEAMS):  string(apistructs.NOTIFY_CHANNEL_TYPE_TEAMS),
+	string(apistructs.NOTIFY_CHANNEL_PROVIDER_TYPE_FEISHU): string(apistructs.NOTIFY_CHANNEL_TYPE_FEISHU),
+	string(apistructs.NOTIFY_CHANNEL_PROVIDER_TYPE_WECOM):  string(apistructs.NOTIFY_CHANNEL_TYPE_WECOM),
 }
 
 func (s notifyChannelService) CreateNotifyChannel(ctx context.Context, req *pb.CreateNotifyChannelRequest) (*pb.CreateNotifyChannelResponse, error) {
@@ -72,18 +77,9 @@ func (s notifyChannelService) CreateNotifyChannel(ctx context.Context, req *pb.C
 	if err != nil {
 		return nil, err
 	}
-	encryptSecret, err := s.p.bdl.KMSEncrypt(apistructs.KMSEncryptRequest{
-		EncryptRequest: kmstypes.EncryptRequest{
-			KeyID:           kmsKey.KeyMetadata.KeyID,
-			PlaintextBase64: base64.StdEncoding.EncodeToString([]byte(req.Config["need_kms_data"].GetStringValue())),
-		},
-	})
-	if err != nil {
+	if _, _, err := s.encryptConfigSecret(kmsKey.KeyMetadata.KeyID, req.Config); err != nil {
 		return nil, err
 	}
-	req.Config[c["need_kms_key"].GetStringValue()] = structpb.NewStringValue(encryptSecret.CiphertextBase64)
-	delete(req.Config, "need_kms_key")
-	delete(req.Config, "need_kms_data")
 	ch, err := s.NotifyChannelDB.GetByName(req.Name)
 	if err != nil {
 		return nil, err
@@ -239,10 +235,125 @@ func (s *notifyChannelService) ConfigValidate(channelType string, c map[string]*
 		c["need_kms_data"] = structpb.NewStringValue(vms.AccessKeySecret)
 		return c, nil
 	default:
+		if im := newIM(channelType); im != nil {
+			return imConfigValidate(c, im)
+		}
 		return nil, errors.New("Not support notify channel type")
 	}
 }
 
+func newIM(channelType string) kind.IM {
+	switch channelType {
+	case string(apistructs.NOTIFY_CHANNEL_PROVIDER_TYPE_SLACK):
+		return &kind.Slack{}
+	case string(apistructs.NOTIFY_CHANNEL_PROVIDER_TYPE_TEAMS):
+		return &kind.Teams{}
+	case string(apistructs.NOTIFY_CHANNEL_PROVIDER_TYPE_FEISHU):
+		return &kind.Feishu{}
+	case string(apistructs.NOTIFY_CHANNEL_PROVIDER_TYPE_WECOM):
+		return &kind.WeCom{}
+	default:
+		return nil
+	}
+}
+
+func imConfigValidate(c map[string]*structpb.Value, im kind.IM) (map[string]*structpb.Value, error) {
+	if err := imConfigParse(c, im); err != nil {
+		return nil, err
+	}
+	if err := im.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// imConfigParse 解析 IM 渠道配置，并标记需要 kms 加密的配置项
+func imConfigParse(c map[string]*structpb.Value, im kind.IM) error {
+	bytes, err := json.Marshal(c)
+	if err != nil {
+		return errors.New("Json parser failed.")
+	}
+	if err := json.Unmarshal(bytes, im); err != nil {
+		return err
+	}
+	key, value := im.Secret()
+	c["need_kms_key"] = structpb.NewStringValue(key)
+	c["need_kms_data"] = structpb.NewStringValue(value)
+	return nil
+}
+
+// decryptConfig 解密已保存的渠道配置；IM 渠道 webhook 模式加密的是 webhook 地址，需要解密后再校验
+func (s notifyChannelService) decryptConfig(channelType, keyID string, c map[string]*structpb.Value) (map[string]*structpb.Value, error) {
+	im := newIM(channelType)
+	if im == nil {
+		cTemp, err := s.ConfigValidate(channelType, c)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.decryptConfigSecret(keyID, cTemp); err != nil {
+			return nil, err
+		}
+		return cTemp, nil
+	}
+	if err := imConfigParse(c, im); err != nil {
+		return nil, err
+	}
+	if err := s.decryptConfigSecret(keyID, c); err != nil {
+		return nil, err
+	}
+	if _, err := imConfigValidate(c, newIM(channelType)); err != nil {
+		return nil, err
+	}
+	delete(c, "need_kms_key")
+	delete(c, "need_kms_data")
+	return c, nil
+}
+
+// encryptConfigSecret 使用 kms 加密 ConfigValidate 标记的敏感配置项，返回该配置项及其明文
+func (s notifyChannelService) encryptConfigSecret(keyID string, c map[string]*structpb.Value) (string, string, error) {
+	key, data := c["need_kms_key"].GetStringValue(), c["need_kms_data"].GetStringValue()
+	delete(c, "need_kms_key")
+	delete(c, "need_kms_data")
+	if key == "" {
+		return "", "", nil
+	}
+	encryptSecret, err := s.p.bdl.KMSEncrypt(apistructs.KMSEncryptRequest{
+		EncryptRequest: kmstypes.EncryptRequest{
+			KeyID:           keyID,
+			PlaintextBase64: base64.StdEncoding.EncodeToString([]byte(data)),
+		},
+	})
+	if err != nil {
+		return "", "", err
+	}
+	c[key] = structpb.NewStringValue(encryptSecret.CiphertextBase64)
+	return key, data, nil
+}
+
+// decryptConfigSecret 解密 ConfigValidate 标记的敏感配置项
+func (s notifyChannelService) decryptConfigSecret(keyID string, c map[string]*structpb.Value) error {
+	key, data := c["need_kms_key"].GetStringValue(), c["need_kms_data"].GetStringValue()
+	delete(c, "need_kms_key")
+	delete(c, "need_kms_data")
+	if key == "" {
+		return nil
+	}
+	decrypt, err := s.p.bdl.KMSDecrypt(apistructs.KMSDecryptRequest{
+		DecryptRequest: kmstypes.DecryptRequest{
+			KeyID:            keyID,
+			CiphertextBase64: data,
+		}})
+	if err != nil {
+		return err
+	}
+	decodeString, err := base64.StdEncoding.DecodeString(decrypt.PlaintextBase64)
+	if err != nil {
+		return err
+	}
+	c[key] = structpb.NewStringValue(string(decodeString))
+	return nil
+}
+
 func (s notifyChannelService) GetNotifyChannels(ctx context.Context, req *pb.GetNotifyChannelsRequest) (*pb.GetNotifyChannelsResponse, error) {
 	if req.PageNo < 1 {
 		req.PageNo = 1
@@ -320,20 +431,10 @@ func (s notifyChannelService) UpdateNotifyChannel(ctx context.Context, req *pb.U
 		if err != nil {
 			return nil, err
 		}
-		encryptSecret, err := s.p.bdl.KMSEncrypt(apistructs.KMSEncryptRequest{
-			EncryptRequest: kmstypes.EncryptRequest{
-				KeyID:           channel.KmsKey,
-				PlaintextBase64: base64.StdEncoding.EncodeToString([]byte(c["need_kms_data"].GetStringValue())),
-			},
-		})
+		needKmsKey, needKmsData, err = s.encryptConfigSecret(channel.KmsKey, c)
 		if err != nil {
 			return nil, err
 		}
-		needKmsKey = c["need_kms_key"].GetStringValue()
-		needKmsData = c["need_kms_data"].GetStringValue()
-		req.Config[needKmsKey] = structpb.NewStringValue(encryptSecret.CiphertextBase64)
-		delete(req.Config, "need_kms_key")
-		delete(req.Config, "need_kms_data")
 		config, err := json.Marshal(req.Config)
 		if err != nil {
 			return nil, err
@@ -365,25 +466,10 @@ func (s notifyChannelService) GetNotifyChannel(ctx context.Context, req *pb.GetN
 	if err != nil {
 		return nil, pkgerrors.NewInternalServerError(err)
 	}
-	cTemp, err := s.ConfigValidate(channel.ChannelProvider, c)
-	if err != nil {
-		return nil, err
-	}
-	decrypt, err := s.p.bdl.KMSDecrypt(apistructs.KMSDecryptRequest{
-		DecryptRequest: kmstypes.DecryptRequest{
-			KeyID:            channel.KmsKey,
-			CiphertextBase64: cTemp["need_kms_data"].GetStringValue(),
-		}})
-	if err != nil {
-		return nil, err
-	}
-	decodeString, err := base64.StdEncoding.DecodeString(decrypt.PlaintextBase64)
+	cTemp, err := s.decryptConfig(channel.ChannelProvider, channel.KmsKey, c)
 	if err != nil {
 		return nil, err
 	}
-	cTemp[cTemp["need_kms_key"].GetStringValue()] = structpb.NewStringValue(string(decodeString))
-	delete(cTemp, "need_kms_key")
-	delete(cTemp, "need_kms_data")
 	bytes, err := json.Marshal(cTemp)
 	if err != nil {
 		return nil, err
@@ -447,19 +533,10 @@ func (s notifyChannelService) GetNotifyChannelEnabled(ctx context.Context, req *
 	if err != nil {
 		return nil, pkgerrors.NewInternalServerError(err)
 	}
-	cTemp, err := s.ConfigValidate(data.ChannelProvider, c)
-	decrypt, err := s.p.bdl.KMSDecrypt(apistructs.KMSDecryptRequest{
-		DecryptRequest: kmstypes.DecryptRequest{
-			KeyID:            data.KmsKey,
-			CiphertextBase64: cTemp["need_kms_data"].GetStringValue(),
-		}})
-	decodeString, err := base64.StdEncoding.DecodeString(decrypt.PlaintextBase64)
+	cTemp, err := s.decryptConfig(data.ChannelProvider, data.KmsKey, c)
 	if err != nil {
 		return nil, err
 	}
-	cTemp[cTemp["need_kms_key"].GetStringValue()] = structpb.NewStringValue(string(decodeString))
-	delete(cTemp, "need_kms_key")
-	delete(cTemp, "need_kms_data")
 	bytes, err := json.Marshal(cTemp)
 	if err != nil {
 		return nil, err
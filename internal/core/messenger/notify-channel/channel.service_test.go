@@ -17,9 +17,11 @@ package notify_channel
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -403,6 +405,9 @@ func Test_notifyChannelService_ConfigValidate(t *testing.T) {
 	}{
 		{"case1", args{channelType: "aliyun_sms", c: map[string]*structpb.Value{"accessKeyId": structpb.NewStringValue("xx"), "accessKeySecret": structpb.NewStringValue("xx"), "signName": structpb.NewStringValue("xx"), "templateCode": structpb.NewStringValue("xx")}}, false},
 		{"case2", args{channelType: "dingtalk", c: map[string]*structpb.Value{"agentId": structpb.NewNumberValue(33), "appKey": structpb.NewStringValue("xx"), "appSecret": structpb.NewStringValue("xx")}}, false},
+		{"case3", args{channelType: "slack", c: map[string]*structpb.Value{"mode": structpb.NewStringValue("webhook"), "webhookUrl": structpb.NewStringValue("https://hooks.slack.com/services/xx")}}, false},
+		{"case4", args{channelType: "feishu", c: map[string]*structpb.Value{"mode": structpb.NewStringValue("bot"), "appId": structpb.NewStringValue("xx")}}, true},
+		{"case5", args{channelType: "wecom", c: map[string]*structpb.Value{"mode": structpb.NewStringValue("bot"), "corpId": structpb.NewStringValue("xx"), "corpSecret": structpb.NewStringValue("xx"), "agentId": structpb.NewNumberValue(1000002), "channelId": structpb.NewStringValue("@all")}}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -787,3 +792,112 @@ func Test_notifyChannelService_CovertToPbNotifyChannel(t *testing.T) {
 		})
 	}
 }
+
+func Test_notifyChannelService_IMChannelRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		config   map[string]*structpb.Value
+		secret   string
+	}{
+		{"slack webhook", "slack", map[string]*structpb.Value{"mode": structpb.NewStringValue("webhook"), "webhookUrl": structpb.NewStringValue("https://hooks.slack.com/services/xx")}, "webhookUrl"},
+		{"feishu webhook", "feishu", map[string]*structpb.Value{"mode": structpb.NewStringValue("webhook"), "webhookUrl": structpb.NewStringValue("https://open.feishu.cn/open-apis/bot/v2/hook/xx")}, "webhookUrl"},
+		{"feishu webhook with secret", "feishu", map[string]*structpb.Value{"mode": structpb.NewStringValue("webhook"), "webhookUrl": structpb.NewStringValue("https://open.feishu.cn/open-apis/bot/v2/hook/xx"), "webhookSecret": structpb.NewStringValue("sign")}, "webhookUrl"},
+		{"slack bot", "slack", map[string]*structpb.Value{"mode": structpb.NewStringValue("bot"), "botToken": structpb.NewStringValue("xoxb-xx"), "channelId": structpb.NewStringValue("C01")}, "botToken"},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	identitySvc := NewMockUserServiceServer(ctrl)
+	identitySvc.EXPECT().GetUser(gomock.Any(), gomock.Any()).AnyTimes().Return(&userpb.GetUserResponse{Data: &userpb.User{ID: "1", Name: "a", Nick: "a"}}, nil)
+
+	var stored *db.NotifyChannel
+	var ncdb *db.NotifyChannelDB
+	defer monkey.PatchInstanceMethod(reflect.TypeOf(ncdb), "GetByName", func(ncdb *db.NotifyChannelDB, name string) (*db.NotifyChannel, error) {
+		return nil, nil
+	}).Unpatch()
+	defer monkey.PatchInstanceMethod(reflect.TypeOf(ncdb), "Create", func(ncdb *db.NotifyChannelDB, notifyChannel *db.NotifyChannel) (*db.NotifyChannel, error) {
+		c := *notifyChannel
+		stored = &c
+		return notifyChannel, nil
+	}).Unpatch()
+	defer monkey.PatchInstanceMethod(reflect.TypeOf(ncdb), "GetById", func(ncdb *db.NotifyChannelDB, id string) (*db.NotifyChannel, error) {
+		c := *stored
+		return &c, nil
+	}).Unpatch()
+	defer monkey.PatchInstanceMethod(reflect.TypeOf(ncdb), "GetByScopeAndType", func(ncdb *db.NotifyChannelDB, scopeId, scopeType, channelType string) (*db.NotifyChannel, error) {
+		c := *stored
+		return &c, nil
+	}).Unpatch()
+	var ncs *notifyChannelService
+	defer monkey.PatchInstanceMethod(reflect.TypeOf(ncs), "CovertToPbNotifyChannel", func(ncs *notifyChannelService, lang i18n.LanguageCodes, channel *db.NotifyChannel, needConfig bool) *pb.NotifyChannel {
+		c := map[string]*structpb.Value{}
+		_ = json.Unmarshal([]byte(channel.Config), &c)
+		return &pb.NotifyChannel{Id: channel.Id, Config: c}
+	}).Unpatch()
+	// 模拟 kms: 密文为 cipher: 前缀加明文
+	var b *bundle.Bundle
+	defer monkey.PatchInstanceMethod(reflect.TypeOf(b), "KMSCreateKey", func(b *bundle.Bundle, req apistructs.KMSCreateKeyRequest) (*kmstypes.CreateKeyResponse, error) {
+		return &kmstypes.CreateKeyResponse{KeyMetadata: kmstypes.KeyMetadata{KeyID: "key"}}, nil
+	}).Unpatch()
+	defer monkey.PatchInstanceMethod(reflect.TypeOf(b), "KMSEncrypt", func(b *bundle.Bundle, req apistructs.KMSEncryptRequest) (*kmstypes.EncryptResponse, error) {
+		return &kmstypes.EncryptResponse{KeyID: req.KeyID, CiphertextBase64: "cipher:" + req.PlaintextBase64}, nil
+	}).Unpatch()
+	defer monkey.PatchInstanceMethod(reflect.TypeOf(b), "KMSDecrypt", func(b *bundle.Bundle, req apistructs.KMSDecryptRequest) (*kmstypes.DecryptResponse, error) {
+		if !strings.HasPrefix(req.CiphertextBase64, "cipher:") {
+			return nil, errors.New("invalid ciphertext")
+		}
+		return &kmstypes.DecryptResponse{PlaintextBase64: strings.TrimPrefix(req.CiphertextBase64, "cipher:")}, nil
+	}).Unpatch()
+	defer monkey.Patch(apis.GetUserID, func(ctx context.Context) string { return "1" }).Unpatch()
+	defer monkey.Patch(apis.GetOrgID, func(ctx context.Context) string { return "1" }).Unpatch()
+	defer monkey.Patch(apis.Language, func(ctx context.Context) i18n.LanguageCodes {
+		return i18n.LanguageCodes{{Code: "zh"}}
+	}).Unpatch()
+
+	s := &notifyChannelService{p: &provider{bdl: bundle.New(), Identity: identitySvc}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := map[string]string{}
+			for k, v := range tt.config {
+				want[k] = v.GetStringValue()
+			}
+			_, err := s.CreateNotifyChannel(context.Background(), &pb.CreateNotifyChannelRequest{
+				Name: "test", Type: "im", ChannelProviderType: tt.provider, Config: tt.config,
+			})
+			if err != nil {
+				t.Fatalf("CreateNotifyChannel() error = %v", err)
+			}
+			storedConfig := map[string]string{}
+			_ = json.Unmarshal([]byte(stored.Config), &storedConfig)
+			for k, v := range want {
+				if k == tt.secret {
+					if storedConfig[k] == v {
+						t.Errorf("secret %s is stored in plaintext", k)
+					}
+				} else if storedConfig[k] != v {
+					t.Errorf("stored %s = %q, want %q", k, storedConfig[k], v)
+				}
+			}
+
+			got, err := s.GetNotifyChannel(context.Background(), &pb.GetNotifyChannelRequest{Id: stored.Id})
+			if err != nil {
+				t.Fatalf("GetNotifyChannel() error = %v", err)
+			}
+			enabled, err := s.GetNotifyChannelEnabled(context.Background(), &pb.GetNotifyChannelEnabledRequest{ScopeId: "1", Type: "im"})
+			if err != nil {
+				t.Fatalf("GetNotifyChannelEnabled() error = %v", err)
+			}
+			for _, config := range []map[string]*structpb.Value{got.Data.Config, enabled.Data.Config} {
+				if len(config) != len(want) {
+					t.Errorf("config = %v, want %v", config, want)
+				}
+				for k, v := range want {
+					if config[k].GetStringValue() != v {
+						t.Errorf("config %s = %q, want %q", k, config[k].GetStringValue(), v)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kind
+
+import (
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/common/errors"
+)
+
+// Feishu webhook 模式为自定义机器人，可选签名校验密钥；bot 模式为企业自建应用
+type Feishu struct {
+	Mode          string `json:"mode"`
+	WebhookURL    string `json:"webhookUrl"`
+	WebhookSecret string `json:"webhookSecret"`
+	AppID         string `json:"appId"`
+	AppSecret     string `json:"appSecret"`
+	ChannelID     string `json:"channelId"`
+}
+
+func (feishu *Feishu) Validate() error {
+	if err := validateMode(feishu.Mode); err != nil {
+		return err
+	}
+	if feishu.Mode == apistructs.NOTIFY_CHANNEL_MODE_WEBHOOK {
+		return validateURL("webhookUrl", feishu.WebhookURL)
+	}
+	if feishu.AppID == "" {
+		return errors.NewMissingParameterError("appId")
+	}
+	if feishu.AppSecret == "" {
+		return errors.NewMissingParameterError("appSecret")
+	}
+	if feishu.ChannelID == "" {
+		return errors.NewMissingParameterError("channelId")
+	}
+	return nil
+}
+
+func (feishu *Feishu) Secret() (string, string) {
+	if feishu.Mode == apistructs.NOTIFY_CHANNEL_MODE_WEBHOOK {
+		return "webhookUrl", feishu.WebhookURL
+	}
+	return "appSecret", feishu.AppSecret
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kind
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/common/errors"
+)
+
+// IM 即时通讯类渠道，支持 incoming webhook 和 bot token 两种模式
+type IM interface {
+	Interface
+	// Secret 返回需要通过 kms 加密保存的配置项及其值，webhook 模式下为 webhook 地址
+	Secret() (key, value string)
+}
+
+func validateMode(mode string) error {
+	switch mode {
+	case apistructs.NOTIFY_CHANNEL_MODE_WEBHOOK, apistructs.NOTIFY_CHANNEL_MODE_BOT:
+		return nil
+	case "":
+		return errors.NewMissingParameterError("mode")
+	default:
+		return fmt.Errorf("invalid mode %q, only support [webhook, bot]", mode)
+	}
+}
+
+func validateURL(name, u string) error {
+	if u == "" {
+		return errors.NewMissingParameterError(name)
+	}
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid %s: %s", name, u)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kind
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIM_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    IM
+		wantErr bool
+	}{
+		{name: "empty mode", kind: &Slack{WebhookURL: "https://hooks.slack.com/services/x"}, wantErr: true},
+		{name: "invalid mode", kind: &Slack{Mode: "app"}, wantErr: true},
+		{name: "slack webhook", kind: &Slack{Mode: "webhook", WebhookURL: "https://hooks.slack.com/services/x"}},
+		{name: "slack bad webhook", kind: &Slack{Mode: "webhook", WebhookURL: "hooks.slack.com"}, wantErr: true},
+		{name: "slack bot without channel", kind: &Slack{Mode: "bot", BotToken: "xoxb"}, wantErr: true},
+		{name: "slack bot", kind: &Slack{Mode: "bot", BotToken: "xoxb", ChannelID: "C1"}},
+		{name: "teams webhook", kind: &Teams{Mode: "webhook", WebhookURL: "https://x.webhook.office.com/webhookb2/x"}},
+		{name: "teams bot without service url", kind: &Teams{Mode: "bot", AppID: "a", AppSecret: "s", ChannelID: "c"}, wantErr: true},
+		{name: "teams bot", kind: &Teams{Mode: "bot", AppID: "a", AppSecret: "s", ServiceURL: "https://smba.trafficmanager.net/apac/", ChannelID: "c"}},
+		{name: "feishu webhook", kind: &Feishu{Mode: "webhook", WebhookURL: "https://open.feishu.cn/open-apis/bot/v2/hook/x"}},
+		{name: "feishu bot without secret", kind: &Feishu{Mode: "bot", AppID: "cli_x", ChannelID: "oc_x"}, wantErr: true},
+		{name: "feishu bot", kind: &Feishu{Mode: "bot", AppID: "cli_x", AppSecret: "s", ChannelID: "oc_x"}},
+		{name: "wecom webhook", kind: &WeCom{Mode: "webhook", WebhookURL: "https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=x"}},
+		{name: "wecom bot without agent", kind: &WeCom{Mode: "bot", CorpID: "ww", CorpSecret: "s", ChannelID: "@all"}, wantErr: true},
+		{name: "wecom bot", kind: &WeCom{Mode: "bot", CorpID: "ww", CorpSecret: "s", AgentId: 1000002, ChannelID: "@all"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.kind.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIM_Secret(t *testing.T) {
+	key, value := (&Slack{Mode: "webhook", WebhookURL: "u"}).Secret()
+	assert.Equal(t, "webhookUrl", key)
+	assert.Equal(t, "u", value)
+	key, value = (&Feishu{Mode: "webhook", WebhookURL: "u", WebhookSecret: "s"}).Secret()
+	assert.Equal(t, "webhookUrl", key)
+	assert.Equal(t, "u", value)
+	key, _ = (&Slack{Mode: "bot"}).Secret()
+	assert.Equal(t, "botToken", key)
+	key, _ = (&Teams{Mode: "bot"}).Secret()
+	assert.Equal(t, "appSecret", key)
+	key, _ = (&WeCom{Mode: "bot"}).Secret()
+	assert.Equal(t, "corpSecret", key)
+}
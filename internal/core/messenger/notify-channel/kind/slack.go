@@ -0,0 +1,50 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kind
+
+import (
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/common/errors"
+)
+
+type Slack struct {
+	Mode       string `json:"mode"`
+	WebhookURL string `json:"webhookUrl"`
+	BotToken   string `json:"botToken"`
+	ChannelID  string `json:"channelId"`
+}
+
+func (slack *Slack) Validate() error {
+	if err := validateMode(slack.Mode); err != nil {
+		return err
+	}
+	if slack.Mode == apistructs.NOTIFY_CHANNEL_MODE_WEBHOOK {
+		return validateURL("webhookUrl", slack.WebhookURL)
+	}
+	if slack.BotToken == "" {
+		return errors.NewMissingParameterError("botToken")
+	}
+	if slack.ChannelID == "" {
+		return errors.NewMissingParameterError("channelId")
+	}
+	return nil
+}
+
+func (slack *Slack) Secret() (string, string) {
+	if slack.Mode == apistructs.NOTIFY_CHANNEL_MODE_WEBHOOK {
+		return "webhookUrl", slack.WebhookURL
+	}
+	return "botToken", slack.BotToken
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kind
+
+import (
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/common/errors"
+)
+
+// Teams webhook 模式使用 incoming webhook(或 workflows) 地址，
+// bot 模式使用 Azure Bot 的 appId/appSecret 通过 Bot Framework 发送到指定会话
+type Teams struct {
+	Mode       string `json:"mode"`
+	WebhookURL string `json:"webhookUrl"`
+	AppID      string `json:"appId"`
+	AppSecret  string `json:"appSecret"`
+	TenantID   string `json:"tenantId"`
+	ServiceURL string `json:"serviceUrl"`
+	ChannelID  string `json:"channelId"`
+}
+
+func (teams *Teams) Validate() error {
+	if err := validateMode(teams.Mode); err != nil {
+		return err
+	}
+	if teams.Mode == apistructs.NOTIFY_CHANNEL_MODE_WEBHOOK {
+		return validateURL("webhookUrl", teams.WebhookURL)
+	}
+	if teams.AppID == "" {
+		return errors.NewMissingParameterError("appId")
+	}
+	if teams.AppSecret == "" {
+		return errors.NewMissingParameterError("appSecret")
+	}
+	if err := validateURL("serviceUrl", teams.ServiceURL); err != nil {
+		return err
+	}
+	if teams.ChannelID == "" {
+		return errors.NewMissingParameterError("channelId")
+	}
+	return nil
+}
+
+func (teams *Teams) Secret() (string, string) {
+	if teams.Mode == apistructs.NOTIFY_CHANNEL_MODE_WEBHOOK {
+		return "webhookUrl", teams.WebhookURL
+	}
+	return "appSecret", teams.AppSecret
+}
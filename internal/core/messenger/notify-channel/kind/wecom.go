@@ -0,0 +1,60 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kind
+
+import (
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/common/errors"
+)
+
+// WeCom webhook 模式为群机器人，bot 模式为企业微信自建应用
+type WeCom struct {
+	Mode       string `json:"mode"`
+	WebhookURL string `json:"webhookUrl"`
+	CorpID     string `json:"corpId"`
+	CorpSecret string `json:"corpSecret"`
+	AgentId    int64  `json:"agentId"`
+	// ChannelID 应用消息的默认接收人，多个用 | 分隔，@all 表示全部成员
+	ChannelID string `json:"channelId"`
+}
+
+func (wecom *WeCom) Validate() error {
+	if err := validateMode(wecom.Mode); err != nil {
+		return err
+	}
+	if wecom.Mode == apistructs.NOTIFY_CHANNEL_MODE_WEBHOOK {
+		return validateURL("webhookUrl", wecom.WebhookURL)
+	}
+	if wecom.CorpID == "" {
+		return errors.NewMissingParameterError("corpId")
+	}
+	if wecom.CorpSecret == "" {
+		return errors.NewMissingParameterError("corpSecret")
+	}
+	if wecom.AgentId == 0 {
+		return errors.NewMissingParameterError("agentId")
+	}
+	if wecom.ChannelID == "" {
+		return errors.NewMissingParameterError("channelId")
+	}
+	return nil
+}
+
+func (wecom *WeCom) Secret() (string, string) {
+	if wecom.Mode == apistructs.NOTIFY_CHANNEL_MODE_WEBHOOK {
+		return "webhookUrl", wecom.WebhookURL
+	}
+	return "corpSecret", wecom.CorpSecret
+}
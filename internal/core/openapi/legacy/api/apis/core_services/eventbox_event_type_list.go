@@ -0,0 +1,35 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core_services
+
+import (
+	"net/http"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/core/openapi/legacy/api/apis"
+)
+
+var EVENTBOX_EVENT_TYPES_LIST = apis.ApiSpec{
+	Path:         "/api/event-types",
+	BackendPath:  "/api/dice/eventbox/event-types",
+	Host:         "erda-server.marathon.l4lb.thisdcos.directory:9095",
+	Scheme:       "http",
+	Method:       http.MethodGet,
+	ResponseType: apistructs.EventStreamTypeListResponse{},
+	CheckLogin:   true,
+	CheckToken:   true,
+	IsOpenAPI:    true,
+	Doc:          "summary: 事件流中的事件类型列表",
+}
@@ -0,0 +1,33 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicehub
+
+import (
+	"net/http"
+
+	"github.com/erda-project/erda/internal/core/openapi/legacy/api/apis"
+)
+
+var RELEASE_CHANGELOG_DOWNLOAD = apis.ApiSpec{
+	Path:        "/api/releases/<releaseId>/changelog",
+	BackendPath: "/api/releases/<releaseId>/changelog",
+	Host:        "erda-server.marathon.l4lb.thisdcos.directory:9095",
+	Scheme:      "http",
+	Method:      http.MethodGet,
+	IsOpenAPI:   true,
+	CheckLogin:  true,
+	CheckToken:  true,
+	Doc:         `下载项目制品 changelog markdown 文件`,
+}
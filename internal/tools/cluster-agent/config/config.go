@@ -26,6 +26,8 @@ type Config struct {
 	RenewDeadline          int    `desc:"renew deadline"`
 	RetryPeriod            int    `desc:"retry period"`
 	ConRetryInterval       int    `desc:"agent connection retry interval"`
+	ConRetryMaxInterval    int    `default:"120" desc:"max agent connection retry interval, retry interval doubles after each failure"`
+	TunnelCount            int    `default:"2" desc:"parallel tunnels connected to cluster manager"`
 	ClusterManagerEndpoint string `desc:"cluster manager endpoint"`
 	ClusterKey             string `desc:"cluster key"`
 	ErdaNamespace          string `desc:"erda namespace"`
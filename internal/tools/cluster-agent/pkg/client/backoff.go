@@ -0,0 +1,62 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	minRetryInterval         = time.Second
+	stableConnectionDuration = time.Minute
+)
+
+// backoff doubles the retry interval after each failure with jitter,
+// so that agents behind a flaky link don't reconnect at the same time.
+type backoff struct {
+	base    time.Duration
+	max     time.Duration
+	current time.Duration
+}
+
+func newBackoff(base, max time.Duration) *backoff {
+	if base < minRetryInterval {
+		base = minRetryInterval
+	}
+	if max < base {
+		max = base
+	}
+	return &backoff{base: base, max: max}
+}
+
+// next returns interval before the next retry
+func (b *backoff) next() time.Duration {
+	switch {
+	case b.current == 0:
+		b.current = b.base
+	case b.current*2 > b.max:
+		b.current = b.max
+	default:
+		b.current *= 2
+	}
+	// +-20% jitter
+	jitter := time.Duration(rand.Int63n(int64(b.current)/5*2+1)) - b.current/5
+	return b.current + jitter
+}
+
+func (b *backoff) reset() {
+	b.current = 0
+}
@@ -0,0 +1,35 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	b := newBackoff(0, 8*time.Second)
+	expects := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second}
+	for i, expect := range expects {
+		interval := b.next()
+		if interval < expect*8/10 || interval > expect*12/10 {
+			t.Errorf("retry %d, interval %s, expect about %s", i, interval, expect)
+		}
+	}
+	b.reset()
+	if interval := b.next(); interval > 1200*time.Millisecond {
+		t.Errorf("interval after reset: %s", interval)
+	}
+}
@@ -60,9 +60,11 @@ type Option func(*Client)
 
 type Client struct {
 	sync.Mutex
-	cfg        *config.Config
-	accessKey  string
-	connected  bool
+	cfg       *config.Config
+	accessKey string
+	// connected is the number of connected tunnels
+	connected int
+	// disconnect is closed to disconnect all tunnels
 	disconnect chan struct{}
 }
 
@@ -82,11 +84,15 @@ func WithConfig(cfg *config.Config) Option {
 	}
 }
 
+// DisConnect disconnects all tunnels, they will reconnect later
 func (c *Client) DisConnect() {
-	if !c.IsConnected() {
+	c.Lock()
+	defer c.Unlock()
+	if c.connected == 0 {
 		return
 	}
-	c.disconnect <- struct{}{}
+	close(c.disconnect)
+	c.disconnect = make(chan struct{})
 }
 
 func (c *Client) Start(ctx context.Context) error {
@@ -126,12 +132,28 @@ func (c *Client) Start(ctx context.Context) error {
 		logrus.Infof("use specified cluster access key: %v", c.cfg.ClusterAccessKey)
 	}
 
+	// parallel tunnels are balanced by cluster manager, and keep the cluster reachable when some are broken
+	for i := 1; i < c.cfg.TunnelCount; i++ {
+		go c.connect(ctx, ep, headers.Clone(), i)
+	}
+	c.connect(ctx, ep, headers, 0)
+	return nil
+}
+
+// connect keeps the index-th tunnel connected until ctx done
+func (c *Client) connect(ctx context.Context, ep string, headers http.Header, index int) {
+	if index > 0 {
+		headers.Set(apistructs.ClusterManagerHeaderKeyTunnelIndex.String(), strconv.Itoa(index))
+	}
+	b := newBackoff(time.Duration(c.cfg.ConRetryInterval)*time.Second, time.Duration(c.cfg.ConRetryMaxInterval)*time.Second)
+
 	for {
 		if c.getAccessKey() == "" {
 			continue
 		}
 
 		headers.Set("Authorization", c.getAccessKey())
+		start := time.Now()
 		_ = remotedialer.ClientConnect(ctx, ep, headers, nil, func(proto, address string) bool {
 			switch proto {
 			case "tcp":
@@ -144,42 +166,48 @@ func (c *Client) Start(ctx context.Context) error {
 			return false
 		}, c.onConnect)
 
+		// the tunnel has been stable for a while, retry quickly
+		if time.Since(start) > stableConnectionDuration {
+			b.reset()
+		}
+		interval := b.next()
+		logrus.Infof("tunnel %d disconnected, reconnect after %s", index, interval)
+
 		select {
 		case <-ctx.Done():
-			return nil
-		case <-time.After(time.Duration(c.cfg.ConRetryInterval) * time.Second):
+			return
+		case <-time.After(interval):
 		}
 	}
 }
 
 // onConnect
 func (c *Client) onConnect(ctx context.Context, _ *remotedialer.Session) error {
+	c.Lock()
+	c.connected++
+	disconnect := c.disconnect
+	c.Unlock()
+
 	defer func() {
-		c.setConnected(false)
+		c.Lock()
+		c.connected--
+		c.Unlock()
 	}()
 
-	c.setConnected(true)
-
 	// Or passThrough cancel() function
 	select {
-	case <-c.disconnect:
+	case <-disconnect:
 		return fmt.Errorf("cluster credential reload")
 	case <-ctx.Done():
 		return nil
 	}
 }
 
-func (c *Client) setConnected(b bool) {
-	c.Lock()
-	defer c.Unlock()
-	c.connected = b
-}
-
+// IsConnected reports whether any tunnel is connected
 func (c *Client) IsConnected() bool {
-	// data race
 	c.Lock()
 	defer c.Unlock()
-	return c.connected
+	return c.connected > 0
 }
 
 func (c *Client) getClusterInfo() (map[string]interface{}, error) {
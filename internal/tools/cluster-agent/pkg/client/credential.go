@@ -107,9 +107,7 @@ func (c *Client) watchClusterCredential(ctx context.Context) error {
 				// change value
 				c.setAccessKey(string(ak))
 				// if connected, reconnect.
-				if c.IsConnected() {
-					c.disconnect <- struct{}{}
-				}
+				c.DisConnect()
 			}
 		case <-ctx.Done():
 			return nil
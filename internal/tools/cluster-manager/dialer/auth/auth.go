@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -79,10 +80,13 @@ func (a *Authorizer) Authorizer(req *http.Request) (string, bool, error) {
 		return "", false, fmt.Errorf("cluster key or auth info is empty")
 	}
 
+	// parallel tunnels of the same client use different session keys
+	tunnelIndex, _ := strconv.Atoi(req.Header.Get(apistructs.ClusterManagerHeaderKeyTunnelIndex.String()))
+
 	if !a.CheckNeedAuth(clusterKey) {
 		// doesn't need auth, skip
 		logrus.Infof("cluster %s in white list, skip auth", clusterKey)
-		return clusterKey, true, nil
+		return apistructs.MakeClusterTunnelKey(clusterKey, tunnelIndex), true, nil
 	}
 
 	logrus.Debugf("get auth info: %s", authInfo)
@@ -107,7 +111,7 @@ func (a *Authorizer) Authorizer(req *http.Request) (string, bool, error) {
 
 	logrus.Infof("auth success, resp info: %+v", akSkResp)
 
-	return clientType.MakeClientKey(clusterKey), true, nil
+	return apistructs.MakeClusterTunnelKey(clientType.MakeClientKey(clusterKey), tunnelIndex), true, nil
 }
 
 // CheckNeedAuth check auth
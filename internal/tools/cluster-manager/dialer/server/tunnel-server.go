@@ -81,7 +81,7 @@ func clusterRegister(ctx context.Context, server *remotedialer.Server, rw http.R
 				logrus.Errorf("register cluster info timeout [%s]", clusterKey)
 				return
 			default:
-				if !server.HasSession(clusterKey) {
+				if !tunnels.hasSession(server, clusterKey) {
 					logrus.Debugf("session not found, try again [%s]", clusterKey)
 					<-time.After(registerCheckInterval)
 					continue
@@ -142,7 +142,11 @@ func clusterRegister(ctx context.Context, server *remotedialer.Server, rw http.R
 		return
 	}
 
-	_, err = etcd.Put(ctx, ClusterManagerETCDKeyPrefix+clusterKey, podIP)
+	// parallel tunnels of the same cluster are registered with different keys
+	tunnelIndex, _ := strconv.Atoi(req.Header.Get(apistructs.ClusterManagerHeaderKeyTunnelIndex.String()))
+	tunnelKey := apistructs.MakeClusterTunnelKey(clusterKey, tunnelIndex)
+
+	_, err = etcd.Put(ctx, ClusterManagerETCDKeyPrefix+tunnelKey, podIP)
 	if err != nil {
 		logrus.Errorf("failed to put clusterKey to etcd, %v", err)
 		return
@@ -207,7 +211,23 @@ func clusterRegister(ctx context.Context, server *remotedialer.Server, rw http.R
 	}
 
 register:
-	server.ServeHTTP(rw, req)
+	tunnels.serve(tunnelKey, func() {
+		server.ServeHTTP(rw, req)
+	})
+	if tunnelIndex > 0 {
+		unregisterTunnel(etcd, tunnelKey, podIP)
+	}
+}
+
+// unregisterTunnel remove the key of disconnected tunnel, unless it has been connected to another instance
+func unregisterTunnel(etcd *clientv3.Client, tunnelKey, podIP string) {
+	key := ClusterManagerETCDKeyPrefix + tunnelKey
+	if _, err := etcd.Txn(context.Background()).
+		If(clientv3.Compare(clientv3.Value(key), "=", podIP)).
+		Then(clientv3.OpDelete(key)).
+		Commit(); err != nil {
+		logrus.Errorf("failed to delete tunnel key %s from etcd, %v", tunnelKey, err)
+	}
 }
 
 func getLocalIP() (string, error) {
@@ -257,6 +277,20 @@ func queryIP(rw http.ResponseWriter, req *http.Request, etcd *clientv3.Client) {
 
 	resp.Succeeded = true
 	resp.IP = string(r.Kvs[0].Value)
+	resp.Tunnels = []string{clusterKey}
+
+	// parallel tunnels connected to the same instance
+	tunnelPrefix := ClusterManagerETCDKeyPrefix + clusterKey + apistructs.ClusterTunnelKeySeparator
+	tr, err := etcd.Get(req.Context(), tunnelPrefix, clientv3.WithPrefix())
+	if err != nil {
+		logrus.Warnf("failed to get tunnels for clusterKey %s from etcd, %v", clusterKey, err)
+	} else {
+		for _, kv := range tr.Kvs {
+			if strings.HasPrefix(string(kv.Key), tunnelPrefix) && string(kv.Value) == resp.IP {
+				resp.Tunnels = append(resp.Tunnels, strings.TrimPrefix(string(kv.Key), ClusterManagerETCDKeyPrefix))
+			}
+		}
+	}
 	writeResp(rw, resp, http.StatusOK)
 }
 
@@ -320,7 +354,7 @@ func checkClusterIsExisted(server *remotedialer.Server, rw http.ResponseWriter,
 	clusterKey := req.URL.Query().Get("clusterKey")
 	clientType := apistructs.ClusterManagerClientType(req.URL.Query().Get("clientType"))
 	clusterKey = clientType.MakeClientKey(clusterKey)
-	isExisted := server.HasSession(clusterKey)
+	isExisted := tunnels.hasSession(server, clusterKey)
 	rw.Write([]byte(strconv.FormatBool(isExisted)))
 }
 
@@ -328,7 +362,7 @@ func getClusterClientData(server *remotedialer.Server, rw http.ResponseWriter, r
 	clusterKey := mux.Vars(req)["clusterKey"]
 	clientType := apistructs.ClusterManagerClientType(mux.Vars(req)["clientType"])
 	clusterKey = clientType.MakeClientKey(clusterKey)
-	isExisted := server.HasSession(clusterKey)
+	isExisted := tunnels.hasSession(server, clusterKey)
 	if !isExisted {
 		remotedialer.DefaultErrorWriter(rw, req, 404, errors.New("cluster not found"))
 		return
@@ -359,6 +393,21 @@ func listClusterClientsByType(server *remotedialer.Server, rw http.ResponseWrite
 	rw.Write(clientDetailBytes)
 }
 
+// tunnelHealth list tunnel states of clusters connected to this instance
+func tunnelHealth(server *remotedialer.Server, rw http.ResponseWriter, req *http.Request) {
+	clusterKey, ok := mux.Vars(req)["clusterKey"]
+	if !ok {
+		writeResp(rw, tunnels.health(server), http.StatusOK)
+		return
+	}
+	health := tunnels.health(server, clusterKey)
+	if len(health) == 0 {
+		remotedialer.DefaultErrorWriter(rw, req, 404, errors.New("cluster not found"))
+		return
+	}
+	writeResp(rw, health[0], http.StatusOK)
+}
+
 func getClusterClient(server *remotedialer.Server, clusterKey string, timeout time.Duration) *http.Client {
 	l.Lock()
 	defer l.Unlock()
@@ -369,7 +418,7 @@ func getClusterClient(server *remotedialer.Server, clusterKey string, timeout ti
 		return client
 	}
 
-	dialer := server.Dialer(clusterKey)
+	dialer := tunnels.dialer(server, clusterKey)
 	client = &http.Client{
 		Transport: &http.Transport{
 			DialContext: dialer,
@@ -410,6 +459,12 @@ func NewDialerRouter(ctx context.Context, clusterSvc clusterpb.ClusterServiceSer
 	router.HandleFunc("/clusterdialer/ip", func(rw http.ResponseWriter, req *http.Request) {
 		queryIP(rw, req, etcd)
 	})
+	router.HandleFunc("/clusterdialer/health", func(rw http.ResponseWriter, req *http.Request) {
+		tunnelHealth(handler, rw, req)
+	})
+	router.HandleFunc("/clusterdialer/health/{clusterKey}", func(rw http.ResponseWriter, req *http.Request) {
+		tunnelHealth(handler, rw, req)
+	})
 	router.HandleFunc("/clusteragent/connect", func(rw http.ResponseWriter,
 		req *http.Request) {
 		clusterRegister(ctx, handler, rw, req, cfg.NeedClusterInfo, etcd, clusterSvc)
@@ -0,0 +1,341 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rancher/remotedialer"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+const labelClusterKey = "cluster_key"
+
+var (
+	tunnelSessionsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cluster_dialer_tunnel_sessions",
+		Help: "Number of connected tunnel sessions of cluster.",
+	}, []string{labelClusterKey})
+	tunnelReconnectsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cluster_dialer_tunnel_reconnects_total",
+		Help: "Number of tunnel reconnections of cluster.",
+	}, []string{labelClusterKey})
+	activeStreamsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cluster_dialer_active_streams",
+		Help: "Number of streams opened in tunnels of cluster.",
+	}, []string{labelClusterKey})
+	streamsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cluster_dialer_streams_total",
+		Help: "Number of streams dialed in tunnels of cluster.",
+	}, []string{labelClusterKey, "result"})
+	streamBytesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cluster_dialer_stream_bytes_total",
+		Help: "Bytes transferred by streams in tunnels of cluster.",
+	}, []string{labelClusterKey, "direction"})
+	dialLatencyHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cluster_dialer_dial_latency_seconds",
+		Help:    "Latency of opening a stream in tunnels of cluster.",
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 12),
+	}, []string{labelClusterKey})
+	streamDurationHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cluster_dialer_stream_duration_seconds",
+		Help:    "Lifetime of streams in tunnels of cluster.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 4, 10),
+	}, []string{labelClusterKey})
+)
+
+func init() {
+	prometheus.MustRegister(tunnelSessionsGauge, tunnelReconnectsCounter, activeStreamsGauge,
+		streamsCounter, streamBytesCounter, dialLatencyHistogram, streamDurationHistogram)
+}
+
+// sessionServer is the part of remotedialer server used by tunnel registry
+type sessionServer interface {
+	HasSession(clientKey string) bool
+	Dialer(clientKey string) remotedialer.Dialer
+}
+
+// tunnelRegistry records parallel tunnels of clusters connected to this instance,
+// dials streams over the least loaded tunnel and keeps statistics of each tunnel.
+type tunnelRegistry struct {
+	sync.Mutex
+	clusters map[string]map[int]*tunnelStats
+	rr       uint64
+}
+
+type tunnelStats struct {
+	index int
+	key   string
+
+	// guarded by registry lock
+	sessions       int
+	connectedAt    time.Time
+	disconnectedAt time.Time
+	reconnects     int64
+
+	activeStreams int64
+	totalStreams  int64
+	failedStreams int64
+	bytesReceived int64
+	bytesSent     int64
+	dialLatency   int64
+}
+
+var tunnels = newTunnelRegistry()
+
+func newTunnelRegistry() *tunnelRegistry {
+	return &tunnelRegistry{clusters: make(map[string]map[int]*tunnelStats)}
+}
+
+// parseTunnelKey split tunnel session key to client key and tunnel index
+func parseTunnelKey(key string) (string, int) {
+	i := strings.LastIndex(key, apistructs.ClusterTunnelKeySeparator)
+	if i < 0 {
+		return key, 0
+	}
+	index, err := strconv.Atoi(key[i+len(apistructs.ClusterTunnelKeySeparator):])
+	if err != nil || index <= 0 {
+		return key, 0
+	}
+	return key[:i], index
+}
+
+func (r *tunnelRegistry) get(clientKey string, index int) *tunnelStats {
+	ts, ok := r.clusters[clientKey]
+	if !ok {
+		ts = make(map[int]*tunnelStats)
+		r.clusters[clientKey] = ts
+	}
+	t, ok := ts[index]
+	if !ok {
+		t = &tunnelStats{index: index, key: apistructs.MakeClusterTunnelKey(clientKey, index)}
+		ts[index] = t
+	}
+	return t
+}
+
+// serve tracks the tunnel during serving, serve blocks until the tunnel is disconnected
+func (r *tunnelRegistry) serve(tunnelKey string, serve func()) {
+	clientKey, index := parseTunnelKey(tunnelKey)
+
+	r.Lock()
+	t := r.get(clientKey, index)
+	if t.sessions == 0 {
+		if !t.connectedAt.IsZero() {
+			t.reconnects++
+			tunnelReconnectsCounter.WithLabelValues(clientKey).Inc()
+		}
+		t.connectedAt = time.Now()
+	}
+	t.sessions++
+	r.Unlock()
+	tunnelSessionsGauge.WithLabelValues(clientKey).Inc()
+
+	defer func() {
+		r.Lock()
+		t.sessions--
+		if t.sessions == 0 {
+			t.disconnectedAt = time.Now()
+		}
+		r.Unlock()
+		tunnelSessionsGauge.WithLabelValues(clientKey).Dec()
+	}()
+
+	serve()
+}
+
+// candidates returns connected tunnels of client ordered by active streams
+func (r *tunnelRegistry) candidates(server sessionServer, clientKey string) []*tunnelStats {
+	r.Lock()
+	all := make([]*tunnelStats, 0, len(r.clusters[clientKey])+1)
+	for _, t := range r.clusters[clientKey] {
+		all = append(all, t)
+	}
+	_, tracked := r.clusters[clientKey][0]
+	r.rr++
+	rr := r.rr
+	r.Unlock()
+
+	// the first tunnel may be connected without tracking, e.g. connected before registry created
+	if !tracked && server.HasSession(clientKey) {
+		r.Lock()
+		all = append(all, r.get(clientKey, 0))
+		r.Unlock()
+	}
+
+	result := make([]*tunnelStats, 0, len(all))
+	for _, t := range all {
+		if server.HasSession(t.key) {
+			result = append(result, t)
+		}
+	}
+	// rotate before stable sort, so tunnels with same load are used in turn
+	if len(result) > 1 {
+		sort.Slice(result, func(i, j int) bool { return result[i].index < result[j].index })
+		offset := int(rr % uint64(len(result)))
+		result = append(result[offset:], result[:offset]...)
+		sort.SliceStable(result, func(i, j int) bool {
+			return atomic.LoadInt64(&result[i].activeStreams) < atomic.LoadInt64(&result[j].activeStreams)
+		})
+	}
+	return result
+}
+
+// hasSession reports whether any tunnel of client is connected
+func (r *tunnelRegistry) hasSession(server sessionServer, clientKey string) bool {
+	return len(r.candidates(server, clientKey)) > 0
+}
+
+// dialer returns dialer which opens streams over the least loaded tunnel of client,
+// and fails over to other tunnels when the dial fails.
+func (r *tunnelRegistry) dialer(server sessionServer, clientKey string) remotedialer.Dialer {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		candidates := r.candidates(server, clientKey)
+		if len(candidates) == 0 {
+			streamsCounter.WithLabelValues(clientKey, "no_tunnel").Inc()
+			return nil, errors.Errorf("no tunnel connected for cluster %s", clientKey)
+		}
+		var lastErr error
+		for _, t := range candidates {
+			start := time.Now()
+			conn, err := server.Dialer(t.key)(ctx, network, address)
+			latency := time.Since(start)
+			atomic.AddInt64(&t.totalStreams, 1)
+			if err != nil {
+				atomic.AddInt64(&t.failedStreams, 1)
+				streamsCounter.WithLabelValues(clientKey, "failed").Inc()
+				logrus.Warnf("dial %s over tunnel %s failed: %v", address, t.key, err)
+				lastErr = err
+				if ctx.Err() != nil {
+					break
+				}
+				continue
+			}
+			atomic.AddInt64(&t.dialLatency, int64(latency))
+			streamsCounter.WithLabelValues(clientKey, "success").Inc()
+			dialLatencyHistogram.WithLabelValues(clientKey).Observe(latency.Seconds())
+			return newMeteredConn(conn, clientKey, t), nil
+		}
+		return nil, lastErr
+	}
+}
+
+// health returns tunnel states of clusters
+func (r *tunnelRegistry) health(server sessionServer, clientKeys ...string) []apistructs.ClusterTunnelHealth {
+	r.Lock()
+	defer r.Unlock()
+
+	if len(clientKeys) == 0 {
+		for k := range r.clusters {
+			clientKeys = append(clientKeys, k)
+		}
+		sort.Strings(clientKeys)
+	}
+	result := make([]apistructs.ClusterTunnelHealth, 0, len(clientKeys))
+	for _, clientKey := range clientKeys {
+		ts, ok := r.clusters[clientKey]
+		if !ok {
+			continue
+		}
+		h := apistructs.ClusterTunnelHealth{ClusterKey: clientKey}
+		for _, t := range ts {
+			state := t.state(server)
+			h.Connected = h.Connected || state.Connected
+			h.Tunnels = append(h.Tunnels, state)
+		}
+		sort.Slice(h.Tunnels, func(i, j int) bool { return h.Tunnels[i].Index < h.Tunnels[j].Index })
+		result = append(result, h)
+	}
+	return result
+}
+
+func (t *tunnelStats) state(server sessionServer) apistructs.ClusterTunnelState {
+	state := apistructs.ClusterTunnelState{
+		Index:         t.index,
+		Key:           t.key,
+		Connected:     server.HasSession(t.key),
+		Reconnects:    t.reconnects,
+		ActiveStreams: atomic.LoadInt64(&t.activeStreams),
+		TotalStreams:  atomic.LoadInt64(&t.totalStreams),
+		FailedStreams: atomic.LoadInt64(&t.failedStreams),
+		BytesReceived: atomic.LoadInt64(&t.bytesReceived),
+		BytesSent:     atomic.LoadInt64(&t.bytesSent),
+	}
+	if !t.connectedAt.IsZero() {
+		connectedAt := t.connectedAt
+		state.ConnectedAt = &connectedAt
+	}
+	if !t.disconnectedAt.IsZero() {
+		disconnectedAt := t.disconnectedAt
+		state.DisconnectedAt = &disconnectedAt
+	}
+	if succeeded := state.TotalStreams - state.FailedStreams; succeeded > 0 {
+		state.AvgDialLatency = float64(atomic.LoadInt64(&t.dialLatency)) / float64(succeeded) / float64(time.Millisecond)
+	}
+	return state
+}
+
+// meteredConn counts bytes and lifetime of a stream
+type meteredConn struct {
+	net.Conn
+	clientKey string
+	tunnel    *tunnelStats
+	start     time.Time
+	once      sync.Once
+}
+
+func newMeteredConn(conn net.Conn, clientKey string, t *tunnelStats) *meteredConn {
+	atomic.AddInt64(&t.activeStreams, 1)
+	activeStreamsGauge.WithLabelValues(clientKey).Inc()
+	return &meteredConn{Conn: conn, clientKey: clientKey, tunnel: t, start: time.Now()}
+}
+
+func (c *meteredConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		atomic.AddInt64(&c.tunnel.bytesReceived, int64(n))
+		streamBytesCounter.WithLabelValues(c.clientKey, "received").Add(float64(n))
+	}
+	return n, err
+}
+
+func (c *meteredConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		atomic.AddInt64(&c.tunnel.bytesSent, int64(n))
+		streamBytesCounter.WithLabelValues(c.clientKey, "sent").Add(float64(n))
+	}
+	return n, err
+}
+
+func (c *meteredConn) Close() error {
+	c.once.Do(func() {
+		atomic.AddInt64(&c.tunnel.activeStreams, -1)
+		activeStreamsGauge.WithLabelValues(c.clientKey).Dec()
+		streamDurationHistogram.WithLabelValues(c.clientKey).Observe(time.Since(c.start).Seconds())
+	})
+	return c.Conn.Close()
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/rancher/remotedialer"
+)
+
+type fakeSessionServer struct {
+	sync.Mutex
+	sessions map[string]bool
+	failed   map[string]bool
+	dialed   []string
+}
+
+func (f *fakeSessionServer) HasSession(clientKey string) bool {
+	f.Lock()
+	defer f.Unlock()
+	return f.sessions[clientKey]
+}
+
+func (f *fakeSessionServer) Dialer(clientKey string) remotedialer.Dialer {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		f.Lock()
+		defer f.Unlock()
+		f.dialed = append(f.dialed, clientKey)
+		if f.failed[clientKey] {
+			return nil, fmt.Errorf("tunnel %s broken", clientKey)
+		}
+		c1, c2 := net.Pipe()
+		go func() {
+			buf := make([]byte, 64)
+			n, _ := c2.Read(buf)
+			c2.Write(buf[:n])
+			c2.Close()
+		}()
+		return c1, nil
+	}
+}
+
+func TestParseTunnelKey(t *testing.T) {
+	tests := []struct {
+		key       string
+		clientKey string
+		index     int
+	}{
+		{"test", "test", 0},
+		{"test-tunnel-2", "test", 2},
+		{"test-client-type-pipeline-tunnel-1", "test-client-type-pipeline", 1},
+		{"test-tunnel-x", "test-tunnel-x", 0},
+		{"test-tunnel-0", "test-tunnel-0", 0},
+	}
+	for _, tt := range tests {
+		clientKey, index := parseTunnelKey(tt.key)
+		if clientKey != tt.clientKey || index != tt.index {
+			t.Errorf("parseTunnelKey(%s) = %s, %d, want %s, %d", tt.key, clientKey, index, tt.clientKey, tt.index)
+		}
+	}
+}
+
+func TestTunnelRegistry(t *testing.T) {
+	r := newTunnelRegistry()
+	server := &fakeSessionServer{
+		sessions: map[string]bool{},
+		failed:   map[string]bool{},
+	}
+
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, key := range []string{"c1", "c1-tunnel-1"} {
+		server.sessions[key] = true
+		wg.Add(1)
+		serving := make(chan struct{})
+		go r.serve(key, func() {
+			close(serving)
+			<-release
+			wg.Done()
+		})
+		<-serving
+	}
+
+	if !r.hasSession(server, "c1") || r.hasSession(server, "c2") {
+		t.Fatal("unexpected session state")
+	}
+
+	// streams are balanced over tunnels
+	dial := r.dialer(server, "c1")
+	conn1, err := dial(context.Background(), "tcp", "svc:80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn2, err := dial(context.Background(), "tcp", "svc:80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.dialed[0] == server.dialed[1] {
+		t.Errorf("streams are not balanced: %v", server.dialed)
+	}
+
+	if _, err = conn1.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 64)
+	if n, _ := conn1.Read(buf); string(buf[:n]) != "hello" {
+		t.Errorf("read %q", buf[:n])
+	}
+	conn1.Close()
+	conn1.Close()
+
+	// the least loaded tunnel is broken, failover to the other one
+	server.failed[server.dialed[0]] = true
+	conn3, err := dial(context.Background(), "tcp", "svc:80")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	health := r.health(server)
+	if len(health) != 1 || !health[0].Connected || len(health[0].Tunnels) != 2 {
+		t.Fatalf("unexpected health: %+v", health)
+	}
+	var total, failed, active, sent, received int64
+	for _, ts := range health[0].Tunnels {
+		total += ts.TotalStreams
+		failed += ts.FailedStreams
+		active += ts.ActiveStreams
+		sent += ts.BytesSent
+		received += ts.BytesReceived
+	}
+	if total != 4 || failed != 1 || active != 2 || sent != 5 || received != 5 {
+		t.Errorf("unexpected statistics, total: %d, failed: %d, active: %d, sent: %d, received: %d",
+			total, failed, active, sent, received)
+	}
+	conn2.Close()
+	conn3.Close()
+
+	// all tunnels disconnected
+	server.sessions["c1"], server.sessions["c1-tunnel-1"] = false, false
+	close(release)
+	wg.Wait()
+	if _, err = dial(context.Background(), "tcp", "svc:80"); err == nil {
+		t.Error("dial should fail without tunnels")
+	}
+
+	// reconnect of the same tunnel is recorded
+	server.sessions["c1"] = true
+	r.serve("c1", func() {})
+	health = r.health(server, "c1")
+	if health[0].Tunnels[0].Reconnects != 1 || health[0].Tunnels[0].DisconnectedAt == nil {
+		t.Errorf("unexpected tunnel state: %+v", health[0].Tunnels[0])
+	}
+	if len(r.health(server, "c2")) != 0 {
+		t.Error("unknown cluster should not in health")
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/erda-project/erda/bundle"
+	"github.com/erda-project/erda/internal/apps/cmp/dbclient"
+	"github.com/erda-project/erda/internal/tools/cluster-ops/upgrade"
+	"github.com/erda-project/erda/pkg/database/dbengine"
+	kc "github.com/erda-project/erda/pkg/k8sclient/config"
+)
+
+const ActionUpgrade = "UPGRADE"
+
+// Upgrade upgrades kubernetes of target cluster, progress is saved into the ops record
+func (c *Client) Upgrade(ctx context.Context) error {
+	if c.config.UpgradeVersion == "" || c.config.UpgradeProvider == "" || c.config.RecordID == 0 {
+		return fmt.Errorf("UPGRADE_K8S_VERSION, UPGRADE_PROVIDER and OPS_RECORD_ID are required")
+	}
+	providerConfig := make(map[string]string)
+	if c.config.UpgradeProviderConfig != "" {
+		if err := json.Unmarshal([]byte(c.config.UpgradeProviderConfig), &providerConfig); err != nil {
+			return fmt.Errorf("invalid upgrade provider config, %v", err)
+		}
+	}
+	provider, err := upgrade.NewProvider(c.config.UpgradeProvider, providerConfig)
+	if err != nil {
+		return err
+	}
+
+	rc, err := c.restConfig()
+	if err != nil {
+		return err
+	}
+	cs, err := kubernetes.NewForConfig(rc)
+	if err != nil {
+		return fmt.Errorf("create clientSet error: %v", err)
+	}
+
+	db, err := dbengine.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open database, %v", err)
+	}
+	defer db.Close()
+	store := &recordStore{db: dbclient.Open(db), recordID: c.config.RecordID}
+
+	logrus.Infof("start to upgrade cluster %s to %s by provider %s", c.config.TargetCluster,
+		c.config.UpgradeVersion, c.config.UpgradeProvider)
+	return upgrade.New(provider, cs, store, c.config.UpgradeVersion,
+		upgrade.WithMaxSurge(c.config.UpgradeMaxSurge),
+		upgrade.WithDrainTimeout(c.config.UpgradeDrainTimeout),
+	).Run(ctx)
+}
+
+func (c *Client) restConfig() (*rest.Config, error) {
+	if strings.ToUpper(c.config.InstallMode) != InstallModeRemote {
+		return rest.InClusterConfig()
+	}
+	b := bundle.New(bundle.WithClusterManager())
+	cluster, err := b.GetCluster(c.config.TargetCluster)
+	if err != nil {
+		return nil, err
+	}
+	return kc.ParseManageConfig(c.config.TargetCluster, cluster.ManageConfig)
+}
+
+// recordStore saves upgrade progress as the detail of an ops record
+type recordStore struct {
+	db       *dbclient.DBClient
+	recordID uint64
+}
+
+func (s *recordStore) record() (*dbclient.Record, *upgrade.Progress, error) {
+	records, err := s.db.RecordsReader().ByIDs(strconv.FormatUint(s.recordID, 10)).Do()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("record %d not found", s.recordID)
+	}
+	record := records[0]
+	if record.Detail == "" {
+		return &record, nil, nil
+	}
+	var p upgrade.Progress
+	if err := json.Unmarshal([]byte(record.Detail), &p); err != nil {
+		return nil, nil, fmt.Errorf("invalid upgrade progress of record %d, %v", s.recordID, err)
+	}
+	return &record, &p, nil
+}
+
+func (s *recordStore) Load(ctx context.Context) (*upgrade.Progress, error) {
+	_, p, err := s.record()
+	return p, err
+}
+
+func (s *recordStore) Save(ctx context.Context, p *upgrade.Progress) error {
+	record, saved, err := s.record()
+	if err != nil {
+		return err
+	}
+	// pause request is only changed by users
+	progress := *p
+	progress.PauseRequested = saved != nil && saved.PauseRequested
+	detail, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	record.Detail = string(detail)
+	switch p.Status {
+	case upgrade.StatusSuccess:
+		record.Status = dbclient.StatusTypeSuccess
+	case upgrade.StatusFailed:
+		record.Status = dbclient.StatusTypeFailed
+	default:
+		record.Status = dbclient.StatusTypeProcessing
+	}
+	return s.db.RecordsWriter().Update(*record)
+}
+
+func (s *recordStore) PauseRequested(ctx context.Context) (bool, error) {
+	_, p, err := s.record()
+	if err != nil || p == nil {
+		return false, err
+	}
+	return p.PauseRequested, nil
+}
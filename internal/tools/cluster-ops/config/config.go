@@ -14,6 +14,8 @@
 
 package config
 
+import "time"
+
 type Config struct {
 	Debug         bool   `env:"DEBUG" default:"false" desc:"enable debug logging"`
 	RepoURL       string `env:"HELM_REPO_URL" desc:"helm repo url"`
@@ -25,6 +27,16 @@ type Config struct {
 	InstallMode   string `env:"INSTALL_MODE" default:"local" desc:"install mode, remote or local"`
 	TargetCluster string `env:"TARGET_CLUSTER" desc:"special when CREDENTIAL_FROM=CLUSTER_MANAGER"`
 	NodeLabels    string `env:"NODE_LABELS" desc:"node labels after install erda"`
+
+	// Action is install or upgrade
+	Action string `env:"CLUSTER_OPS_ACTION" default:"install" desc:"cluster ops action, install or upgrade"`
+	// kubernetes upgrade
+	RecordID              uint64        `env:"OPS_RECORD_ID" desc:"ops record id to save upgrade progress"`
+	UpgradeVersion        string        `env:"UPGRADE_K8S_VERSION" desc:"target kubernetes version"`
+	UpgradeProvider       string        `env:"UPGRADE_PROVIDER" desc:"upgrade provider of control plane and node pools"`
+	UpgradeProviderConfig string        `env:"UPGRADE_PROVIDER_CONFIG" desc:"upgrade provider config in json"`
+	UpgradeMaxSurge       int           `env:"UPGRADE_MAX_SURGE" default:"1" desc:"nodes replaced at the same time in a node pool"`
+	UpgradeDrainTimeout   time.Duration `env:"UPGRADE_DRAIN_TIMEOUT" default:"10m" desc:"max time to drain a node"`
 	// HELM_NAMESPACE: helm deploy namespace
 	// HELM_REPO_URL: helm repo address
 	// HELM_REPOSITORY_CONFIG: helm repository store path
@@ -17,6 +17,7 @@ package cluster_ops
 import (
 	"context"
 	"os"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 
@@ -40,6 +41,9 @@ func (p *provider) Init(ctx servicehub.Context) error {
 
 func (p *provider) Run(ctx context.Context) error {
 	c := client.New(client.WithConfig(p.Cfg))
+	if strings.ToUpper(p.Cfg.Action) == client.ActionUpgrade {
+		return c.Upgrade(ctx)
+	}
 	return c.Execute()
 }
 
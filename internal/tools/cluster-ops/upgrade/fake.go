@@ -0,0 +1,133 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+const FakeProviderName = "fake"
+
+func init() {
+	Register(FakeProviderName, func(config map[string]string) (Provider, error) {
+		return NewFakeProvider(config["version"]), nil
+	})
+}
+
+// FakeProvider is an in-memory provider for tests and dry runs
+type FakeProvider struct {
+	mu      sync.Mutex
+	version string
+	pools   map[string]*NodePool
+	order   []string
+	seq     int
+
+	// OnSurge is called with the new node name when a node is added, e.g. to create the node in a fake clientset
+	OnSurge func(pool, node string) error
+	// Err makes the named operation fail, key is one of UpgradeControlPlane, SurgeNode and DeleteNode
+	Err map[string]error
+	// Operations records all mutating operations in order
+	Operations []string
+}
+
+// NewFakeProvider creates a FakeProvider with control plane version and node pools
+func NewFakeProvider(version string, pools ...NodePool) *FakeProvider {
+	f := &FakeProvider{version: version, pools: make(map[string]*NodePool), Err: make(map[string]error)}
+	for i := range pools {
+		pool := pools[i]
+		pool.Nodes = append([]string(nil), pool.Nodes...)
+		f.pools[pool.Name] = &pool
+		f.order = append(f.order, pool.Name)
+	}
+	return f
+}
+
+func (f *FakeProvider) ControlPlaneVersion(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.version, nil
+}
+
+func (f *FakeProvider) UpgradeControlPlane(ctx context.Context, version string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.Err["UpgradeControlPlane"]; err != nil {
+		return err
+	}
+	f.Operations = append(f.Operations, "upgrade-control-plane "+version)
+	f.version = version
+	return nil
+}
+
+func (f *FakeProvider) ListNodePools(ctx context.Context) ([]NodePool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var pools []NodePool
+	for _, name := range f.order {
+		pool := *f.pools[name]
+		pool.Nodes = append([]string(nil), pool.Nodes...)
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+func (f *FakeProvider) SurgeNode(ctx context.Context, pool, version string) (string, error) {
+	f.mu.Lock()
+	if err := f.Err["SurgeNode"]; err != nil {
+		f.mu.Unlock()
+		return "", err
+	}
+	p, ok := f.pools[pool]
+	if !ok {
+		f.mu.Unlock()
+		return "", fmt.Errorf("node pool %s not found", pool)
+	}
+	f.seq++
+	node := fmt.Sprintf("%s-%s-%d", pool, version, f.seq)
+	p.Nodes = append(p.Nodes, node)
+	p.Version = version
+	f.Operations = append(f.Operations, "surge "+node)
+	onSurge := f.OnSurge
+	f.mu.Unlock()
+
+	if onSurge != nil {
+		if err := onSurge(pool, node); err != nil {
+			return "", err
+		}
+	}
+	return node, nil
+}
+
+func (f *FakeProvider) DeleteNode(ctx context.Context, pool, node string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.Err["DeleteNode"]; err != nil {
+		return err
+	}
+	p, ok := f.pools[pool]
+	if !ok {
+		return fmt.Errorf("node pool %s not found", pool)
+	}
+	for i, n := range p.Nodes {
+		if n == node {
+			p.Nodes = append(p.Nodes[:i], p.Nodes[i+1:]...)
+			f.Operations = append(f.Operations, "delete "+node)
+			return nil
+		}
+	}
+	return fmt.Errorf("node %s not found in pool %s", node, pool)
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Stage is a stage of the upgrade workflow, stages are executed in the order of Stages
+type Stage string
+
+const (
+	StagePreflight    Stage = "preflight"
+	StageControlPlane Stage = "controlPlane"
+	StageNodePools    Stage = "nodePools"
+	StagePostflight   Stage = "postflight"
+	StageDone         Stage = "done"
+)
+
+// Stages are all stages in execution order
+var Stages = []Stage{StagePreflight, StageControlPlane, StageNodePools, StagePostflight, StageDone}
+
+// Status is the status of an upgrade
+type Status string
+
+const (
+	StatusRunning Status = "running"
+	StatusPaused  Status = "paused"
+	StatusSuccess Status = "success"
+	StatusFailed  Status = "failed"
+)
+
+const maxEvents = 200
+
+// Progress is the persisted state of an upgrade, it's used to show progress and to resume an interrupted upgrade
+type Progress struct {
+	TargetVersion string             `json:"targetVersion"`
+	FromVersion   string             `json:"fromVersion"`
+	Stage         Stage              `json:"stage"`
+	Status        Status             `json:"status"`
+	NodePools     []NodePoolProgress `json:"nodePools"`
+	Events        []Event            `json:"events"`
+	// PauseRequested is set by user to pause the upgrade before next step, the upgrader never changes it
+	PauseRequested bool `json:"pauseRequested"`
+}
+
+// NodePoolProgress is the progress of replacing nodes in a node pool
+type NodePoolProgress struct {
+	Name string `json:"name"`
+	// Total is the number of old nodes to replace
+	Total int `json:"total"`
+	// Surged are the new nodes added by upgrade
+	Surged []string `json:"surged"`
+	// Replaced are the old nodes which have been drained and deleted
+	Replaced []string `json:"replaced"`
+}
+
+// Event is a message of upgrade progress
+type Event struct {
+	Time    time.Time `json:"time"`
+	Stage   Stage     `json:"stage"`
+	Message string    `json:"message"`
+}
+
+// ProgressStore persists upgrade progress
+type ProgressStore interface {
+	// Load returns the saved progress, nil if the upgrade has not started
+	Load(ctx context.Context) (*Progress, error)
+	// Save persists progress, PauseRequested must not be overwritten
+	Save(ctx context.Context, p *Progress) error
+	// PauseRequested reports whether user requests to pause the upgrade
+	PauseRequested(ctx context.Context) (bool, error)
+}
+
+func (p *Progress) addEvent(format string, args ...interface{}) {
+	p.Events = append(p.Events, Event{Time: time.Now(), Stage: p.Stage, Message: fmt.Sprintf(format, args...)})
+	if len(p.Events) > maxEvents {
+		p.Events = p.Events[len(p.Events)-maxEvents:]
+	}
+}
+
+func (p *Progress) nodePool(name string) *NodePoolProgress {
+	for i := range p.NodePools {
+		if p.NodePools[i].Name == name {
+			return &p.NodePools[i]
+		}
+	}
+	return nil
+}
+
+func stageIndex(stage Stage) int {
+	for i, s := range Stages {
+		if s == stage {
+			return i
+		}
+	}
+	return 0
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// NodePool is a group of nodes with the same configuration
+type NodePool struct {
+	Name    string   `json:"name"`
+	Version string   `json:"version"`
+	Nodes   []string `json:"nodes"`
+}
+
+// Provider upgrades the kubernetes control plane and manages node pools of a cluster.
+// Implementations are cloud vendor specific, e.g. aliyun ACK or kubeadm managed clusters.
+type Provider interface {
+	// ControlPlaneVersion returns current kubernetes version of control plane
+	ControlPlaneVersion(ctx context.Context) (string, error)
+	// UpgradeControlPlane upgrades control plane to version and returns when it finished
+	UpgradeControlPlane(ctx context.Context, version string) error
+	// ListNodePools returns all node pools of the cluster
+	ListNodePools(ctx context.Context) ([]NodePool, error)
+	// SurgeNode adds a new node of version into pool and returns its node name after it joined the cluster
+	SurgeNode(ctx context.Context, pool, version string) (string, error)
+	// DeleteNode removes the node from pool and releases it
+	DeleteNode(ctx context.Context, pool, node string) error
+}
+
+// Creator creates a provider with config
+type Creator func(config map[string]string) (Provider, error)
+
+var (
+	lock      sync.RWMutex
+	providers = map[string]Creator{}
+)
+
+// Register registers a provider creator with name, it panics if the name is registered twice
+func Register(name string, creator Creator) {
+	lock.Lock()
+	defer lock.Unlock()
+	if _, ok := providers[name]; ok {
+		panic(fmt.Sprintf("upgrade provider %s is already registered", name))
+	}
+	providers[name] = creator
+}
+
+// NewProvider creates the provider registered with name
+func NewProvider(name string, config map[string]string) (Provider, error) {
+	lock.RLock()
+	creator, ok := providers[name]
+	lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("upgrade provider %s not found, supported: %v", name, Providers())
+	}
+	return creator(config)
+}
+
+// Providers returns names of all registered providers
+func Providers() []string {
+	lock.RLock()
+	defer lock.RUnlock()
+	var names []string
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
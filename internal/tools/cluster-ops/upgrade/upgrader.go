@@ -0,0 +1,485 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/erda-project/erda/pkg/k8s/node/drain"
+)
+
+const (
+	defaultMaxSurge         = 1
+	defaultDrainTimeout     = 10 * time.Minute
+	defaultNodeReadyTimeout = 10 * time.Minute
+	defaultPollInterval     = 10 * time.Second
+)
+
+// Upgrader performs a staged kubernetes upgrade:
+// pre-flight checks, control plane upgrade, node pool surge/drain/replace and post-flight checks.
+// Progress is saved after every step, so an interrupted upgrade continues from where it stopped,
+// and users can pause it between steps.
+type Upgrader struct {
+	provider Provider
+	client   kubernetes.Interface
+	store    ProgressStore
+
+	targetVersion    string
+	maxSurge         int
+	drainTimeout     time.Duration
+	nodeReadyTimeout time.Duration
+	pollInterval     time.Duration
+}
+
+// Option configures an Upgrader
+type Option func(*Upgrader)
+
+// WithMaxSurge sets the number of nodes replaced at the same time in a node pool
+func WithMaxSurge(n int) Option {
+	return func(u *Upgrader) {
+		if n > 0 {
+			u.maxSurge = n
+		}
+	}
+}
+
+// WithDrainTimeout sets the max time to drain one node
+func WithDrainTimeout(d time.Duration) Option {
+	return func(u *Upgrader) {
+		if d > 0 {
+			u.drainTimeout = d
+		}
+	}
+}
+
+// WithNodeReadyTimeout sets the max time to wait a new node ready
+func WithNodeReadyTimeout(d time.Duration) Option {
+	return func(u *Upgrader) {
+		if d > 0 {
+			u.nodeReadyTimeout = d
+		}
+	}
+}
+
+// WithPollInterval sets the interval to poll node status and pause requests
+func WithPollInterval(d time.Duration) Option {
+	return func(u *Upgrader) {
+		if d > 0 {
+			u.pollInterval = d
+		}
+	}
+}
+
+// New creates an Upgrader which upgrades the cluster to targetVersion
+func New(provider Provider, client kubernetes.Interface, store ProgressStore, targetVersion string, options ...Option) *Upgrader {
+	u := &Upgrader{
+		provider:         provider,
+		client:           client,
+		store:            store,
+		targetVersion:    targetVersion,
+		maxSurge:         defaultMaxSurge,
+		drainTimeout:     defaultDrainTimeout,
+		nodeReadyTimeout: defaultNodeReadyTimeout,
+		pollInterval:     defaultPollInterval,
+	}
+	for _, op := range options {
+		op(u)
+	}
+	return u
+}
+
+// Run runs the upgrade until it's done, failed or ctx is canceled
+func (u *Upgrader) Run(ctx context.Context) (err error) {
+	if _, err := parseVersion(u.targetVersion); err != nil {
+		return err
+	}
+	p, err := u.store.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load upgrade progress, %v", err)
+	}
+	if p == nil || p.Stage == "" {
+		p = &Progress{TargetVersion: u.targetVersion, Stage: StagePreflight}
+	}
+	if p.TargetVersion != u.targetVersion {
+		return fmt.Errorf("another upgrade to %s is in progress", p.TargetVersion)
+	}
+	if p.Stage == StageDone {
+		return nil
+	}
+	p.Status = StatusRunning
+	p.addEvent("upgrade to %s started from stage %s", u.targetVersion, p.Stage)
+	if err := u.save(ctx, p); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			p.Status = StatusFailed
+			p.addEvent("upgrade failed: %v", err)
+		} else {
+			p.Status = StatusSuccess
+			p.addEvent("upgrade to %s finished", u.targetVersion)
+		}
+		if saveErr := u.save(context.Background(), p); saveErr != nil {
+			logrus.Errorf("failed to save upgrade progress, %v", saveErr)
+		}
+	}()
+
+	steps := map[Stage]func(context.Context, *Progress) error{
+		StagePreflight:    u.preflight,
+		StageControlPlane: u.upgradeControlPlane,
+		StageNodePools:    u.upgradeNodePools,
+		StagePostflight:   u.postflight,
+	}
+	for _, stage := range Stages[stageIndex(p.Stage):] {
+		if stage == StageDone {
+			break
+		}
+		if err := u.checkpoint(ctx, p); err != nil {
+			return err
+		}
+		p.Stage = stage
+		p.addEvent("stage %s started", stage)
+		if err := u.save(ctx, p); err != nil {
+			return err
+		}
+		if err := steps[stage](ctx, p); err != nil {
+			return fmt.Errorf("stage %s: %v", stage, err)
+		}
+	}
+	p.Stage = StageDone
+	return nil
+}
+
+// preflight checks the version skew, node status and disruption budgets before upgrade
+func (u *Upgrader) preflight(ctx context.Context, p *Progress) error {
+	current, err := u.provider.ControlPlaneVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get control plane version, %v", err)
+	}
+	if err := checkVersionSkew(current, u.targetVersion); err != nil {
+		return err
+	}
+	p.FromVersion = current
+
+	nodes, err := u.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list nodes, %v", err)
+	}
+	for _, node := range nodes.Items {
+		if !isNodeReady(&node) {
+			return fmt.Errorf("node %s is not ready", node.Name)
+		}
+	}
+
+	pools, err := u.provider.ListNodePools(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list node pools, %v", err)
+	}
+	for _, pool := range pools {
+		if len(pool.Nodes) == 0 {
+			continue
+		}
+		if p.nodePool(pool.Name) == nil {
+			p.NodePools = append(p.NodePools, NodePoolProgress{Name: pool.Name, Total: len(pool.Nodes)})
+		}
+	}
+
+	pdbs, err := u.client.PolicyV1().PodDisruptionBudgets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list pod disruption budgets, %v", err)
+	}
+	for _, pdb := range pdbs.Items {
+		if pdb.Status.DisruptionsAllowed == 0 {
+			p.addEvent("warning: disruption budget %s/%s allows no disruption, draining nodes may wait until timeout",
+				pdb.Namespace, pdb.Name)
+		}
+	}
+	p.addEvent("preflight passed, %d nodes in %d node pools will be replaced", len(nodes.Items), len(p.NodePools))
+	return nil
+}
+
+func (u *Upgrader) upgradeControlPlane(ctx context.Context, p *Progress) error {
+	current, err := u.provider.ControlPlaneVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get control plane version, %v", err)
+	}
+	if sameVersion(current, u.targetVersion) {
+		p.addEvent("control plane is already %s", current)
+		return nil
+	}
+	if err := u.provider.UpgradeControlPlane(ctx, u.targetVersion); err != nil {
+		return fmt.Errorf("failed to upgrade control plane, %v", err)
+	}
+	p.addEvent("control plane upgraded from %s to %s", current, u.targetVersion)
+	return u.save(ctx, p)
+}
+
+// upgradeNodePools replaces old nodes pool by pool: surge new nodes, wait them ready,
+// then drain and delete old nodes, at most maxSurge nodes at a time.
+func (u *Upgrader) upgradeNodePools(ctx context.Context, p *Progress) error {
+	pools, err := u.provider.ListNodePools(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list node pools, %v", err)
+	}
+	for _, pool := range pools {
+		pp := p.nodePool(pool.Name)
+		if pp == nil {
+			continue
+		}
+		var old []string
+		for _, node := range pool.Nodes {
+			if !contains(pp.Surged, node) && !contains(pp.Replaced, node) {
+				old = append(old, node)
+			}
+		}
+		for len(old) > 0 {
+			batch := old
+			if len(batch) > u.maxSurge {
+				batch = old[:u.maxSurge]
+			}
+			old = old[len(batch):]
+			if err := u.replaceNodes(ctx, p, pp, batch); err != nil {
+				return err
+			}
+		}
+		p.addEvent("node pool %s upgraded", pool.Name)
+		if err := u.save(ctx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *Upgrader) replaceNodes(ctx context.Context, p *Progress, pp *NodePoolProgress, nodes []string) error {
+	if err := u.checkpoint(ctx, p); err != nil {
+		return err
+	}
+	// new nodes surged before an interruption are reused
+	surge := len(nodes) - (len(pp.Surged) - len(pp.Replaced))
+	for i := 0; i < surge; i++ {
+		node, err := u.provider.SurgeNode(ctx, pp.Name, u.targetVersion)
+		if err != nil {
+			return fmt.Errorf("failed to add node to pool %s, %v", pp.Name, err)
+		}
+		pp.Surged = append(pp.Surged, node)
+		p.addEvent("node %s added to pool %s", node, pp.Name)
+		if err := u.save(ctx, p); err != nil {
+			return err
+		}
+		if err := u.waitNodeReady(ctx, node); err != nil {
+			return err
+		}
+	}
+
+	for _, node := range nodes {
+		if err := u.drainNode(ctx, node); err != nil {
+			return fmt.Errorf("failed to drain node %s, %v", node, err)
+		}
+		if err := u.provider.DeleteNode(ctx, pp.Name, node); err != nil {
+			return fmt.Errorf("failed to delete node %s from pool %s, %v", node, pp.Name, err)
+		}
+		err := u.client.CoreV1().Nodes().Delete(ctx, node, metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete node %s, %v", node, err)
+		}
+		pp.Replaced = append(pp.Replaced, node)
+		p.addEvent("node %s drained and replaced (%d/%d)", node, len(pp.Replaced), pp.Total)
+		if err := u.save(ctx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drainNode cordons the node and evicts its pods through the eviction API, so that disruption budgets are respected.
+// DaemonSet and mirror pods are kept, unmanaged and emptyDir pods are removed as the node will be deleted.
+func (u *Upgrader) drainNode(ctx context.Context, name string) error {
+	node, err := u.client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if err := drain.RunCordonOrUncordon(ctx, u.client, node, true); err != nil {
+		return fmt.Errorf("failed to cordon, %v", err)
+	}
+	helper := &drain.Helper{
+		Ctx:                 ctx,
+		Client:              u.client,
+		Force:               true,
+		IgnoreAllDaemonSets: true,
+		DeleteLocalData:     true,
+		GracePeriodSeconds:  -1,
+		Timeout:             u.drainTimeout,
+		Out:                 os.Stdout,
+		ErrOut:              os.Stderr,
+	}
+	return drain.RunNodeDrain(ctx, helper, name)
+}
+
+// postflight checks all nodes are ready and running the target version
+func (u *Upgrader) postflight(ctx context.Context, p *Progress) error {
+	current, err := u.provider.ControlPlaneVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get control plane version, %v", err)
+	}
+	if !sameVersion(current, u.targetVersion) {
+		return fmt.Errorf("control plane version is %s, expect %s", current, u.targetVersion)
+	}
+	nodes, err := u.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list nodes, %v", err)
+	}
+	for _, node := range nodes.Items {
+		if !isNodeReady(&node) {
+			return fmt.Errorf("node %s is not ready", node.Name)
+		}
+		if v := node.Status.NodeInfo.KubeletVersion; !sameVersion(v, u.targetVersion) {
+			return fmt.Errorf("kubelet version of node %s is %s, expect %s", node.Name, v, u.targetVersion)
+		}
+	}
+	p.addEvent("postflight passed, %d nodes are running %s", len(nodes.Items), u.targetVersion)
+	return nil
+}
+
+// checkpoint blocks while user requests to pause the upgrade
+func (u *Upgrader) checkpoint(ctx context.Context, p *Progress) error {
+	for {
+		paused, err := u.store.PauseRequested(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get pause request, %v", err)
+		}
+		if !paused {
+			if p.Status == StatusPaused {
+				p.Status = StatusRunning
+				p.addEvent("upgrade resumed")
+				return u.save(ctx, p)
+			}
+			return nil
+		}
+		if p.Status != StatusPaused {
+			p.Status = StatusPaused
+			p.addEvent("upgrade paused")
+			if err := u.save(ctx, p); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(u.pollInterval):
+		}
+	}
+}
+
+func (u *Upgrader) waitNodeReady(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, u.nodeReadyTimeout)
+	defer cancel()
+	for {
+		node, err := u.client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err == nil && isNodeReady(node) {
+			return nil
+		}
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get node %s, %v", name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout to wait node %s ready", name)
+		case <-time.After(u.pollInterval):
+		}
+	}
+}
+
+func (u *Upgrader) save(ctx context.Context, p *Progress) error {
+	if err := u.store.Save(ctx, p); err != nil {
+		return fmt.Errorf("failed to save upgrade progress, %v", err)
+	}
+	return nil
+}
+
+func isNodeReady(node *corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// parseVersion parses kubernetes version like v1.21.2 or 1.21.2-aliyun.1 into major, minor and patch
+func parseVersion(v string) ([3]int, error) {
+	var result [3]int
+	s := strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return result, fmt.Errorf("invalid kubernetes version %q", v)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return result, fmt.Errorf("invalid kubernetes version %q", v)
+		}
+		result[i] = n
+	}
+	return result, nil
+}
+
+func sameVersion(a, b string) bool {
+	va, err := parseVersion(a)
+	if err != nil {
+		return false
+	}
+	vb, err := parseVersion(b)
+	return err == nil && va == vb
+}
+
+// checkVersionSkew allows upgrading to a newer patch or the next minor version only
+func checkVersionSkew(current, target string) error {
+	c, err := parseVersion(current)
+	if err != nil {
+		return err
+	}
+	t, err := parseVersion(target)
+	if err != nil {
+		return err
+	}
+	if t[0] != c[0] {
+		return fmt.Errorf("can not upgrade major version from %s to %s", current, target)
+	}
+	if t[1] < c[1] || (t[1] == c[1] && t[2] < c[2]) {
+		return fmt.Errorf("can not downgrade from %s to %s", current, target)
+	}
+	if t[1]-c[1] > 1 {
+		return fmt.Errorf("can not skip minor versions from %s to %s, upgrade one minor version at a time", current, target)
+	}
+	return nil
+}
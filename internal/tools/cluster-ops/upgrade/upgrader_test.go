@@ -0,0 +1,273 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+type memoryStore struct {
+	mu     sync.Mutex
+	data   []byte
+	paused bool
+}
+
+func (m *memoryStore) Load(ctx context.Context) (*Progress, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		return nil, nil
+	}
+	var p Progress
+	err := json.Unmarshal(m.data, &p)
+	return &p, err
+}
+
+func (m *memoryStore) Save(ctx context.Context, p *Progress) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := json.Marshal(p)
+	m.data = data
+	return err
+}
+
+func (m *memoryStore) PauseRequested(ctx context.Context) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.paused, nil
+}
+
+func (m *memoryStore) setPaused(paused bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.paused = paused
+}
+
+func (m *memoryStore) progress(t *testing.T) *Progress {
+	p, err := m.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func newNode(name, version string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: version},
+		},
+	}
+}
+
+func newPod(name, node string, owner string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID("uid-" + name)},
+		Spec:       corev1.PodSpec{NodeName: node},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if owner != "" {
+		controller := true
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: owner, Name: "owner", Controller: &controller}}
+	}
+	return pod
+}
+
+// newTestCluster returns a fake clientset whose evictions delete pods, blocked is the number of evictions
+// rejected by disruption budget before success
+func newTestCluster(t *testing.T, blocked int, objects ...runtime.Object) (*fake.Clientset, *int) {
+	client := fake.NewSimpleClientset(objects...)
+	client.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "policy/v1"},
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods/eviction", Kind: "Eviction"}}},
+	}
+	evictions := 0
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		evictions++
+		if evictions <= blocked {
+			return true, nil, k8serrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+		gvr := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+		if err := client.Tracker().Delete(gvr, eviction.Namespace, eviction.Name); err != nil {
+			t.Errorf("failed to delete pod %s, %v", eviction.Name, err)
+		}
+		return true, nil, nil
+	})
+	return client, &evictions
+}
+
+func newTestProvider(t *testing.T, client *fake.Clientset) *FakeProvider {
+	provider := NewFakeProvider("v1.20.4", NodePool{Name: "pool", Version: "v1.20.4", Nodes: []string{"n1", "n2"}})
+	provider.OnSurge = func(pool, node string) error {
+		_, err := client.CoreV1().Nodes().Create(context.Background(), newNode(node, "v1.21.2"), metav1.CreateOptions{})
+		return err
+	}
+	return provider
+}
+
+func TestUpgraderRun(t *testing.T) {
+	client, evictions := newTestCluster(t, 1,
+		newNode("n1", "v1.20.4"), newNode("n2", "v1.20.4"),
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: "default"}},
+		newPod("web", "n1", "ReplicaSet"), newPod("agent", "n1", "DaemonSet"), newPod("api", "n2", ""),
+	)
+	provider := newTestProvider(t, client)
+	store := &memoryStore{}
+	u := New(provider, client, store, "v1.21.2", WithPollInterval(time.Millisecond))
+	if err := u.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"upgrade-control-plane v1.21.2", "surge pool-v1.21.2-1", "delete n1", "surge pool-v1.21.2-2", "delete n2"}
+	if len(provider.Operations) != len(expected) {
+		t.Fatalf("expect operations %v, got %v", expected, provider.Operations)
+	}
+	for i := range expected {
+		if provider.Operations[i] != expected[i] {
+			t.Errorf("expect operations %v, got %v", expected, provider.Operations)
+			break
+		}
+	}
+	// web and api are evicted, web is blocked by disruption budget once, agent is owned by daemonSet
+	if *evictions != 3 {
+		t.Errorf("expect 3 evictions, got %d", *evictions)
+	}
+	if _, err := client.CoreV1().Pods("default").Get(context.Background(), "agent", metav1.GetOptions{}); err != nil {
+		t.Errorf("daemonSet pod should not be evicted, %v", err)
+	}
+	p := store.progress(t)
+	if p.Status != StatusSuccess || p.Stage != StageDone || p.FromVersion != "v1.20.4" {
+		t.Errorf("unexpected progress %+v", p)
+	}
+	if len(p.NodePools) != 1 || len(p.NodePools[0].Replaced) != 2 {
+		t.Errorf("unexpected node pool progress %+v", p.NodePools)
+	}
+
+	// run again is a no-op
+	if err := u.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.Operations) != len(expected) {
+		t.Errorf("finished upgrade should not run again, operations %v", provider.Operations)
+	}
+}
+
+func TestUpgraderPauseResume(t *testing.T) {
+	client, _ := newTestCluster(t, 0, newNode("n1", "v1.20.4"), newNode("n2", "v1.20.4"))
+	provider := newTestProvider(t, client)
+	store := &memoryStore{paused: true}
+	u := New(provider, client, store, "v1.21.2", WithPollInterval(time.Millisecond))
+
+	done := make(chan error)
+	go func() { done <- u.Run(context.Background()) }()
+	for i := 0; i < 1000; i++ {
+		if p := store.progress(t); p != nil && p.Status == StatusPaused {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if p := store.progress(t); p.Status != StatusPaused || len(provider.Operations) != 0 {
+		t.Fatalf("expect paused before any operation, progress %+v, operations %v", p, provider.Operations)
+	}
+	store.setPaused(false)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if p := store.progress(t); p.Status != StatusSuccess {
+		t.Errorf("expect success after resumed, got %s", p.Status)
+	}
+}
+
+func TestUpgraderResumeFromFailure(t *testing.T) {
+	client, _ := newTestCluster(t, 0, newNode("n1", "v1.20.4"), newNode("n2", "v1.20.4"))
+	provider := newTestProvider(t, client)
+	provider.Err["DeleteNode"] = errors.New("quota exceeded")
+	store := &memoryStore{}
+	u := New(provider, client, store, "v1.21.2", WithPollInterval(time.Millisecond))
+	if err := u.Run(context.Background()); err == nil {
+		t.Fatal("expect error")
+	}
+	p := store.progress(t)
+	if p.Status != StatusFailed || p.Stage != StageNodePools || len(p.NodePools[0].Surged) != 1 {
+		t.Fatalf("unexpected progress %+v", p)
+	}
+
+	// the surged node is reused after resumed
+	delete(provider.Err, "DeleteNode")
+	if err := u.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	p = store.progress(t)
+	if p.Status != StatusSuccess || len(p.NodePools[0].Surged) != 2 || len(p.NodePools[0].Replaced) != 2 {
+		t.Errorf("unexpected progress %+v", p.NodePools)
+	}
+	if another := New(provider, client, store, "v1.22.0").Run(context.Background()); another == nil {
+		t.Errorf("expect error for different target version")
+	}
+}
+
+func TestCheckVersionSkew(t *testing.T) {
+	tests := []struct {
+		current, target string
+		ok              bool
+	}{
+		{"v1.20.4", "v1.21.2", true},
+		{"v1.20.4", "v1.20.11", true},
+		{"v1.20.4-aliyun.1", "1.21.2-aliyun.1", true},
+		{"v1.20.4", "v1.22.0", false},
+		{"v1.21.2", "v1.20.4", false},
+		{"v1.21.2", "v2.0.0", false},
+		{"v1.21.2", "latest", false},
+	}
+	for _, tt := range tests {
+		if err := checkVersionSkew(tt.current, tt.target); (err == nil) != tt.ok {
+			t.Errorf("checkVersionSkew(%s, %s) = %v", tt.current, tt.target, err)
+		}
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	p, err := NewProvider(FakeProviderName, map[string]string{"version": "v1.20.4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := p.ControlPlaneVersion(context.Background()); v != "v1.20.4" {
+		t.Errorf("unexpected version %s", v)
+	}
+	if _, err := NewProvider("unknown", nil); err == nil {
+		t.Errorf("expect error for unknown provider")
+	}
+}
@@ -17,6 +17,7 @@ package esinfluxql
 import (
 	"fmt"
 	"math"
+	"strings"
 
 	"github.com/doug-martin/goqu/v9"
 	"github.com/doug-martin/goqu/v9/exp"
@@ -349,7 +350,13 @@ func (c ckUnaryFunction) Aggregations(p *Parser, aggs map[string]exp.Expression,
 	if ref, ok := arg.(*influxql.VarRef); ok {
 		c.field = ref.Val
 		id := c.id
-		a, err := c.agg(c.ctx, p, id, ref, nil, flags...)
+		var a exp.Expression
+		var err error
+		if p.resolution != nil {
+			a, err = p.rollupAggregation(call.Name, id, ref)
+		} else {
+			a, err = c.agg(c.ctx, p, id, ref, nil, flags...)
+		}
 		if err != nil {
 			return err
 		}
@@ -375,3 +382,28 @@ func (c ckUnaryFunction) Handle(row map[string]interface{}) (interface{}, error)
 	}
 	return val, nil
 }
+
+// rollupAggregation computes the aggregation from the partial aggregates of the rollup table.
+func (p *Parser) rollupAggregation(name, id string, ref *influxql.VarRef) (exp.Expression, error) {
+	switch name {
+	case "max", "value":
+		return goqu.MAX(goqu.L(ckRollupField("number_field_maxs", ref.Val))).As(id), nil
+	case "min", "diff", "diffps":
+		return goqu.MIN(goqu.L(ckRollupField("number_field_mins", ref.Val))).As(id), nil
+	case "sum", "rateps":
+		return goqu.SUM(goqu.L(ckRollupField("number_field_sums", ref.Val))).As(id), nil
+	case "count":
+		return goqu.SUM(goqu.L(ckRollupField("number_field_counts", ref.Val))).As(id), nil
+	case "avg":
+		return goqu.L(fmt.Sprintf("sum(%s)/sum(%s)",
+			ckRollupField("number_field_sums", ref.Val), ckRollupField("number_field_counts", ref.Val))).As(id), nil
+	}
+	return nil, fmt.Errorf("not support function '%s' on rollup", name)
+}
+
+var ckStringEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+func ckRollupField(column, key string) string {
+	index := fmt.Sprintf("indexOf(tupleElement(%s,1),'%s')", column, ckStringEscaper.Replace(key))
+	return fmt.Sprintf("if(%s == 0,null,tupleElement(%s,2)[%s])", index, column, index)
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/erda-project/erda/internal/tools/monitor/core/metric/model"
 	tsql "github.com/erda-project/erda/internal/tools/monitor/core/metric/query/es-tsql"
 	"github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/meta"
+	"github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/rollup"
 )
 
 // Parser .
@@ -41,6 +42,9 @@ type Parser struct {
 	orgName     string
 	terminusKey string
 	metric      []string
+
+	rollup     rollup.Interface
+	resolution *rollup.Resolution
 }
 
 // New start and end always nanosecond
@@ -91,6 +95,11 @@ func (p *Parser) SetMeta(meta meta.Interface) {
 	p.meta = meta
 }
 
+// SetRollup enables clickhouse queries to read the pre-aggregated rollup tables.
+func (p *Parser) SetRollup(r rollup.Interface) {
+	p.rollup = r
+}
+
 // SetParams .
 func (p *Parser) SetParams(params map[string]interface{}) tsql.Parser {
 	if len(params) > 0 {
@@ -41,6 +41,8 @@ func (p *Parser) ParseClickhouse(s *influxql.SelectStatement) (tsql.Query, error
 
 	expr := goqu.From("metric") // metric is fake, in execution layer it's real table
 
+	p.resolution = p.planRollup(s)
+
 	expr = p.appendTimeKeyByExpr(expr)
 
 	expr, err = p.conditionOnExpr(expr, s)
@@ -79,9 +81,17 @@ func (p *Parser) ParseClickhouse(s *influxql.SelectStatement) (tsql.Query, error
 		debug:       p.debug,
 		orgName:     p.orgName,
 		terminusKey: p.terminusKey,
+		table:       p.rollupTable(),
 	}, nil
 }
 
+func (p *Parser) rollupTable() string {
+	if p.resolution == nil {
+		return ""
+	}
+	return p.rollup.SearchTable(p.resolution)
+}
+
 func appendOrderedExpression(expr *goqu.SelectDataset, express exp.IdentifierExpression, isAsc bool) *goqu.SelectDataset {
 	if !isAsc {
 		expr = expr.OrderAppend(express.Desc())
@@ -469,6 +479,10 @@ func (p *Parser) parseFiledRefByExpr(expr influxql.Expr, cols *_columns) error {
 
 func (p *Parser) appendTimeKeyByExpr(expr *goqu.SelectDataset) *goqu.SelectDataset {
 	start, end := p.ctx.Range(true)
+	if p.resolution != nil {
+		// rollup rows are stamped with the start of their bucket
+		start -= start % int64(p.resolution.Interval)
+	}
 	expr = expr.Where(
 		goqu.C(p.ctx.timeKey).Gte(goqu.L("fromUnixTimestamp64Nano(cast(?,'Int64'))", start)),
 		goqu.C(p.ctx.timeKey).Lt(goqu.L("fromUnixTimestamp64Nano(cast(?,'Int64'))", end)),
@@ -18,6 +18,7 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/doug-martin/goqu/v9"
 	"github.com/influxdata/influxql"
@@ -25,6 +26,7 @@ import (
 
 	"github.com/erda-project/erda/internal/tools/monitor/core/metric/model"
 	tsql "github.com/erda-project/erda/internal/tools/monitor/core/metric/query/es-tsql"
+	"github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/rollup"
 )
 
 func TestClickhouse(t *testing.T) {
@@ -940,3 +942,86 @@ func TestGroupColumnShouldBeExist(t *testing.T) {
 	}
 
 }
+
+type fakeRollup struct {
+	resolution *rollup.Resolution
+}
+
+func (f fakeRollup) Plan(start, end time.Time, interval time.Duration) *rollup.Resolution {
+	if interval%f.resolution.Interval != 0 {
+		return nil
+	}
+	return f.resolution
+}
+
+func (f fakeRollup) SearchTable(r *rollup.Resolution) string {
+	return "monitor.rollup_metrics_" + r.Name + "_all"
+}
+
+func TestRollup(t *testing.T) {
+	end := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	start := end.Add(-time.Hour)
+	tests := []struct {
+		name     string
+		stm      string
+		table    string
+		contains []string
+	}{
+		{
+			name:  "aggregations on rollup",
+			stm:   "select max(cpu),avg(cpu),count(cpu),host::tag from host_summary where cluster::tag='c1' group by time(5m),host::tag",
+			table: "monitor.rollup_metrics_1m_all",
+			contains: []string{
+				"MAX(if(indexOf(tupleElement(number_field_maxs,1),'cpu') == 0,null,tupleElement(number_field_maxs,2)[indexOf(tupleElement(number_field_maxs,1),'cpu')]))",
+				"sum(if(indexOf(tupleElement(number_field_sums,1),'cpu') == 0,null,tupleElement(number_field_sums,2)[indexOf(tupleElement(number_field_sums,1),'cpu')]))/sum(",
+				"SUM(if(indexOf(tupleElement(number_field_counts,1),'cpu') == 0,null,tupleElement(number_field_counts,2)[indexOf(tupleElement(number_field_counts,1),'cpu')]))",
+			},
+		},
+		{
+			name:  "escape field key",
+			stm:   `select max("cpu') or ('1") from host_summary group by time(5m)`,
+			table: "monitor.rollup_metrics_1m_all",
+			contains: []string{
+				`indexOf(tupleElement(number_field_maxs,1),'cpu\') or (\'1')`,
+			},
+		},
+		{
+			name: "unsupported function",
+			stm:  "select last(cpu) from host_summary group by time(5m)",
+		},
+		{
+			name: "without time bucket",
+			stm:  "select max(cpu) from host_summary",
+		},
+		{
+			name: "condition on field",
+			stm:  "select max(cpu) from host_summary where mem > 10 group by time(5m)",
+		},
+		{
+			name: "interval not divisible",
+			stm:  "select max(cpu) from host_summary group by time(90s)",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := New(start.UnixNano(), end.UnixNano(), test.stm, false)
+			p.(*Parser).SetRollup(fakeRollup{resolution: &rollup.Resolution{Name: "1m", Interval: time.Minute}})
+			require.NoError(t, p.Build())
+			queries, err := p.ParseQuery(context.Background(), model.ClickhouseKind)
+			require.NoError(t, err)
+			require.Len(t, queries, 1)
+			q, ok := queries[0].(QueryClickhouse)
+			require.True(t, ok)
+			require.Equal(t, test.table, q.Table())
+
+			sql, _, err := q.SearchSource().(*goqu.SelectDataset).ToSQL()
+			require.NoError(t, err)
+			for _, s := range test.contains {
+				require.Contains(t, sql, s)
+			}
+			if len(test.table) == 0 {
+				require.NotContains(t, sql, "tupleElement")
+			}
+		})
+	}
+}
@@ -30,6 +30,7 @@ import (
 
 	"github.com/erda-project/erda/internal/tools/monitor/core/metric/model"
 	tsql "github.com/erda-project/erda/internal/tools/monitor/core/metric/query/es-tsql"
+	"github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/rollup"
 	"github.com/erda-project/erda/pkg/common/trace"
 )
 
@@ -46,6 +47,8 @@ type QueryClickhouse struct {
 
 	orgName     string
 	terminusKey string
+
+	table string
 }
 
 func (q QueryClickhouse) Sources() []*model.Source {
@@ -358,3 +361,129 @@ func (q QueryClickhouse) OrgName() []string {
 func (q QueryClickhouse) TerminusKey() string {
 	return q.terminusKey
 }
+
+// Table returns the rollup table chosen for the query, empty means the raw tables.
+func (q QueryClickhouse) Table() string {
+	return q.table
+}
+
+var rollupFunctions = map[string]bool{
+	"max":    true,
+	"min":    true,
+	"avg":    true,
+	"sum":    true,
+	"count":  true,
+	"value":  true,
+	"diff":   true,
+	"diffps": true,
+	"rateps": true,
+}
+
+// planRollup chooses the coarsest rollup resolution able to answer the statement,
+// nil means the statement reads the raw tables.
+func (p *Parser) planRollup(s *influxql.SelectStatement) *rollup.Resolution {
+	if p.rollup == nil || p.ctx.OriginalTimeUnit() != tsql.Nanosecond {
+		return nil
+	}
+	interval, ok := p.bucketInterval(s.Dimensions)
+	if !ok || !p.rollupSupported(s) {
+		return nil
+	}
+	start, end := p.ctx.Range(true)
+	return p.rollup.Plan(time.Unix(0, start), time.Unix(0, end), interval)
+}
+
+// bucketInterval returns the interval of the time() dimension, the same way as parseQueryDimensionsByExpr does.
+func (p *Parser) bucketInterval(dimensions influxql.Dimensions) (time.Duration, bool) {
+	for _, dim := range dimensions {
+		call, ok := dim.Expr.(*influxql.Call)
+		if !ok || call.Name != "time" {
+			continue
+		}
+		var interval int64
+		if len(call.Args) == 1 {
+			d, ok := call.Args[0].(*influxql.DurationLiteral)
+			if !ok || d.Val < time.Second {
+				return 0, false
+			}
+			interval = int64(d.Val)
+		}
+		start, end := p.ctx.Range(true)
+		interval = adjustInterval(start, end, interval, p.ctx.maxTimePoints)
+		return time.Duration(interval/int64(tsql.Second)) * time.Second, true
+	}
+	return 0, false
+}
+
+// rollupSupported reports whether every part of the statement can be computed from the rollup columns:
+// aggregations of number fields, tags and the origin columns.
+func (p *Parser) rollupSupported(s *influxql.SelectStatement) bool {
+	for _, field := range s.Fields {
+		if !p.rollupSupportedField(field.Expr) {
+			return false
+		}
+	}
+	for _, dim := range s.Dimensions {
+		switch expr := dim.Expr.(type) {
+		case *influxql.Call:
+			if expr.Name != "time" {
+				return false
+			}
+		case *influxql.VarRef:
+			if !isRollupKey(expr) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	if s.Condition != nil {
+		for _, ref := range influxql.ExprNames(s.Condition) {
+			if !isRollupKey(&ref) {
+				return false
+			}
+		}
+	}
+	for _, field := range s.SortFields {
+		if ref, ok := field.Expr.(*influxql.VarRef); ok && !isRollupKey(ref) && ref.Val != model.TimeKey {
+			return false
+		}
+	}
+	for _, filter := range p.filter {
+		if _, ok := originColumn[filter.Key]; !ok && !strings.HasPrefix(filter.Key, "tags.") {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Parser) rollupSupportedField(expr influxql.Expr) bool {
+	switch expr := expr.(type) {
+	case *influxql.Call:
+		if !rollupFunctions[expr.Name] || len(expr.Args) != 1 {
+			return false
+		}
+		ref, ok := expr.Args[0].(*influxql.VarRef)
+		if !ok || isRollupKey(ref) || ref.Val == model.TimestampKey || ref.Val == model.TimeKey {
+			return false
+		}
+		_, isNumber := p.ckField(ref.Val)
+		return isNumber
+	case *influxql.BinaryExpr:
+		return p.rollupSupportedField(expr.LHS) && p.rollupSupportedField(expr.RHS)
+	case *influxql.ParenExpr:
+		return p.rollupSupportedField(expr.Expr)
+	case *influxql.VarRef:
+		return isRollupKey(expr)
+	case *influxql.IntegerLiteral, *influxql.NumberLiteral, *influxql.StringLiteral:
+		return true
+	}
+	return false
+}
+
+func isRollupKey(ref *influxql.VarRef) bool {
+	if _, ok := originColumn[ref.Val]; ok {
+		return ref.Val != model.TimestampKey
+	}
+	return ref.Type == influxql.Tag
+}
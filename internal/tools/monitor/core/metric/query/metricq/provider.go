@@ -39,6 +39,7 @@ import (
 	_ "github.com/erda-project/erda/internal/tools/monitor/core/metric/query/query/v1/language/params" //
 	"github.com/erda-project/erda/internal/tools/monitor/core/metric/storage"
 	"github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/meta"
+	"github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/rollup"
 	indexloader "github.com/erda-project/erda/internal/tools/monitor/core/storekit/elasticsearch/index/loader"
 )
 
@@ -72,8 +73,9 @@ type provider struct {
 
 	Storage storage.Storage `autowired:"metric-storage" optional:"true"`
 
-	CkMetaLoader    meta.Interface  `autowired:"clickhouse.meta.loader@metric" optional:"true"`
-	CkStorageReader storage.Storage `autowired:"metric-storage-clickhouse" optional:"true"`
+	CkMetaLoader    meta.Interface   `autowired:"clickhouse.meta.loader@metric" optional:"true"`
+	CkRollup        rollup.Interface `autowired:"clickhouse.table.rollup@metric" optional:"true"`
+	CkStorageReader storage.Storage  `autowired:"metric-storage-clickhouse" optional:"true"`
 
 	Org org.ClientInterface
 }
@@ -103,7 +105,7 @@ func (p *provider) Init(ctx servicehub.Context) error {
 	}
 
 	p.q = &Metricq{
-		Queryer:         query.New(p.CkMetaLoader, p.Storage, p.CkStorageReader, p.Log),
+		Queryer:         query.New(p.CkMetaLoader, p.Storage, p.CkStorageReader, p.CkRollup, p.Log),
 		queryv1:         queryv1.New(&query.MetricIndexLoader{Interface: p.Index}, charts, p.Meta, p.ChartTrans),
 		index:           p.Index,
 		meta:            p.Meta,
@@ -0,0 +1,184 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// ValueType is the type an expression evaluates to.
+type ValueType string
+
+// value types
+const (
+	ValueTypeScalar ValueType = "scalar"
+	ValueTypeVector ValueType = "vector"
+	ValueTypeMatrix ValueType = "matrix"
+	ValueTypeString ValueType = "string"
+)
+
+// Expr is a node of a parsed PromQL expression.
+type Expr interface {
+	Type() ValueType
+}
+
+// NumberLiteral is a float constant.
+type NumberLiteral struct {
+	Val float64
+}
+
+// StringLiteral is a string constant.
+type StringLiteral struct {
+	Val string
+}
+
+// VectorSelector selects the latest sample of every matching series.
+type VectorSelector struct {
+	Name     string
+	Matchers []*Matcher
+	Offset   time.Duration
+}
+
+// MatrixSelector selects a range of samples of every matching series.
+type MatrixSelector struct {
+	Vector *VectorSelector
+	Range  time.Duration
+}
+
+// Call is a function call.
+type Call struct {
+	Func *Function
+	Args []Expr
+}
+
+// AggregateExpr is an aggregation over a vector.
+type AggregateExpr struct {
+	Op       string
+	Expr     Expr
+	Param    Expr
+	Grouping []string
+	Without  bool
+}
+
+// VectorMatching describes how the series of a binary operation are matched.
+type VectorMatching struct {
+	On     bool
+	Labels []string
+}
+
+// BinaryExpr is a binary operation.
+type BinaryExpr struct {
+	Op         string
+	LHS, RHS   Expr
+	ReturnBool bool
+	Matching   *VectorMatching
+}
+
+// UnaryExpr is a unary minus or plus.
+type UnaryExpr struct {
+	Op   string
+	Expr Expr
+}
+
+// ParenExpr is an expression in parentheses.
+type ParenExpr struct {
+	Expr Expr
+}
+
+// Type .
+func (*NumberLiteral) Type() ValueType { return ValueTypeScalar }
+
+// Type .
+func (*StringLiteral) Type() ValueType { return ValueTypeString }
+
+// Type .
+func (*VectorSelector) Type() ValueType { return ValueTypeVector }
+
+// Type .
+func (*MatrixSelector) Type() ValueType { return ValueTypeMatrix }
+
+// Type .
+func (c *Call) Type() ValueType { return c.Func.ReturnType }
+
+// Type .
+func (*AggregateExpr) Type() ValueType { return ValueTypeVector }
+
+// Type .
+func (e *BinaryExpr) Type() ValueType {
+	if e.LHS.Type() == ValueTypeScalar && e.RHS.Type() == ValueTypeScalar {
+		return ValueTypeScalar
+	}
+	return ValueTypeVector
+}
+
+// Type .
+func (e *UnaryExpr) Type() ValueType { return e.Expr.Type() }
+
+// Type .
+func (e *ParenExpr) Type() ValueType { return e.Expr.Type() }
+
+// MatchType is the kind of a label matcher.
+type MatchType string
+
+// match types
+const (
+	MatchEqual     MatchType = "="
+	MatchNotEqual  MatchType = "!="
+	MatchRegexp    MatchType = "=~"
+	MatchNotRegexp MatchType = "!~"
+)
+
+// Matcher matches a label against a value.
+type Matcher struct {
+	Type  MatchType
+	Name  string
+	Value string
+	re    *regexp.Regexp
+}
+
+// NewMatcher creates a matcher, compiling the anchored regexp if needed.
+func NewMatcher(t MatchType, name, value string) (*Matcher, error) {
+	m := &Matcher{Type: t, Name: name, Value: value}
+	if t == MatchRegexp || t == MatchNotRegexp {
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %s", value, err)
+		}
+		m.re = re
+	}
+	return m, nil
+}
+
+// Matches reports whether the value matches.
+func (m *Matcher) Matches(v string) bool {
+	switch m.Type {
+	case MatchEqual:
+		return v == m.Value
+	case MatchNotEqual:
+		return v != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(v)
+	case MatchNotRegexp:
+		return !m.re.MatchString(v)
+	}
+	return false
+}
+
+// String .
+func (m *Matcher) String() string {
+	return fmt.Sprintf("%s%s%q", m.Name, m.Type, m.Value)
+}
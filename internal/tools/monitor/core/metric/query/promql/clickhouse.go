@@ -0,0 +1,315 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+// JobLabel is the label mapped to the metric group of erda metrics,
+// which is also where prometheus remote write stores the job.
+const JobLabel = "job"
+
+type queryFunc func(ctx context.Context, query string, args ...interface{}) (driver.Rows, error)
+
+// clickhouseQuerier reads series from the metric table of an org.
+// A series is identified by the metric group and its tags, and the metric
+// name selects a number field. A name like "group:field" selects the field
+// of that group, a plain name selects the field of the group given by the
+// job label, or of any group when no job is given.
+type clickhouseQuerier struct {
+	query     queryFunc
+	table     string
+	orgs      []string
+	maxSeries int
+}
+
+func newClickhouseQuerier(query queryFunc, table string, org string, maxSeries int) *clickhouseQuerier {
+	return &clickhouseQuerier{
+		query:     query,
+		table:     table,
+		orgs:      []string{org, "erda", ""},
+		maxSeries: maxSeries,
+	}
+}
+
+type selection struct {
+	name  string
+	field string
+	where []exp.Expression
+}
+
+func (q *clickhouseQuerier) baseWhere(start, end int64) []exp.Expression {
+	return []exp.Expression{
+		goqu.C("org_name").In(q.orgs),
+		goqu.C("timestamp").Gte(goqu.L("fromUnixTimestamp64Milli(cast(?,'Int64'))", start)),
+		goqu.C("timestamp").Lte(goqu.L("fromUnixTimestamp64Milli(cast(?,'Int64'))", end)),
+	}
+}
+
+// selection translates the matchers into the selected field and filters.
+func (q *clickhouseQuerier) selection(start, end int64, matchers []*Matcher, requireName bool) (*selection, error) {
+	sel := &selection{where: q.baseWhere(start, end)}
+	hasJob := false
+	for _, m := range matchers {
+		if m.Name == JobLabel && m.Type == MatchEqual {
+			hasJob = true
+		}
+	}
+	for _, m := range matchers {
+		switch m.Name {
+		case MetricNameLabel:
+			if m.Type != MatchEqual {
+				return nil, fmt.Errorf("matcher %s is not supported on the metric name, use an equality matcher", m)
+			}
+			sel.name, sel.field = m.Value, m.Value
+			if idx := strings.Index(m.Value, ":"); idx > 0 && !hasJob {
+				sel.field = m.Value[idx+1:]
+				sel.where = append(sel.where, goqu.C("metric_group").Eq(m.Value[:idx]))
+			}
+		case JobLabel:
+			sel.where = append(sel.where, matcherExpression("metric_group", nil, m))
+		default:
+			sel.where = append(sel.where, matcherExpression("tag_values[indexOf(tag_keys,?)]", []interface{}{m.Name}, m))
+		}
+	}
+	if len(sel.field) > 0 {
+		sel.where = append(sel.where, goqu.L("has(number_field_keys,?)", sel.field))
+	} else if requireName {
+		return nil, fmt.Errorf("a metric name is required in the selector")
+	}
+	return sel, nil
+}
+
+// matcherExpression applies the matcher to the column expression.
+func matcherExpression(column string, args []interface{}, m *Matcher) exp.Expression {
+	switch m.Type {
+	case MatchNotEqual:
+		return goqu.L(column, args...).Neq(m.Value)
+	case MatchRegexp:
+		return goqu.L("match("+column+",?)", append(args, "^(?:"+m.Value+")$")...)
+	case MatchNotRegexp:
+		return goqu.L("NOT match("+column+",?)", append(args, "^(?:"+m.Value+")$")...)
+	}
+	return goqu.L(column, args...).Eq(m.Value)
+}
+
+func (q *clickhouseQuerier) rows(ctx context.Context, expr *goqu.SelectDataset) (driver.Rows, error) {
+	sql, _, err := expr.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.query(ctx, sql)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %s", err)
+	}
+	if rows.Err() != nil {
+		rows.Close()
+		return nil, fmt.Errorf("failed to query: %s", rows.Err())
+	}
+	return rows, nil
+}
+
+// Select implements Querier.
+func (q *clickhouseQuerier) Select(ctx context.Context, hints *SelectHints, matchers []*Matcher) ([]*Series, error) {
+	sel, err := q.selection(hints.Start, hints.End, matchers, true)
+	if err != nil {
+		return nil, err
+	}
+	expr := goqu.From(q.table).Select(
+		goqu.C("metric_group"),
+		goqu.C("tag_keys"),
+		goqu.C("tag_values"),
+		goqu.L("toUnixTimestamp64Milli(timestamp)").As("ts"),
+		goqu.L("number_field_values[indexOf(number_field_keys,?)]", sel.field).As("value"),
+	).Where(sel.where...).Order(goqu.C("ts").Asc())
+
+	rows, err := q.rows(ctx, expr)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	series := make(map[string]*Series)
+	var list []*Series
+	for rows.Next() {
+		var (
+			group      string
+			keys, vals []string
+			ts         int64
+			value      float64
+		)
+		if err := rows.Scan(&group, &keys, &vals, &ts, &value); err != nil {
+			return nil, err
+		}
+		labels := seriesLabels(sel.name, group, keys, vals)
+		key := labels.Key()
+		s, ok := series[key]
+		if !ok {
+			if q.maxSeries > 0 && len(list) >= q.maxSeries {
+				return nil, fmt.Errorf("the selector matches more than %d series", q.maxSeries)
+			}
+			s = &Series{Metric: labels}
+			series[key] = s
+			list = append(list, s)
+		}
+		s.Points = append(s.Points, Point{T: ts, V: value})
+	}
+	return list, rows.Err()
+}
+
+func seriesLabels(name, group string, keys, vals []string) Labels {
+	m := make(map[string]string, len(keys)+2)
+	for i, k := range keys {
+		if i < len(vals) {
+			m[SanitizeLabelName(k)] = vals[i]
+		}
+	}
+	m[MetricNameLabel] = name
+	m[JobLabel] = group
+	return NewLabels(m)
+}
+
+// Series implements Querier.
+func (q *clickhouseQuerier) Series(ctx context.Context, start, end time.Time, matcherSets [][]*Matcher) ([]Labels, error) {
+	seen := make(map[string]bool)
+	var result []Labels
+	for _, matchers := range matcherSets {
+		sel, err := q.selection(timeMilliseconds(start), timeMilliseconds(end), matchers, true)
+		if err != nil {
+			return nil, err
+		}
+		expr := goqu.From(q.table).Select(
+			goqu.C("metric_group"),
+			goqu.C("tag_keys"),
+			goqu.C("tag_values"),
+		).Distinct().Where(sel.where...)
+		if q.maxSeries > 0 {
+			expr = expr.Limit(uint(q.maxSeries))
+		}
+		err = q.scan(ctx, expr, func(rows driver.Rows) error {
+			var (
+				group      string
+				keys, vals []string
+			)
+			if err := rows.Scan(&group, &keys, &vals); err != nil {
+				return err
+			}
+			labels := seriesLabels(sel.name, group, keys, vals)
+			if key := labels.Key(); !seen[key] {
+				seen[key] = true
+				result = append(result, labels)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// LabelNames implements Querier.
+func (q *clickhouseQuerier) LabelNames(ctx context.Context, start, end time.Time, matchers []*Matcher) ([]string, error) {
+	sel, err := q.selection(timeMilliseconds(start), timeMilliseconds(end), matchers, false)
+	if err != nil {
+		return nil, err
+	}
+	expr := goqu.From(q.table).Select(goqu.L("arrayJoin(tag_keys)").As("name")).Distinct().Where(sel.where...)
+	names := []string{MetricNameLabel, JobLabel}
+	err = q.scanStrings(ctx, expr, func(name string) {
+		names = append(names, SanitizeLabelName(name))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uniqueSorted(names), nil
+}
+
+// LabelValues implements Querier.
+func (q *clickhouseQuerier) LabelValues(ctx context.Context, name string, start, end time.Time, matchers []*Matcher) ([]string, error) {
+	sel, err := q.selection(timeMilliseconds(start), timeMilliseconds(end), matchers, false)
+	if err != nil {
+		return nil, err
+	}
+	var expr *goqu.SelectDataset
+	switch name {
+	case MetricNameLabel:
+		expr = goqu.From(q.table).Select(goqu.L("arrayJoin(number_field_keys)").As("value"))
+	case JobLabel:
+		expr = goqu.From(q.table).Select(goqu.C("metric_group").As("value"))
+	default:
+		expr = goqu.From(q.table).Select(goqu.L("tag_values[indexOf(tag_keys,?)]", name).As("value"))
+		sel.where = append(sel.where, goqu.L("has(tag_keys,?)", name))
+	}
+	expr = expr.Distinct().Where(sel.where...)
+	var values []string
+	err = q.scanStrings(ctx, expr, func(v string) {
+		if len(v) > 0 {
+			values = append(values, v)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uniqueSorted(values), nil
+}
+
+func (q *clickhouseQuerier) scan(ctx context.Context, expr *goqu.SelectDataset, fn func(rows driver.Rows) error) error {
+	rows, err := q.rows(ctx, expr)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (q *clickhouseQuerier) scanStrings(ctx context.Context, expr *goqu.SelectDataset, fn func(string)) error {
+	if q.maxSeries > 0 {
+		expr = expr.Limit(uint(q.maxSeries))
+	}
+	return q.scan(ctx, expr, func(rows driver.Rows) error {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return err
+		}
+		fn(v)
+		return nil
+	})
+}
+
+func uniqueSorted(list []string) []string {
+	sort.Strings(list)
+	out := list[:0]
+	for i, s := range list {
+		if i == 0 || s != list[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,675 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// SelectHints describes the time range a selector needs, in milliseconds.
+type SelectHints struct {
+	Start int64
+	End   int64
+	Step  int64
+	Range int64
+}
+
+// Querier loads raw series from the underlying storage.
+type Querier interface {
+	// Select returns all series matching the matchers with their points in [Start, End] ordered by time.
+	Select(ctx context.Context, hints *SelectHints, matchers []*Matcher) ([]*Series, error)
+	// Series returns the label sets of the series matching any of the matcher sets.
+	Series(ctx context.Context, start, end time.Time, matcherSets [][]*Matcher) ([]Labels, error)
+	// LabelNames returns all label names in the time range.
+	LabelNames(ctx context.Context, start, end time.Time, matchers []*Matcher) ([]string, error)
+	// LabelValues returns all values of the label in the time range.
+	LabelValues(ctx context.Context, name string, start, end time.Time, matchers []*Matcher) ([]string, error)
+}
+
+// EngineOptions configures an Engine.
+type EngineOptions struct {
+	// LookbackDelta is how far back an instant selector looks for the latest sample.
+	LookbackDelta time.Duration
+	// MaxSamples limits the number of samples loaded by a single query.
+	MaxSamples int
+	// Timeout limits the duration of a single query.
+	Timeout time.Duration
+}
+
+// Engine evaluates PromQL expressions.
+type Engine struct {
+	lookback   int64
+	maxSamples int
+	timeout    time.Duration
+}
+
+// NewEngine creates an engine.
+func NewEngine(opts EngineOptions) *Engine {
+	if opts.LookbackDelta <= 0 {
+		opts.LookbackDelta = 5 * time.Minute
+	}
+	return &Engine{
+		lookback:   durationMilliseconds(opts.LookbackDelta),
+		maxSamples: opts.MaxSamples,
+		timeout:    opts.Timeout,
+	}
+}
+
+// ErrorType classifies query errors the way the Prometheus API does.
+type ErrorType string
+
+// error types
+const (
+	ErrorBadData  ErrorType = "bad_data"
+	ErrorExec     ErrorType = "execution"
+	ErrorTimeout  ErrorType = "timeout"
+	ErrorCanceled ErrorType = "canceled"
+	ErrorInternal ErrorType = "internal"
+)
+
+// QueryError is an error raised while evaluating a query.
+type QueryError struct {
+	Type ErrorType
+	Err  error
+}
+
+func (e *QueryError) Error() string { return e.Err.Error() }
+
+func durationMilliseconds(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
+
+func timeMilliseconds(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// InstantQuery evaluates the expression at a single point in time.
+func (ng *Engine) InstantQuery(ctx context.Context, q Querier, qs string, ts time.Time) (Value, error) {
+	expr, err := ParseExpr(qs)
+	if err != nil {
+		return nil, &QueryError{Type: ErrorBadData, Err: err}
+	}
+	t := timeMilliseconds(ts)
+	ev, cancel, err := ng.newEvaluator(ctx, q, expr, t, t, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	return ev.run(func() Value {
+		if ms, ok := expr.(*MatrixSelector); ok {
+			return ev.matrixSelector(ms, t)
+		}
+		return ev.eval(expr, t)
+	})
+}
+
+// RangeQuery evaluates the expression at every step in [start, end].
+func (ng *Engine) RangeQuery(ctx context.Context, q Querier, qs string, start, end time.Time, step time.Duration) (Value, error) {
+	expr, err := ParseExpr(qs)
+	if err != nil {
+		return nil, &QueryError{Type: ErrorBadData, Err: err}
+	}
+	if t := expr.Type(); t != ValueTypeVector && t != ValueTypeScalar {
+		return nil, &QueryError{Type: ErrorBadData, Err: fmt.Errorf("invalid expression type %q for range query, must be scalar or instant vector", t)}
+	}
+	s, e, st := timeMilliseconds(start), timeMilliseconds(end), durationMilliseconds(step)
+	if st <= 0 {
+		return nil, &QueryError{Type: ErrorBadData, Err: fmt.Errorf("zero or negative query resolution step widths are not accepted")}
+	}
+	ev, cancel, err := ng.newEvaluator(ctx, q, expr, s, e, st)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	return ev.run(func() Value {
+		series := make(map[string]*Series)
+		var order []string
+		for ts := s; ts <= e; ts += st {
+			ev.checkContext()
+			var vec Vector
+			switch v := ev.eval(expr, ts).(type) {
+			case Scalar:
+				vec = Vector{{Point: Point(v)}}
+			case Vector:
+				vec = v
+			}
+			for _, sample := range vec {
+				key := sample.Metric.Key()
+				ss, ok := series[key]
+				if !ok {
+					ss = &Series{Metric: sample.Metric}
+					series[key] = ss
+					order = append(order, key)
+				}
+				ss.Points = append(ss.Points, Point{T: ts, V: sample.Point.V})
+			}
+		}
+		sort.Strings(order)
+		mat := make(Matrix, 0, len(order))
+		for _, key := range order {
+			mat = append(mat, series[key])
+		}
+		return mat
+	})
+}
+
+type evaluator struct {
+	ctx        context.Context
+	lookback   int64
+	start, end int64
+	step       int64
+	series     map[*VectorSelector][]*Series
+}
+
+type evalError struct {
+	err *QueryError
+}
+
+func (ev *evaluator) errorf(format string, args ...interface{}) {
+	panic(evalError{err: &QueryError{Type: ErrorExec, Err: fmt.Errorf(format, args...)}})
+}
+
+func (ev *evaluator) checkContext() {
+	switch ev.ctx.Err() {
+	case nil:
+	case context.DeadlineExceeded:
+		panic(evalError{err: &QueryError{Type: ErrorTimeout, Err: fmt.Errorf("query timed out")}})
+	default:
+		panic(evalError{err: &QueryError{Type: ErrorCanceled, Err: fmt.Errorf("query was canceled")}})
+	}
+}
+
+func (ev *evaluator) run(fn func() Value) (val Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			ee, ok := r.(evalError)
+			if !ok {
+				panic(r)
+			}
+			val, err = nil, ee.err
+		}
+	}()
+	return fn(), nil
+}
+
+func (ng *Engine) newEvaluator(ctx context.Context, q Querier, expr Expr, start, end, step int64) (*evaluator, context.CancelFunc, error) {
+	cancel := context.CancelFunc(func() {})
+	if ng.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, ng.timeout)
+	}
+	ev := &evaluator{
+		ctx:      ctx,
+		lookback: ng.lookback,
+		start:    start,
+		end:      end,
+		step:     step,
+		series:   make(map[*VectorSelector][]*Series),
+	}
+	selectors := make(map[*VectorSelector]int64)
+	inspect(expr, func(node Expr) {
+		switch n := node.(type) {
+		case *MatrixSelector:
+			selectors[n.Vector] = durationMilliseconds(n.Range)
+		case *VectorSelector:
+			if _, ok := selectors[n]; !ok {
+				selectors[n] = ng.lookback
+			}
+		}
+	})
+	samples := 0
+	for vs, rng := range selectors {
+		offset := durationMilliseconds(vs.Offset)
+		hints := &SelectHints{
+			Start: start - offset - rng,
+			End:   end - offset,
+			Step:  step,
+			Range: rng,
+		}
+		series, err := q.Select(ctx, hints, vs.Matchers)
+		if err != nil {
+			cancel()
+			if ctx.Err() == context.DeadlineExceeded {
+				return nil, nil, &QueryError{Type: ErrorTimeout, Err: fmt.Errorf("query timed out in series fetching")}
+			}
+			return nil, nil, &QueryError{Type: ErrorExec, Err: err}
+		}
+		for _, s := range series {
+			samples += len(s.Points)
+		}
+		if ng.maxSamples > 0 && samples > ng.maxSamples {
+			cancel()
+			return nil, nil, &QueryError{Type: ErrorExec, Err: fmt.Errorf("query processing would load too many samples into memory")}
+		}
+		ev.series[vs] = series
+	}
+	return ev, cancel, nil
+}
+
+// inspect walks the expression tree in depth-first order.
+func inspect(expr Expr, fn func(Expr)) {
+	if expr == nil {
+		return
+	}
+	fn(expr)
+	switch n := expr.(type) {
+	case *MatrixSelector:
+		inspect(n.Vector, fn)
+	case *Call:
+		for _, arg := range n.Args {
+			inspect(arg, fn)
+		}
+	case *AggregateExpr:
+		inspect(n.Param, fn)
+		inspect(n.Expr, fn)
+	case *BinaryExpr:
+		inspect(n.LHS, fn)
+		inspect(n.RHS, fn)
+	case *UnaryExpr:
+		inspect(n.Expr, fn)
+	case *ParenExpr:
+		inspect(n.Expr, fn)
+	}
+}
+
+func (ev *evaluator) eval(expr Expr, ts int64) Value {
+	switch e := expr.(type) {
+	case *NumberLiteral:
+		return Scalar{T: ts, V: e.Val}
+	case *StringLiteral:
+		return String{T: ts, V: e.Val}
+	case *ParenExpr:
+		return ev.eval(e.Expr, ts)
+	case *VectorSelector:
+		return ev.vectorSelector(e, ts)
+	case *MatrixSelector:
+		return ev.matrixSelector(e, ts)
+	case *UnaryExpr:
+		switch v := ev.eval(e.Expr, ts).(type) {
+		case Scalar:
+			return Scalar{T: v.T, V: -v.V}
+		case Vector:
+			out := make(Vector, 0, len(v))
+			for _, s := range v {
+				out = append(out, Sample{Metric: s.Metric.WithoutName(), Point: Point{T: ts, V: -s.Point.V}})
+			}
+			return out
+		}
+	case *Call:
+		args := make([]Value, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = ev.eval(arg, ts)
+		}
+		return e.Func.Call(ev, e, args, ts)
+	case *AggregateExpr:
+		var param Value
+		if e.Param != nil {
+			param = ev.eval(e.Param, ts)
+		}
+		return ev.aggregate(e, param, ev.eval(e.Expr, ts).(Vector), ts)
+	case *BinaryExpr:
+		return ev.binary(e, ev.eval(e.LHS, ts), ev.eval(e.RHS, ts), ts)
+	}
+	ev.errorf("unhandled expression of type %T", expr)
+	return nil
+}
+
+func (ev *evaluator) vectorSelector(vs *VectorSelector, ts int64) Vector {
+	refTime := ts - durationMilliseconds(vs.Offset)
+	var vec Vector
+	for _, s := range ev.series[vs] {
+		// index of the first point after refTime
+		i := sort.Search(len(s.Points), func(i int) bool { return s.Points[i].T > refTime })
+		if i == 0 {
+			continue
+		}
+		p := s.Points[i-1]
+		if p.T <= refTime-ev.lookback {
+			continue
+		}
+		vec = append(vec, Sample{Metric: s.Metric, Point: Point{T: ts, V: p.V}})
+	}
+	return vec
+}
+
+func (ev *evaluator) matrixSelector(ms *MatrixSelector, ts int64) Matrix {
+	refTime := ts - durationMilliseconds(ms.Vector.Offset)
+	mint := refTime - durationMilliseconds(ms.Range)
+	var mat Matrix
+	for _, s := range ev.series[ms.Vector] {
+		from := sort.Search(len(s.Points), func(i int) bool { return s.Points[i].T > mint })
+		to := sort.Search(len(s.Points), func(i int) bool { return s.Points[i].T > refTime })
+		if from >= to {
+			continue
+		}
+		mat = append(mat, &Series{Metric: s.Metric, Points: s.Points[from:to]})
+	}
+	return mat
+}
+
+type aggregationGroup struct {
+	labels  Labels
+	value   float64
+	mean    float64
+	count   int
+	values  []float64
+	samples Vector
+}
+
+func (ev *evaluator) aggregate(e *AggregateExpr, param Value, vec Vector, ts int64) Vector {
+	var k int
+	var q float64
+	switch e.Op {
+	case "topk", "bottomk":
+		f := param.(Scalar).V
+		if math.IsNaN(f) || f < 1 {
+			return nil
+		}
+		k = int(f)
+	case "quantile":
+		q = param.(Scalar).V
+	}
+
+	without := append([]string{MetricNameLabel}, e.Grouping...)
+	groups := make(map[string]*aggregationGroup)
+	var order []string
+	for _, s := range vec {
+		var gl Labels
+		if e.Without {
+			gl = s.Metric.Without(without...)
+		} else {
+			gl = s.Metric.Only(e.Grouping...)
+		}
+		key := gl.Key()
+		g, ok := groups[key]
+		if !ok {
+			g = &aggregationGroup{labels: gl, value: s.Point.V, mean: s.Point.V, count: 1}
+			switch e.Op {
+			case "stddev", "stdvar":
+				g.value = 0
+			case "quantile":
+				g.values = []float64{s.Point.V}
+			case "topk", "bottomk":
+				g.samples = Vector{s}
+			case "group":
+				g.value = 1
+			}
+			groups[key] = g
+			order = append(order, key)
+			continue
+		}
+		g.count++
+		switch e.Op {
+		case "sum":
+			g.value += s.Point.V
+		case "avg":
+			g.mean += (s.Point.V - g.mean) / float64(g.count)
+		case "max":
+			if g.value < s.Point.V || math.IsNaN(g.value) {
+				g.value = s.Point.V
+			}
+		case "min":
+			if g.value > s.Point.V || math.IsNaN(g.value) {
+				g.value = s.Point.V
+			}
+		case "stddev", "stdvar":
+			delta := s.Point.V - g.mean
+			g.mean += delta / float64(g.count)
+			g.value += delta * (s.Point.V - g.mean)
+		case "quantile":
+			g.values = append(g.values, s.Point.V)
+		case "topk", "bottomk":
+			g.samples = append(g.samples, s)
+		}
+	}
+
+	var out Vector
+	for _, key := range order {
+		g := groups[key]
+		switch e.Op {
+		case "topk", "bottomk":
+			sort.SliceStable(g.samples, func(i, j int) bool {
+				if e.Op == "topk" {
+					return g.samples[i].Point.V > g.samples[j].Point.V || (math.IsNaN(g.samples[j].Point.V) && !math.IsNaN(g.samples[i].Point.V))
+				}
+				return g.samples[i].Point.V < g.samples[j].Point.V || (math.IsNaN(g.samples[j].Point.V) && !math.IsNaN(g.samples[i].Point.V))
+			})
+			if len(g.samples) > k {
+				g.samples = g.samples[:k]
+			}
+			for _, s := range g.samples {
+				out = append(out, Sample{Metric: s.Metric, Point: Point{T: ts, V: s.Point.V}})
+			}
+			continue
+		case "avg":
+			g.value = g.mean
+		case "count":
+			g.value = float64(g.count)
+		case "stddev":
+			g.value = math.Sqrt(g.value / float64(g.count))
+		case "stdvar":
+			g.value = g.value / float64(g.count)
+		case "quantile":
+			g.value = quantile(q, g.values)
+		}
+		out = append(out, Sample{Metric: g.labels, Point: Point{T: ts, V: g.value}})
+	}
+	return out
+}
+
+// quantile calculates the φ-quantile of the values using linear interpolation.
+func quantile(q float64, values []float64) float64 {
+	if len(values) == 0 || math.IsNaN(q) {
+		return math.NaN()
+	}
+	if q < 0 {
+		return math.Inf(-1)
+	}
+	if q > 1 {
+		return math.Inf(1)
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := float64(len(sorted))
+	rank := q * (n - 1)
+	lower := math.Max(0, math.Floor(rank))
+	upper := math.Min(n-1, lower+1)
+	weight := rank - math.Floor(rank)
+	return sorted[int(lower)]*(1-weight) + sorted[int(upper)]*weight
+}
+
+func (ev *evaluator) binary(e *BinaryExpr, lhs, rhs Value, ts int64) Value {
+	switch l := lhs.(type) {
+	case Scalar:
+		switch r := rhs.(type) {
+		case Scalar:
+			v, _ := binaryOp(e.Op, l.V, r.V)
+			if e.ReturnBool {
+				v = boolValue(v != 0)
+			}
+			return Scalar{T: ts, V: v}
+		case Vector:
+			return ev.vectorScalar(e, r, l.V, true, ts)
+		}
+	case Vector:
+		switch r := rhs.(type) {
+		case Scalar:
+			return ev.vectorScalar(e, l, r.V, false, ts)
+		case Vector:
+			if isSetOp(e.Op) {
+				return ev.vectorSet(e, l, r)
+			}
+			return ev.vectorVector(e, l, r, ts)
+		}
+	}
+	ev.errorf("invalid operands for binary operator %q", e.Op)
+	return nil
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// binaryOp applies the operator, the returned bool reports whether a comparison held.
+func binaryOp(op string, l, r float64) (float64, bool) {
+	switch op {
+	case "+":
+		return l + r, true
+	case "-":
+		return l - r, true
+	case "*":
+		return l * r, true
+	case "/":
+		return l / r, true
+	case "%":
+		return math.Mod(l, r), true
+	case "^":
+		return math.Pow(l, r), true
+	case "==":
+		return boolValue(l == r), l == r
+	case "!=":
+		return boolValue(l != r), l != r
+	case ">":
+		return boolValue(l > r), l > r
+	case "<":
+		return boolValue(l < r), l < r
+	case ">=":
+		return boolValue(l >= r), l >= r
+	case "<=":
+		return boolValue(l <= r), l <= r
+	}
+	return math.NaN(), false
+}
+
+func (ev *evaluator) vectorScalar(e *BinaryExpr, vec Vector, s float64, swap bool, ts int64) Vector {
+	comparison := isComparisonOp(e.Op)
+	var out Vector
+	for _, sample := range vec {
+		l, r := sample.Point.V, s
+		if swap {
+			l, r = r, l
+		}
+		v, keep := binaryOp(e.Op, l, r)
+		metric := sample.Metric
+		switch {
+		case comparison && !e.ReturnBool:
+			if !keep {
+				continue
+			}
+			v = sample.Point.V
+		default:
+			metric = metric.WithoutName()
+		}
+		out = append(out, Sample{Metric: metric, Point: Point{T: ts, V: v}})
+	}
+	return out
+}
+
+func matchingSignature(m *VectorMatching) func(Labels) string {
+	if m != nil && m.On {
+		return func(ls Labels) string { return ls.Only(m.Labels...).Key() }
+	}
+	var ignoring []string
+	if m != nil {
+		ignoring = m.Labels
+	}
+	names := append([]string{MetricNameLabel}, ignoring...)
+	return func(ls Labels) string { return ls.Without(names...).Key() }
+}
+
+func (ev *evaluator) vectorSet(e *BinaryExpr, lhs, rhs Vector) Vector {
+	sig := matchingSignature(e.Matching)
+	var out Vector
+	switch e.Op {
+	case "and", "unless":
+		rs := make(map[string]bool, len(rhs))
+		for _, s := range rhs {
+			rs[sig(s.Metric)] = true
+		}
+		for _, s := range lhs {
+			if rs[sig(s.Metric)] == (e.Op == "and") {
+				out = append(out, s)
+			}
+		}
+	case "or":
+		ls := make(map[string]bool, len(lhs))
+		for _, s := range lhs {
+			ls[sig(s.Metric)] = true
+			out = append(out, s)
+		}
+		for _, s := range rhs {
+			if !ls[sig(s.Metric)] {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+func (ev *evaluator) vectorVector(e *BinaryExpr, lhs, rhs Vector, ts int64) Vector {
+	sig := matchingSignature(e.Matching)
+	rs := make(map[string]Sample, len(rhs))
+	for _, s := range rhs {
+		key := sig(s.Metric)
+		if dup, ok := rs[key]; ok {
+			ev.errorf("found duplicate series for the match group on the right hand-side of the operation: [%s, %s]; many-to-many matching not allowed", labelsString(dup.Metric), labelsString(s.Metric))
+		}
+		rs[key] = s
+	}
+	comparison := isComparisonOp(e.Op)
+	matched := make(map[string]bool, len(lhs))
+	var out Vector
+	for _, ls := range lhs {
+		key := sig(ls.Metric)
+		rsample, ok := rs[key]
+		if !ok {
+			continue
+		}
+		if matched[key] {
+			ev.errorf("multiple matches for labels: many-to-one matching must be explicit (group_left/group_right)")
+		}
+		matched[key] = true
+		v, keep := binaryOp(e.Op, ls.Point.V, rsample.Point.V)
+		if comparison && !e.ReturnBool {
+			if !keep {
+				continue
+			}
+			v = ls.Point.V
+		}
+		metric := ls.Metric
+		if !comparison || e.ReturnBool {
+			metric = metric.WithoutName()
+		}
+		if e.Matching != nil {
+			if e.Matching.On {
+				metric = metric.Only(e.Matching.Labels...)
+			} else {
+				metric = metric.Without(e.Matching.Labels...)
+			}
+		}
+		out = append(out, Sample{Metric: metric, Point: Point{T: ts, V: v}})
+	}
+	return out
+}
+
+func labelsString(ls Labels) string {
+	return fmt.Sprint(ls.Map())
+}
@@ -0,0 +1,250 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type memoryQuerier struct {
+	series []*Series
+}
+
+func (q *memoryQuerier) Select(ctx context.Context, hints *SelectHints, matchers []*Matcher) ([]*Series, error) {
+	var result []*Series
+	for _, s := range q.series {
+		if !matchLabels(s.Metric, matchers) {
+			continue
+		}
+		out := &Series{Metric: s.Metric}
+		for _, p := range s.Points {
+			if p.T >= hints.Start && p.T <= hints.End {
+				out.Points = append(out.Points, p)
+			}
+		}
+		result = append(result, out)
+	}
+	return result, nil
+}
+
+func (q *memoryQuerier) Series(ctx context.Context, start, end time.Time, matcherSets [][]*Matcher) ([]Labels, error) {
+	return nil, nil
+}
+
+func (q *memoryQuerier) LabelNames(ctx context.Context, start, end time.Time, matchers []*Matcher) ([]string, error) {
+	return nil, nil
+}
+
+func (q *memoryQuerier) LabelValues(ctx context.Context, name string, start, end time.Time, matchers []*Matcher) ([]string, error) {
+	return nil, nil
+}
+
+func matchLabels(ls Labels, matchers []*Matcher) bool {
+	for _, m := range matchers {
+		if !m.Matches(ls.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// counter returns points every 15s from 0 to 10m increasing by 1.5 per point.
+func counter(labels map[string]string, perPoint float64) *Series {
+	s := &Series{Metric: NewLabels(labels)}
+	for i := 0; i <= 40; i++ {
+		s.Points = append(s.Points, Point{T: int64(i) * 15000, V: float64(i) * perPoint})
+	}
+	return s
+}
+
+func testQuerier() *memoryQuerier {
+	return &memoryQuerier{series: []*Series{
+		counter(map[string]string{MetricNameLabel: "http_requests_total", "job": "api", "instance": "a", "code": "200"}, 1.5),
+		counter(map[string]string{MetricNameLabel: "http_requests_total", "job": "api", "instance": "b", "code": "200"}, 3),
+		counter(map[string]string{MetricNameLabel: "http_requests_total", "job": "api", "instance": "b", "code": "500"}, 0.15),
+		{Metric: NewLabels(map[string]string{MetricNameLabel: "up", "job": "api", "instance": "a"}), Points: []Point{{T: 0, V: 1}, {T: 300000, V: 1}}},
+		{Metric: NewLabels(map[string]string{MetricNameLabel: "up", "job": "api", "instance": "b"}), Points: []Point{{T: 0, V: 1}, {T: 300000, V: 0}}},
+		{Metric: NewLabels(map[string]string{MetricNameLabel: "latency_bucket", "le": "0.1"}), Points: []Point{{T: 600000, V: 50}}},
+		{Metric: NewLabels(map[string]string{MetricNameLabel: "latency_bucket", "le": "0.5"}), Points: []Point{{T: 600000, V: 90}}},
+		{Metric: NewLabels(map[string]string{MetricNameLabel: "latency_bucket", "le": "+Inf"}), Points: []Point{{T: 600000, V: 100}}},
+	}}
+}
+
+func instant(t *testing.T, qs string, ts int64) Value {
+	val, err := NewEngine(EngineOptions{}).InstantQuery(context.Background(), testQuerier(), qs, time.Unix(0, ts*int64(time.Millisecond)))
+	require.NoError(t, err, qs)
+	return val
+}
+
+func TestEngine_InstantSelector(t *testing.T) {
+	vec := instant(t, `up{instance="b"}`, 400000).(Vector)
+	require.Len(t, vec, 1)
+	require.Equal(t, 0.0, vec[0].Point.V)
+	require.Equal(t, "up", vec[0].Metric.Get(MetricNameLabel))
+
+	// the latest sample is older than the lookback delta
+	vec = instant(t, `up`, 300000+int64(6*time.Minute/time.Millisecond)).(Vector)
+	require.Len(t, vec, 0)
+
+	vec = instant(t, `up offset 5m`, 400000).(Vector)
+	require.Len(t, vec, 2)
+	require.Equal(t, 1.0, vec[1].Point.V)
+}
+
+func TestEngine_Rate(t *testing.T) {
+	vec := instant(t, `rate(http_requests_total{instance="a"}[5m])`, 600000).(Vector)
+	require.Len(t, vec, 1)
+	require.InDelta(t, 0.1, vec[0].Point.V, 1e-9)
+	require.Empty(t, vec[0].Metric.Get(MetricNameLabel))
+
+	vec = instant(t, `increase(http_requests_total{instance="a"}[5m])`, 600000).(Vector)
+	require.InDelta(t, 30, vec[0].Point.V, 1e-9)
+
+	vec = instant(t, `irate(http_requests_total{instance="b", code="200"}[1m])`, 600000).(Vector)
+	require.InDelta(t, 0.2, vec[0].Point.V, 1e-9)
+}
+
+func TestEngine_CounterReset(t *testing.T) {
+	q := &memoryQuerier{series: []*Series{{
+		Metric: NewLabels(map[string]string{MetricNameLabel: "c"}),
+		Points: []Point{{T: 0, V: 10}, {T: 15000, V: 20}, {T: 30000, V: 5}, {T: 45000, V: 15}, {T: 60000, V: 25}},
+	}}}
+	val, err := NewEngine(EngineOptions{}).InstantQuery(context.Background(), q, `increase(c[1m])`, time.Unix(60, 0))
+	require.NoError(t, err)
+	// the reset adds 20 to the 5 increase sampled over 45s, extrapolated to the 60s range
+	require.InDelta(t, 25*60.0/45.0, val.(Vector)[0].Point.V, 1e-9)
+}
+
+func TestEngine_Aggregations(t *testing.T) {
+	vec := instant(t, `sum by (code) (rate(http_requests_total[5m]))`, 600000).(Vector)
+	require.Len(t, vec, 2)
+	require.Equal(t, "200", vec[0].Metric.Get("code"))
+	require.InDelta(t, 0.3, vec[0].Point.V, 1e-9)
+	require.InDelta(t, 0.01, vec[1].Point.V, 1e-9)
+	require.Equal(t, Labels{{Name: "code", Value: "200"}}, vec[0].Metric)
+
+	vec = instant(t, `count without (code) (http_requests_total)`, 600000).(Vector)
+	require.Len(t, vec, 2)
+	require.Equal(t, 1.0, vec[0].Point.V)
+	require.Equal(t, 2.0, vec[1].Point.V)
+
+	vec = instant(t, `topk(1, http_requests_total)`, 600000).(Vector)
+	require.Len(t, vec, 1)
+	require.Equal(t, "b", vec[0].Metric.Get("instance"))
+	require.Equal(t, "http_requests_total", vec[0].Metric.Get(MetricNameLabel))
+
+	vec = instant(t, `avg(up)`, 400000).(Vector)
+	require.Equal(t, 0.5, vec[0].Point.V)
+
+	vec = instant(t, `quantile(0.5, http_requests_total)`, 600000).(Vector)
+	require.Equal(t, 60.0, vec[0].Point.V)
+}
+
+func TestEngine_BinaryOperations(t *testing.T) {
+	vec := instant(t, `up == 0`, 400000).(Vector)
+	require.Len(t, vec, 1)
+	require.Equal(t, "b", vec[0].Metric.Get("instance"))
+	require.Equal(t, "up", vec[0].Metric.Get(MetricNameLabel))
+
+	vec = instant(t, `up > bool 0`, 400000).(Vector)
+	require.Len(t, vec, 2)
+	require.Empty(t, vec[0].Metric.Get(MetricNameLabel))
+
+	vec = instant(t, `sum by (instance) (http_requests_total) / on (instance) (up == 1)`, 400000).(Vector)
+	require.Len(t, vec, 1)
+	require.Equal(t, Labels{{Name: "instance", Value: "a"}}, vec[0].Metric)
+
+	vec = instant(t, `http_requests_total{code="200"} and up == 1`, 400000).(Vector)
+	require.Len(t, vec, 0)
+	vec = instant(t, `http_requests_total{code="200"} and ignoring (code) up == 1`, 400000).(Vector)
+	require.Len(t, vec, 1)
+	require.Equal(t, "a", vec[0].Metric.Get("instance"))
+
+	vec = instant(t, `up{instance="a"} or up`, 400000).(Vector)
+	require.Len(t, vec, 2)
+	vec = instant(t, `up unless up == 0`, 400000).(Vector)
+	require.Len(t, vec, 1)
+
+	_, err := NewEngine(EngineOptions{}).InstantQuery(context.Background(), testQuerier(), `http_requests_total + ignoring (code) up`, time.Unix(400, 0))
+	require.Error(t, err)
+	require.Equal(t, ErrorExec, err.(*QueryError).Type)
+}
+
+func TestEngine_Functions(t *testing.T) {
+	vec := instant(t, `histogram_quantile(0.5, latency_bucket)`, 600000).(Vector)
+	require.Len(t, vec, 1)
+	require.InDelta(t, 0.1, vec[0].Point.V, 1e-9)
+
+	vec = instant(t, `histogram_quantile(0.9, latency_bucket)`, 600000).(Vector)
+	require.InDelta(t, 0.5, vec[0].Point.V, 1e-9)
+
+	vec = instant(t, `max_over_time(http_requests_total{instance="a"}[1m])`, 600000).(Vector)
+	require.Equal(t, 60.0, vec[0].Point.V)
+
+	vec = instant(t, `count_over_time(http_requests_total{instance="a"}[1m])`, 600000).(Vector)
+	require.Equal(t, 4.0, vec[0].Point.V)
+
+	vec = instant(t, `clamp_max(http_requests_total, 10)`, 600000).(Vector)
+	for _, s := range vec {
+		require.LessOrEqual(t, s.Point.V, 10.0)
+	}
+
+	require.Equal(t, 600.0, instant(t, `time()`, 600000).(Scalar).V)
+	require.True(t, math.IsNaN(instant(t, `scalar(up)`, 400000).(Scalar).V))
+	require.Equal(t, 2.0, instant(t, `scalar(count(up))`, 400000).(Scalar).V)
+
+	vec = instant(t, `sort_desc(http_requests_total)`, 600000).(Vector)
+	require.Equal(t, 120.0, vec[0].Point.V)
+}
+
+func TestEngine_RangeQuery(t *testing.T) {
+	val, err := NewEngine(EngineOptions{}).RangeQuery(context.Background(), testQuerier(), `sum(up)`, time.Unix(0, 0), time.Unix(600, 0), time.Minute)
+	require.NoError(t, err)
+	mat := val.(Matrix)
+	require.Len(t, mat, 1)
+	// samples at 0 and 300s, both stale after 600s
+	require.Len(t, mat[0].Points, 10)
+	require.Equal(t, Point{T: 0, V: 2}, mat[0].Points[0])
+	require.Equal(t, Point{T: 300000, V: 1}, mat[0].Points[5])
+
+	val, err = NewEngine(EngineOptions{}).RangeQuery(context.Background(), testQuerier(), `1 + 1`, time.Unix(0, 0), time.Unix(120, 0), time.Minute)
+	require.NoError(t, err)
+	require.Len(t, val.(Matrix)[0].Points, 3)
+
+	_, err = NewEngine(EngineOptions{}).RangeQuery(context.Background(), testQuerier(), `up[5m]`, time.Unix(0, 0), time.Unix(120, 0), time.Minute)
+	require.Error(t, err)
+	require.Equal(t, ErrorBadData, err.(*QueryError).Type)
+}
+
+func TestEngine_MaxSamples(t *testing.T) {
+	_, err := NewEngine(EngineOptions{MaxSamples: 10}).InstantQuery(context.Background(), testQuerier(), `rate(http_requests_total[10m])`, time.Unix(600, 0))
+	require.Error(t, err)
+	require.Equal(t, ErrorExec, err.(*QueryError).Type)
+}
+
+func TestValue_MarshalJSON(t *testing.T) {
+	data, err := Vector{{Metric: Labels{{Name: "job", Value: "api"}}, Point: Point{T: 1500, V: math.Inf(1)}}}.MarshalJSON()
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"metric":{"job":"api"},"value":[1.5,"+Inf"]}]`, string(data))
+
+	data, err = Matrix(nil).MarshalJSON()
+	require.NoError(t, err)
+	require.Equal(t, "[]", string(data))
+}
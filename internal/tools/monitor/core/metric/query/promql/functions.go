@@ -0,0 +1,336 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Function describes a PromQL function.
+type Function struct {
+	Name       string
+	ArgTypes   []ValueType
+	Optional   int
+	ReturnType ValueType
+	Call       func(ev *evaluator, call *Call, args []Value, ts int64) Value
+}
+
+var functions = map[string]*Function{}
+
+func registerFunction(name string, args []ValueType, optional int, ret ValueType, fn func(ev *evaluator, call *Call, args []Value, ts int64) Value) {
+	functions[name] = &Function{Name: name, ArgTypes: args, Optional: optional, ReturnType: ret, Call: fn}
+}
+
+func init() {
+	matrix := []ValueType{ValueTypeMatrix}
+	vector := []ValueType{ValueTypeVector}
+
+	registerFunction("rate", matrix, 0, ValueTypeVector, extrapolatedRate(true, true))
+	registerFunction("increase", matrix, 0, ValueTypeVector, extrapolatedRate(true, false))
+	registerFunction("delta", matrix, 0, ValueTypeVector, extrapolatedRate(false, false))
+	registerFunction("irate", matrix, 0, ValueTypeVector, instantValue(true))
+	registerFunction("idelta", matrix, 0, ValueTypeVector, instantValue(false))
+
+	overTime := map[string]func(points []Point) float64{
+		"avg_over_time": func(points []Point) float64 {
+			var mean float64
+			for i, p := range points {
+				mean += (p.V - mean) / float64(i+1)
+			}
+			return mean
+		},
+		"sum_over_time": func(points []Point) float64 {
+			var sum float64
+			for _, p := range points {
+				sum += p.V
+			}
+			return sum
+		},
+		"min_over_time": func(points []Point) float64 {
+			min := points[0].V
+			for _, p := range points {
+				if p.V < min || math.IsNaN(min) {
+					min = p.V
+				}
+			}
+			return min
+		},
+		"max_over_time": func(points []Point) float64 {
+			max := points[0].V
+			for _, p := range points {
+				if p.V > max || math.IsNaN(max) {
+					max = p.V
+				}
+			}
+			return max
+		},
+		"count_over_time": func(points []Point) float64 {
+			return float64(len(points))
+		},
+		"last_over_time": func(points []Point) float64 {
+			return points[len(points)-1].V
+		},
+	}
+	for name, fn := range overTime {
+		registerFunction(name, matrix, 0, ValueTypeVector, aggrOverTime(fn))
+	}
+
+	simple := map[string]func(float64) float64{
+		"abs":   math.Abs,
+		"ceil":  math.Ceil,
+		"floor": math.Floor,
+		"exp":   math.Exp,
+		"ln":    math.Log,
+		"log2":  math.Log2,
+		"log10": math.Log10,
+		"sqrt":  math.Sqrt,
+	}
+	for name, fn := range simple {
+		fn := fn
+		registerFunction(name, vector, 0, ValueTypeVector, func(ev *evaluator, call *Call, args []Value, ts int64) Value {
+			return mapVector(args[0].(Vector), fn)
+		})
+	}
+	registerFunction("round", []ValueType{ValueTypeVector, ValueTypeScalar}, 1, ValueTypeVector, func(ev *evaluator, call *Call, args []Value, ts int64) Value {
+		toNearest := 1.0
+		if len(args) > 1 {
+			toNearest = args[1].(Scalar).V
+		}
+		inverse := 1 / toNearest
+		return mapVector(args[0].(Vector), func(v float64) float64 {
+			return math.Floor(v*inverse+0.5) / inverse
+		})
+	})
+	registerFunction("clamp_min", []ValueType{ValueTypeVector, ValueTypeScalar}, 0, ValueTypeVector, func(ev *evaluator, call *Call, args []Value, ts int64) Value {
+		min := args[1].(Scalar).V
+		return mapVector(args[0].(Vector), func(v float64) float64 { return math.Max(min, v) })
+	})
+	registerFunction("clamp_max", []ValueType{ValueTypeVector, ValueTypeScalar}, 0, ValueTypeVector, func(ev *evaluator, call *Call, args []Value, ts int64) Value {
+		max := args[1].(Scalar).V
+		return mapVector(args[0].(Vector), func(v float64) float64 { return math.Min(max, v) })
+	})
+	registerFunction("time", nil, 0, ValueTypeScalar, func(ev *evaluator, call *Call, args []Value, ts int64) Value {
+		return Scalar{T: ts, V: float64(ts) / 1000}
+	})
+	registerFunction("vector", []ValueType{ValueTypeScalar}, 0, ValueTypeVector, func(ev *evaluator, call *Call, args []Value, ts int64) Value {
+		return Vector{{Metric: Labels{}, Point: Point{T: ts, V: args[0].(Scalar).V}}}
+	})
+	registerFunction("scalar", vector, 0, ValueTypeScalar, func(ev *evaluator, call *Call, args []Value, ts int64) Value {
+		vec := args[0].(Vector)
+		if len(vec) != 1 {
+			return Scalar{T: ts, V: math.NaN()}
+		}
+		return Scalar{T: ts, V: vec[0].Point.V}
+	})
+	registerFunction("sort", vector, 0, ValueTypeVector, func(ev *evaluator, call *Call, args []Value, ts int64) Value {
+		return sortVector(args[0].(Vector), false)
+	})
+	registerFunction("sort_desc", vector, 0, ValueTypeVector, func(ev *evaluator, call *Call, args []Value, ts int64) Value {
+		return sortVector(args[0].(Vector), true)
+	})
+	registerFunction("histogram_quantile", []ValueType{ValueTypeScalar, ValueTypeVector}, 0, ValueTypeVector, histogramQuantile)
+}
+
+func mapVector(vec Vector, fn func(float64) float64) Vector {
+	out := make(Vector, 0, len(vec))
+	for _, s := range vec {
+		out = append(out, Sample{Metric: s.Metric.WithoutName(), Point: Point{T: s.Point.T, V: fn(s.Point.V)}})
+	}
+	return out
+}
+
+func sortVector(vec Vector, desc bool) Vector {
+	out := append(Vector(nil), vec...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if desc {
+			return out[i].Point.V > out[j].Point.V
+		}
+		return out[i].Point.V < out[j].Point.V
+	})
+	return out
+}
+
+func aggrOverTime(fn func(points []Point) float64) func(ev *evaluator, call *Call, args []Value, ts int64) Value {
+	return func(ev *evaluator, call *Call, args []Value, ts int64) Value {
+		var out Vector
+		for _, s := range args[0].(Matrix) {
+			if len(s.Points) == 0 {
+				continue
+			}
+			out = append(out, Sample{Metric: s.Metric.WithoutName(), Point: Point{T: ts, V: fn(s.Points)}})
+		}
+		return out
+	}
+}
+
+// extrapolatedRate implements rate, increase and delta, extrapolating the
+// result to the boundaries of the selected range like Prometheus does.
+func extrapolatedRate(isCounter, isRate bool) func(ev *evaluator, call *Call, args []Value, ts int64) Value {
+	return func(ev *evaluator, call *Call, args []Value, ts int64) Value {
+		ms := call.Args[0].(*MatrixSelector)
+		rangeEnd := ts - durationMilliseconds(ms.Vector.Offset)
+		rangeStart := rangeEnd - durationMilliseconds(ms.Range)
+		var out Vector
+		for _, s := range args[0].(Matrix) {
+			points := s.Points
+			if len(points) < 2 {
+				continue
+			}
+			first, last := points[0], points[len(points)-1]
+			result := last.V - first.V
+			if isCounter {
+				prev := first.V
+				for _, p := range points[1:] {
+					if p.V < prev {
+						result += prev
+					}
+					prev = p.V
+				}
+			}
+			durationToStart := float64(first.T-rangeStart) / 1000
+			durationToEnd := float64(rangeEnd-last.T) / 1000
+			sampledInterval := float64(last.T-first.T) / 1000
+			averageDurationBetweenSamples := sampledInterval / float64(len(points)-1)
+			if isCounter && result > 0 && first.V >= 0 {
+				durationToZero := sampledInterval * (first.V / result)
+				if durationToZero < durationToStart {
+					durationToStart = durationToZero
+				}
+			}
+			threshold := averageDurationBetweenSamples * 1.1
+			extrapolateToInterval := sampledInterval
+			if durationToStart < threshold {
+				extrapolateToInterval += durationToStart
+			} else {
+				extrapolateToInterval += averageDurationBetweenSamples / 2
+			}
+			if durationToEnd < threshold {
+				extrapolateToInterval += durationToEnd
+			} else {
+				extrapolateToInterval += averageDurationBetweenSamples / 2
+			}
+			result = result * (extrapolateToInterval / sampledInterval)
+			if isRate {
+				result = result / ms.Range.Seconds()
+			}
+			out = append(out, Sample{Metric: s.Metric.WithoutName(), Point: Point{T: ts, V: result}})
+		}
+		return out
+	}
+}
+
+// instantValue implements irate and idelta from the last two samples.
+func instantValue(isRate bool) func(ev *evaluator, call *Call, args []Value, ts int64) Value {
+	return func(ev *evaluator, call *Call, args []Value, ts int64) Value {
+		var out Vector
+		for _, s := range args[0].(Matrix) {
+			if len(s.Points) < 2 {
+				continue
+			}
+			last, prev := s.Points[len(s.Points)-1], s.Points[len(s.Points)-2]
+			result := last.V - prev.V
+			if isRate {
+				if last.V < prev.V {
+					result = last.V
+				}
+				interval := last.T - prev.T
+				if interval == 0 {
+					continue
+				}
+				result = result / (float64(interval) / 1000)
+			}
+			out = append(out, Sample{Metric: s.Metric.WithoutName(), Point: Point{T: ts, V: result}})
+		}
+		return out
+	}
+}
+
+type bucket struct {
+	upperBound float64
+	count      float64
+}
+
+func histogramQuantile(ev *evaluator, call *Call, args []Value, ts int64) Value {
+	q := args[0].(Scalar).V
+	type group struct {
+		labels  Labels
+		buckets []bucket
+	}
+	groups := make(map[string]*group)
+	var order []string
+	for _, s := range args[1].(Vector) {
+		upperBound, err := strconv.ParseFloat(s.Metric.Get("le"), 64)
+		if err != nil {
+			continue
+		}
+		labels := s.Metric.Without(MetricNameLabel, "le")
+		key := labels.Key()
+		g, ok := groups[key]
+		if !ok {
+			g = &group{labels: labels}
+			groups[key] = g
+			order = append(order, key)
+		}
+		g.buckets = append(g.buckets, bucket{upperBound: upperBound, count: s.Point.V})
+	}
+	var out Vector
+	for _, key := range order {
+		g := groups[key]
+		out = append(out, Sample{Metric: g.labels, Point: Point{T: ts, V: bucketQuantile(q, g.buckets)}})
+	}
+	return out
+}
+
+func bucketQuantile(q float64, buckets []bucket) float64 {
+	if q < 0 {
+		return math.Inf(-1)
+	}
+	if q > 1 {
+		return math.Inf(1)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].upperBound < buckets[j].upperBound })
+	if len(buckets) < 2 || !math.IsInf(buckets[len(buckets)-1].upperBound, 1) {
+		return math.NaN()
+	}
+	// counts of cumulative buckets must never decrease
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i].count < buckets[i-1].count {
+			buckets[i].count = buckets[i-1].count
+		}
+	}
+	observations := buckets[len(buckets)-1].count
+	if observations == 0 {
+		return math.NaN()
+	}
+	rank := q * observations
+	b := sort.Search(len(buckets)-1, func(i int) bool { return buckets[i].count >= rank })
+	if b == len(buckets)-1 {
+		return buckets[len(buckets)-2].upperBound
+	}
+	if b == 0 && buckets[0].upperBound <= 0 {
+		return buckets[0].upperBound
+	}
+	var bucketStart float64
+	bucketEnd := buckets[b].upperBound
+	count := buckets[b].count
+	if b > 0 {
+		bucketStart = buckets[b-1].upperBound
+		count -= buckets[b-1].count
+		rank -= buckets[b-1].count
+	}
+	return bucketStart + (bucketEnd-bucketStart)*(rank/count)
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"sort"
+	"strings"
+)
+
+// MetricNameLabel is the reserved label holding the metric name.
+const MetricNameLabel = "__name__"
+
+// Label is a name/value pair attached to a series.
+type Label struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Labels is a set of labels sorted by name.
+type Labels []Label
+
+// NewLabels builds sorted labels from a map, dropping empty values.
+func NewLabels(m map[string]string) Labels {
+	ls := make(Labels, 0, len(m))
+	for k, v := range m {
+		if len(v) == 0 {
+			continue
+		}
+		ls = append(ls, Label{Name: k, Value: v})
+	}
+	sort.Slice(ls, func(i, j int) bool { return ls[i].Name < ls[j].Name })
+	return ls
+}
+
+// Get returns the value of the label, or an empty string.
+func (ls Labels) Get(name string) string {
+	for _, l := range ls {
+		if l.Name == name {
+			return l.Value
+		}
+	}
+	return ""
+}
+
+// Map returns the labels as a map.
+func (ls Labels) Map() map[string]string {
+	m := make(map[string]string, len(ls))
+	for _, l := range ls {
+		m[l.Name] = l.Value
+	}
+	return m
+}
+
+// Key returns a string which uniquely identifies the label set.
+func (ls Labels) Key() string {
+	var sb strings.Builder
+	for _, l := range ls {
+		sb.WriteString(l.Name)
+		sb.WriteByte('\xff')
+		sb.WriteString(l.Value)
+		sb.WriteByte('\xff')
+	}
+	return sb.String()
+}
+
+// WithoutName returns a copy of the labels without the metric name.
+func (ls Labels) WithoutName() Labels {
+	return ls.Without(MetricNameLabel)
+}
+
+// Without returns a copy of the labels without the given names.
+func (ls Labels) Without(names ...string) Labels {
+	out := make(Labels, 0, len(ls))
+	for _, l := range ls {
+		if containsString(names, l.Name) {
+			continue
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+// Only returns a copy of the labels restricted to the given names.
+func (ls Labels) Only(names ...string) Labels {
+	out := make(Labels, 0, len(names))
+	for _, l := range ls {
+		if containsString(names, l.Name) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// SanitizeLabelName converts a tag key into a valid Prometheus label name.
+func SanitizeLabelName(name string) string {
+	if len(name) == 0 {
+		return "_"
+	}
+	b := []byte(name)
+	for i, c := range b {
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		b[i] = '_'
+	}
+	return string(b)
+}
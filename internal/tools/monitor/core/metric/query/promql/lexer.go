@@ -0,0 +1,219 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdentifier
+	tokenNumber
+	tokenDuration
+	tokenString
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenLeftBrace
+	tokenRightBrace
+	tokenLeftBracket
+	tokenRightBracket
+	tokenComma
+	tokenAssign
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", t.val)
+}
+
+// lex splits the input into tokens.
+func lex(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(input) && input[i] != '\n' {
+				i++
+			}
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLeftParen, val: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRightParen, val: ")", pos: i})
+			i++
+		case c == '{':
+			tokens = append(tokens, token{kind: tokenLeftBrace, val: "{", pos: i})
+			i++
+		case c == '}':
+			tokens = append(tokens, token{kind: tokenRightBrace, val: "}", pos: i})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{kind: tokenLeftBracket, val: "[", pos: i})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{kind: tokenRightBracket, val: "]", pos: i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokenComma, val: ",", pos: i})
+			i++
+		case c == '"' || c == '\'' || c == '`':
+			s, n, err := lexString(input[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%s at position %d", err, i)
+			}
+			tokens = append(tokens, token{kind: tokenString, val: s, pos: i})
+			i += n
+		case isDigit(c) || (c == '.' && i+1 < len(input) && isDigit(input[i+1])):
+			start := i
+			for i < len(input) && (isAlphaNumeric(input[i]) || input[i] == '.' ||
+				((input[i] == '+' || input[i] == '-') && (input[i-1] == 'e' || input[i-1] == 'E') && !isDurationText(input[start:i]))) {
+				i++
+			}
+			text := input[start:i]
+			if isDurationText(text) {
+				tokens = append(tokens, token{kind: tokenDuration, val: text, pos: start})
+				continue
+			}
+			if _, err := strconv.ParseFloat(text, 64); err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", text, start)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, val: text, pos: start})
+		case isAlpha(c) || c == ':':
+			start := i
+			for i < len(input) && (isAlphaNumeric(input[i]) || input[i] == ':') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdentifier, val: input[start:i], pos: start})
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", ">=", "<=", "=~", "!~", "+", "-", "*", "/", "%", "^", ">", "<", "="} {
+				if strings.HasPrefix(input[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if len(op) == 0 {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			kind := tokenOperator
+			if op == "=" {
+				kind = tokenAssign
+			}
+			tokens = append(tokens, token{kind: kind, val: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(input)}), nil
+}
+
+func lexString(input string) (string, int, error) {
+	quote := input[0]
+	if quote == '`' {
+		end := strings.IndexByte(input[1:], '`')
+		if end < 0 {
+			return "", 0, fmt.Errorf("unterminated raw string")
+		}
+		return input[1 : end+1], end + 2, nil
+	}
+	for i := 1; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			i++
+		case quote:
+			raw := input[:i+1]
+			if quote == '\'' {
+				raw = `"` + strings.ReplaceAll(strings.ReplaceAll(raw[1:i], `\'`, `'`), `"`, `\"`) + `"`
+			}
+			s, err := strconv.Unquote(raw)
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid string %s", input[:i+1])
+			}
+			return s, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isAlpha(c byte) bool { return c == '_' || unicode.IsLetter(rune(c)) }
+
+func isAlphaNumeric(c byte) bool { return isAlpha(c) || isDigit(c) }
+
+var durationUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+	"y":  365 * 24 * time.Hour,
+}
+
+func isDurationText(s string) bool {
+	_, err := ParseDuration(s)
+	return err == nil
+}
+
+// ParseDuration parses a Prometheus duration such as 1h30m or 5m.
+func ParseDuration(s string) (time.Duration, error) {
+	if len(s) == 0 {
+		return 0, fmt.Errorf("empty duration")
+	}
+	var total time.Duration
+	for len(s) > 0 {
+		i := 0
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+		if i == 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		n, err := strconv.ParseInt(s[:i], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		j := i
+		for j < len(s) && !isDigit(s[j]) {
+			j++
+		}
+		unit, ok := durationUnits[s[i:j]]
+		if !ok {
+			return 0, fmt.Errorf("invalid duration unit %q", s[i:j])
+		}
+		total += time.Duration(n) * unit
+		s = s[j:]
+	}
+	return total, nil
+}
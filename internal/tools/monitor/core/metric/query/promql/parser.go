@@ -0,0 +1,450 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var aggregators = map[string]bool{
+	"sum": true, "avg": true, "min": true, "max": true, "count": true, "group": true,
+	"stddev": true, "stdvar": true, "topk": true, "bottomk": true, "quantile": true,
+}
+
+var aggregatorsWithParam = map[string]ValueType{
+	"topk":     ValueTypeScalar,
+	"bottomk":  ValueTypeScalar,
+	"quantile": ValueTypeScalar,
+}
+
+var binaryPrecedence = map[string]int{
+	"or":     1,
+	"and":    2,
+	"unless": 2,
+	"==":     3,
+	"!=":     3,
+	">":      3,
+	"<":      3,
+	">=":     3,
+	"<=":     3,
+	"+":      4,
+	"-":      4,
+	"*":      5,
+	"/":      5,
+	"%":      5,
+	"^":      6,
+}
+
+func isComparisonOp(op string) bool {
+	switch op {
+	case "==", "!=", ">", "<", ">=", "<=":
+		return true
+	}
+	return false
+}
+
+func isSetOp(op string) bool {
+	return op == "and" || op == "or" || op == "unless"
+}
+
+// ParseError is returned for invalid expressions.
+type ParseError struct {
+	Pos int
+	Err string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parse error at char %d: %s", e.Pos+1, e.Err)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// ParseExpr parses a PromQL expression.
+func ParseExpr(input string) (expr Expr, err error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, &ParseError{Err: err.Error()}
+	}
+	p := &parser{tokens: tokens}
+	defer func() {
+		if r := recover(); r != nil {
+			pe, ok := r.(*ParseError)
+			if !ok {
+				panic(r)
+			}
+			expr, err = nil, pe
+		}
+	}()
+	expr = p.parseExpr(0)
+	if t := p.peek(); t.kind != tokenEOF {
+		p.errorf(t, "unexpected %s", t)
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(kind tokenKind, context string) token {
+	t := p.next()
+	if t.kind != kind {
+		p.errorf(t, "unexpected %s in %s", t, context)
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) {
+	panic(&ParseError{Pos: t.pos, Err: fmt.Sprintf(format, args...)})
+}
+
+// binaryOp returns the binary operator at the current position, if any.
+func (p *parser) binaryOp() (string, bool) {
+	t := p.peek()
+	switch t.kind {
+	case tokenOperator:
+		_, ok := binaryPrecedence[t.val]
+		return t.val, ok
+	case tokenIdentifier:
+		op := strings.ToLower(t.val)
+		if isSetOp(op) {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseExpr(minPrec int) Expr {
+	lhs := p.parseUnary()
+	for {
+		op, ok := p.binaryOp()
+		if !ok {
+			return lhs
+		}
+		prec := binaryPrecedence[op]
+		if prec < minPrec {
+			return lhs
+		}
+		opToken := p.next()
+		bin := &BinaryExpr{Op: op, LHS: lhs}
+		p.parseBinaryModifiers(bin)
+		next := prec + 1
+		if op == "^" {
+			next = prec
+		}
+		bin.RHS = p.parseExpr(next)
+		p.checkBinary(opToken, bin)
+		lhs = bin
+	}
+}
+
+func (p *parser) parseBinaryModifiers(bin *BinaryExpr) {
+	if t := p.peek(); t.kind == tokenIdentifier && strings.ToLower(t.val) == "bool" {
+		p.next()
+		if !isComparisonOp(bin.Op) {
+			p.errorf(t, "bool modifier can only be used on comparison operators")
+		}
+		bin.ReturnBool = true
+	}
+	t := p.peek()
+	if t.kind != tokenIdentifier {
+		return
+	}
+	switch strings.ToLower(t.val) {
+	case "on", "ignoring":
+		p.next()
+		bin.Matching = &VectorMatching{On: strings.ToLower(t.val) == "on", Labels: p.parseLabelList()}
+		if t := p.peek(); t.kind == tokenIdentifier {
+			switch strings.ToLower(t.val) {
+			case "group_left", "group_right":
+				p.errorf(t, "%s is not supported", t.val)
+			}
+		}
+	}
+}
+
+func (p *parser) checkBinary(t token, bin *BinaryExpr) {
+	lt, rt := bin.LHS.Type(), bin.RHS.Type()
+	for _, vt := range []ValueType{lt, rt} {
+		if vt != ValueTypeScalar && vt != ValueTypeVector {
+			p.errorf(t, "binary expression must contain only scalar and instant vector types")
+		}
+	}
+	if isSetOp(bin.Op) && (lt != ValueTypeVector || rt != ValueTypeVector) {
+		p.errorf(t, "set operator %q not allowed in binary scalar expression", bin.Op)
+	}
+	if isComparisonOp(bin.Op) && !bin.ReturnBool && lt == ValueTypeScalar && rt == ValueTypeScalar {
+		p.errorf(t, "comparisons between scalars must use BOOL modifier")
+	}
+	if bin.Matching != nil && (lt != ValueTypeVector || rt != ValueTypeVector) {
+		p.errorf(t, "vector matching only allowed between instant vectors")
+	}
+}
+
+func (p *parser) parseUnary() Expr {
+	t := p.peek()
+	if t.kind == tokenOperator && (t.val == "-" || t.val == "+") {
+		p.next()
+		// unary operators bind tighter than everything except ^
+		expr := p.parseExpr(binaryPrecedence["^"])
+		if vt := expr.Type(); vt != ValueTypeScalar && vt != ValueTypeVector {
+			p.errorf(t, "unary expression only allowed on expressions of type scalar or instant vector")
+		}
+		if t.val == "+" {
+			return expr
+		}
+		if n, ok := expr.(*NumberLiteral); ok {
+			n.Val = -n.Val
+			return n
+		}
+		return &UnaryExpr{Op: t.val, Expr: expr}
+	}
+	return p.parsePostfix(p.parsePrimary())
+}
+
+func (p *parser) parsePostfix(expr Expr) Expr {
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tokenLeftBracket:
+			vs, ok := expr.(*VectorSelector)
+			if !ok || vs.Offset != 0 {
+				p.errorf(t, "ranges only allowed for vector selectors")
+			}
+			p.next()
+			d := p.parseDuration()
+			p.expect(tokenRightBracket, "range selector")
+			expr = &MatrixSelector{Vector: vs, Range: d}
+		case t.kind == tokenIdentifier && strings.ToLower(t.val) == "offset":
+			p.next()
+			d := p.parseDuration()
+			switch e := expr.(type) {
+			case *VectorSelector:
+				e.Offset = d
+			case *MatrixSelector:
+				e.Vector.Offset = d
+			default:
+				p.errorf(t, "offset modifier must be preceded by a vector or range selector")
+			}
+		default:
+			return expr
+		}
+	}
+}
+
+func (p *parser) parseDuration() time.Duration {
+	t := p.next()
+	if t.kind != tokenDuration {
+		p.errorf(t, "unexpected %s, expected duration", t)
+	}
+	v, err := ParseDuration(t.val)
+	if err != nil {
+		p.errorf(t, "%s", err)
+	}
+	if v <= 0 {
+		p.errorf(t, "duration must be greater than 0")
+	}
+	return v
+}
+
+func (p *parser) parsePrimary() Expr {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		v, _ := strconv.ParseFloat(t.val, 64)
+		return &NumberLiteral{Val: v}
+	case tokenString:
+		return &StringLiteral{Val: t.val}
+	case tokenLeftParen:
+		expr := p.parseExpr(0)
+		p.expect(tokenRightParen, "parenthesized expression")
+		return &ParenExpr{Expr: expr}
+	case tokenLeftBrace:
+		p.pos--
+		return p.parseVectorSelector("", t)
+	case tokenIdentifier:
+		name := t.val
+		lower := strings.ToLower(name)
+		if aggregators[lower] && (p.peek().kind == tokenLeftParen || isGroupingKeyword(p.peek())) {
+			return p.parseAggregate(lower, t)
+		}
+		if p.peek().kind == tokenLeftParen {
+			return p.parseCall(name, t)
+		}
+		switch lower {
+		case "inf":
+			return &NumberLiteral{Val: math.Inf(1)}
+		case "nan":
+			return &NumberLiteral{Val: math.NaN()}
+		}
+		return p.parseVectorSelector(name, t)
+	}
+	p.errorf(t, "unexpected %s", t)
+	return nil
+}
+
+func isGroupingKeyword(t token) bool {
+	if t.kind != tokenIdentifier {
+		return false
+	}
+	kw := strings.ToLower(t.val)
+	return kw == "by" || kw == "without"
+}
+
+func (p *parser) parseAggregate(op string, start token) Expr {
+	agg := &AggregateExpr{Op: op}
+	modifiers := false
+	if isGroupingKeyword(p.peek()) {
+		agg.Without = strings.ToLower(p.next().val) == "without"
+		agg.Grouping = p.parseLabelList()
+		modifiers = true
+	}
+	p.expect(tokenLeftParen, "aggregation")
+	if _, ok := aggregatorsWithParam[op]; ok {
+		agg.Param = p.parseExpr(0)
+		if agg.Param.Type() != ValueTypeScalar {
+			p.errorf(start, "expected type scalar in aggregation parameter, got %s", agg.Param.Type())
+		}
+		p.expect(tokenComma, "aggregation")
+	}
+	agg.Expr = p.parseExpr(0)
+	p.expect(tokenRightParen, "aggregation")
+	if !modifiers && isGroupingKeyword(p.peek()) {
+		agg.Without = strings.ToLower(p.next().val) == "without"
+		agg.Grouping = p.parseLabelList()
+	}
+	if agg.Expr.Type() != ValueTypeVector {
+		p.errorf(start, "expected type instant vector in aggregation expression, got %s", agg.Expr.Type())
+	}
+	return agg
+}
+
+func (p *parser) parseLabelList() []string {
+	p.expect(tokenLeftParen, "grouping")
+	var labels []string
+	for p.peek().kind != tokenRightParen {
+		t := p.expect(tokenIdentifier, "grouping")
+		labels = append(labels, t.val)
+		if p.peek().kind != tokenComma {
+			break
+		}
+		p.next()
+	}
+	p.expect(tokenRightParen, "grouping")
+	return labels
+}
+
+func (p *parser) parseCall(name string, start token) Expr {
+	fn, ok := functions[name]
+	if !ok {
+		p.errorf(start, "unknown function with name %q", name)
+	}
+	p.expect(tokenLeftParen, "function call")
+	var args []Expr
+	for p.peek().kind != tokenRightParen {
+		args = append(args, p.parseExpr(0))
+		if p.peek().kind != tokenComma {
+			break
+		}
+		p.next()
+	}
+	p.expect(tokenRightParen, "function call")
+	if len(args) < len(fn.ArgTypes)-fn.Optional || len(args) > len(fn.ArgTypes) {
+		p.errorf(start, "wrong number of arguments for function %s()", name)
+	}
+	for i, arg := range args {
+		if arg.Type() != fn.ArgTypes[i] {
+			p.errorf(start, "expected type %s in call to function %s(), got %s", fn.ArgTypes[i], name, arg.Type())
+		}
+	}
+	return &Call{Func: fn, Args: args}
+}
+
+func (p *parser) parseVectorSelector(name string, start token) Expr {
+	vs := &VectorSelector{Name: name}
+	if p.peek().kind == tokenLeftBrace {
+		p.next()
+		for p.peek().kind != tokenRightBrace {
+			vs.Matchers = append(vs.Matchers, p.parseMatcher())
+			if p.peek().kind != tokenComma {
+				break
+			}
+			p.next()
+		}
+		p.expect(tokenRightBrace, "label matching")
+	}
+	for _, m := range vs.Matchers {
+		if m.Name == MetricNameLabel {
+			if len(name) > 0 {
+				p.errorf(start, "metric name must not be set twice: %q", name)
+			}
+			if m.Type == MatchEqual {
+				vs.Name = m.Value
+			}
+		}
+	}
+	if len(name) > 0 {
+		m, _ := NewMatcher(MatchEqual, MetricNameLabel, name)
+		vs.Matchers = append([]*Matcher{m}, vs.Matchers...)
+	}
+	nonEmpty := false
+	for _, m := range vs.Matchers {
+		if !m.Matches("") {
+			nonEmpty = true
+			break
+		}
+	}
+	if !nonEmpty {
+		p.errorf(start, "vector selector must contain at least one non-empty matcher")
+	}
+	return vs
+}
+
+func (p *parser) parseMatcher() *Matcher {
+	name := p.expect(tokenIdentifier, "label matching")
+	op := p.next()
+	var mt MatchType
+	switch {
+	case op.kind == tokenAssign:
+		mt = MatchEqual
+	case op.kind == tokenOperator && op.val == "!=":
+		mt = MatchNotEqual
+	case op.kind == tokenOperator && op.val == "=~":
+		mt = MatchRegexp
+	case op.kind == tokenOperator && op.val == "!~":
+		mt = MatchNotRegexp
+	default:
+		p.errorf(op, "unexpected %s in label matching, expected label matching operator", op)
+	}
+	val := p.expect(tokenString, "label matching")
+	m, err := NewMatcher(mt, name.val, val.val)
+	if err != nil {
+		p.errorf(val, "%s", err)
+	}
+	return m
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExpr(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantType ValueType
+		wantErr  bool
+	}{
+		{input: `1 + 2 * 3`, wantType: ValueTypeScalar},
+		{input: `http_requests_total`, wantType: ValueTypeVector},
+		{input: `http_requests_total{job="api", code=~"5.."}`, wantType: ValueTypeVector},
+		{input: `{__name__="up", job!="x"}`, wantType: ValueTypeVector},
+		{input: `http_requests_total[5m] offset 1h`, wantType: ValueTypeMatrix},
+		{input: `rate(http_requests_total{job="api"}[1m30s])`, wantType: ValueTypeVector},
+		{input: `sum by (code) (rate(http_requests_total[5m]))`, wantType: ValueTypeVector},
+		{input: `sum(rate(http_requests_total[5m])) without (instance)`, wantType: ValueTypeVector},
+		{input: `topk(3, docker_container_summary:cpu_usage_percent)`, wantType: ValueTypeVector},
+		{input: `histogram_quantile(0.9, sum by (le) (rate(latency_bucket[5m])))`, wantType: ValueTypeVector},
+		{input: `a / on (instance) b`, wantType: ValueTypeVector},
+		{input: `a > bool 1`, wantType: ValueTypeVector},
+		{input: `a and b or c unless d`, wantType: ValueTypeVector},
+		{input: `-a`, wantType: ValueTypeVector},
+		{input: `2 ^ 3 ^ 2`, wantType: ValueTypeScalar},
+		{input: `time()`, wantType: ValueTypeScalar},
+		{input: `1 > 2`, wantErr: true},
+		{input: `1 and 2`, wantErr: true},
+		{input: `rate(a)`, wantErr: true},
+		{input: `unknown(a)`, wantErr: true},
+		{input: `a[5m][5m]`, wantErr: true},
+		{input: `{job=~".*"}`, wantErr: true},
+		{input: `a{b="c"`, wantErr: true},
+		{input: `a * on(x) group_left b`, wantErr: true},
+		{input: `sum(a`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			expr, err := ParseExpr(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantType, expr.Type())
+		})
+	}
+}
+
+func TestParseExprPrecedence(t *testing.T) {
+	expr, err := ParseExpr(`1 + 2 * 3 - 4`)
+	require.NoError(t, err)
+	v, err := NewEngine(EngineOptions{}).InstantQuery(context.Background(), nil, `1 + 2 * 3 - 4`, time.Unix(0, 0))
+	require.NoError(t, err)
+	require.Equal(t, 3.0, v.(Scalar).V)
+	require.Equal(t, "-", expr.(*BinaryExpr).Op)
+
+	v, err = NewEngine(EngineOptions{}).InstantQuery(context.Background(), nil, `2 ^ 3 ^ 2`, time.Unix(0, 0))
+	require.NoError(t, err)
+	require.Equal(t, 512.0, v.(Scalar).V)
+
+	v, err = NewEngine(EngineOptions{}).InstantQuery(context.Background(), nil, `-2 ^ 2`, time.Unix(0, 0))
+	require.NoError(t, err)
+	require.Equal(t, -4.0, v.(Scalar).V)
+}
+
+func TestParseVectorSelector(t *testing.T) {
+	expr, err := ParseExpr(`rate(docker_container_summary:rx_bytes{cluster_name="c1", pod_name=~"web-.*"}[5m] offset 10m)`)
+	require.NoError(t, err)
+	ms := expr.(*Call).Args[0].(*MatrixSelector)
+	require.Equal(t, 5*time.Minute, ms.Range)
+	require.Equal(t, 10*time.Minute, ms.Vector.Offset)
+	require.Equal(t, "docker_container_summary:rx_bytes", ms.Vector.Name)
+	require.Len(t, ms.Vector.Matchers, 3)
+	require.Equal(t, MetricNameLabel, ms.Vector.Matchers[0].Name)
+	require.True(t, ms.Vector.Matchers[2].Matches("web-1"))
+	require.False(t, ms.Vector.Matchers[2].Matches("api-web-1"))
+}
+
+func TestParseDuration(t *testing.T) {
+	d, err := ParseDuration("1h30m")
+	require.NoError(t, err)
+	require.Equal(t, 90*time.Minute, d)
+	d, err = ParseDuration("250ms")
+	require.NoError(t, err)
+	require.Equal(t, 250*time.Millisecond, d)
+	_, err = ParseDuration("5x")
+	require.Error(t, err)
+}
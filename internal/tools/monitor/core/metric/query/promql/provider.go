@@ -0,0 +1,77 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/erda-project/erda-infra/base/logs"
+	"github.com/erda-project/erda-infra/base/servicehub"
+	"github.com/erda-project/erda-infra/providers/clickhouse"
+	"github.com/erda-project/erda-infra/providers/httpserver"
+	"github.com/erda-project/erda-infra/providers/httpserver/interceptors"
+	"github.com/erda-project/erda/internal/core/org"
+	"github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/loader"
+)
+
+type config struct {
+	LookbackDelta time.Duration `file:"lookback_delta" default:"5m"`
+	QueryTimeout  time.Duration `file:"query_timeout" default:"2m"`
+	MaxSamples    int           `file:"max_samples" default:"5000000"`
+	MaxSeries     int           `file:"max_series" default:"10000"`
+	MaxPoints     int           `file:"max_points" default:"11000"`
+}
+
+type provider struct {
+	Cfg    *config
+	Log    logs.Logger
+	Loader loader.Interface `autowired:"clickhouse.table.loader@metric"`
+	Org    org.ClientInterface
+
+	clickhouse clickhouse.Interface
+	engine     *Engine
+}
+
+func (p *provider) Init(ctx servicehub.Context) error {
+	svc := ctx.Service("clickhouse@metric")
+	if svc == nil {
+		svc = ctx.Service("clickhouse")
+	}
+	if svc == nil {
+		return fmt.Errorf("service clickhouse is required")
+	}
+	p.clickhouse = svc.(clickhouse.Interface)
+	p.engine = NewEngine(EngineOptions{
+		LookbackDelta: p.Cfg.LookbackDelta,
+		MaxSamples:    p.Cfg.MaxSamples,
+		Timeout:       p.Cfg.QueryTimeout,
+	})
+
+	routes := ctx.Service("http-server", interceptors.Recover(p.Log), interceptors.CORS(true)).(httpserver.Router)
+	return p.initRoutes(routes)
+}
+
+func init() {
+	servicehub.Register("metric-query-promql", &servicehub.Spec{
+		Services:     []string{"metric-query-promql"},
+		Dependencies: []string{"http-server"},
+		Description:  "prometheus compatible query api over metrics stored in clickhouse",
+		ConfigFunc:   func() interface{} { return &config{} },
+		Creator: func() servicehub.Provider {
+			return &provider{}
+		},
+	})
+}
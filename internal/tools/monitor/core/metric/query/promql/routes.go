@@ -0,0 +1,374 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erda-project/erda-infra/providers/httpserver"
+	orgpb "github.com/erda-project/erda-proto-go/core/org/pb"
+	"github.com/erda-project/erda/internal/tools/monitor/common"
+	"github.com/erda-project/erda/internal/tools/monitor/common/permission"
+	"github.com/erda-project/erda/pkg/common/apis"
+	api "github.com/erda-project/erda/pkg/common/httpapi"
+	"github.com/erda-project/erda/pkg/discover"
+)
+
+const apiPathPrefix = "/api/orgCenter/promql/api/v1"
+
+// defaultMetadataRange is the time range of metadata queries without start and end.
+const defaultMetadataRange = time.Hour
+
+func (p *provider) initRoutes(routes httpserver.Router) error {
+	checkOrg := permission.Intercepter(
+		permission.ScopeOrg, permission.OrgIDFromHeader(),
+		common.ResourceOrgCenter, permission.ActionGet, p.Org,
+	)
+	handlers := map[string]func(rw http.ResponseWriter, r *http.Request){
+		"/query":       p.query,
+		"/query_range": p.queryRange,
+		"/series":      p.series,
+		"/labels":      p.labelNames,
+	}
+	for path, handler := range handlers {
+		for _, method := range []string{http.MethodGet, http.MethodPost} {
+			if err := routes.Add(method, apiPathPrefix+path, handler, checkOrg); err != nil {
+				return err
+			}
+		}
+	}
+	routes.GET(apiPathPrefix+"/label/:name/values", p.labelValues, checkOrg)
+	return nil
+}
+
+type response struct {
+	Status    string      `json:"status"`
+	Data      interface{} `json:"data,omitempty"`
+	ErrorType ErrorType   `json:"errorType,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+type queryData struct {
+	ResultType ValueType `json:"resultType"`
+	Result     Value     `json:"result"`
+}
+
+func writeJSON(rw http.ResponseWriter, status int, resp *response) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	json.NewEncoder(rw).Encode(resp)
+}
+
+func writeData(rw http.ResponseWriter, data interface{}) {
+	writeJSON(rw, http.StatusOK, &response{Status: "success", Data: data})
+}
+
+func writeError(rw http.ResponseWriter, err error) {
+	qe, ok := err.(*QueryError)
+	if !ok {
+		qe = &QueryError{Type: ErrorInternal, Err: err}
+	}
+	status := http.StatusInternalServerError
+	switch qe.Type {
+	case ErrorBadData:
+		status = http.StatusBadRequest
+	case ErrorExec:
+		status = http.StatusUnprocessableEntity
+	case ErrorTimeout, ErrorCanceled:
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(rw, status, &response{Status: "error", ErrorType: qe.Type, Error: qe.Error()})
+}
+
+func badData(format string, args ...interface{}) error {
+	return &QueryError{Type: ErrorBadData, Err: fmt.Errorf(format, args...)}
+}
+
+// querier creates a querier on the metric table of the org of the request.
+func (p *provider) querier(r *http.Request) (Querier, error) {
+	orgName := r.Header.Get("org")
+	if len(orgName) == 0 {
+		orgID := api.OrgID(r)
+		if len(orgID) == 0 {
+			return nil, badData("missing org")
+		}
+		resp, err := p.Org.GetOrg(apis.WithInternalClientContext(context.Background(), discover.SvcMonitor), &orgpb.GetOrgRequest{
+			IdOrName: orgID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get org %s: %s", orgID, err)
+		}
+		orgName = resp.Data.Name
+	}
+	table, _ := p.Loader.GetSearchTable(orgName)
+	return newClickhouseQuerier(p.clickhouse.Client().Query, table, orgName, p.Cfg.MaxSeries), nil
+}
+
+func (p *provider) query(rw http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(rw, badData("%s", err))
+		return
+	}
+	ts, err := parseTimeParam(r, "time", time.Now())
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	q, err := p.querier(r)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	ctx, cancel, err := queryContext(r)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	defer cancel()
+	val, err := p.engine.InstantQuery(ctx, q, r.Form.Get("query"), ts)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	writeData(rw, &queryData{ResultType: val.Type(), Result: val})
+}
+
+func (p *provider) queryRange(rw http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(rw, badData("%s", err))
+		return
+	}
+	start, err := parseTime(r.Form.Get("start"))
+	if err != nil {
+		writeError(rw, badData("invalid parameter \"start\": %s", err))
+		return
+	}
+	end, err := parseTime(r.Form.Get("end"))
+	if err != nil {
+		writeError(rw, badData("invalid parameter \"end\": %s", err))
+		return
+	}
+	if end.Before(start) {
+		writeError(rw, badData("end timestamp must not be before start time"))
+		return
+	}
+	step, err := parseDurationParam(r.Form.Get("step"))
+	if err != nil {
+		writeError(rw, badData("invalid parameter \"step\": %s", err))
+		return
+	}
+	if step <= 0 {
+		writeError(rw, badData("zero or negative query resolution step widths are not accepted. Try a positive integer"))
+		return
+	}
+	if p.Cfg.MaxPoints > 0 && end.Sub(start)/step > time.Duration(p.Cfg.MaxPoints) {
+		writeError(rw, badData("exceeded maximum resolution of %d points per timeseries. Try decreasing the query resolution (?step=XX)", p.Cfg.MaxPoints))
+		return
+	}
+	q, err := p.querier(r)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	ctx, cancel, err := queryContext(r)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	defer cancel()
+	val, err := p.engine.RangeQuery(ctx, q, r.Form.Get("query"), start, end, step)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	writeData(rw, &queryData{ResultType: val.Type(), Result: val})
+}
+
+func (p *provider) series(rw http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(rw, badData("%s", err))
+		return
+	}
+	if len(r.Form["match[]"]) == 0 {
+		writeError(rw, badData("no match[] parameter provided"))
+		return
+	}
+	start, end, err := parseMetadataRange(r)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	matcherSets, err := parseMatchersParam(r.Form["match[]"])
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	q, err := p.querier(r)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	list, err := q.Series(r.Context(), start, end, matcherSets)
+	if err != nil {
+		writeError(rw, &QueryError{Type: ErrorExec, Err: err})
+		return
+	}
+	if list == nil {
+		list = []Labels{}
+	}
+	writeData(rw, list)
+}
+
+func (p *provider) labelNames(rw http.ResponseWriter, r *http.Request) {
+	p.labels(rw, r, func(ctx context.Context, q Querier, start, end time.Time, matchers []*Matcher) ([]string, error) {
+		return q.LabelNames(ctx, start, end, matchers)
+	})
+}
+
+func (p *provider) labelValues(rw http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, apiPathPrefix+"/label/"), "/values")
+	if len(name) == 0 || strings.Contains(name, "/") {
+		writeError(rw, badData("invalid label name: %q", name))
+		return
+	}
+	p.labels(rw, r, func(ctx context.Context, q Querier, start, end time.Time, matchers []*Matcher) ([]string, error) {
+		return q.LabelValues(ctx, name, start, end, matchers)
+	})
+}
+
+func (p *provider) labels(rw http.ResponseWriter, r *http.Request, fn func(ctx context.Context, q Querier, start, end time.Time, matchers []*Matcher) ([]string, error)) {
+	if err := r.ParseForm(); err != nil {
+		writeError(rw, badData("%s", err))
+		return
+	}
+	start, end, err := parseMetadataRange(r)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	matcherSets, err := parseMatchersParam(r.Form["match[]"])
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	if len(matcherSets) > 1 {
+		writeError(rw, badData("only one match[] parameter is supported"))
+		return
+	}
+	var matchers []*Matcher
+	if len(matcherSets) > 0 {
+		matchers = matcherSets[0]
+	}
+	q, err := p.querier(r)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	list, err := fn(r.Context(), q, start, end, matchers)
+	if err != nil {
+		writeError(rw, &QueryError{Type: ErrorExec, Err: err})
+		return
+	}
+	if list == nil {
+		list = []string{}
+	}
+	writeData(rw, list)
+}
+
+func queryContext(r *http.Request) (context.Context, context.CancelFunc, error) {
+	ctx := r.Context()
+	if to := r.Form.Get("timeout"); len(to) > 0 {
+		timeout, err := parseDurationParam(to)
+		if err != nil {
+			return nil, nil, badData("invalid parameter \"timeout\": %s", err)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		return ctx, cancel, nil
+	}
+	return ctx, func() {}, nil
+}
+
+func parseMatchersParam(list []string) ([][]*Matcher, error) {
+	var sets [][]*Matcher
+	for _, s := range list {
+		expr, err := ParseExpr(s)
+		if err != nil {
+			return nil, badData("%s", err)
+		}
+		vs, ok := expr.(*VectorSelector)
+		if !ok {
+			return nil, badData("invalid series selector %q", s)
+		}
+		sets = append(sets, vs.Matchers)
+	}
+	return sets, nil
+}
+
+func parseMetadataRange(r *http.Request) (time.Time, time.Time, error) {
+	end, err := parseTimeParam(r, "end", time.Now())
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	start, err := parseTimeParam(r, "start", end.Add(-defaultMetadataRange))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return start, end, nil
+}
+
+func parseTimeParam(r *http.Request, name string, defaultValue time.Time) (time.Time, error) {
+	val := r.Form.Get(name)
+	if len(val) == 0 {
+		return defaultValue, nil
+	}
+	t, err := parseTime(val)
+	if err != nil {
+		return time.Time{}, badData("invalid parameter %q: %s", name, err)
+	}
+	return t, nil
+}
+
+// parseTime accepts unix timestamps in seconds and RFC3339 times.
+func parseTime(s string) (time.Time, error) {
+	if t, err := strconv.ParseFloat(s, 64); err == nil {
+		sec, frac := math.Modf(t)
+		return time.Unix(int64(sec), int64(math.Round(frac*1000))*int64(time.Millisecond)), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q to a valid timestamp", s)
+}
+
+// parseDurationParam accepts durations in seconds and Prometheus durations.
+func parseDurationParam(s string) (time.Duration, error) {
+	if d, err := strconv.ParseFloat(s, 64); err == nil {
+		if math.IsNaN(d) || math.IsInf(d, 0) || d > float64(math.MaxInt64)/float64(time.Second) {
+			return 0, fmt.Errorf("cannot parse %q to a valid duration", s)
+		}
+		return time.Duration(d * float64(time.Second)), nil
+	}
+	if d, err := ParseDuration(s); err == nil {
+		return d, nil
+	}
+	return 0, fmt.Errorf("cannot parse %q to a valid duration", s)
+}
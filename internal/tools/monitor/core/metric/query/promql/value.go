@@ -0,0 +1,110 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package promql
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Value is the result of evaluating an expression.
+type Value interface {
+	Type() ValueType
+}
+
+// Point is a sample value at a timestamp in milliseconds.
+type Point struct {
+	T int64
+	V float64
+}
+
+// MarshalJSON encodes the point as [<unix seconds>, "<value>"].
+func (p Point) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{float64(p.T) / 1000, formatFloat(p.V)})
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// MarshalJSON encodes the labels as an object.
+func (ls Labels) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ls.Map())
+}
+
+// Sample is a single sample of an instant vector.
+type Sample struct {
+	Metric Labels `json:"metric"`
+	Point  Point  `json:"value"`
+}
+
+// Series is a list of points belonging to one label set.
+type Series struct {
+	Metric Labels  `json:"metric"`
+	Points []Point `json:"values"`
+}
+
+// Scalar is a single float value.
+type Scalar Point
+
+// String is a single string value.
+type String struct {
+	T int64
+	V string
+}
+
+// Vector is an instant vector.
+type Vector []Sample
+
+// Matrix is a range vector.
+type Matrix []*Series
+
+// Type .
+func (Scalar) Type() ValueType { return ValueTypeScalar }
+
+// Type .
+func (String) Type() ValueType { return ValueTypeString }
+
+// Type .
+func (Vector) Type() ValueType { return ValueTypeVector }
+
+// Type .
+func (Matrix) Type() ValueType { return ValueTypeMatrix }
+
+// MarshalJSON .
+func (s Scalar) MarshalJSON() ([]byte, error) {
+	return Point(s).MarshalJSON()
+}
+
+// MarshalJSON .
+func (s String) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{float64(s.T) / 1000, s.V})
+}
+
+// MarshalJSON makes sure empty vectors are encoded as [] instead of null.
+func (v Vector) MarshalJSON() ([]byte, error) {
+	if v == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]Sample(v))
+}
+
+// MarshalJSON makes sure empty matrices are encoded as [] instead of null.
+func (m Matrix) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]*Series(m))
+}
@@ -40,6 +40,7 @@ import (
 	"github.com/erda-project/erda/internal/tools/monitor/core/metric/query/query"
 	"github.com/erda-project/erda/internal/tools/monitor/core/metric/storage"
 	"github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/meta"
+	"github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/rollup"
 	indexloader "github.com/erda-project/erda/internal/tools/monitor/core/storekit/elasticsearch/index/loader"
 	"github.com/erda-project/erda/pkg/common/apis"
 	"github.com/erda-project/erda/pkg/common/errors"
@@ -68,9 +69,10 @@ type provider struct {
 	metricService     *metricService
 	metricMetaService *metricMetaService
 
-	Storage         storage.Storage `autowired:"metric-storage" optional:"true"`
-	CkStorageReader storage.Storage `autowired:"metric-storage-clickhouse" optional:"true"`
-	CkMetaLoader    meta.Interface  `autowired:"clickhouse.meta.loader@metric" optional:"true"`
+	Storage         storage.Storage  `autowired:"metric-storage" optional:"true"`
+	CkStorageReader storage.Storage  `autowired:"metric-storage-clickhouse" optional:"true"`
+	CkMetaLoader    meta.Interface   `autowired:"clickhouse.meta.loader@metric" optional:"true"`
+	CkRollup        rollup.Interface `autowired:"clickhouse.table.rollup@metric" optional:"true"`
 }
 
 func (p *provider) Init(ctx servicehub.Context) error {
@@ -87,7 +89,7 @@ func (p *provider) Init(ctx servicehub.Context) error {
 	}
 	p.metricService = &metricService{
 		p:     p,
-		query: query.New(p.CkMetaLoader, p.Storage, p.CkStorageReader, p.Log),
+		query: query.New(p.CkMetaLoader, p.Storage, p.CkStorageReader, p.CkRollup, p.Log),
 	}
 	if p.Register != nil {
 		pb.RegisterMetricServiceImp(p.Register, p.metricService, apis.Options(),
@@ -33,6 +33,7 @@ import (
 	"github.com/erda-project/erda/internal/tools/monitor/core/metric/query/es-tsql/formats"
 	"github.com/erda-project/erda/internal/tools/monitor/core/metric/storage"
 	"github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/meta"
+	"github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/rollup"
 )
 
 type queryer struct {
@@ -40,14 +41,16 @@ type queryer struct {
 	ckStorage    storage.Storage `autowired:"metric-storage-clickhouse"`
 	log          logs.Logger
 	ckMetaLoader meta.Interface
+	ckRollup     rollup.Interface
 }
 
 // New .
-func New(meta meta.Interface, esStorage storage.Storage, ckStorage storage.Storage, log logs.Logger) Queryer {
+func New(meta meta.Interface, esStorage storage.Storage, ckStorage storage.Storage, ckRollup rollup.Interface, log logs.Logger) Queryer {
 	return &queryer{
 		ckMetaLoader: meta,
 		storage:      esStorage,
 		ckStorage:    ckStorage,
+		ckRollup:     ckRollup,
 		log:          log,
 	}
 }
@@ -204,6 +207,9 @@ func (q *queryer) GetResult(ctx context.Context, parser tsql.Parser) (*model.Res
 		metrics, err := parser.Metrics()
 		if err == nil {
 			if q.ckStorage.Select(metrics) {
+				if rp, ok := parser.(interface{ SetRollup(rollup.Interface) }); ok && q.ckRollup != nil {
+					rp.SetRollup(q.ckRollup)
+				}
 				queries, err := parser.ParseQuery(ctx, model.ClickhouseKind)
 				if err != nil {
 					return nil, nil, err
@@ -53,6 +53,10 @@ func (p *provider) Query(ctx context.Context, q tsql.Query) (*model.ResultSet, e
 	}
 
 	table, _ := p.Loader.GetSearchTable(orgs[0])
+	if rq, ok := q.(interface{ Table() string }); ok && len(rq.Table()) > 0 {
+		// the query planner chose a rollup table
+		table = rq.Table()
+	}
 
 	if len(q.OrgName()) > 0 {
 		// compatible erda and empty, erda components sometimes use empty and erda org
@@ -0,0 +1,179 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rollup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	ckapi "github.com/ClickHouse/clickhouse-go/v2"
+	cfgpkg "github.com/recallsong/go-utils/config"
+
+	"github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table"
+	"github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/loader"
+)
+
+var ddlRegex = regexp.MustCompile("[^;]+[;$]")
+
+func (p *provider) runManager(ctx context.Context) {
+	for _, r := range p.getResolutions() {
+		if err := p.createRollupTable(r); err != nil {
+			p.Log.Errorf("failed to create rollup table of resolution %s: %s", r.Name, err)
+		}
+	}
+
+	ticker := time.NewTicker(p.Cfg.TTLSyncInterval)
+	defer ticker.Stop()
+	for {
+		p.syncViews(p.Loader.WaitAndGetTables(ctx))
+		p.syncTTL(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *provider) createRollupTable(r *Resolution) error {
+	replacer := strings.NewReplacer(
+		table.DatabaseNameKey, p.Cfg.Database,
+		table.TableNameKey, r.Table(p.Cfg.TablePrefix),
+		table.TtlDaysNameKey, strconv.FormatInt(r.TTLDays(), 10),
+	)
+	return p.executeDDLs(p.Cfg.TableDDLFile, replacer)
+}
+
+// syncViews makes sure every raw write table feeds each resolution through a materialized view.
+func (p *provider) syncViews(tables map[string]*loader.TableMeta) {
+	for _, source := range p.sourceTables(tables) {
+		for _, r := range p.getResolutions() {
+			view := fmt.Sprintf("%s_mv_%s", r.Table(p.Cfg.TablePrefix), source)
+			if p.views[view] {
+				continue
+			}
+			replacer := strings.NewReplacer(
+				table.DatabaseNameKey, p.Cfg.Database,
+				table.TableNameKey, r.Table(p.Cfg.TablePrefix),
+				table.SourceTableNameKey, source,
+				table.ViewNameKey, view,
+				table.IntervalSecondsNameKey, strconv.FormatInt(int64(r.Interval/time.Second), 10),
+			)
+			if err := p.executeDDLs(p.Cfg.ViewDDLFile, replacer); err != nil {
+				p.Log.Errorf("failed to create rollup view %s: %s", view, err)
+				continue
+			}
+			p.views[view] = true
+		}
+	}
+}
+
+// sourceTables returns the local raw tables of the database, the ones having a distributed table.
+func (p *provider) sourceTables(tables map[string]*loader.TableMeta) []string {
+	var list []string
+	prefix := p.Cfg.Database + "."
+	for name, meta := range tables {
+		if !strings.HasPrefix(name, prefix) || meta == nil || !strings.Contains(meta.Engine, "MergeTree") {
+			continue
+		}
+		if _, ok := tables[name+"_all"]; !ok {
+			continue
+		}
+		list = append(list, strings.TrimPrefix(name, prefix))
+	}
+	return list
+}
+
+func (p *provider) syncTTL(ctx context.Context) {
+	for _, r := range p.getResolutions() {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		name := r.Table(p.Cfg.TablePrefix)
+		var createTableSQL string
+		err := p.Clickhouse.Client().QueryRow(ctx,
+			"SELECT create_table_query FROM system.tables WHERE database = ? AND name = ?", p.Cfg.Database, name).Scan(&createTableSQL)
+		if err != nil {
+			p.Log.Warnf("failed to load rollup table %s: %s", name, err)
+			continue
+		}
+		days, _ := strconv.ParseInt(loader.GetStringInBetween(createTableSQL, "toIntervalDay(", ")"), 10, 64)
+		if days == r.TTLDays() {
+			continue
+		}
+		sql := fmt.Sprintf("ALTER TABLE %s.%s ON CLUSTER '{cluster}' MODIFY TTL toDateTime(timestamp) + toIntervalDay(%d);", p.Cfg.Database, name, r.TTLDays())
+		err = p.Clickhouse.Client().Exec(ckapi.Context(ctx, ckapi.WithSettings(map[string]interface{}{
+			"materialize_ttl_after_modify": 0,
+		})), sql)
+		if err != nil {
+			p.Log.Warnf("failed to change ttl of rollup table[%s] to %v day, sql: %s, err: %s", name, r.TTLDays(), sql, err)
+			continue
+		}
+		p.Log.Infof("finish change ttl of rollup table[%s] from %v to %v day", name, days, r.TTLDays())
+	}
+}
+
+func (p *provider) executeDDLs(file string, replacer *strings.Replacer) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %s", file)
+	}
+	data = cfgpkg.EscapeEnv(data)
+	for _, ddl := range ddlRegex.FindAllString(string(data), -1) {
+		ddl = replacer.Replace(ddl)
+		if err := p.Clickhouse.Client().Exec(context.Background(), ddl); err != nil {
+			p.Log.Warnf("failed to execute ddl of file[%s], ddl: %s, err: %s", file, ddl, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// runRefresher tracks the earliest bucket of each rollup table,
+// so that queries never read a range the views have not populated.
+func (p *provider) runRefresher(ctx context.Context) error {
+	ticker := time.NewTicker(p.Cfg.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		var list []*Resolution
+		for _, r := range p.getResolutions() {
+			r := *r
+			var since time.Time
+			err := p.Clickhouse.Client().QueryRow(ctx, fmt.Sprintf("SELECT min(timestamp) FROM %s", p.SearchTable(&r))).Scan(&since)
+			if err != nil {
+				p.Log.Debugf("failed to load earliest bucket of rollup %s: %s", r.Name, err)
+			} else if since.Unix() > 0 {
+				// the first bucket may be partial, it was filled since the view was created
+				r.Since = since.Add(r.Interval)
+			}
+			list = append(list, &r)
+		}
+		p.resolutions.Store(list)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
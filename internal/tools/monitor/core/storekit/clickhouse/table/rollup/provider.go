@@ -0,0 +1,115 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rollup
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/erda-project/erda-infra/base/logs"
+	"github.com/erda-project/erda-infra/base/servicehub"
+	"github.com/erda-project/erda-infra/providers/clickhouse"
+	election "github.com/erda-project/erda-infra/providers/etcd-election"
+	"github.com/erda-project/erda/internal/tools/monitor/core/storekit/clickhouse/table/loader"
+)
+
+type resolutionConfig struct {
+	Name     string        `file:"name"`
+	Interval time.Duration `file:"interval"`
+	TTL      time.Duration `file:"ttl"`
+}
+
+type config struct {
+	Database        string             `file:"database" default:"monitor"`
+	TablePrefix     string             `file:"table_prefix"`
+	Resolutions     []resolutionConfig `file:"resolutions"`
+	ManageTables    bool               `file:"manage_tables" default:"false"`
+	TableDDLFile    string             `file:"table_ddl_file"`
+	ViewDDLFile     string             `file:"view_ddl_file"`
+	TTLSyncInterval time.Duration      `file:"ttl_sync_interval" default:"1h"`
+	RefreshInterval time.Duration      `file:"refresh_interval" default:"10m"`
+}
+
+type provider struct {
+	Cfg        *config
+	Log        logs.Logger
+	Clickhouse clickhouse.Interface `autowired:"clickhouse" inherit-label:"preferred"`
+	Loader     loader.Interface     `autowired:"clickhouse.table.loader" inherit-label:"true"`
+	Election   election.Interface   `autowired:"etcd-election@table-rollup" optional:"true"`
+
+	resolutions atomic.Value // []*Resolution
+	views       map[string]bool
+}
+
+func (p *provider) Init(ctx servicehub.Context) error {
+	if len(p.Cfg.TablePrefix) == 0 {
+		return fmt.Errorf("table_prefix is required")
+	}
+	var list []*Resolution
+	for _, rc := range p.Cfg.Resolutions {
+		list = append(list, &Resolution{Name: rc.Name, Interval: rc.Interval, TTL: rc.TTL})
+	}
+	list, err := NormalizeResolutions(list)
+	if err != nil {
+		return err
+	}
+	p.resolutions.Store(list)
+
+	if p.Cfg.ManageTables {
+		if p.Election == nil {
+			return fmt.Errorf("etcd-election@table-rollup is required to manage rollup tables")
+		}
+		if len(p.Cfg.TableDDLFile) == 0 || len(p.Cfg.ViewDDLFile) == 0 {
+			return fmt.Errorf("table_ddl_file and view_ddl_file are required to manage rollup tables")
+		}
+		p.views = make(map[string]bool)
+		p.Election.OnLeader(p.runManager)
+	}
+	ctx.AddTask(p.runRefresher, servicehub.WithTaskName("rollup availability refresher"))
+	return nil
+}
+
+func (p *provider) getResolutions() []*Resolution {
+	list, _ := p.resolutions.Load().([]*Resolution)
+	return list
+}
+
+func (p *provider) Plan(start, end time.Time, interval time.Duration) *Resolution {
+	if !end.After(start) {
+		return nil
+	}
+	return Choose(p.getResolutions(), time.Now(), start, interval)
+}
+
+func (p *provider) SearchTable(r *Resolution) string {
+	return fmt.Sprintf("%s.%s_all", p.Cfg.Database, r.Table(p.Cfg.TablePrefix))
+}
+
+func init() {
+	servicehub.Register("clickhouse.table.rollup", &servicehub.Spec{
+		Services: []string{"clickhouse.table.rollup"},
+		Types: []reflect.Type{
+			reflect.TypeOf((*Interface)(nil)).Elem(),
+		},
+		Dependencies:         []string{"clickhouse"},
+		OptionalDependencies: []string{"etcd-election"},
+		ConfigFunc:           func() interface{} { return &config{} },
+		Creator: func() servicehub.Provider {
+			return &provider{}
+		},
+	})
+}
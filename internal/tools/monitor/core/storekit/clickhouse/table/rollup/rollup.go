@@ -0,0 +1,103 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rollup
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Resolution is a pre-aggregated view of the raw metric tables.
+type Resolution struct {
+	Name     string
+	Interval time.Duration
+	TTL      time.Duration
+
+	// Since is the earliest bucket known to exist in the rollup table,
+	// queries starting before it must be served from raw data.
+	Since time.Time
+}
+
+// Table returns the local table name of the resolution.
+func (r *Resolution) Table(prefix string) string {
+	return fmt.Sprintf("rollup_%s_%s", prefix, r.Name)
+}
+
+// TTLDays returns the retention of the resolution in days, at least one day.
+func (r *Resolution) TTLDays() int64 {
+	days := int64(r.TTL / (24 * time.Hour))
+	if days <= 0 {
+		days = 1
+	}
+	return days
+}
+
+// Interface selects rollup tables for metric queries.
+type Interface interface {
+	// Plan returns the coarsest resolution able to answer a query over [start, end)
+	// bucketed by interval, or nil if the query must read raw data.
+	Plan(start, end time.Time, interval time.Duration) *Resolution
+	// SearchTable returns the distributed table to query for the resolution.
+	SearchTable(r *Resolution) string
+}
+
+// Choose returns the coarsest resolution whose interval divides the query interval
+// and whose retention still covers the query start.
+func Choose(resolutions []*Resolution, now, start time.Time, interval time.Duration) *Resolution {
+	if interval <= 0 {
+		return nil
+	}
+	var chosen *Resolution
+	for _, r := range resolutions {
+		if r.Interval <= 0 || r.Interval > interval || interval%r.Interval != 0 {
+			continue
+		}
+		if start.Before(now.Add(-r.TTL)) {
+			continue
+		}
+		if r.Since.IsZero() || start.Before(r.Since) {
+			continue
+		}
+		if chosen == nil || r.Interval > chosen.Interval {
+			chosen = r
+		}
+	}
+	return chosen
+}
+
+// NormalizeResolutions validates the resolutions and sorts them from fine to coarse.
+func NormalizeResolutions(resolutions []*Resolution) ([]*Resolution, error) {
+	names := make(map[string]bool)
+	for _, r := range resolutions {
+		if len(r.Name) == 0 {
+			return nil, fmt.Errorf("resolution name is required")
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("duplicate resolution %q", r.Name)
+		}
+		names[r.Name] = true
+		if r.Interval < time.Minute || r.Interval%time.Second != 0 {
+			return nil, fmt.Errorf("invalid interval %s of resolution %q", r.Interval, r.Name)
+		}
+		if r.TTL < r.Interval {
+			return nil, fmt.Errorf("ttl %s of resolution %q is shorter than its interval", r.TTL, r.Name)
+		}
+	}
+	sort.SliceStable(resolutions, func(i, j int) bool {
+		return resolutions[i].Interval < resolutions[j].Interval
+	})
+	return resolutions, nil
+}
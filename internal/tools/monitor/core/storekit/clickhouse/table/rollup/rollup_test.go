@@ -0,0 +1,86 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rollup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChoose(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	since := now.Add(-365 * 24 * time.Hour)
+	resolutions := []*Resolution{
+		{Name: "1m", Interval: time.Minute, TTL: 7 * 24 * time.Hour, Since: since},
+		{Name: "5m", Interval: 5 * time.Minute, TTL: 30 * 24 * time.Hour, Since: since},
+		{Name: "1h", Interval: time.Hour, TTL: 365 * 24 * time.Hour, Since: now.Add(-10 * 24 * time.Hour)},
+	}
+	tests := []struct {
+		name     string
+		start    time.Time
+		interval time.Duration
+		want     string
+	}{
+		{name: "raw interval", start: now.Add(-time.Hour), interval: 30 * time.Second},
+		{name: "one minute", start: now.Add(-time.Hour), interval: time.Minute, want: "1m"},
+		{name: "not divisible by coarser", start: now.Add(-time.Hour), interval: 3 * time.Minute, want: "1m"},
+		{name: "five minutes", start: now.Add(-time.Hour), interval: 10 * time.Minute, want: "5m"},
+		{name: "coarsest", start: now.Add(-24 * time.Hour), interval: 2 * time.Hour, want: "1h"},
+		{name: "expired fine resolution", start: now.Add(-8 * 24 * time.Hour), interval: time.Minute},
+		{name: "fallback on retention", start: now.Add(-8 * 24 * time.Hour), interval: 10 * time.Minute, want: "5m"},
+		{name: "fallback before populated", start: now.Add(-20 * 24 * time.Hour), interval: time.Hour, want: "5m"},
+		{name: "no interval", start: now.Add(-time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Choose(resolutions, now, tt.start, tt.interval)
+			if len(tt.want) == 0 {
+				require.Nil(t, r)
+				return
+			}
+			require.NotNil(t, r)
+			require.Equal(t, tt.want, r.Name)
+		})
+	}
+}
+
+func TestChooseUnpopulated(t *testing.T) {
+	now := time.Now()
+	r := Choose([]*Resolution{{Name: "1m", Interval: time.Minute, TTL: time.Hour}}, now, now.Add(-time.Minute), time.Minute)
+	require.Nil(t, r)
+}
+
+func TestNormalizeResolutions(t *testing.T) {
+	list, err := NormalizeResolutions([]*Resolution{
+		{Name: "1h", Interval: time.Hour, TTL: 24 * time.Hour},
+		{Name: "1m", Interval: time.Minute, TTL: 24 * time.Hour},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "1m", list[0].Name)
+	require.Equal(t, "rollup_metrics_1m", list[0].Table("metrics"))
+	require.Equal(t, int64(1), list[0].TTLDays())
+
+	_, err = NormalizeResolutions([]*Resolution{{Name: "1s", Interval: time.Second, TTL: time.Hour}})
+	require.Error(t, err)
+	_, err = NormalizeResolutions([]*Resolution{
+		{Name: "1m", Interval: time.Minute, TTL: time.Hour},
+		{Name: "1m", Interval: 2 * time.Minute, TTL: time.Hour},
+	})
+	require.Error(t, err)
+	_, err = NormalizeResolutions([]*Resolution{{Name: "1h", Interval: time.Hour, TTL: time.Minute}})
+	require.Error(t, err)
+}
@@ -21,11 +21,14 @@ import (
 )
 
 const (
-	TableNameKey          = "<table_name>"
-	AliasTableNameKey     = "<alias_table_name>"
-	DatabaseNameKey       = "<database>"
-	TtlDaysNameKey        = "<ttl_in_days>"
-	TtlHotDataDaysNameKey = "<ttl_in_hot_days>"
+	TableNameKey           = "<table_name>"
+	AliasTableNameKey      = "<alias_table_name>"
+	DatabaseNameKey        = "<database>"
+	TtlDaysNameKey         = "<ttl_in_days>"
+	TtlHotDataDaysNameKey  = "<ttl_in_hot_days>"
+	SourceTableNameKey     = "<source_table_name>"
+	ViewNameKey            = "<view_name>"
+	IntervalSecondsNameKey = "<interval_in_seconds>"
 )
 
 var keyReplacer = strings.NewReplacer(
@@ -166,6 +166,21 @@ func (mr *MockServiceGroupMockRecorder) Precheck(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Precheck", reflect.TypeOf((*MockServiceGroup)(nil).Precheck), arg0)
 }
 
+// Simulate mocks base method.
+func (m *MockServiceGroup) Simulate(arg0 apistructs.ServiceGroupPrecheckRequest) (apistructs.ServiceGroupSimulateData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Simulate", arg0)
+	ret0, _ := ret[0].(apistructs.ServiceGroupSimulateData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Simulate indicates an expected call of Simulate.
+func (mr *MockServiceGroupMockRecorder) Simulate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Simulate", reflect.TypeOf((*MockServiceGroup)(nil).Simulate), arg0)
+}
+
 // Restart mocks base method.
 func (m *MockServiceGroup) Restart(arg0, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -50,6 +50,8 @@ type Conf struct {
 	InspectServiceGroupTimeout int    `env:"INSPECT_SERVICEGROUP_TIMEOUT" default:"60"`
 	CollectorPublicURL         string `env:"COLLECTOR_PUBLIC_URL"`
 	OpenAPIPublicURL           string `env:"OPENAPI_PUBLIC_URL"`
+	// MSPMetricsServerAddr prometheus compatible query address of msp metrics, used by dice.yml autoscale msp triggers
+	MSPMetricsServerAddr string `env:"MSP_METRICS_SERVER_ADDR" default:""`
 
 	// Conf for scheduler
 	DefaultRuntimeExecutor string `env:"DEFAULT_RUNTIME_EXECUTOR" default:"MARATHON"`
@@ -217,3 +219,7 @@ func CollectorPublicURL() string {
 func OpenAPIPublicURL() string {
 	return cfg.OpenAPIPublicURL
 }
+
+func MSPMetricsServerAddr() string {
+	return cfg.MSPMetricsServerAddr
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dbclient
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/database/dbengine"
+)
+
+// PreviewEnvironment an ephemeral runtime created from the artifacts of a merge request
+type PreviewEnvironment struct {
+	dbengine.BaseModel
+	OrgID          uint64                              `gorm:"not null"`
+	ProjectID      uint64                              `gorm:"not null"`
+	ApplicationID  uint64                              `gorm:"not null"`
+	MergeID        int64                               `gorm:"not null"`
+	SourceBranch   string                              `gorm:"column:source_branch"`
+	ReleaseID      string                              `gorm:"column:release_id"`
+	RuntimeID      uint64                              `gorm:"column:runtime_id"`
+	RuntimeName    string                              `gorm:"column:runtime_name"`
+	BaseWorkspace  string                              `gorm:"column:base_workspace"`
+	Namespace      string                              `gorm:"column:namespace"`
+	ClusterName    string                              `gorm:"column:cluster_name"`
+	AddonPolicies  string                              `gorm:"column:addon_policies;type:text"`
+	Status         apistructs.PreviewEnvironmentStatus `gorm:"column:status"`
+	TeardownReason apistructs.PreviewTeardownReason    `gorm:"column:teardown_reason"`
+	Creator        string                              `gorm:"column:creator"`
+	ExpiredAt      time.Time                           `gorm:"column:expired_at"`
+	SoftDeletedAt  uint64                              `gorm:"column:soft_deleted_at"`
+}
+
+func (PreviewEnvironment) TableName() string {
+	return "erda_v2_preview_environment"
+}
+
+// Convert2DTO convert preview environment model to dto
+func (p *PreviewEnvironment) Convert2DTO() *apistructs.PreviewEnvironmentDTO {
+	policies := make(map[string]apistructs.PreviewAddonPolicy)
+	if p.AddonPolicies != "" {
+		if err := json.Unmarshal([]byte(p.AddonPolicies), &policies); err != nil {
+			policies = nil
+		}
+	}
+	return &apistructs.PreviewEnvironmentDTO{
+		ID:             p.ID,
+		ProjectID:      p.ProjectID,
+		ApplicationID:  p.ApplicationID,
+		MergeID:        p.MergeID,
+		SourceBranch:   p.SourceBranch,
+		RuntimeID:      p.RuntimeID,
+		RuntimeName:    p.RuntimeName,
+		BaseWorkspace:  p.BaseWorkspace,
+		Namespace:      p.Namespace,
+		ClusterName:    p.ClusterName,
+		ReleaseID:      p.ReleaseID,
+		Status:         p.Status,
+		AddonPolicies:  policies,
+		TeardownReason: p.TeardownReason,
+		Creator:        p.Creator,
+		ExpiredAt:      p.ExpiredAt,
+		CreatedAt:      p.CreatedAt,
+		UpdatedAt:      p.UpdatedAt,
+	}
+}
+
+// IsAlive whether the preview environment still holds a runtime
+func (p *PreviewEnvironment) IsAlive() bool {
+	return p.Status == apistructs.PreviewEnvironmentStatusCreating || p.Status == apistructs.PreviewEnvironmentStatusRunning
+}
+
+func (db *DBClient) UpdatePreviewEnvironment(env *PreviewEnvironment) error {
+	if err := db.Save(env).Error; err != nil {
+		return errors.Wrapf(err, "failed to update preview environment, id: %d", env.ID)
+	}
+	return nil
+}
+
+// GetPreviewEnvironment if not found, return (nil, error)
+func (db *DBClient) GetPreviewEnvironment(id uint64) (*PreviewEnvironment, error) {
+	var env PreviewEnvironment
+	if err := db.Where("id = ? AND soft_deleted_at = 0", id).Find(&env).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to get preview environment, id: %d", id)
+	}
+	return &env, nil
+}
+
+// FindAlivePreviewEnvironmentByMR if not found, return (nil, nil)
+func (db *DBClient) FindAlivePreviewEnvironmentByMR(appID uint64, mergeID int64) (*PreviewEnvironment, error) {
+	var env PreviewEnvironment
+	result := db.
+		Where("application_id = ? AND merge_id = ? AND status in (?) AND soft_deleted_at = 0", appID, mergeID,
+			[]apistructs.PreviewEnvironmentStatus{apistructs.PreviewEnvironmentStatusCreating, apistructs.PreviewEnvironmentStatusRunning}).
+		Order("id desc").
+		Take(&env)
+	if result.Error != nil {
+		if result.RecordNotFound() {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(result.Error, "failed to find preview environment, appID: %d, mergeID: %d", appID, mergeID)
+	}
+	return &env, nil
+}
+
+func (db *DBClient) ListPreviewEnvironments(appID uint64, status string) ([]PreviewEnvironment, error) {
+	var envs []PreviewEnvironment
+	query := db.Where("application_id = ? AND soft_deleted_at = 0", appID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("id desc").Find(&envs).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to list preview environments, appID: %d", appID)
+	}
+	return envs, nil
+}
+
+// FindExpiredPreviewEnvironments find alive preview environments which are expired before the given time
+func (db *DBClient) FindExpiredPreviewEnvironments(before time.Time) ([]PreviewEnvironment, error) {
+	var envs []PreviewEnvironment
+	if err := db.
+		Where("status in (?) AND expired_at < ? AND soft_deleted_at = 0",
+			[]apistructs.PreviewEnvironmentStatus{apistructs.PreviewEnvironmentStatusCreating, apistructs.PreviewEnvironmentStatusRunning}, before).
+		Find(&envs).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to find expired preview environments")
+	}
+	return envs, nil
+}
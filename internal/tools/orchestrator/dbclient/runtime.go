@@ -83,17 +83,51 @@ type ScheduleName struct {
 	Name      string
 }
 
+const (
+	defaultScheduleNamespace = "services"
+	// previewScheduleNamespace preview runtimes are scheduled into their own namespace instead of the project one,
+	// so that the whole namespace can be removed with the runtime
+	previewScheduleNamespace = "preview"
+)
+
 func (r *Runtime) InitScheduleName(clusterType string) {
 	name := fnvV(fmt.Sprintf("%d-%s-%s", r.ApplicationID, r.Workspace, r.Name))
 	if clusterType == apistructs.EDAS {
 		name = fmt.Sprintf("%s-%d", strings.ToLower(r.Workspace), r.ID)
 	}
+	namespace := defaultScheduleNamespace
+	if r.IsPreview() {
+		namespace = previewScheduleNamespace
+	}
 	r.ScheduleName = ScheduleName{
-		Namespace: "services",
+		Namespace: namespace,
 		Name:      name,
 	}
 }
 
+// GetExtraParams returns the runtime extra params, invalid params are ignored
+func (r *Runtime) GetExtraParams() map[string]string {
+	extraParams := make(map[string]string)
+	if r.ExtraParams == "" {
+		return extraParams
+	}
+	if err := json.Unmarshal([]byte(r.ExtraParams), &extraParams); err != nil {
+		return make(map[string]string)
+	}
+	return extraParams
+}
+
+// IsPreview whether the runtime is an ephemeral preview runtime of a merge request
+func (r *Runtime) IsPreview() bool {
+	return r.GetExtraParams()[apistructs.PreviewMergeIDParamKey] != ""
+}
+
+// PreviewNamespace returns the k8s namespace of the preview runtime,
+// which is the same as the one made by scheduler from ScheduleName.
+func PreviewNamespace(appID uint64, workspace, name string) string {
+	return strutil.Concat(previewScheduleNamespace, "--", fnvV(fmt.Sprintf("%d-%s-%s", appID, workspace, name)))
+}
+
 // fnvV 生成10位的哈希值
 func fnvV(s string) string {
 	h := fnv.New64a()
@@ -117,3 +117,21 @@ func TestFnvV(t *testing.T) {
 	assert.Equal(t, 10, len(str1))
 	assert.Equal(t, 10, len(str2))
 }
+
+func TestInitScheduleNameForPreview(t *testing.T) {
+	r := Runtime{
+		ApplicationID: 1,
+		Workspace:     "DEV",
+		Name:          "preview-mr-12",
+		ExtraParams:   `{"PREVIEW_MERGE_ID":"12"}`,
+	}
+	assert.True(t, r.IsPreview())
+	r.InitScheduleName("k8s")
+	assert.Equal(t, "preview", r.ScheduleName.Namespace)
+	assert.Equal(t, PreviewNamespace(1, "DEV", "preview-mr-12"), "preview--"+r.ScheduleName.Name)
+
+	r = Runtime{ApplicationID: 1, Workspace: "DEV", Name: "master", ExtraParams: "invalid"}
+	assert.False(t, r.IsPreview())
+	r.InitScheduleName("k8s")
+	assert.Equal(t, "services", r.ScheduleName.Namespace)
+}
@@ -39,8 +39,10 @@ import (
 	"github.com/erda-project/erda/internal/tools/orchestrator/services/domain"
 	"github.com/erda-project/erda/internal/tools/orchestrator/services/instance"
 	"github.com/erda-project/erda/internal/tools/orchestrator/services/migration"
+	"github.com/erda-project/erda/internal/tools/orchestrator/services/preview"
 	"github.com/erda-project/erda/internal/tools/orchestrator/services/resource"
 	"github.com/erda-project/erda/internal/tools/orchestrator/services/runtime"
+	"github.com/erda-project/erda/internal/tools/orchestrator/services/simulation"
 	"github.com/erda-project/erda/pkg/crypto/encryption"
 	"github.com/erda-project/erda/pkg/goroutinepool"
 	"github.com/erda-project/erda/pkg/http/httpserver"
@@ -58,6 +60,8 @@ type Endpoints struct {
 	deployment       *deployment.Deployment
 	deploymentOrder  *deployment_order.DeploymentOrder
 	domain           *domain.Domain
+	preview          *preview.Preview
+	simulation       *simulation.Simulation
 	addon            *addon.Addon
 	resource         *resource.Resource
 	encrypt          *encryption.EnvEncrypt
@@ -145,6 +149,20 @@ func WithDomain(domain *domain.Domain) Option {
 	}
 }
 
+// WithPreview 设置 preview 对象.
+func WithPreview(preview *preview.Preview) Option {
+	return func(e *Endpoints) {
+		e.preview = preview
+	}
+}
+
+// WithSimulation 设置 deployment simulation service
+func WithSimulation(simulation *simulation.Simulation) Option {
+	return func(e *Endpoints) {
+		e.simulation = simulation
+	}
+}
+
 // WithAddon 设置 addon service
 func WithAddon(addon *addon.Addon) Option {
 	return func(e *Endpoints) {
@@ -251,6 +269,13 @@ func (e *Endpoints) Routes() []httpserver.Endpoint {
 		{Path: "/api/deployment-orders/{deploymentOrderID}/actions/cancel", Method: http.MethodPost, Handler: e.CancelDeploymentOrder},
 		{Path: "/api/deployment-orders/actions/render-detail", Method: http.MethodGet, Handler: httpserver.Wrap(e.RenderDeploymentOrderDetail, httpserver.WithI18nCodes)},
 
+		// preview environment endpoints
+		{Path: "/api/preview-environments", Method: http.MethodPost, Handler: e.CreatePreviewEnvironment},
+		{Path: "/api/preview-environments", Method: http.MethodGet, Handler: e.ListPreviewEnvironments},
+		{Path: "/api/preview-environments/{previewID}", Method: http.MethodGet, Handler: e.GetPreviewEnvironment},
+		{Path: "/api/preview-environments/{previewID}", Method: http.MethodDelete, Handler: e.DeletePreviewEnvironment},
+		{Path: PreviewMergeRequestCallbackPath, Method: http.MethodPost, Handler: e.PreviewMergeRequestCallback},
+
 		// kill pod (only k8s)
 		{Path: "/api/runtimes/actions/killpod", Method: http.MethodPost, Handler: e.KillPod},
 
@@ -260,6 +285,7 @@ func (e *Endpoints) Routes() []httpserver.Endpoint {
 		{Path: "/api/deployments/actions/list-pending-approval", Method: http.MethodGet, Handler: e.ListPendingApprovalDeployments},
 		{Path: "/api/deployments/actions/list-approved", Method: http.MethodGet, Handler: e.ListApprovedDeployments},
 		{Path: "/api/deployments/actions/approve", Method: http.MethodPost, Handler: e.DeploymentApprove},
+		{Path: "/api/deployments/actions/simulate", Method: http.MethodPost, Handler: e.SimulateDeployment},
 
 		// TODO: do not returns runtime info, use /api/runtimes/{runtimeId} instead
 		{Path: "/api/deployments/{deploymentID}/status", Method: http.MethodGet, Handler: e.GetDeploymentStatus},
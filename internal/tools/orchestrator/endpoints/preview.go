@@ -0,0 +1,124 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/pkg/user"
+	"github.com/erda-project/erda/internal/tools/orchestrator/services/apierrors"
+	"github.com/erda-project/erda/pkg/http/httpserver"
+	"github.com/erda-project/erda/pkg/http/httpserver/errorresp"
+	"github.com/erda-project/erda/pkg/strutil"
+)
+
+// PreviewMergeRequestCallbackPath eventbox webhook path of gittar merge request events
+const PreviewMergeRequestCallbackPath = "/api/preview-environments/actions/mr-callback"
+
+// CreatePreviewEnvironment 基于合并请求的制品创建预览环境
+func (e *Endpoints) CreatePreviewEnvironment(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	var req apistructs.PreviewEnvironmentCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apierrors.ErrCreatePreviewEnvironment.InvalidParameter(err).ToResp(), nil
+	}
+	userID, err := user.GetUserID(r)
+	if err != nil {
+		return apierrors.ErrCreatePreviewEnvironment.NotLogin().ToResp(), nil
+	}
+	req.Operator = userID.String()
+	data, err := e.preview.Create(ctx, userID, &req)
+	if err != nil {
+		return errorresp.ErrResp(err)
+	}
+	return httpserver.OkResp(data)
+}
+
+// GetPreviewEnvironment 查询预览环境
+func (e *Endpoints) GetPreviewEnvironment(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	v := vars["previewID"]
+	previewID, err := strutil.Atoi64(v)
+	if err != nil {
+		return apierrors.ErrGetPreviewEnvironment.InvalidParameter(strutil.Concat("previewID: ", v)).ToResp(), nil
+	}
+	userID, err := user.GetUserID(r)
+	if err != nil {
+		return apierrors.ErrGetPreviewEnvironment.NotLogin().ToResp(), nil
+	}
+	data, err := e.preview.Get(userID, uint64(previewID))
+	if err != nil {
+		return errorresp.ErrResp(err)
+	}
+	return httpserver.OkResp(data)
+}
+
+// ListPreviewEnvironments 查询应用的预览环境列表
+func (e *Endpoints) ListPreviewEnvironments(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	var req apistructs.PreviewEnvironmentListRequest
+	if err := queryStringDecoder.Decode(&req, r.URL.Query()); err != nil {
+		return apierrors.ErrListPreviewEnvironment.InvalidParameter(err).ToResp(), nil
+	}
+	userID, err := user.GetUserID(r)
+	if err != nil {
+		return apierrors.ErrListPreviewEnvironment.NotLogin().ToResp(), nil
+	}
+	data, err := e.preview.List(userID, &req)
+	if err != nil {
+		return errorresp.ErrResp(err)
+	}
+	return httpserver.OkResp(data)
+}
+
+// DeletePreviewEnvironment 手动销毁预览环境
+func (e *Endpoints) DeletePreviewEnvironment(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	v := vars["previewID"]
+	previewID, err := strutil.Atoi64(v)
+	if err != nil {
+		return apierrors.ErrDeletePreviewEnvironment.InvalidParameter(strutil.Concat("previewID: ", v)).ToResp(), nil
+	}
+	userID, err := user.GetUserID(r)
+	if err != nil {
+		return apierrors.ErrDeletePreviewEnvironment.NotLogin().ToResp(), nil
+	}
+	data, err := e.preview.Delete(userID, uint64(previewID))
+	if err != nil {
+		return errorresp.ErrResp(err)
+	}
+	return httpserver.OkResp(data)
+}
+
+// PreviewMergeRequestCallback eventbox 回调的 gittar 合并请求事件，MR 合并或关闭时销毁预览环境
+func (e *Endpoints) PreviewMergeRequestCallback(ctx context.Context, r *http.Request, vars map[string]string) (httpserver.Responser, error) {
+	var event apistructs.RepoCreateMrEvent
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		logrus.Errorf("failed to decode merge request event, err: %v", err)
+		return apierrors.ErrDeletePreviewEnvironment.InvalidParameter(err).ToResp(), nil
+	}
+	if err := e.preview.OnMergeRequestEvent(&event); err != nil {
+		logrus.Errorf("failed to handle merge request event %s of app %s, err: %v",
+			event.Content.EventName, event.ApplicationID, err)
+		return errorresp.ErrResp(err)
+	}
+	return httpserver.OkResp(nil)
+}
+
+// CleanExpiredPreviewEnvironments 销毁过期的预览环境
+func (e *Endpoints) CleanExpiredPreviewEnvironments() (bool, error) {
+	return e.preview.CleanExpired()
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/erda-project/erda-infra/base/servicehub"
 	infrahttpserver "github.com/erda-project/erda-infra/providers/httpserver"
 	clusterpb "github.com/erda-project/erda-proto-go/core/clustermanager/cluster/pb"
+	"github.com/erda-project/erda/apistructs"
 	"github.com/erda-project/erda/bundle"
 	"github.com/erda-project/erda/internal/core/org"
 	orgCache "github.com/erda-project/erda/internal/tools/orchestrator/cache/org"
@@ -43,15 +44,19 @@ import (
 	"github.com/erda-project/erda/internal/tools/orchestrator/services/environment"
 	"github.com/erda-project/erda/internal/tools/orchestrator/services/instance"
 	"github.com/erda-project/erda/internal/tools/orchestrator/services/migration"
+	"github.com/erda-project/erda/internal/tools/orchestrator/services/preview"
 	"github.com/erda-project/erda/internal/tools/orchestrator/services/resource"
 	"github.com/erda-project/erda/internal/tools/orchestrator/services/runtime"
+	"github.com/erda-project/erda/internal/tools/orchestrator/services/simulation"
 	"github.com/erda-project/erda/pkg/crypto/encryption"
 	"github.com/erda-project/erda/pkg/database/dbengine"
+	"github.com/erda-project/erda/pkg/discover"
 	"github.com/erda-project/erda/pkg/goroutinepool"
 	"github.com/erda-project/erda/pkg/http/httpclient"
 	"github.com/erda-project/erda/pkg/http/httpserver"
 	"github.com/erda-project/erda/pkg/http/httpserver/errorresp"
 	"github.com/erda-project/erda/pkg/loop"
+	"github.com/erda-project/erda/pkg/strutil"
 )
 
 // Initialize 初始化应用启动服务.
@@ -102,6 +107,12 @@ func (p *provider) Initialize(ctx servicehub.Context) error {
 
 	go scheduler.GetDCOSTokenAuthPeriodically()
 
+	go func() {
+		if err := registerPreviewMergeRequestHook(); err != nil {
+			logrus.Errorf("failed to register preview merge request hook, err: %v", err)
+		}
+	}()
+
 	// start cron jobs to sync addon & project infos
 	go initCron(ep, ctx)
 
@@ -239,6 +250,22 @@ func (p *provider) initEndpoints(db *dbclient.DBClient) (*endpoints.Endpoints, e
 		deployment_order.WithAddon(a),
 	)
 
+	// init preview environment service
+	pre := preview.New(
+		preview.WithDBClient(db),
+		preview.WithBundle(bdl),
+		preview.WithRuntime(rt),
+		preview.WithReleaseSvc(p.DicehubReleaseSvc),
+	)
+
+	// init deployment simulation service
+	sim := simulation.New(
+		simulation.WithDBClient(db),
+		simulation.WithBundle(bdl),
+		simulation.WithReleaseSvc(p.DicehubReleaseSvc),
+		simulation.WithServiceGroup(scheduler.Httpendpoints.ServiceGroupImpl),
+	)
+
 	// compose endpoints
 	ep := endpoints.New(
 		endpoints.WithDBClient(db),
@@ -250,6 +277,8 @@ func (p *provider) initEndpoints(db *dbclient.DBClient) (*endpoints.Endpoints, e
 		endpoints.WithDeployment(d),
 		endpoints.WithDeploymentOrder(do),
 		endpoints.WithDomain(dom),
+		endpoints.WithPreview(pre),
+		endpoints.WithSimulation(sim),
 		endpoints.WithAddon(a),
 		endpoints.WithInstance(ins),
 		endpoints.WithEnvEncrypt(encrypt),
@@ -288,6 +317,8 @@ func initLeaderCron(ep *endpoints.Endpoints, ctx context.Context) error {
 
 	go loop.New(loop.WithContext(ctx), loop.WithInterval(1*time.Hour)).Do(ep.CleanRemainingAddonAttachment)
 
+	go loop.New(loop.WithContext(ctx), loop.WithInterval(time.Minute)).Do(ep.CleanExpiredPreviewEnvironments)
+
 	ep.FullGCLoop(ctx)
 
 	return nil
@@ -385,3 +416,20 @@ func registerEcpRouter(bdl *bundle.Bundle, dbEngine *dbengine.DBEngine, clusterS
 		ecpednpoints.WithClusterSvc(clusterSvc),
 		ecpednpoints.WithOrg(org)).Routes()
 }
+
+// registerPreviewMergeRequestHook register webhook in eventbox, preview environments are torn down when mr merged or closed
+func registerPreviewMergeRequestHook() error {
+	bdl := bundle.New(bundle.WithErdaServer())
+	ev := apistructs.CreateHookRequest{
+		Name:   "orchestrator-preview-mr",
+		Events: []string{apistructs.GitMergeMREvent, apistructs.GitCloseMREvent},
+		URL:    strutil.Concat("http://", discover.Orchestrator(), endpoints.PreviewMergeRequestCallbackPath),
+		Active: true,
+		HookLocation: apistructs.HookLocation{
+			Org:         "-1",
+			Project:     "-1",
+			Application: "-1",
+		},
+	}
+	return bdl.CreateWebhook(ev)
+}
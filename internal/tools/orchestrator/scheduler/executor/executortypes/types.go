@@ -104,6 +104,12 @@ type TerminalExecutor interface {
 	Terminal(namespace, podname, containername string, conn *websocket.Conn)
 }
 
+// SimulateExecutor dry-run the scheduling of a service group against the current cluster resources
+// only k8s executor supported
+type SimulateExecutor interface {
+	Simulate(ctx context.Context, spec interface{}) (apistructs.ServiceGroupSimulateData, error)
+}
+
 type StopEventsChans struct {
 	StopWatchEventCh  chan struct{}
 	StopHandleEventCh chan struct{}
@@ -77,6 +77,7 @@ func (r *ResourceInfo) Get(brief bool) (apistructs.ClusterResourceInfoData, erro
 		nodeResourceInfoMap[ip.String()] = &apistructs.NodeResourceInfo{}
 		info := nodeResourceInfoMap[ip.String()]
 		info.Labels = nodeLabels(&node)
+		info.Taints = nodeTaints(&node)
 		info.Ready = nodeReady(&node)
 		cpuAllocatable, err := strconv.ParseFloat(fmt.Sprintf("%f", node.Status.Allocatable.Cpu().AsDec()), 64)
 		if err != nil {
@@ -130,6 +131,15 @@ func nodeLabels(n *v1.Node) []string {
 	return r
 }
 
+// nodeTaints return taints in format key=value:effect, or key:effect if value is empty
+func nodeTaints(n *v1.Node) []string {
+	r := []string{}
+	for i := range n.Spec.Taints {
+		r = append(r, n.Spec.Taints[i].ToString())
+	}
+	return r
+}
+
 func nodeReady(n *v1.Node) bool {
 	for _, cond := range n.Status.Conditions {
 		if cond.Type == v1.NodeReady {
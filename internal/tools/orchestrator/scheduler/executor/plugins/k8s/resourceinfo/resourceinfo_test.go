@@ -107,3 +107,21 @@ func TestSplitPodsByNodeName(t *testing.T) {
 		})
 	}
 }
+
+func TestNodeTaints(t *testing.T) {
+	node := &corev1.Node{
+		Spec: corev1.NodeSpec{
+			Taints: []corev1.Taint{
+				{Key: "node-role.kubernetes.io/master", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoExecute},
+			},
+		},
+	}
+	want := []string{"node-role.kubernetes.io/master:NoSchedule", "dedicated=gpu:NoExecute"}
+	if got := nodeTaints(node); !reflect.DeepEqual(got, want) {
+		t.Errorf("nodeTaints() = %v, want %v", got, want)
+	}
+	if got := nodeTaints(&corev1.Node{}); len(got) != 0 {
+		t.Errorf("nodeTaints() = %v, want empty", got)
+	}
+}
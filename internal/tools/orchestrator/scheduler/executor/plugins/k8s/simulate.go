@@ -0,0 +1,276 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/orchestrator/scheduler/executor/plugins/k8s/toleration"
+	"github.com/erda-project/erda/pkg/schedule/schedulepolicy/constraintbuilders"
+)
+
+// resourceRequests requests of one replica, cpu in millicores, memory in bytes
+type resourceRequests struct {
+	cpu int64
+	mem int64
+}
+
+type simulationNode struct {
+	ip      string
+	info    *apistructs.NodeResourceInfo
+	freeCPU int64
+	freeMem int64
+}
+
+// Simulate dry-run the scheduling of the service group, checks every replica against
+// the free allocatable resources of ready nodes, the node label constraints, the node taints
+// and the project workspace quota. Nothing is created in the cluster.
+func (k *Kubernetes) Simulate(ctx context.Context, specObj interface{}) (apistructs.ServiceGroupSimulateData, error) {
+	sg, err := ValidateRuntime(specObj, "Simulate")
+	if err != nil {
+		return apistructs.ServiceGroupSimulateData{}, err
+	}
+	resourceinfo, err := k.resourceInfo.Get(false)
+	if err != nil {
+		return apistructs.ServiceGroupSimulateData{}, err
+	}
+
+	workspace := sg.Labels[DiceWorkSpace]
+	requests := make(map[string]resourceRequests, len(sg.Services))
+	for _, svc := range sg.Services {
+		// the overcommit ratio depends on the workspace env of the service
+		if _, ok := svc.Env[DiceWorkSpace]; !ok && workspace != "" {
+			env := map[string]string{DiceWorkSpace: workspace}
+			for key, value := range svc.Env {
+				env[key] = value
+			}
+			svc.Env = env
+		}
+		container := corev1.Container{Name: svc.Name}
+		if err := k.setContainerResources(svc, &container); err != nil {
+			return apistructs.ServiceGroupSimulateData{}, errors.Wrapf(err, "invalid resources of service %s", svc.Name)
+		}
+		cpu, mem := getRequestsResources([]corev1.Container{container})
+		requests[svc.Name] = resourceRequests{cpu: cpu, mem: mem}
+	}
+
+	var quota *apistructs.QuotaSimulation
+	if projectID := sg.Labels["DICE_PROJECT_ID"]; projectID != "" && workspace != "" {
+		leftCPU, leftMem, err := k.GetWorkspaceLeftQuota(ctx, projectID, workspace)
+		if err != nil {
+			return apistructs.ServiceGroupSimulateData{}, err
+		}
+		quota = &apistructs.QuotaSimulation{
+			ProjectID: projectID,
+			Workspace: workspace,
+			LeftCPU:   leftCPU,
+			LeftMem:   leftMem,
+		}
+	}
+
+	return simulate(sg, resourceinfo, requests, quota), nil
+}
+
+// simulate places the replicas of the services one by one, larger services first.
+// Each replica goes to the feasible node with the most free cpu, like the LeastAllocated
+// score of kube-scheduler, so the result is an estimation instead of the final placement.
+// All replicas are treated as new pods, replicas of the running version are not subtracted.
+func simulate(sg *apistructs.ServiceGroup, resourceinfo apistructs.ClusterResourceInfoData,
+	requests map[string]resourceRequests, quota *apistructs.QuotaSimulation) apistructs.ServiceGroupSimulateData {
+	nodes := make([]*simulationNode, 0, len(resourceinfo.Nodes))
+	for ip, info := range resourceinfo.Nodes {
+		nodes = append(nodes, &simulationNode{
+			ip:      ip,
+			info:    info,
+			freeCPU: int64((info.CPUAllocatable - info.CPUReqsUsage) * 1000),
+			freeMem: info.MemAllocatable - info.MemReqsUsage,
+		})
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ip < nodes[j].ip })
+
+	services := make([]apistructs.Service, len(sg.Services))
+	copy(services, sg.Services)
+	sort.SliceStable(services, func(i, j int) bool {
+		ri, rj := requests[services[i].Name], requests[services[j].Name]
+		if ri.cpu != rj.cpu {
+			return ri.cpu > rj.cpu
+		}
+		return ri.mem > rj.mem
+	})
+
+	tolerations := toleration.GenTolerations()
+	r := apistructs.ServiceGroupSimulateData{
+		Status:   "ok",
+		Quota:    quota,
+		Services: make(map[string]apistructs.ServiceSimulation, len(services)),
+	}
+	var totalCPU, totalMem int64
+	for i := range services {
+		svc := &services[i]
+		req := requests[svc.Name]
+		totalCPU += req.cpu * int64(svc.Scale)
+		totalMem += req.mem * int64(svc.Scale)
+
+		cons := constraintbuilders.K8S(&sg.ScheduleInfo2, svc, nil, nil)
+		var svclabels [][2][]string
+		if cons.Affinity.NodeAffinity != nil && cons.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+			svclabels = extractLabels(cons.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms)
+		}
+
+		var notReady, labelMismatch, untolerated int
+		candidates := make([]*simulationNode, 0, len(nodes))
+		for _, node := range nodes {
+			switch {
+			case !node.info.Ready:
+				notReady++
+			case !node.info.IgnoreLabels && len(svclabels) > 0 && !matchNodeLabels(node.info.Labels, svclabels):
+				labelMismatch++
+			case !toleratesTaints(node.info.Taints, tolerations):
+				untolerated++
+			default:
+				candidates = append(candidates, node)
+			}
+		}
+
+		result := apistructs.ServiceSimulation{
+			Status:      "ok",
+			Replicas:    svc.Scale,
+			RequestsCPU: req.cpu,
+			RequestsMem: req.mem,
+			Constraints: pp(svclabels),
+			Placements:  map[string]int{},
+		}
+		for result.SchedulableReplicas < svc.Scale {
+			node := pickNode(candidates, req)
+			if node == nil {
+				break
+			}
+			node.freeCPU -= req.cpu
+			node.freeMem -= req.mem
+			result.Placements[node.ip]++
+			result.SchedulableReplicas++
+		}
+
+		if result.SchedulableReplicas < svc.Scale {
+			result.Status = statusUnschedulable
+			r.Status = statusUnschedulable
+			var insufficientCPU, insufficientMem int
+			for _, node := range candidates {
+				if node.freeCPU < req.cpu {
+					insufficientCPU++
+				}
+				if node.freeMem < req.mem {
+					insufficientMem++
+				}
+			}
+			result.Reasons = unschedulableReasons(len(nodes), notReady, labelMismatch, untolerated, insufficientCPU, insufficientMem)
+		}
+		r.Services[svc.Name] = result
+	}
+
+	if quota != nil {
+		quota.RequestsCPU = totalCPU
+		quota.RequestsMem = totalMem
+		quota.Fits = totalCPU <= quota.LeftCPU && totalMem <= quota.LeftMem
+		if !quota.Fits {
+			quota.Info = fmt.Sprintf("Resource quota is not enough in current workspace, requests cpu %s core(s), memory %s, "+
+				"remaining cpu %s core(s), memory %s",
+				resourceToString(float64(totalCPU), "cpu"), resourceToString(float64(totalMem), "memory"),
+				resourceToString(float64(max(quota.LeftCPU, 0)), "cpu"), resourceToString(float64(max(quota.LeftMem, 0)), "memory"))
+			r.Status = statusUnschedulable
+		}
+	}
+	return r
+}
+
+// pickNode return the node with the most free cpu which can hold the requests, nil if none
+func pickNode(candidates []*simulationNode, req resourceRequests) *simulationNode {
+	var picked *simulationNode
+	for _, node := range candidates {
+		if node.freeCPU < req.cpu || node.freeMem < req.mem {
+			continue
+		}
+		if picked == nil || node.freeCPU > picked.freeCPU {
+			picked = node
+		}
+	}
+	return picked
+}
+
+// toleratesTaints whether all NoSchedule and NoExecute taints are tolerated
+func toleratesTaints(taints []string, tolerations []corev1.Toleration) bool {
+	for _, s := range taints {
+		taint := parseTaint(s)
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for i := range tolerations {
+			if tolerations[i].ToleratesTaint(&taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTaint parse taint in format key=value:effect or key:effect
+func parseTaint(s string) corev1.Taint {
+	var taint corev1.Taint
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		taint.Effect = corev1.TaintEffect(s[i+1:])
+		s = s[:i]
+	}
+	if i := strings.Index(s, "="); i >= 0 {
+		taint.Key, taint.Value = s[:i], s[i+1:]
+	} else {
+		taint.Key = s
+	}
+	return taint
+}
+
+func unschedulableReasons(total, notReady, labelMismatch, untolerated, insufficientCPU, insufficientMem int) []string {
+	reasons := []string{}
+	if total == 0 {
+		return append(reasons, "no node in the cluster")
+	}
+	if notReady > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d node(s) were not ready", notReady))
+	}
+	if labelMismatch > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d node(s) didn't match the label constraints", labelMismatch))
+	}
+	if untolerated > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d node(s) had taints that the pod didn't tolerate", untolerated))
+	}
+	if insufficientCPU > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d node(s) had insufficient cpu", insufficientCPU))
+	}
+	if insufficientMem > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d node(s) had insufficient memory", insufficientMem))
+	}
+	return reasons
+}
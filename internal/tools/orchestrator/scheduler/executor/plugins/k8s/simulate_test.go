@@ -0,0 +1,134 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/orchestrator/scheduler/executor/plugins/k8s/toleration"
+)
+
+const gib = int64(1 << 30)
+
+func Test_simulate(t *testing.T) {
+	sg := &apistructs.ServiceGroup{
+		Dice: apistructs.Dice{
+			Services: []apistructs.Service{
+				{Name: "web", Scale: 3},
+				{Name: "worker", Scale: 2},
+			},
+		},
+		ScheduleInfo2: apistructs.ScheduleInfo2{IsUnLocked: true, Stateless: true},
+	}
+	requests := map[string]resourceRequests{
+		"web":    {cpu: 1000, mem: gib},
+		"worker": {cpu: 2000, mem: 2 * gib},
+	}
+	resourceinfo := apistructs.ClusterResourceInfoData{
+		Nodes: map[string]*apistructs.NodeResourceInfo{
+			"10.0.0.1": {
+				Labels: []string{"dice/stateless-service"}, Ready: true,
+				CPUAllocatable: 4, MemAllocatable: 8 * gib, CPUReqsUsage: 1, MemReqsUsage: gib,
+			},
+			"10.0.0.2": {
+				Labels: []string{"dice/stateless-service"}, Ready: true,
+				CPUAllocatable: 2, MemAllocatable: 4 * gib,
+			},
+			// not ready
+			"10.0.0.3": {
+				Labels: []string{"dice/stateless-service"}, Ready: false,
+				CPUAllocatable: 16, MemAllocatable: 32 * gib,
+			},
+			// no stateless-service label
+			"10.0.0.4": {
+				Labels: []string{"dice/stateful-service"}, Ready: true,
+				CPUAllocatable: 16, MemAllocatable: 32 * gib,
+			},
+			// master taint is tolerated, the gpu taint is not
+			"10.0.0.5": {
+				Labels: []string{"dice/stateless-service"}, Ready: true,
+				Taints:         []string{"node-role.kubernetes.io/master:NoSchedule", "dedicated=gpu:NoSchedule"},
+				CPUAllocatable: 16, MemAllocatable: 32 * gib,
+			},
+		},
+	}
+	quota := &apistructs.QuotaSimulation{ProjectID: "1", Workspace: "prod", LeftCPU: 6000, LeftMem: 16 * gib}
+
+	r := simulate(sg, resourceinfo, requests, quota)
+	// worker is placed first: 10.0.0.1 (3 cores free) and 10.0.0.2 (2 cores free),
+	// then only 1 core is left on 10.0.0.1 for web
+	worker := r.Services["worker"]
+	assert.Equal(t, "ok", worker.Status)
+	assert.Equal(t, 2, worker.SchedulableReplicas)
+	assert.Equal(t, map[string]int{"10.0.0.1": 1, "10.0.0.2": 1}, worker.Placements)
+
+	web := r.Services["web"]
+	assert.Equal(t, statusUnschedulable, web.Status)
+	assert.Equal(t, 1, web.SchedulableReplicas)
+	assert.Equal(t, map[string]int{"10.0.0.1": 1}, web.Placements)
+	assert.Equal(t, []string{
+		"1 node(s) were not ready",
+		"1 node(s) didn't match the label constraints",
+		"1 node(s) had taints that the pod didn't tolerate",
+		"2 node(s) had insufficient cpu",
+	}, web.Reasons)
+
+	assert.Equal(t, statusUnschedulable, r.Status)
+	assert.Equal(t, int64(7000), quota.RequestsCPU)
+	assert.Equal(t, 7*gib, quota.RequestsMem)
+	assert.False(t, quota.Fits)
+	assert.NotEmpty(t, quota.Info)
+}
+
+func Test_simulateFits(t *testing.T) {
+	sg := &apistructs.ServiceGroup{
+		Dice: apistructs.Dice{
+			Services: []apistructs.Service{{Name: "web", Scale: 2}},
+		},
+		ScheduleInfo2: apistructs.ScheduleInfo2{IsUnLocked: true, Stateless: true},
+	}
+	resourceinfo := apistructs.ClusterResourceInfoData{
+		Nodes: map[string]*apistructs.NodeResourceInfo{
+			"10.0.0.1": {IgnoreLabels: true, Ready: true, CPUAllocatable: 4, MemAllocatable: 8 * gib},
+		},
+	}
+	r := simulate(sg, resourceinfo, map[string]resourceRequests{"web": {cpu: 500, mem: gib}}, nil)
+	assert.Equal(t, "ok", r.Status)
+	assert.Nil(t, r.Quota)
+	assert.Equal(t, map[string]int{"10.0.0.1": 2}, r.Services["web"].Placements)
+
+	r = simulate(sg, apistructs.ClusterResourceInfoData{}, map[string]resourceRequests{"web": {cpu: 500, mem: gib}}, nil)
+	assert.Equal(t, statusUnschedulable, r.Status)
+	assert.Equal(t, []string{"no node in the cluster"}, r.Services["web"].Reasons)
+}
+
+func Test_toleratesTaints(t *testing.T) {
+	tolerations := toleration.GenTolerations()
+	assert.True(t, toleratesTaints(nil, tolerations))
+	assert.True(t, toleratesTaints([]string{"node-role.kubernetes.io/lb:NoSchedule"}, tolerations))
+	assert.True(t, toleratesTaints([]string{"dedicated=gpu:PreferNoSchedule"}, tolerations))
+	assert.False(t, toleratesTaints([]string{"dedicated=gpu:NoExecute"}, tolerations))
+}
+
+func Test_parseTaint(t *testing.T) {
+	assert.Equal(t, v1.Taint{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}, parseTaint("dedicated=gpu:NoSchedule"))
+	assert.Equal(t, v1.Taint{Key: "node-role.kubernetes.io/master", Effect: v1.TaintEffectNoSchedule},
+		parseTaint("node-role.kubernetes.io/master:NoSchedule"))
+	assert.Equal(t, v1.Taint{Key: "dedicated"}, parseTaint("dedicated"))
+}
@@ -180,6 +180,20 @@ func (s ServiceGroupImpl) Precheck(req apistructs.ServiceGroupPrecheckRequest) (
 	return t.Extra.(apistructs.ServiceGroupPrecheckData), nil
 }
 
+// Simulate dry-run the scheduling of the service group, nothing is created in the cluster
+func (s ServiceGroupImpl) Simulate(req apistructs.ServiceGroupPrecheckRequest) (apistructs.ServiceGroupSimulateData, error) {
+	sg, err := convertServiceGroupCreateV2Request(apistructs.ServiceGroupCreateV2Request(req), s.Clusterinfo)
+	if err != nil {
+		return apistructs.ServiceGroupSimulateData{}, err
+	}
+	t, err := s.handleServiceGroup(context.Background(), &sg, task.TaskSimulate)
+	if err != nil {
+		return apistructs.ServiceGroupSimulateData{}, err
+	}
+
+	return t.Extra.(apistructs.ServiceGroupSimulateData), nil
+}
+
 func (s ServiceGroupImpl) Restart(namespace string, name string) error {
 	sg := apistructs.ServiceGroup{}
 	if err := s.Js.Get(context.Background(), mkServiceGroupKey(namespace, name), &sg); err != nil {
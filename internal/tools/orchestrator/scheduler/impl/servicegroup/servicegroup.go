@@ -57,6 +57,7 @@ type ServiceGroup interface {
 	Delete(namespace, name string, force bool, extra map[string]string) error
 	Info(ctx context.Context, namespace string, name string) (apistructs.ServiceGroup, error)
 	Precheck(sg apistructs.ServiceGroupPrecheckRequest) (apistructs.ServiceGroupPrecheckData, error)
+	Simulate(sg apistructs.ServiceGroupPrecheckRequest) (apistructs.ServiceGroupSimulateData, error)
 	ConfigUpdate(sg apistructs.ServiceGroup) error
 	KillPod(ctx context.Context, namespace string, name string, podname string) error
 	Scale(sg *apistructs.ServiceGroup) (interface{}, error)
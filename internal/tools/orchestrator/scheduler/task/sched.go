@@ -155,7 +155,8 @@ func (s *Sched) FindExecutor(name, kind string) (executortypes.Executor, error)
 
 func (s *Sched) setObjLabelScheduleInfo(task *Task) error {
 	// Only do tag filtering for POST or PUT requests
-	if task.Action != TaskCreate && task.Action != TaskUpdate && task.Action != TaskPrecheck {
+	if task.Action != TaskCreate && task.Action != TaskUpdate && task.Action != TaskPrecheck &&
+		task.Action != TaskSimulate {
 		return nil
 	}
 
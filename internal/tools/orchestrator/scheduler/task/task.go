@@ -47,6 +47,7 @@ const (
 	TaskVPAObjectApply
 	TaskVPAObjectCancel
 	TaskVPAObjectReApply
+	TaskSimulate
 )
 
 var (
@@ -220,6 +221,18 @@ func (t *Task) Run(ctx context.Context) TaskResponse {
 			err:   err,
 			Extra: r,
 		}
+	case TaskSimulate:
+		simulator, ok := executor.(executortypes.SimulateExecutor)
+		if !ok {
+			return TaskResponse{
+				err: errors.Errorf("executor %s(%s) does not support simulation", executor.Name(), executor.Kind()),
+			}
+		}
+		r, err := simulator.Simulate(ctx, t.Spec)
+		return TaskResponse{
+			err:   err,
+			Extra: r,
+		}
 	case TaskJobVolumeCreate:
 		r, err := executor.JobVolumeCreate(ctx, t.Spec)
 		return TaskResponse{
@@ -302,6 +315,8 @@ func (a *Action) String() string {
 		return "TaskScale"
 	case TaskKedaScaledObjectCreate:
 		return "TaskKedaScaledObjectCreate"
+	case TaskSimulate:
+		return "TaskSimulate"
 	}
 	panic("unreachable")
 }
@@ -56,6 +56,19 @@ var (
 	ErrRenderDeploymentOrderDetail = err("ErrRenderDeploymentOrderDetail", "渲染部署单详情失败")
 )
 
+// preview environment errors
+var (
+	ErrCreatePreviewEnvironment = err("ErrCreatePreviewEnvironment", "创建预览环境失败")
+	ErrGetPreviewEnvironment    = err("ErrGetPreviewEnvironment", "查询预览环境失败")
+	ErrListPreviewEnvironment   = err("ErrListPreviewEnvironment", "查询预览环境列表失败")
+	ErrDeletePreviewEnvironment = err("ErrDeletePreviewEnvironment", "销毁预览环境失败")
+)
+
+// deployment simulation errors
+var (
+	ErrSimulateDeployment = err("ErrSimulateDeployment", "部署资源模拟失败")
+)
+
 // domain errors
 var (
 	ErrListDomain   = err("ErrListDomain", "查询域名列表失败")
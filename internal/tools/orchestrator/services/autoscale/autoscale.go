@@ -0,0 +1,444 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package autoscale 将 dice.yml 中声明的 autoscale 规则同步为 runtime 服务的 HPA 规则（KEDA ScaledObject）
+package autoscale
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda-proto-go/orchestrator/podscaler/pb"
+	"github.com/erda-project/erda/apistructs"
+	pstypes "github.com/erda-project/erda/internal/tools/orchestrator/components/podscaler/types"
+	"github.com/erda-project/erda/internal/tools/orchestrator/conf"
+	"github.com/erda-project/erda/internal/tools/orchestrator/dbclient"
+	"github.com/erda-project/erda/internal/tools/orchestrator/scheduler/impl/servicegroup"
+	"github.com/erda-project/erda/internal/tools/orchestrator/spec"
+	"github.com/erda-project/erda/pkg/parser/diceyml"
+	"github.com/erda-project/erda/pkg/strutil"
+)
+
+const (
+	// RuleNamePrefix dice.yml 声明的规则名前缀，用于和页面上手动创建的规则区分
+	RuleNamePrefix = "diceyml-"
+
+	defaultPollingInterval   int32 = 30
+	defaultCooldownPeriod    int32 = 300
+	defaultCronTimezone            = "Asia/Shanghai"
+	defaultKafkaLagThreshold       = 10
+	kedaTriggerPrometheus          = "prometheus"
+	kedaTriggerKafka               = "kafka"
+)
+
+// Autoscale dice.yml 弹性伸缩规则同步对象封装
+type Autoscale struct {
+	db               *dbclient.DBClient
+	serviceGroupImpl servicegroup.ServiceGroup
+}
+
+// Option Autoscale 对象配置选项
+type Option func(*Autoscale)
+
+// New 新建 Autoscale 对象实例
+func New(options ...Option) *Autoscale {
+	a := &Autoscale{}
+	for _, op := range options {
+		op(a)
+	}
+	return a
+}
+
+// WithDBClient 配置 db client
+func WithDBClient(db *dbclient.DBClient) Option {
+	return func(a *Autoscale) {
+		a.db = db
+	}
+}
+
+// WithServiceGroup 配置 service group
+func WithServiceGroup(sg servicegroup.ServiceGroup) Option {
+	return func(a *Autoscale) {
+		a.serviceGroupImpl = sg
+	}
+}
+
+// Sync 按照部署的 dice.yml 创建、更新或删除 runtime 中由 dice.yml 管理的 HPA 规则，
+// 页面上手动创建的规则不受影响，同一个服务已存在手动规则时跳过该服务
+func (a *Autoscale) Sync(runtime *dbclient.Runtime, app *apistructs.ApplicationDTO, operator string, dice *diceyml.Object) error {
+	uniqueID := spec.RuntimeUniqueId{
+		ApplicationId: runtime.ApplicationID,
+		Workspace:     runtime.Workspace,
+		Name:          runtime.Name,
+	}
+	rules, err := a.db.GetRuntimeHPAByServices(uniqueID, nil)
+	if err != nil {
+		return err
+	}
+	managed := make(map[string]dbclient.RuntimeHPA)
+	manual := make(map[string]bool)
+	for _, rule := range rules {
+		if strings.HasPrefix(rule.RuleName, RuleNamePrefix) {
+			managed[rule.ServiceName] = rule
+		} else {
+			manual[rule.ServiceName] = true
+		}
+	}
+
+	desired := make(map[string]*diceyml.Service)
+	if dice != nil {
+		for name, svc := range dice.Services {
+			if svc != nil && svc.Autoscale != nil {
+				desired[name] = svc
+			}
+		}
+	}
+
+	var errs []string
+	// 规则已从 dice.yml 中移除
+	for name, rule := range managed {
+		if _, ok := desired[name]; ok {
+			continue
+		}
+		if err := a.remove(runtime, rule); err != nil {
+			errs = append(errs, fmt.Sprintf("service %s: %v", name, err))
+		}
+	}
+
+	services := make([]string, 0, len(desired))
+	for name := range desired {
+		if manual[name] {
+			logrus.Warnf("runtime %d service %s already has manual hpa rule, skip dice.yml autoscale", runtime.ID, name)
+			continue
+		}
+		services = append(services, name)
+	}
+	if len(services) == 0 {
+		return joinErrors(errs)
+	}
+
+	targets, err := a.targetRefs(runtime, services)
+	if err != nil {
+		errs = append(errs, err.Error())
+		return joinErrors(errs)
+	}
+	for _, name := range services {
+		target, ok := targets[name]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("service %s: scale target not found", name))
+			continue
+		}
+		var existing *dbclient.RuntimeHPA
+		if rule, ok := managed[name]; ok {
+			existing = &rule
+		}
+		if err := a.apply(runtime, app, operator, name, desired[name], target, existing); err != nil {
+			errs = append(errs, fmt.Sprintf("service %s: %v", name, err))
+		}
+	}
+	return joinErrors(errs)
+}
+
+func (a *Autoscale) apply(runtime *dbclient.Runtime, app *apistructs.ApplicationDTO, operator, name string,
+	svc *diceyml.Service, target pstypes.ErdaHPAObject, existing *dbclient.RuntimeHPA) error {
+	sc, err := BuildScaledConfig(runtime, name, svc, conf.MSPMetricsServerAddr())
+	if err != nil {
+		return err
+	}
+	if hasResourceTrigger(sc) {
+		applied, err := a.vpaApplied(runtime, name)
+		if err != nil {
+			return err
+		}
+		if applied {
+			return errors.New("vpa is enabled, can not apply autoscale with cpu and/or memory trigger")
+		}
+	}
+
+	sc.RuleNameSpace = target.Namespace
+	sc.ScaleTargetRef = &pb.ScaleTargetRef{
+		Kind:       target.Kind,
+		ApiVersion: target.APIVersion,
+		Name:       target.Name,
+	}
+	sc.OrgID = app.OrgID
+	if existing != nil {
+		sc.RuleID = existing.ID
+	} else {
+		sc.RuleID = uuid.NewString()
+	}
+	b, err := json.Marshal(sc)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		if existing.Rules == string(b) && existing.IsApplied == pstypes.RuntimePARuleApplied {
+			return nil
+		}
+		action := pstypes.ErdaHPALabelValueApply
+		if existing.IsApplied == pstypes.RuntimePARuleApplied {
+			action = pstypes.ErdaHPALabelValueReApply
+		}
+		if err := a.scale(runtime, name, string(b), action); err != nil {
+			return err
+		}
+		existing.Rules = string(b)
+		existing.RuleNameSpace = target.Namespace
+		existing.UserID = operator
+		existing.IsApplied = pstypes.RuntimePARuleApplied
+		return a.db.UpdateRuntimeHPA(existing)
+	}
+
+	if err := a.scale(runtime, name, string(b), pstypes.ErdaHPALabelValueApply); err != nil {
+		return err
+	}
+	return a.db.CreateRuntimeHPA(&dbclient.RuntimeHPA{
+		ID:                     sc.RuleID,
+		RuleName:               sc.RuleName,
+		RuleNameSpace:          target.Namespace,
+		OrgID:                  app.OrgID,
+		OrgName:                app.OrgName,
+		OrgDisPlayName:         app.OrgDisplayName,
+		ProjectID:              app.ProjectID,
+		ProjectName:            app.ProjectName,
+		ProjectDisplayName:     app.ProjectDisplayName,
+		ApplicationID:          app.ID,
+		ApplicationName:        app.Name,
+		ApplicationDisPlayName: app.DisplayName,
+		RuntimeID:              runtime.ID,
+		RuntimeName:            runtime.Name,
+		ClusterName:            runtime.ClusterName,
+		Workspace:              runtime.Workspace,
+		UserID:                 operator,
+		ServiceName:            name,
+		Rules:                  string(b),
+		IsApplied:              pstypes.RuntimePARuleApplied,
+	})
+}
+
+func (a *Autoscale) remove(runtime *dbclient.Runtime, rule dbclient.RuntimeHPA) error {
+	if rule.IsApplied == pstypes.RuntimePARuleApplied {
+		if err := a.scale(runtime, rule.ServiceName, rule.Rules, pstypes.ErdaHPALabelValueCancel); err != nil {
+			return err
+		}
+	}
+	return a.db.DeleteRuntimeHPAByRuleId(rule.ID)
+}
+
+func (a *Autoscale) vpaApplied(runtime *dbclient.Runtime, service string) (bool, error) {
+	rules, err := a.db.GetRuntimeVPAByServices(spec.RuntimeUniqueId{
+		ApplicationId: runtime.ApplicationID,
+		Workspace:     runtime.Workspace,
+		Name:          runtime.Name,
+	}, []string{service})
+	if err != nil {
+		return false, err
+	}
+	for _, rule := range rules {
+		if rule.IsApplied == pstypes.RuntimePARuleApplied {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// scale 通过 scheduler 下发 ScaledObject 的创建、更新或删除
+func (a *Autoscale) scale(runtime *dbclient.Runtime, service, rules, action string) error {
+	namespace, name := runtime.ScheduleName.Args()
+	sg := &apistructs.ServiceGroup{
+		ClusterName: runtime.ClusterName,
+		Dice: apistructs.Dice{
+			ID:       name,
+			Type:     namespace,
+			Labels:   map[string]string{pstypes.ErdaPALabelKey: action},
+			Services: []apistructs.Service{{Name: service}},
+		},
+		Extra: map[string]string{service: rules},
+	}
+	_, err := a.serviceGroupImpl.Scale(sg)
+	return err
+}
+
+// targetRefs 查询服务对应的 k8s workload，作为 ScaledObject 的 scaleTargetRef
+func (a *Autoscale) targetRefs(runtime *dbclient.Runtime, services []string) (map[string]pstypes.ErdaHPAObject, error) {
+	namespace, name := runtime.ScheduleName.Args()
+	sg := &apistructs.ServiceGroup{
+		ClusterName: runtime.ClusterName,
+		Dice: apistructs.Dice{
+			ID:       name,
+			Type:     namespace,
+			Labels:   map[string]string{pstypes.ErdaPALabelKey: pstypes.ErdaHPALabelValueCreate},
+			Services: make([]apistructs.Service, 0, len(services)),
+		},
+	}
+	for _, svc := range services {
+		sg.Services = append(sg.Services, apistructs.Service{Name: svc})
+	}
+	objects, err := a.serviceGroupImpl.Scale(sg)
+	if err != nil {
+		return nil, errors.Errorf("get scale target of runtime %s failed: %v", runtime.Name, err)
+	}
+	targets, ok := objects.(map[string]pstypes.ErdaHPAObject)
+	if !ok {
+		return nil, errors.Errorf("get scale target of runtime %s failed: unexpected result type %T", runtime.Name, objects)
+	}
+	return targets, nil
+}
+
+// BuildScaledConfig 将 dice.yml 中服务的 autoscale 声明转换为 HPA 规则, 不包含 scaleTargetRef 和 namespace
+func BuildScaledConfig(runtime *dbclient.Runtime, name string, svc *diceyml.Service, mspServerAddr string) (*pb.ScaledConfig, error) {
+	as := svc.Autoscale
+	if as == nil {
+		return nil, errors.Errorf("service %s not set autoscale", name)
+	}
+
+	replicas := int32(svc.Deployments.Replicas)
+	if replicas <= 0 {
+		replicas = 1
+	}
+	maxReplicas := int32(as.MaxReplicas)
+	minReplicas := int32(as.MinReplicas)
+	if minReplicas == 0 {
+		minReplicas = replicas
+	}
+	if minReplicas > maxReplicas {
+		minReplicas = maxReplicas
+	}
+	if as.ScaleToZero {
+		minReplicas = 0
+	}
+	pollingInterval := int32(as.PollingInterval)
+	if pollingInterval == 0 {
+		pollingInterval = defaultPollingInterval
+	}
+	cooldownPeriod := int32(as.CooldownPeriod)
+	if cooldownPeriod == 0 {
+		cooldownPeriod = defaultCooldownPeriod
+	}
+
+	triggers := make([]*pb.ScaleTriggers, 0, len(as.Triggers))
+	for i, t := range as.Triggers {
+		trigger, err := convertTrigger(runtime, name, t, mspServerAddr)
+		if err != nil {
+			return nil, errors.Errorf("service %s autoscale triggers[%d]: %v", name, i, err)
+		}
+		triggers = append(triggers, trigger)
+	}
+
+	return &pb.ScaledConfig{
+		RuleName:        RuleNamePrefix + strutil.ToLower(name),
+		RuntimeID:       runtime.ID,
+		ApplicationID:   runtime.ApplicationID,
+		ServiceName:     name,
+		PollingInterval: pollingInterval,
+		CooldownPeriod:  cooldownPeriod,
+		MinReplicaCount: minReplicas,
+		MaxReplicaCount: maxReplicas,
+		Advanced: &pb.HPAAdvanced{
+			RestoreToOriginalReplicaCount: true,
+		},
+		Triggers: triggers,
+		Fallback: &pb.FallBack{
+			Replicas: replicas,
+		},
+	}, nil
+}
+
+func convertTrigger(runtime *dbclient.Runtime, name string, t diceyml.AutoscaleTrigger, mspServerAddr string) (*pb.ScaleTriggers, error) {
+	switch t.Type {
+	case diceyml.AutoscaleTriggerCPU, diceyml.AutoscaleTriggerMemory:
+		return &pb.ScaleTriggers{
+			Type: t.Type,
+			Metadata: map[string]string{
+				"type":  pstypes.ErdaHPATriggerCPUMetaType,
+				"value": strconv.Itoa(t.Target),
+			},
+		}, nil
+	case diceyml.AutoscaleTriggerMSP:
+		if mspServerAddr == "" {
+			return nil, errors.New("msp metrics server address not configured")
+		}
+		metricName := t.Metric
+		if metricName == "" {
+			metricName = "msp_custom_metric"
+		}
+		query := t.Query
+		if query == "" {
+			query = fmt.Sprintf(`avg(%s{application_id="%d",runtime_name="%s",service_name="%s"})`,
+				t.Metric, runtime.ApplicationID, runtime.Name, name)
+		}
+		return &pb.ScaleTriggers{
+			Type: kedaTriggerPrometheus,
+			Metadata: map[string]string{
+				"serverAddress": mspServerAddr,
+				"metricName":    metricName,
+				"query":         query,
+				"threshold":     strconv.FormatFloat(t.Threshold, 'f', -1, 64),
+			},
+		}, nil
+	case diceyml.AutoscaleTriggerKafka:
+		lag := t.LagThreshold
+		if lag == 0 {
+			lag = defaultKafkaLagThreshold
+		}
+		return &pb.ScaleTriggers{
+			Type: kedaTriggerKafka,
+			Metadata: map[string]string{
+				"bootstrapServers": t.BootstrapServers,
+				"consumerGroup":    t.ConsumerGroup,
+				"topic":            t.Topic,
+				"lagThreshold":     strconv.Itoa(lag),
+			},
+		}, nil
+	case diceyml.AutoscaleTriggerCron:
+		timezone := t.Timezone
+		if timezone == "" {
+			timezone = defaultCronTimezone
+		}
+		return &pb.ScaleTriggers{
+			Type: pstypes.ErdaHPATriggerCron,
+			Metadata: map[string]string{
+				pstypes.ErdaHPATriggerCronMetaTimeZone:        timezone,
+				pstypes.ErdaHPATriggerCronMetaStart:           t.Start,
+				pstypes.ErdaHPATriggerCronMetaEnd:             t.End,
+				pstypes.ErdaHPATriggerCronMetaDesiredReplicas: strconv.Itoa(t.DesiredReplicas),
+			},
+		}, nil
+	default:
+		return nil, errors.Errorf("unsupported trigger type %q", t.Type)
+	}
+}
+
+func hasResourceTrigger(sc *pb.ScaledConfig) bool {
+	for _, t := range sc.Triggers {
+		if t.Type == pstypes.ErdaHPATriggerCPU || t.Type == pstypes.ErdaHPATriggerMemory {
+			return true
+		}
+	}
+	return false
+}
+
+func joinErrors(errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errs, "; "))
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoscale
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/internal/tools/orchestrator/dbclient"
+	"github.com/erda-project/erda/pkg/parser/diceyml"
+)
+
+func TestBuildScaledConfig(t *testing.T) {
+	runtime := &dbclient.Runtime{Name: "feature/autoscale", ApplicationID: 3}
+	runtime.ID = 10
+	svc := &diceyml.Service{
+		Deployments: diceyml.Deployments{Replicas: 2},
+		Autoscale: &diceyml.Autoscale{
+			MaxReplicas: 8,
+			Triggers: []diceyml.AutoscaleTrigger{
+				{Type: "cpu", Target: 70},
+				{Type: "msp", Metric: "http_qps", Threshold: 100},
+				{Type: "kafka", BootstrapServers: "kafka:9092", Topic: "orders", ConsumerGroup: "g1"},
+				{Type: "cron", Start: "0 8 * * *", End: "0 20 * * *", DesiredReplicas: 4},
+			},
+		},
+	}
+
+	sc, err := BuildScaledConfig(runtime, "Web", svc, "http://monitor:7096")
+	assert.NoError(t, err)
+	assert.Equal(t, "diceyml-web", sc.RuleName)
+	assert.Equal(t, int32(2), sc.MinReplicaCount)
+	assert.Equal(t, int32(8), sc.MaxReplicaCount)
+	assert.Equal(t, int32(30), sc.PollingInterval)
+	assert.Equal(t, int32(300), sc.CooldownPeriod)
+	assert.Equal(t, int32(2), sc.Fallback.Replicas)
+	assert.Equal(t, 4, len(sc.Triggers))
+
+	assert.Equal(t, "cpu", sc.Triggers[0].Type)
+	assert.Equal(t, "70", sc.Triggers[0].Metadata["value"])
+
+	assert.Equal(t, "prometheus", sc.Triggers[1].Type)
+	assert.Equal(t, `avg(http_qps{application_id="3",runtime_name="feature/autoscale",service_name="Web"})`, sc.Triggers[1].Metadata["query"])
+	assert.Equal(t, "100", sc.Triggers[1].Metadata["threshold"])
+
+	assert.Equal(t, "kafka", sc.Triggers[2].Type)
+	assert.Equal(t, "10", sc.Triggers[2].Metadata["lagThreshold"])
+
+	assert.Equal(t, "Asia/Shanghai", sc.Triggers[3].Metadata["timezone"])
+	assert.Equal(t, "4", sc.Triggers[3].Metadata["desiredReplicas"])
+}
+
+func TestBuildScaledConfigScaleToZero(t *testing.T) {
+	runtime := &dbclient.Runtime{Name: "master", ApplicationID: 1}
+	svc := &diceyml.Service{
+		Deployments: diceyml.Deployments{Replicas: 1},
+		Autoscale: &diceyml.Autoscale{
+			MinReplicas:     3,
+			MaxReplicas:     5,
+			ScaleToZero:     true,
+			PollingInterval: 10,
+			Triggers: []diceyml.AutoscaleTrigger{
+				{Type: "kafka", BootstrapServers: "kafka:9092", Topic: "t", ConsumerGroup: "g", LagThreshold: 50},
+			},
+		},
+	}
+	sc, err := BuildScaledConfig(runtime, "consumer", svc, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), sc.MinReplicaCount)
+	assert.Equal(t, int32(10), sc.PollingInterval)
+	assert.Equal(t, "50", sc.Triggers[0].Metadata["lagThreshold"])
+	assert.False(t, hasResourceTrigger(sc))
+}
+
+func TestBuildScaledConfigMSPWithoutServer(t *testing.T) {
+	svc := &diceyml.Service{
+		Autoscale: &diceyml.Autoscale{
+			MaxReplicas: 2,
+			Triggers:    []diceyml.AutoscaleTrigger{{Type: "msp", Query: "sum(x)", Threshold: 1}},
+		},
+	}
+	_, err := BuildScaledConfig(&dbclient.Runtime{}, "web", svc, "")
+	assert.Error(t, err)
+}
@@ -44,6 +44,8 @@ import (
 	"github.com/erda-project/erda/internal/tools/orchestrator/scheduler/impl/servicegroup"
 	"github.com/erda-project/erda/internal/tools/orchestrator/services/addon"
 	"github.com/erda-project/erda/internal/tools/orchestrator/services/apierrors"
+	"github.com/erda-project/erda/internal/tools/orchestrator/services/autoscale"
+	"github.com/erda-project/erda/internal/tools/orchestrator/services/domain"
 	"github.com/erda-project/erda/internal/tools/orchestrator/services/environment"
 	"github.com/erda-project/erda/internal/tools/orchestrator/services/log"
 	"github.com/erda-project/erda/internal/tools/orchestrator/services/migration"
@@ -419,8 +421,7 @@ func (fsm *DeployFSMContext) continuePhasePreService() error {
 			}
 			rootDomain := rootDomains[0]
 			if _, err := fsm.db.GetDefaultDomainOrCreate(fsm.Runtime.ID, name,
-				// TODO: default domain format should be refactored
-				fmt.Sprintf("%s-%s-%d-app.%s", name, strings.ToLower(fsm.Runtime.Workspace), fsm.Runtime.ID, rootDomain)); err != nil {
+				domain.BuildDefaultDomain(fsm.Runtime, name, rootDomain)); err != nil {
 				return err
 			}
 		}
@@ -696,6 +697,14 @@ func (fsm *DeployFSMContext) continuePhaseCompleted() error {
 		fsm.pushLog(fmt.Sprintf("clear previous MySQL Account fialed, error (%v)", err))
 	}
 
+	// 根据 dice.yml 中的 autoscale 声明下发 ScaledObject，失败不影响部署结果
+	if err := autoscale.New(
+		autoscale.WithDBClient(fsm.db),
+		autoscale.WithServiceGroup(fsm.serviceGroupImpl),
+	).Sync(fsm.Runtime, fsm.App, fsm.Deployment.Operator, fsm.Spec); err != nil {
+		fsm.pushLog(fmt.Sprintf("sync dice.yml autoscale rules failed, error (%v)", err))
+	}
+
 	fsm.pushLog(`Deployment Is READY`)
 	fsm.Deployment.Status = apistructs.DeploymentStatusOK
 	now := time.Now()
@@ -813,6 +822,9 @@ func (fsm *DeployFSMContext) requestAddons() error {
 	runtime := fsm.Runtime
 	app := fsm.App
 
+	// addons cloned for preview runtime get a dedicated instance name, so they won't share the instance of base workspace
+	extraParams := runtime.GetExtraParams()
+	cloneAddons := strutil.Split(extraParams[apistructs.PreviewCloneAddonsParamKey], ",", true)
 	var baseAddons []apistructs.AddonCreateItem
 	for name, a := range fsm.Spec.AddOns {
 		if a == nil {
@@ -823,6 +835,9 @@ func (fsm *DeployFSMContext) requestAddons() error {
 		if len(plan) != 2 {
 			return errors.Errorf("addon plan information is not compliant")
 		}
+		if strutil.Exist(cloneAddons, name) {
+			name = strutil.Concat(name, "-mr", extraParams[apistructs.PreviewMergeIDParamKey])
+		}
 		baseAddons = append(baseAddons, apistructs.AddonCreateItem{
 			Name:    name,
 			Type:    plan[0],
@@ -1178,7 +1193,10 @@ func (fsm *DeployFSMContext) generateDeployServiceRequest(group *apistructs.Serv
 	group.ClusterName = runtime.ClusterName
 
 	// generate project namespace into serviceGroup
-	group.ProjectNamespace = fsm.GetProjectNamespace(runtime.Workspace)
+	// preview runtime runs in its own namespace, which is removed with the runtime
+	if !runtime.IsPreview() {
+		group.ProjectNamespace = fsm.GetProjectNamespace(runtime.Workspace)
+	}
 
 	groupLabels := make(map[string]string)
 	utils.AppendEnv(groupLabels, obj.Meta)
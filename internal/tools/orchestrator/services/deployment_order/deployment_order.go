@@ -15,6 +15,7 @@
 package deployment_order
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -110,6 +111,33 @@ func WithAddon(a *addon.Addon) Option {
 	}
 }
 
+// errVulnerabilityGateBlocked is returned when the release violates the vulnerability gate
+var errVulnerabilityGateBlocked = errors.New("blocked by the vulnerability gate")
+
+// checkVulnerabilityGate checks the release against the vulnerability gate of the project workspace.
+// Workspaces without an enabled gate are skipped. Once a gate is enabled the check fails closed:
+// the deployment is refused if dicehub can't evaluate the gate.
+func (d *DeploymentOrder) checkVulnerabilityGate(projectId uint64, releaseId, workspace string) error {
+	gate, err := d.bdl.GetReleaseVulnerabilityGate(projectId, workspace)
+	if err != nil {
+		return fmt.Errorf("failed to get vulnerability gate of %s, deployment is refused until it can be checked, err: %v",
+			workspace, err)
+	}
+	if !gate.Enabled {
+		return nil
+	}
+	result, err := d.bdl.CheckReleaseVulnerabilityGate(releaseId, workspace)
+	if err != nil {
+		return fmt.Errorf("failed to check vulnerability gate of release %s, deployment is refused until it can be checked, err: %v",
+			releaseId, err)
+	}
+	if !result.Passed {
+		return fmt.Errorf("release %s is %w of %s: %s",
+			releaseId, errVulnerabilityGateBlocked, workspace, strings.Join(result.Violations, "; "))
+	}
+	return nil
+}
+
 // batchCheckExecutePermission
 func (d *DeploymentOrder) batchCheckExecutePermission(userId, workspace string, applicationsInfo map[int64]string) error {
 	deniedApps := make([]string, 0)
@@ -120,6 +120,14 @@ func (d *DeploymentOrder) Create(ctx context.Context, req *apistructs.Deployment
 		return nil, apierrors.ErrCreateDeploymentOrder.InternalError(err)
 	}
 
+	// vulnerability gate check, the gate of each project workspace is configured in dicehub
+	if err := d.checkVulnerabilityGate(uint64(releaseData.GetProjectID()), releaseId, req.Workspace); err != nil {
+		if errors.Is(err, errVulnerabilityGateBlocked) {
+			return nil, apierrors.ErrCreateDeploymentOrder.InvalidState(err.Error())
+		}
+		return nil, apierrors.ErrCreateDeploymentOrder.InternalError(err)
+	}
+
 	// compose deployment order
 	order, deployListStr, err := d.composeDeploymentOrder(releaseData, req, deployList)
 	if err != nil {
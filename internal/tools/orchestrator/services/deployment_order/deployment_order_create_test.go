@@ -554,3 +554,54 @@ func Test_renderDeployListWithCrossProject(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckVulnerabilityGate(t *testing.T) {
+	bdl := bundle.New()
+	order := New(WithBundle(bdl))
+
+	var checked []string
+	defer monkey.UnpatchAll()
+	monkey.PatchInstanceMethod(reflect.TypeOf(bdl), "GetReleaseVulnerabilityGate",
+		func(_ *bundle.Bundle, projectID uint64, workspace string) (*apistructs.ReleaseVulnerabilityGate, error) {
+			if projectID == 2 {
+				return nil, fmt.Errorf("dicehub unavailable")
+			}
+			return &apistructs.ReleaseVulnerabilityGate{
+				ProjectID: projectID,
+				Workspace: workspace,
+				Enabled:   workspace == apistructs.WORKSPACE_PROD,
+			}, nil
+		})
+	monkey.PatchInstanceMethod(reflect.TypeOf(bdl), "CheckReleaseVulnerabilityGate",
+		func(_ *bundle.Bundle, releaseId, workspace string) (*apistructs.ReleaseVulnerabilityGateCheckResult, error) {
+			checked = append(checked, releaseId)
+			switch releaseId {
+			case "blocked":
+				return &apistructs.ReleaseVulnerabilityGateCheckResult{
+					Enabled:    true,
+					Violations: []string{"1 CRITICAL vulnerabilities found, at most 0 allowed"},
+				}, nil
+			case "error":
+				return nil, fmt.Errorf("dicehub unavailable")
+			}
+			return &apistructs.ReleaseVulnerabilityGateCheckResult{Passed: true}, nil
+		})
+
+	// gate disabled, release is not checked
+	assert.NoError(t, order.checkVulnerabilityGate(1, "blocked", apistructs.WORKSPACE_TEST))
+	assert.Empty(t, checked)
+
+	assert.NoError(t, order.checkVulnerabilityGate(1, "passed", apistructs.WORKSPACE_PROD))
+	err := order.checkVulnerabilityGate(1, "blocked", apistructs.WORKSPACE_PROD)
+	assert.ErrorIs(t, err, errVulnerabilityGateBlocked)
+	assert.EqualError(t, err, "release blocked is blocked by the vulnerability gate of PROD: "+
+		"1 CRITICAL vulnerabilities found, at most 0 allowed")
+
+	// fail closed when the gate can't be evaluated
+	err = order.checkVulnerabilityGate(1, "error", apistructs.WORKSPACE_PROD)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, errVulnerabilityGateBlocked)
+	err = order.checkVulnerabilityGate(2, "passed", apistructs.WORKSPACE_PROD)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, errVulnerabilityGateBlocked)
+}
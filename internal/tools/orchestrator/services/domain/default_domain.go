@@ -0,0 +1,33 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package domain
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/orchestrator/dbclient"
+)
+
+// BuildDefaultDomain 生成服务的默认域名
+// 普通 runtime: {service}-{workspace}-{runtimeID}-app.{rootDomain}
+// 预览 runtime: {service}-mr{mergeID}-{runtimeID}-preview.{rootDomain}
+func BuildDefaultDomain(runtime *dbclient.Runtime, serviceName, rootDomain string) string {
+	if mergeID := runtime.GetExtraParams()[apistructs.PreviewMergeIDParamKey]; mergeID != "" {
+		return fmt.Sprintf("%s-mr%s-%d-preview.%s", serviceName, mergeID, runtime.ID, rootDomain)
+	}
+	return fmt.Sprintf("%s-%s-%d-app.%s", serviceName, strings.ToLower(runtime.Workspace), runtime.ID, rootDomain)
+}
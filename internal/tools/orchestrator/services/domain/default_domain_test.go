@@ -0,0 +1,32 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/internal/tools/orchestrator/dbclient"
+	"github.com/erda-project/erda/pkg/database/dbengine"
+)
+
+func TestBuildDefaultDomain(t *testing.T) {
+	rt := &dbclient.Runtime{BaseModel: dbengine.BaseModel{ID: 10}, Workspace: "DEV"}
+	assert.Equal(t, "web-dev-10-app.erda.cloud", BuildDefaultDomain(rt, "web", "erda.cloud"))
+
+	rt.ExtraParams = `{"PREVIEW_MERGE_ID":"3"}`
+	assert.Equal(t, "web-mr3-10-preview.erda.cloud", BuildDefaultDomain(rt, "web", "erda.cloud"))
+}
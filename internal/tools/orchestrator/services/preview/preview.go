@@ -0,0 +1,424 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package preview 合并请求预览环境：基于 MR 构建产物创建的临时 runtime，MR 合并或关闭、或者过期后自动销毁
+package preview
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/erda-project/erda-infra/pkg/transport"
+	"github.com/erda-project/erda-proto-go/core/dicehub/release/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/bundle"
+	"github.com/erda-project/erda/internal/pkg/user"
+	"github.com/erda-project/erda/internal/tools/orchestrator/dbclient"
+	"github.com/erda-project/erda/internal/tools/orchestrator/services/apierrors"
+	"github.com/erda-project/erda/internal/tools/orchestrator/services/runtime"
+	"github.com/erda-project/erda/pkg/http/httputil"
+	"github.com/erda-project/erda/pkg/parser/diceyml"
+	"github.com/erda-project/erda/pkg/strutil"
+)
+
+const (
+	// DefaultTTL 预览环境默认存活时间
+	DefaultTTL = 72 * time.Hour
+	// MaxTTL 预览环境最长存活时间
+	MaxTTL = 30 * 24 * time.Hour
+
+	defaultBaseWorkspace = apistructs.DevWorkspace
+	// systemOperator internal service account, used when preview environment is torn down by system
+	systemOperator = "2000"
+)
+
+// Preview 预览环境对象封装
+type Preview struct {
+	db         *dbclient.DBClient
+	bdl        *bundle.Bundle
+	runtime    *runtime.Runtime
+	releaseSvc pb.ReleaseServiceServer
+}
+
+// Option 预览环境对象配置选项
+type Option func(*Preview)
+
+// New 新建预览环境对象实例
+func New(options ...Option) *Preview {
+	p := &Preview{}
+	for _, op := range options {
+		op(p)
+	}
+	return p
+}
+
+// WithDBClient 配置 db client
+func WithDBClient(db *dbclient.DBClient) Option {
+	return func(p *Preview) {
+		p.db = db
+	}
+}
+
+// WithBundle 配置 bundle
+func WithBundle(bdl *bundle.Bundle) Option {
+	return func(p *Preview) {
+		p.bdl = bdl
+	}
+}
+
+// WithRuntime 配置 runtime service
+func WithRuntime(rt *runtime.Runtime) Option {
+	return func(p *Preview) {
+		p.runtime = rt
+	}
+}
+
+// WithReleaseSvc 配置 dicehub release service
+func WithReleaseSvc(svc pb.ReleaseServiceServer) Option {
+	return func(p *Preview) {
+		p.releaseSvc = svc
+	}
+}
+
+// Create 创建预览环境，同一个 MR 已存在存活的预览环境时使用新的制品重新部署并延长过期时间
+func (p *Preview) Create(ctx context.Context, userID user.ID, req *apistructs.PreviewEnvironmentCreateRequest) (*apistructs.PreviewEnvironmentDTO, error) {
+	if err := p.checkPermission(userID, req.ApplicationID, apistructs.CreateAction); err != nil {
+		return nil, err
+	}
+	if err := checkCreateRequest(req); err != nil {
+		return nil, apierrors.ErrCreatePreviewEnvironment.InvalidParameter(err)
+	}
+	ttl := previewTTL(req.TTL)
+	baseWorkspace := strutil.ToUpper(req.BaseWorkspace)
+	if baseWorkspace == "" {
+		baseWorkspace = defaultBaseWorkspace.String()
+	}
+
+	ctx = transport.WithHeader(ctx, metadata.New(map[string]string{httputil.InternalHeader: "true"}))
+	releaseResp, err := p.releaseSvc.GetRelease(ctx, &pb.ReleaseGetRequest{ReleaseID: req.ReleaseID})
+	if err != nil {
+		return nil, apierrors.ErrCreatePreviewEnvironment.InternalError(err)
+	}
+	release := releaseResp.Data
+	if uint64(release.ApplicationID) != req.ApplicationID || uint64(release.ProjectID) != req.ProjectID {
+		return nil, apierrors.ErrCreatePreviewEnvironment.InvalidParameter("release does not correspond to the application")
+	}
+	project, err := p.bdl.GetProject(req.ProjectID)
+	if err != nil {
+		return nil, apierrors.ErrCreatePreviewEnvironment.InternalError(err)
+	}
+	clusterName, ok := project.ClusterConfig[baseWorkspace]
+	if !ok || clusterName == "" {
+		return nil, apierrors.ErrCreatePreviewEnvironment.InvalidState(
+			fmt.Sprintf("cluster of workspace %s is not configured", baseWorkspace))
+	}
+
+	addons, err := addonNames(release.Diceyml, baseWorkspace)
+	if err != nil {
+		return nil, apierrors.ErrCreatePreviewEnvironment.InvalidParameter(err)
+	}
+	policies := resolveAddonPolicies(addons, req.AddonPolicies, req.DefaultAddonPolicy)
+	policiesStr, err := json.Marshal(policies)
+	if err != nil {
+		return nil, apierrors.ErrCreatePreviewEnvironment.InternalError(err)
+	}
+
+	env, err := p.db.FindAlivePreviewEnvironmentByMR(req.ApplicationID, req.MergeID)
+	if err != nil {
+		return nil, apierrors.ErrCreatePreviewEnvironment.InternalError(err)
+	}
+	if env == nil {
+		runtimeName := RuntimeName(req.MergeID)
+		env = &dbclient.PreviewEnvironment{
+			OrgID:         uint64(release.OrgID),
+			ProjectID:     req.ProjectID,
+			ApplicationID: req.ApplicationID,
+			MergeID:       req.MergeID,
+			RuntimeName:   runtimeName,
+			BaseWorkspace: baseWorkspace,
+			Namespace:     dbclient.PreviewNamespace(req.ApplicationID, baseWorkspace, runtimeName),
+			ClusterName:   clusterName,
+			Creator:       req.Operator,
+		}
+	}
+	env.SourceBranch = release.Labels["gitBranch"]
+	env.ReleaseID = req.ReleaseID
+	env.AddonPolicies = string(policiesStr)
+	env.Status = apistructs.PreviewEnvironmentStatusCreating
+	env.ExpiredAt = time.Now().Add(ttl)
+	if err := p.db.UpdatePreviewEnvironment(env); err != nil {
+		return nil, apierrors.ErrCreatePreviewEnvironment.InternalError(err)
+	}
+
+	extraParams, err := json.Marshal(runtimeExtraParams(req.MergeID, policies))
+	if err != nil {
+		return nil, apierrors.ErrCreatePreviewEnvironment.InternalError(err)
+	}
+	deployResp, err := p.runtime.Create(user.ID(req.Operator), &apistructs.RuntimeCreateRequest{
+		Name:        env.RuntimeName,
+		ReleaseID:   req.ReleaseID,
+		Operator:    req.Operator,
+		ClusterName: clusterName,
+		Source:      apistructs.RELEASE,
+		ExtraParams: string(extraParams),
+		Extra: apistructs.RuntimeCreateRequestExtra{
+			OrgID:           uint64(release.OrgID),
+			ProjectID:       req.ProjectID,
+			ApplicationID:   req.ApplicationID,
+			ApplicationName: release.ApplicationName,
+			Workspace:       baseWorkspace,
+			DeployType:      "RELEASE",
+		},
+	})
+	if err != nil {
+		env.Status = apistructs.PreviewEnvironmentStatusCreateFailed
+		if updateErr := p.db.UpdatePreviewEnvironment(env); updateErr != nil {
+			logrus.Errorf("failed to update preview environment %d status, err: %v", env.ID, updateErr)
+		}
+		return nil, err
+	}
+	env.RuntimeID = deployResp.RuntimeID
+	env.Status = apistructs.PreviewEnvironmentStatusRunning
+	if err := p.db.UpdatePreviewEnvironment(env); err != nil {
+		return nil, apierrors.ErrCreatePreviewEnvironment.InternalError(err)
+	}
+	return env.Convert2DTO(), nil
+}
+
+// Get 查询预览环境
+func (p *Preview) Get(userID user.ID, id uint64) (*apistructs.PreviewEnvironmentDTO, error) {
+	env, err := p.db.GetPreviewEnvironment(id)
+	if err != nil {
+		return nil, apierrors.ErrGetPreviewEnvironment.NotFound()
+	}
+	if err := p.checkPermission(userID, env.ApplicationID, apistructs.GetAction); err != nil {
+		return nil, err
+	}
+	return env.Convert2DTO(), nil
+}
+
+// List 查询应用的预览环境列表
+func (p *Preview) List(userID user.ID, req *apistructs.PreviewEnvironmentListRequest) ([]*apistructs.PreviewEnvironmentDTO, error) {
+	if req.ApplicationID == 0 {
+		return nil, apierrors.ErrListPreviewEnvironment.MissingParameter("applicationId")
+	}
+	if err := p.checkPermission(userID, req.ApplicationID, apistructs.GetAction); err != nil {
+		return nil, err
+	}
+	envs, err := p.db.ListPreviewEnvironments(req.ApplicationID, req.Status)
+	if err != nil {
+		return nil, apierrors.ErrListPreviewEnvironment.InternalError(err)
+	}
+	data := make([]*apistructs.PreviewEnvironmentDTO, 0, len(envs))
+	for i := range envs {
+		data = append(data, envs[i].Convert2DTO())
+	}
+	return data, nil
+}
+
+// Delete 手动销毁预览环境
+func (p *Preview) Delete(userID user.ID, id uint64) (*apistructs.PreviewEnvironmentDTO, error) {
+	env, err := p.db.GetPreviewEnvironment(id)
+	if err != nil {
+		return nil, apierrors.ErrDeletePreviewEnvironment.NotFound()
+	}
+	if err := p.checkPermission(userID, env.ApplicationID, apistructs.DeleteAction); err != nil {
+		return nil, err
+	}
+	if err := p.teardown(userID, env, apistructs.PreviewTeardownReasonManual); err != nil {
+		return nil, apierrors.ErrDeletePreviewEnvironment.InternalError(err)
+	}
+	return env.Convert2DTO(), nil
+}
+
+// OnMergeRequestEvent 处理 gittar 合并请求事件，MR 合并或关闭时销毁对应的预览环境
+func (p *Preview) OnMergeRequestEvent(event *apistructs.RepoCreateMrEvent) error {
+	var reason apistructs.PreviewTeardownReason
+	switch event.Content.EventName {
+	case apistructs.GitMergeMREvent:
+		reason = apistructs.PreviewTeardownReasonMerged
+	case apistructs.GitCloseMREvent:
+		reason = apistructs.PreviewTeardownReasonClosed
+	default:
+		return nil
+	}
+	appID, err := strconv.ParseUint(event.ApplicationID, 10, 64)
+	if err != nil {
+		return errors.Wrapf(err, "invalid applicationID %q in event", event.ApplicationID)
+	}
+	env, err := p.db.FindAlivePreviewEnvironmentByMR(appID, int64(event.Content.RepoMergeId))
+	if err != nil {
+		return err
+	}
+	if env == nil {
+		return nil
+	}
+	// the user who merges or closes the mr may have no permission to delete runtime, so tear down by system
+	logrus.Infof("tear down preview environment %d of app %d mr %d, reason: %s", env.ID, appID, env.MergeID, reason)
+	return p.teardown(user.ID(systemOperator), env, reason)
+}
+
+// CleanExpired 销毁所有过期的预览环境
+func (p *Preview) CleanExpired() (bool, error) {
+	envs, err := p.db.FindExpiredPreviewEnvironments(time.Now())
+	if err != nil {
+		logrus.Errorf("failed to find expired preview environments, err: %v", err)
+		return false, nil
+	}
+	for i := range envs {
+		if err := p.teardown(user.ID(systemOperator), &envs[i], apistructs.PreviewTeardownReasonExpired); err != nil {
+			logrus.Errorf("failed to tear down expired preview environment %d, err: %v", envs[i].ID, err)
+		}
+	}
+	return false, nil
+}
+
+func (p *Preview) teardown(operator user.ID, env *dbclient.PreviewEnvironment, reason apistructs.PreviewTeardownReason) error {
+	if !env.IsAlive() {
+		return nil
+	}
+	env.Status = apistructs.PreviewEnvironmentStatusTearingDown
+	env.TeardownReason = reason
+	if err := p.db.UpdatePreviewEnvironment(env); err != nil {
+		return err
+	}
+	if env.RuntimeID != 0 {
+		// runtime, domains and cloned addons are destroyed by runtime deletion
+		if _, err := p.runtime.Delete(operator, env.OrgID, env.RuntimeID); err != nil {
+			return errors.Wrapf(err, "failed to delete preview runtime %d", env.RuntimeID)
+		}
+	}
+	env.Status = apistructs.PreviewEnvironmentStatusDestroyed
+	return p.db.UpdatePreviewEnvironment(env)
+}
+
+func (p *Preview) checkPermission(userID user.ID, appID uint64, action string) error {
+	perm, err := p.bdl.CheckPermission(&apistructs.PermissionCheckRequest{
+		UserID:   userID.String(),
+		Scope:    apistructs.AppScope,
+		ScopeID:  appID,
+		Resource: apistructs.AppResource,
+		Action:   action,
+	})
+	if err != nil {
+		return apierrors.ErrGetPreviewEnvironment.InternalError(err)
+	}
+	if !perm.Access {
+		return apierrors.ErrGetPreviewEnvironment.AccessDenied()
+	}
+	return nil
+}
+
+// RuntimeName 预览环境 runtime 名称
+func RuntimeName(mergeID int64) string {
+	return fmt.Sprintf("preview-mr-%d", mergeID)
+}
+
+func checkCreateRequest(req *apistructs.PreviewEnvironmentCreateRequest) error {
+	if req.ReleaseID == "" {
+		return errors.New("releaseId is empty")
+	}
+	if req.ProjectID == 0 || req.ApplicationID == 0 {
+		return errors.New("projectId or applicationId is empty")
+	}
+	if req.MergeID <= 0 {
+		return errors.New("mergeId is invalid")
+	}
+	if req.BaseWorkspace != "" && !apistructs.DiceWorkspace(strutil.ToUpper(req.BaseWorkspace)).Deployable() {
+		return errors.Errorf("baseWorkspace %s is invalid", req.BaseWorkspace)
+	}
+	for name, policy := range req.AddonPolicies {
+		if !isValidAddonPolicy(policy) {
+			return errors.Errorf("addon policy %s of %s is invalid", policy, name)
+		}
+	}
+	if req.DefaultAddonPolicy != "" && !isValidAddonPolicy(req.DefaultAddonPolicy) {
+		return errors.Errorf("default addon policy %s is invalid", req.DefaultAddonPolicy)
+	}
+	return nil
+}
+
+func isValidAddonPolicy(policy apistructs.PreviewAddonPolicy) bool {
+	return policy == apistructs.PreviewAddonPolicyShare || policy == apistructs.PreviewAddonPolicyClone
+}
+
+// previewTTL ttl in seconds, <= 0 means default, limited by MaxTTL
+func previewTTL(seconds int64) time.Duration {
+	if seconds <= 0 {
+		return DefaultTTL
+	}
+	ttl := time.Duration(seconds) * time.Second
+	if ttl > MaxTTL {
+		return MaxTTL
+	}
+	return ttl
+}
+
+// addonNames returns the addons declared in dice.yml for the workspace
+func addonNames(diceYml, workspace string) ([]string, error) {
+	dice, err := diceyml.NewDeployable([]byte(diceYml), workspace, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse dice.yml of release")
+	}
+	var names []string
+	for name := range dice.Obj().AddOns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// resolveAddonPolicies returns the policy of every addon declared in dice.yml
+func resolveAddonPolicies(addons []string, policies map[string]apistructs.PreviewAddonPolicy,
+	defaultPolicy apistructs.PreviewAddonPolicy) map[string]apistructs.PreviewAddonPolicy {
+	if defaultPolicy == "" {
+		defaultPolicy = apistructs.PreviewAddonPolicyShare
+	}
+	result := make(map[string]apistructs.PreviewAddonPolicy)
+	for _, name := range addons {
+		policy, ok := policies[name]
+		if !ok {
+			policy = defaultPolicy
+		}
+		result[name] = policy
+	}
+	return result
+}
+
+// runtimeExtraParams marks the runtime as preview runtime and carries the addons to clone
+func runtimeExtraParams(mergeID int64, policies map[string]apistructs.PreviewAddonPolicy) map[string]string {
+	var cloneAddons []string
+	for name, policy := range policies {
+		if policy == apistructs.PreviewAddonPolicyClone {
+			cloneAddons = append(cloneAddons, name)
+		}
+	}
+	sort.Strings(cloneAddons)
+	params := map[string]string{
+		apistructs.PreviewMergeIDParamKey: strconv.FormatInt(mergeID, 10),
+	}
+	if len(cloneAddons) > 0 {
+		params[apistructs.PreviewCloneAddonsParamKey] = strings.Join(cloneAddons, ",")
+	}
+	return params
+}
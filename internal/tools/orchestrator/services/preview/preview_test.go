@@ -0,0 +1,103 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preview
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+const testDiceYml = `version: "2.0"
+services:
+  web:
+    image: nginx
+    ports:
+      - port: 80
+        expose: true
+addons:
+  mysql:
+    plan: mysql:basic
+  redis:
+    plan: redis:basic
+  kafka:
+    plan: kafka:basic
+`
+
+func TestCheckCreateRequest(t *testing.T) {
+	valid := apistructs.PreviewEnvironmentCreateRequest{
+		ReleaseID:     "r1",
+		ProjectID:     1,
+		ApplicationID: 2,
+		MergeID:       3,
+		AddonPolicies: map[string]apistructs.PreviewAddonPolicy{"mysql": apistructs.PreviewAddonPolicyClone},
+	}
+	assert.NoError(t, checkCreateRequest(&valid))
+
+	noRelease := valid
+	noRelease.ReleaseID = ""
+	assert.Error(t, checkCreateRequest(&noRelease))
+
+	noMR := valid
+	noMR.MergeID = 0
+	assert.Error(t, checkCreateRequest(&noMR))
+
+	badWorkspace := valid
+	badWorkspace.BaseWorkspace = "preview"
+	assert.Error(t, checkCreateRequest(&badWorkspace))
+
+	badPolicy := valid
+	badPolicy.AddonPolicies = map[string]apistructs.PreviewAddonPolicy{"mysql": "COPY"}
+	assert.Error(t, checkCreateRequest(&badPolicy))
+}
+
+func TestPreviewTTL(t *testing.T) {
+	assert.Equal(t, DefaultTTL, previewTTL(0))
+	assert.Equal(t, time.Hour, previewTTL(3600))
+	assert.Equal(t, MaxTTL, previewTTL(int64(MaxTTL/time.Second)+1))
+}
+
+func TestAddonPolicies(t *testing.T) {
+	addons, err := addonNames(testDiceYml, "DEV")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"kafka", "mysql", "redis"}, addons)
+
+	policies := resolveAddonPolicies(addons, map[string]apistructs.PreviewAddonPolicy{
+		"mysql": apistructs.PreviewAddonPolicyClone,
+	}, "")
+	assert.Equal(t, map[string]apistructs.PreviewAddonPolicy{
+		"kafka": apistructs.PreviewAddonPolicyShare,
+		"mysql": apistructs.PreviewAddonPolicyClone,
+		"redis": apistructs.PreviewAddonPolicyShare,
+	}, policies)
+
+	policies = resolveAddonPolicies(addons, map[string]apistructs.PreviewAddonPolicy{
+		"kafka": apistructs.PreviewAddonPolicyShare,
+	}, apistructs.PreviewAddonPolicyClone)
+	params := runtimeExtraParams(12, policies)
+	assert.Equal(t, "12", params[apistructs.PreviewMergeIDParamKey])
+	assert.Equal(t, "mysql,redis", params[apistructs.PreviewCloneAddonsParamKey])
+
+	params = runtimeExtraParams(12, map[string]apistructs.PreviewAddonPolicy{"kafka": apistructs.PreviewAddonPolicyShare})
+	_, ok := params[apistructs.PreviewCloneAddonsParamKey]
+	assert.False(t, ok)
+}
+
+func TestRuntimeName(t *testing.T) {
+	assert.Equal(t, "preview-mr-7", RuntimeName(7))
+}
@@ -1809,6 +1809,7 @@ func fillRuntimeDataWithServiceGroup(data *apistructs.RuntimeInspectDTO, targetS
 			Expose:      expose,
 			Status:      status,
 			Deployments: apistructs.RuntimeServiceDeploymentsDTO{Replicas: 0},
+			Autoscale:   v.Autoscale,
 		}
 		if sgStatus, ok := statusServiceMap[k]; ok {
 			runtimeInspectService.Status = sgStatus
@@ -0,0 +1,344 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package simulation 部署资源模拟：在不创建任何资源的情况下，评估 dice.yml / 制品 / 部署单
+// 在目标集群中的调度可行性，包括节点可分配资源、标签约束、节点污点以及项目环境配额
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/erda-project/erda-infra/pkg/transport"
+	"github.com/erda-project/erda-proto-go/core/dicehub/release/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/bundle"
+	"github.com/erda-project/erda/internal/pkg/user"
+	"github.com/erda-project/erda/internal/tools/orchestrator/dbclient"
+	"github.com/erda-project/erda/internal/tools/orchestrator/scheduler/impl/servicegroup"
+	"github.com/erda-project/erda/internal/tools/orchestrator/services/apierrors"
+	"github.com/erda-project/erda/pkg/http/httputil"
+	"github.com/erda-project/erda/pkg/parser/diceyml"
+	"github.com/erda-project/erda/pkg/strutil"
+)
+
+const (
+	// scheduleNamespace the service group namespace of simulations, nothing is created under it
+	scheduleNamespace = "simulation"
+	// serviceKeySeparator separates application name and service name when services of
+	// several applications are simulated in one service group
+	serviceKeySeparator = "/"
+)
+
+// Simulation 部署资源模拟对象封装
+type Simulation struct {
+	db               *dbclient.DBClient
+	bdl              *bundle.Bundle
+	releaseSvc       pb.ReleaseServiceServer
+	serviceGroupImpl servicegroup.ServiceGroup
+}
+
+// Option 部署资源模拟对象配置选项
+type Option func(*Simulation)
+
+// New 新建部署资源模拟对象实例
+func New(options ...Option) *Simulation {
+	s := &Simulation{}
+	for _, op := range options {
+		op(s)
+	}
+	return s
+}
+
+// WithDBClient 配置 db client
+func WithDBClient(db *dbclient.DBClient) Option {
+	return func(s *Simulation) {
+		s.db = db
+	}
+}
+
+// WithBundle 配置 bundle
+func WithBundle(bdl *bundle.Bundle) Option {
+	return func(s *Simulation) {
+		s.bdl = bdl
+	}
+}
+
+// WithReleaseSvc 配置 dicehub release service
+func WithReleaseSvc(svc pb.ReleaseServiceServer) Option {
+	return func(s *Simulation) {
+		s.releaseSvc = svc
+	}
+}
+
+// WithServiceGroup 配置 scheduler service group
+func WithServiceGroup(serviceGroupImpl servicegroup.ServiceGroup) Option {
+	return func(s *Simulation) {
+		s.serviceGroupImpl = serviceGroupImpl
+	}
+}
+
+// application the dice.yml of an application to simulate
+type application struct {
+	id        uint64
+	name      string
+	releaseID string
+	diceYml   string
+}
+
+// Simulate 模拟部署，返回每个服务的调度可行性与项目环境配额的检查结果
+func (s *Simulation) Simulate(ctx context.Context, req *apistructs.DeploymentSimulateRequest) (*apistructs.DeploymentSimulateData, error) {
+	if err := checkSimulateRequest(req); err != nil {
+		return nil, apierrors.ErrSimulateDeployment.InvalidParameter(err)
+	}
+
+	if err := s.checkPermission(user.ID(req.Operator), req.ProjectID); err != nil {
+		return nil, err
+	}
+
+	ctx = transport.WithHeader(ctx, metadata.New(map[string]string{httputil.InternalHeader: "true"}))
+	apps, err := s.collectApplications(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(apps) == 0 {
+		return nil, apierrors.ErrSimulateDeployment.InvalidParameter("no application to simulate")
+	}
+
+	project, err := s.bdl.GetProject(req.ProjectID)
+	if err != nil {
+		return nil, apierrors.ErrSimulateDeployment.InternalError(err)
+	}
+	workspace := strutil.ToUpper(req.Workspace)
+	clusterName, ok := project.ClusterConfig[workspace]
+	if !ok || clusterName == "" {
+		return nil, apierrors.ErrSimulateDeployment.InvalidState(
+			fmt.Sprintf("cluster of workspace %s is not configured", workspace))
+	}
+	app, err := s.bdl.GetApp(apps[0].id)
+	if err != nil {
+		return nil, apierrors.ErrSimulateDeployment.InternalError(err)
+	}
+
+	obj, err := mergeDiceYmls(apps, workspace)
+	if err != nil {
+		return nil, apierrors.ErrSimulateDeployment.InvalidParameter(err)
+	}
+	obj.Meta = groupLabels(app, workspace, clusterName)
+	group := apistructs.ServiceGroupCreateV2Request{
+		DiceYml:     *obj,
+		ClusterName: clusterName,
+		ID:          fmt.Sprintf("project-%d-%s", req.ProjectID, strutil.ToLower(workspace)),
+		Type:        scheduleNamespace,
+	}
+	data, err := s.serviceGroupImpl.Simulate(apistructs.ServiceGroupPrecheckRequest(group))
+	if err != nil {
+		return nil, apierrors.ErrSimulateDeployment.InternalError(err)
+	}
+	return convertSimulateData(apps, clusterName, workspace, &data), nil
+}
+
+// collectApplications collect dice.yml of the applications from the request
+func (s *Simulation) collectApplications(ctx context.Context, req *apistructs.DeploymentSimulateRequest) ([]application, error) {
+	switch {
+	case req.DiceYml != "":
+		app, err := s.bdl.GetApp(req.ApplicationID)
+		if err != nil {
+			return nil, apierrors.ErrSimulateDeployment.InternalError(err)
+		}
+		if app.ProjectID != req.ProjectID {
+			return nil, apierrors.ErrSimulateDeployment.InvalidParameter("application does not belong to the project")
+		}
+		return []application{{id: app.ID, name: app.Name, diceYml: req.DiceYml}}, nil
+	case req.ReleaseID != "":
+		return s.releaseApplications(ctx, req.ProjectID, req.ReleaseID, nil)
+	default:
+		order, err := s.db.GetDeploymentOrder(req.DeploymentOrderID)
+		if err != nil {
+			return nil, apierrors.ErrSimulateDeployment.NotFound()
+		}
+		if order.ProjectId != req.ProjectID {
+			return nil, apierrors.ErrSimulateDeployment.InvalidParameter("deployment order does not belong to the project")
+		}
+		var modes []string
+		if order.Modes != "" {
+			modes = strings.Split(order.Modes, ",")
+		}
+		return s.releaseApplications(ctx, req.ProjectID, order.ReleaseId, modes)
+	}
+}
+
+// releaseApplications return the applications of an application release, or of the selected modes of a project release,
+// the release must belong to the project
+func (s *Simulation) releaseApplications(ctx context.Context, projectID uint64, releaseID string, modes []string) ([]application, error) {
+	resp, err := s.releaseSvc.GetRelease(ctx, &pb.ReleaseGetRequest{ReleaseID: releaseID})
+	if err != nil {
+		return nil, apierrors.ErrSimulateDeployment.InternalError(err)
+	}
+	release := resp.GetData()
+	if uint64(release.GetProjectID()) != projectID {
+		return nil, apierrors.ErrSimulateDeployment.InvalidParameter("release does not belong to the project")
+	}
+	if !release.GetIsProjectRelease() {
+		return []application{{
+			id:        uint64(release.GetApplicationID()),
+			name:      release.GetApplicationName(),
+			releaseID: release.GetReleaseID(),
+			diceYml:   release.GetDiceyml(),
+		}}, nil
+	}
+
+	if len(modes) == 0 {
+		for name := range release.GetModes() {
+			modes = append(modes, name)
+		}
+		sort.Strings(modes)
+	}
+	var apps []application
+	registered := make(map[string]bool)
+	for _, mode := range modes {
+		summary, ok := release.GetModes()[mode]
+		if !ok {
+			return nil, apierrors.ErrSimulateDeployment.InvalidParameter(fmt.Sprintf("mode %s does not exist in release", mode))
+		}
+		for _, list := range summary.GetApplicationReleaseList() {
+			for _, r := range list.GetList() {
+				if registered[r.GetApplicationName()] {
+					continue
+				}
+				registered[r.GetApplicationName()] = true
+				apps = append(apps, application{
+					id:        uint64(r.GetApplicationID()),
+					name:      r.GetApplicationName(),
+					releaseID: r.GetReleaseID(),
+					diceYml:   r.GetDiceYml(),
+				})
+			}
+		}
+	}
+	return apps, nil
+}
+
+func (s *Simulation) checkPermission(userID user.ID, projectID uint64) error {
+	perm, err := s.bdl.CheckPermission(&apistructs.PermissionCheckRequest{
+		UserID:   userID.String(),
+		Scope:    apistructs.ProjectScope,
+		ScopeID:  projectID,
+		Resource: apistructs.ProjectResource,
+		Action:   apistructs.GetAction,
+	})
+	if err != nil {
+		return apierrors.ErrSimulateDeployment.InternalError(err)
+	}
+	if !perm.Access {
+		return apierrors.ErrSimulateDeployment.AccessDenied()
+	}
+	return nil
+}
+
+func checkSimulateRequest(req *apistructs.DeploymentSimulateRequest) error {
+	if req.ProjectID == 0 {
+		return fmt.Errorf("missing projectId")
+	}
+	if !apistructs.DiceWorkspace(strutil.ToUpper(req.Workspace)).Deployable() {
+		return fmt.Errorf("invalid workspace %q", req.Workspace)
+	}
+	sources := 0
+	for _, v := range []string{req.DiceYml, req.ReleaseID, req.DeploymentOrderID} {
+		if v != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("exactly one of diceYml, releaseId and deploymentOrderId is required")
+	}
+	if req.DiceYml != "" && req.ApplicationID == 0 {
+		return fmt.Errorf("applicationId is required with diceYml")
+	}
+	return nil
+}
+
+// mergeDiceYmls merge services of all applications into one dice.yml,
+// service names are prefixed with application name when there are several applications
+func mergeDiceYmls(apps []application, workspace string) (*diceyml.Object, error) {
+	obj := &diceyml.Object{
+		Version:  "2.0",
+		Services: diceyml.Services{},
+	}
+	for _, app := range apps {
+		d, err := diceyml.NewDeployable([]byte(app.diceYml), workspace, false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dice.yml of application %s: %v", app.name, err)
+		}
+		for name, svc := range d.Obj().Services {
+			obj.Services[serviceKey(apps, app.name, name)] = svc
+		}
+	}
+	return obj, nil
+}
+
+func serviceKey(apps []application, appName, serviceName string) string {
+	if len(apps) == 1 {
+		return serviceName
+	}
+	return strutil.Concat(appName, serviceKeySeparator, serviceName)
+}
+
+// groupLabels labels used by the label scheduling of the service group, see convertGroupLabels of deployment
+func groupLabels(app *apistructs.ApplicationDTO, workspace, clusterName string) map[string]string {
+	return map[string]string{
+		"SERVICE_TYPE":      "STATELESS",
+		"DICE_ORG":          strconv.FormatUint(app.OrgID, 10),
+		"DICE_ORG_ID":       strconv.FormatUint(app.OrgID, 10),
+		"DICE_ORG_NAME":     app.OrgName,
+		"DICE_PROJECT":      strconv.FormatUint(app.ProjectID, 10),
+		"DICE_PROJECT_ID":   strconv.FormatUint(app.ProjectID, 10),
+		"DICE_PROJECT_NAME": app.ProjectName,
+		"DICE_WORKSPACE":    strutil.ToLower(workspace),
+		"DICE_CLUSTER_NAME": clusterName,
+	}
+}
+
+// convertSimulateData split the service results of the merged service group by application
+func convertSimulateData(apps []application, clusterName, workspace string, data *apistructs.ServiceGroupSimulateData) *apistructs.DeploymentSimulateData {
+	r := &apistructs.DeploymentSimulateData{
+		ClusterName:  clusterName,
+		Workspace:    workspace,
+		Feasible:     strutil.ToLower(data.Status) == strutil.ToLower(string(apistructs.DeploymentStatusOK)),
+		Quota:        data.Quota,
+		Applications: make([]apistructs.ApplicationSimulation, 0, len(apps)),
+	}
+	for _, app := range apps {
+		appSimulation := apistructs.ApplicationSimulation{
+			ApplicationID:   app.id,
+			ApplicationName: app.name,
+			ReleaseID:       app.releaseID,
+			Services:        make(map[string]apistructs.ServiceSimulation),
+		}
+		prefix := serviceKey(apps, app.name, "")
+		for key, svc := range data.Services {
+			if len(apps) > 1 && !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			appSimulation.Services[strings.TrimPrefix(key, prefix)] = svc
+		}
+		r.Applications = append(r.Applications, appSimulation)
+	}
+	return r
+}
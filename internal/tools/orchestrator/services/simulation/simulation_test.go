@@ -0,0 +1,133 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+const webDiceYml = `version: "2.0"
+services:
+  web:
+    image: nginx
+    resources:
+      cpu: 0.5
+      mem: 512
+    deployments:
+      replicas: 1
+environments:
+  production:
+    services:
+      web:
+        deployments:
+          replicas: 3
+`
+
+const workerDiceYml = `version: "2.0"
+services:
+  worker:
+    image: busybox
+    resources:
+      cpu: 1
+      mem: 1024
+`
+
+func TestCheckSimulateRequest(t *testing.T) {
+	valid := apistructs.DeploymentSimulateRequest{ProjectID: 1, Workspace: "prod", ReleaseID: "r1"}
+	assert.NoError(t, checkSimulateRequest(&valid))
+
+	noProject := valid
+	noProject.ProjectID = 0
+	assert.Error(t, checkSimulateRequest(&noProject))
+
+	badWorkspace := valid
+	badWorkspace.Workspace = "preview"
+	assert.Error(t, checkSimulateRequest(&badWorkspace))
+
+	noSource := valid
+	noSource.ReleaseID = ""
+	assert.Error(t, checkSimulateRequest(&noSource))
+
+	twoSources := valid
+	twoSources.DeploymentOrderID = "o1"
+	assert.Error(t, checkSimulateRequest(&twoSources))
+
+	diceYmlWithoutApp := apistructs.DeploymentSimulateRequest{ProjectID: 1, Workspace: "dev", DiceYml: webDiceYml}
+	assert.Error(t, checkSimulateRequest(&diceYmlWithoutApp))
+	diceYmlWithoutApp.ApplicationID = 2
+	assert.NoError(t, checkSimulateRequest(&diceYmlWithoutApp))
+}
+
+func TestMergeDiceYmls(t *testing.T) {
+	single := []application{{id: 1, name: "web-app", diceYml: webDiceYml}}
+	obj, err := mergeDiceYmls(single, "PROD")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, obj.Services["web"].Deployments.Replicas)
+
+	apps := []application{
+		{id: 1, name: "web-app", diceYml: webDiceYml},
+		{id: 2, name: "worker-app", diceYml: workerDiceYml},
+	}
+	obj, err = mergeDiceYmls(apps, "DEV")
+	assert.NoError(t, err)
+	assert.Len(t, obj.Services, 2)
+	assert.Equal(t, 1, obj.Services["web-app/web"].Deployments.Replicas)
+	assert.Equal(t, 1.0, obj.Services["worker-app/worker"].Resources.CPU)
+
+	_, err = mergeDiceYmls([]application{{name: "bad", diceYml: "services: ["}}, "DEV")
+	assert.Error(t, err)
+}
+
+func TestConvertSimulateData(t *testing.T) {
+	apps := []application{
+		{id: 1, name: "web-app", releaseID: "r1"},
+		{id: 2, name: "worker-app", releaseID: "r2"},
+	}
+	data := &apistructs.ServiceGroupSimulateData{
+		Status: "unschedulable",
+		Quota:  &apistructs.QuotaSimulation{Fits: true},
+		Services: map[string]apistructs.ServiceSimulation{
+			"web-app/web":       {Status: "ok", Replicas: 1, SchedulableReplicas: 1},
+			"worker-app/worker": {Status: "unschedulable", Replicas: 2, SchedulableReplicas: 1},
+		},
+	}
+	r := convertSimulateData(apps, "c1", "PROD", data)
+	assert.False(t, r.Feasible)
+	assert.Equal(t, "c1", r.ClusterName)
+	assert.Len(t, r.Applications, 2)
+	assert.Equal(t, "r1", r.Applications[0].ReleaseID)
+	assert.Equal(t, map[string]apistructs.ServiceSimulation{"web": data.Services["web-app/web"]}, r.Applications[0].Services)
+	assert.Equal(t, map[string]apistructs.ServiceSimulation{"worker": data.Services["worker-app/worker"]}, r.Applications[1].Services)
+
+	data = &apistructs.ServiceGroupSimulateData{
+		Status:   "ok",
+		Services: map[string]apistructs.ServiceSimulation{"web": {Status: "ok"}},
+	}
+	r = convertSimulateData(apps[:1], "c1", "PROD", data)
+	assert.True(t, r.Feasible)
+	assert.Contains(t, r.Applications[0].Services, "web")
+}
+
+func TestGroupLabels(t *testing.T) {
+	labels := groupLabels(&apistructs.ApplicationDTO{OrgID: 1, OrgName: "erda", ProjectID: 2, ProjectName: "p"}, "PROD", "c1")
+	assert.Equal(t, "erda", labels["DICE_ORG_NAME"])
+	assert.Equal(t, "prod", labels["DICE_WORKSPACE"])
+	assert.Equal(t, "2", labels["DICE_PROJECT_ID"])
+	assert.Equal(t, "STATELESS", labels["SERVICE_TYPE"])
+}
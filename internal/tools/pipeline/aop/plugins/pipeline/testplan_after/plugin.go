@@ -324,6 +324,10 @@ func filterPipelineTask(allTasks []*spec.PipelineTask) ([]*spec.PipelineTask, []
 	var apiTestTasks []*spec.PipelineTask
 	var snippetTaskPipelineIDs []uint64
 	for _, task := range allTasks {
+		// load test workers and report are not api steps
+		if task.Extra.Action.Labels[apistructs.AutotestType] == apistructs.AutotestLoadTest {
+			continue
+		}
 		if task.Type == apistructs.ActionTypeAPITest && task.Extra.Action.Version == "2.0" {
 			apiTestTasks = append(apiTestTasks, task)
 			continue
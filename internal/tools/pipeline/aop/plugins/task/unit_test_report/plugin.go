@@ -49,11 +49,11 @@ func (p *provider) Handle(ctx *aoptypes.TuneContext) error {
 	}
 
 	var meta = map[string]interface{}{}
+	var results apistructs.TestResults
 	for _, v := range metadata {
 		var err error
 		switch v.Name {
 		case "results":
-			var results apistructs.TestResults
 			err = json.Unmarshal([]byte(v.Value), &results)
 			meta["results"] = results
 		case "totals":
@@ -82,7 +82,31 @@ func (p *provider) Handle(ctx *aoptypes.TuneContext) error {
 		return err
 	}
 
-	return nil
+	return p.applyReportGate(ctx, results)
+}
+
+// applyReportGate decides whether the task passes by the test report gate,
+// failures of quarantined tests don't fail the task.
+func (p *provider) applyReportGate(ctx *aoptypes.TuneContext, results apistructs.TestResults) error {
+	task := ctx.SDK.Task
+	if results.UUID == "" || (task.Status != apistructs.PipelineStatusSuccess && task.Status != apistructs.PipelineStatusFailed) {
+		return nil
+	}
+	gate, err := ctx.SDK.Bundle.GetTestReportGateByUUID(results.UUID)
+	if err != nil {
+		return fmt.Errorf("get unit-test report gate error: %v", err)
+	}
+	status := task.Status
+	switch {
+	case !gate.Passed:
+		status = apistructs.PipelineStatusFailed
+	case len(gate.QuarantinedFailed) > 0:
+		status = apistructs.PipelineStatusSuccess
+	}
+	if status == task.Status {
+		return nil
+	}
+	return ctx.SDK.DBClient.UpdatePipelineTaskStatus(task.ID, status)
 }
 
 func (p *provider) Init(ctx servicehub.Context) error {
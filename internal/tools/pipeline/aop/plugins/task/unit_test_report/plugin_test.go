@@ -0,0 +1,98 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unit_test_report
+
+import (
+	"reflect"
+	"testing"
+
+	"bou.ke/monkey"
+	"github.com/alecthomas/assert"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/bundle"
+	"github.com/erda-project/erda/internal/tools/pipeline/aop/aoptypes"
+	"github.com/erda-project/erda/internal/tools/pipeline/dbclient"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+)
+
+func Test_applyReportGate(t *testing.T) {
+	bdl := &bundle.Bundle{}
+	client := &dbclient.Client{}
+	var gate apistructs.TestReportGateResult
+	monkey.PatchInstanceMethod(reflect.TypeOf(bdl), "GetTestReportGateByUUID", func(_ *bundle.Bundle, uuid string) (*apistructs.TestReportGateResult, error) {
+		return &gate, nil
+	})
+	var updated apistructs.PipelineStatus
+	monkey.PatchInstanceMethod(reflect.TypeOf(client), "UpdatePipelineTaskStatus", func(_ *dbclient.Client, id uint64, status apistructs.PipelineStatus, ops ...dbclient.SessionOption) error {
+		updated = status
+		return nil
+	})
+	defer monkey.UnpatchAll()
+
+	tests := []struct {
+		name   string
+		uuid   string
+		status apistructs.PipelineStatus
+		gate   apistructs.TestReportGateResult
+		want   apistructs.PipelineStatus
+	}{
+		{
+			name:   "only quarantined tests failed",
+			uuid:   "u1",
+			status: apistructs.PipelineStatusFailed,
+			gate:   apistructs.TestReportGateResult{Passed: true, QuarantinedFailed: []string{"suite/a"}},
+			want:   apistructs.PipelineStatusSuccess,
+		},
+		{
+			name:   "gate failed",
+			uuid:   "u1",
+			status: apistructs.PipelineStatusSuccess,
+			gate:   apistructs.TestReportGateResult{Failed: []string{"suite/a"}},
+			want:   apistructs.PipelineStatusFailed,
+		},
+		{
+			name:   "failed without quarantined tests",
+			uuid:   "u1",
+			status: apistructs.PipelineStatusFailed,
+			gate:   apistructs.TestReportGateResult{Passed: true},
+		},
+		{
+			name:   "no uuid",
+			status: apistructs.PipelineStatusFailed,
+			gate:   apistructs.TestReportGateResult{Passed: true, QuarantinedFailed: []string{"suite/a"}},
+		},
+		{
+			name:   "timeout",
+			uuid:   "u1",
+			status: apistructs.PipelineStatusTimeout,
+			gate:   apistructs.TestReportGateResult{Passed: true, QuarantinedFailed: []string{"suite/a"}},
+		},
+	}
+	p := &provider{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gate = tt.gate
+			updated = ""
+			ctx := &aoptypes.TuneContext{SDK: aoptypes.SDK{
+				Bundle:   bdl,
+				DBClient: client,
+				Task:     spec.PipelineTask{ID: 1, Status: tt.status},
+			}}
+			assert.NoError(t, p.applyReportGate(ctx, apistructs.TestResults{UUID: tt.uuid}))
+			assert.Equal(t, tt.want, updated)
+		})
+	}
+}
@@ -29,6 +29,7 @@ type EnvConfig struct {
 	OutParams    []apistructs.APIOutParam      `env:"ACTION_OUT_PARAMS"`
 	Asserts      []APIAssert                   `env:"ACTION_ASSERTS"`
 	GlobalConfig *apistructs.AutoTestAPIConfig `env:"AUTOTEST_API_GLOBAL_CONFIG"`
+	DataSet      *apistructs.APIDataSet        `env:"ACTION_DATASET"`
 
 	MetaFile string `env:"METAFILE"`
 }
@@ -87,15 +88,21 @@ func (h APIHeader) convert() apistructs.APIHeader {
 }
 
 type APIAssert struct {
-	Arg      string      `json:"arg"`
-	Operator string      `json:"operator"`
-	Value    interface{} `json:"value"`
+	Arg        string                         `json:"arg"`
+	Operator   string                         `json:"operator"`
+	Value      interface{}                    `json:"value"`
+	Type       apistructs.APIAssertType       `json:"type"`
+	Quantifier apistructs.APIAssertQuantifier `json:"quantifier"`
+	Capture    string                         `json:"capture"`
 }
 
 func (a APIAssert) convert() apistructs.APIAssert {
 	return apistructs.APIAssert{
-		Arg:      a.Arg,
-		Operator: a.Operator,
-		Value:    jsonparse.JsonOneLine(a.Value),
+		Arg:        a.Arg,
+		Operator:   a.Operator,
+		Value:      jsonparse.JsonOneLine(a.Value),
+		Type:       a.Type,
+		Quantifier: a.Quantifier,
+		Capture:    a.Capture,
 	}
 }
@@ -0,0 +1,378 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logic
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/internal/tools/pipeline/spec"
+	"github.com/erda-project/erda/pkg/apitestsv2"
+	"github.com/erda-project/erda/pkg/discover"
+	"github.com/erda-project/erda/pkg/encoding/jsonparse"
+	"github.com/erda-project/erda/pkg/envconf"
+	"github.com/erda-project/erda/pkg/expression"
+	"github.com/erda-project/erda/pkg/http/customhttp"
+	"github.com/erda-project/erda/pkg/http/httpclient"
+)
+
+const (
+	metaKeyLoadResult     = "load_result"
+	metaKeyLoadSummary    = "load_summary"
+	metaKeyLoadThresholds = "load_thresholds"
+
+	// metricLoadTest is the time series of each worker, metricLoadTestSummary is the aggregated result of the plan
+	metricLoadTest        = "autotest_load_test"
+	metricLoadTestSummary = "autotest_load_test_summary"
+)
+
+// LoadEnvConfig is the params of load test worker and report, see DOP loadTestPlanToYml
+type LoadEnvConfig struct {
+	Load         *apistructs.LoadTestWorkerConfig `env:"ACTION_LOAD"`
+	Steps        string                           `env:"ACTION_LOAD_STEPS"`
+	Report       *apistructs.LoadTestReportConfig `env:"ACTION_LOAD_REPORT"`
+	Results      []string                         `env:"ACTION_LOAD_RESULTS"`
+	GlobalConfig *apistructs.AutoTestAPIConfig    `env:"AUTOTEST_API_GLOBAL_CONFIG"`
+}
+
+// loadStepSpec is the api spec of scene step, same as params of api-test action
+type loadStepSpec struct {
+	Name      string                   `json:"name"`
+	URL       string                   `json:"url"`
+	Method    string                   `json:"method"`
+	Params    []APIParam               `json:"params"`
+	Headers   []APIHeader              `json:"headers"`
+	Body      apistructs.APIBody       `json:"body"`
+	OutParams []apistructs.APIOutParam `json:"out_params"`
+	Asserts   []APIAssert              `json:"asserts"`
+}
+
+// loadStep is the api spec json of step to be rendered by outputs of previous steps in each iteration
+type loadStep struct {
+	alias   string
+	spec    string
+	waitSec int
+}
+
+func isLoadTestTask(task *spec.PipelineTask) bool {
+	envs := mergeEnvs(task)
+	return envs["ACTION_LOAD"] != "" || envs["ACTION_LOAD_REPORT"] != ""
+}
+
+func doLoadTest(ctx context.Context, task *spec.PipelineTask) {
+	kvs := &KVs{}
+	result := ResultFailed
+	defer func() {
+		*kvs = append(KVs{{MetaKeyResult, result}}, *kvs...)
+		callbackMetadata(ctx, task, kvs)
+	}()
+
+	var cfg LoadEnvConfig
+	if err := envconf.Load(&cfg, mergeEnvs(task)); err != nil {
+		clog(ctx).Errorf("failed to parse load test envs, err: %v", err)
+		return
+	}
+	var err error
+	if cfg.Report != nil {
+		result, err = doLoadTestReport(ctx, task, cfg, kvs)
+	} else {
+		result, err = doLoadTestWorker(ctx, task, cfg, kvs)
+	}
+	if err != nil {
+		clog(ctx).Errorf("%v", err)
+	}
+}
+
+// doLoadTestWorker run the scene steps by virtual users of the worker, the result is always success
+// if the load test is finished, errors of requests are judged by the report.
+func doLoadTestWorker(ctx context.Context, task *spec.PipelineTask, cfg LoadEnvConfig, kvs *KVs) (string, error) {
+	steps, err := parseLoadSteps(cfg.Steps)
+	if err != nil {
+		return ResultFailed, err
+	}
+	load := cfg.Load
+	apiTestEnvData, caseParams := parseGlobalConfig(cfg.GlobalConfig)
+	printGlobalAPIConfig(ctx, apiTestEnvData)
+	clusterName, _ := ctx.Value(apistructs.ClusterNameContextKey).(string)
+
+	clog(ctx).Printf("Load test worker %d: %d virtual users, ramp up %ds, duration %ds, think time %dms, %d steps",
+		load.Worker, load.VirtualUsers, load.RampUpSec, load.DurationSec, load.ThinkTimeMs, len(steps))
+
+	// each virtual user has its own cookie jar like a browser session
+	clients := make([]*http.Client, load.VirtualUsers)
+	for i := range clients {
+		jar, err := newCookieJar(apiTestEnvData)
+		if err != nil {
+			return ResultFailed, err
+		}
+		clients[i] = &http.Client{Jar: jar}
+	}
+
+	tags := loadMetricTags(task, load.ProjectID, load.TestPlanID, load.SceneID)
+	tags["worker"] = strconv.Itoa(load.Worker)
+	runner := loadRunner{
+		virtualUsers: load.VirtualUsers,
+		rampUp:       time.Duration(load.RampUpSec) * time.Second,
+		duration:     time.Duration(load.DurationSec) * time.Second,
+		thinkTime:    time.Duration(load.ThinkTimeMs) * time.Millisecond,
+		iterate: func(ctx context.Context, vu int, rec *loadRecorder) bool {
+			return runLoadIteration(ctx, clients[vu], steps, apiTestEnvData, caseParams, clusterName, rec)
+		},
+		onWindow: func(window loadResult) {
+			summary := window.summary()
+			clog(ctx).Printf("Virtual users: %d, requests: %d, errors: %d, rps: %.2f, avg: %.2fms, p95: %.0fms",
+				summary.VirtualUsers, summary.Requests, summary.Errors, summary.RPS, summary.Avg, summary.P95)
+			pushLoadMetric(ctx, metricLoadTest, window.EndAt, tags, summary)
+		},
+	}
+	total := runner.run(ctx)
+
+	b, err := json.Marshal(total)
+	if err != nil {
+		return ResultFailed, err
+	}
+	summary := total.summary()
+	printLoadSummary(ctx, summary)
+	// base64 so that the result can be referenced in json params of report action
+	kvs.add(metaKeyLoadResult, base64.StdEncoding.EncodeToString(b))
+	kvs.add(metaKeyLoadSummary, jsonOneLine(ctx, summary))
+	return ResultSuccess, nil
+}
+
+// doLoadTestReport merge results of workers, push summary metric and judge thresholds
+func doLoadTestReport(ctx context.Context, task *spec.PipelineTask, cfg LoadEnvConfig, kvs *KVs) (string, error) {
+	report := cfg.Report
+	if len(cfg.Results) == 0 {
+		return ResultFailed, fmt.Errorf("no results of load test workers")
+	}
+	var total loadResult
+	for i, encoded := range cfg.Results {
+		result, err := parseLoadResult(encoded)
+		if err != nil {
+			return ResultFailed, fmt.Errorf("invalid result of load test worker %d, err: %v", i, err)
+		}
+		total.merge(result)
+	}
+	summary := total.summary()
+	printLoadSummary(ctx, summary)
+	kvs.add(metaKeyLoadSummary, jsonOneLine(ctx, summary))
+
+	pushLoadMetric(ctx, metricLoadTestSummary, total.EndAt, loadMetricTags(task, report.ProjectID, report.TestPlanID, report.SceneID), summary)
+
+	thresholdResults, passed := judgeLoadThresholds(summary, report.Thresholds)
+	if len(thresholdResults) > 0 {
+		kvs.add(metaKeyLoadThresholds, jsonOneLine(ctx, thresholdResults))
+		addNewLine(ctx)
+		for _, r := range thresholdResults {
+			status := "passed"
+			if !r.Passed {
+				status = "failed"
+			}
+			clog(ctx).Printf("Threshold %s %s %v: actual %v, %s", r.Metric, r.Operator, r.Value, r.Actual, status)
+		}
+	}
+	if !passed {
+		addNewLine(ctx)
+		clog(ctx).Errorf("Load Test failed, thresholds not satisfied")
+		return ResultFailed, nil
+	}
+	addNewLine(ctx, 2)
+	clog(ctx).Println("Load Test Success")
+	return ResultSuccess, nil
+}
+
+func parseLoadSteps(encoded string) ([]loadStep, error) {
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode load test steps, err: %v", err)
+	}
+	var testSteps []apistructs.LoadTestStep
+	if err := json.Unmarshal(b, &testSteps); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal load test steps, err: %v", err)
+	}
+	if len(testSteps) == 0 {
+		return nil, fmt.Errorf("no load test steps")
+	}
+	steps := make([]loadStep, 0, len(testSteps))
+	for _, s := range testSteps {
+		step := loadStep{alias: s.Alias, waitSec: s.WaitSec}
+		if s.Spec != nil {
+			spec, err := json.Marshal(s.Spec)
+			if err != nil {
+				return nil, err
+			}
+			step.spec = string(spec)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func parseLoadResult(encoded string) (loadResult, error) {
+	var result loadResult
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(b, &result)
+	return result, err
+}
+
+// runLoadIteration execute steps of the scene once, the outputs of steps are rendered into later steps.
+// The iteration stops at the first failed step because later steps usually depend on it.
+func runLoadIteration(ctx context.Context, hc *http.Client, steps []loadStep, apiTestEnvData *apistructs.APITestEnvData,
+	globalParams map[string]*apistructs.CaseParams, clusterName string, rec *loadRecorder) bool {
+	// out params are also written to case params, so each iteration has its own copy
+	caseParams := make(map[string]*apistructs.CaseParams, len(globalParams))
+	for k, v := range globalParams {
+		caseParams[k] = v
+	}
+	outputs := map[string]map[string]interface{}{}
+	for _, step := range steps {
+		if step.spec == "" {
+			if !sleepContext(ctx, time.Duration(step.waitSec)*time.Second) {
+				return false
+			}
+			continue
+		}
+		apiInfo, err := renderLoadStep(step.spec, outputs)
+		if err != nil {
+			rec.record(0, true)
+			return false
+		}
+		apiTest := apitestsv2.New(apiInfo, apitestsv2.WithNetportalConfigs(customhttp.GetNetPortalUrl(clusterName)))
+		begin := time.Now()
+		_, apiResp, err := apiTest.Invoke(hc, apiTestEnvData, caseParams)
+		latency := time.Since(begin)
+		if err != nil {
+			rec.record(latency, true)
+			return false
+		}
+		outParams := apiTest.ParseOutParams(apiTest.API.OutParams, apiResp, caseParams)
+		succ := true
+		for _, group := range apiTest.API.Asserts {
+			if groupSucc, _ := apiTest.JudgeAssertsWithResp(apiResp, outParams, group); !groupSucc {
+				succ = false
+				break
+			}
+		}
+		rec.record(latency, !succ)
+		if !succ {
+			return false
+		}
+		outputs[step.alias] = outParams
+	}
+	return true
+}
+
+// renderLoadStep render outputs references of previous steps and convert to api info
+func renderLoadStep(spec string, outputs map[string]map[string]interface{}) (*apistructs.APIInfo, error) {
+	for alias, outParams := range outputs {
+		for key, value := range outParams {
+			ref := expression.GenOutputRef(alias, key)
+			if !strings.Contains(spec, ref) {
+				continue
+			}
+			escaped, err := json.Marshal(jsonparse.JsonOneLine(value))
+			if err != nil {
+				return nil, err
+			}
+			spec = strings.ReplaceAll(spec, ref, string(escaped[1:len(escaped)-1]))
+		}
+	}
+	var stepSpec loadStepSpec
+	if err := json.Unmarshal([]byte(spec), &stepSpec); err != nil {
+		return nil, err
+	}
+	return generateAPIInfoFromEnv(EnvConfig{
+		Name:      stepSpec.Name,
+		URL:       stepSpec.URL,
+		Method:    stepSpec.Method,
+		Params:    stepSpec.Params,
+		Headers:   stepSpec.Headers,
+		Body:      stepSpec.Body,
+		OutParams: stepSpec.OutParams,
+		Asserts:   stepSpec.Asserts,
+	}), nil
+}
+
+func printLoadSummary(ctx context.Context, s loadSummary) {
+	addLineDelimiter(ctx)
+	clog(ctx).Printf("Virtual users: %d, duration: %.2fs", s.VirtualUsers, s.DurationSec)
+	clog(ctx).Printf("Iterations: %d, failed: %d", s.Iterations, s.FailedIterations)
+	clog(ctx).Printf("Requests: %d, errors: %d, error rate: %.2f%%, rps: %.2f", s.Requests, s.Errors, s.ErrorRate, s.RPS)
+	clog(ctx).Printf("Latency(ms): avg %.2f, p50 %.0f, p90 %.0f, p95 %.0f, p99 %.0f, max %.0f", s.Avg, s.P50, s.P90, s.P95, s.P99, s.Max)
+	addLineDelimiter(ctx)
+}
+
+func loadMetricTags(task *spec.PipelineTask, projectID, testPlanID, sceneID uint64) map[string]string {
+	return map[string]string{
+		TagDiceOrgName:  task.Extra.Labels[apistructs.EnvDiceOrgName],
+		TagDiceOrgID:    task.Extra.Labels[apistructs.EnvDiceOrgID],
+		"project_id":    strconv.FormatUint(projectID, 10),
+		"test_plan_id":  strconv.FormatUint(testPlanID, 10),
+		"scene_id":      strconv.FormatUint(sceneID, 10),
+		"pipeline_id":   strconv.FormatUint(task.PipelineID, 10),
+		"pipeline_task": task.Name,
+	}
+}
+
+// pushLoadMetric push load test metric to collector, so that it can be queried in monitor,
+// failure is only logged and does not affect the load test.
+func pushLoadMetric(ctx context.Context, name string, timestampMs int64, tags map[string]string, s loadSummary) {
+	metrics := apistructs.Metrics{Metric: []apistructs.Metric{{
+		Name:      name,
+		Timestamp: time.UnixMilli(timestampMs).UnixNano(),
+		Tags:      tags,
+		Fields: map[string]interface{}{
+			"virtual_users":     s.VirtualUsers,
+			"requests":          s.Requests,
+			"errors":            s.Errors,
+			"iterations":        s.Iterations,
+			"failed_iterations": s.FailedIterations,
+			"rps":               s.RPS,
+			"error_rate":        s.ErrorRate,
+			"avg":               s.Avg,
+			"p50":               s.P50,
+			"p90":               s.P90,
+			"p95":               s.P95,
+			"p99":               s.P99,
+			"max":               s.Max,
+		},
+	}}}
+	var respBody bytes.Buffer
+	resp, err := httpclient.New(httpclient.WithCompleteRedirect()).
+		Post(discover.Collector()).
+		Path("/collect/metrics").
+		JSONBody(&metrics).
+		Header("Content-Type", "application/json").
+		Do().
+		Body(&respBody)
+	if err != nil {
+		clog(ctx).Warnf("failed to push load test metric, err: %v", err)
+		return
+	}
+	if !resp.IsOK() {
+		clog(ctx).Warnf("failed to push load test metric, resp body: %s", respBody.String())
+	}
+}
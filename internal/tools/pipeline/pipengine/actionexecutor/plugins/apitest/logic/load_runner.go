@@ -0,0 +1,159 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logic
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// loadRecorder records requests of all virtual users concurrently,
+// window is the result since last flush which is reported as time series metrics.
+type loadRecorder struct {
+	mu        sync.Mutex
+	total     loadResult
+	window    loadResult
+	activeVUs int64
+}
+
+func (r *loadRecorder) record(latency time.Duration, failed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.total.record(latency, failed)
+	r.window.record(latency, failed)
+}
+
+func (r *loadRecorder) recordIteration(failed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.total.recordIteration(failed)
+	r.window.recordIteration(failed)
+}
+
+// flushWindow return the window result ended at now and start a new window
+func (r *loadRecorder) flushWindow(now time.Time) loadResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	window := r.window
+	window.VirtualUsers = int(atomic.LoadInt64(&r.activeVUs))
+	window.EndAt = now.UnixMilli()
+	r.window = loadResult{StartAt: window.EndAt}
+	return window
+}
+
+// loadIteration execute the scene once by the virtual user, return false if any step failed
+type loadIteration func(ctx context.Context, vu int, rec *loadRecorder) bool
+
+// loadRunner runs iterations by virtual users, virtual users are started evenly in ramp up,
+// and all of them stop after ramp up and duration.
+type loadRunner struct {
+	virtualUsers int
+	rampUp       time.Duration
+	duration     time.Duration
+	thinkTime    time.Duration
+	window       time.Duration
+
+	iterate  loadIteration
+	onWindow func(window loadResult)
+}
+
+const defaultLoadWindow = 10 * time.Second
+
+func (r *loadRunner) run(ctx context.Context) loadResult {
+	rec := &loadRecorder{}
+	start := time.Now()
+	rec.total.StartAt = start.UnixMilli()
+	rec.window.StartAt = rec.total.StartAt
+
+	runCtx, cancel := context.WithDeadline(ctx, start.Add(r.rampUp+r.duration))
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for vu := 0; vu < r.virtualUsers; vu++ {
+		wg.Add(1)
+		go func(vu int) {
+			defer wg.Done()
+			if !sleepContext(runCtx, r.rampUp*time.Duration(vu)/time.Duration(r.virtualUsers)) {
+				return
+			}
+			atomic.AddInt64(&rec.activeVUs, 1)
+			defer atomic.AddInt64(&rec.activeVUs, -1)
+			for runCtx.Err() == nil {
+				succ := r.iterate(runCtx, vu, rec)
+				// the iteration is interrupted by the end of test, not failed
+				if !succ && runCtx.Err() != nil {
+					return
+				}
+				rec.recordIteration(!succ)
+				if !sleepContext(runCtx, r.thinkTime) {
+					return
+				}
+			}
+		}(vu)
+	}
+
+	done := make(chan struct{})
+	windowDone := make(chan struct{})
+	go func() {
+		defer close(windowDone)
+		window := r.window
+		if window <= 0 {
+			window = defaultLoadWindow
+		}
+		ticker := time.NewTicker(window)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				if r.onWindow != nil {
+					r.onWindow(rec.flushWindow(now))
+				}
+			}
+		}
+	}()
+	wg.Wait()
+	close(done)
+	<-windowDone
+
+	end := time.Now()
+	if r.onWindow != nil {
+		r.onWindow(rec.flushWindow(end))
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	total := rec.total
+	total.VirtualUsers = r.virtualUsers
+	total.EndAt = end.UnixMilli()
+	return total
+}
+
+// sleepContext sleep for d, return false if ctx is done before that
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logic
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+// loadBucket return the lower bound of the latency bucket in milliseconds,
+// the relative error of percentiles is within 1%, except for latencies over one minute.
+func loadBucket(ms int64) int64 {
+	switch {
+	case ms < 1000:
+		return ms
+	case ms < 10000:
+		return ms / 10 * 10
+	case ms < 60000:
+		return ms / 100 * 100
+	default:
+		return ms / 1000 * 1000
+	}
+}
+
+// loadHistogram is the latency histogram of requests, buckets are sparse
+// so that it is small enough to be passed between workers and merged.
+type loadHistogram struct {
+	Buckets map[int64]int64 `json:"buckets"`
+	Count   int64           `json:"count"`
+	SumMs   int64           `json:"sumMs"`
+	MaxMs   int64           `json:"maxMs"`
+}
+
+func (h *loadHistogram) add(latency time.Duration) {
+	ms := latency.Milliseconds()
+	if h.Buckets == nil {
+		h.Buckets = make(map[int64]int64)
+	}
+	h.Buckets[loadBucket(ms)]++
+	h.Count++
+	h.SumMs += ms
+	if ms > h.MaxMs {
+		h.MaxMs = ms
+	}
+}
+
+func (h *loadHistogram) merge(o loadHistogram) {
+	if h.Buckets == nil {
+		h.Buckets = make(map[int64]int64)
+	}
+	for bucket, count := range o.Buckets {
+		h.Buckets[bucket] += count
+	}
+	h.Count += o.Count
+	h.SumMs += o.SumMs
+	if o.MaxMs > h.MaxMs {
+		h.MaxMs = o.MaxMs
+	}
+}
+
+// percentile return the latency in milliseconds that p percent of requests are not greater than
+func (h *loadHistogram) percentile(p float64) float64 {
+	if h.Count == 0 {
+		return 0
+	}
+	buckets := make([]int64, 0, len(h.Buckets))
+	for bucket := range h.Buckets {
+		buckets = append(buckets, bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+
+	rank := int64(math.Ceil(p / 100 * float64(h.Count)))
+	if rank < 1 {
+		rank = 1
+	}
+	var cumulative int64
+	for _, bucket := range buckets {
+		cumulative += h.Buckets[bucket]
+		if cumulative >= rank {
+			return float64(bucket)
+		}
+	}
+	return float64(h.MaxMs)
+}
+
+func (h *loadHistogram) avg() float64 {
+	if h.Count == 0 {
+		return 0
+	}
+	return float64(h.SumMs) / float64(h.Count)
+}
+
+// loadResult is the result of a worker, or merged result of all workers.
+// A request is one api step invocation, it is failed if invoke failed or asserts not passed.
+type loadResult struct {
+	VirtualUsers     int   `json:"virtualUsers"`
+	Requests         int64 `json:"requests"`
+	Errors           int64 `json:"errors"`
+	Iterations       int64 `json:"iterations"`
+	FailedIterations int64 `json:"failedIterations"`
+	// StartAt and EndAt are unix milliseconds
+	StartAt int64         `json:"startAt"`
+	EndAt   int64         `json:"endAt"`
+	Latency loadHistogram `json:"latency"`
+}
+
+func (r *loadResult) record(latency time.Duration, failed bool) {
+	r.Requests++
+	if failed {
+		r.Errors++
+	}
+	r.Latency.add(latency)
+}
+
+func (r *loadResult) recordIteration(failed bool) {
+	r.Iterations++
+	if failed {
+		r.FailedIterations++
+	}
+}
+
+// merge merge result of another worker, workers run at the same time so the duration is not accumulated
+func (r *loadResult) merge(o loadResult) {
+	r.VirtualUsers += o.VirtualUsers
+	r.Requests += o.Requests
+	r.Errors += o.Errors
+	r.Iterations += o.Iterations
+	r.FailedIterations += o.FailedIterations
+	if r.StartAt == 0 || (o.StartAt != 0 && o.StartAt < r.StartAt) {
+		r.StartAt = o.StartAt
+	}
+	if o.EndAt > r.EndAt {
+		r.EndAt = o.EndAt
+	}
+	r.Latency.merge(o.Latency)
+}
+
+// loadSummary is the aggregated metrics of a load result, latencies are in milliseconds
+type loadSummary struct {
+	VirtualUsers     int     `json:"virtualUsers"`
+	Requests         int64   `json:"requests"`
+	Errors           int64   `json:"errors"`
+	Iterations       int64   `json:"iterations"`
+	FailedIterations int64   `json:"failedIterations"`
+	DurationSec      float64 `json:"durationSec"`
+	P50              float64 `json:"p50"`
+	P90              float64 `json:"p90"`
+	P95              float64 `json:"p95"`
+	P99              float64 `json:"p99"`
+	Avg              float64 `json:"avg"`
+	Max              float64 `json:"max"`
+	RPS              float64 `json:"rps"`
+	// ErrorRate is percentage
+	ErrorRate float64 `json:"errorRate"`
+}
+
+func (r *loadResult) summary() loadSummary {
+	s := loadSummary{
+		VirtualUsers:     r.VirtualUsers,
+		Requests:         r.Requests,
+		Errors:           r.Errors,
+		Iterations:       r.Iterations,
+		FailedIterations: r.FailedIterations,
+		P50:              r.Latency.percentile(50),
+		P90:              r.Latency.percentile(90),
+		P95:              r.Latency.percentile(95),
+		P99:              r.Latency.percentile(99),
+		Avg:              roundLoadMetric(r.Latency.avg()),
+		Max:              float64(r.Latency.MaxMs),
+	}
+	if r.EndAt > r.StartAt {
+		s.DurationSec = float64(r.EndAt-r.StartAt) / 1000
+		s.RPS = roundLoadMetric(float64(r.Requests) / s.DurationSec)
+	}
+	if r.Requests > 0 {
+		s.ErrorRate = roundLoadMetric(float64(r.Errors) * 100 / float64(r.Requests))
+	}
+	return s
+}
+
+func roundLoadMetric(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// metric return value of the threshold metric
+func (s loadSummary) metric(metric apistructs.LoadTestMetric) float64 {
+	switch metric {
+	case apistructs.LoadTestMetricP50:
+		return s.P50
+	case apistructs.LoadTestMetricP90:
+		return s.P90
+	case apistructs.LoadTestMetricP95:
+		return s.P95
+	case apistructs.LoadTestMetricP99:
+		return s.P99
+	case apistructs.LoadTestMetricAvg:
+		return s.Avg
+	case apistructs.LoadTestMetricMax:
+		return s.Max
+	case apistructs.LoadTestMetricRPS:
+		return s.RPS
+	case apistructs.LoadTestMetricErrorRate:
+		return s.ErrorRate
+	default:
+		return 0
+	}
+}
+
+type loadThresholdResult struct {
+	apistructs.LoadTestThreshold
+	Actual float64 `json:"actual"`
+	Passed bool    `json:"passed"`
+}
+
+// judgeLoadThresholds judge all thresholds, return false if any threshold is not satisfied
+func judgeLoadThresholds(s loadSummary, thresholds []apistructs.LoadTestThreshold) ([]loadThresholdResult, bool) {
+	passed := true
+	results := make([]loadThresholdResult, 0, len(thresholds))
+	for _, threshold := range thresholds {
+		actual := s.metric(threshold.Metric)
+		result := loadThresholdResult{LoadTestThreshold: threshold, Actual: actual, Passed: threshold.Satisfied(actual)}
+		if !result.Passed {
+			passed = false
+		}
+		results = append(results, result)
+	}
+	return results, passed
+}
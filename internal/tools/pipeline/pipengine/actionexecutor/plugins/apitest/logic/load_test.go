@@ -0,0 +1,205 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logic
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+func TestLoadBucket(t *testing.T) {
+	assert.Equal(t, int64(999), loadBucket(999))
+	assert.Equal(t, int64(1230), loadBucket(1234))
+	assert.Equal(t, int64(12300), loadBucket(12345))
+	assert.Equal(t, int64(123000), loadBucket(123456))
+}
+
+func TestLoadHistogram(t *testing.T) {
+	var h loadHistogram
+	for i := 1; i <= 100; i++ {
+		h.add(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, int64(100), h.Count)
+	assert.Equal(t, float64(50), h.percentile(50))
+	assert.Equal(t, float64(95), h.percentile(95))
+	assert.Equal(t, float64(99), h.percentile(99))
+	assert.Equal(t, float64(100), h.percentile(100))
+	assert.Equal(t, 50.5, h.avg())
+
+	// merged histogram is same as recorded by one worker
+	var other loadHistogram
+	for i := 101; i <= 200; i++ {
+		other.add(time.Duration(i) * time.Millisecond)
+	}
+	h.merge(other)
+	assert.Equal(t, int64(200), h.Count)
+	assert.Equal(t, int64(200), h.MaxMs)
+	assert.Equal(t, float64(100), h.percentile(50))
+	assert.Equal(t, float64(190), h.percentile(95))
+
+	var empty loadHistogram
+	assert.Equal(t, float64(0), empty.percentile(95))
+	assert.Equal(t, float64(0), empty.avg())
+}
+
+func TestLoadResultMergeAndSummary(t *testing.T) {
+	var worker1, worker2 loadResult
+	worker1 = loadResult{VirtualUsers: 2, StartAt: 1000, EndAt: 11000}
+	worker2 = loadResult{VirtualUsers: 3, StartAt: 1500, EndAt: 11000}
+	for i := 0; i < 60; i++ {
+		worker1.record(10*time.Millisecond, false)
+	}
+	for i := 0; i < 40; i++ {
+		worker2.record(30*time.Millisecond, i < 10)
+	}
+	worker1.recordIteration(false)
+	worker2.recordIteration(true)
+
+	// results are passed between actions as base64 json
+	b, err := json.Marshal(worker2)
+	assert.NoError(t, err)
+	decoded, err := parseLoadResult(base64.StdEncoding.EncodeToString(b))
+	assert.NoError(t, err)
+
+	var total loadResult
+	total.merge(worker1)
+	total.merge(decoded)
+	s := total.summary()
+	assert.Equal(t, 5, s.VirtualUsers)
+	assert.Equal(t, int64(100), s.Requests)
+	assert.Equal(t, int64(10), s.Errors)
+	assert.Equal(t, int64(2), s.Iterations)
+	assert.Equal(t, int64(1), s.FailedIterations)
+	assert.Equal(t, float64(10), s.DurationSec)
+	assert.Equal(t, float64(10), s.RPS)
+	assert.Equal(t, float64(10), s.ErrorRate)
+	assert.Equal(t, float64(10), s.P50)
+	assert.Equal(t, float64(30), s.P95)
+	assert.Equal(t, float64(18), s.Avg)
+	assert.Equal(t, float64(30), s.Max)
+
+	results, passed := judgeLoadThresholds(s, []apistructs.LoadTestThreshold{
+		{Metric: apistructs.LoadTestMetricP95, Operator: "<", Value: 500},
+		{Metric: apistructs.LoadTestMetricRPS, Operator: ">=", Value: 10},
+	})
+	assert.True(t, passed)
+	assert.Len(t, results, 2)
+
+	results, passed = judgeLoadThresholds(s, []apistructs.LoadTestThreshold{
+		{Metric: apistructs.LoadTestMetricP95, Operator: "<", Value: 500},
+		{Metric: apistructs.LoadTestMetricErrorRate, Operator: "<=", Value: 5},
+	})
+	assert.False(t, passed)
+	assert.True(t, results[0].Passed)
+	assert.False(t, results[1].Passed)
+	assert.Equal(t, float64(10), results[1].Actual)
+}
+
+func TestLoadRunner(t *testing.T) {
+	var iterations, windows int64
+	var maxVU int64
+	runner := loadRunner{
+		virtualUsers: 4,
+		rampUp:       40 * time.Millisecond,
+		duration:     100 * time.Millisecond,
+		thinkTime:    5 * time.Millisecond,
+		window:       30 * time.Millisecond,
+		iterate: func(ctx context.Context, vu int, rec *loadRecorder) bool {
+			atomic.AddInt64(&iterations, 1)
+			for {
+				old := atomic.LoadInt64(&maxVU)
+				if int64(vu) <= old || atomic.CompareAndSwapInt64(&maxVU, old, int64(vu)) {
+					break
+				}
+			}
+			rec.record(time.Millisecond, vu == 0)
+			return vu != 0
+		},
+		onWindow: func(window loadResult) {
+			atomic.AddInt64(&windows, 1)
+		},
+	}
+	result := runner.run(context.Background())
+
+	assert.Equal(t, 4, result.VirtualUsers)
+	assert.Equal(t, int64(3), maxVU)
+	assert.Equal(t, atomic.LoadInt64(&iterations), result.Requests)
+	// the last iterations interrupted by the end of test are not counted
+	assert.True(t, result.Iterations <= result.Requests)
+	assert.True(t, result.FailedIterations > 0)
+	assert.True(t, result.Errors >= result.FailedIterations)
+	assert.True(t, result.EndAt-result.StartAt >= 140)
+	assert.True(t, windows >= 2)
+}
+
+func TestLoadRunnerCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	runner := loadRunner{
+		virtualUsers: 2,
+		rampUp:       time.Second,
+		duration:     time.Minute,
+		iterate: func(ctx context.Context, vu int, rec *loadRecorder) bool {
+			return true
+		},
+	}
+	result := runner.run(ctx)
+	assert.Equal(t, int64(0), result.Iterations)
+}
+
+func TestParseAndRenderLoadSteps(t *testing.T) {
+	testSteps := []apistructs.LoadTestStep{
+		{Alias: "1", Spec: map[string]interface{}{
+			"name":       "login",
+			"url":        "http://localhost/login",
+			"method":     "POST",
+			"out_params": []map[string]interface{}{{"key": "token", "source": "body:json", "expression": "data.token"}},
+		}},
+		{Alias: "2", WaitSec: 1},
+		{Alias: "3", Spec: map[string]interface{}{
+			"name":    "profile",
+			"url":     "http://localhost/profile",
+			"method":  "GET",
+			"headers": []map[string]interface{}{{"key": "Authorization", "value": "Bearer ${{ outputs.1.token }}"}},
+			"asserts": []map[string]interface{}{{"arg": "status", "operator": "=", "value": "200"}},
+		}},
+	}
+	b, err := json.Marshal(testSteps)
+	assert.NoError(t, err)
+	steps, err := parseLoadSteps(base64.StdEncoding.EncodeToString(b))
+	assert.NoError(t, err)
+	assert.Len(t, steps, 3)
+	assert.Equal(t, "", steps[1].spec)
+	assert.Equal(t, 1, steps[1].waitSec)
+
+	api, err := renderLoadStep(steps[2].spec, map[string]map[string]interface{}{"1": {"token": `a"b`}})
+	assert.NoError(t, err)
+	assert.Equal(t, "GET", api.Method)
+	assert.Equal(t, `Bearer a"b`, api.Headers[0].Value)
+	assert.Equal(t, "200", api.Asserts[0][0].Value)
+
+	_, err = parseLoadSteps("invalid")
+	assert.Error(t, err)
+	_, err = parseLoadSteps(base64.StdEncoding.EncodeToString([]byte("[]")))
+	assert.Error(t, err)
+}
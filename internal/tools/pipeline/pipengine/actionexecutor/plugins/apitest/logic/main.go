@@ -69,6 +69,12 @@ func Do(ctx context.Context, task *spec.PipelineTask) {
 	// print logo
 	printLogo(ctx)
 
+	// load test worker and report have their own params
+	if isLoadTestTask(task) {
+		doLoadTest(ctx, task)
+		return
+	}
+
 	// parse conf from task
 	cfg, err := parseConfFromTask(task)
 	if err != nil {
@@ -95,83 +101,165 @@ func Do(ctx context.Context, task *spec.PipelineTask) {
 	defer writeMetaFile(ctx, task, meta)
 
 	// global config
-	var apiTestEnvData *apistructs.APITestEnvData
-	caseParams := make(map[string]*apistructs.CaseParams)
-	if cfg.GlobalConfig != nil {
-		apiTestEnvData = &apistructs.APITestEnvData{}
-		apiTestEnvData.Domain = cfg.GlobalConfig.Domain
-		apiTestEnvData.Header = cfg.GlobalConfig.Header
-		apiTestEnvData.Global = make(map[string]*apistructs.APITestEnvVariable)
-		for name, item := range cfg.GlobalConfig.Global {
-			apiTestEnvData.Global[name] = &apistructs.APITestEnvVariable{
-				Value: item.Value,
-				Type:  item.Type,
+	apiTestEnvData, caseParams := parseGlobalConfig(cfg.GlobalConfig)
+
+	// add cookie jar
+	cookieJar, err := newCookieJar(apiTestEnvData)
+	if err != nil {
+		success = false
+		clog(ctx).Errorf("%v\n", err)
+		return
+	}
+	hc := http.Client{Jar: cookieJar}
+	printGlobalAPIConfig(ctx, apiTestEnvData)
+
+	// do apiTest
+	clusterName := ctx.Value(apistructs.ClusterNameContextKey)
+	if clusterName == nil {
+		clusterName = ""
+	}
+
+	// data driven: invoke once for each row of dataset
+	if cfg.DataSet != nil {
+		rows, err := apitestsv2.ParseDataSet(cfg.DataSet)
+		if err != nil {
+			meta.Result = ResultFailed
+			clog(ctx).Errorf("%v", err)
+			success = false
+			return
+		}
+		var failedRows []int
+		for i, row := range rows {
+			addLineDelimiter(ctx)
+			clog(ctx).Printf("Dataset row %d/%d: %s", i+1, len(rows), jsonOneLine(ctx, row))
+			api, err := apitestsv2.CloneAPIInfo(apiInfo)
+			if err != nil {
+				meta.Result = ResultFailed
+				clog(ctx).Errorf("failed to clone api info, err: %v", err)
+				success = false
+				return
 			}
-			caseParams[name] = &apistructs.CaseParams{
-				Key:   name,
-				Type:  item.Type,
-				Value: item.Value,
+			if !invokeAndAssert(ctx, &hc, api, apiTestEnvData, apitestsv2.DataSetCaseParams(caseParams, row), clusterName.(string), meta) {
+				failedRows = append(failedRows, i+1)
 			}
 		}
+		if len(failedRows) > 0 {
+			meta.Result = ResultFailed
+			addNewLine(ctx)
+			clog(ctx).Errorf("API Test failed on dataset rows: %v", failedRows)
+			success = false
+			return
+		}
+	} else if !invokeAndAssert(ctx, &hc, apiInfo, apiTestEnvData, caseParams, clusterName.(string), meta) {
+		success = false
+		return
 	}
 
-	// add cookie jar
+	meta.Result = ResultSuccess
+
+	addNewLine(ctx, 2)
+	clog(ctx).Println("API Test Success")
+}
+
+// parseGlobalConfig convert global config to api test env data and case params
+func parseGlobalConfig(globalConfig *apistructs.AutoTestAPIConfig) (*apistructs.APITestEnvData, map[string]*apistructs.CaseParams) {
+	var apiTestEnvData *apistructs.APITestEnvData
+	caseParams := make(map[string]*apistructs.CaseParams)
+	if globalConfig == nil {
+		return nil, caseParams
+	}
+	apiTestEnvData = &apistructs.APITestEnvData{}
+	apiTestEnvData.Domain = globalConfig.Domain
+	apiTestEnvData.Header = globalConfig.Header
+	apiTestEnvData.Global = make(map[string]*apistructs.APITestEnvVariable)
+	for name, item := range globalConfig.Global {
+		apiTestEnvData.Global[name] = &apistructs.APITestEnvVariable{
+			Value: item.Value,
+			Type:  item.Type,
+		}
+		caseParams[name] = &apistructs.CaseParams{
+			Key:   name,
+			Type:  item.Type,
+			Value: item.Value,
+		}
+	}
+	return apiTestEnvData, caseParams
+}
+
+// newCookieJar create cookie jar with the cookies kept in global config header
+func newCookieJar(apiTestEnvData *apistructs.APITestEnvData) (*cookiejar.Jar, error) {
 	cookieJar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
 	if apiTestEnvData != nil && apiTestEnvData.Header != nil && len(apiTestEnvData.Header[CookieJar]) > 0 {
 		var cookies cookiejar.Cookies
-		err := json.Unmarshal([]byte(apiTestEnvData.Header[CookieJar]), &cookies)
-		if err != nil {
-			success = false
-			clog(ctx).Errorf("failed to unmarshal cookieJar from header, err: %v\n", err)
-			return
+		if err := json.Unmarshal([]byte(apiTestEnvData.Header[CookieJar]), &cookies); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal cookieJar from header, err: %v", err)
 		}
 		cookieJar.SetEntries(cookies)
 	}
-	hc := http.Client{Jar: cookieJar}
-	printGlobalAPIConfig(ctx, apiTestEnvData)
+	return cookieJar, nil
+}
 
-	// do apiTest
-	clusterName := ctx.Value(apistructs.ClusterNameContextKey)
-	if clusterName == nil {
-		clusterName = ""
-	}
-	apiTest := apitestsv2.New(apiInfo, apitestsv2.WithNetportalConfigs(customhttp.GetNetPortalUrl(clusterName.(string))))
-	apiReq, apiResp, err := apiTest.Invoke(&hc, apiTestEnvData, caseParams)
+// invokeAndAssert invoke api once, parse out params and judge asserts, return false if invoke or asserts failed.
+func invokeAndAssert(ctx context.Context, hc *http.Client, apiInfo *apistructs.APIInfo, apiTestEnvData *apistructs.APITestEnvData,
+	caseParams map[string]*apistructs.CaseParams, clusterName string, meta *Meta) bool {
+	apiTest := apitestsv2.New(apiInfo, apitestsv2.WithNetportalConfigs(customhttp.GetNetPortalUrl(clusterName)))
+	apiReq, apiResp, err := apiTest.Invoke(hc, apiTestEnvData, caseParams)
 	printRenderedHTTPReq(ctx, apiReq)
 	meta.Req = apiReq
 	meta.Resp = apiResp
-	meta.CookieJar = cookieJar.GetEntries()
+	if jar, ok := hc.Jar.(*cookiejar.Jar); ok {
+		meta.CookieJar = jar.GetEntries()
+	}
 	if apiResp != nil {
 		printHTTPResp(ctx, apiResp)
 	}
 	if err != nil {
 		meta.Result = ResultFailed
 		clog(ctx).Errorf("failed to do api test, err: %v", err)
-		success = false
-		return
+		return false
 	}
 
 	// outParams store in metafile for latter use
 	outParams := apiTest.ParseOutParams(apiTest.API.OutParams, apiResp, caseParams)
-	printOutParams(ctx, outParams, meta)
 
 	// judge asserts
+	succ := true
 	if len(apiTest.API.Asserts) > 0 {
 		// 目前有且只有一组 asserts
 		for _, group := range apiTest.API.Asserts {
-			succ, assertResults := apiTest.JudgeAsserts(outParams, group)
-			printAssertResults(ctx, succ, assertResults)
-			if !succ {
-				addNewLine(ctx)
-				clog(ctx).Errorf("API Test Success, but asserts failed")
-				success = false
-				return
+			groupSucc, assertResults := apiTest.JudgeAssertsWithResp(apiResp, outParams, group)
+			printAssertResults(ctx, groupSucc, assertResults)
+			// values captured by regex asserts are exported as out params too
+			addRegexCaptureOutParams(meta, apitestsv2.RegexCaptures(group))
+			if !groupSucc {
+				succ = false
+				break
 			}
 		}
 	}
+	printOutParams(ctx, outParams, meta)
+	if !succ {
+		addNewLine(ctx)
+		clog(ctx).Errorf("API Test Success, but asserts failed")
+		return false
+	}
+	return true
+}
 
-	meta.Result = ResultSuccess
-
-	addNewLine(ctx, 2)
-	clog(ctx).Println("API Test Success")
+func addRegexCaptureOutParams(meta *Meta, captures []string) {
+	for _, capture := range captures {
+		defined := false
+		for _, define := range meta.OutParamsDefine {
+			if define.Key == capture {
+				defined = true
+				break
+			}
+		}
+		if !defined {
+			meta.OutParamsDefine = append(meta.OutParamsDefine, apistructs.APIOutParam{
+				Key:    capture,
+				Source: apistructs.APIOutParamSourceRegexCapture,
+			})
+		}
+	}
 }
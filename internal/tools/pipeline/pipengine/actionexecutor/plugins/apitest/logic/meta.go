@@ -73,8 +73,6 @@ func (kvs *KVs) add(k, v string) {
 }
 
 func writeMetaFile(ctx context.Context, task *spec.PipelineTask, meta *Meta) {
-	log := clog(ctx)
-
 	// kvs 保证顺序
 	kvs := &KVs{}
 
@@ -104,6 +102,13 @@ func writeMetaFile(ctx context.Context, task *spec.PipelineTask, meta *Meta) {
 		}
 	}
 
+	callbackMetadata(ctx, task, kvs)
+}
+
+// callbackMetadata callback metadata fields to pipeline in order
+func callbackMetadata(ctx context.Context, task *spec.PipelineTask, kvs *KVs) {
+	log := clog(ctx)
+
 	var fields []*metadata.MetadataField
 	for _, kv := range *kvs {
 		fields = append(fields, &metadata.MetadataField{Name: kv.k, Value: kv.v})
@@ -327,6 +327,10 @@ func (tr *defaultTaskReconciler) TeardownAfterReconcileDone(ctx context.Context,
 
 	// handle aop synchronously, then do subsequent tasks
 	_ = aop.Handle(aop.NewContextForTask(*task, *p, aoptypes.TuneTriggerTaskAfterExec))
+	// reload task, status may be changed by aop, e.g. unit-test report gate
+	if err := tr.overwriteTaskWithLatest(task); err != nil {
+		tr.log.Errorf("failed to overwrite task with latest after aop(continue teardown), pipelineID: %d, taskID: %d, err: %v", p.ID, task.ID, err)
+	}
 	// report task in edge cluster
 	if tr.edgeRegister.IsEdge() {
 		tr.edgeReporter.TriggerOnceTaskReport(task.ID)
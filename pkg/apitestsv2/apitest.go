@@ -23,6 +23,7 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/erda-project/erda/apistructs"
 	"github.com/erda-project/erda/pkg/http/httpclient"
@@ -212,6 +213,7 @@ func (at *APITest) Invoke(httpClient *http.Client, testEnv *apistructs.APITestEn
 	apiReq.Headers = polishHeadersForCompression(apiReq.Headers)
 
 	var buffer bytes.Buffer
+	start := time.Now()
 	req := httpclient.New(httpclient.WithCompleteRedirect()).
 		Method(apiReq.Method, customReq.URL.Scheme+"://"+customReq.URL.Host, httpclient.NoRetry).
 		Path(customReq.URL.Path).
@@ -222,8 +224,9 @@ func (at *APITest) Invoke(httpClient *http.Client, testEnv *apistructs.APITestEn
 
 	// resp
 	apiResp := apistructs.APIResp{
-		Body:    buffer.Bytes(),
-		BodyStr: buffer.String(),
+		Body:         buffer.Bytes(),
+		BodyStr:      buffer.String(),
+		ResponseTime: time.Since(start).Milliseconds(),
 	}
 	if httpResp != nil {
 		apiResp.Status = httpResp.StatusCode()
@@ -15,25 +15,37 @@
 package apitestsv2
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+
 	"github.com/erda-project/erda/apistructs"
 	"github.com/erda-project/erda/pkg/assert"
+	"github.com/erda-project/erda/pkg/encoding/jsonparse"
+	"github.com/erda-project/erda/pkg/encoding/jsonpath"
+)
+
+const (
+	regexOperatorMatches    = "matches"
+	regexOperatorNotMatches = "not_matches"
 )
 
 // JudgeAsserts 执行断言测试
 func (at *APITest) JudgeAsserts(outParams map[string]interface{}, asserts []apistructs.APIAssert) (bool, []*apistructs.APITestsAssertData) {
+	return at.JudgeAssertsWithResp(nil, outParams, asserts)
+}
+
+// JudgeAssertsWithResp 执行断言测试, JSON Schema、JSONPath、正则、响应头及响应耗时断言需要 apiResp,
+// 正则断言捕获的值写入 outParams
+func (at *APITest) JudgeAssertsWithResp(apiResp *apistructs.APIResp, outParams map[string]interface{}, asserts []apistructs.APIAssert) (bool, []*apistructs.APITestsAssertData) {
 	var results []*apistructs.APITestsAssertData
 	for _, ast := range asserts {
-		// 出参里的值
-		actualValue := outParams[ast.Arg]
-		//var buffer bytes.Buffer
-		//enc := json.NewEncoder(&buffer)
-		//enc.SetEscapeHTML(false)
-		//enc.Encode(actualValue)
-		//actualValueString := buffer.String()
-		//if len(actualValueString) > 0 {
-		//	actualValueString = actualValueString[:len(actualValueString)-1]
-		//}
-		succ, err := assert.DoAssert(actualValue, ast.Operator, ast.Value)
+		actualValue, succ, err := judgeAssert(apiResp, outParams, ast)
 		result := apistructs.APITestsAssertData{
 			Arg:         ast.Arg,
 			Operator:    ast.Operator,
@@ -59,3 +71,175 @@ func (at *APITest) JudgeAsserts(outParams map[string]interface{}, asserts []apis
 	}
 	return globalSuccess, results
 }
+
+// RegexCaptures 返回正则断言会写入出参的名称, 包括 Capture 及命名分组
+func RegexCaptures(asserts []apistructs.APIAssert) []string {
+	var names []string
+	for _, ast := range asserts {
+		if ast.Type != apistructs.APIAssertTypeRegex || ast.Capture == "" {
+			continue
+		}
+		names = append(names, ast.Capture)
+		re, err := regexp.Compile(ast.Value)
+		if err != nil {
+			continue
+		}
+		for _, name := range re.SubexpNames() {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func judgeAssert(apiResp *apistructs.APIResp, outParams map[string]interface{}, ast apistructs.APIAssert) (interface{}, bool, error) {
+	switch ast.Type {
+	case "", apistructs.APIAssertTypeOutParam:
+		// 出参里的值
+		actualValue := outParams[ast.Arg]
+		succ, err := assert.DoAssert(actualValue, ast.Operator, ast.Value)
+		return actualValue, succ, err
+	case apistructs.APIAssertTypeJSONSchema:
+		return assertJSONSchema(apiResp, outParams, ast)
+	case apistructs.APIAssertTypeJSONPath:
+		return assertJSONPath(apiResp, ast)
+	case apistructs.APIAssertTypeRegex:
+		return assertRegex(apiResp, outParams, ast)
+	case apistructs.APIAssertTypeHeader:
+		if apiResp == nil {
+			return nil, false, fmt.Errorf("no response to assert")
+		}
+		var actualValue interface{}
+		if values := http.Header(apiResp.Headers).Values(ast.Arg); len(values) > 0 {
+			actualValue = strings.Join(values, ",")
+		}
+		succ, err := assert.DoAssert(actualValue, ast.Operator, ast.Value)
+		return actualValue, succ, err
+	case apistructs.APIAssertTypeResponseTime:
+		if apiResp == nil {
+			return nil, false, fmt.Errorf("no response to assert")
+		}
+		op := ast.Operator
+		if op == "" {
+			op = "<="
+		}
+		succ, err := assert.DoAssert(apiResp.ResponseTime, op, ast.Value)
+		return apiResp.ResponseTime, succ, err
+	default:
+		return nil, false, fmt.Errorf("invalid assert type: %s", ast.Type)
+	}
+}
+
+func assertJSONSchema(apiResp *apistructs.APIResp, outParams map[string]interface{}, ast apistructs.APIAssert) (interface{}, bool, error) {
+	var doc gojsonschema.JSONLoader
+	if ast.Arg != "" {
+		doc = gojsonschema.NewGoLoader(outParams[ast.Arg])
+	} else {
+		if apiResp == nil {
+			return nil, false, fmt.Errorf("no response to assert")
+		}
+		doc = gojsonschema.NewBytesLoader(apiResp.Body)
+	}
+	result, err := gojsonschema.Validate(gojsonschema.NewStringLoader(ast.Value), doc)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to validate json schema, err: %v", err)
+	}
+	if result.Valid() {
+		return nil, true, nil
+	}
+	var errs []string
+	for _, e := range result.Errors() {
+		errs = append(errs, e.String())
+	}
+	return errs, false, fmt.Errorf("json schema validation failed: %s", strings.Join(errs, "; "))
+}
+
+func assertJSONPath(apiResp *apistructs.APIResp, ast apistructs.APIAssert) (interface{}, bool, error) {
+	if apiResp == nil {
+		return nil, false, fmt.Errorf("no response to assert")
+	}
+	var body interface{}
+	d := json.NewDecoder(bytes.NewReader(apiResp.Body))
+	d.UseNumber()
+	if err := d.Decode(&body); err != nil {
+		return nil, false, fmt.Errorf("response body is not json, err: %v", err)
+	}
+	matches, err := jsonpath.Query(body, ast.Arg)
+	if err != nil {
+		return nil, false, err
+	}
+
+	quantifier := ast.Quantifier
+	if quantifier == "" {
+		quantifier = apistructs.APIAssertQuantifierAny
+	}
+	if quantifier == apistructs.APIAssertQuantifierCount {
+		succ, err := assert.DoAssert(len(matches), ast.Operator, ast.Value)
+		return len(matches), succ, err
+	}
+
+	var passed int
+	for _, m := range matches {
+		succ, err := assert.DoAssert(m, ast.Operator, ast.Value)
+		if err != nil {
+			return matches, false, err
+		}
+		if succ {
+			passed++
+		}
+	}
+	switch quantifier {
+	case apistructs.APIAssertQuantifierAny:
+		return matches, passed > 0, nil
+	case apistructs.APIAssertQuantifierAll:
+		return matches, len(matches) > 0 && passed == len(matches), nil
+	case apistructs.APIAssertQuantifierNone:
+		return matches, passed == 0, nil
+	default:
+		return matches, false, fmt.Errorf("invalid quantifier: %s", quantifier)
+	}
+}
+
+func assertRegex(apiResp *apistructs.APIResp, outParams map[string]interface{}, ast apistructs.APIAssert) (interface{}, bool, error) {
+	var target string
+	if ast.Arg != "" {
+		target = jsonparse.JsonOneLine(outParams[ast.Arg])
+	} else {
+		if apiResp == nil {
+			return nil, false, fmt.Errorf("no response to assert")
+		}
+		target = apiResp.BodyStr
+	}
+	re, err := regexp.Compile(ast.Value)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid regex: %v", err)
+	}
+	sub := re.FindStringSubmatch(target)
+	matched := sub != nil
+	if matched && ast.Capture != "" {
+		captured := sub[0]
+		if len(sub) > 1 {
+			captured = sub[1]
+		}
+		outParams[ast.Capture] = captured
+		for i, name := range re.SubexpNames() {
+			if name != "" {
+				outParams[name] = sub[i]
+			}
+		}
+	}
+
+	var actualValue interface{}
+	if matched {
+		actualValue = sub
+	}
+	switch ast.Operator {
+	case "", regexOperatorMatches:
+		return actualValue, matched, nil
+	case regexOperatorNotMatches:
+		return actualValue, !matched, nil
+	default:
+		return actualValue, false, fmt.Errorf("invalid operator for regex assert: %s", ast.Operator)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apitestsv2
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+func TestJudgeAssertsWithResp(t *testing.T) {
+	body := `{"code":"OK","data":{"total":3,"list":[{"id":1,"status":"ok"},{"id":2,"status":"ok"},{"id":3,"status":"failed"}]},"traceId":"trace-42-abc"}`
+	resp := &apistructs.APIResp{
+		Status:       200,
+		Headers:      http.Header{"Content-Type": []string{"application/json"}, "X-Request-Id": []string{"r1"}},
+		Body:         []byte(body),
+		BodyStr:      body,
+		ResponseTime: 120,
+	}
+	schema := `{"type":"object","required":["code","data"],"properties":{"code":{"type":"string"},"data":{"type":"object","required":["list"]}}}`
+
+	tests := []struct {
+		name   string
+		assert apistructs.APIAssert
+		want   bool
+	}{
+		{name: "out param", assert: apistructs.APIAssert{Arg: "status", Operator: "=", Value: "200"}, want: true},
+		{name: "json schema", assert: apistructs.APIAssert{Type: apistructs.APIAssertTypeJSONSchema, Value: schema}, want: true},
+		{name: "json schema failed", assert: apistructs.APIAssert{Type: apistructs.APIAssertTypeJSONSchema, Value: `{"required":["missing"]}`}, want: false},
+		{name: "jsonpath any", assert: apistructs.APIAssert{Type: apistructs.APIAssertTypeJSONPath, Arg: "$.data.list[*].status", Operator: "=", Value: "failed"}, want: true},
+		{name: "jsonpath all", assert: apistructs.APIAssert{Type: apistructs.APIAssertTypeJSONPath, Arg: "$.data.list[*].status", Operator: "=", Value: "ok", Quantifier: apistructs.APIAssertQuantifierAll}, want: false},
+		{name: "jsonpath none", assert: apistructs.APIAssert{Type: apistructs.APIAssertTypeJSONPath, Arg: "$.data.list[*].id", Operator: ">", Value: "5", Quantifier: apistructs.APIAssertQuantifierNone}, want: true},
+		{name: "jsonpath count", assert: apistructs.APIAssert{Type: apistructs.APIAssertTypeJSONPath, Arg: "$.data.list[*]", Operator: "=", Value: "3", Quantifier: apistructs.APIAssertQuantifierCount}, want: true},
+		{name: "regex", assert: apistructs.APIAssert{Type: apistructs.APIAssertTypeRegex, Value: `trace-(?P<traceNo>\d+)`, Capture: "trace"}, want: true},
+		{name: "regex not matches", assert: apistructs.APIAssert{Type: apistructs.APIAssertTypeRegex, Operator: "not_matches", Value: `error`}, want: true},
+		{name: "header", assert: apistructs.APIAssert{Type: apistructs.APIAssertTypeHeader, Arg: "x-request-id", Operator: "=", Value: "r1"}, want: true},
+		{name: "response time", assert: apistructs.APIAssert{Type: apistructs.APIAssertTypeResponseTime, Value: "100"}, want: false},
+		{name: "response time with operator", assert: apistructs.APIAssert{Type: apistructs.APIAssertTypeResponseTime, Operator: "<", Value: "500"}, want: true},
+		{name: "invalid type", assert: apistructs.APIAssert{Type: "unknown"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := New(&apistructs.APIInfo{})
+			outParams := map[string]interface{}{"status": 200}
+			succ, results := at.JudgeAssertsWithResp(resp, outParams, []apistructs.APIAssert{tt.assert})
+			assert.Equal(t, tt.want, succ, results[0].ErrorInfo)
+		})
+	}
+}
+
+func TestRegexCapture(t *testing.T) {
+	resp := &apistructs.APIResp{BodyStr: `token=abc123; user=42`}
+	asserts := []apistructs.APIAssert{{Type: apistructs.APIAssertTypeRegex, Value: `token=(\w+); user=(?P<userID>\d+)`, Capture: "token"}}
+	outParams := map[string]interface{}{}
+	succ, _ := New(&apistructs.APIInfo{}).JudgeAssertsWithResp(resp, outParams, asserts)
+	assert.True(t, succ)
+	assert.Equal(t, "abc123", outParams["token"])
+	assert.Equal(t, "42", outParams["userID"])
+	assert.Equal(t, []string{"token", "userID"}, RegexCaptures(asserts))
+}
+
+func TestJudgeAssertsWithoutResp(t *testing.T) {
+	succ, results := New(&apistructs.APIInfo{}).JudgeAsserts(map[string]interface{}{}, []apistructs.APIAssert{{Type: apistructs.APIAssertTypeHeader, Arg: "a", Operator: "exist"}})
+	assert.False(t, succ)
+	assert.NotEmpty(t, results[0].ErrorInfo)
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apitestsv2
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/encoding/jsonparse"
+)
+
+const (
+	DataSetFormatCSV  = "csv"
+	DataSetFormatJSON = "json"
+
+	// MaxDataSetRows 数据集最大行数
+	MaxDataSetRows = 1000
+)
+
+// ParseDataSet 解析数据驱动步骤的数据集, 每行返回列名到值的映射
+func ParseDataSet(ds *apistructs.APIDataSet) ([]map[string]interface{}, error) {
+	if ds == nil {
+		return nil, nil
+	}
+	var (
+		rows []map[string]interface{}
+		err  error
+	)
+	switch strings.ToLower(strings.TrimSpace(ds.Format)) {
+	case "", DataSetFormatCSV:
+		rows, err = parseCSVDataSet(ds.Content)
+	case DataSetFormatJSON:
+		err = json.Unmarshal([]byte(ds.Content), &rows)
+	default:
+		return nil, fmt.Errorf("invalid dataset format: %s", ds.Format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse dataset, err: %v", err)
+	}
+	if len(rows) > MaxDataSetRows {
+		return nil, fmt.Errorf("dataset has %d rows, exceeds the limit of %d", len(rows), MaxDataSetRows)
+	}
+	return rows, nil
+}
+
+func parseCSVDataSet(content string) ([]map[string]interface{}, error) {
+	r := csv.NewReader(strings.NewReader(content))
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	var rows []map[string]interface{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(header))
+		for i, key := range header {
+			row[key] = record[i]
+		}
+		rows = append(rows, row)
+	}
+}
+
+// DataSetCaseParams 在 base 的基础上追加一行数据, 供 {{列名}} 渲染使用, 不修改 base
+func DataSetCaseParams(base map[string]*apistructs.CaseParams, row map[string]interface{}) map[string]*apistructs.CaseParams {
+	params := make(map[string]*apistructs.CaseParams, len(base)+len(row))
+	for k, v := range base {
+		params[k] = v
+	}
+	for k, v := range row {
+		params[k] = &apistructs.CaseParams{Key: k, Type: "string", Value: jsonparse.JsonOneLine(v)}
+	}
+	return params
+}
+
+// CloneAPIInfo 深拷贝 API 声明, 渲染会修改 API 声明, 数据集每行需使用独立副本
+func CloneAPIInfo(api *apistructs.APIInfo) (*apistructs.APIInfo, error) {
+	b, err := json.Marshal(api)
+	if err != nil {
+		return nil, err
+	}
+	var cloned apistructs.APIInfo
+	if err := json.Unmarshal(b, &cloned); err != nil {
+		return nil, err
+	}
+	return &cloned, nil
+}
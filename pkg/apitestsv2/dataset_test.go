@@ -0,0 +1,51 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apitestsv2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+func TestParseDataSet(t *testing.T) {
+	rows, err := ParseDataSet(&apistructs.APIDataSet{Format: "csv", Content: "name, age\nalice, 18\nbob,20\n"})
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{{"name": "alice", "age": "18"}, {"name": "bob", "age": "20"}}, rows)
+
+	rows, err = ParseDataSet(&apistructs.APIDataSet{Format: "json", Content: `[{"name":"alice","tags":["a"]}]`})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(rows))
+
+	_, err = ParseDataSet(&apistructs.APIDataSet{Format: "csv", Content: "a,b\n1\n"})
+	assert.Error(t, err)
+	_, err = ParseDataSet(&apistructs.APIDataSet{Format: "xml"})
+	assert.Error(t, err)
+
+	rows, err = ParseDataSet(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, rows)
+}
+
+func TestDataSetCaseParams(t *testing.T) {
+	base := map[string]*apistructs.CaseParams{"host": {Key: "host", Value: "erda.cloud"}}
+	params := DataSetCaseParams(base, map[string]interface{}{"name": "alice", "tags": []interface{}{"a"}})
+	assert.Equal(t, 1, len(base))
+	assert.Equal(t, "erda.cloud", params["host"].Value)
+	assert.Equal(t, "alice", params["name"].Value)
+	assert.Equal(t, `["a"]`, params["tags"].Value)
+}
@@ -40,34 +40,32 @@ var (
 type DialContextFunc func(ctx context.Context, network, address string) (net.Conn, error)
 type DialContextProtoFunc func(ctx context.Context, address string) (net.Conn, error)
 
+// clusterEndpoint is the cluster manager instance which tunnels of cluster connected to
+type clusterEndpoint struct {
+	Addr string
+	// Tunnels are session keys of parallel tunnels of cluster
+	Tunnels []string
+}
+
 func DialContext(clusterKey string) DialContextFunc {
 	return func(ctx context.Context, network, addr string) (net.Conn, error) {
-		session, err := getSession(clusterKey)
-		if err != nil {
-			return nil, errors.Errorf("failed to get session for clusterKey %s, %v", clusterKey, err)
-		}
-		logrus.Debugf("use cluster dialer, key:%s, addr:%s", clusterKey, addr)
-		f := session.getClusterDialer(ctx, clusterKey)
-		if f == nil {
-			return nil, errors.New("get cluster dialer failed")
-		}
-		return f(ctx, network, addr)
+		return dial(ctx, clusterKey, network, addr)
 	}
 }
 
 func DialContextProto(clusterKey, proto string) DialContextProtoFunc {
 	return func(ctx context.Context, addr string) (net.Conn, error) {
-		session, err := getSession(clusterKey)
-		if err != nil {
-			return nil, errors.Errorf("failed to get session for clusterKey %s, %v", clusterKey, err)
-		}
-		logrus.Debugf("use cluster dialer, key:%s, addr:%s", clusterKey, addr)
-		f := session.getClusterDialer(ctx, clusterKey)
-		if f == nil {
-			return nil, errors.New("get cluster dialer failed")
-		}
-		return f(ctx, proto, addr)
+		return dial(ctx, clusterKey, proto, addr)
+	}
+}
+
+func dial(ctx context.Context, clusterKey, network, addr string) (net.Conn, error) {
+	pool, ep, err := getSession(clusterKey)
+	if err != nil {
+		return nil, errors.Errorf("failed to get session for clusterKey %s, %v", clusterKey, err)
 	}
+	logrus.Debugf("use cluster dialer, key:%s, addr:%s", clusterKey, addr)
+	return pool.dial(ctx, clusterKey, ep.Tunnels, network, addr)
 }
 
 func DialContextTCP(clusterKey string) DialContextProtoFunc {
@@ -81,7 +79,7 @@ func queryClusterManagerIP(clusterKey interface{}) (interface{}, bool) {
 	splits := strings.Split(discover.ClusterDialer(), ":")
 	if len(splits) != 2 {
 		log.Errorf("invalid clusterManager addr: %s", discover.ClusterDialer())
-		return "", false
+		return clusterEndpoint{}, false
 	}
 	addr := splits[0]
 	port := splits[1]
@@ -89,51 +87,57 @@ func queryClusterManagerIP(clusterKey interface{}) (interface{}, bool) {
 	resp, err := http.Get(host + fmt.Sprintf("/clusterdialer/ip?clusterKey=%s", clusterKey))
 	if err != nil {
 		log.Errorf("failed to request clusterManager in cache updating in dialContext, %v", err)
-		return "", false
+		return clusterEndpoint{}, false
 	}
 
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		log.Errorf("failed to read from resp in cache updating, %v", err)
-		return "", false
+		return clusterEndpoint{}, false
 	}
 	r := make(map[string]interface{})
 	if err = json.Unmarshal(data, &r); err != nil {
 		log.Errorf("failed to unmarshal resp in cache updating, %v", err)
-		return "", false
+		return clusterEndpoint{}, false
 	}
 
 	succeeded, _ := r["succeeded"].(bool)
 	if !succeeded {
 		errStr, _ := r["error"].(string)
 		log.Errorf("return error from clusterManager in cache updating, %s", errStr)
-		return "", false
+		return clusterEndpoint{}, false
 	}
 
 	ip, _ := r["IP"].(string)
-	return fmt.Sprintf("%s:%s", ip, port), true
+	ep := clusterEndpoint{Addr: fmt.Sprintf("%s:%s", ip, port)}
+	tunnels, _ := r["tunnels"].([]interface{})
+	for _, t := range tunnels {
+		if key, ok := t.(string); ok {
+			ep.Tunnels = append(ep.Tunnels, key)
+		}
+	}
+	return ep, true
 }
 
-func getSession(clusterKey string) (*TunnelSession, error) {
-	clusterManagerEndpoint, ok := ipCache.LoadWithUpdateSync(clusterKey)
+func getSession(clusterKey string) (*tunnelPool, *clusterEndpoint, error) {
+	v, ok := ipCache.LoadWithUpdateSync(clusterKey)
 	if !ok {
 		logrus.Errorf("failed to get clusterManager endpoint for clusterKey %s", clusterKey)
-		return nil, errors.Errorf("failed to get clusterManager endpoint for clusterKey %s", clusterKey)
+		return nil, nil, errors.Errorf("failed to get clusterManager endpoint for clusterKey %s", clusterKey)
 	}
-	if clusterManagerEndpoint == "" {
-		return nil, errors.Errorf("can not found clusterManager endpoint for clusterKey %s", clusterKey)
+	ep, _ := v.(clusterEndpoint)
+	if ep.Addr == "" {
+		return nil, nil, errors.Errorf("can not found clusterManager endpoint for clusterKey %s", clusterKey)
 	}
-	logrus.Debugf("[DEBUG] get clusterManager endpoint succeeded, IP: %s", clusterManagerEndpoint)
+	logrus.Debugf("[DEBUG] get clusterManager endpoint succeeded, IP: %s", ep.Addr)
 
-	var session *TunnelSession
-	v, ok := sessions.Load(clusterManagerEndpoint)
-	if !ok {
-		ctx, cancel := context.WithCancel(context.Background())
-		session = &TunnelSession{expired: ctx, cancel: cancel, clusterManagerEndpoint: clusterManagerEndpoint.(string)}
-		go session.initialize(fmt.Sprintf("ws://%s%s", clusterManagerEndpoint, "/clusterdialer"))
-		sessions.Store(clusterManagerEndpoint, session)
-	} else {
-		session, _ = v.(*TunnelSession)
+	if v, ok := sessions.Load(ep.Addr); ok {
+		return v.(*tunnelPool), &ep, nil
+	}
+	pool := newTunnelPool(ep.Addr, tunnelCount())
+	v, loaded := sessions.LoadOrStore(ep.Addr, pool)
+	if !loaded {
+		pool.start()
 	}
-	return session, nil
+	return v.(*tunnelPool), &ep, nil
 }
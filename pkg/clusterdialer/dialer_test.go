@@ -36,6 +36,7 @@ func TestQueryClusterManagerIP(t *testing.T) {
 		res := map[string]interface{}{
 			"succeeded": true,
 			"IP":        queryIPAddr,
+			"tunnels":   []string{"test", "test-tunnel-1"},
 		}
 		data, _ := json.Marshal(res)
 		io.WriteString(rw, string(data))
@@ -50,8 +51,11 @@ func TestQueryClusterManagerIP(t *testing.T) {
 		t.Error("failed to get cluster manager ip")
 	}
 
-	ip, _ := res.(string)
-	if ip != targetEndpoint {
-		t.Errorf("got IP: %s, want: %s", ip, targetEndpoint)
+	ep, _ := res.(clusterEndpoint)
+	if ep.Addr != targetEndpoint {
+		t.Errorf("got IP: %s, want: %s", ep.Addr, targetEndpoint)
+	}
+	if len(ep.Tunnels) != 2 || ep.Tunnels[1] != "test-tunnel-1" {
+		t.Errorf("got tunnels: %v", ep.Tunnels)
 	}
 }
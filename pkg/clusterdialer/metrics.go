@@ -0,0 +1,53 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterdialer
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const labelClusterKey = "cluster_key"
+
+var (
+	tunnelReconnectsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "clusterdialer_client_tunnel_reconnects_total",
+		Help: "Number of tunnel reconnections to cluster manager.",
+	}, []string{"endpoint"})
+	activeStreamsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "clusterdialer_client_active_streams",
+		Help: "Number of streams opened to cluster.",
+	}, []string{labelClusterKey})
+	streamsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "clusterdialer_client_streams_total",
+		Help: "Number of streams dialed to cluster.",
+	}, []string{labelClusterKey, "result"})
+	streamBytesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "clusterdialer_client_stream_bytes_total",
+		Help: "Bytes transferred by streams to cluster.",
+	}, []string{labelClusterKey, "direction"})
+	dialLatencyHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "clusterdialer_client_dial_latency_seconds",
+		Help:    "Latency of opening a stream to cluster.",
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 12),
+	}, []string{labelClusterKey})
+	streamDurationHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "clusterdialer_client_stream_duration_seconds",
+		Help:    "Lifetime of streams to cluster.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 4, 10),
+	}, []string{labelClusterKey})
+)
+
+func init() {
+	prometheus.MustRegister(tunnelReconnectsCounter, activeStreamsGauge, streamsCounter,
+		streamBytesCounter, dialLatencyHistogram, streamDurationHistogram)
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterdialer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rancher/remotedialer"
+)
+
+const (
+	// EnvTunnelCount is the number of parallel tunnel sessions to each cluster manager instance
+	EnvTunnelCount     = "CLUSTER_DIALER_TUNNELS"
+	defaultTunnelCount = 2
+
+	// maxDialAttempts limits the attempts of a dial over different tunnels
+	maxDialAttempts = 3
+)
+
+// tunnelPool is parallel tunnel sessions to a cluster manager instance,
+// streams are balanced over both the sessions and the tunnels between cluster manager and cluster agent.
+type tunnelPool struct {
+	endpoint string
+	sessions []*TunnelSession
+	// cancel expires all sessions of the pool
+	cancel context.CancelFunc
+
+	lock sync.Mutex
+	// streams is active streams of tunnel keys of clusters
+	streams map[string]int64
+	rr      uint64
+}
+
+func newTunnelPool(endpoint string, size int) *tunnelPool {
+	if size <= 0 {
+		size = 1
+	}
+	p := &tunnelPool{endpoint: endpoint, streams: make(map[string]int64)}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	for i := 0; i < size; i++ {
+		p.sessions = append(p.sessions, &TunnelSession{
+			expired:                ctx,
+			cancel:                 p.cancel,
+			clusterManagerEndpoint: endpoint,
+			index:                  i,
+		})
+	}
+	return p
+}
+
+func (p *tunnelPool) start() {
+	for _, s := range p.sessions {
+		go s.initialize(fmt.Sprintf("ws://%s%s", p.endpoint, "/clusterdialer"))
+	}
+}
+
+func tunnelCount() int {
+	if n, err := strconv.Atoi(os.Getenv(EnvTunnelCount)); err == nil && n > 0 {
+		return n
+	}
+	return defaultTunnelCount
+}
+
+// sessionOrder returns sessions in order of preference, connected sessions with fewer active streams first
+func (p *tunnelPool) sessionOrder() []*TunnelSession {
+	p.lock.Lock()
+	p.rr++
+	offset := int(p.rr % uint64(len(p.sessions)))
+	p.lock.Unlock()
+
+	result := append(append([]*TunnelSession{}, p.sessions[offset:]...), p.sessions[:offset]...)
+	connected := make(map[*TunnelSession]bool, len(result))
+	for _, s := range result {
+		connected[s] = s.isConnected()
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if connected[result[i]] != connected[result[j]] {
+			return connected[result[i]]
+		}
+		return atomic.LoadInt64(&result[i].activeStreams) < atomic.LoadInt64(&result[j].activeStreams)
+	})
+	return result
+}
+
+// tunnelOrder returns tunnel keys of cluster in order of active streams
+func (p *tunnelPool) tunnelOrder(keys []string) []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.rr++
+	offset := int(p.rr % uint64(len(keys)))
+	result := append(append([]string{}, keys[offset:]...), keys[:offset]...)
+	sort.SliceStable(result, func(i, j int) bool {
+		return p.streams[result[i]] < p.streams[result[j]]
+	})
+	return result
+}
+
+func (p *tunnelPool) addStream(key string, delta int64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.streams[key] += delta
+	if p.streams[key] <= 0 {
+		delete(p.streams, key)
+	}
+}
+
+// dial opens a stream to address in cluster, it waits for reconnecting sessions,
+// and retries over other tunnels when a tunnel is broken.
+func (p *tunnelPool) dial(ctx context.Context, clusterKey string, tunnelKeys []string, network, address string) (net.Conn, error) {
+	if len(tunnelKeys) == 0 {
+		tunnelKeys = []string{clusterKey}
+	}
+	var (
+		attempts int
+		lastErr  error
+	)
+	for _, s := range p.sessionOrder() {
+		session := s.waitSession(ctx, clusterKey)
+		if session == nil {
+			break
+		}
+		for _, key := range p.tunnelOrder(tunnelKeys) {
+			attempts++
+			start := time.Now()
+			conn, err := remotedialer.ToDialer(session, key)(ctx, network, address)
+			if err == nil {
+				streamsCounter.WithLabelValues(clusterKey, "success").Inc()
+				dialLatencyHistogram.WithLabelValues(clusterKey).Observe(time.Since(start).Seconds())
+				return p.newMeteredConn(conn, clusterKey, key, s), nil
+			}
+			streamsCounter.WithLabelValues(clusterKey, "failed").Inc()
+			lastErr = errors.Errorf("dial %s over tunnel %s of %s failed, %v", address, key, p.endpoint, err)
+			if ctx.Err() != nil || attempts >= maxDialAttempts {
+				return nil, lastErr
+			}
+		}
+	}
+	if lastErr == nil {
+		lastErr = errors.New("get cluster dialer failed")
+	}
+	return nil, lastErr
+}
+
+// meteredConn counts bytes and lifetime of a stream
+type meteredConn struct {
+	net.Conn
+	clusterKey string
+	start      time.Time
+	once       sync.Once
+	release    func()
+}
+
+func (p *tunnelPool) newMeteredConn(conn net.Conn, clusterKey, tunnelKey string, s *TunnelSession) *meteredConn {
+	atomic.AddInt64(&s.activeStreams, 1)
+	p.addStream(tunnelKey, 1)
+	activeStreamsGauge.WithLabelValues(clusterKey).Inc()
+	return &meteredConn{
+		Conn:       conn,
+		clusterKey: clusterKey,
+		start:      time.Now(),
+		release: func() {
+			atomic.AddInt64(&s.activeStreams, -1)
+			p.addStream(tunnelKey, -1)
+			activeStreamsGauge.WithLabelValues(clusterKey).Dec()
+		},
+	}
+}
+
+func (c *meteredConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		streamBytesCounter.WithLabelValues(c.clusterKey, "received").Add(float64(n))
+	}
+	return n, err
+}
+
+func (c *meteredConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		streamBytesCounter.WithLabelValues(c.clusterKey, "sent").Add(float64(n))
+	}
+	return n, err
+}
+
+func (c *meteredConn) Close() error {
+	c.once.Do(func() {
+		c.release()
+		streamDurationHistogram.WithLabelValues(c.clusterKey).Observe(time.Since(c.start).Seconds())
+	})
+	return c.Conn.Close()
+}
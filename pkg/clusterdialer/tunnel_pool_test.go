@@ -0,0 +1,103 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterdialer
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rancher/remotedialer"
+)
+
+func TestTunnelPool_sessionOrder(t *testing.T) {
+	p := newTunnelPool("127.0.0.1:80", 3)
+	// only the last session is connected
+	p.sessions[2].setSession(&remotedialer.Session{})
+	p.sessions[0].activeStreams = 1
+	for i := 0; i < 3; i++ {
+		order := p.sessionOrder()
+		if order[0] != p.sessions[2] {
+			t.Errorf("connected session should be preferred")
+		}
+	}
+
+	p.sessions[1].setSession(&remotedialer.Session{})
+	p.sessions[2].activeStreams = 2
+	if order := p.sessionOrder(); order[0] != p.sessions[1] || order[2] != p.sessions[0] {
+		t.Errorf("session with less streams should be preferred")
+	}
+}
+
+func TestTunnelPool_tunnelOrder(t *testing.T) {
+	p := newTunnelPool("127.0.0.1:80", 1)
+	keys := []string{"c1", "c1-tunnel-1"}
+
+	// tunnels without streams are used in turn
+	first, second := p.tunnelOrder(keys)[0], p.tunnelOrder(keys)[0]
+	if first == second {
+		t.Errorf("tunnels are not used in turn")
+	}
+
+	p.addStream("c1", 1)
+	for i := 0; i < 2; i++ {
+		if order := p.tunnelOrder(keys); order[0] != "c1-tunnel-1" {
+			t.Errorf("tunnel with less streams should be preferred, got %v", order)
+		}
+	}
+	p.addStream("c1", -1)
+	if len(p.streams) != 0 {
+		t.Errorf("streams should be cleaned, got %v", p.streams)
+	}
+}
+
+func TestTunnelSession_ready(t *testing.T) {
+	p := newTunnelPool("127.0.0.1:80", 1)
+	s := p.sessions[0]
+	if s.isConnected() {
+		t.Fatal("session should not be connected")
+	}
+	_, ready := s.current()
+	go s.setSession(&remotedialer.Session{})
+	select {
+	case <-ready:
+	case <-time.After(time.Second):
+		t.Fatal("waiting dialers should be notified when session connected")
+	}
+	if !s.isConnected() {
+		t.Error("session should be connected")
+	}
+}
+
+func TestTunnelCount(t *testing.T) {
+	defer os.Unsetenv(EnvTunnelCount)
+	if n := tunnelCount(); n != defaultTunnelCount {
+		t.Errorf("got %d, want %d", n, defaultTunnelCount)
+	}
+	os.Setenv(EnvTunnelCount, "4")
+	if n := tunnelCount(); n != 4 {
+		t.Errorf("got %d, want 4", n)
+	}
+}
+
+func TestNextRetryInterval(t *testing.T) {
+	d := minRetryInterval
+	for i := 0; i < 10; i++ {
+		d = nextRetryInterval(d)
+	}
+	if d != maxRetryInterval {
+		t.Errorf("got %s, want %s", d, maxRetryInterval)
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"math/rand"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -26,7 +27,16 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-const HandshakeTimeOut = 10 * time.Second
+const (
+	HandshakeTimeOut = 10 * time.Second
+
+	// maxDialFailures is the consecutive failures of connecting to cluster manager before the session expired,
+	// the cluster manager endpoint of cluster may be changed.
+	maxDialFailures    = 3
+	minRetryInterval   = time.Second
+	maxRetryInterval   = 30 * time.Second
+	stableSessionAfter = time.Minute
+)
 
 type TunnelSession struct {
 	session                *remotedialer.Session
@@ -34,6 +44,14 @@ type TunnelSession struct {
 	expired                context.Context
 	cancel                 context.CancelFunc
 	clusterManagerEndpoint string
+
+	// index is the index of session in the tunnel pool of endpoint
+	index         int
+	activeStreams int64
+	reconnects    int64
+	// ready is closed and replaced when the session is (re)connected,
+	// so that dialers waiting for the session resume on the new one immediately
+	ready chan struct{}
 }
 
 func (s *TunnelSession) initialize(endpoint string) {
@@ -43,41 +61,100 @@ func (s *TunnelSession) initialize(endpoint string) {
 	dialer := &websocket.Dialer{
 		HandshakeTimeout: HandshakeTimeOut,
 	}
+	failures := 0
+	retryInterval := minRetryInterval
 	for {
 		ws, _, err := dialer.Dial(endpoint, headers)
 		if err != nil {
-			logrus.Errorf("Failed to connect to proxy server %s, err: %v", endpoint, err)
-			s.cancel()
-			sessions.Delete(s.clusterManagerEndpoint)
-			return
+			failures++
+			logrus.Errorf("Failed to connect to proxy server %s (%d/%d), err: %v", endpoint, failures, maxDialFailures, err)
+			if failures >= maxDialFailures {
+				s.cancel()
+				sessions.Delete(s.clusterManagerEndpoint)
+				return
+			}
+			if !s.sleep(retryInterval) {
+				return
+			}
+			retryInterval = nextRetryInterval(retryInterval)
+			continue
 		}
-		s.lock.Lock()
-		s.session = remotedialer.NewClientSession(func(string, string) bool { return true }, ws)
-		s.lock.Unlock()
-		_, err = s.session.Serve(context.Background())
+		failures = 0
+
+		session := remotedialer.NewClientSession(func(string, string) bool { return true }, ws)
+		s.setSession(session)
+		start := time.Now()
+		_, err = session.Serve(context.Background())
 		if err != nil {
 			logrus.Errorf("Failed to serve proxy connection err: %v", err)
 		}
-		s.session.Close()
-		s.lock.Lock()
-		s.session = nil
-		s.lock.Unlock()
+		session.Close()
+		s.setSession(nil)
 		ws.Close()
+		atomic.AddInt64(&s.reconnects, 1)
+		tunnelReconnectsCounter.WithLabelValues(s.clusterManagerEndpoint).Inc()
+
+		// the session was stable, reconnect quickly, otherwise back off
+		if time.Since(start) > stableSessionAfter {
+			retryInterval = minRetryInterval
+		}
 		// retry connect after sleep a random time
-		time.Sleep(time.Duration(rand.Int()%10) * time.Second)
+		if !s.sleep(retryInterval/2 + time.Duration(rand.Int63n(int64(retryInterval)))) {
+			return
+		}
+		retryInterval = nextRetryInterval(retryInterval)
 	}
+}
 
+func nextRetryInterval(d time.Duration) time.Duration {
+	if d*2 > maxRetryInterval {
+		return maxRetryInterval
+	}
+	return d * 2
 }
 
-func (s *TunnelSession) getClusterDialer(ctx context.Context, clusterKey string) remotedialer.Dialer {
-	var session *remotedialer.Session
+// sleep returns false if the session expired during sleeping
+func (s *TunnelSession) sleep(d time.Duration) bool {
+	select {
+	case <-s.expired.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+func (s *TunnelSession) setSession(session *remotedialer.Session) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.session = session
+	if session != nil && s.ready != nil {
+		close(s.ready)
+		s.ready = make(chan struct{})
+	}
+}
+
+// current returns the connected session and the channel closed when a new session connected
+func (s *TunnelSession) current() (*remotedialer.Session, <-chan struct{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.ready == nil {
+		s.ready = make(chan struct{})
+	}
+	return s.session, s.ready
+}
+
+func (s *TunnelSession) isConnected() bool {
+	session, _ := s.current()
+	return session != nil
+}
+
+// waitSession waits until the session connected, returns nil if ctx done or session expired
+func (s *TunnelSession) waitSession(ctx context.Context, clusterKey string) *remotedialer.Session {
 	start := time.Now()
 	for {
-		s.lock.Lock()
-		session = s.session
-		s.lock.Unlock()
+		session, ready := s.current()
 		if session != nil {
-			break
+			return session
 		}
 		select {
 		case <-s.expired.Done():
@@ -86,9 +163,17 @@ func (s *TunnelSession) getClusterDialer(ctx context.Context, clusterKey string)
 		case <-ctx.Done():
 			logrus.Errorf("get clusterdial session failed for clusterKey %s, cost %.3fs", clusterKey, time.Since(start).Seconds())
 			return nil
+		case <-ready:
 		case <-time.After(1 * time.Second):
 			logrus.Infof("waiting for clusterdial session ready for clusterKey %s... ", clusterKey)
 		}
 	}
+}
+
+func (s *TunnelSession) getClusterDialer(ctx context.Context, clusterKey string) remotedialer.Dialer {
+	session := s.waitSession(ctx, clusterKey)
+	if session == nil {
+		return nil
+	}
 	return remotedialer.ToDialer(session, clusterKey)
 }
@@ -0,0 +1,185 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonpath
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type stepKind int
+
+const (
+	stepChild stepKind = iota
+	stepIndex
+	stepWildcard
+	stepDescendants
+)
+
+type step struct {
+	kind  stepKind
+	name  string
+	index int
+}
+
+// Query 按 JSONPath 表达式返回所有匹配的值.
+// 支持 $、.key、['key']、[n]、[-n]、[*]、.* 以及 ..key 递归查找, 表达式可省略开头的 $.
+func Query(data interface{}, path string) ([]interface{}, error) {
+	steps, err := parseSteps(path)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, nil
+	}
+	nodes := []interface{}{followPtr(data)}
+	for _, s := range steps {
+		var next []interface{}
+		for _, node := range nodes {
+			next = append(next, s.apply(node)...)
+		}
+		nodes = next
+	}
+	return nodes, nil
+}
+
+func parseSteps(path string) ([]step, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+	var steps []step
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+			if i < len(path) && path[i] == '.' {
+				steps = append(steps, step{kind: stepDescendants})
+				i++
+				if i < len(path) && path[i] == '[' {
+					continue
+				}
+			}
+			name, n := readName(path[i:])
+			if name == "" {
+				return nil, fmt.Errorf("invalid jsonpath %q: empty name at %d", path, i)
+			}
+			steps = append(steps, nameStep(name))
+			i += n
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid jsonpath %q: unclosed bracket at %d", path, i)
+			}
+			s, err := bracketStep(strings.TrimSpace(path[i+1 : i+end]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid jsonpath %q: %v", path, err)
+			}
+			steps = append(steps, s)
+			i += end + 1
+		default:
+			if i != 0 {
+				return nil, fmt.Errorf("invalid jsonpath %q: unexpected %q at %d", path, path[i], i)
+			}
+			name, n := readName(path)
+			steps = append(steps, nameStep(name))
+			i += n
+		}
+	}
+	return steps, nil
+}
+
+func readName(s string) (string, int) {
+	n := strings.IndexAny(s, ".[")
+	if n < 0 {
+		n = len(s)
+	}
+	return strings.TrimSpace(s[:n]), n
+}
+
+func nameStep(name string) step {
+	if name == "*" {
+		return step{kind: stepWildcard}
+	}
+	return step{kind: stepChild, name: name}
+}
+
+func bracketStep(content string) (step, error) {
+	if content == "*" {
+		return step{kind: stepWildcard}, nil
+	}
+	if len(content) >= 2 && (content[0] == '\'' || content[0] == '"') && content[len(content)-1] == content[0] {
+		return step{kind: stepChild, name: content[1 : len(content)-1]}, nil
+	}
+	index, err := strconv.Atoi(content)
+	if err != nil {
+		return step{}, fmt.Errorf("unsupported bracket expression [%s]", content)
+	}
+	return step{kind: stepIndex, index: index}, nil
+}
+
+func (s step) apply(node interface{}) []interface{} {
+	switch s.kind {
+	case stepChild:
+		if m, ok := node.(map[string]interface{}); ok {
+			if v, ok := m[s.name]; ok {
+				return []interface{}{v}
+			}
+		}
+	case stepIndex:
+		if arr, ok := node.([]interface{}); ok {
+			index := s.index
+			if index < 0 {
+				index += len(arr)
+			}
+			if index >= 0 && index < len(arr) {
+				return []interface{}{arr[index]}
+			}
+		}
+	case stepWildcard:
+		return children(node)
+	case stepDescendants:
+		var result []interface{}
+		var walk func(interface{})
+		walk = func(n interface{}) {
+			result = append(result, n)
+			for _, child := range children(n) {
+				walk(child)
+			}
+		}
+		walk(node)
+		return result
+	}
+	return nil
+}
+
+func children(node interface{}) []interface{} {
+	switch v := node.(type) {
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		result := make([]interface{}, 0, len(keys))
+		for _, k := range keys {
+			result = append(result, v[k])
+		}
+		return result
+	}
+	return nil
+}
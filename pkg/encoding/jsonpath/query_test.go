@@ -0,0 +1,63 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonpath
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery(t *testing.T) {
+	var data interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"success": true,
+		"data": {
+			"total": 3,
+			"list": [
+				{"id": 1, "status": "ok", "tags": ["a"]},
+				{"id": 2, "status": "ok", "tags": []},
+				{"id": 3, "status": "failed", "owner": {"id": 9}}
+			]
+		}
+	}`), &data))
+
+	tests := []struct {
+		path string
+		want []interface{}
+	}{
+		{path: "$.success", want: []interface{}{true}},
+		{path: "data.total", want: []interface{}{float64(3)}},
+		{path: "$.data.list[*].status", want: []interface{}{"ok", "ok", "failed"}},
+		{path: "$.data.list[-1].id", want: []interface{}{float64(3)}},
+		{path: "$['data']['list'][0]['tags'][0]", want: []interface{}{"a"}},
+		{path: "$..id", want: []interface{}{float64(1), float64(2), float64(3), float64(9)}},
+		{path: "$.data.list[5].id", want: nil},
+		{path: "$.missing", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := Query(data, tt.path)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := Query(data, "$.data.list[abc]")
+	assert.Error(t, err)
+	_, err = Query(data, "$.data.list[0")
+	assert.Error(t, err)
+}
@@ -482,3 +482,49 @@ func (mr *MockIssueQueryMockRecIssorder) UpdateLabels(id, projectID, labelNames
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLabels", reflect.TypeOf((*MockIssueQuery)(nil).UpdateLabels), id, projectID, labelNames)
 }
+
+// MatchIssue mocks base method.
+func (m *MockIssueQuery) MatchIssue(projectID, issueID uint64, ql string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MatchIssue", projectID, issueID, ql)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MatchIssue indicates an expected call of MatchIssue.
+func (mr *MockIssueQueryMockRecIssorder) MatchIssue(projectID, issueID, ql interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MatchIssue", reflect.TypeOf((*MockIssueQuery)(nil).MatchIssue), projectID, issueID, ql)
+}
+
+// QueryIssueStats mocks base method.
+func (m *MockIssueQuery) QueryIssueStats(req *apistructs.IssueQueryStatsRequest) ([]apistructs.IssueQueryStatsItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryIssueStats", req)
+	ret0, _ := ret[0].([]apistructs.IssueQueryStatsItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryIssueStats indicates an expected call of QueryIssueStats.
+func (mr *MockIssueQueryMockRecIssorder) QueryIssueStats(req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryIssueStats", reflect.TypeOf((*MockIssueQuery)(nil).QueryIssueStats), req)
+}
+
+// QueryIssues mocks base method.
+func (m *MockIssueQuery) QueryIssues(req *apistructs.IssueQueryRequest) ([]*pb0.Issue, uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryIssues", req)
+	ret0, _ := ret[0].([]*pb0.Issue)
+	ret1, _ := ret[1].(uint64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// QueryIssues indicates an expected call of QueryIssues.
+func (mr *MockIssueQueryMockRecIssorder) QueryIssues(req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryIssues", reflect.TypeOf((*MockIssueQuery)(nil).QueryIssues), req)
+}
@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
 )
 
 type BasicValidateVisitor struct {
@@ -132,6 +133,66 @@ func (o *BasicValidateVisitor) VisitService(v DiceYmlVisitor, obj *Service) {
 			break
 		}
 	}
+	if obj.Autoscale != nil {
+		if err := validateAutoscale(obj.Autoscale); err != nil {
+			o.collectErrors[yamlHeaderRegexWithUpperHeader([]string{o.currentService}, "autoscale")] = errors.Wrap(invalidAutoscale, o.currentService+": "+err.Error())
+		}
+	}
+}
+
+func validateAutoscale(as *Autoscale) error {
+	if as.MaxReplicas <= 0 {
+		return errors.New("max_replicas must be greater than 0")
+	}
+	if as.MinReplicas < 0 || as.MinReplicas > as.MaxReplicas {
+		return errors.New("min_replicas must be in range [0, max_replicas]")
+	}
+	if as.PollingInterval < 0 || as.CooldownPeriod < 0 {
+		return errors.New("polling_interval and cooldown_period can not be negative")
+	}
+	if len(as.Triggers) == 0 {
+		return errors.New("at least one trigger required")
+	}
+	if as.ScaleToZero && !as.IsEventDriven() {
+		return errors.New("scale_to_zero requires at least one msp, kafka or cron trigger")
+	}
+
+	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+	for i, t := range as.Triggers {
+		switch t.Type {
+		case AutoscaleTriggerCPU, AutoscaleTriggerMemory:
+			if t.Target <= 0 || t.Target >= 100 {
+				return errors.Errorf("triggers[%d]: target must be in range (0, 100)", i)
+			}
+		case AutoscaleTriggerMSP:
+			if t.Metric == "" && t.Query == "" {
+				return errors.Errorf("triggers[%d]: metric or query required", i)
+			}
+			if t.Threshold <= 0 {
+				return errors.Errorf("triggers[%d]: threshold must be greater than 0", i)
+			}
+		case AutoscaleTriggerKafka:
+			if t.BootstrapServers == "" || t.Topic == "" || t.ConsumerGroup == "" {
+				return errors.Errorf("triggers[%d]: bootstrap_servers, topic and consumer_group required", i)
+			}
+			if t.LagThreshold < 0 {
+				return errors.Errorf("triggers[%d]: lag_threshold can not be negative", i)
+			}
+		case AutoscaleTriggerCron:
+			if _, err := parser.Parse(t.Start); err != nil {
+				return errors.Errorf("triggers[%d]: invalid start schedule %q", i, t.Start)
+			}
+			if _, err := parser.Parse(t.End); err != nil {
+				return errors.Errorf("triggers[%d]: invalid end schedule %q", i, t.End)
+			}
+			if t.DesiredReplicas <= 0 || t.DesiredReplicas > as.MaxReplicas {
+				return errors.Errorf("triggers[%d]: desired_replicas must be in range (0, max_replicas]", i)
+			}
+		default:
+			return errors.Errorf("triggers[%d]: unsupported type %q", i, t.Type)
+		}
+	}
+	return nil
 }
 func (o *BasicValidateVisitor) VisitBinds(v DiceYmlVisitor, obj *Binds) {
 	for _, bind := range *obj {
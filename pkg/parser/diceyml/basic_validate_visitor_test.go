@@ -86,3 +86,82 @@ func TestBasicValidate(t *testing.T) {
 	assert.Equal(t, 6, len(es), "%v", es)
 
 }
+
+var autoscale_validate_yml = `version: 2.0
+services:
+  web:
+    deployments:
+      replicas: 2
+    resources:
+      cpu: 0.5
+      mem: 512
+    autoscale:
+      max_replicas: 10
+      triggers:
+      - type: cpu
+        target: 70
+      - type: msp
+        metric: http_requests_per_second
+        threshold: 100
+      - type: cron
+        start: "0 8 * * *"
+        end: "0 20 * * *"
+        desired_replicas: 5
+  consumer:
+    deployments:
+      replicas: 1
+    resources:
+      cpu: 0.5
+      mem: 512
+    autoscale:
+      max_replicas: 5
+      scale_to_zero: true
+      triggers:
+      - type: kafka
+        bootstrap_servers: kafka:9092
+        topic: orders
+        consumer_group: order-consumer
+        lag_threshold: 50
+`
+
+func TestBasicValidateAutoscale(t *testing.T) {
+	d, err := New([]byte(autoscale_validate_yml), false)
+	assert.Nil(t, err)
+	es := BasicValidate(d.Obj())
+	assert.Equal(t, 0, len(es), "%v", es)
+
+	as := d.Obj().Services["consumer"].Autoscale
+	assert.True(t, as.ScaleToZero)
+	assert.True(t, as.IsEventDriven())
+	assert.Equal(t, 50, as.Triggers[0].LagThreshold)
+}
+
+func TestValidateAutoscale(t *testing.T) {
+	cases := []struct {
+		name string
+		as   Autoscale
+		ok   bool
+	}{
+		{"no max replicas", Autoscale{Triggers: []AutoscaleTrigger{{Type: "cpu", Target: 50}}}, false},
+		{"min greater than max", Autoscale{MinReplicas: 3, MaxReplicas: 2, Triggers: []AutoscaleTrigger{{Type: "cpu", Target: 50}}}, false},
+		{"no triggers", Autoscale{MaxReplicas: 2}, false},
+		{"scale to zero with cpu only", Autoscale{MaxReplicas: 2, ScaleToZero: true, Triggers: []AutoscaleTrigger{{Type: "cpu", Target: 50}}}, false},
+		{"cpu target out of range", Autoscale{MaxReplicas: 2, Triggers: []AutoscaleTrigger{{Type: "memory", Target: 100}}}, false},
+		{"msp without metric", Autoscale{MaxReplicas: 2, Triggers: []AutoscaleTrigger{{Type: "msp", Threshold: 1}}}, false},
+		{"kafka without topic", Autoscale{MaxReplicas: 2, Triggers: []AutoscaleTrigger{{Type: "kafka", BootstrapServers: "k:9092", ConsumerGroup: "g"}}}, false},
+		{"cron invalid schedule", Autoscale{MaxReplicas: 2, Triggers: []AutoscaleTrigger{{Type: "cron", Start: "bad", End: "0 20 * * *", DesiredReplicas: 1}}}, false},
+		{"cron replicas over max", Autoscale{MaxReplicas: 2, Triggers: []AutoscaleTrigger{{Type: "cron", Start: "0 8 * * *", End: "0 20 * * *", DesiredReplicas: 3}}}, false},
+		{"unknown type", Autoscale{MaxReplicas: 2, Triggers: []AutoscaleTrigger{{Type: "rabbitmq"}}}, false},
+		{"valid msp query", Autoscale{MaxReplicas: 2, ScaleToZero: true, Triggers: []AutoscaleTrigger{{Type: "msp", Query: "sum(rate(x[1m]))", Threshold: 0.5}}}, true},
+	}
+	for _, c := range cases {
+		err := validateAutoscale(&c.as)
+		assert.Equal(t, c.ok, err == nil, "%s: %v", c.name, err)
+	}
+}
+
+func TestAutoscaleFieldname(t *testing.T) {
+	d, err := New([]byte(autoscale_validate_yml), true)
+	assert.Nil(t, err)
+	assert.NotNil(t, d.Obj().Services["web"].Autoscale)
+}
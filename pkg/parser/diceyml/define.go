@@ -196,6 +196,7 @@ type Service struct {
 	TrafficSecurity TrafficSecurity          `yaml:"traffic_security,omitempty" json:"traffic_security,omitempty"`
 	Endpoints       []Endpoint               `yaml:"endpoints,omitempty" json:"endpoints,omitempty"`
 	K8SSnippet      *K8SSnippet              `yaml:"k8s_snippet,omitempty" json:"k8s_snippet,omitempty"`
+	Autoscale       *Autoscale               `yaml:"autoscale,omitempty" json:"autoscale,omitempty"`
 }
 
 type ContainerSnippet apiv1.Container
@@ -684,6 +685,61 @@ type Deployments struct {
 	Selectors Selectors `yaml:"selectors,omitempty" json:"selectors,omitempty"`
 }
 
+// Autoscale 定义服务的事件驱动弹性伸缩规则，部署时会被转换为 KEDA ScaledObject
+type Autoscale struct {
+	// MinReplicas 最小副本数，未设置时使用 deployments.replicas
+	MinReplicas int `yaml:"min_replicas,omitempty" json:"min_replicas,omitempty"`
+	MaxReplicas int `yaml:"max_replicas,omitempty" json:"max_replicas"`
+	// ScaleToZero 允许在没有事件时缩容到 0，至少需要一个非 cpu/memory 的触发器
+	ScaleToZero     bool               `yaml:"scale_to_zero,omitempty" json:"scale_to_zero,omitempty"`
+	PollingInterval int                `yaml:"polling_interval,omitempty" json:"polling_interval,omitempty"`
+	CooldownPeriod  int                `yaml:"cooldown_period,omitempty" json:"cooldown_period,omitempty"`
+	Triggers        []AutoscaleTrigger `yaml:"triggers,omitempty" json:"triggers"`
+}
+
+// AutoscaleTrigger 伸缩触发器, type 可选: cpu, memory, msp, kafka, cron
+type AutoscaleTrigger struct {
+	Type string `yaml:"type" json:"type"`
+
+	// cpu, memory: 目标利用率百分比
+	Target int `yaml:"target,omitempty" json:"target,omitempty"`
+
+	// msp: 自定义监控指标，metric 和 query 至少设置一个
+	Metric    string  `yaml:"metric,omitempty" json:"metric,omitempty"`
+	Query     string  `yaml:"query,omitempty" json:"query,omitempty"`
+	Threshold float64 `yaml:"threshold,omitempty" json:"threshold,omitempty"`
+
+	// kafka: 消费组堆积
+	BootstrapServers string `yaml:"bootstrap_servers,omitempty" json:"bootstrap_servers,omitempty"`
+	Topic            string `yaml:"topic,omitempty" json:"topic,omitempty"`
+	ConsumerGroup    string `yaml:"consumer_group,omitempty" json:"consumer_group,omitempty"`
+	LagThreshold     int    `yaml:"lag_threshold,omitempty" json:"lag_threshold,omitempty"`
+
+	// cron: 定时伸缩
+	Start           string `yaml:"start,omitempty" json:"start,omitempty"`
+	End             string `yaml:"end,omitempty" json:"end,omitempty"`
+	Timezone        string `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+	DesiredReplicas int    `yaml:"desired_replicas,omitempty" json:"desired_replicas,omitempty"`
+}
+
+const (
+	AutoscaleTriggerCPU    = "cpu"
+	AutoscaleTriggerMemory = "memory"
+	AutoscaleTriggerMSP    = "msp"
+	AutoscaleTriggerKafka  = "kafka"
+	AutoscaleTriggerCron   = "cron"
+)
+
+// IsEventDriven 是否包含可以从 0 唤醒服务的触发器
+func (a *Autoscale) IsEventDriven() bool {
+	for _, t := range a.Triggers {
+		if t.Type != AutoscaleTriggerCPU && t.Type != AutoscaleTriggerMemory {
+			return true
+		}
+	}
+	return false
+}
+
 type TrafficSecurity struct {
 	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
 }
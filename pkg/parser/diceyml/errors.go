@@ -43,6 +43,7 @@ var (
 	invalidEndpointPath        = errortype("invalid path in endpoints, must start with '/'")
 	invalidEmptyDir            = errortype("invalid emptydir_size defined in yaml")
 	invalidEphemeralStorage    = errortype("invalid ephemeral_storage_size defined in yaml, at least set 1")
+	invalidAutoscale           = errortype("invalid autoscale defined in yaml")
 )
 
 type errortype string
@@ -69,6 +69,9 @@ func (o *MergeEnvVisitor) VisitService(v DiceYmlVisitor, obj *Service) {
 	overrideIfNotZero(o.envObj.Services[o.currentService].Volumes, &obj.Volumes)
 	overrideIfNotZero(o.envObj.Services[o.currentService].DependsOn, &obj.DependsOn)
 	overrideIfNotZero(o.envObj.Services[o.currentService].Expose, &obj.Expose)
+	if o.envObj.Services[o.currentService].Autoscale != nil {
+		obj.Autoscale = o.envObj.Services[o.currentService].Autoscale
+	}
 }
 
 func (o *MergeEnvVisitor) VisitResources(v DiceYmlVisitor, obj *Resources) {
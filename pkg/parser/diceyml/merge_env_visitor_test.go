@@ -80,3 +80,40 @@ func TestMergeAddons(t *testing.T) {
 	_, ok = d.obj.AddOns["monitor"]
 	assert.False(t, ok)
 }
+
+var mergeAutoscaleYml = `version: "2.0"
+services:
+  web:
+    resources:
+      cpu: 0.1
+      mem: 256
+    deployments:
+      replicas: 1
+    autoscale:
+      max_replicas: 3
+      triggers:
+      - type: cpu
+        target: 80
+environments:
+  production:
+    services:
+      web:
+        autoscale:
+          max_replicas: 20
+          triggers:
+          - type: cpu
+            target: 60
+`
+
+func TestMergeAutoscale(t *testing.T) {
+	d, err := New([]byte(mergeAutoscaleYml), false)
+	assert.Nil(t, err)
+	MergeEnv(d.obj, "test")
+	assert.Equal(t, 3, d.obj.Services["web"].Autoscale.MaxReplicas)
+
+	d, err = New([]byte(mergeAutoscaleYml), false)
+	assert.Nil(t, err)
+	MergeEnv(d.obj, "production")
+	assert.Equal(t, 20, d.obj.Services["web"].Autoscale.MaxReplicas)
+	assert.Equal(t, 60, d.obj.Services["web"].Autoscale.Triggers[0].Target)
+}
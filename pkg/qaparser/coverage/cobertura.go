@@ -0,0 +1,94 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coverage
+
+import (
+	"encoding/xml"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda-proto-go/dop/qa/unittest/pb"
+)
+
+type coberturaReport struct {
+	XMLName  xml.Name           `xml:"coverage"`
+	Packages []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name    string           `xml:"name,attr"`
+	Classes []coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Name     string          `xml:"name,attr"`
+	Filename string          `xml:"filename,attr"`
+	Lines    []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number            int64  `xml:"number,attr"`
+	Hits              int64  `xml:"hits,attr"`
+	Branch            bool   `xml:"branch,attr"`
+	ConditionCoverage string `xml:"condition-coverage,attr"`
+}
+
+// e.g. condition-coverage="50% (1/2)"
+var conditionCoverageRegex = regexp.MustCompile(`\((\d+)/(\d+)\)`)
+
+// IngestCobertura parses a cobertura xml report, each package becomes a test suite.
+// The counters are computed from the lines instead of the rate attributes,
+// which are rounded by some generators.
+func IngestCobertura(data []byte) ([]*pb.TestSuite, error) {
+	var report coberturaReport
+	if err := xml.Unmarshal(data, &report); err != nil {
+		return nil, errors.Wrap(err, "invalid cobertura report")
+	}
+
+	packages := make(map[string]*packageCoverage)
+	for _, p := range report.Packages {
+		pkg, ok := packages[p.Name]
+		if !ok {
+			pkg = &packageCoverage{name: p.Name}
+			packages[p.Name] = pkg
+		}
+		for _, class := range p.Classes {
+			filename := class.Filename
+			if len(filename) == 0 {
+				filename = class.Name
+			}
+			var c Counter
+			for _, line := range class.Lines {
+				c.LinesValid++
+				if line.Hits > 0 {
+					c.LinesCovered++
+				}
+				if !line.Branch {
+					continue
+				}
+				if m := conditionCoverageRegex.FindStringSubmatch(line.ConditionCoverage); len(m) == 3 {
+					covered, _ := strconv.ParseInt(m[1], 10, 64)
+					valid, _ := strconv.ParseInt(m[2], 10, 64)
+					c.BranchesCovered += covered
+					c.BranchesValid += valid
+				}
+			}
+			pkg.addFile(filename, c)
+		}
+	}
+	return toSuites("cobertura", packages), nil
+}
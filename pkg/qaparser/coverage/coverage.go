@@ -0,0 +1,122 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coverage
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/erda-project/erda-proto-go/dop/qa/unittest/pb"
+	"github.com/erda-project/erda/pkg/qaparser"
+)
+
+// keys of pb.TestSuite.Extra carrying the coverage of a package
+const (
+	ExtraCoverageType    = "coverage_type"
+	ExtraLinesCovered    = "lines_covered"
+	ExtraLinesValid      = "lines_valid"
+	ExtraLineRate        = "line_rate"
+	ExtraBranchesCovered = "branches_covered"
+	ExtraBranchesValid   = "branches_valid"
+	ExtraBranchRate      = "branch_rate"
+)
+
+// Counter is the coverage of a file or a package.
+type Counter struct {
+	LinesCovered    int64
+	LinesValid      int64
+	BranchesCovered int64
+	BranchesValid   int64
+}
+
+func (c *Counter) Add(o Counter) {
+	c.LinesCovered += o.LinesCovered
+	c.LinesValid += o.LinesValid
+	c.BranchesCovered += o.BranchesCovered
+	c.BranchesValid += o.BranchesValid
+}
+
+func (c Counter) LineRate() float64 {
+	return rate(c.LinesCovered, c.LinesValid)
+}
+
+func (c Counter) BranchRate() float64 {
+	return rate(c.BranchesCovered, c.BranchesValid)
+}
+
+func rate(covered, valid int64) float64 {
+	if valid <= 0 {
+		return 0
+	}
+	return float64(covered) / float64(valid)
+}
+
+type packageCoverage struct {
+	name  string
+	total Counter
+	files map[string]Counter
+}
+
+func (p *packageCoverage) addFile(filename string, c Counter) {
+	if p.files == nil {
+		p.files = make(map[string]Counter)
+	}
+	f := p.files[filename]
+	f.Add(c)
+	p.files[filename] = f
+	p.total.Add(c)
+}
+
+// toSuites converts the coverage of each package to a test suite without tests,
+// the counters are in Extra and the line rate of each file is in Properties.
+func toSuites(coverageType string, packages map[string]*packageCoverage) []*pb.TestSuite {
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	suites := make([]*pb.TestSuite, 0, len(names))
+	for _, name := range names {
+		pkg := packages[name]
+		props := make(map[string]string, len(pkg.files))
+		for file, c := range pkg.files {
+			props[file] = formatRate(c.LineRate())
+		}
+		suites = append(suites, &pb.TestSuite{
+			Name:       name,
+			Package:    name,
+			Properties: props,
+			Totals: &pb.TestTotal{
+				Statuses: qaparser.NewStatuses(0, 0, 0, 0),
+			},
+			Extra: map[string]string{
+				ExtraCoverageType:    coverageType,
+				ExtraLinesCovered:    strconv.FormatInt(pkg.total.LinesCovered, 10),
+				ExtraLinesValid:      strconv.FormatInt(pkg.total.LinesValid, 10),
+				ExtraLineRate:        formatRate(pkg.total.LineRate()),
+				ExtraBranchesCovered: strconv.FormatInt(pkg.total.BranchesCovered, 10),
+				ExtraBranchesValid:   strconv.FormatInt(pkg.total.BranchesValid, 10),
+				ExtraBranchRate:      formatRate(pkg.total.BranchRate()),
+			},
+		})
+	}
+	return suites
+}
+
+func formatRate(r float64) string {
+	return fmt.Sprintf("%.4f", r)
+}
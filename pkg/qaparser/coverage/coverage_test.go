@@ -0,0 +1,76 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coverage
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIngestCobertura(t *testing.T) {
+	data, err := os.ReadFile("../testdata/cobertura.xml")
+	assert.NoError(t, err)
+
+	suites, err := IngestCobertura(data)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(suites))
+
+	api := suites[0]
+	assert.Equal(t, "app.api", api.Name)
+	assert.Equal(t, "cobertura", api.Extra[ExtraCoverageType])
+	assert.Equal(t, "3", api.Extra[ExtraLinesCovered])
+	assert.Equal(t, "4", api.Extra[ExtraLinesValid])
+	assert.Equal(t, "0.7500", api.Extra[ExtraLineRate])
+	assert.Equal(t, "1", api.Extra[ExtraBranchesCovered])
+	assert.Equal(t, "2", api.Extra[ExtraBranchesValid])
+	assert.Equal(t, "0.7500", api.Properties["app/api/handlers.py"])
+	assert.Equal(t, int64(0), api.Totals.Tests)
+
+	assert.Equal(t, "0.5000", suites[1].Extra[ExtraLineRate])
+}
+
+func TestIngestLCOV(t *testing.T) {
+	data, err := os.ReadFile("../testdata/lcov.info")
+	assert.NoError(t, err)
+
+	suites, err := IngestLCOV(data)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(suites))
+
+	components := suites[0]
+	assert.Equal(t, "src/components", components.Name)
+	assert.Equal(t, "lcov", components.Extra[ExtraCoverageType])
+	// Button.js uses the LF/LH summary, Input.js the DA lines
+	assert.Equal(t, "3", components.Extra[ExtraLinesCovered])
+	assert.Equal(t, "5", components.Extra[ExtraLinesValid])
+	assert.Equal(t, "0.6667", components.Properties["src/components/Button.js"])
+	assert.Equal(t, "0.5000", components.Properties["src/components/Input.js"])
+	assert.Equal(t, "1", components.Extra[ExtraBranchesCovered])
+	assert.Equal(t, "2", components.Extra[ExtraBranchesValid])
+
+	utils := suites[1]
+	assert.Equal(t, "src/utils", utils.Name)
+	assert.Equal(t, "1.0000", utils.Extra[ExtraLineRate])
+	assert.Equal(t, "0.5000", utils.Extra[ExtraBranchRate])
+}
+
+func TestIngestInvalid(t *testing.T) {
+	_, err := IngestCobertura([]byte("<coverage"))
+	assert.Error(t, err)
+	_, err = IngestLCOV([]byte("not a tracefile"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coverage
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda-proto-go/dop/qa/unittest/pb"
+)
+
+type lcovRecord struct {
+	file    string
+	counter Counter
+	// summary lines, preferred to the counters computed from DA and BRDA lines
+	lf, lh, brf, brh int64
+	hasLF, hasBRF    bool
+}
+
+func (r *lcovRecord) result() Counter {
+	c := r.counter
+	if r.hasLF {
+		c.LinesValid, c.LinesCovered = r.lf, r.lh
+	}
+	if r.hasBRF {
+		c.BranchesValid, c.BranchesCovered = r.brf, r.brh
+	}
+	return c
+}
+
+// IngestLCOV parses a lcov tracefile, the files are grouped by directory, each directory becomes a test suite.
+func IngestLCOV(data []byte) ([]*pb.TestSuite, error) {
+	packages := make(map[string]*packageCoverage)
+	var (
+		record  *lcovRecord
+		records int
+	)
+	flush := func() {
+		if record == nil || len(record.file) == 0 {
+			return
+		}
+		dir := path.Dir(strings.ReplaceAll(record.file, "\\", "/"))
+		pkg, ok := packages[dir]
+		if !ok {
+			pkg = &packageCoverage{name: dir}
+			packages[dir] = pkg
+		}
+		pkg.addFile(record.file, record.result())
+		records++
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "end_of_record" {
+			flush()
+			record = nil
+			continue
+		}
+		idx := strings.IndexByte(line, ':')
+		if idx < 0 {
+			continue
+		}
+		key, value := line[:idx], line[idx+1:]
+		if key == "SF" {
+			flush()
+			record = &lcovRecord{file: value}
+			continue
+		}
+		if record == nil {
+			continue
+		}
+		fields := strings.Split(value, ",")
+		switch key {
+		case "DA":
+			if len(fields) < 2 {
+				continue
+			}
+			record.counter.LinesValid++
+			if hits, _ := strconv.ParseInt(fields[1], 10, 64); hits > 0 {
+				record.counter.LinesCovered++
+			}
+		case "BRDA":
+			if len(fields) < 4 {
+				continue
+			}
+			record.counter.BranchesValid++
+			if taken, _ := strconv.ParseInt(fields[3], 10, 64); taken > 0 {
+				record.counter.BranchesCovered++
+			}
+		case "LF":
+			record.lf, _ = strconv.ParseInt(value, 10, 64)
+			record.hasLF = true
+		case "LH":
+			record.lh, _ = strconv.ParseInt(value, 10, 64)
+		case "BRF":
+			record.brf, _ = strconv.ParseInt(value, 10, 64)
+			record.hasBRF = true
+		case "BRH":
+			record.brh, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read lcov tracefile")
+	}
+	flush()
+	if records == 0 {
+		return nil, errors.New("no lcov record found")
+	}
+	return toSuites("lcov", packages), nil
+}
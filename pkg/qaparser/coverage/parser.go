@@ -0,0 +1,65 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coverage
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda-proto-go/dop/qa/unittest/pb"
+	"github.com/erda-project/erda/pkg/qaparser"
+	"github.com/erda-project/erda/pkg/qaparser/types"
+)
+
+type CoberturaParser struct {
+}
+
+type LCOVParser struct {
+}
+
+func init() {
+	logrus.Info("register Cobertura and LCOV Parser to manager")
+	(CoberturaParser{}).Register()
+	(LCOVParser{}).Register()
+}
+
+func (c CoberturaParser) Register() {
+	qaparser.Register(c, types.Cobertura)
+}
+
+// parse cobertura xml to entity
+// 1. get file from cloud storage
+// 2. parse
+func (CoberturaParser) Parse(endpoint, ak, sk, bucket, objectName string) ([]*pb.TestSuite, error) {
+	data, err := qaparser.Download(endpoint, ak, sk, bucket, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return IngestCobertura(data)
+}
+
+func (l LCOVParser) Register() {
+	qaparser.Register(l, types.LCOV)
+}
+
+// parse lcov tracefile to entity
+// 1. get file from cloud storage
+// 2. parse
+func (LCOVParser) Parse(endpoint, ak, sk, bucket, objectName string) ([]*pb.TestSuite, error) {
+	data, err := qaparser.Download(endpoint, ak, sk, bucket, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return IngestLCOV(data)
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gotestjson
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda-proto-go/dop/qa/unittest/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/qaparser"
+)
+
+// event is a line of go test -json output, see go doc test2json.
+type event struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+type packageResult struct {
+	suite  *pb.TestSuite
+	tests  map[string]*pb.Test
+	output map[string]*strings.Builder
+	status string
+	stdout strings.Builder
+}
+
+// Ingest parses the output of go test -json, each package becomes a test suite.
+// Lines which are not json events, e.g. build errors printed by go vet, are ignored.
+func Ingest(data []byte) ([]*pb.TestSuite, error) {
+	var (
+		packages = make(map[string]*packageResult)
+		order    []string
+		events   int
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		var e event
+		if err := json.Unmarshal(line, &e); err != nil || len(e.Action) == 0 {
+			continue
+		}
+		events++
+
+		pkg, ok := packages[e.Package]
+		if !ok {
+			pkg = &packageResult{
+				suite: &pb.TestSuite{
+					Name:    e.Package,
+					Package: e.Package,
+				},
+				tests:  make(map[string]*pb.Test),
+				output: make(map[string]*strings.Builder),
+			}
+			packages[e.Package] = pkg
+			order = append(order, e.Package)
+		}
+		pkg.handle(e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read go test output")
+	}
+	if events == 0 {
+		return nil, errors.New("no go test json events found")
+	}
+
+	var suites []*pb.TestSuite
+	for _, name := range order {
+		if suite := packages[name].build(); suite != nil {
+			suites = append(suites, suite)
+		}
+	}
+	return suites, nil
+}
+
+func (p *packageResult) handle(e event) {
+	if len(e.Test) == 0 {
+		switch e.Action {
+		case "output":
+			p.stdout.WriteString(e.Output)
+		case "pass", "fail", "skip":
+			p.status = e.Action
+		}
+		return
+	}
+
+	test, ok := p.tests[e.Test]
+	if !ok {
+		test = &pb.Test{
+			Name:      e.Test,
+			Classname: e.Package,
+		}
+		p.tests[e.Test] = test
+		p.output[e.Test] = &strings.Builder{}
+		p.suite.Tests = append(p.suite.Tests, test)
+	}
+
+	switch e.Action {
+	case "output":
+		p.output[e.Test].WriteString(e.Output)
+	case "pass":
+		test.Status = string(apistructs.TestStatusPassed)
+		test.Duration = elapsed(e.Elapsed)
+	case "skip":
+		test.Status = string(apistructs.TestStatusSkipped)
+		test.Duration = elapsed(e.Elapsed)
+	case "fail":
+		test.Status = string(apistructs.TestStatusFailed)
+		test.Duration = elapsed(e.Elapsed)
+	}
+}
+
+func (p *packageResult) build() *pb.TestSuite {
+	suite := p.suite
+	for name, test := range p.tests {
+		test.Stdout = p.output[name].String()
+		switch test.Status {
+		case string(apistructs.TestStatusFailed):
+			test.Error = &pb.TestError{
+				Message: "test failed",
+				Body:    test.Stdout,
+			}
+		case "":
+			// the package was interrupted, e.g. by a panic or the test timeout
+			test.Status = string(apistructs.TestStatusError)
+			test.Error = &pb.TestError{
+				Message: "test did not finish",
+				Body:    test.Stdout,
+			}
+		}
+	}
+	suite.Stdout = p.stdout.String()
+
+	if len(suite.Tests) == 0 {
+		if p.status != "fail" {
+			// packages without test files
+			return nil
+		}
+		// the package failed before running any test, e.g. build failed
+		suite.Tests = append(suite.Tests, &pb.Test{
+			Name:      suite.Name,
+			Classname: suite.Package,
+			Status:    string(apistructs.TestStatusError),
+			Error: &pb.TestError{
+				Message: "package failed",
+				Body:    suite.Stdout,
+			},
+		})
+	}
+	sort.SliceStable(suite.Tests, func(i, j int) bool {
+		return suite.Tests[i].Name < suite.Tests[j].Name
+	})
+
+	su := &qaparser.Suite{TestSuite: suite}
+	su.Aggregate()
+	return suite
+}
+
+func elapsed(seconds float64) int64 {
+	return int64(time.Duration(seconds * float64(time.Second)))
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gotestjson
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+func TestIngest(t *testing.T) {
+	data, err := os.ReadFile("../testdata/go-test.json")
+	assert.NoError(t, err)
+
+	suites, err := Ingest(data)
+	assert.NoError(t, err)
+	// the package without test files is dropped
+	assert.Equal(t, 2, len(suites))
+
+	calc := suites[0]
+	assert.Equal(t, "github.com/example/app/calc", calc.Name)
+	assert.Equal(t, int64(3), calc.Totals.Tests)
+	assert.Equal(t, int64(1), calc.Totals.Statuses[string(apistructs.TestStatusPassed)])
+	assert.Equal(t, int64(1), calc.Totals.Statuses[string(apistructs.TestStatusFailed)])
+	assert.Equal(t, int64(1), calc.Totals.Statuses[string(apistructs.TestStatusSkipped)])
+	assert.Equal(t, int64(30*time.Millisecond), calc.Totals.Duration)
+
+	div := calc.Tests[1]
+	assert.Equal(t, "TestDiv", div.Name)
+	assert.Equal(t, string(apistructs.TestStatusFailed), div.Status)
+	assert.Contains(t, div.Error.Body, "expected 2, got 3")
+
+	broken := suites[1]
+	assert.Equal(t, "github.com/example/app/broken", broken.Name)
+	assert.Equal(t, 1, len(broken.Tests))
+	assert.Equal(t, string(apistructs.TestStatusError), broken.Tests[0].Status)
+	assert.Contains(t, broken.Tests[0].Error.Body, "build failed")
+}
+
+func TestIngestUnfinished(t *testing.T) {
+	data := []byte(`{"Action":"run","Package":"p","Test":"TestHang"}
+{"Action":"output","Package":"p","Test":"TestHang","Output":"panic: test timed out after 10m0s\n"}
+{"Action":"fail","Package":"p","Elapsed":600}`)
+	suites, err := Ingest(data)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(suites))
+	assert.Equal(t, string(apistructs.TestStatusError), suites[0].Tests[0].Status)
+	assert.Equal(t, int64(1), suites[0].Totals.Statuses[string(apistructs.TestStatusError)])
+}
+
+func TestIngestInvalid(t *testing.T) {
+	_, err := Ingest([]byte("ok  \tgithub.com/example/app\t0.01s\n"))
+	assert.Error(t, err)
+}
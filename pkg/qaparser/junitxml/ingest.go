@@ -0,0 +1,205 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package junitxml
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erda-project/erda-proto-go/dop/qa/unittest/pb"
+	"github.com/erda-project/erda/apistructs"
+	"github.com/erda-project/erda/pkg/qaparser"
+)
+
+// Ingest parses the given junit xml data and returns all test suites having tests.
+//
+// Compared with the surefire format, it tolerates:
+//   - nested testsuite nodes, the nested suites are flattened with their names joined by "/"
+//   - failure and skipped nodes without a message attribute
+//   - time attributes with thousands separators, e.g. "1,024.5"
+//   - testcase nodes without classname, the suite name is used instead
+func Ingest(data []byte) ([]*pb.TestSuite, error) {
+	nodes, err := qaparser.NodeParse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var suites []*pb.TestSuite
+	findSuites(nodes, "", nil, &suites)
+	return suites, nil
+}
+
+func findSuites(nodes []qaparser.XmlNode, parent string, props map[string]string, suites *[]*pb.TestSuite) {
+	for _, node := range nodes {
+		switch node.XMLName.Local {
+		case "testsuite":
+			ingestSuite(node, parent, props, suites)
+		case "testsuites":
+			findSuites(node.Nodes, parent, mergeProperties(props, node), suites)
+		default:
+			findSuites(node.Nodes, parent, props, suites)
+		}
+	}
+}
+
+func ingestSuite(root qaparser.XmlNode, parent string, parentProps map[string]string, suites *[]*pb.TestSuite) {
+	name := root.Attr("name")
+	if len(parent) > 0 {
+		name = parent + "/" + name
+	}
+	suite := &pb.TestSuite{
+		Name:       name,
+		Package:    root.Attr("package"),
+		Properties: mergeProperties(parentProps, root),
+	}
+
+	for _, node := range root.Nodes {
+		switch node.XMLName.Local {
+		case "testcase":
+			suite.Tests = append(suite.Tests, ingestTestcase(node, root.Attr("name")))
+		case "testsuite":
+			ingestSuite(node, name, suite.Properties, suites)
+		case "system-out":
+			suite.Stdout = string(node.Content)
+		case "system-err":
+			suite.Stderr = string(node.Content)
+		}
+	}
+	if len(suite.Tests) == 0 {
+		return
+	}
+	if len(suite.Package) == 0 {
+		suite.Package = commonPackage(suite.Tests)
+	}
+	if file := root.Attr("file"); len(file) > 0 {
+		if suite.Extra == nil {
+			suite.Extra = make(map[string]string)
+		}
+		suite.Extra["file"] = file
+	}
+
+	su := &qaparser.Suite{TestSuite: suite}
+	su.Aggregate()
+	*suites = append(*suites, suite)
+}
+
+func mergeProperties(parent map[string]string, root qaparser.XmlNode) map[string]string {
+	var props map[string]string
+	for _, node := range root.Nodes {
+		if node.XMLName.Local != "properties" {
+			continue
+		}
+		for _, prop := range node.Nodes {
+			if prop.XMLName.Local != "property" {
+				continue
+			}
+			if props == nil {
+				props = make(map[string]string, len(parent)+len(node.Nodes))
+				for k, v := range parent {
+					props[k] = v
+				}
+			}
+			value := prop.Attr("value")
+			if len(value) == 0 {
+				value = string(prop.Content)
+			}
+			props[prop.Attr("name")] = value
+		}
+	}
+	if props == nil {
+		return parent
+	}
+	return props
+}
+
+func ingestTestcase(root qaparser.XmlNode, suiteName string) *pb.Test {
+	test := &pb.Test{
+		Name:      root.Attr("name"),
+		Classname: root.Attr("classname"),
+		Duration:  int64(duration(root.Attr("time"))),
+		Status:    string(apistructs.TestStatusPassed),
+	}
+	if len(test.Classname) == 0 {
+		test.Classname = suiteName
+	}
+
+	for _, node := range root.Nodes {
+		switch node.XMLName.Local {
+		case "skipped":
+			if test.Status == string(apistructs.TestStatusPassed) {
+				test.Status = string(apistructs.TestStatusSkipped)
+				if msg := message(node); len(msg) > 0 {
+					test.Error = &pb.TestError{Message: msg, Type: node.Attr("type")}
+				}
+			}
+		case "failure":
+			if test.Status != string(apistructs.TestStatusError) {
+				test.Status = string(apistructs.TestStatusFailed)
+				test.Error = ingestError(node)
+			}
+		case "error":
+			test.Status = string(apistructs.TestStatusError)
+			test.Error = ingestError(node)
+		case "system-out":
+			test.Stdout = string(node.Content)
+		case "system-err":
+			test.Stderr = string(node.Content)
+		}
+	}
+	return test
+}
+
+func ingestError(root qaparser.XmlNode) *pb.TestError {
+	return &pb.TestError{
+		Body:    strings.TrimSpace(string(root.Content)),
+		Type:    root.Attr("type"),
+		Message: message(root),
+	}
+}
+
+// message returns the message attribute, or the first line of the content if absent.
+func message(root qaparser.XmlNode) string {
+	if msg := root.Attr("message"); len(msg) > 0 {
+		return msg
+	}
+	content := strings.TrimSpace(string(root.Content))
+	if idx := strings.IndexByte(content, '\n'); idx >= 0 {
+		content = content[:idx]
+	}
+	return content
+}
+
+// commonPackage returns the classname shared by all tests, it's the go package for gotestsum reports.
+func commonPackage(tests []*pb.Test) string {
+	pkg := tests[0].Classname
+	for _, test := range tests[1:] {
+		if test.Classname != pkg {
+			return ""
+		}
+	}
+	return pkg
+}
+
+func duration(t string) time.Duration {
+	t = strings.ReplaceAll(strings.TrimSpace(t), ",", "")
+	if s, err := strconv.ParseFloat(t, 64); err == nil {
+		return time.Duration(s*1000000) * time.Microsecond
+	}
+	if d, err := time.ParseDuration(t); err == nil {
+		return d
+	}
+	return 0
+}
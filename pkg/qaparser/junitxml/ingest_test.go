@@ -0,0 +1,106 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package junitxml
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/erda-project/erda/apistructs"
+)
+
+func TestIngestPytest(t *testing.T) {
+	data, err := os.ReadFile("../testdata/junit-pytest.xml")
+	assert.NoError(t, err)
+
+	suites, err := Ingest(data)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(suites))
+
+	suite := suites[0]
+	assert.Equal(t, "pytest", suite.Name)
+	assert.Equal(t, "", suite.Package)
+	assert.Equal(t, int64(4), suite.Totals.Tests)
+	assert.Equal(t, int64(1), suite.Totals.Statuses[string(apistructs.TestStatusPassed)])
+	assert.Equal(t, int64(1), suite.Totals.Statuses[string(apistructs.TestStatusFailed)])
+	assert.Equal(t, int64(1), suite.Totals.Statuses[string(apistructs.TestStatusSkipped)])
+	assert.Equal(t, int64(1), suite.Totals.Statuses[string(apistructs.TestStatusError)])
+
+	post := suite.Tests[1]
+	assert.Equal(t, "tests.test_api", post.Classname)
+	assert.Equal(t, "AssertionError: assert 500 == 201", post.Error.Message)
+	assert.Contains(t, post.Error.Body, "E       AssertionError")
+
+	skipped := suite.Tests[2]
+	assert.Equal(t, "not implemented", skipped.Error.Message)
+	assert.Equal(t, "pytest.skip", skipped.Error.Type)
+
+	assert.Equal(t, int64(1024001*time.Millisecond), suite.Tests[3].Duration)
+	assert.Equal(t, `failed on setup with "ConnectionError"`, suite.Tests[3].Error.Message)
+}
+
+func TestIngestJest(t *testing.T) {
+	data, err := os.ReadFile("../testdata/junit-jest.xml")
+	assert.NoError(t, err)
+
+	suites, err := Ingest(data)
+	assert.NoError(t, err)
+	// the suite without tests is dropped
+	assert.Equal(t, 1, len(suites))
+
+	suite := suites[0]
+	assert.Equal(t, "Button", suite.Name)
+	assert.Equal(t, int64(3), suite.Totals.Tests)
+
+	click := suite.Tests[1]
+	assert.Equal(t, string(apistructs.TestStatusFailed), click.Status)
+	// jest-junit has no message attribute, the first line of the body is used
+	assert.Equal(t, "Error: expect(jest.fn()).toHaveBeenCalled()", click.Error.Message)
+	assert.Contains(t, click.Error.Body, "src/Button.test.js:18:23")
+
+	disabled := suite.Tests[2]
+	assert.Equal(t, string(apistructs.TestStatusSkipped), disabled.Status)
+	assert.Nil(t, disabled.Error)
+}
+
+func TestIngestGotestsum(t *testing.T) {
+	data, err := os.ReadFile("../testdata/junit-gotestsum.xml")
+	assert.NoError(t, err)
+
+	suites, err := Ingest(data)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(suites))
+
+	suite := suites[0]
+	assert.Equal(t, "github.com/example/app/calc", suite.Package)
+	assert.Equal(t, "go1.19.13 linux/amd64", suite.Properties["go.version"])
+	assert.Equal(t, int64(1), suite.Totals.Statuses[string(apistructs.TestStatusFailed)])
+	assert.Contains(t, suite.Tests[1].Error.Body, "expected 2, got 3")
+}
+
+func TestIngestNested(t *testing.T) {
+	data := []byte(`<testsuites><testsuite name="outer"><properties><property name="env" value="ci"/></properties>
+<testsuite name="inner"><testcase name="a" time="0.5"/></testsuite></testsuite></testsuites>`)
+	suites, err := Ingest(data)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(suites))
+	assert.Equal(t, "outer/inner", suites[0].Name)
+	assert.Equal(t, "ci", suites[0].Properties["env"])
+	assert.Equal(t, "inner", suites[0].Tests[0].Classname)
+	assert.Equal(t, int64(500*time.Millisecond), suites[0].Tests[0].Duration)
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2021 Terminus, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package junitxml
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/erda-project/erda-proto-go/dop/qa/unittest/pb"
+	"github.com/erda-project/erda/pkg/qaparser"
+	"github.com/erda-project/erda/pkg/qaparser/types"
+)
+
+// GenericParser parses the junit xml variants produced by pytest, jest-junit, gotestsum and so on.
+type GenericParser struct {
+}
+
+func init() {
+	logrus.Info("register JUnit Generic Parser to manager")
+	(GenericParser{}).Register()
+}
+
+func (g GenericParser) Register() {
+	qaparser.Register(g, types.JUnitGeneric, types.Pytest, types.Jest)
+}
+
+// parse xml to entity
+// 1. get file from cloud storage
+// 2. parse
+func (GenericParser) Parse(endpoint, ak, sk, bucket, objectName string) ([]*pb.TestSuite, error) {
+	data, err := qaparser.Download(endpoint, ak, sk, bucket, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return Ingest(data)
+}
@@ -24,6 +24,18 @@ const (
 	NGTest TestParserType = "NGTEST"
 	// 使用 junit 生成的 xml 格式进行解析
 	JUnit TestParserType = "JUNIT"
+	// 使用 go test -json 的输出进行解析
+	GoTest TestParserType = "GOTEST"
+	// 使用通用 junit xml 格式进行解析, 兼容 gotestsum 等工具的输出
+	JUnitGeneric TestParserType = "JUNIT_GENERIC"
+	// 使用 pytest --junitxml 生成的格式进行解析
+	Pytest TestParserType = "PYTEST"
+	// 使用 jest-junit 生成的格式进行解析
+	Jest TestParserType = "JEST"
+	// 使用 cobertura 覆盖率报告进行解析
+	Cobertura TestParserType = "COBERTURA"
+	// 使用 lcov 覆盖率报告进行解析
+	LCOV TestParserType = "LCOV"
 )
 
 func (t TestParserType) TPValue() string {
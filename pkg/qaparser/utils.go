@@ -26,6 +26,8 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+
+	"github.com/erda-project/erda/pkg/cloudstorage"
 )
 
 // 解压 tar.gz
@@ -186,3 +188,17 @@ func NodeParse(data []byte) ([]XmlNode, error) {
 
 	return root.Nodes, nil
 }
+
+// Download gets the report file from cloud storage.
+func Download(endpoint, ak, sk, bucket, objectName string) ([]byte, error) {
+	client, err := cloudstorage.New(endpoint, ak, sk)
+	if err != nil {
+		return nil, errors.Wrap(err, "get cloud storage client")
+	}
+
+	data, err := client.DownloadFile(bucket, objectName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "download filename=%s", objectName)
+	}
+	return data, nil
+}
@@ -17,6 +17,8 @@ package semver
 import (
 	"fmt"
 	"regexp"
+	"strconv"
+	"strings"
 )
 
 // Semantic Version
@@ -42,3 +44,145 @@ func New(major int, vers ...int) string {
 	}
 	return fmt.Sprintf("%d.%d.%d", major, minor, patch)
 }
+
+// Version is a parsed semantic version
+type Version struct {
+	Prefix     string // "v" or empty
+	Major      int
+	Minor      int
+	Patch      int
+	PreRelease string
+	Build      string
+}
+
+// Parse parses ver as a semantic version
+func Parse(ver string) (*Version, error) {
+	m := SemverRegexp.FindStringSubmatch(ver)
+	if m == nil {
+		return nil, fmt.Errorf("invalid semantic version: %s", ver)
+	}
+	v := &Version{Prefix: m[1], PreRelease: m[5], Build: m[6]}
+	var err error
+	if v.Major, err = strconv.Atoi(m[2]); err != nil {
+		return nil, err
+	}
+	if v.Minor, err = strconv.Atoi(m[3]); err != nil {
+		return nil, err
+	}
+	if v.Patch, err = strconv.Atoi(m[4]); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// String returns the version in the form of [v]major.minor.patch[-prerelease][+build]
+func (v Version) String() string {
+	s := v.Prefix + New(v.Major, v.Minor, v.Patch)
+	if v.PreRelease != "" {
+		s += "-" + v.PreRelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Level is the level of a version bump
+type Level int
+
+const (
+	LevelNone Level = iota
+	LevelPatch
+	LevelMinor
+	LevelMajor
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelPatch:
+		return "patch"
+	case LevelMinor:
+		return "minor"
+	case LevelMajor:
+		return "major"
+	}
+	return "none"
+}
+
+// Bump returns the next version of the level, the pre-release and build metadata are dropped.
+// A pre-release version is released as is if it already covers the level, e.g. 2.0.0-rc.1 bumps to 2.0.0.
+func (v Version) Bump(level Level) Version {
+	next := Version{Prefix: v.Prefix, Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+	if v.PreRelease != "" {
+		switch {
+		case v.Minor == 0 && v.Patch == 0,
+			level <= LevelMinor && v.Patch == 0,
+			level <= LevelPatch:
+			return next
+		}
+	}
+	switch level {
+	case LevelMajor:
+		next.Major++
+		next.Minor, next.Patch = 0, 0
+	case LevelMinor:
+		next.Minor++
+		next.Patch = 0
+	case LevelPatch:
+		next.Patch++
+	}
+	return next
+}
+
+// Compare compares two versions by precedence, returns -1, 0 or 1. Build metadata is ignored.
+func Compare(a, b Version) int {
+	for _, pair := range [][2]int{{a.Major, b.Major}, {a.Minor, b.Minor}, {a.Patch, b.Patch}} {
+		switch {
+		case pair[0] < pair[1]:
+			return -1
+		case pair[0] > pair[1]:
+			return 1
+		}
+	}
+	return comparePreRelease(a.PreRelease, b.PreRelease)
+}
+
+func comparePreRelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(pa[i], pb[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	switch {
+	case len(pa) < len(pb):
+		return -1
+	case len(pa) > len(pb):
+		return 1
+	}
+	return 0
+}
@@ -57,3 +57,50 @@ func TestNew(t *testing.T) {
 	v3 := New(3, 5, 1)
 	assert.Equal(t, "3.5.1", v3)
 }
+
+func TestParse(t *testing.T) {
+	v, err := Parse("v1.2.3-rc.1+20220210153458")
+	assert.NoError(t, err)
+	assert.Equal(t, Version{Prefix: "v", Major: 1, Minor: 2, Patch: 3, PreRelease: "rc.1", Build: "20220210153458"}, *v)
+	assert.Equal(t, "v1.2.3-rc.1+20220210153458", v.String())
+
+	_, err = Parse("1.2")
+	assert.Error(t, err)
+}
+
+func TestBump(t *testing.T) {
+	cases := []struct {
+		ver   string
+		level Level
+		want  string
+	}{
+		{"1.2.3", LevelPatch, "1.2.4"},
+		{"1.2.3", LevelMinor, "1.3.0"},
+		{"v1.2.3", LevelMajor, "v2.0.0"},
+		{"1.2.3+20220210153458", LevelNone, "1.2.3"},
+		{"2.0.0-rc.1", LevelMajor, "2.0.0"},
+		{"1.3.0-rc.1", LevelMinor, "1.3.0"},
+		{"1.3.0-rc.1", LevelMajor, "2.0.0"},
+		{"1.2.4-rc.1", LevelPatch, "1.2.4"},
+		{"1.2.4-rc.1", LevelMinor, "1.3.0"},
+	}
+	for _, c := range cases {
+		v, err := Parse(c.ver)
+		assert.NoError(t, err)
+		assert.Equal(t, c.want, v.Bump(c.level).String(), "%s bump %s", c.ver, c.level)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	ordered := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2",
+		"1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0"}
+	for i := 0; i < len(ordered)-1; i++ {
+		a, _ := Parse(ordered[i])
+		b, _ := Parse(ordered[i+1])
+		assert.Equal(t, -1, Compare(*a, *b), "%s < %s", ordered[i], ordered[i+1])
+		assert.Equal(t, 1, Compare(*b, *a), "%s > %s", ordered[i+1], ordered[i])
+	}
+	a, _ := Parse("1.0.0+build1")
+	b, _ := Parse("v1.0.0")
+	assert.Equal(t, 0, Compare(*a, *b))
+}